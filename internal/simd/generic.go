@@ -0,0 +1,61 @@
+package simd
+
+// The generic kernels keep four independent sums so the loop is not bound by
+// the latency of a single floating point add.
+
+func dotGeneric(a, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+func squaredL2Generic(a, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		d0 := a[i] - b[i]
+		d1 := a[i+1] - b[i+1]
+		d2 := a[i+2] - b[i+2]
+		d3 := a[i+3] - b[i+3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+	}
+	for ; i < len(a); i++ {
+		d := a[i] - b[i]
+		s0 += d * d
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+func dotNormsGeneric(a, b []float32) (dot, normA, normB float32) {
+	b = b[:len(a)]
+	var d0, d1, na0, na1, nb0, nb1 float32
+	i := 0
+	for ; i+2 <= len(a); i += 2 {
+		d0 += a[i] * b[i]
+		d1 += a[i+1] * b[i+1]
+		na0 += a[i] * a[i]
+		na1 += a[i+1] * a[i+1]
+		nb0 += b[i] * b[i]
+		nb1 += b[i+1] * b[i+1]
+	}
+	for ; i < len(a); i++ {
+		d0 += a[i] * b[i]
+		na0 += a[i] * a[i]
+		nb0 += b[i] * b[i]
+	}
+	return d0 + d1, na0 + na1, nb0 + nb1
+}
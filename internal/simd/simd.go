@@ -0,0 +1,110 @@
+// Package simd provides float32 distance kernels for the vector indexes.
+//
+// On amd64 with AVX2 and FMA, and on arm64 with NEON, the bulk of each vector
+// is processed by assembly kernels chosen once at startup; the remaining
+// elements, and every other platform, use portable Go loops. Building with
+// the purego tag disables the assembly kernels.
+package simd
+
+import "math"
+
+// Implementation reports which kernels are in use: "avx2", "neon" or "generic"
+func Implementation() string {
+	if useAsm {
+		return asmName
+	}
+	return "generic"
+}
+
+// Dot returns the dot product of a and b. b must be at least as long as a.
+func Dot(a, b []float32) float32 {
+	b = b[:len(a)]
+	n := asmLen(len(a))
+	var sum float32
+	if n > 0 {
+		sum = dotAsm(a[:n], b[:n])
+	}
+	return sum + dotGeneric(a[n:], b[n:])
+}
+
+// SquaredL2 returns the squared Euclidean distance between a and b. b must be
+// at least as long as a.
+func SquaredL2(a, b []float32) float32 {
+	b = b[:len(a)]
+	n := asmLen(len(a))
+	var sum float32
+	if n > 0 {
+		sum = squaredL2Asm(a[:n], b[:n])
+	}
+	return sum + squaredL2Generic(a[n:], b[n:])
+}
+
+// DotNorms returns the dot product of a and b together with their squared
+// norms, computed in a single pass. b must be at least as long as a.
+func DotNorms(a, b []float32) (dot, normA, normB float32) {
+	b = b[:len(a)]
+	n := asmLen(len(a))
+	if n > 0 {
+		dot, normA, normB = dotNormsAsm(a[:n], b[:n])
+	}
+	d, na, nb := dotNormsGeneric(a[n:], b[n:])
+	return dot + d, normA + na, normB + nb
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 when either vector
+// has zero norm. b must be at least as long as a.
+func Cosine(a, b []float32) float32 {
+	dot, normA, normB := DotNorms(a, b)
+	return cosine(dot, normA, normB)
+}
+
+// DotBatch writes the dot product of query with each vector to out, which
+// must be at least len(vectors) long
+func DotBatch(query []float32, vectors [][]float32, out []float32) {
+	out = out[:len(vectors)]
+	for i, vec := range vectors {
+		out[i] = Dot(query, vec)
+	}
+}
+
+// SquaredL2Batch writes the squared Euclidean distance between query and
+// each vector to out, which must be at least len(vectors) long
+func SquaredL2Batch(query []float32, vectors [][]float32, out []float32) {
+	out = out[:len(vectors)]
+	for i, vec := range vectors {
+		out[i] = SquaredL2(query, vec)
+	}
+}
+
+// CosineBatch writes the cosine similarity of query with each vector to out,
+// which must be at least len(vectors) long. The query norm is computed once.
+func CosineBatch(query []float32, vectors [][]float32, out []float32) {
+	out = out[:len(vectors)]
+	queryNorm := Dot(query, query)
+	for i, vec := range vectors {
+		vec = vec[:len(query)]
+		n := asmLen(len(query))
+		var dot, norm float32
+		if n > 0 {
+			dot, norm = dotNormAsm(query[:n], vec[:n])
+		}
+		d, _, nv := dotNormsGeneric(query[n:], vec[n:])
+		out[i] = cosine(dot+d, queryNorm, norm+nv)
+	}
+}
+
+func cosine(dot, normA, normB float32) float32 {
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / float32(math.Sqrt(float64(normA))*math.Sqrt(float64(normB)))
+}
+
+// asmLen returns how many leading elements of an n-element vector the
+// assembly kernels handle
+func asmLen(n int) int {
+	if !useAsm {
+		return 0
+	}
+	return n &^ (asmBlock - 1)
+}
@@ -0,0 +1,24 @@
+//go:build amd64 && !purego
+
+package simd
+
+import "golang.org/x/sys/cpu"
+
+const (
+	asmName  = "avx2"
+	asmBlock = 8 // float32 lanes in a YMM register
+)
+
+var useAsm = cpu.X86.HasAVX2 && cpu.X86.HasFMA
+
+//go:noescape
+func dotAsm(a, b []float32) float32
+
+//go:noescape
+func squaredL2Asm(a, b []float32) float32
+
+//go:noescape
+func dotNormsAsm(a, b []float32) (dot, normA, normB float32)
+
+//go:noescape
+func dotNormAsm(a, b []float32) (dot, normB float32)
@@ -0,0 +1,24 @@
+//go:build arm64 && !purego
+
+package simd
+
+import "golang.org/x/sys/cpu"
+
+const (
+	asmName  = "neon"
+	asmBlock = 4 // float32 lanes in a 128-bit vector register
+)
+
+var useAsm = cpu.ARM64.HasASIMD
+
+//go:noescape
+func dotAsm(a, b []float32) float32
+
+//go:noescape
+func squaredL2Asm(a, b []float32) float32
+
+//go:noescape
+func dotNormsAsm(a, b []float32) (dot, normA, normB float32)
+
+//go:noescape
+func dotNormAsm(a, b []float32) (dot, normB float32)
@@ -0,0 +1,183 @@
+//go:build arm64 && !purego
+
+#include "textflag.h"
+
+// The kernels require len(a) == len(b) and a length that is a multiple of 4;
+// the Go wrappers handle the remaining elements.
+
+// HSUM reduces the four lanes of V (whose low lane is F) into F, using T
+// (low lane FT) as scratch
+#define HSUM(V, F, T, FT) \
+	VEXT  $8, V.B16, V.B16, T.B16; \
+	VFADD T.S4, V.S4, V.S4;        \
+	VDUP  V.S[1], T.S4;            \
+	FADDS FT, F, F
+
+// func dotAsm(a, b []float32) float32
+TEXT ·dotAsm(SB), NOSPLIT, $0-52
+	MOVD a_base+0(FP), R0
+	MOVD a_len+8(FP), R2
+	MOVD b_base+24(FP), R1
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+dotLoop16:
+	CMP    $16, R2
+	BLT    dotLoop4
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1.P 64(R1), [V8.S4, V9.S4, V10.S4, V11.S4]
+	VFMLA  V4.S4, V8.S4, V0.S4
+	VFMLA  V5.S4, V9.S4, V1.S4
+	VFMLA  V6.S4, V10.S4, V2.S4
+	VFMLA  V7.S4, V11.S4, V3.S4
+	SUB    $16, R2
+	B      dotLoop16
+
+dotLoop4:
+	CBZ    R2, dotReduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R1), [V8.S4]
+	VFMLA  V4.S4, V8.S4, V0.S4
+	SUB    $4, R2
+	B      dotLoop4
+
+dotReduce:
+	VFADD V1.S4, V0.S4, V0.S4
+	VFADD V3.S4, V2.S4, V2.S4
+	VFADD V2.S4, V0.S4, V0.S4
+	HSUM(V0, F0, V1, F1)
+	FMOVS F0, ret+48(FP)
+	RET
+
+// func squaredL2Asm(a, b []float32) float32
+TEXT ·squaredL2Asm(SB), NOSPLIT, $0-52
+	MOVD a_base+0(FP), R0
+	MOVD a_len+8(FP), R2
+	MOVD b_base+24(FP), R1
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+l2Loop16:
+	CMP    $16, R2
+	BLT    l2Loop4
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1.P 64(R1), [V8.S4, V9.S4, V10.S4, V11.S4]
+	VFSUB  V8.S4, V4.S4, V4.S4
+	VFSUB  V9.S4, V5.S4, V5.S4
+	VFSUB  V10.S4, V6.S4, V6.S4
+	VFSUB  V11.S4, V7.S4, V7.S4
+	VFMLA  V4.S4, V4.S4, V0.S4
+	VFMLA  V5.S4, V5.S4, V1.S4
+	VFMLA  V6.S4, V6.S4, V2.S4
+	VFMLA  V7.S4, V7.S4, V3.S4
+	SUB    $16, R2
+	B      l2Loop16
+
+l2Loop4:
+	CBZ    R2, l2Reduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R1), [V8.S4]
+	VFSUB  V8.S4, V4.S4, V4.S4
+	VFMLA  V4.S4, V4.S4, V0.S4
+	SUB    $4, R2
+	B      l2Loop4
+
+l2Reduce:
+	VFADD V1.S4, V0.S4, V0.S4
+	VFADD V3.S4, V2.S4, V2.S4
+	VFADD V2.S4, V0.S4, V0.S4
+	HSUM(V0, F0, V1, F1)
+	FMOVS F0, ret+48(FP)
+	RET
+
+// func dotNormsAsm(a, b []float32) (dot, normA, normB float32)
+TEXT ·dotNormsAsm(SB), NOSPLIT, $0-60
+	MOVD a_base+0(FP), R0
+	MOVD a_len+8(FP), R2
+	MOVD b_base+24(FP), R1
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+	VEOR V4.B16, V4.B16, V4.B16
+	VEOR V5.B16, V5.B16, V5.B16
+
+normsLoop8:
+	CMP    $8, R2
+	BLT    normsLoop4
+	VLD1.P 32(R0), [V6.S4, V7.S4]
+	VLD1.P 32(R1), [V8.S4, V9.S4]
+	VFMLA  V6.S4, V8.S4, V0.S4
+	VFMLA  V7.S4, V9.S4, V1.S4
+	VFMLA  V6.S4, V6.S4, V2.S4
+	VFMLA  V7.S4, V7.S4, V3.S4
+	VFMLA  V8.S4, V8.S4, V4.S4
+	VFMLA  V9.S4, V9.S4, V5.S4
+	SUB    $8, R2
+	B      normsLoop8
+
+normsLoop4:
+	CBZ    R2, normsReduce
+	VLD1.P 16(R0), [V6.S4]
+	VLD1.P 16(R1), [V8.S4]
+	VFMLA  V6.S4, V8.S4, V0.S4
+	VFMLA  V6.S4, V6.S4, V2.S4
+	VFMLA  V8.S4, V8.S4, V4.S4
+	SUB    $4, R2
+	B      normsLoop4
+
+normsReduce:
+	VFADD V1.S4, V0.S4, V0.S4
+	VFADD V3.S4, V2.S4, V2.S4
+	VFADD V5.S4, V4.S4, V4.S4
+	HSUM(V0, F0, V1, F1)
+	HSUM(V2, F2, V3, F3)
+	HSUM(V4, F4, V5, F5)
+	FMOVS F0, dot+48(FP)
+	FMOVS F2, normA+52(FP)
+	FMOVS F4, normB+56(FP)
+	RET
+
+// func dotNormAsm(a, b []float32) (dot, normB float32)
+TEXT ·dotNormAsm(SB), NOSPLIT, $0-56
+	MOVD a_base+0(FP), R0
+	MOVD a_len+8(FP), R2
+	MOVD b_base+24(FP), R1
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+normLoop8:
+	CMP    $8, R2
+	BLT    normLoop4
+	VLD1.P 32(R0), [V6.S4, V7.S4]
+	VLD1.P 32(R1), [V8.S4, V9.S4]
+	VFMLA  V6.S4, V8.S4, V0.S4
+	VFMLA  V7.S4, V9.S4, V1.S4
+	VFMLA  V8.S4, V8.S4, V2.S4
+	VFMLA  V9.S4, V9.S4, V3.S4
+	SUB    $8, R2
+	B      normLoop8
+
+normLoop4:
+	CBZ    R2, normReduce
+	VLD1.P 16(R0), [V6.S4]
+	VLD1.P 16(R1), [V8.S4]
+	VFMLA  V6.S4, V8.S4, V0.S4
+	VFMLA  V8.S4, V8.S4, V2.S4
+	SUB    $4, R2
+	B      normLoop4
+
+normReduce:
+	VFADD V1.S4, V0.S4, V0.S4
+	VFADD V3.S4, V2.S4, V2.S4
+	HSUM(V0, F0, V1, F1)
+	HSUM(V2, F2, V3, F3)
+	FMOVS F0, dot+48(FP)
+	FMOVS F2, normB+52(FP)
+	RET
@@ -0,0 +1,20 @@
+//go:build (!amd64 && !arm64) || purego
+
+package simd
+
+const (
+	asmName  = "generic"
+	asmBlock = 1
+	useAsm   = false
+)
+
+func dotAsm(a, b []float32) float32 { return dotGeneric(a, b) }
+
+func squaredL2Asm(a, b []float32) float32 { return squaredL2Generic(a, b) }
+
+func dotNormsAsm(a, b []float32) (dot, normA, normB float32) { return dotNormsGeneric(a, b) }
+
+func dotNormAsm(a, b []float32) (dot, normB float32) {
+	dot, _, normB = dotNormsGeneric(a, b)
+	return dot, normB
+}
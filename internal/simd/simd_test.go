@@ -0,0 +1,151 @@
+package simd
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomVector(rng *rand.Rand, n int) []float32 {
+	vec := make([]float32, n)
+	for i := range vec {
+		vec[i] = rng.Float32()*2 - 1
+	}
+	return vec
+}
+
+func approxEqual(got float32, want float64) bool {
+	return math.Abs(float64(got)-want) <= 1e-4*math.Max(1, math.Abs(want))
+}
+
+func TestKernels(t *testing.T) {
+	t.Logf("using %s kernels", Implementation())
+	rng := rand.New(rand.NewSource(1))
+
+	lengths := []int{0, 1, 3, 4, 7, 8, 9, 15, 16, 17, 31, 32, 33, 63, 100, 127, 384, 768, 1536}
+	for _, n := range lengths {
+		// Offset by one element so the kernels also see unaligned data
+		a := randomVector(rng, n+1)[1:]
+		b := randomVector(rng, n+1)[1:]
+
+		var dot, normA, normB, l2 float64
+		for i := range a {
+			dot += float64(a[i]) * float64(b[i])
+			normA += float64(a[i]) * float64(a[i])
+			normB += float64(b[i]) * float64(b[i])
+			diff := float64(a[i]) - float64(b[i])
+			l2 += diff * diff
+		}
+		cos := 0.0
+		if normA > 0 && normB > 0 {
+			cos = dot / (math.Sqrt(normA) * math.Sqrt(normB))
+		}
+
+		if got := Dot(a, b); !approxEqual(got, dot) {
+			t.Errorf("n=%d: Dot = %v, want %v", n, got, dot)
+		}
+		if got := SquaredL2(a, b); !approxEqual(got, l2) {
+			t.Errorf("n=%d: SquaredL2 = %v, want %v", n, got, l2)
+		}
+		d, na, nb := DotNorms(a, b)
+		if !approxEqual(d, dot) || !approxEqual(na, normA) || !approxEqual(nb, normB) {
+			t.Errorf("n=%d: DotNorms = %v, %v, %v, want %v, %v, %v", n, d, na, nb, dot, normA, normB)
+		}
+		if got := Cosine(a, b); !approxEqual(got, cos) {
+			t.Errorf("n=%d: Cosine = %v, want %v", n, got, cos)
+		}
+
+		if got := dotGeneric(a, b); !approxEqual(got, dot) {
+			t.Errorf("n=%d: dotGeneric = %v, want %v", n, got, dot)
+		}
+		if got := squaredL2Generic(a, b); !approxEqual(got, l2) {
+			t.Errorf("n=%d: squaredL2Generic = %v, want %v", n, got, l2)
+		}
+	}
+
+	if got := Cosine(make([]float32, 16), randomVector(rng, 16)); got != 0 {
+		t.Errorf("Cosine with zero vector = %v, want 0", got)
+	}
+}
+
+func TestBatchKernels(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, dim := range []int{5, 64, 300} {
+		query := randomVector(rng, dim)
+		vectors := make([][]float32, 50)
+		for i := range vectors {
+			vectors[i] = randomVector(rng, dim)
+		}
+		vectors[7] = make([]float32, dim)
+
+		dots := make([]float32, len(vectors))
+		dists := make([]float32, len(vectors))
+		cosines := make([]float32, len(vectors))
+		DotBatch(query, vectors, dots)
+		SquaredL2Batch(query, vectors, dists)
+		CosineBatch(query, vectors, cosines)
+
+		for i, vec := range vectors {
+			if dots[i] != Dot(query, vec) {
+				t.Errorf("dim=%d: DotBatch[%d] = %v, want %v", dim, i, dots[i], Dot(query, vec))
+			}
+			if dists[i] != SquaredL2(query, vec) {
+				t.Errorf("dim=%d: SquaredL2Batch[%d] = %v, want %v", dim, i, dists[i], SquaredL2(query, vec))
+			}
+			if want := Cosine(query, vec); !approxEqual(cosines[i], float64(want)) {
+				t.Errorf("dim=%d: CosineBatch[%d] = %v, want %v", dim, i, cosines[i], want)
+			}
+		}
+	}
+}
+
+func TestShortSecondOperand(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic when b is shorter than a")
+		}
+	}()
+	Dot(make([]float32, 8), make([]float32, 4))
+}
+
+func benchmarkPair(b *testing.B, fn func(a, b []float32) float32) {
+	rng := rand.New(rand.NewSource(3))
+	x, y := randomVector(rng, 768), randomVector(rng, 768)
+	b.SetBytes(2 * 4 * 768)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = fn(x, y)
+	}
+}
+
+func BenchmarkDot(b *testing.B) {
+	b.Run("generic", func(b *testing.B) { benchmarkPair(b, dotGeneric) })
+	b.Run(Implementation(), func(b *testing.B) { benchmarkPair(b, Dot) })
+}
+
+func BenchmarkSquaredL2(b *testing.B) {
+	b.Run("generic", func(b *testing.B) { benchmarkPair(b, squaredL2Generic) })
+	b.Run(Implementation(), func(b *testing.B) { benchmarkPair(b, SquaredL2) })
+}
+
+func BenchmarkCosine(b *testing.B) {
+	generic := func(a, b []float32) float32 { return cosine(dotNormsGeneric(a, b)) }
+	b.Run("generic", func(b *testing.B) { benchmarkPair(b, generic) })
+	b.Run(Implementation(), func(b *testing.B) { benchmarkPair(b, Cosine) })
+}
+
+func BenchmarkCosineBatch(b *testing.B) {
+	rng := rand.New(rand.NewSource(4))
+	query := randomVector(rng, 768)
+	vectors := make([][]float32, 1000)
+	for i := range vectors {
+		vectors[i] = randomVector(rng, 768)
+	}
+	out := make([]float32, len(vectors))
+
+	b.SetBytes(int64(len(vectors)) * 4 * 768)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CosineBatch(query, vectors, out)
+	}
+}
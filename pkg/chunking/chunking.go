@@ -0,0 +1,190 @@
+// Package chunking splits documents into chunks for embedding. Every chunk
+// records where it came from in the source, so callers can cite or
+// highlight the original text.
+package chunking
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of a source text
+type Chunk struct {
+	Text     string            `json:"text"`
+	Start    int               `json:"start"`              // Offset of the chunk in the source
+	End      int               `json:"end"`                // Offset just past the chunk
+	Metadata map[string]string `json:"metadata,omitempty"` // Strategy-specific context, e.g. the markdown section
+}
+
+// Offsets are byte positions, so source[chunk.Start:chunk.End] is the
+// chunk's text for every strategy except Words, which normalizes whitespace.
+
+// Chunker splits text into chunks in source order
+type Chunker interface {
+	Chunk(text string) []Chunk
+}
+
+// Strategy names accepted by New
+const (
+	StrategyWords     = "words"
+	StrategyRecursive = "recursive"
+	StrategyMarkdown  = "markdown"
+	StrategySentence  = "sentence"
+	StrategyToken     = "token"
+	StrategyCode      = "code"
+)
+
+// Strategies lists the strategy names accepted by New
+func Strategies() []string {
+	return []string{StrategyWords, StrategyRecursive, StrategyMarkdown, StrategySentence, StrategyToken, StrategyCode}
+}
+
+// New returns the chunker for a strategy name; empty means words. Size and
+// overlap are in the strategy's unit (words for words, tokens for token,
+// sentences for the sentence overlap, characters otherwise), 0 takes the
+// strategy's default.
+func New(strategy string, size, overlap int) (Chunker, error) {
+	if size < 0 || overlap < 0 {
+		return nil, fmt.Errorf("chunking: size and overlap must not be negative")
+	}
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "", StrategyWords:
+		return Words{Size: size, Overlap: overlap}, nil
+	case StrategyRecursive:
+		return Recursive{Size: size, Overlap: overlap}, nil
+	case StrategyMarkdown:
+		return Markdown{Size: size, Overlap: overlap}, nil
+	case StrategySentence:
+		return Sentence{Size: size, Overlap: overlap}, nil
+	case StrategyToken:
+		return Token{Size: size, Overlap: overlap}, nil
+	case StrategyCode:
+		return Code{Size: size}, nil
+	default:
+		return nil, fmt.Errorf("chunking: unknown strategy %q (want one of %s)", strategy, strings.Join(Strategies(), ", "))
+	}
+}
+
+// Span is a byte range of a text
+type Span struct {
+	Start int
+	End   int
+}
+
+// Words is the original GraphRAG splitter: each line is a paragraph, and
+// paragraphs longer than Size words are cut into windows of Size words
+// overlapping by Overlap. Whitespace inside a chunk is collapsed to single
+// spaces.
+type Words struct {
+	Size    int // Words per chunk (default: 120)
+	Overlap int // Words repeated from the previous window (default: 0, at most Size/4 if not below Size)
+}
+
+// Chunk implements Chunker
+func (w Words) Chunk(text string) []Chunk {
+	size, overlap := w.Size, w.Overlap
+	if size <= 0 {
+		size = 120
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	if overlap >= size {
+		overlap = size / 4
+	}
+	step := size - overlap
+	if step <= 0 {
+		step = size
+	}
+
+	var chunks []Chunk
+	for _, line := range splitLines(text) {
+		words := WordTokenizer{}.Tokenize(text[line.Start:line.End])
+		if len(words) == 0 {
+			continue
+		}
+		for start := 0; start < len(words); start += step {
+			end := start + size
+			if end > len(words) {
+				end = len(words)
+			}
+			parts := make([]string, 0, end-start)
+			for _, word := range words[start:end] {
+				parts = append(parts, text[line.Start+word.Start:line.Start+word.End])
+			}
+			chunks = append(chunks, Chunk{
+				Text:  strings.Join(parts, " "),
+				Start: line.Start + words[start].Start,
+				End:   line.Start + words[end-1].End,
+			})
+			if end == len(words) {
+				break
+			}
+		}
+	}
+	return chunks
+}
+
+// splitLines returns the spans between line breaks
+func splitLines(text string) []Span {
+	var lines []Span
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' || text[i] == '\r' {
+			if i > start {
+				lines = append(lines, Span{start, i})
+			}
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		lines = append(lines, Span{start, len(text)})
+	}
+	return lines
+}
+
+// trimSpan shrinks s to exclude leading and trailing whitespace
+func trimSpan(text string, s Span) Span {
+	for s.Start < s.End {
+		r, n := utf8.DecodeRuneInString(text[s.Start:s.End])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		s.Start += n
+	}
+	for s.End > s.Start {
+		r, n := utf8.DecodeLastRuneInString(text[s.Start:s.End])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		s.End -= n
+	}
+	return s
+}
+
+// appendChunk adds the trimmed span as a chunk unless it is blank
+func appendChunk(chunks []Chunk, text string, s Span, metadata map[string]string) []Chunk {
+	s = trimSpan(text, s)
+	if s.Start >= s.End {
+		return chunks
+	}
+	return append(chunks, Chunk{Text: text[s.Start:s.End], Start: s.Start, End: s.End, Metadata: cloneMetadata(metadata)})
+}
+
+func cloneMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		out[k] = v
+	}
+	return out
+}
+
+// charCount measures text in characters
+func charCount(text string) int {
+	return utf8.RuneCountInString(text)
+}
@@ -0,0 +1,230 @@
+package chunking
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkOffsets verifies that every chunk is its slice of the source
+func checkOffsets(t *testing.T, text string, chunks []Chunk) {
+	t.Helper()
+	if len(chunks) == 0 {
+		t.Fatal("Expected chunks")
+	}
+	for i, c := range chunks {
+		if c.Start < 0 || c.End > len(text) || c.Start >= c.End {
+			t.Fatalf("Chunk %d has invalid offsets [%d, %d)", i, c.Start, c.End)
+		}
+		if text[c.Start:c.End] != c.Text {
+			t.Errorf("Chunk %d text %q doesn't match source %q", i, c.Text, text[c.Start:c.End])
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	text := "one two  three four five\n\nsix"
+	chunks := Words{Size: 3, Overlap: 1}.Chunk(text)
+	want := []string{"one two three", "three four five", "six"}
+	if len(chunks) != len(want) {
+		t.Fatalf("Expected %d chunks, got %+v", len(want), chunks)
+	}
+	for i, c := range chunks {
+		if c.Text != want[i] {
+			t.Errorf("Chunk %d: expected %q, got %q", i, want[i], c.Text)
+		}
+	}
+	if text[chunks[0].Start:chunks[0].End] != "one two  three" {
+		t.Errorf("Expected offsets to span the source words, got [%d, %d)", chunks[0].Start, chunks[0].End)
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	text := "First paragraph is short.\n\nSecond paragraph is a good deal longer. It has two sentences.\n\n" + strings.Repeat("x", 50)
+	chunks := Recursive{Size: 40}.Chunk(text)
+	checkOffsets(t, text, chunks)
+	for _, c := range chunks {
+		if charCount(c.Text) > 40 {
+			t.Errorf("Chunk exceeds size: %q", c.Text)
+		}
+	}
+	if chunks[0].Text != "First paragraph is short." {
+		t.Errorf("Expected the first paragraph alone, got %q", chunks[0].Text)
+	}
+	if chunks[1].Text != "Second paragraph is a good deal longer." {
+		t.Errorf("Expected a sentence split, got %q", chunks[1].Text)
+	}
+
+	t.Run("Overlap", func(t *testing.T) {
+		text := "aa bb cc dd ee ff gg hh"
+		chunks := Recursive{Size: 9, Overlap: 3}.Chunk(text)
+		checkOffsets(t, text, chunks)
+		if chunks[0].Text != "aa bb cc" || chunks[1].Text != "cc dd ee" {
+			t.Errorf("Expected chunks sharing a word, got %+v", chunks)
+		}
+	})
+
+	t.Run("Unicode", func(t *testing.T) {
+		text := strings.Repeat("日本語", 10)
+		chunks := Recursive{Size: 7}.Chunk(text)
+		checkOffsets(t, text, chunks)
+		if len(chunks) != 5 || charCount(chunks[0].Text) != 7 {
+			t.Errorf("Expected 7-character chunks, got %d chunks", len(chunks))
+		}
+	})
+}
+
+func TestMarkdown(t *testing.T) {
+	text := "Intro text.\n\n# Install\n\nRun the installer.\n\n## Linux\n\nUse the package.\n\n```sh\n# not a heading\napt install x\n```\n\n# Usage ##\n\nCall it.\n"
+	chunks := Markdown{}.Chunk(text)
+	checkOffsets(t, text, chunks)
+
+	sections := []string{"", "Install", "Install > Linux", "Usage"}
+	if len(chunks) != len(sections) {
+		t.Fatalf("Expected %d chunks, got %d: %+v", len(sections), len(chunks), chunks)
+	}
+	for i, c := range chunks {
+		if c.Metadata["section"] != sections[i] {
+			t.Errorf("Chunk %d: expected section %q, got %q", i, sections[i], c.Metadata["section"])
+		}
+	}
+	if !strings.Contains(chunks[2].Text, "apt install x") {
+		t.Errorf("Expected the code block to stay in its section, got %q", chunks[2].Text)
+	}
+	if chunks[2].Metadata["heading_level"] != "2" || chunks[3].Metadata["heading"] != "Usage" {
+		t.Errorf("Unexpected heading metadata %v, %v", chunks[2].Metadata, chunks[3].Metadata)
+	}
+
+	long := "# Big\n\n" + strings.Repeat("word ", 100)
+	chunks = Markdown{Size: 80}.Chunk(long)
+	checkOffsets(t, long, chunks)
+	if len(chunks) < 5 || chunks[len(chunks)-1].Metadata["section"] != "Big" {
+		t.Errorf("Expected a long section split with its path kept, got %d chunks", len(chunks))
+	}
+}
+
+func TestSentence(t *testing.T) {
+	text := `Dr. Smith arrived at 10 a.m. on Monday. "Is it done?" she asked! The U.S. team agreed.` + "\n\nNew paragraph without a period"
+	spans := Sentences(text)
+	var got []string
+	for _, s := range spans {
+		got = append(got, text[s.Start:s.End])
+	}
+	want := []string{
+		"Dr. Smith arrived at 10 a.m. on Monday.",
+		`"Is it done?" she asked!`,
+		"The U.S. team agreed.",
+		"New paragraph without a period",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Unexpected sentences:\n%q\nwant\n%q", got, want)
+	}
+
+	chunks := Sentence{Size: 70, Overlap: 1}.Chunk(text)
+	checkOffsets(t, text, chunks)
+	if len(chunks) != 3 || !strings.HasPrefix(chunks[1].Text, `"Is it done?"`) {
+		t.Errorf("Expected sentences packed with one repeated, got %+v", chunks)
+	}
+
+	if chunks := (Sentence{}).Chunk("日本語です。次の文。"); len(chunks) != 2 {
+		t.Errorf("Expected CJK sentences split, got %+v", chunks)
+	}
+}
+
+func TestToken(t *testing.T) {
+	text := "a b c d e f g"
+	chunks := Token{Size: 3, Overlap: 1}.Chunk(text)
+	checkOffsets(t, text, chunks)
+	if len(chunks) != 3 || chunks[1].Text != "c d e" || chunks[2].Text != "e f g" {
+		t.Errorf("Unexpected token windows %+v", chunks)
+	}
+
+	chunks = Token{Size: 2, Tokenizer: CharTokenizer{CharsPerToken: 4}}.Chunk("abcdefghij kl")
+	if len(chunks) != 2 || chunks[0].Text != "abcdefgh" || chunks[1].Text != "ij kl" {
+		t.Errorf("Unexpected subword windows %+v", chunks)
+	}
+
+	custom := TokenizerFunc(func(text string) []Span {
+		var spans []Span
+		for i := range text {
+			if text[i] != ',' {
+				spans = append(spans, Span{i, i + 1})
+			}
+		}
+		return spans
+	})
+	if chunks := (Token{Size: 2, Tokenizer: custom}).Chunk("ab,cd"); len(chunks) != 2 || chunks[1].Text != "cd" {
+		t.Errorf("Expected the custom tokenizer to be used, got %+v", chunks)
+	}
+}
+
+func TestCode(t *testing.T) {
+	text := `package main
+
+import "fmt"
+
+// Greet says hello.
+// It is exported.
+func Greet(name string) {
+	fmt.Println("hello", name)
+}
+
+type Server struct {
+	addr string
+}
+
+func (s *Server) Start() error {
+	if s.addr == "" {
+		return errors.New("no address")
+	}
+	log.Printf("listening on %s", s.addr)
+	return nil
+}
+`
+	chunks := Code{Size: 100}.Chunk(text)
+	checkOffsets(t, text, chunks)
+
+	var greet *Chunk
+	for i := range chunks {
+		if chunks[i].Metadata["declaration"] == "func Greet(name string) {" {
+			greet = &chunks[i]
+		}
+	}
+	if greet == nil {
+		t.Fatalf("Expected a chunk for Greet, got %+v", chunks)
+	}
+	if !strings.HasPrefix(greet.Text, "// Greet says hello.") || !strings.HasSuffix(greet.Text, "}") {
+		t.Errorf("Expected Greet with its doc comment, got %q", greet.Text)
+	}
+	if chunks[0].Metadata["declaration"] != "package main" || !strings.Contains(chunks[0].Text, `import "fmt"`) {
+		t.Errorf("Expected small declarations packed together, got %q", chunks[0].Text)
+	}
+	last := chunks[len(chunks)-1]
+	if last.Metadata["declaration"] != "func (s *Server) Start() error {" {
+		t.Errorf("Expected the long method split with its declaration kept, got %v", last.Metadata)
+	}
+
+	python := "import os\n\n@cache\ndef load(path):\n    return open(path).read()\n\nclass Store:\n    def get(self):\n        pass\n"
+	chunks = Code{Size: 60}.Chunk(python)
+	checkOffsets(t, python, chunks)
+	if len(chunks) != 3 || !strings.HasPrefix(chunks[1].Text, "@cache") || chunks[1].Metadata["declaration"] != "def load(path):" {
+		t.Errorf("Expected decorators attached to their function, got %+v", chunks)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range Strategies() {
+		chunker, err := New(name, 0, 0)
+		if err != nil {
+			t.Fatalf("New(%q): %v", name, err)
+		}
+		if chunks := chunker.Chunk("Some text. More text."); len(chunks) == 0 {
+			t.Errorf("Strategy %q returned no chunks", name)
+		}
+	}
+	if _, err := New("paragraphs", 0, 0); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+	if chunker, _ := New("", 5, 1); chunker.(Words).Size != 5 {
+		t.Error("Expected words as the default strategy")
+	}
+}
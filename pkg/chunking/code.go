@@ -0,0 +1,156 @@
+package chunking
+
+import "strings"
+
+// Code splits source code at its top-level declarations: lines that start
+// in the first column, with the comments, decorators and attributes right
+// above them. Small neighbouring declarations are packed together up to
+// Size; longer ones are split at blank lines, then lines. The first line of
+// each chunk's first declaration is kept as the "declaration" metadata.
+//
+// Detection is by indentation only, so it works for most brace and
+// indentation based languages without parsing them.
+type Code struct {
+	Size int // Maximum chunk length in characters (default: 1500)
+}
+
+// Chunk implements Chunker
+func (c Code) Chunk(text string) []Chunk {
+	size := c.Size
+	if size <= 0 {
+		size = 1500
+	}
+
+	type line struct {
+		span      Span
+		content   string
+		boundary  bool // Starts a declaration
+		attached  bool // Comment or decorator at column 0
+		blankLine bool
+	}
+	var lines []line
+	for start := 0; start < len(text); {
+		end := strings.IndexByte(text[start:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += start
+		}
+		content := strings.TrimRight(text[start:end], "\r")
+		l := line{span: Span{start, end}, content: content, blankLine: strings.TrimSpace(content) == ""}
+		if !l.blankLine && content[0] != ' ' && content[0] != '\t' {
+			l.attached = isLeadingComment(content)
+			l.boundary = !l.attached && !isBlockContinuation(content)
+		}
+		lines = append(lines, l)
+		start = end + 1
+	}
+
+	// Declarations start at boundary lines, moved up over attached comments
+	var starts []int
+	for i, l := range lines {
+		if !l.boundary {
+			continue
+		}
+		first := i
+		for first > 0 && lines[first-1].attached {
+			first--
+		}
+		if len(starts) == 0 || first > starts[len(starts)-1] {
+			starts = append(starts, first)
+		}
+	}
+	if len(starts) == 0 || starts[0] != 0 {
+		starts = append([]int{0}, starts...)
+	}
+
+	type declaration struct {
+		span Span
+		name string
+	}
+	var decls []declaration
+	for i, first := range starts {
+		last := len(lines)
+		if i+1 < len(starts) {
+			last = starts[i+1]
+		}
+		if first >= last {
+			continue
+		}
+		decl := declaration{span: Span{lines[first].span.Start, lines[last-1].span.End}}
+		for _, l := range lines[first:last] {
+			if l.boundary {
+				decl.name = strings.TrimSpace(l.content)
+				break
+			}
+		}
+		decls = append(decls, decl)
+	}
+
+	splitter := Recursive{Size: size, Separators: []string{"\n\n", "\n", " ", ""}}
+	var chunks []Chunk
+	var group []declaration
+	total := 0
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		var metadata map[string]string
+		for _, decl := range group {
+			if decl.name != "" {
+				metadata = map[string]string{"declaration": decl.name}
+				break
+			}
+		}
+		chunks = appendChunk(chunks, text, Span{group[0].span.Start, group[len(group)-1].span.End}, metadata)
+		group, total = nil, 0
+	}
+	for _, decl := range decls {
+		n := charCount(text[decl.span.Start:decl.span.End])
+		if n > size {
+			flush()
+			var metadata map[string]string
+			if decl.name != "" {
+				metadata = map[string]string{"declaration": decl.name}
+			}
+			chunks = append(chunks, splitter.chunkSpan(text, decl.span, metadata)...)
+			continue
+		}
+		if total+n > size {
+			flush()
+		}
+		group = append(group, decl)
+		total += n
+	}
+	flush()
+	return chunks
+}
+
+// isLeadingComment reports whether a column-0 line belongs to the
+// declaration below it
+func isLeadingComment(line string) bool {
+	for _, prefix := range []string{"//", "/*", "*", "#", "--", ";", "@", `"""`, "'''"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBlockContinuation reports whether a column-0 line closes or continues
+// the declaration above it
+func isBlockContinuation(line string) bool {
+	switch line[0] {
+	case '}', ')', ']':
+		return true
+	}
+	word := line
+	if idx := strings.IndexAny(line, " :;{("); idx >= 0 {
+		word = line[:idx]
+	}
+	switch word {
+	case "end", "else", "elif", "except", "finally", "catch", "fi", "done", "esac":
+		return true
+	}
+	return false
+}
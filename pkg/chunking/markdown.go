@@ -0,0 +1,123 @@
+package chunking
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Markdown splits a document at its ATX headings (# to ######) so that no
+// chunk mixes sections. Each chunk carries its section path, e.g.
+// "Install > Linux", as the "section" metadata, along with "heading" and
+// "heading_level". Sections longer than Size are split further by
+// Recursive. Headings inside fenced code blocks are ignored.
+type Markdown struct {
+	Size    int // Maximum chunk length in characters (default: 1000)
+	Overlap int // Characters repeated when a long section is split (default: 0)
+}
+
+// Chunk implements Chunker
+func (m Markdown) Chunk(text string) []Chunk {
+	type section struct {
+		span  Span
+		path  []string
+		level int
+	}
+
+	var sections []section
+	var path []string
+	var levels []int
+	current := section{span: Span{0, 0}}
+	fence := ""
+
+	for lineStart := 0; lineStart < len(text); {
+		lineEnd := strings.IndexByte(text[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(text)
+		} else {
+			lineEnd += lineStart
+		}
+		line := strings.TrimRight(text[lineStart:lineEnd], "\r")
+
+		if marker := codeFence(line); marker != "" {
+			switch {
+			case fence == "":
+				fence = marker
+			case strings.HasPrefix(marker, fence[:1]) && len(marker) >= len(fence):
+				fence = ""
+			}
+		} else if level, title, ok := markdownHeading(line); ok && fence == "" {
+			current.span.End = lineStart
+			sections = append(sections, current)
+
+			for len(levels) > 0 && levels[len(levels)-1] >= level {
+				levels, path = levels[:len(levels)-1], path[:len(path)-1]
+			}
+			levels, path = append(levels, level), append(path, title)
+			current = section{span: Span{lineStart, lineStart}, path: append([]string(nil), path...), level: level}
+		}
+		lineStart = lineEnd + 1
+	}
+	current.span.End = len(text)
+	sections = append(sections, current)
+
+	splitter := Recursive{Size: m.Size, Overlap: m.Overlap}
+	var chunks []Chunk
+	for _, sec := range sections {
+		var metadata map[string]string
+		if len(sec.path) > 0 {
+			metadata = map[string]string{
+				"section":       strings.Join(sec.path, " > "),
+				"heading":       sec.path[len(sec.path)-1],
+				"heading_level": strconv.Itoa(sec.level),
+			}
+		}
+		chunks = append(chunks, splitter.chunkSpan(text, sec.span, metadata)...)
+	}
+	return chunks
+}
+
+// markdownHeading parses an ATX heading line
+func markdownHeading(line string) (int, string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return 0, "", false
+	}
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	rest := trimmed[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+	title := strings.TrimSpace(rest)
+	// Closing hashes are optional decoration
+	if stripped := strings.TrimRight(title, "#"); stripped != title && (stripped == "" || strings.HasSuffix(stripped, " ")) {
+		title = strings.TrimSpace(stripped)
+	}
+	return level, title, true
+}
+
+// codeFence returns the fence marker when line opens or closes a fenced
+// code block
+func codeFence(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return ""
+	}
+	c := trimmed[0]
+	if c != '`' && c != '~' {
+		return ""
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == c {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return trimmed[:n]
+}
@@ -0,0 +1,140 @@
+package chunking
+
+import "strings"
+
+// DefaultSeparators are tried in order by Recursive: paragraphs, lines,
+// sentences, words and finally single characters
+var DefaultSeparators = []string{"\n\n", "\n", ". ", "? ", "! ", " ", ""}
+
+// Recursive splits text at the coarsest separator that makes pieces fit,
+// retrying the next separator on pieces that are still too long, then packs
+// neighbouring pieces into chunks of up to Size.
+type Recursive struct {
+	Size       int                   // Maximum chunk length (default: 1000)
+	Overlap    int                   // Length repeated from the end of the previous chunk (default: 0)
+	Separators []string              // Tried in order; "" splits between characters (default: DefaultSeparators)
+	Length     func(text string) int // Measures text (default: characters)
+}
+
+// Chunk implements Chunker
+func (r Recursive) Chunk(text string) []Chunk {
+	return r.chunkSpan(text, Span{0, len(text)}, nil)
+}
+
+// chunkSpan chunks one range of text, giving each chunk metadata
+func (r Recursive) chunkSpan(text string, s Span, metadata map[string]string) []Chunk {
+	size := r.Size
+	if size <= 0 {
+		size = 1000
+	}
+	overlap := r.Overlap
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	length := r.Length
+	if length == nil {
+		length = charCount
+	}
+	separators := r.Separators
+	if len(separators) == 0 {
+		separators = DefaultSeparators
+	}
+
+	pieces := splitRecursive(text, s, separators, size, length)
+	var chunks []Chunk
+	for _, span := range packSpans(text, pieces, size, overlap, length) {
+		chunks = appendChunk(chunks, text, span, metadata)
+	}
+	return chunks
+}
+
+// splitRecursive cuts s into contiguous pieces no longer than size where
+// the separators allow it
+func splitRecursive(text string, s Span, separators []string, size int, length func(string) int) []Span {
+	if length(text[s.Start:s.End]) <= size {
+		return []Span{s}
+	}
+
+	for i, sep := range separators {
+		if sep == "" {
+			return splitRunes(text, s)
+		}
+		if !strings.Contains(text[s.Start:s.End], sep) {
+			continue
+		}
+		var pieces []Span
+		for _, piece := range splitAfter(text, s, sep) {
+			if length(text[piece.Start:piece.End]) > size {
+				pieces = append(pieces, splitRecursive(text, piece, separators[i+1:], size, length)...)
+				continue
+			}
+			pieces = append(pieces, piece)
+		}
+		return pieces
+	}
+	// No separator applies and characters aren't allowed: keep it whole
+	return []Span{s}
+}
+
+// splitAfter cuts s after each occurrence of sep
+func splitAfter(text string, s Span, sep string) []Span {
+	var pieces []Span
+	start := s.Start
+	for start < s.End {
+		idx := strings.Index(text[start:s.End], sep)
+		if idx < 0 {
+			break
+		}
+		end := start + idx + len(sep)
+		pieces = append(pieces, Span{start, end})
+		start = end
+	}
+	if start < s.End {
+		pieces = append(pieces, Span{start, s.End})
+	}
+	return pieces
+}
+
+// splitRunes cuts s into single characters
+func splitRunes(text string, s Span) []Span {
+	pieces := make([]Span, 0, s.End-s.Start)
+	for i := range text[s.Start:s.End] {
+		pieces = append(pieces, Span{s.Start + i, s.Start + i})
+	}
+	for i := range pieces {
+		if i+1 < len(pieces) {
+			pieces[i].End = pieces[i+1].Start
+		} else {
+			pieces[i].End = s.End
+		}
+	}
+	return pieces
+}
+
+// packSpans joins contiguous pieces into spans of up to size, starting
+// each span with up to overlap of the previous one's trailing pieces.
+// Lengths are summed per piece, which is exact for characters and close
+// enough for tokenizers.
+func packSpans(text string, pieces []Span, size, overlap int, length func(string) int) []Span {
+	var spans []Span
+	var window []Span
+	var lengths []int
+	total := 0
+	for _, piece := range pieces {
+		n := length(text[piece.Start:piece.End])
+		if total+n > size && len(window) > 0 {
+			spans = append(spans, Span{window[0].Start, window[len(window)-1].End})
+			for len(window) > 0 && (total > overlap || total+n > size) {
+				total -= lengths[0]
+				window, lengths = window[1:], lengths[1:]
+			}
+		}
+		window = append(window, piece)
+		lengths = append(lengths, n)
+		total += n
+	}
+	if len(window) > 0 {
+		spans = append(spans, Span{window[0].Start, window[len(window)-1].End})
+	}
+	return spans
+}
@@ -0,0 +1,158 @@
+package chunking
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sentence splits text into sentences and packs consecutive sentences into
+// chunks of up to Size characters. A sentence longer than Size becomes a
+// chunk on its own. Sentences end at ., ! or ? (and their CJK forms)
+// followed by whitespace, and at blank lines; abbreviations and a following
+// lowercase word don't end a sentence.
+type Sentence struct {
+	Size    int // Maximum chunk length in characters (default: 0, one sentence per chunk)
+	Overlap int // Sentences repeated from the end of the previous chunk (default: 0)
+}
+
+// Chunk implements Chunker
+func (s Sentence) Chunk(text string) []Chunk {
+	sentences := Sentences(text)
+	if s.Size <= 0 {
+		var chunks []Chunk
+		for _, span := range sentences {
+			chunks = appendChunk(chunks, text, span, nil)
+		}
+		return chunks
+	}
+
+	var chunks []Chunk
+	var window []Span
+	total := 0
+	flush := func() {
+		chunks = appendChunk(chunks, text, Span{window[0].Start, window[len(window)-1].End}, nil)
+	}
+	for _, span := range sentences {
+		n := charCount(text[span.Start:span.End])
+		if total+n > s.Size && len(window) > 0 {
+			flush()
+			keep := s.Overlap
+			if keep >= len(window) {
+				keep = len(window) - 1
+			}
+			window = window[len(window)-keep:]
+			total = 0
+			for _, kept := range window {
+				total += charCount(text[kept.Start:kept.End])
+			}
+			// Drop carried sentences that leave no room for this one
+			for len(window) > 0 && total+n > s.Size {
+				total -= charCount(text[window[0].Start:window[0].End])
+				window = window[1:]
+			}
+		}
+		window = append(window, span)
+		total += n
+	}
+	if len(window) > 0 {
+		flush()
+	}
+	return chunks
+}
+
+// abbreviations don't end a sentence when followed by a period
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true, "jr": true, "st": true,
+	"vs": true, "etc": true, "e.g": true, "i.e": true, "cf": true, "al": true, "approx": true,
+	"no": true, "fig": true, "inc": true, "ltd": true, "co": true, "corp": true,
+}
+
+// Sentences returns the spans of the sentences in text, trimmed of
+// surrounding whitespace
+func Sentences(text string) []Span {
+	var spans []Span
+	start := 0
+	emit := func(end int) {
+		span := trimSpan(text, Span{start, end})
+		if span.Start < span.End {
+			spans = append(spans, span)
+		}
+		start = end
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		next := i + size
+
+		switch {
+		case r == '\n' && blankLineAt(text, next):
+			emit(next)
+		case r == '。' || r == '！' || r == '？':
+			emit(closeSentence(text, next))
+			next = start
+		case r == '.' || r == '!' || r == '?':
+			end := closeSentence(text, next)
+			if end < len(text) && !unicode.IsSpace(rune(text[end])) {
+				break
+			}
+			if nextWordLowercase(text[end:]) || (r == '.' && isAbbreviation(text[start:i])) {
+				break
+			}
+			emit(end)
+			next = start
+		}
+		i = next
+	}
+	emit(len(text))
+	return spans
+}
+
+// closeSentence skips closing quotes and brackets after a terminator
+func closeSentence(text string, i int) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !strings.ContainsRune(`"')]’”」』`, r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// blankLineAt reports whether only spaces come before the next line break
+func blankLineAt(text string, i int) bool {
+	for ; i < len(text); i++ {
+		switch text[i] {
+		case '\n':
+			return true
+		case ' ', '\t', '\r':
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// isAbbreviation reports whether before ends with a known abbreviation or
+// a single letter, as in initials
+func isAbbreviation(before string) bool {
+	word := before
+	if idx := strings.LastIndexFunc(before, unicode.IsSpace); idx >= 0 {
+		word = before[idx+1:]
+	}
+	word = strings.ToLower(strings.TrimLeft(word, `"'([`))
+	return utf8.RuneCountInString(word) == 1 || abbreviations[word]
+}
+
+// nextWordLowercase reports whether the text after a terminator continues
+// in lowercase, which means it didn't end the sentence
+func nextWordLowercase(after string) bool {
+	for _, r := range after {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		return unicode.IsLower(r)
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package chunking
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer finds the tokens of a text. Spans are returned in order and
+// must not overlap; text between them, such as whitespace, is not counted.
+type Tokenizer interface {
+	Tokenize(text string) []Span
+}
+
+// TokenizerFunc adapts a function to Tokenizer
+type TokenizerFunc func(text string) []Span
+
+// Tokenize implements Tokenizer
+func (f TokenizerFunc) Tokenize(text string) []Span {
+	return f(text)
+}
+
+// WordTokenizer treats each run of non-space characters as a token
+type WordTokenizer struct{}
+
+// Tokenize implements Tokenizer
+func (WordTokenizer) Tokenize(text string) []Span {
+	var spans []Span
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				spans = append(spans, Span{start, i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, Span{start, len(text)})
+	}
+	return spans
+}
+
+// CharTokenizer approximates subword tokenizers by cutting each word into
+// tokens of CharsPerToken characters; about four matches English text for
+// common embedding models
+type CharTokenizer struct {
+	CharsPerToken int // Characters per token (default: 4)
+}
+
+// Tokenize implements Tokenizer
+func (c CharTokenizer) Tokenize(text string) []Span {
+	per := c.CharsPerToken
+	if per <= 0 {
+		per = 4
+	}
+	var spans []Span
+	for _, word := range (WordTokenizer{}).Tokenize(text) {
+		start, count := word.Start, 0
+		for i := word.Start; i < word.End; {
+			_, size := utf8.DecodeRuneInString(text[i:word.End])
+			i += size
+			if count++; count == per || i == word.End {
+				spans = append(spans, Span{start, i})
+				start, count = i, 0
+			}
+		}
+	}
+	return spans
+}
+
+// Token cuts text into windows of Size tokens that overlap by Overlap
+// tokens, as counted by Tokenizer. Use it to stay under an embedding
+// model's input limit.
+type Token struct {
+	Size      int       // Tokens per chunk (default: 256)
+	Overlap   int       // Tokens repeated from the previous chunk (default: 0)
+	Tokenizer Tokenizer // Counts tokens (default: WordTokenizer)
+}
+
+// Chunk implements Chunker
+func (t Token) Chunk(text string) []Chunk {
+	size := t.Size
+	if size <= 0 {
+		size = 256
+	}
+	overlap := t.Overlap
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	tokenizer := t.Tokenizer
+	if tokenizer == nil {
+		tokenizer = WordTokenizer{}
+	}
+
+	tokens := tokenizer.Tokenize(text)
+	var chunks []Chunk
+	for start := 0; start < len(tokens); start += size - overlap {
+		end := start + size
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		chunks = appendChunk(chunks, text, Span{tokens[start].Start, tokens[end-1].End}, nil)
+		if end == len(tokens) {
+			break
+		}
+	}
+	return chunks
+}
@@ -527,6 +527,8 @@ func (s *SQLiteStore) SearchWithAdvancedFilter(ctx context.Context, query []floa
 	if s.closed {
 		return nil, wrapError("advanced_search", ErrStoreClosed)
 	}
+
+	opts.Collection = s.resolveCollectionName(ctx, opts.Collection)
 	
 	// Build SQL query with pre-filter
 	var whereClause string
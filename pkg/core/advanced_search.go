@@ -29,6 +29,8 @@ func (s *SQLiteStore) SearchWithACL(ctx context.Context, query []float32, acl []
 		return nil, wrapError("search_acl", ErrStoreClosed)
 	}
 
+	opts.Collection = s.resolveCollectionName(ctx, opts.Collection)
+
 	// If ACL is empty, only return public documents (acl IS NULL)
 	// If ACL is provided, return public OR matching acl
 	
@@ -57,6 +59,7 @@ func (s *SQLiteStore) SearchWithACL(ctx context.Context, query []float32, acl []
 
 // HybridSearch performs combined vector and keyword search using RRF fusion
 func (s *SQLiteStore) HybridSearch(ctx context.Context, vectorQuery []float32, textQuery string, opts HybridSearchOptions) ([]ScoredEmbedding, error) {
+	s.applyExternalWrites(ctx)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -68,7 +71,7 @@ func (s *SQLiteStore) HybridSearch(ctx context.Context, vectorQuery []float32, t
 	var vectorResults []ScoredEmbedding
 	var err error
 	if len(vectorQuery) > 0 {
-		vectorResults, err = s.Search(ctx, vectorQuery, opts.SearchOptions)
+		vectorResults, err = s.search(ctx, vectorQuery, opts.SearchOptions)
 		if err != nil {
 			return nil, fmt.Errorf("vector search failed: %w", err)
 		}
@@ -340,6 +343,7 @@ func (s *SQLiteStore) SearchWithNegatives(ctx context.Context, opts NegativeSear
 
 // SearchWithDiversity performs search with result diversification
 func (s *SQLiteStore) SearchWithDiversity(ctx context.Context, query []float32, opts DiversitySearchOptions) ([]ScoredEmbedding, error) {
+	s.applyExternalWrites(ctx)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
@@ -351,7 +355,7 @@ func (s *SQLiteStore) SearchWithDiversity(ctx context.Context, query []float32,
 	searchOpts := opts.SearchOptions
 	searchOpts.TopK = opts.TopK * 3 // Get 3x candidates for diversity selection
 	
-	candidates, err := s.Search(ctx, query, searchOpts)
+	candidates, err := s.search(ctx, query, searchOpts)
 	if err != nil {
 		return nil, wrapError("search_diversity", err)
 	}
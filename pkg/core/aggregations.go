@@ -60,6 +60,8 @@ func (s *SQLiteStore) Aggregate(ctx context.Context, req AggregationRequest) (*A
 		return nil, wrapError("aggregate", err)
 	}
 
+	req.Collection = s.resolveCollectionName(ctx, req.Collection)
+
 	switch req.Type {
 	case AggregationCount:
 		return s.aggregateCount(ctx, req)
@@ -0,0 +1,269 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CollectionAlias is a stable name that points at a concrete collection.
+// Clients address the alias while the target can be swapped underneath,
+// e.g. to cut over to a collection built with a new embedding model.
+type CollectionAlias struct {
+	Alias      string    `json:"alias"`
+	Collection string    `json:"collection"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreateAlias creates a new alias pointing at an existing collection
+func (s *SQLiteStore) CreateAlias(ctx context.Context, alias, collection string) (*CollectionAlias, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, wrapError("create_alias", ErrStoreClosed)
+	}
+
+	alias = strings.TrimSpace(alias)
+	if alias == "" {
+		return nil, wrapError("create_alias", fmt.Errorf("alias name cannot be empty"))
+	}
+
+	// Aliases and collections share one namespace
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM collections WHERE name = ?)", alias).Scan(&exists)
+	if err != nil {
+		return nil, wrapError("create_alias", fmt.Errorf("failed to check collection existence: %w", err))
+	}
+	if exists {
+		return nil, wrapError("create_alias", fmt.Errorf("a collection named '%s' already exists", alias))
+	}
+
+	err = s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM collection_aliases WHERE alias = ?)", alias).Scan(&exists)
+	if err != nil {
+		return nil, wrapError("create_alias", fmt.Errorf("failed to check alias existence: %w", err))
+	}
+	if exists {
+		return nil, wrapError("create_alias", fmt.Errorf("alias '%s' already exists", alias))
+	}
+
+	collectionID, err := s.collectionIDByName(ctx, collection)
+	if err != nil {
+		return nil, wrapError("create_alias", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO collection_aliases (alias, collection_id, created_at, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	`, alias, collectionID)
+	if err != nil {
+		return nil, wrapError("create_alias", fmt.Errorf("failed to create alias: %w", err))
+	}
+
+	created, err := s.getAlias(ctx, alias)
+	if err != nil {
+		return nil, wrapError("create_alias", err)
+	}
+
+	return created, nil
+}
+
+// UpdateAlias repoints an existing alias at another collection.
+// The swap is a single UPDATE, so concurrent readers either see the old
+// target or the new one, never a missing alias.
+func (s *SQLiteStore) UpdateAlias(ctx context.Context, alias, collection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return wrapError("update_alias", ErrStoreClosed)
+	}
+
+	collectionID, err := s.collectionIDByName(ctx, collection)
+	if err != nil {
+		return wrapError("update_alias", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE collection_aliases SET collection_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE alias = ?
+	`, collectionID, alias)
+	if err != nil {
+		return wrapError("update_alias", fmt.Errorf("failed to update alias: %w", err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return wrapError("update_alias", fmt.Errorf("failed to get affected rows: %w", err))
+	}
+	if rows == 0 {
+		return wrapError("update_alias", fmt.Errorf("alias '%s' not found", alias))
+	}
+
+	return nil
+}
+
+// DeleteAlias removes an alias; the target collection is left untouched
+func (s *SQLiteStore) DeleteAlias(ctx context.Context, alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return wrapError("delete_alias", ErrStoreClosed)
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM collection_aliases WHERE alias = ?", alias)
+	if err != nil {
+		return wrapError("delete_alias", fmt.Errorf("failed to delete alias: %w", err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return wrapError("delete_alias", fmt.Errorf("failed to get affected rows: %w", err))
+	}
+	if rows == 0 {
+		return wrapError("delete_alias", fmt.Errorf("alias '%s' not found", alias))
+	}
+
+	return nil
+}
+
+// GetAlias retrieves an alias and the collection it currently points at
+func (s *SQLiteStore) GetAlias(ctx context.Context, alias string) (*CollectionAlias, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("get_alias", ErrStoreClosed)
+	}
+
+	result, err := s.getAlias(ctx, alias)
+	if err != nil {
+		return nil, wrapError("get_alias", err)
+	}
+
+	return result, nil
+}
+
+// ListAliases lists all aliases ordered by name
+func (s *SQLiteStore) ListAliases(ctx context.Context) ([]*CollectionAlias, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("list_aliases", ErrStoreClosed)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.alias, c.name, a.created_at, a.updated_at
+		FROM collection_aliases a
+		JOIN collections c ON c.id = a.collection_id
+		ORDER BY a.alias
+	`)
+	if err != nil {
+		return nil, wrapError("list_aliases", fmt.Errorf("failed to list aliases: %w", err))
+	}
+	defer rows.Close()
+
+	var aliases []*CollectionAlias
+	for rows.Next() {
+		alias := &CollectionAlias{}
+		if err := rows.Scan(&alias.Alias, &alias.Collection, &alias.CreatedAt, &alias.UpdatedAt); err != nil {
+			return nil, wrapError("list_aliases", fmt.Errorf("failed to scan alias: %w", err))
+		}
+		aliases = append(aliases, alias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("list_aliases", fmt.Errorf("failed to iterate aliases: %w", err))
+	}
+
+	return aliases, nil
+}
+
+// ResolveCollection returns the concrete collection name for name.
+// Names that are not aliases are returned unchanged.
+func (s *SQLiteStore) ResolveCollection(ctx context.Context, name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return "", wrapError("resolve_collection", ErrStoreClosed)
+	}
+
+	resolved, err := s.lookupAliasTarget(ctx, name)
+	if err != nil {
+		return "", wrapError("resolve_collection", err)
+	}
+
+	return resolved, nil
+}
+
+// resolveCollectionName maps an alias onto its collection for use inside
+// query paths that already hold the store lock. Lookup failures fall back to
+// the original name so the caller's own "not found" handling applies.
+func (s *SQLiteStore) resolveCollectionName(ctx context.Context, name string) string {
+	resolved, err := s.lookupAliasTarget(ctx, name)
+	if err != nil {
+		s.logger.Warn("failed to resolve collection alias", "name", name, "error", err)
+		return name
+	}
+	return resolved
+}
+
+// lookupAliasTarget performs the alias lookup without taking the store lock
+func (s *SQLiteStore) lookupAliasTarget(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return name, nil
+	}
+
+	var target string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT c.name FROM collection_aliases a
+		JOIN collections c ON c.id = a.collection_id
+		WHERE a.alias = ?
+	`, name).Scan(&target)
+	if err == sql.ErrNoRows {
+		return name, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve alias: %w", err)
+	}
+
+	return target, nil
+}
+
+// getAlias loads a single alias without taking the store lock
+func (s *SQLiteStore) getAlias(ctx context.Context, alias string) (*CollectionAlias, error) {
+	result := &CollectionAlias{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT a.alias, c.name, a.created_at, a.updated_at
+		FROM collection_aliases a
+		JOIN collections c ON c.id = a.collection_id
+		WHERE a.alias = ?
+	`, alias).Scan(&result.Alias, &result.Collection, &result.CreatedAt, &result.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("alias '%s' not found", alias)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alias: %w", err)
+	}
+
+	return result, nil
+}
+
+// collectionIDByName finds the ID of a concrete collection.
+// Aliases are deliberately not followed so alias chains cannot form.
+func (s *SQLiteStore) collectionIDByName(ctx context.Context, name string) (int, error) {
+	var id int
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM collections WHERE name = ?", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("collection '%s' not found", name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find collection: %w", err)
+	}
+	return id, nil
+}
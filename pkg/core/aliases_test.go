@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCollectionAliases(t *testing.T) {
+	dbPath := "test_aliases.db"
+	defer os.Remove(dbPath)
+
+	store, err := New(dbPath, 3)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.Init(ctx); err != nil {
+		t.Fatalf("Failed to initialize store: %v", err)
+	}
+
+	for _, name := range []string{"docs_v1", "docs_v2"} {
+		if _, err := store.CreateCollection(ctx, name, 3); err != nil {
+			t.Fatalf("Failed to create collection %s: %v", name, err)
+		}
+	}
+
+	if err := store.Upsert(ctx, &Embedding{ID: "v1", Collection: "docs_v1", Vector: []float32{1, 0, 0}, Content: "old"}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if err := store.Upsert(ctx, &Embedding{ID: "v2", Collection: "docs_v2", Vector: []float32{1, 0, 0}, Content: "new"}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	t.Run("CreateAlias", func(t *testing.T) {
+		alias, err := store.CreateAlias(ctx, "docs", "docs_v1")
+		if err != nil {
+			t.Fatalf("Failed to create alias: %v", err)
+		}
+		if alias.Alias != "docs" || alias.Collection != "docs_v1" {
+			t.Errorf("Unexpected alias %+v", alias)
+		}
+
+		if _, err := store.CreateAlias(ctx, "docs", "docs_v2"); err == nil {
+			t.Error("Expected error when creating duplicate alias")
+		}
+		if _, err := store.CreateAlias(ctx, "docs_v2", "docs_v1"); err == nil {
+			t.Error("Expected error when alias shadows a collection")
+		}
+		if _, err := store.CreateAlias(ctx, "chained", "docs"); err == nil {
+			t.Error("Expected error when targeting another alias")
+		}
+		if _, err := store.CreateCollection(ctx, "docs", 3); err == nil {
+			t.Error("Expected error when collection shadows an alias")
+		}
+	})
+
+	t.Run("ResolveThroughAlias", func(t *testing.T) {
+		collection, err := store.GetCollection(ctx, "docs")
+		if err != nil {
+			t.Fatalf("Failed to get collection through alias: %v", err)
+		}
+		if collection.Name != "docs_v1" {
+			t.Errorf("Expected docs_v1, got %s", collection.Name)
+		}
+
+		results, err := store.Search(ctx, []float32{1, 0, 0}, SearchOptions{Collection: "docs", TopK: 5})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "v1" {
+			t.Errorf("Expected only v1 via alias, got %+v", results)
+		}
+
+		if err := store.Upsert(ctx, &Embedding{ID: "v1b", Collection: "docs", Vector: []float32{0, 1, 0}, Content: "via alias"}); err != nil {
+			t.Fatalf("Upsert through alias failed: %v", err)
+		}
+		resp, err := store.Aggregate(ctx, AggregationRequest{Type: AggregationCount, Collection: "docs"})
+		if err != nil {
+			t.Fatalf("Aggregate failed: %v", err)
+		}
+		if resp.Results[0].Count != 2 {
+			t.Errorf("Expected 2 embeddings in docs_v1, got %d", resp.Results[0].Count)
+		}
+	})
+
+	t.Run("UpdateAlias", func(t *testing.T) {
+		if err := store.UpdateAlias(ctx, "docs", "docs_v2"); err != nil {
+			t.Fatalf("Failed to update alias: %v", err)
+		}
+
+		results, err := store.Search(ctx, []float32{1, 0, 0}, SearchOptions{Collection: "docs", TopK: 5})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "v2" {
+			t.Errorf("Expected only v2 after swap, got %+v", results)
+		}
+
+		if err := store.UpdateAlias(ctx, "missing", "docs_v2"); err == nil {
+			t.Error("Expected error when updating unknown alias")
+		}
+		if err := store.UpdateAlias(ctx, "docs", "missing"); err == nil {
+			t.Error("Expected error when targeting unknown collection")
+		}
+	})
+
+	t.Run("ListAndDeleteAlias", func(t *testing.T) {
+		aliases, err := store.ListAliases(ctx)
+		if err != nil {
+			t.Fatalf("Failed to list aliases: %v", err)
+		}
+		if len(aliases) != 1 || aliases[0].Collection != "docs_v2" {
+			t.Errorf("Unexpected aliases %+v", aliases)
+		}
+
+		if err := store.DeleteAlias(ctx, "docs"); err != nil {
+			t.Fatalf("Failed to delete alias: %v", err)
+		}
+		if _, err := store.GetCollection(ctx, "docs"); err == nil {
+			t.Error("Expected alias to be gone")
+		}
+		if _, err := store.GetCollection(ctx, "docs_v2"); err != nil {
+			t.Errorf("Target collection should survive alias deletion: %v", err)
+		}
+	})
+
+	t.Run("DeleteCollectionDropsAliases", func(t *testing.T) {
+		if _, err := store.CreateAlias(ctx, "legacy", "docs_v1"); err != nil {
+			t.Fatalf("Failed to create alias: %v", err)
+		}
+		if err := store.DeleteCollection(ctx, "docs_v1"); err != nil {
+			t.Fatalf("Failed to delete collection: %v", err)
+		}
+		if _, err := store.GetAlias(ctx, "legacy"); err == nil {
+			t.Error("Expected alias to be removed with its collection")
+		}
+	})
+}
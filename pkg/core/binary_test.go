@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinaryIndex(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "binary.db")
+
+	config := DefaultConfig()
+	config.Path = dbPath
+	config.IndexType = IndexTypeBinary
+	config.Binary.Substrings = 4
+	config.AutoSave.Enabled = false
+
+	ctx := context.Background()
+	randomVec := newRandomVec(11, 64)
+
+	// The dimension is detected on the first insert
+	store := openTestStore(t, config)
+	if err := store.Upsert(ctx, &Embedding{ID: "vec_0", Vector: randomVec()}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	batch := make([]*Embedding, 0, 499)
+	for i := 1; i < 500; i++ {
+		batch = append(batch, &Embedding{ID: fmt.Sprintf("vec_%d", i), Vector: randomVec()})
+	}
+	if err := store.UpsertBatch(ctx, batch); err != nil {
+		t.Fatalf("UpsertBatch failed: %v", err)
+	}
+
+	t.Run("Codes", func(t *testing.T) {
+		if store.binaryIndex == nil || store.binaryIndex.Size() != 500 {
+			t.Fatal("Expected a code for every vector")
+		}
+		var n, size int
+		err := store.db.QueryRowContext(ctx, "SELECT COUNT(*), MAX(length(binary_code)) FROM embeddings WHERE binary_code IS NOT NULL").Scan(&n, &size)
+		if err != nil || n != 500 || size != 8 {
+			t.Errorf("Expected 500 stored 8-byte codes, got %d of %d bytes, %v", n, size, err)
+		}
+		expectTop(t, store, "vec_0")
+		expectTop(t, store, "vec_250")
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		if err := store.Upsert(ctx, &Embedding{ID: "vec_5", Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		expectTop(t, store, "vec_5")
+
+		if err := store.UpsertWithCondition(ctx, &Embedding{ID: "vec_6", Vector: randomVec()}, WriteCondition{UpdateOnly: true}); err != nil {
+			t.Fatalf("UpsertWithCondition failed: %v", err)
+		}
+		expectTop(t, store, "vec_6")
+
+		if err := store.Delete(ctx, "vec_1"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if store.binaryIndex.Contains("vec_1") || store.binaryIndex.Size() != 499 {
+			t.Error("Deleted vector should leave the binary index")
+		}
+	})
+
+	t.Run("WithoutRescore", func(t *testing.T) {
+		store.config.Binary.Rescore = false
+		defer func() { store.config.Binary.Rescore = true }()
+
+		emb, _ := store.GetByID(ctx, "vec_42")
+		results, err := store.Search(ctx, emb.Vector, SearchOptions{TopK: 5})
+		if err != nil || len(results) == 0 || results[0].ID != "vec_42" {
+			t.Fatalf("Expected vec_42 first, got %v, %v", results, err)
+		}
+		if math.Abs(results[0].Score-1) > 1e-9 {
+			t.Errorf("Expected an estimated score of 1 for an identical code, got %f", results[0].Score)
+		}
+		for i := 1; i < len(results); i++ {
+			if results[i].Score > results[i-1].Score {
+				t.Error("Expected results sorted by score")
+			}
+		}
+	})
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	t.Run("Reopen", func(t *testing.T) {
+		config.VectorDim = 64
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		if store.binaryIndex.Size() != 499 {
+			t.Errorf("Expected 499 codes loaded, got %d", store.binaryIndex.Size())
+		}
+		expectTop(t, store, "vec_99")
+		expectTop(t, store, "vec_5")
+	})
+}
+
+func TestBinaryIndexTrain(t *testing.T) {
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "binary_train.db")
+	config.VectorDim = 32
+	config.IndexType = IndexTypeBinary
+	config.AutoSave.Enabled = false
+
+	ctx := context.Background()
+
+	// Vectors far from the origin all share the same sign bits
+	rng := rand.New(rand.NewSource(13))
+	store := openTestStore(t, config)
+	embs := make([]*Embedding, 300)
+	for i := range embs {
+		vec := make([]float32, 32)
+		for j := range vec {
+			vec[j] = 5 + rng.Float32()*2 - 1
+		}
+		embs[i] = &Embedding{ID: fmt.Sprintf("vec_%d", i), Vector: vec}
+	}
+	if err := store.UpsertBatch(ctx, embs); err != nil {
+		t.Fatalf("UpsertBatch failed: %v", err)
+	}
+	code, _ := store.binaryQuantizer.Encode(embs[0].Vector)
+	if _, dists, _ := store.binaryIndex.Search(code, 300); dists[len(dists)-1] != 0 {
+		t.Fatal("Expected identical sign codes before training")
+	}
+
+	if err := store.TrainIndex(ctx, 0); err != nil {
+		t.Fatalf("TrainIndex failed: %v", err)
+	}
+	if math.Abs(float64(store.binaryQuantizer.Threshold[0])-5) > 0.2 {
+		t.Errorf("Expected a threshold near the mean, got %f", store.binaryQuantizer.Threshold[0])
+	}
+	code, _ = store.binaryQuantizer.Encode(embs[0].Vector)
+	if _, dists, _ := store.binaryIndex.Search(code, 300); dists[len(dists)-1] == 0 {
+		t.Fatal("Expected trained codes to separate the vectors")
+	}
+	threshold := store.binaryQuantizer.Threshold[0]
+	store.Close()
+
+	store = openTestStore(t, config)
+	defer store.Close()
+	if store.binaryQuantizer.Threshold[0] != threshold {
+		t.Error("Expected thresholds restored from the snapshot")
+	}
+	results, err := store.Search(ctx, embs[42].Vector, SearchOptions{TopK: 3})
+	if err != nil || len(results) == 0 || results[0].ID != "vec_42" {
+		t.Errorf("Expected vec_42 as top result, got %v, %v", results, err)
+	}
+}
@@ -0,0 +1,473 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+)
+
+// BulkLoadPhase identifies the stage a bulk load is in
+type BulkLoadPhase string
+
+const (
+	// BulkLoadPhaseLoading means rows are being streamed into the embeddings table
+	BulkLoadPhaseLoading BulkLoadPhase = "loading"
+	// BulkLoadPhaseFullText means the loaded rows are being added to chunks_fts
+	BulkLoadPhaseFullText BulkLoadPhase = "fts"
+	// BulkLoadPhaseIndex means the vector index is being built
+	BulkLoadPhaseIndex BulkLoadPhase = "index"
+	// BulkLoadPhaseSnapshot means the index snapshot is being written
+	BulkLoadPhaseSnapshot BulkLoadPhase = "snapshot"
+	// BulkLoadPhaseDone means the load finished and the store is fully indexed
+	BulkLoadPhaseDone BulkLoadPhase = "done"
+)
+
+const defaultBulkLoadBatchSize = 10000
+
+// BulkLoadOptions configures a bulk load session
+type BulkLoadOptions struct {
+	// LoadID names the load. Opening a loader with the ID of an interrupted
+	// load resumes it; RowsLoaded then reports how many input rows to skip.
+	LoadID string
+	// BatchSize is the number of rows committed per transaction (default 10000)
+	BatchSize int
+	// OnProgress is called after every committed batch and at each phase change
+	OnProgress func(BulkLoadProgress)
+}
+
+// BulkLoadProgress reports the state of a running bulk load
+type BulkLoadProgress struct {
+	LoadID     string        `json:"load_id"`
+	Phase      BulkLoadPhase `json:"phase"`
+	RowsLoaded int64         `json:"rows_loaded"`
+	Elapsed    time.Duration `json:"elapsed"`
+}
+
+// BulkLoadState is the persisted checkpoint of a bulk load
+type BulkLoadState struct {
+	LoadID     string        `json:"load_id"`
+	Status     BulkLoadPhase `json:"status"`
+	RowsLoaded int64         `json:"rows_loaded"`
+	LastID     string        `json:"last_id,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// BulkLoader streams embeddings into the store for initial ingestion.
+//
+// Loaded rows are flagged so the chunks_fts triggers skip them, while other
+// writes keep being indexed as usual, and the vector index is not touched
+// per row. Finish adds the flagged rows to full-text search in one pass,
+// builds the HNSW index with parallel construction and snapshots it.
+// Every committed batch also advances a checkpoint, so after a crash the load
+// can be resumed by opening a loader with the same LoadID.
+//
+// A BulkLoader is not safe for concurrent use.
+type BulkLoader struct {
+	store      *SQLiteStore
+	opts       BulkLoadOptions
+	pending    []*Embedding
+	rowsLoaded int64
+	lastID     string
+	started    time.Time
+	finished   bool
+}
+
+// NewBulkLoader starts a bulk load, or resumes an interrupted one with the same LoadID
+func (s *SQLiteStore) NewBulkLoader(ctx context.Context, opts BulkLoadOptions) (*BulkLoader, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("bulk_load", ErrStoreClosed)
+	}
+
+	opts.LoadID = strings.TrimSpace(opts.LoadID)
+	if opts.LoadID == "" {
+		return nil, wrapError("bulk_load", fmt.Errorf("load ID cannot be empty"))
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBulkLoadBatchSize
+	}
+
+	loader := &BulkLoader{store: s, opts: opts, started: time.Now()}
+
+	state, err := s.getBulkLoad(ctx, opts.LoadID)
+	if err != nil {
+		return nil, wrapError("bulk_load", err)
+	}
+	if state != nil {
+		if state.Status == BulkLoadPhaseDone {
+			return nil, wrapError("bulk_load", fmt.Errorf("bulk load '%s' already finished", opts.LoadID))
+		}
+		loader.rowsLoaded = state.RowsLoaded
+		loader.lastID = state.LastID
+		s.logger.Info("resuming bulk load", "load_id", opts.LoadID, "rows_loaded", state.RowsLoaded)
+	} else {
+		_, err = s.db.ExecContext(ctx, `
+			INSERT INTO bulk_loads (id, status, rows_loaded, started_at, updated_at)
+			VALUES (?, ?, 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		`, opts.LoadID, BulkLoadPhaseLoading)
+		if err != nil {
+			return nil, wrapError("bulk_load", fmt.Errorf("failed to record bulk load: %w", err))
+		}
+	}
+
+	return loader, nil
+}
+
+// ID returns the load ID
+func (l *BulkLoader) ID() string {
+	return l.opts.LoadID
+}
+
+// RowsLoaded returns the number of rows committed so far, including rows
+// committed before the load was resumed
+func (l *BulkLoader) RowsLoaded() int64 {
+	return l.rowsLoaded
+}
+
+// LastID returns the ID of the last committed row
+func (l *BulkLoader) LastID() string {
+	return l.lastID
+}
+
+// Add queues embeddings and commits a batch whenever BatchSize rows are pending.
+// On error the pending batch is discarded; RowsLoaded tells where to resume.
+func (l *BulkLoader) Add(ctx context.Context, embs ...*Embedding) error {
+	if l.finished {
+		return wrapError("bulk_load", fmt.Errorf("bulk load '%s' already finished", l.opts.LoadID))
+	}
+
+	for _, emb := range embs {
+		l.pending = append(l.pending, emb)
+		if len(l.pending) >= l.opts.BatchSize {
+			if err := l.Flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flush commits pending rows together with the resume checkpoint
+func (l *BulkLoader) Flush(ctx context.Context) error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+
+	batch := l.pending
+	l.pending = nil
+
+	if err := l.store.bulkInsert(ctx, l.opts.LoadID, batch); err != nil {
+		return err
+	}
+
+	l.rowsLoaded += int64(len(batch))
+	l.lastID = batch[len(batch)-1].ID
+	l.report(BulkLoadPhaseLoading)
+
+	return nil
+}
+
+// Finish commits remaining rows, indexes them for full-text search, builds the vector
+// index and snapshots it. The store is fully consistent once Finish returns.
+func (l *BulkLoader) Finish(ctx context.Context) error {
+	if l.finished {
+		return nil
+	}
+
+	if err := l.Flush(ctx); err != nil {
+		return err
+	}
+
+	s := l.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return wrapError("bulk_load", ErrStoreClosed)
+	}
+
+	l.report(BulkLoadPhaseFullText)
+	if err := s.indexDeferredFullText(ctx); err != nil {
+		return wrapError("bulk_load", err)
+	}
+
+	l.report(BulkLoadPhaseIndex)
+	latest, err := s.latestChangeSeq(ctx)
+	if err != nil {
+		return wrapError("bulk_load", err)
+	}
+	if err := s.rebuildIndexes(ctx, false); err != nil {
+		return wrapError("bulk_load", err)
+	}
+	s.changeSeq.Store(latest)
+	s.localChanges.reset()
+
+	l.report(BulkLoadPhaseSnapshot)
+	if err := s.saveIndexSnapshot(ctx); err != nil {
+		return wrapError("bulk_load", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE bulk_loads SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, BulkLoadPhaseDone, l.opts.LoadID)
+	if err != nil {
+		return wrapError("bulk_load", fmt.Errorf("failed to mark bulk load finished: %w", err))
+	}
+
+	l.finished = true
+	l.report(BulkLoadPhaseDone)
+	s.logger.Info("bulk load finished", "load_id", l.opts.LoadID, "rows", l.rowsLoaded, "elapsed", time.Since(l.started))
+
+	return nil
+}
+
+func (l *BulkLoader) report(phase BulkLoadPhase) {
+	if l.opts.OnProgress == nil {
+		return
+	}
+	l.opts.OnProgress(BulkLoadProgress{
+		LoadID:     l.opts.LoadID,
+		Phase:      phase,
+		RowsLoaded: l.rowsLoaded,
+		Elapsed:    time.Since(l.started),
+	})
+}
+
+// ListBulkLoads lists recorded bulk loads, most recent first
+func (s *SQLiteStore) ListBulkLoads(ctx context.Context) ([]*BulkLoadState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("list_bulk_loads", ErrStoreClosed)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, status, rows_loaded, COALESCE(last_id, ''), started_at, updated_at
+		FROM bulk_loads ORDER BY started_at DESC, id
+	`)
+	if err != nil {
+		return nil, wrapError("list_bulk_loads", fmt.Errorf("failed to list bulk loads: %w", err))
+	}
+	defer rows.Close()
+
+	var states []*BulkLoadState
+	for rows.Next() {
+		state := &BulkLoadState{}
+		if err := rows.Scan(&state.LoadID, &state.Status, &state.RowsLoaded, &state.LastID, &state.StartedAt, &state.UpdatedAt); err != nil {
+			return nil, wrapError("list_bulk_loads", fmt.Errorf("failed to scan bulk load: %w", err))
+		}
+		states = append(states, state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("list_bulk_loads", fmt.Errorf("failed to iterate bulk loads: %w", err))
+	}
+
+	return states, nil
+}
+
+// bulkInsertEmbeddingSQL is upsertEmbeddingSQL for bulk loads, flagging
+// rows to be left out of chunks_fts until Finish
+const bulkInsertEmbeddingSQL = `
+	INSERT OR REPLACE INTO embeddings (id, collection_id, vector, content, doc_id, metadata, acl, model_id, revision, fts_deferred, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE((SELECT revision FROM embeddings WHERE id = ?), 0) + 1, 1, CURRENT_TIMESTAMP)
+	RETURNING revision
+`
+
+// bulkInsert writes one batch and advances the load checkpoint in the same transaction
+func (s *SQLiteStore) bulkInsert(ctx context.Context, loadID string, embs []*Embedding) error {
+	s.mu.RLock()
+	dim := s.config.VectorDim
+	s.mu.RUnlock()
+	if dim == 0 && len(embs) > 0 && len(embs[0].Vector) > 0 {
+		dim = s.detectVectorDim(ctx, len(embs[0].Vector))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return wrapError("bulk_load", ErrStoreClosed)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError("bulk_load", fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer func() {
+		if rollErr := tx.Rollback(); rollErr != nil && rollErr != sql.ErrTxDone {
+			s.logger.Warn("failed to rollback transaction during bulk load", "error", rollErr)
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, bulkInsertEmbeddingSQL)
+	if err != nil {
+		return wrapError("bulk_load", fmt.Errorf("failed to prepare statement: %w", err))
+	}
+	defer stmt.Close()
+
+	collectionIDs := make(map[string]int)
+	schemas := make(map[int]*MetadataSchema)
+
+	for _, emb := range embs {
+		// Rows skip dimension adaptation; a wrong length would break the index build
+		if len(emb.Vector) == 0 || len(emb.Vector) != dim {
+			return wrapError("bulk_load", fmt.Errorf("%w: embedding '%s' has %d dimensions, expected %d", ErrInvalidDimension, emb.ID, len(emb.Vector), dim))
+		}
+
+		collectionID := emb.CollectionID
+		if collectionID == 0 {
+			collectionID = 1 // Default collection
+			if emb.Collection != "" {
+				id, ok := collectionIDs[emb.Collection]
+				if !ok {
+					name, err := s.lookupAliasTarget(ctx, emb.Collection)
+					if err != nil {
+						return wrapError("bulk_load", err)
+					}
+					if id, err = s.collectionIDByName(ctx, name); err != nil {
+						return wrapError("bulk_load", err)
+					}
+					collectionIDs[emb.Collection] = id
+				}
+				collectionID = id
+			}
+		}
+
+		metadata, err := s.applyMetadataSchema(ctx, collectionID, emb.Metadata, schemas)
+		if err != nil {
+			return wrapError("bulk_load", fmt.Errorf("embedding '%s': %w", emb.ID, err))
+		}
+
+		vectorBytes, err := encoding.EncodeVector(emb.Vector)
+		if err != nil {
+			return wrapError("bulk_load", fmt.Errorf("failed to encode vector for '%s': %w", emb.ID, err))
+		}
+
+		metadataJSON, err := encoding.EncodeMetadata(metadata)
+		if err != nil {
+			return wrapError("bulk_load", fmt.Errorf("failed to encode metadata for '%s': %w", emb.ID, err))
+		}
+
+		var aclJSON []byte
+		if len(emb.ACL) > 0 {
+			aclJSON, err = json.Marshal(emb.ACL)
+			if err != nil {
+				return wrapError("bulk_load", fmt.Errorf("failed to marshal ACL for '%s': %w", emb.ID, err))
+			}
+		}
+
+		var docID sql.NullString
+		if emb.DocID != "" {
+			docID.String = emb.DocID
+			docID.Valid = true
+		}
+
+		err = stmt.QueryRowContext(ctx, emb.ID, collectionID, vectorBytes, emb.Content, docID, metadataJSON, aclJSON, nullString(emb.ModelID), emb.ID).Scan(&emb.Revision)
+		if err != nil {
+			return wrapError("bulk_load", fmt.Errorf("failed to insert embedding '%s': %w", emb.ID, err))
+		}
+		if err := s.writeBinaryCode(ctx, tx, emb); err != nil {
+			return wrapError("bulk_load", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE bulk_loads SET rows_loaded = rows_loaded + ?, last_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, len(embs), embs[len(embs)-1].ID, loadID)
+	if err != nil {
+		return wrapError("bulk_load", fmt.Errorf("failed to update checkpoint: %w", err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return wrapError("bulk_load", fmt.Errorf("failed to commit transaction: %w", err))
+	}
+	for _, emb := range embs {
+		s.trackIndexWrite(emb.ID)
+	}
+
+	return nil
+}
+
+// indexDeferredFullText clears fts_deferred on every bulk-loaded row, which
+// makes embeddings_au add it to chunks_fts, in one transaction
+func (s *SQLiteStore) indexDeferredFullText(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "UPDATE embeddings SET fts_deferred = 0 WHERE fts_deferred = 1"); err != nil {
+		return fmt.Errorf("failed to index loaded rows for full-text search: %w", err)
+	}
+	return nil
+}
+
+// refillIVFIndex re-adds every stored vector to a trained IVF index
+func (s *SQLiteStore) refillIVFIndex(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, vector FROM embeddings")
+	if err != nil {
+		return fmt.Errorf("failed to query vectors: %w", err)
+	}
+	defer rows.Close()
+
+	s.ivfIndex.Clear()
+	for rows.Next() {
+		var id string
+		var vectorBytes []byte
+		if err := rows.Scan(&id, &vectorBytes); err != nil {
+			return fmt.Errorf("failed to scan vector: %w", err)
+		}
+		vec, err := encoding.DecodeVector(vectorBytes)
+		if err != nil {
+			s.logger.Warn("failed to decode vector during IVF refill", "id", id, "error", err)
+			continue
+		}
+		if err := s.ivfIndex.Add(id, vec); err != nil {
+			s.logger.Warn("failed to add vector to IVF index", "id", id, "error", err)
+		}
+	}
+
+	return rows.Err()
+}
+
+// getBulkLoad loads a bulk load checkpoint, returning nil if it does not exist
+func (s *SQLiteStore) getBulkLoad(ctx context.Context, loadID string) (*BulkLoadState, error) {
+	state := &BulkLoadState{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, status, rows_loaded, COALESCE(last_id, ''), started_at, updated_at
+		FROM bulk_loads WHERE id = ?
+	`, loadID).Scan(&state.LoadID, &state.Status, &state.RowsLoaded, &state.LastID, &state.StartedAt, &state.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bulk load: %w", err)
+	}
+	return state, nil
+}
+
+// checkInterruptedBulkLoads warns about loads that never reached Finish.
+// Their rows are stored but missing from chunks_fts and the vector index
+// until the load is resumed and finished.
+func (s *SQLiteStore) checkInterruptedBulkLoads(ctx context.Context) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, rows_loaded FROM bulk_loads WHERE status != ?", BulkLoadPhaseDone)
+	if err != nil {
+		s.logger.Warn("failed to check for interrupted bulk loads", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var loaded int64
+		if err := rows.Scan(&id, &loaded); err == nil {
+			s.logger.Warn("bulk load was interrupted; resume it with the same load ID to finish indexing", "load_id", id, "rows_loaded", loaded)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBulkLoader(t *testing.T) {
+	dbPath := "test_bulk_loader.db"
+	defer os.Remove(dbPath)
+
+	config := DefaultConfig()
+	config.Path = dbPath
+	config.VectorDim = 3
+	config.HNSW.Enabled = true
+
+	store, err := NewWithConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := store.Init(ctx); err != nil {
+		t.Fatalf("Failed to initialize store: %v", err)
+	}
+
+	makeRow := func(i int) *Embedding {
+		return &Embedding{
+			ID:      fmt.Sprintf("doc-%03d", i),
+			Vector:  []float32{float32(i%7) + 1, float32(i%5) + 1, float32(i%3) + 1},
+			Content: fmt.Sprintf("bulk row number%d", i),
+		}
+	}
+
+	t.Run("InterruptedLoad", func(t *testing.T) {
+		loader, err := store.NewBulkLoader(ctx, BulkLoadOptions{LoadID: "initial", BatchSize: 50})
+		if err != nil {
+			t.Fatalf("Failed to start bulk load: %v", err)
+		}
+		for i := 0; i < 120; i++ {
+			if err := loader.Add(ctx, makeRow(i)); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+		}
+		if loader.RowsLoaded() != 100 || loader.LastID() != "doc-099" {
+			t.Fatalf("Expected 100 committed rows ending at doc-099, got %d/%s", loader.RowsLoaded(), loader.LastID())
+		}
+
+		// Simulate a crash: the 20 pending rows are lost and Finish never runs
+		if err := store.Close(); err != nil {
+			t.Fatalf("Failed to close store: %v", err)
+		}
+	})
+
+	store, err = NewWithConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store.Close()
+	if err := store.Init(ctx); err != nil {
+		t.Fatalf("Failed to reinitialize store: %v", err)
+	}
+
+	t.Run("ResumeAndFinish", func(t *testing.T) {
+		states, err := store.ListBulkLoads(ctx)
+		if err != nil {
+			t.Fatalf("Failed to list bulk loads: %v", err)
+		}
+		if len(states) != 1 || states[0].Status != BulkLoadPhaseLoading || states[0].RowsLoaded != 100 {
+			t.Fatalf("Expected one interrupted load at 100 rows, got %+v", states)
+		}
+
+		var phases []BulkLoadPhase
+		loader, err := store.NewBulkLoader(ctx, BulkLoadOptions{
+			LoadID:    "initial",
+			BatchSize: 50,
+			OnProgress: func(p BulkLoadProgress) {
+				if len(phases) == 0 || phases[len(phases)-1] != p.Phase {
+					phases = append(phases, p.Phase)
+				}
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to resume bulk load: %v", err)
+		}
+
+		for i := int(loader.RowsLoaded()); i < 200; i++ {
+			if err := loader.Add(ctx, makeRow(i)); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+		}
+		if err := loader.Finish(ctx); err != nil {
+			t.Fatalf("Finish failed: %v", err)
+		}
+
+		expected := []BulkLoadPhase{BulkLoadPhaseLoading, BulkLoadPhaseFullText, BulkLoadPhaseIndex, BulkLoadPhaseSnapshot, BulkLoadPhaseDone}
+		if fmt.Sprint(phases) != fmt.Sprint(expected) {
+			t.Errorf("Expected phases %v, got %v", expected, phases)
+		}
+
+		stats, err := store.Stats(ctx)
+		if err != nil {
+			t.Fatalf("Stats failed: %v", err)
+		}
+		if stats.Count != 200 {
+			t.Errorf("Expected 200 embeddings, got %d", stats.Count)
+		}
+		if store.hnswIndex.Size() != 200 {
+			t.Errorf("Expected 200 vectors in HNSW index, got %d", store.hnswIndex.Size())
+		}
+
+		var ftsCount int
+		if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM chunks_fts WHERE chunks_fts MATCH 'number150'").Scan(&ftsCount); err != nil {
+			t.Fatalf("FTS query failed: %v", err)
+		}
+		if ftsCount != 1 {
+			t.Errorf("Expected rebuilt FTS to find number150, got %d", ftsCount)
+		}
+
+		var snapshots int
+		if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM index_snapshots WHERE type = 'HNSW'").Scan(&snapshots); err != nil || snapshots != 1 {
+			t.Errorf("Expected HNSW snapshot after bulk load, got %d (%v)", snapshots, err)
+		}
+
+		if _, err := store.NewBulkLoader(ctx, BulkLoadOptions{LoadID: "initial"}); err == nil {
+			t.Error("Expected error when reopening a finished load")
+		}
+	})
+
+	t.Run("TriggersRestored", func(t *testing.T) {
+		if err := store.Upsert(ctx, &Embedding{ID: "after", Vector: []float32{1, 1, 1}, Content: "incremental afterload"}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		var ftsCount int
+		if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM chunks_fts WHERE chunks_fts MATCH 'afterload'").Scan(&ftsCount); err != nil {
+			t.Fatalf("FTS query failed: %v", err)
+		}
+		if ftsCount != 1 {
+			t.Errorf("Expected trigger to index new row, got %d", ftsCount)
+		}
+	})
+
+	t.Run("WritesDuringLoad", func(t *testing.T) {
+		ftsCount := func(term string) int {
+			t.Helper()
+			var n int
+			if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM chunks_fts WHERE chunks_fts MATCH ?", term).Scan(&n); err != nil {
+				t.Fatalf("FTS query failed: %v", err)
+			}
+			return n
+		}
+
+		loader, err := store.NewBulkLoader(ctx, BulkLoadOptions{LoadID: "second"})
+		if err != nil {
+			t.Fatalf("Failed to start bulk load: %v", err)
+		}
+		for _, id := range []string{"loaded", "dropped"} {
+			if err := loader.Add(ctx, &Embedding{ID: id, Vector: []float32{1, 2, 3}, Content: "deferred " + id}); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+		}
+		if err := loader.Flush(ctx); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+
+		// Writes outside the load are indexed straight away
+		if err := store.Upsert(ctx, &Embedding{ID: "live", Vector: []float32{3, 2, 1}, Content: "duringload"}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		if err := store.Delete(ctx, "dropped"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if n := ftsCount("duringload"); n != 1 {
+			t.Errorf("Expected the write during the load indexed, got %d", n)
+		}
+		if n := ftsCount("deferred"); n != 0 {
+			t.Errorf("Expected loaded rows deferred until Finish, got %d", n)
+		}
+
+		if err := loader.Finish(ctx); err != nil {
+			t.Fatalf("Finish failed: %v", err)
+		}
+		if n := ftsCount("deferred"); n != 1 {
+			t.Errorf("Expected the remaining loaded row indexed by Finish, got %d", n)
+		}
+		if err := store.Delete(ctx, "loaded"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if n := ftsCount("deferred"); n != 0 {
+			t.Errorf("Expected the deleted row removed from FTS, got %d", n)
+		}
+		if _, err := store.db.ExecContext(ctx, "INSERT INTO chunks_fts(chunks_fts) VALUES('integrity-check')"); err != nil {
+			t.Errorf("FTS integrity check failed: %v", err)
+		}
+	})
+
+	t.Run("WrongDimension", func(t *testing.T) {
+		loader, err := store.NewBulkLoader(ctx, BulkLoadOptions{LoadID: "mismatch"})
+		if err != nil {
+			t.Fatalf("Failed to start bulk load: %v", err)
+		}
+		if err := loader.Add(ctx, &Embedding{ID: "short", Vector: []float32{1, 2}}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		err = loader.Flush(ctx)
+		var storeErr *StoreError
+		if !errors.Is(err, ErrInvalidDimension) || !errors.As(err, &storeErr) {
+			t.Fatalf("Expected a StoreError for ErrInvalidDimension, got %v", err)
+		}
+		if _, err := store.GetByID(ctx, "short"); err == nil {
+			t.Error("Expected the mismatched row not to be stored")
+		}
+	})
+
+	t.Run("DetectDimension", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Path = filepath.Join(t.TempDir(), "detect.db")
+		config.VectorDim = 0
+		config.HNSW.Enabled = true
+		detect, err := NewWithConfig(config)
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		defer detect.Close()
+		if err := detect.Init(ctx); err != nil {
+			t.Fatalf("Failed to initialize store: %v", err)
+		}
+
+		loader, err := detect.NewBulkLoader(ctx, BulkLoadOptions{LoadID: "detect"})
+		if err != nil {
+			t.Fatalf("Failed to start bulk load: %v", err)
+		}
+		if err := loader.Add(ctx, &Embedding{ID: "first", Vector: []float32{1, 2, 3, 4}}, &Embedding{ID: "second", Vector: []float32{1, 2, 3}}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if err := loader.Flush(ctx); !errors.Is(err, ErrInvalidDimension) {
+			t.Fatalf("Expected ErrInvalidDimension, got %v", err)
+		}
+		if detect.config.VectorDim != 4 {
+			t.Errorf("Expected the dimension taken from the first row, got %d", detect.config.VectorDim)
+		}
+	})
+}
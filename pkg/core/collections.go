@@ -15,10 +15,21 @@ type Collection struct {
 	Dimensions  int                    `json:"dimensions"`
 	Description string                 `json:"description,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Schema      *MetadataSchema        `json:"schema,omitempty"`
+	PrefixDims  int                    `json:"prefix_dims,omitempty"` // Leading dimensions indexed for search, 0 = full vectors
+	ModelID     string                 `json:"model_id,omitempty"`    // Embedding model the collection's vectors come from, set by re-embedding
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
 }
 
+// CollectionOption configures optional settings when creating a collection
+type CollectionOption func(*collectionOptions)
+
+type collectionOptions struct {
+	schema     *MetadataSchema
+	prefixDims int
+}
+
 // CollectionStats represents statistics for a collection
 type CollectionStats struct {
 	Name            string    `json:"name"`
@@ -30,7 +41,7 @@ type CollectionStats struct {
 }
 
 // CreateCollection creates a new collection
-func (s *SQLiteStore) CreateCollection(ctx context.Context, name string, dimensions int) (*Collection, error) {
+func (s *SQLiteStore) CreateCollection(ctx context.Context, name string, dimensions int, opts ...CollectionOption) (*Collection, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -48,25 +59,75 @@ func (s *SQLiteStore) CreateCollection(ctx context.Context, name string, dimensi
 		return nil, wrapError("create_collection", fmt.Errorf("collection '%s' already exists", name))
 	}
 
+	// Collections and aliases share one namespace
+	err = s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM collection_aliases WHERE alias = ?)", name).Scan(&exists)
+	if err != nil {
+		return nil, wrapError("create_collection", fmt.Errorf("failed to check alias existence: %w", err))
+	}
+	if exists {
+		return nil, wrapError("create_collection", fmt.Errorf("an alias named '%s' already exists", name))
+	}
+
 	// Allow 0 dimensions for auto-detection
 	if dimensions < 0 {
 		return nil, wrapError("create_collection", fmt.Errorf("dimensions must be non-negative"))
 	}
 
+	var options collectionOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.prefixDims < 0 {
+		return nil, wrapError("create_collection", fmt.Errorf("prefix dimensions must be positive"))
+	}
+	if dimensions > 0 && options.prefixDims >= dimensions {
+		return nil, wrapError("create_collection", fmt.Errorf("prefix dimensions %d must be smaller than the collection dimensions %d", options.prefixDims, dimensions))
+	}
+
+	var schema *MetadataSchema
+	if options.schema != nil {
+		schema = cloneMetadataSchema(options.schema)
+		if err := schema.Validate(); err != nil {
+			return nil, wrapError("create_collection", fmt.Errorf("invalid metadata schema: %w", err))
+		}
+		schema.Version = 1
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, wrapError("create_collection", fmt.Errorf("failed to start transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			s.logger.Warn("failed to rollback collection creation", "error", err)
+		}
+	}()
+
 	// Insert new collection
-	result, err := s.db.ExecContext(ctx, `
-		INSERT INTO collections (name, dimensions, created_at, updated_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-	`, name, dimensions)
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO collections (name, dimensions, prefix_dims, created_at, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	`, name, dimensions, options.prefixDims)
 	if err != nil {
 		return nil, wrapError("create_collection", fmt.Errorf("failed to create collection: %w", err))
 	}
 
-	_, err = result.LastInsertId()
+	collectionID, err := result.LastInsertId()
 	if err != nil {
 		return nil, wrapError("create_collection", fmt.Errorf("failed to get collection ID: %w", err))
 	}
 
+	if schema != nil {
+		if err := saveMetadataSchema(ctx, tx, int(collectionID), schema); err != nil {
+			return nil, wrapError("create_collection", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapError("create_collection", fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
 	// Get the created collection directly without lock conflict
 	collection := &Collection{}
 	var metadataJSON sql.NullString
@@ -101,10 +162,17 @@ func (s *SQLiteStore) CreateCollection(ctx context.Context, name string, dimensi
 		}
 	}
 
+	collection.Schema = schema
+	collection.PrefixDims = options.prefixDims
+
+	if collection.PrefixDims > 0 {
+		s.addPrefixIndex(collection.ID, collection.Name, collection.PrefixDims)
+	}
+
 	return collection, nil
 }
 
-// GetCollection retrieves a collection by name
+// GetCollection retrieves a collection by name or alias
 func (s *SQLiteStore) GetCollection(ctx context.Context, name string) (*Collection, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -113,21 +181,35 @@ func (s *SQLiteStore) GetCollection(ctx context.Context, name string) (*Collecti
 		return nil, wrapError("get_collection", ErrStoreClosed)
 	}
 
+	resolved, err := s.lookupAliasTarget(ctx, name)
+	if err != nil {
+		return nil, wrapError("get_collection", err)
+	}
+
 	collection := &Collection{}
 	var metadataJSON sql.NullString
 	var description sql.NullString
 
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, dimensions, description, metadata, created_at, updated_at
-		FROM collections WHERE name = ?
-	`, name).Scan(
+	var schemaJSON sql.NullString
+	var schemaVersion sql.NullInt64
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT c.id, c.name, c.dimensions, c.prefix_dims, COALESCE(c.model_id, ''), c.description, c.metadata, c.created_at, c.updated_at, cs.schema, cs.version
+		FROM collections c
+		LEFT JOIN collection_schemas cs ON cs.collection_id = c.id
+		WHERE c.name = ?
+	`, resolved).Scan(
 		&collection.ID,
 		&collection.Name,
 		&collection.Dimensions,
+		&collection.PrefixDims,
+		&collection.ModelID,
 		&description,
 		&metadataJSON,
 		&collection.CreatedAt,
 		&collection.UpdatedAt,
+		&schemaJSON,
+		&schemaVersion,
 	)
 
 	if description.Valid {
@@ -149,6 +231,13 @@ func (s *SQLiteStore) GetCollection(ctx context.Context, name string) (*Collecti
 		}
 	}
 
+	if schemaJSON.Valid {
+		collection.Schema, err = decodeMetadataSchema(schemaJSON.String, int(schemaVersion.Int64))
+		if err != nil {
+			return nil, wrapError("get_collection", err)
+		}
+	}
+
 	return collection, nil
 }
 
@@ -162,8 +251,10 @@ func (s *SQLiteStore) ListCollections(ctx context.Context) ([]*Collection, error
 	}
 
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, name, dimensions, description, metadata, created_at, updated_at
-		FROM collections ORDER BY created_at DESC
+		SELECT c.id, c.name, c.dimensions, c.prefix_dims, COALESCE(c.model_id, ''), c.description, c.metadata, c.created_at, c.updated_at, cs.schema, cs.version
+		FROM collections c
+		LEFT JOIN collection_schemas cs ON cs.collection_id = c.id
+		ORDER BY c.created_at DESC
 	`)
 	if err != nil {
 		return nil, wrapError("list_collections", fmt.Errorf("failed to list collections: %w", err))
@@ -180,15 +271,21 @@ func (s *SQLiteStore) ListCollections(ctx context.Context) ([]*Collection, error
 		collection := &Collection{}
 		var metadataJSON sql.NullString
 		var description sql.NullString
+		var schemaJSON sql.NullString
+		var schemaVersion sql.NullInt64
 
 		err := rows.Scan(
 			&collection.ID,
 			&collection.Name,
 			&collection.Dimensions,
+			&collection.PrefixDims,
+			&collection.ModelID,
 			&description,
 			&metadataJSON,
 			&collection.CreatedAt,
 			&collection.UpdatedAt,
+			&schemaJSON,
+			&schemaVersion,
 		)
 		if err != nil {
 			return nil, wrapError("list_collections", fmt.Errorf("failed to scan collection: %w", err))
@@ -206,6 +303,13 @@ func (s *SQLiteStore) ListCollections(ctx context.Context) ([]*Collection, error
 			}
 		}
 
+		if schemaJSON.Valid {
+			collection.Schema, err = decodeMetadataSchema(schemaJSON.String, int(schemaVersion.Int64))
+			if err != nil {
+				return nil, wrapError("list_collections", err)
+			}
+		}
+
 		collections = append(collections, collection)
 	}
 
@@ -249,6 +353,24 @@ func (s *SQLiteStore) DeleteCollection(ctx context.Context, name string) error {
 		return wrapError("delete_collection", fmt.Errorf("failed to delete embeddings: %w", err))
 	}
 
+	// Drop the metadata schema
+	_, err = tx.ExecContext(ctx, "DELETE FROM collection_schemas WHERE collection_id = ?", collectionID)
+	if err != nil {
+		return wrapError("delete_collection", fmt.Errorf("failed to delete metadata schema: %w", err))
+	}
+
+	// Drop learned projections between embedding models
+	_, err = tx.ExecContext(ctx, "DELETE FROM dimension_projections WHERE collection_id = ?", collectionID)
+	if err != nil {
+		return wrapError("delete_collection", fmt.Errorf("failed to delete projections: %w", err))
+	}
+
+	// Drop aliases that point at the collection
+	_, err = tx.ExecContext(ctx, "DELETE FROM collection_aliases WHERE collection_id = ?", collectionID)
+	if err != nil {
+		return wrapError("delete_collection", fmt.Errorf("failed to delete aliases: %w", err))
+	}
+
 	// Delete the collection
 	_, err = tx.ExecContext(ctx, "DELETE FROM collections WHERE id = ?", collectionID)
 	if err != nil {
@@ -259,6 +381,8 @@ func (s *SQLiteStore) DeleteCollection(ctx context.Context, name string) error {
 	if err := tx.Commit(); err != nil {
 		return wrapError("delete_collection", fmt.Errorf("failed to commit transaction: %w", err))
 	}
+	delete(s.prefixIndexes, collectionID)
+	s.adapter.removeCollectionProjections(projectionCollectionKey(name))
 
 	return nil
 }
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WriteCondition expresses preconditions for optimistic concurrency control.
+// The zero value writes unconditionally (last writer wins).
+type WriteCondition struct {
+	// IfMatch writes only if the stored revision equals this value.
+	// Zero means no revision check.
+	IfMatch int64 `json:"if_match,omitempty"`
+	// IfNotExists writes only if no record with the ID exists yet.
+	IfNotExists bool `json:"if_not_exists,omitempty"`
+	// UpdateOnly writes only if a record with the ID already exists.
+	UpdateOnly bool `json:"update_only,omitempty"`
+}
+
+// IsZero reports whether the condition imposes no precondition
+func (c WriteCondition) IsZero() bool {
+	return c.IfMatch == 0 && !c.IfNotExists && !c.UpdateOnly
+}
+
+func (c WriteCondition) validate() error {
+	if c.IfMatch < 0 {
+		return fmt.Errorf("if_match revision must be positive, got %d", c.IfMatch)
+	}
+	if c.IfNotExists && (c.UpdateOnly || c.IfMatch != 0) {
+		return fmt.Errorf("if_not_exists cannot be combined with update_only or if_match")
+	}
+	return nil
+}
+
+// upsertEmbeddingSQL replaces an embedding unconditionally and bumps its revision.
+// The trailing parameter repeats the ID for the revision lookup.
+const upsertEmbeddingSQL = `
+	INSERT OR REPLACE INTO embeddings (id, collection_id, vector, content, doc_id, metadata, acl, model_id, revision, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE((SELECT revision FROM embeddings WHERE id = ?), 0) + 1, CURRENT_TIMESTAMP)
+	RETURNING revision
+`
+
+// embeddingWriteQuery builds a single statement that performs the write only
+// when the condition holds. The statement returns the new revision, or no row
+// when the precondition failed, so check and write are atomic.
+func (c WriteCondition) embeddingWriteQuery(id string, collectionID int, vector []byte, content string, docID sql.NullString, metadata string, acl []byte, modelID sql.NullString) (string, []interface{}) {
+	switch {
+	case c.IfNotExists:
+		return `
+			INSERT INTO embeddings (id, collection_id, vector, content, doc_id, metadata, acl, model_id, revision, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, CURRENT_TIMESTAMP)
+			ON CONFLICT(id) DO NOTHING
+			RETURNING revision
+		`, []interface{}{id, collectionID, vector, content, docID, metadata, acl, modelID}
+	case c.IfMatch != 0:
+		return `
+			UPDATE embeddings
+			SET collection_id = ?, vector = ?, content = ?, doc_id = ?, metadata = ?, acl = ?, model_id = ?,
+				binary_code = NULL, shadow_vector = NULL, shadow_model_id = NULL, revision = revision + 1
+			WHERE id = ? AND revision = ?
+			RETURNING revision
+		`, []interface{}{collectionID, vector, content, docID, metadata, acl, modelID, id, c.IfMatch}
+	case c.UpdateOnly:
+		return `
+			UPDATE embeddings
+			SET collection_id = ?, vector = ?, content = ?, doc_id = ?, metadata = ?, acl = ?, model_id = ?,
+				binary_code = NULL, shadow_vector = NULL, shadow_model_id = NULL, revision = revision + 1
+			WHERE id = ?
+			RETURNING revision
+		`, []interface{}{collectionID, vector, content, docID, metadata, acl, modelID, id}
+	default:
+		return upsertEmbeddingSQL, []interface{}{id, collectionID, vector, content, docID, metadata, acl, modelID, id}
+	}
+}
+
+// embeddingConflict describes why a conditional embedding write was rejected
+func embeddingConflict(ctx context.Context, q sqlExecutor, id string, cond WriteCondition) error {
+	var current int64
+	err := q.QueryRowContext(ctx, "SELECT revision FROM embeddings WHERE id = ?", id).Scan(&current)
+	return conflictError("embedding", id, cond, current, err == nil)
+}
+
+// conflictError builds an ErrConflict describing the failed precondition
+func conflictError(kind, id string, cond WriteCondition, current int64, exists bool) error {
+	switch {
+	case cond.IfNotExists:
+		return fmt.Errorf("%w: %s '%s' already exists at revision %d", ErrConflict, kind, id, current)
+	case !exists:
+		return fmt.Errorf("%w: %s '%s' does not exist", ErrConflict, kind, id)
+	default:
+		return fmt.Errorf("%w: %s '%s' is at revision %d, expected %d", ErrConflict, kind, id, current, cond.IfMatch)
+	}
+}
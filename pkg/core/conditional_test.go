@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestConditionalUpsert(t *testing.T) {
+	dbPath := "test_conditional.db"
+	defer os.Remove(dbPath)
+
+	store, err := New(dbPath, 3)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.Init(ctx); err != nil {
+		t.Fatalf("Failed to initialize store: %v", err)
+	}
+
+	newEmb := func(content string) *Embedding {
+		return &Embedding{ID: "e1", Vector: []float32{1, 0, 0}, Content: content}
+	}
+
+	t.Run("UpdateOnlyMissing", func(t *testing.T) {
+		err := store.UpsertWithCondition(ctx, newEmb("v0"), WriteCondition{UpdateOnly: true})
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("Expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("IfNotExists", func(t *testing.T) {
+		emb := newEmb("v1")
+		if err := store.UpsertWithCondition(ctx, emb, WriteCondition{IfNotExists: true}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if emb.Revision != 1 {
+			t.Errorf("Expected revision 1, got %d", emb.Revision)
+		}
+		if err := store.UpsertWithCondition(ctx, newEmb("dup"), WriteCondition{IfNotExists: true}); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict on second create, got %v", err)
+		}
+	})
+
+	t.Run("IfMatch", func(t *testing.T) {
+		emb := newEmb("v2")
+		if err := store.UpsertWithCondition(ctx, emb, WriteCondition{IfMatch: 1}); err != nil {
+			t.Fatalf("Matching update failed: %v", err)
+		}
+		if emb.Revision != 2 {
+			t.Errorf("Expected revision 2, got %d", emb.Revision)
+		}
+
+		err := store.UpsertWithCondition(ctx, newEmb("lost update"), WriteCondition{IfMatch: 1})
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("Expected ErrConflict for stale revision, got %v", err)
+		}
+
+		stored, err := store.GetByID(ctx, "e1")
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if stored.Content != "v2" || stored.Revision != 2 {
+			t.Errorf("Expected v2 at revision 2, got %q at %d", stored.Content, stored.Revision)
+		}
+	})
+
+	t.Run("UnconditionalBumpsRevision", func(t *testing.T) {
+		emb := newEmb("v3")
+		if err := store.Upsert(ctx, emb); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		if emb.Revision != 3 {
+			t.Errorf("Expected revision 3, got %d", emb.Revision)
+		}
+	})
+
+	t.Run("InvalidCondition", func(t *testing.T) {
+		if err := store.UpsertWithCondition(ctx, newEmb("x"), WriteCondition{IfNotExists: true, IfMatch: 3}); err == nil {
+			t.Error("Expected error for contradictory condition")
+		}
+	})
+
+	t.Run("Documents", func(t *testing.T) {
+		doc := &Document{ID: "doc1", Title: "first"}
+		if err := store.UpsertDocumentWithCondition(ctx, doc, WriteCondition{IfNotExists: true}); err != nil {
+			t.Fatalf("Create document failed: %v", err)
+		}
+		if err := store.CreateDocument(ctx, &Document{ID: "doc1"}); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict for duplicate document, got %v", err)
+		}
+
+		doc.Title = "second"
+		if err := store.UpsertDocumentWithCondition(ctx, doc, WriteCondition{IfMatch: 1}); err != nil {
+			t.Fatalf("Conditional document update failed: %v", err)
+		}
+		if doc.Version != 2 {
+			t.Errorf("Expected document version 2, got %d", doc.Version)
+		}
+		if err := store.UpsertDocumentWithCondition(ctx, &Document{ID: "doc1", Title: "stale"}, WriteCondition{IfMatch: 1}); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict for stale document version, got %v", err)
+		}
+	})
+}
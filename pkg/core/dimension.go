@@ -5,6 +5,7 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"sync"
 )
 
 // DimensionAnalysis contains information about vector dimensions in the store
@@ -18,7 +19,15 @@ type DimensionAnalysis struct {
 
 // DimensionAdapter handles vector dimension adaptation
 type DimensionAdapter struct {
-	policy AdaptPolicy
+	mu          sync.RWMutex
+	policy      AdaptPolicy
+	projections map[projectionKey]*Projection // Learned projections for LearnedProjection
+}
+
+// projectionKey identifies a learned projection by collection and source dimension
+type projectionKey struct {
+	collection string
+	sourceDim  int
 }
 
 // NewDimensionAdapter creates a new dimension adapter with the given policy
@@ -26,8 +35,57 @@ func NewDimensionAdapter(policy AdaptPolicy) *DimensionAdapter {
 	return &DimensionAdapter{policy: policy}
 }
 
-// AdaptVector adapts a vector from source dimension to target dimension
+// SetPolicy changes the adaptation policy
+func (da *DimensionAdapter) SetPolicy(policy AdaptPolicy) {
+	da.mu.Lock()
+	defer da.mu.Unlock()
+	da.policy = policy
+}
+
+// SetProjection registers a learned projection for vectors of p.SourceDim
+// dimensions written to or searched in a collection. A nil p removes it.
+func (da *DimensionAdapter) SetProjection(collection string, sourceDim int, p *Projection) {
+	da.mu.Lock()
+	defer da.mu.Unlock()
+	key := projectionKey{collection: collection, sourceDim: sourceDim}
+	if p == nil {
+		delete(da.projections, key)
+		return
+	}
+	if da.projections == nil {
+		da.projections = make(map[projectionKey]*Projection)
+	}
+	da.projections[key] = p
+}
+
+// removeCollectionProjections drops every projection of a deleted collection
+func (da *DimensionAdapter) removeCollectionProjections(collection string) {
+	da.mu.Lock()
+	defer da.mu.Unlock()
+	for key := range da.projections {
+		if key.collection == collection {
+			delete(da.projections, key)
+		}
+	}
+}
+
+// usesProjections reports whether the policy is LearnedProjection
+func (da *DimensionAdapter) usesProjections() bool {
+	da.mu.RLock()
+	defer da.mu.RUnlock()
+	return da.policy == LearnedProjection
+}
+
+// AdaptVector adapts a vector from source dimension to target dimension.
+// LearnedProjection uses the projection of the default collection.
 func (da *DimensionAdapter) AdaptVector(vector []float32, sourceDim, targetDim int) ([]float32, error) {
+	return da.AdaptCollectionVector("", vector, sourceDim, targetDim)
+}
+
+// AdaptCollectionVector adapts a vector written to or searched in a
+// collection, where LearnedProjection looks up that collection's projection.
+// An empty collection means the default collection.
+func (da *DimensionAdapter) AdaptCollectionVector(collection string, vector []float32, sourceDim, targetDim int) ([]float32, error) {
 	if len(vector) != sourceDim {
 		return nil, fmt.Errorf("vector length %d doesn't match source dimension %d", len(vector), sourceDim)
 	}
@@ -36,7 +94,12 @@ func (da *DimensionAdapter) AdaptVector(vector []float32, sourceDim, targetDim i
 		return vector, nil // No adaptation needed
 	}
 
-	switch da.policy {
+	da.mu.RLock()
+	policy := da.policy
+	projection := da.projections[projectionKey{collection: collection, sourceDim: sourceDim}]
+	da.mu.RUnlock()
+
+	switch policy {
 	case StrictMode:
 		return vector, fmt.Errorf("dimension mismatch: expected %d, got %d (StrictMode)", targetDim, sourceDim)
 	case SmartAdapt:
@@ -47,8 +110,13 @@ func (da *DimensionAdapter) AdaptVector(vector []float32, sourceDim, targetDim i
 		return da.padVector(vector, targetDim), nil
 	case WarnOnly:
 		return vector, fmt.Errorf("dimension mismatch: expected %d, got %d (WarnOnly policy)", targetDim, sourceDim)
+	case LearnedProjection:
+		if projection == nil || projection.TargetDim != targetDim {
+			return vector, fmt.Errorf("no learned projection from %d to %d dimensions for collection %q", sourceDim, targetDim, collection)
+		}
+		return projection.Apply(vector)
 	default:
-		return vector, fmt.Errorf("unknown adaptation policy: %v", da.policy)
+		return vector, fmt.Errorf("unknown adaptation policy: %v", policy)
 	}
 }
 
@@ -204,7 +272,11 @@ func calculateVectorStddev(vector []float32) float32 {
 
 // logDimensionEvent logs dimension-related events
 func (da *DimensionAdapter) logDimensionEvent(event string, sourceDim, targetDim int, vectorID string) {
-	switch da.policy {
+	da.mu.RLock()
+	policy := da.policy
+	da.mu.RUnlock()
+
+	switch policy {
 	case StrictMode:
 		if sourceDim != targetDim {
 			log.Printf("[DIMENSION] ERROR: Dimension mismatch %d ≠ %d for vector %s (StrictMode)", sourceDim, targetDim, vectorID)
@@ -227,6 +299,10 @@ func (da *DimensionAdapter) logDimensionEvent(event string, sourceDim, targetDim
 		}
 	case WarnOnly:
 		log.Printf("[DIMENSION] WARNING: Dimension mismatch %d ≠ %d for vector %s (no adaptation)", sourceDim, targetDim, vectorID)
+	case LearnedProjection:
+		if sourceDim != targetDim {
+			log.Printf("[DIMENSION] Learned projection: %d → %d for vector %s", sourceDim, targetDim, vectorID)
+		}
 	}
 }
 
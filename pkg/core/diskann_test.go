@@ -0,0 +1,202 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskANNIndex(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "diskann.db")
+
+	config := DefaultConfig()
+	config.Path = dbPath
+	config.VectorDim = 8
+	config.IndexType = IndexTypeDiskANN
+	config.AutoSave.Enabled = false
+
+	ctx := context.Background()
+	randomVec := newRandomVec(5, config.VectorDim)
+
+	store := openTestStore(t, config)
+	if store.diskANN != nil {
+		t.Fatal("DiskANN index should not be built for an empty store")
+	}
+	for i := 0; i < 300; i++ {
+		if err := store.Upsert(ctx, &Embedding{ID: fmt.Sprintf("vec_%d", i), Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+	expectTop(t, store, "vec_10")
+
+	// The first file is built in the background once the store has enough vectors
+	build := store.indexBuild.Load()
+	if build == nil {
+		t.Fatal("Expected a DiskANN build to start without autosave")
+	}
+	if err := build.Wait(); err != nil {
+		t.Fatalf("DiskANN build failed: %v", err)
+	}
+	if store.diskANN == nil {
+		t.Fatal("Expected the DiskANN index to be swapped in")
+	}
+	store.Close()
+
+	t.Run("Reopen", func(t *testing.T) {
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		if store.diskANN == nil {
+			t.Fatal("Expected DiskANN index to be opened")
+		}
+		if _, err := os.Stat(dbPath + ".diskann"); err != nil {
+			t.Errorf("Expected index file next to the database: %v", err)
+		}
+		if store.diskANN.Size() != 300 {
+			t.Errorf("Expected 300 vectors, got %d", store.diskANN.Size())
+		}
+		expectTop(t, store, "vec_42")
+
+		if err := store.Upsert(ctx, &Embedding{ID: "extra", Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		if err := store.Delete(ctx, "vec_7"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		expectTop(t, store, "extra")
+	})
+
+	t.Run("ChangesSurviveRestart", func(t *testing.T) {
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		if store.diskANN.Contains("vec_7") {
+			t.Error("Deleted vector should stay tombstoned")
+		}
+		if !store.diskANN.Contains("extra") {
+			t.Error("Vector added after the build should be reloaded")
+		}
+		if store.diskANN.Size() != 300 {
+			t.Errorf("Expected 300 vectors, got %d", store.diskANN.Size())
+		}
+		expectTop(t, store, "extra")
+	})
+
+	t.Run("ReplacedBeforeCrash", func(t *testing.T) {
+		store := openTestStore(t, config)
+		var saved []byte
+		if err := store.db.QueryRowContext(ctx, "SELECT data FROM index_snapshots WHERE type = 'DISKANN'").Scan(&saved); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Upsert(ctx, &Embedding{ID: "vec_20", Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		store.Close()
+
+		// A crash loses the tombstone saved by Close
+		raw, err := sql.Open("sqlite", dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := raw.ExecContext(ctx, "UPDATE index_snapshots SET data = ? WHERE type = 'DISKANN'", saved); err != nil {
+			t.Fatal(err)
+		}
+		raw.Close()
+
+		store = openTestStore(t, config)
+		defer store.Close()
+		expectTop(t, store, "vec_20")
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		buildID := store.diskANN.BuildID()
+		store.config.DiskANN.MergeRatio = 0.001
+		if err := store.Upsert(ctx, &Embedding{ID: "merged", Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		build := store.indexBuild.Load()
+		if build == nil {
+			t.Fatal("Expected the write to start a merge")
+		}
+		if err := build.Wait(); err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+		if store.diskANN.BuildID() == buildID || store.diskANN.PendingChanges() != 0 {
+			t.Error("Expected pending changes to be folded into a new build")
+		}
+		if status, _ := store.IndexBuildStatus(); status.Kind != IndexBuildDiskANN {
+			t.Errorf("Expected a DiskANN build, got %q", status.Kind)
+		}
+		expectTop(t, store, "extra")
+		expectTop(t, store, "merged")
+	})
+
+	t.Run("FailedMergeKeepsIndex", func(t *testing.T) {
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		buildID := store.diskANN.BuildID()
+		store.config.DiskANN.MergeRatio = 0.001
+		store.config.DiskANN.Path = filepath.Join(t.TempDir(), "missing", "index.diskann")
+		if err := store.Delete(ctx, "merged"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		build := store.indexBuild.Load()
+		if build == nil {
+			t.Fatal("Expected the delete to start a merge")
+		}
+		if err := build.Wait(); err == nil {
+			t.Fatal("Expected the merge to fail")
+		}
+		if store.diskANN == nil || store.diskANN.BuildID() != buildID {
+			t.Fatal("Expected the old index to stay in place after a failed merge")
+		}
+		if store.diskANN.Contains("merged") {
+			t.Error("Expected the delete to reach the old index")
+		}
+		expectTop(t, store, "vec_42")
+	})
+
+	t.Run("CorruptFile", func(t *testing.T) {
+		f, err := os.OpenFile(dbPath+".diskann", os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.WriteAt([]byte("junk"), 0); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		store := openTestStore(t, config)
+		defer store.Close()
+		if store.diskANN == nil || store.diskANN.Size() != 300 {
+			t.Fatal("Expected corrupt index file to be rebuilt")
+		}
+		expectTop(t, store, "vec_99")
+	})
+
+	t.Run("SelectiveFilter", func(t *testing.T) {
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		for i := 0; i < 5; i++ {
+			emb := &Embedding{ID: fmt.Sprintf("rare_%d", i), Vector: randomVec(), Metadata: map[string]string{"tag": "rare"}}
+			if err := store.Upsert(ctx, emb); err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+		}
+		results, err := store.Search(ctx, randomVec(), SearchOptions{TopK: 5, Filter: map[string]string{"tag": "rare"}})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 5 {
+			t.Errorf("Expected all 5 matching vectors, got %d", len(results))
+		}
+	})
+}
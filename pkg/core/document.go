@@ -14,7 +14,7 @@ type Document struct {
 	Title     string                 `json:"title"`
 	SourceURL string                 `json:"source_url,omitempty"`
 	Content   string                 `json:"content,omitempty"` // Full document content
-	Version   int                    `json:"version"`
+	Version   int                    `json:"version"` // Doubles as the revision for conditional writes
 	Author    string                 `json:"author,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	ACL       []string               `json:"acl,omitempty"` // Allowed user IDs or groups
@@ -31,24 +31,41 @@ func (s *SQLiteStore) CreateDocument(ctx context.Context, doc *Document) error {
 		return wrapError("create_document", ErrStoreClosed)
 	}
 
+	if err := createDocument(ctx, s.db, doc); err != nil {
+		return wrapError("create_document", err)
+	}
+
+	return nil
+}
+
+func createDocument(ctx context.Context, q sqlExecutor, doc *Document) error {
 	metadataJSON, err := json.Marshal(doc.Metadata)
 	if err != nil {
-		return wrapError("create_document", fmt.Errorf("failed to marshal metadata: %w", err))
+		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
 	aclJSON, err := json.Marshal(doc.ACL)
 	if err != nil {
-		return wrapError("create_document", fmt.Errorf("failed to marshal ACL: %w", err))
+		return fmt.Errorf("failed to marshal ACL: %w", err)
 	}
 
 	query := `
-		INSERT INTO documents (id, title, source_url, content, version, author, metadata, acl, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO documents (id, title, source_url, content, version, author, metadata, acl, created_at, updated_at, valid_from)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(id) DO NOTHING
 	`
 
-	_, err = s.db.ExecContext(ctx, query, doc.ID, doc.Title, doc.SourceURL, doc.Content, doc.Version, doc.Author, metadataJSON, aclJSON)
+	result, err := q.ExecContext(ctx, query, doc.ID, doc.Title, doc.SourceURL, doc.Content, doc.Version, doc.Author, metadataJSON, aclJSON)
 	if err != nil {
-		return wrapError("create_document", fmt.Errorf("failed to insert document: %w", err))
+		return fmt.Errorf("failed to insert document: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: document '%s' already exists", ErrConflict, doc.ID)
 	}
 
 	return nil
@@ -63,6 +80,15 @@ func (s *SQLiteStore) GetDocument(ctx context.Context, id string) (*Document, er
 		return nil, wrapError("get_document", ErrStoreClosed)
 	}
 
+	doc, err := getDocument(ctx, s.db, id)
+	if err != nil {
+		return nil, wrapError("get_document", err)
+	}
+
+	return doc, nil
+}
+
+func getDocument(ctx context.Context, q sqlExecutor, id string) (*Document, error) {
 	var doc Document
 	var metadataJSON, aclJSON []byte
 
@@ -71,16 +97,16 @@ func (s *SQLiteStore) GetDocument(ctx context.Context, id string) (*Document, er
 		FROM documents WHERE id = ?
 	`
 
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	err := q.QueryRowContext(ctx, query, id).Scan(
 		&doc.ID, &doc.Title, &doc.SourceURL, &doc.Content, &doc.Version, &doc.Author,
 		&metadataJSON, &aclJSON, &doc.CreatedAt, &doc.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, wrapError("get_document", ErrNotFound)
+		return nil, ErrNotFound
 	}
 	if err != nil {
-		return nil, wrapError("get_document", err)
+		return nil, err
 	}
 
 	if len(metadataJSON) > 0 {
@@ -102,31 +128,45 @@ func (s *SQLiteStore) DeleteDocument(ctx context.Context, id string) error {
 		return wrapError("delete_document", ErrStoreClosed)
 	}
 
-	// 1. Find all embedding IDs for this document to remove from HNSW index
-	// Note: SQLite FK CASCADE will handle the table rows, but we must manually update memory index
-	if s.hnswIndex != nil || s.ivfIndex != nil {
-		rows, err := s.db.QueryContext(ctx, "SELECT id FROM embeddings WHERE doc_id = ?", id)
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var embID string
-				if err := rows.Scan(&embID); err == nil {
-					if s.hnswIndex != nil {
-						_ = s.hnswIndex.Delete(embID)
-					}
-					if s.ivfIndex != nil {
-						_ = s.ivfIndex.Delete(embID)
-					}
-				}
-			}
-		}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError("delete_document", err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	// 2. Delete document (Cascade will delete embeddings from DB)
-	_, err := s.db.ExecContext(ctx, "DELETE FROM documents WHERE id = ?", id)
+	// 1. Find the document's embeddings. SQLite FK CASCADE deletes the rows;
+	// the in-memory indexes are updated once the delete has committed.
+	var embIDs []string
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM embeddings WHERE doc_id = ?", id)
 	if err != nil {
+		return wrapError("delete_document", fmt.Errorf("failed to find document chunks: %w", err))
+	}
+	for rows.Next() {
+		var embID string
+		if err := rows.Scan(&embID); err != nil {
+			rows.Close()
+			return wrapError("delete_document", fmt.Errorf("failed to scan document chunk: %w", err))
+		}
+		embIDs = append(embIDs, embID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return wrapError("delete_document", fmt.Errorf("failed to find document chunks: %w", err))
+	}
+
+	// 2. Archive the document, then delete it (Cascade will delete embeddings from DB)
+	if err := archiveDocument(ctx, tx, id, ""); err != nil {
+		return wrapError("delete_document", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM documents WHERE id = ?", id); err != nil {
 		return wrapError("delete_document", fmt.Errorf("failed to delete document: %w", err))
 	}
+	if err := tx.Commit(); err != nil {
+		return wrapError("delete_document", err)
+	}
+	for _, embID := range embIDs {
+		s.unindexEmbedding(embID)
+	}
 
 	return nil
 }
@@ -140,35 +180,168 @@ func (s *SQLiteStore) UpdateDocument(ctx context.Context, doc *Document) error {
 		return wrapError("update_document", ErrStoreClosed)
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError("update_document", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := updateDocument(ctx, tx, doc); err != nil {
+		return wrapError("update_document", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return wrapError("update_document", err)
+	}
+
+	return nil
+}
+
+func updateDocument(ctx context.Context, q sqlExecutor, doc *Document) error {
 	metadataJSON, err := json.Marshal(doc.Metadata)
 	if err != nil {
-		return wrapError("update_document", fmt.Errorf("failed to marshal metadata: %w", err))
+		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
 	aclJSON, err := json.Marshal(doc.ACL)
 	if err != nil {
-		return wrapError("update_document", fmt.Errorf("failed to marshal ACL: %w", err))
+		return fmt.Errorf("failed to marshal ACL: %w", err)
+	}
+
+	// A new version number archives the stored version; the same one edits it in place
+	if err := archiveDocument(ctx, q, doc.ID, "AND version != ?", doc.Version); err != nil {
+		return err
 	}
 
 	query := `
 		UPDATE documents
-		SET title = ?, source_url = ?, content = ?, version = ?, author = ?, metadata = ?, acl = ?, updated_at = CURRENT_TIMESTAMP
+		SET title = ?, source_url = ?, content = ?, version = ?, author = ?, metadata = ?, acl = ?, updated_at = CURRENT_TIMESTAMP,
+			valid_from = CASE WHEN version = ? THEN valid_from ELSE CURRENT_TIMESTAMP END
 		WHERE id = ?
 	`
 
-	result, err := s.db.ExecContext(ctx, query, doc.Title, doc.SourceURL, doc.Content, doc.Version, doc.Author, metadataJSON, aclJSON, doc.ID)
+	result, err := q.ExecContext(ctx, query, doc.Title, doc.SourceURL, doc.Content, doc.Version, doc.Author, metadataJSON, aclJSON, doc.Version, doc.ID)
 	if err != nil {
-		return wrapError("update_document", fmt.Errorf("failed to update document: %w", err))
+		return fmt.Errorf("failed to update document: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return wrapError("update_document", err)
+		return err
 	}
 	if rowsAffected == 0 {
-		return wrapError("update_document", ErrNotFound)
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// UpsertDocumentWithCondition creates or updates a document only if cond holds.
+// Every successful write increments the version, which acts as the document's
+// revision for IfMatch; doc.Version is set to the stored version afterwards.
+func (s *SQLiteStore) UpsertDocumentWithCondition(ctx context.Context, doc *Document, cond WriteCondition) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return wrapError("upsert_document", ErrStoreClosed)
+	}
+
+	if err := cond.validate(); err != nil {
+		return wrapError("upsert_document", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError("upsert_document", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := upsertDocument(ctx, tx, doc, cond); err != nil {
+		return wrapError("upsert_document", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return wrapError("upsert_document", err)
+	}
+
+	return nil
+}
+
+func upsertDocument(ctx context.Context, q sqlExecutor, doc *Document, cond WriteCondition) error {
+	metadataJSON, err := json.Marshal(doc.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	aclJSON, err := json.Marshal(doc.ACL)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ACL: %w", err)
+	}
+
+	var query string
+	var args []interface{}
+	switch {
+	case cond.IfNotExists:
+		query = `
+			INSERT INTO documents (id, title, source_url, content, version, author, metadata, acl, created_at, updated_at, valid_from)
+			VALUES (?, ?, ?, ?, 1, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(id) DO NOTHING
+			RETURNING version
+		`
+		args = []interface{}{doc.ID, doc.Title, doc.SourceURL, doc.Content, doc.Author, metadataJSON, aclJSON}
+	case cond.IfMatch != 0 || cond.UpdateOnly:
+		filter, filterArgs := "", []interface{}{}
+		if cond.IfMatch != 0 {
+			filter, filterArgs = "AND version = ?", []interface{}{cond.IfMatch}
+		}
+		if err := archiveDocument(ctx, q, doc.ID, filter, filterArgs...); err != nil {
+			return err
+		}
+		query = `
+			UPDATE documents
+			SET title = ?, source_url = ?, content = ?, author = ?, metadata = ?, acl = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP,
+				valid_from = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`
+		args = []interface{}{doc.Title, doc.SourceURL, doc.Content, doc.Author, metadataJSON, aclJSON, doc.ID}
+		if cond.IfMatch != 0 {
+			query += " AND version = ?"
+			args = append(args, cond.IfMatch)
+		}
+		query += " RETURNING version"
+	default:
+		if err := archiveDocument(ctx, q, doc.ID, ""); err != nil {
+			return err
+		}
+		query = `
+			INSERT INTO documents (id, title, source_url, content, version, author, metadata, acl, created_at, updated_at, valid_from)
+			VALUES (?, ?, ?, ?, 1, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(id) DO UPDATE SET
+				title = excluded.title,
+				source_url = excluded.source_url,
+				content = excluded.content,
+				author = excluded.author,
+				metadata = excluded.metadata,
+				acl = excluded.acl,
+				version = documents.version + 1,
+				updated_at = CURRENT_TIMESTAMP,
+				valid_from = CURRENT_TIMESTAMP
+			RETURNING version
+		`
+		args = []interface{}{doc.ID, doc.Title, doc.SourceURL, doc.Content, doc.Author, metadataJSON, aclJSON}
+	}
+
+	var version int
+	err = q.QueryRowContext(ctx, query, args...).Scan(&version)
+	if err == sql.ErrNoRows {
+		var current int64
+		lookupErr := q.QueryRowContext(ctx, "SELECT version FROM documents WHERE id = ?", doc.ID).Scan(&current)
+		return conflictError("document", doc.ID, cond, current, lookupErr == nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
 	}
 
+	doc.Version = version
 	return nil
 }
 
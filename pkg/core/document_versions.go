@@ -0,0 +1,670 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+)
+
+// Document history: before a write replaces a document's version, or the
+// document is deleted, the stored row is copied to document_versions and
+// its chunk embeddings to document_version_chunks. A version is current
+// from its ValidFrom, set when the version is written, until it is
+// archived; writes that keep the version number edit that version in place
+// and move only its UpdatedAt. History outlives the document and is
+// only removed by PruneDocumentVersions.
+
+// DocumentVersion is a document as it was at one version
+type DocumentVersion struct {
+	Document
+	ChunkIDs   []string  `json:"chunk_ids,omitempty"` // Embeddings the document had at this version
+	Current    bool      `json:"current"`
+	ValidFrom  time.Time `json:"valid_from"`            // When the version became current
+	ArchivedAt time.Time `json:"archived_at,omitempty"` // When the version was replaced or deleted; zero if current
+}
+
+// DocumentDiff describes the changes from one document version to another
+type DocumentDiff struct {
+	DocumentID string         `json:"document_id"`
+	From       int            `json:"from"`
+	To         int            `json:"to"`
+	Fields     []FieldChange  `json:"fields,omitempty"`  // Title, source URL, author and "metadata.<key>" changes
+	Content    []DiffLine     `json:"content,omitempty"` // Line diff of the content; empty if unchanged
+	Chunks     ChunkSetChange `json:"chunks"`
+}
+
+// FieldChange is a changed document field. Non-string metadata values are
+// rendered as JSON.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// DiffLine is one line of a content diff
+type DiffLine struct {
+	Op   string `json:"op"` // "=" kept, "-" removed, "+" added
+	Text string `json:"text"`
+}
+
+// ChunkSetChange compares the chunk embeddings of two versions by ID
+type ChunkSetChange struct {
+	Added     []string `json:"added,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+	Changed   []string `json:"changed,omitempty"` // Same ID, different content
+	Unchanged []string `json:"unchanged,omitempty"`
+}
+
+// VersionRetention selects the archived versions PruneDocumentVersions
+// removes. The current version of a document is never removed.
+type VersionRetention struct {
+	KeepLast    int           // Archived versions kept per document, newest first (0: no limit)
+	MaxAge      time.Duration // Remove versions archived longer ago than this (0: no limit)
+	DropDeleted bool          // Remove all history of documents that no longer exist
+}
+
+// maxDiffCells bounds the line diff table; larger contents are diffed as a
+// whole removal and addition
+const maxDiffCells = 4_000_000
+
+// archiveDocument copies document id and its chunks into the history.
+// filter narrows which stored row is archived, e.g. "AND version != ?".
+// A version that is already archived keeps its first copy: history is never
+// overwritten.
+func archiveDocument(ctx context.Context, q sqlExecutor, id string, filter string, args ...interface{}) error {
+	result, err := q.ExecContext(ctx, `
+		INSERT OR IGNORE INTO document_versions
+			(doc_id, version, title, source_url, content, author, metadata, acl, created_at, updated_at, valid_from, archived_at)
+		SELECT id, version, title, source_url, content, author, metadata, acl, created_at, updated_at, valid_from, CURRENT_TIMESTAMP
+		FROM documents WHERE id = ? `+filter, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to archive document: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	if _, err := q.ExecContext(ctx, `
+		DELETE FROM document_version_chunks
+		WHERE doc_id = ? AND version = (SELECT version FROM documents WHERE id = ?)
+	`, id, id); err != nil {
+		return fmt.Errorf("failed to archive document chunks: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `
+		INSERT INTO document_version_chunks (doc_id, version, embedding_id, collection_id, content, vector, metadata)
+		SELECT e.doc_id, d.version, e.id, e.collection_id, e.content, e.vector, e.metadata
+		FROM embeddings e JOIN documents d ON d.id = e.doc_id
+		WHERE e.doc_id = ?
+	`, id); err != nil {
+		return fmt.Errorf("failed to archive document chunks: %w", err)
+	}
+	return nil
+}
+
+// ListDocumentVersions returns every stored version of a document, oldest
+// first, including the current one
+func (s *SQLiteStore) ListDocumentVersions(ctx context.Context, docID string) ([]*DocumentVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("list_document_versions", ErrStoreClosed)
+	}
+
+	versions, err := s.documentVersions(ctx, docID)
+	if err != nil {
+		return nil, wrapError("list_document_versions", err)
+	}
+	if len(versions) == 0 {
+		return nil, wrapError("list_document_versions", ErrNotFound)
+	}
+	return versions, nil
+}
+
+// GetDocumentVersion returns a document as it was at version
+func (s *SQLiteStore) GetDocumentVersion(ctx context.Context, docID string, version int) (*DocumentVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("get_document_version", ErrStoreClosed)
+	}
+
+	v, err := s.documentVersion(ctx, docID, version)
+	if err != nil {
+		return nil, wrapError("get_document_version", err)
+	}
+	return v, nil
+}
+
+// GetDocumentAsOf returns the version of a document that was current at t.
+// It returns ErrNotFound if the document didn't exist yet, or had been
+// deleted, at t.
+func (s *SQLiteStore) GetDocumentAsOf(ctx context.Context, docID string, t time.Time) (*DocumentVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("get_document_as_of", ErrStoreClosed)
+	}
+
+	versions, err := s.documentVersions(ctx, docID)
+	if err != nil {
+		return nil, wrapError("get_document_as_of", err)
+	}
+
+	var found *DocumentVersion
+	for _, v := range versions {
+		if !v.ValidFrom.After(t) && (found == nil || !v.ValidFrom.Before(found.ValidFrom)) {
+			found = v
+		}
+	}
+	if found == nil {
+		return nil, wrapError("get_document_as_of", ErrNotFound)
+	}
+	last := versions[len(versions)-1]
+	if found == last && !found.Current && !t.Before(found.ArchivedAt) {
+		// Deleted by t
+		return nil, wrapError("get_document_as_of", ErrNotFound)
+	}
+	return found, nil
+}
+
+// SearchDocumentVersion ranks the chunks a document had at version by
+// similarity to query, scanning them exactly
+func (s *SQLiteStore) SearchDocumentVersion(ctx context.Context, docID string, version int, query []float32, topK int) ([]ScoredEmbedding, error) {
+	if len(query) == 0 {
+		return nil, wrapError("search_document_version", ErrEmptyQuery)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("search_document_version", ErrStoreClosed)
+	}
+
+	v, err := s.documentVersion(ctx, docID, version)
+	if err != nil {
+		return nil, wrapError("search_document_version", err)
+	}
+	chunks, err := s.versionChunks(ctx, v)
+	if err != nil {
+		return nil, wrapError("search_document_version", err)
+	}
+
+	results := make([]ScoredEmbedding, 0, len(chunks))
+	for _, chunk := range chunks {
+		if len(chunk.Vector) != len(query) {
+			continue
+		}
+		results = append(results, ScoredEmbedding{Embedding: *chunk, Score: s.similarityFn(query, chunk.Vector)})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if topK > 0 && len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// DiffDocumentVersions compares two versions of a document
+func (s *SQLiteStore) DiffDocumentVersions(ctx context.Context, docID string, from, to int) (*DocumentDiff, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("diff_document_versions", ErrStoreClosed)
+	}
+
+	a, err := s.documentVersion(ctx, docID, from)
+	if err != nil {
+		return nil, wrapError("diff_document_versions", err)
+	}
+	b, err := s.documentVersion(ctx, docID, to)
+	if err != nil {
+		return nil, wrapError("diff_document_versions", err)
+	}
+	chunksA, err := s.versionChunks(ctx, a)
+	if err != nil {
+		return nil, wrapError("diff_document_versions", err)
+	}
+	chunksB, err := s.versionChunks(ctx, b)
+	if err != nil {
+		return nil, wrapError("diff_document_versions", err)
+	}
+
+	diff := &DocumentDiff{DocumentID: docID, From: from, To: to}
+	for _, f := range []struct{ name, from, to string }{
+		{"title", a.Title, b.Title},
+		{"source_url", a.SourceURL, b.SourceURL},
+		{"author", a.Author, b.Author},
+	} {
+		if f.from != f.to {
+			diff.Fields = append(diff.Fields, FieldChange{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+	diff.Fields = append(diff.Fields, diffMetadata(a.Metadata, b.Metadata)...)
+	if a.Content != b.Content {
+		diff.Content = diffLines(a.Content, b.Content)
+	}
+
+	before := make(map[string]string, len(chunksA))
+	for _, c := range chunksA {
+		before[c.ID] = c.Content
+	}
+	for _, c := range chunksB {
+		content, ok := before[c.ID]
+		switch {
+		case !ok:
+			diff.Chunks.Added = append(diff.Chunks.Added, c.ID)
+		case content != c.Content:
+			diff.Chunks.Changed = append(diff.Chunks.Changed, c.ID)
+		default:
+			diff.Chunks.Unchanged = append(diff.Chunks.Unchanged, c.ID)
+		}
+		delete(before, c.ID)
+	}
+	for id := range before {
+		diff.Chunks.Removed = append(diff.Chunks.Removed, id)
+	}
+	sort.Strings(diff.Chunks.Removed)
+	return diff, nil
+}
+
+// RevertDocumentWrite undoes the write that gave a document version, for
+// callers whose follow-up work failed after a conditional write: the
+// archived previous version becomes current again and leaves the history.
+// It returns ErrConflict if the document has moved past version and
+// ErrNotFound if the previous version isn't archived.
+func (s *SQLiteStore) RevertDocumentWrite(ctx context.Context, docID string, version int) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return wrapError("revert_document_write", ErrStoreClosed)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError("revert_document_write", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var current int
+	err = tx.QueryRowContext(ctx, "SELECT version FROM documents WHERE id = ?", docID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return wrapError("revert_document_write", ErrNotFound)
+	}
+	if err != nil {
+		return wrapError("revert_document_write", fmt.Errorf("failed to read document version: %w", err))
+	}
+	if current != version {
+		return wrapError("revert_document_write", fmt.Errorf("%w: document is at version %d, not %d", ErrConflict, current, version))
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE documents
+		SET (title, source_url, content, author, metadata, acl, version, updated_at, valid_from) = (
+			SELECT title, source_url, content, author, metadata, acl, version, updated_at, valid_from
+			FROM document_versions WHERE doc_id = ? AND version = ?
+		)
+		WHERE id = ? AND EXISTS (SELECT 1 FROM document_versions WHERE doc_id = ? AND version = ?)
+	`, docID, version-1, docID, docID, version-1)
+	if err != nil {
+		return wrapError("revert_document_write", fmt.Errorf("failed to restore document: %w", err))
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return wrapError("revert_document_write", ErrNotFound)
+	}
+	for _, table := range []string{"document_versions", "document_version_chunks"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE doc_id = ? AND version = ?", docID, version-1); err != nil {
+			return wrapError("revert_document_write", fmt.Errorf("failed to unarchive document: %w", err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return wrapError("revert_document_write", err)
+	}
+	return nil
+}
+
+// PruneDocumentVersions removes archived versions selected by policy from
+// one document's history, or from every document's when docID is empty.
+// It returns the number of versions removed.
+func (s *SQLiteStore) PruneDocumentVersions(ctx context.Context, docID string, policy VersionRetention) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return 0, wrapError("prune_document_versions", ErrStoreClosed)
+	}
+
+	scope, scopeArgs := "", []interface{}{}
+	if docID != "" {
+		scope, scopeArgs = " AND doc_id = ?", []interface{}{docID}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, wrapError("prune_document_versions", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	removed := 0
+	prune := func(where string, args ...interface{}) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM document_versions WHERE "+where+scope, append(args, scopeArgs...)...)
+		if err != nil {
+			return fmt.Errorf("failed to prune versions: %w", err)
+		}
+		n, err := result.RowsAffected()
+		removed += int(n)
+		return err
+	}
+
+	if policy.DropDeleted {
+		if err := prune("doc_id NOT IN (SELECT id FROM documents)"); err != nil {
+			return 0, wrapError("prune_document_versions", err)
+		}
+	}
+	if policy.MaxAge > 0 {
+		if err := prune("archived_at < datetime('now', ?)", fmt.Sprintf("-%d seconds", int64(policy.MaxAge/time.Second))); err != nil {
+			return 0, wrapError("prune_document_versions", err)
+		}
+	}
+	if policy.KeepLast > 0 {
+		if err := prune(`(doc_id, version) IN (
+			SELECT doc_id, version FROM (
+				SELECT doc_id, version, ROW_NUMBER() OVER (PARTITION BY doc_id ORDER BY version DESC) AS rn
+				FROM document_versions
+			) WHERE rn > ?
+		)`, policy.KeepLast); err != nil {
+			return 0, wrapError("prune_document_versions", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM document_version_chunks WHERE NOT EXISTS (
+			SELECT 1 FROM document_versions v
+			WHERE v.doc_id = document_version_chunks.doc_id AND v.version = document_version_chunks.version
+		)
+	`); err != nil {
+		return 0, wrapError("prune_document_versions", fmt.Errorf("failed to prune version chunks: %w", err))
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, wrapError("prune_document_versions", err)
+	}
+	return removed, nil
+}
+
+// documentVersions loads the archived versions and the current row,
+// oldest first. An archived copy of the current version is shadowed by it.
+func (s *SQLiteStore) documentVersions(ctx context.Context, docID string) ([]*DocumentVersion, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT doc_id, version, title, source_url, content, author, metadata, acl, created_at, updated_at, valid_from, archived_at
+		FROM document_versions WHERE doc_id = ? ORDER BY version
+	`, docID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*DocumentVersion
+	for rows.Next() {
+		v, err := scanDocumentVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query versions: %w", err)
+	}
+
+	current, err := s.currentDocumentVersion(ctx, docID)
+	if err == ErrNotFound {
+		return versions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := versions[:0]
+	for _, v := range versions {
+		if v.Version != current.Version {
+			out = append(out, v)
+		}
+	}
+	out = append(out, current)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Current != out[j].Current {
+			return out[j].Current
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out, nil
+}
+
+// documentVersion loads one version with its chunk IDs
+func (s *SQLiteStore) documentVersion(ctx context.Context, docID string, version int) (*DocumentVersion, error) {
+	var v *DocumentVersion
+	current, err := s.currentDocumentVersion(ctx, docID)
+	switch {
+	case err == nil && current.Version == version:
+		v = current
+	case err != nil && err != ErrNotFound:
+		return nil, err
+	default:
+		row, err := s.db.QueryContext(ctx, `
+			SELECT doc_id, version, title, source_url, content, author, metadata, acl, created_at, updated_at, valid_from, archived_at
+			FROM document_versions WHERE doc_id = ? AND version = ?
+		`, docID, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query version: %w", err)
+		}
+		defer row.Close()
+		if !row.Next() {
+			if err := row.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: document '%s' version %d", ErrNotFound, docID, version)
+		}
+		if v, err = scanDocumentVersion(row); err != nil {
+			return nil, err
+		}
+	}
+
+	chunks, err := s.versionChunks(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chunks {
+		v.ChunkIDs = append(v.ChunkIDs, c.ID)
+	}
+	return v, nil
+}
+
+// currentDocumentVersion loads the stored document as its current version
+func (s *SQLiteStore) currentDocumentVersion(ctx context.Context, docID string) (*DocumentVersion, error) {
+	doc, err := getDocument(ctx, s.db, docID)
+	if err != nil {
+		return nil, err
+	}
+	var validFrom sql.NullTime
+	err = s.db.QueryRowContext(ctx, "SELECT valid_from FROM documents WHERE id = ?", docID).Scan(&validFrom)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to read document valid_from: %w", err)
+	}
+	v := &DocumentVersion{Document: *doc, Current: true}
+	v.ValidFrom = versionValidFrom(validFrom, doc.UpdatedAt)
+	return v, nil
+}
+
+// versionValidFrom falls back to updated_at for rows written before
+// valid_from was recorded
+func versionValidFrom(validFrom sql.NullTime, updatedAt time.Time) time.Time {
+	if validFrom.Valid {
+		return validFrom.Time
+	}
+	return updatedAt
+}
+
+// versionChunks loads the chunk embeddings a version had, in ID order
+func (s *SQLiteStore) versionChunks(ctx context.Context, v *DocumentVersion) ([]*Embedding, error) {
+	var rows *sql.Rows
+	var err error
+	if v.Current {
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT e.id, COALESCE(c.name, ''), e.content, e.vector, e.metadata
+			FROM embeddings e LEFT JOIN collections c ON e.collection_id = c.id
+			WHERE e.doc_id = ? ORDER BY e.id
+		`, v.ID)
+	} else {
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT vc.embedding_id, COALESCE(c.name, ''), vc.content, vc.vector, vc.metadata
+			FROM document_version_chunks vc LEFT JOIN collections c ON vc.collection_id = c.id
+			WHERE vc.doc_id = ? AND vc.version = ? ORDER BY vc.embedding_id
+		`, v.ID, v.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query version chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []*Embedding
+	for rows.Next() {
+		var emb Embedding
+		var vectorBytes []byte
+		var metadataJSON sql.NullString
+		if err := rows.Scan(&emb.ID, &emb.Collection, &emb.Content, &vectorBytes, &metadataJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan version chunk: %w", err)
+		}
+		if emb.Vector, err = encoding.DecodeVector(vectorBytes); err != nil {
+			return nil, fmt.Errorf("failed to decode vector: %w", err)
+		}
+		if metadataJSON.Valid && metadataJSON.String != "" {
+			if emb.Metadata, err = encoding.DecodeMetadata(metadataJSON.String); err != nil {
+				return nil, fmt.Errorf("failed to decode metadata: %w", err)
+			}
+		}
+		emb.DocID = v.ID
+		chunks = append(chunks, &emb)
+	}
+	return chunks, rows.Err()
+}
+
+func scanDocumentVersion(rows *sql.Rows) (*DocumentVersion, error) {
+	var v DocumentVersion
+	var title, sourceURL, content, author sql.NullString
+	var metadataJSON, aclJSON []byte
+	var validFrom sql.NullTime
+	if err := rows.Scan(&v.ID, &v.Version, &title, &sourceURL, &content, &author,
+		&metadataJSON, &aclJSON, &v.CreatedAt, &v.UpdatedAt, &validFrom, &v.ArchivedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan version: %w", err)
+	}
+	v.ValidFrom = versionValidFrom(validFrom, v.UpdatedAt)
+	v.Title, v.SourceURL, v.Content, v.Author = title.String, sourceURL.String, content.String, author.String
+	if len(metadataJSON) > 0 {
+		_ = json.Unmarshal(metadataJSON, &v.Metadata)
+	}
+	if len(aclJSON) > 0 {
+		_ = json.Unmarshal(aclJSON, &v.ACL)
+	}
+	return &v, nil
+}
+
+// diffMetadata lists changed metadata keys in key order
+func diffMetadata(from, to map[string]interface{}) []FieldChange {
+	keys := make(map[string]struct{}, len(from)+len(to))
+	for k := range from {
+		keys[k] = struct{}{}
+	}
+	for k := range to {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []FieldChange
+	for _, k := range sorted {
+		a, b := metadataText(from[k]), metadataText(to[k])
+		if a != b {
+			changes = append(changes, FieldChange{Field: "metadata." + k, From: a, To: b})
+		}
+	}
+	return changes
+}
+
+func metadataText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// diffLines is a longest-common-subsequence line diff
+func diffLines(from, to string) []DiffLine {
+	a, b := strings.Split(from, "\n"), strings.Split(to, "\n")
+	if len(a)*len(b) > maxDiffCells {
+		out := make([]DiffLine, 0, len(a)+len(b))
+		for _, line := range a {
+			out = append(out, DiffLine{Op: "-", Text: line})
+		}
+		for _, line := range b {
+			out = append(out, DiffLine{Op: "+", Text: line})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, DiffLine{Op: "=", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{Op: "-", Text: a[i]})
+			i++
+		default:
+			out = append(out, DiffLine{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, DiffLine{Op: "-", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, DiffLine{Op: "+", Text: b[j]})
+	}
+	return out
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDocumentVersions(t *testing.T) {
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "versions.db")
+	config.VectorDim = 2
+	config.HNSW.Enabled = true
+	store, err := NewWithConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.Init(ctx); err != nil {
+		t.Fatalf("Failed to initialize store: %v", err)
+	}
+
+	doc := &Document{ID: "doc", Title: "Draft", Content: "alpha\nbeta", Version: 1, Metadata: map[string]interface{}{"status": "draft"}}
+	if err := store.CreateDocument(ctx, doc); err != nil {
+		t.Fatalf("CreateDocument failed: %v", err)
+	}
+	if err := store.UpsertBatch(ctx, []*Embedding{
+		{ID: "c0", DocID: "doc", Vector: []float32{1, 0}, Content: "alpha"},
+		{ID: "c1", DocID: "doc", Vector: []float32{0, 1}, Content: "beta"},
+	}); err != nil {
+		t.Fatalf("UpsertBatch failed: %v", err)
+	}
+
+	// Version 2 replaces beta with gamma
+	doc.Title, doc.Content, doc.Version = "Final", "alpha\ngamma", 2
+	doc.Metadata = map[string]interface{}{"status": "final"}
+	if err := store.UpdateDocument(ctx, doc); err != nil {
+		t.Fatalf("UpdateDocument failed: %v", err)
+	}
+	if err := store.Delete(ctx, "c1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Upsert(ctx, &Embedding{ID: "c2", DocID: "doc", Vector: []float32{0.6, 0.8}, Content: "gamma"}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	versions, err := store.ListDocumentVersions(ctx, "doc")
+	if err != nil {
+		t.Fatalf("ListDocumentVersions failed: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 1 || versions[0].Current || !versions[1].Current {
+		t.Fatalf("Unexpected versions: %+v", versions)
+	}
+
+	v1, err := store.GetDocumentVersion(ctx, "doc", 1)
+	if err != nil {
+		t.Fatalf("GetDocumentVersion failed: %v", err)
+	}
+	if v1.Title != "Draft" || v1.Content != "alpha\nbeta" || len(v1.ChunkIDs) != 2 || v1.ChunkIDs[1] != "c1" {
+		t.Errorf("Unexpected version 1: %+v", v1)
+	}
+	if _, err := store.GetDocumentVersion(ctx, "doc", 5); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing version, got %v", err)
+	}
+
+	results, err := store.SearchDocumentVersion(ctx, "doc", 1, []float32{0, 1}, 1)
+	if err != nil {
+		t.Fatalf("SearchDocumentVersion failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "c1" || results[0].Content != "beta" {
+		t.Errorf("Expected the archived chunk c1, got %+v", results)
+	}
+
+	diff, err := store.DiffDocumentVersions(ctx, "doc", 1, 2)
+	if err != nil {
+		t.Fatalf("DiffDocumentVersions failed: %v", err)
+	}
+	if len(diff.Fields) != 2 || diff.Fields[0].Field != "title" || diff.Fields[1] != (FieldChange{Field: "metadata.status", From: "draft", To: "final"}) {
+		t.Errorf("Unexpected field changes: %+v", diff.Fields)
+	}
+	wantLines := []DiffLine{{"=", "alpha"}, {"-", "beta"}, {"+", "gamma"}}
+	if len(diff.Content) != len(wantLines) {
+		t.Fatalf("Unexpected content diff: %+v", diff.Content)
+	}
+	for i, line := range wantLines {
+		if diff.Content[i] != line {
+			t.Errorf("Content diff line %d: expected %+v, got %+v", i, line, diff.Content[i])
+		}
+	}
+	if len(diff.Chunks.Added) != 1 || diff.Chunks.Added[0] != "c2" || len(diff.Chunks.Removed) != 1 || diff.Chunks.Removed[0] != "c1" || len(diff.Chunks.Unchanged) != 1 {
+		t.Errorf("Unexpected chunk changes: %+v", diff.Chunks)
+	}
+
+	t.Run("AsOf", func(t *testing.T) {
+		got, err := store.GetDocumentAsOf(ctx, "doc", time.Now().Add(time.Hour))
+		if err != nil || got.Version != 2 {
+			t.Fatalf("Expected the current version, got %+v, %v", got, err)
+		}
+		got, err = store.GetDocumentAsOf(ctx, "doc", v1.UpdatedAt)
+		if err != nil {
+			t.Fatalf("GetDocumentAsOf failed: %v", err)
+		}
+		if got.Version != 1 && !got.UpdatedAt.Equal(v1.UpdatedAt) {
+			t.Errorf("Expected version 1 or a version from the same second, got %d", got.Version)
+		}
+		if _, err := store.GetDocumentAsOf(ctx, "doc", v1.UpdatedAt.Add(-time.Hour)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound before the document existed, got %v", err)
+		}
+	})
+
+	t.Run("AsOfAfterInPlaceEdit", func(t *testing.T) {
+		// Move both versions into the past so the edit below lands later
+		if _, err := store.db.ExecContext(ctx, "UPDATE document_versions SET valid_from = datetime('now', '-2 hours'), updated_at = datetime('now', '-2 hours') WHERE doc_id = 'doc'"); err != nil {
+			t.Fatalf("Failed to backdate version 1: %v", err)
+		}
+		if _, err := store.db.ExecContext(ctx, "UPDATE documents SET valid_from = datetime('now', '-1 hours'), updated_at = datetime('now', '-1 hours') WHERE id = 'doc'"); err != nil {
+			t.Fatalf("Failed to backdate version 2: %v", err)
+		}
+
+		doc.Content = "alpha\ngamma\ndelta"
+		if err := store.UpdateDocument(ctx, doc); err != nil {
+			t.Fatalf("UpdateDocument failed: %v", err)
+		}
+		got, err := store.GetDocumentAsOf(ctx, "doc", time.Now().Add(-30*time.Minute))
+		if err != nil || got.Version != 2 {
+			t.Fatalf("Expected version 2 to stay current from before the in-place edit, got %+v, %v", got, err)
+		}
+		if !got.UpdatedAt.After(got.ValidFrom) {
+			t.Errorf("Expected the edit to move UpdatedAt past ValidFrom, got %v and %v", got.UpdatedAt, got.ValidFrom)
+		}
+	})
+
+	t.Run("UpsertAndPrune", func(t *testing.T) {
+		for _, title := range []string{"v3", "v4", "v5"} {
+			if err := store.UpsertDocumentWithCondition(ctx, &Document{ID: "doc", Title: title}, WriteCondition{}); err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+		}
+		versions, err := store.ListDocumentVersions(ctx, "doc")
+		if err != nil || len(versions) != 5 {
+			t.Fatalf("Expected 5 versions, got %d, %v", len(versions), err)
+		}
+
+		removed, err := store.PruneDocumentVersions(ctx, "doc", VersionRetention{KeepLast: 2})
+		if err != nil {
+			t.Fatalf("PruneDocumentVersions failed: %v", err)
+		}
+		if removed != 2 {
+			t.Errorf("Expected 2 versions pruned, got %d", removed)
+		}
+		versions, _ = store.ListDocumentVersions(ctx, "doc")
+		if len(versions) != 3 || versions[0].Version != 3 {
+			t.Errorf("Expected versions 3-5 kept, got %+v", versions)
+		}
+		var chunks int
+		_ = store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM document_version_chunks WHERE version < 3").Scan(&chunks)
+		if chunks != 0 {
+			t.Errorf("Expected pruned versions' chunks removed, found %d", chunks)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if store.hnswIndex == nil {
+			t.Fatal("Expected an HNSW index")
+		}
+		indexed := store.hnswIndex.Size()
+
+		// A delete that fails to commit leaves the chunks indexed
+		if _, err := store.db.ExecContext(ctx, `CREATE TRIGGER block_delete BEFORE DELETE ON documents BEGIN SELECT RAISE(ABORT, 'blocked'); END`); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.DeleteDocument(ctx, "doc"); err == nil {
+			t.Fatal("Expected the blocked delete to fail")
+		}
+		if _, err := store.db.ExecContext(ctx, "DROP TRIGGER block_delete"); err != nil {
+			t.Fatal(err)
+		}
+		if size := store.hnswIndex.Size(); size != indexed {
+			t.Errorf("Expected %d indexed chunks after the failed delete, got %d", indexed, size)
+		}
+
+		if err := store.DeleteDocument(ctx, "doc"); err != nil {
+			t.Fatalf("DeleteDocument failed: %v", err)
+		}
+		versions, err := store.ListDocumentVersions(ctx, "doc")
+		if err != nil || len(versions) != 3 || versions[2].Current {
+			t.Fatalf("Expected history kept after delete, got %+v, %v", versions, err)
+		}
+		if size := store.hnswIndex.Size(); size != indexed-2 {
+			t.Errorf("Expected the document's 2 chunks unindexed, got %d of %d left", size, indexed)
+		}
+		if _, err := store.GetDocumentAsOf(ctx, "doc", time.Now().Add(time.Hour)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after delete, got %v", err)
+		}
+
+		removed, err := store.PruneDocumentVersions(ctx, "", VersionRetention{DropDeleted: true})
+		if err != nil || removed != 3 {
+			t.Errorf("Expected 3 versions dropped, got %d, %v", removed, err)
+		}
+		if _, err := store.ListDocumentVersions(ctx, "doc"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after pruning, got %v", err)
+		}
+	})
+}
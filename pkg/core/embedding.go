@@ -3,6 +3,8 @@ package core
 import (
 	"context"
 	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/index"
 )
 
 // Embedding represents a vector embedding with associated metadata
@@ -15,6 +17,8 @@ type Embedding struct {
 	DocID        string            `json:"docId,omitempty"`
 	Metadata     map[string]string `json:"metadata,omitempty"`
 	ACL          []string          `json:"acl,omitempty"` // Allowed user IDs or groups
+	Revision     int64             `json:"revision,omitempty"` // Incremented on every write, set by the store
+	ModelID      string            `json:"model_id,omitempty"` // Embedding model that produced Vector, if known
 }
 
 // ScoredEmbedding represents an embedding with similarity score
@@ -25,19 +29,35 @@ type ScoredEmbedding struct {
 
 // SearchOptions defines options for vector search
 type SearchOptions struct {
-	Collection string            `json:"collection,omitempty"` // Collection name to search in
-	TopK       int               `json:"topK"`
-	Filter     map[string]string `json:"filter,omitempty"`
-	Threshold  float64           `json:"threshold,omitempty"`
-	QueryText  string            `json:"queryText,omitempty"`  // Optional query text for enhanced matching
-	TextWeight float64           `json:"textWeight,omitempty"` // Weight for text similarity (0.0-1.0, default 0.3)
+	Collection   string            `json:"collection,omitempty"` // Collection name to search in
+	TopK         int               `json:"topK"`
+	Filter       map[string]string `json:"filter,omitempty"`
+	Threshold    float64           `json:"threshold,omitempty"`
+	QueryText    string            `json:"queryText,omitempty"`    // Optional query text for enhanced matching
+	TextWeight   float64           `json:"textWeight,omitempty"`   // Weight for text similarity (0.0-1.0, default 0.3)
+	RescoreDepth int               `json:"rescoreDepth,omitempty"` // Prefix-index candidates rescored with full vectors (default: TopK x Prefix.Oversample)
 }
 
 // StoreStats provides statistics about the vector store
 type StoreStats struct {
-	Count      int64 `json:"count"`
-	Dimensions int   `json:"dimensions"`
-	Size       int64 `json:"size"`
+	Count        int64               `json:"count"`
+	Dimensions   int                 `json:"dimensions"`
+	Size         int64               `json:"size"`
+	PrefixSearch []PrefixSearchStats `json:"prefixSearch,omitempty"` // One entry per collection with a prefix index
+}
+
+// PrefixSearchStats reports latency and recall of a collection's prefix index.
+// Recall is measured on sampled queries against an exact full-dimension scan.
+type PrefixSearchStats struct {
+	Collection        string        `json:"collection"`
+	PrefixDims        int           `json:"prefixDims"`
+	Vectors           int           `json:"vectors"`
+	Queries           int64         `json:"queries"`
+	AvgLatency        time.Duration `json:"avgLatency"`        // Prefix search plus rescoring
+	AvgRescoreLatency time.Duration `json:"avgRescoreLatency"` // Fetching and rescoring full vectors
+	AvgRescored       float64       `json:"avgRescored"`       // Candidates rescored per query
+	RecallSamples     int64         `json:"recallSamples"`
+	Recall            float64       `json:"recall"` // Mean recall@k over sampled queries, 0 until sampled
 }
 
 // DocumentInfo provides information about a document in the store
@@ -86,6 +106,133 @@ func DefaultIVFConfig() IVFConfig {
 	}
 }
 
+// IVFPQConfig represents configuration options for the IVF-PQ index
+type IVFPQConfig struct {
+	NCentroids   int  `json:"nCentroids"`   // Number of inverted lists (default: 100)
+	NProbe       int  `json:"nProbe"`       // Lists scanned per query (default: 10)
+	PQSubspaces  int  `json:"pqSubspaces"`  // PQ bytes per vector, must divide the dimension (0 = dim/4)
+	PQCentroids  int  `json:"pqCentroids"`  // Centroids per subspace, at most 256 (default: 256)
+	Rerank       bool `json:"rerank"`       // Rescore candidates with exact vectors from SQLite (default: true)
+	RerankFactor int  `json:"rerankFactor"` // Candidates fetched per requested result when reranking (default: 4)
+}
+
+// DefaultIVFPQConfig returns default IVF-PQ configuration
+func DefaultIVFPQConfig() IVFPQConfig {
+	return IVFPQConfig{
+		NCentroids:   100,
+		NProbe:       10,
+		PQCentroids:  256,
+		Rerank:       true,
+		RerankFactor: 4,
+	}
+}
+
+// DiskANNConfig represents configuration options for the disk-resident DiskANN index
+type DiskANNConfig struct {
+	Path        string  `json:"path"`        // Index file path (default: database path + ".diskann")
+	R           int     `json:"r"`           // Maximum graph degree (default: 32)
+	L           int     `json:"l"`           // Candidate list size during build (default: 64)
+	Alpha       float32 `json:"alpha"`       // Pruning slack that keeps long-range edges (default: 1.2)
+	SearchL     int     `json:"searchL"`     // Candidate list size during search (default: 64)
+	PQSubspaces int     `json:"pqSubspaces"` // PQ bytes per vector kept in RAM, must divide the dimension (0 = dim/4)
+	MergeRatio  float64 `json:"mergeRatio"`  // Rebuild once changes since the build exceed this share of the index (default: 0.1)
+}
+
+// DefaultDiskANNConfig returns default DiskANN configuration
+func DefaultDiskANNConfig() DiskANNConfig {
+	return DiskANNConfig{
+		R:          32,
+		L:          64,
+		Alpha:      1.2,
+		SearchL:    64,
+		MergeRatio: 0.1,
+	}
+}
+
+// LSHConfig represents configuration options for the LSH index
+type LSHConfig struct {
+	NumTables    int   `json:"numTables"`    // Hash tables, more improve recall (default: 10)
+	NumHashFuncs int   `json:"numHashFuncs"` // Hyperplanes per table, more make buckets smaller (default: 8)
+	NumProbes    int   `json:"numProbes"`    // Neighbouring buckets probed per table, 0 = own bucket only (default: 4)
+	Seed         int64 `json:"seed"`         // Seed for the random projections
+}
+
+// DefaultLSHConfig returns default LSH configuration
+func DefaultLSHConfig() LSHConfig {
+	return LSHConfig{
+		NumTables:    10,
+		NumHashFuncs: 8,
+		NumProbes:    4,
+	}
+}
+
+// MultiIndexConfig represents configuration options for multi-index ensembles.
+// Members are built from the HNSW, IVF and LSH configurations.
+type MultiIndexConfig struct {
+	PrimaryIndex     index.IndexType       `json:"primaryIndex"`     // Member used for candidates and sizing (default: hnsw)
+	SecondaryIndices []index.IndexType     `json:"secondaryIndices"` // Other members: hnsw, ivf, flat, lsh or hybrid (default: lsh)
+	CombineStrategy  index.CombineStrategy `json:"combineStrategy"`  // primary_only, merge_all, rerank or voting (default: merge_all)
+	RerankTopK       int                   `json:"rerankTopK"`       // Primary candidates for the rerank strategy, 0 = 2*k
+	Parallel         bool                  `json:"parallel"`         // Insert into members concurrently
+}
+
+// DefaultMultiIndexConfig returns default multi-index configuration
+func DefaultMultiIndexConfig() MultiIndexConfig {
+	return MultiIndexConfig{
+		PrimaryIndex:     index.IndexTypeHNSW,
+		SecondaryIndices: []index.IndexType{index.IndexTypeLSH},
+		CombineStrategy:  index.StrategyMergeAll,
+	}
+}
+
+// BinaryConfig represents configuration options for binary (Hamming) retrieval.
+// Each vector is kept as one bit per dimension, a 32x memory reduction.
+type BinaryConfig struct {
+	Substrings int  `json:"substrings"` // Multi-index hashing tables, 0 = popcount scan of every code (default: 0)
+	Rescore    bool `json:"rescore"`    // Rescore candidates with exact vectors from SQLite (default: true)
+	Oversample int  `json:"oversample"` // Candidates fetched per requested result (default: 8)
+}
+
+// DefaultBinaryConfig returns default binary retrieval configuration
+func DefaultBinaryConfig() BinaryConfig {
+	return BinaryConfig{
+		Rescore:    true,
+		Oversample: 8,
+	}
+}
+
+// PrefixConfig represents configuration options for collections created with
+// WithPrefixIndex, which search the first dimensions of each vector and
+// rescore the shortlist with the full vectors
+type PrefixConfig struct {
+	Oversample       int `json:"oversample"`       // Candidates rescored per requested result (default: 4)
+	RecallSampleRate int `json:"recallSampleRate"` // Measure recall on every Nth query, 0 = never (default: 100)
+}
+
+// DefaultPrefixConfig returns default prefix search configuration
+func DefaultPrefixConfig() PrefixConfig {
+	return PrefixConfig{
+		Oversample:       4,
+		RecallSampleRate: 100,
+	}
+}
+
+// ExternalWritesConfig controls how the store notices embeddings written to
+// the same database file by other processes
+type ExternalWritesConfig struct {
+	Enabled       bool          `json:"enabled"`       // Apply other processes' writes to the indexes before searching (default: true)
+	CheckInterval time.Duration `json:"checkInterval"` // Minimum time between change log checks, 0 = every search (default: 0)
+	LogRetention  int           `json:"logRetention"`  // Change log rows kept for lagging processes (default: 100000)
+}
+
+// DefaultExternalWritesConfig returns default external write detection configuration
+func DefaultExternalWritesConfig() ExternalWritesConfig {
+	return ExternalWritesConfig{
+		Enabled:      true,
+		LogRetention: 100000,
+	}
+}
+
 // TextSimilarityConfig represents configuration for text-based similarity
 type TextSimilarityConfig struct {
 	Enabled       bool    `json:"enabled"`       // Enable text similarity matching
@@ -125,6 +272,7 @@ const (
 	AutoTruncate                    // Always truncate to smaller dimension
 	AutoPad                         // Always pad to larger dimension
 	WarnOnly                        // Only warn, don't auto-adapt
+	LearnedProjection               // Map through the collection's projection fitted with TrainProjection
 )
 
 // IndexType defines the type of index to use
@@ -134,6 +282,12 @@ const (
 	IndexTypeHNSW IndexType = iota
 	IndexTypeIVF
 	IndexTypeFlat
+	IndexTypeDiskANN // Graph and vectors on disk, PQ codes in memory
+	IndexTypeIVFPQ   // Inverted lists of PQ-compressed residuals
+	IndexTypeLSH     // Random-projection hash tables
+	IndexTypeHybrid  // HNSW and IVF searched together
+	IndexTypeMulti   // Ensemble of indexes combined by MultiIndex strategy
+	IndexTypeBinary  // Packed bit codes searched by Hamming distance, rescored with exact vectors
 )
 
 // Config represents configuration options for the vector store
@@ -145,6 +299,13 @@ type Config struct {
 	IndexType      IndexType            `json:"indexType"`               // Index type to use
 	HNSW           HNSWConfig           `json:"hnsw,omitempty"`          // HNSW index configuration
 	IVF            IVFConfig            `json:"ivf,omitempty"`           // IVF index configuration
+	IVFPQ          IVFPQConfig          `json:"ivfpq,omitempty"`         // IVF-PQ index configuration
+	DiskANN        DiskANNConfig        `json:"diskann,omitempty"`       // DiskANN index configuration
+	LSH            LSHConfig            `json:"lsh,omitempty"`           // LSH index configuration
+	MultiIndex     MultiIndexConfig     `json:"multiIndex,omitempty"`    // Multi-index ensemble configuration
+	Binary         BinaryConfig         `json:"binary,omitempty"`        // Binary (Hamming) retrieval configuration
+	Prefix         PrefixConfig         `json:"prefix,omitempty"`        // Prefix (Matryoshka) search configuration
+	ExternalWrites ExternalWritesConfig `json:"externalWrites,omitempty"` // Detection of writes by other processes
 	TextSimilarity TextSimilarityConfig `json:"textSimilarity,omitempty"` // Text similarity configuration
 	Quantization   QuantizationConfig   `json:"quantization,omitempty"`   // Quantization configuration
 	Logger         Logger               `json:"-"`                       // Logger instance (defaults to nop logger)
@@ -168,6 +329,13 @@ func DefaultConfig() Config {
 		IndexType:      IndexTypeHNSW,                  // Default to HNSW
 		HNSW:           DefaultHNSWConfig(),            // HNSW configuration
 		IVF:            DefaultIVFConfig(),             // IVF configuration
+		IVFPQ:          DefaultIVFPQConfig(),           // IVF-PQ configuration
+		DiskANN:        DefaultDiskANNConfig(),         // DiskANN configuration
+		LSH:            DefaultLSHConfig(),             // LSH configuration
+		MultiIndex:     DefaultMultiIndexConfig(),      // Multi-index configuration
+		Binary:         DefaultBinaryConfig(),          // Binary retrieval configuration
+		Prefix:         DefaultPrefixConfig(),          // Prefix search configuration
+		ExternalWrites: DefaultExternalWritesConfig(),  // External write detection
 		TextSimilarity: DefaultTextSimilarityConfig(),  // Text similarity configuration
 		Quantization:   DefaultQuantizationConfig(),    // Quantization configuration
 		AutoSave:       DefaultAutoSaveConfig(),        // Auto-save configuration
@@ -195,10 +363,24 @@ type Store interface {
 	// If the vector dimension doesn't match the store's dimension, it applies the adaptation policy.
 	Upsert(ctx context.Context, emb *Embedding) error
 
+	// UpsertWithCondition writes an embedding only if the given precondition holds,
+	// returning an error matching ErrConflict otherwise.
+	UpsertWithCondition(ctx context.Context, emb *Embedding, cond WriteCondition) error
+
 	// UpsertBatch inserts or updates multiple embeddings in a single database transaction.
 	// This is significantly faster than calling Upsert multiple times.
 	UpsertBatch(ctx context.Context, embs []*Embedding) error
 
+	// BeginTx starts a transaction spanning embeddings, documents and any other table.
+	// In-memory index updates are applied only when the transaction commits.
+	BeginTx(ctx context.Context) (*Tx, error)
+
+	// NewBulkLoader starts (or resumes) a bulk load for initial ingestion.
+	// Full-text and vector indexing are deferred until the loader is finished.
+	NewBulkLoader(ctx context.Context, opts BulkLoadOptions) (*BulkLoader, error)
+	// ListBulkLoads lists recorded bulk loads and their checkpoints.
+	ListBulkLoads(ctx context.Context) ([]*BulkLoadState, error)
+
 	// Search performs a vector similarity search.
 	// It uses the configured index (HNSW or IVF) if available, otherwise falls back to linear search.
 	Search(ctx context.Context, query []float32, opts SearchOptions) ([]ScoredEmbedding, error)
@@ -225,8 +407,11 @@ type Store interface {
 	Stats(ctx context.Context) (StoreStats, error)
 
 	// CreateCollection creates a new named collection for multi-tenant isolation.
-	CreateCollection(ctx context.Context, name string, dimensions int) (*Collection, error)
-	// GetCollection retrieves collection information by name.
+	// Pass WithMetadataSchema to enforce a metadata shape on writes.
+	CreateCollection(ctx context.Context, name string, dimensions int, opts ...CollectionOption) (*Collection, error)
+	// MigrateCollectionSchema adds fields to, or relaxes, a collection's metadata schema.
+	MigrateCollectionSchema(ctx context.Context, name string, schema *MetadataSchema) (*MetadataSchema, error)
+	// GetCollection retrieves collection information, including its schema, by name.
 	GetCollection(ctx context.Context, name string) (*Collection, error)
 	// ListCollections lists all available collections.
 	ListCollections(ctx context.Context) ([]*Collection, error)
@@ -235,10 +420,25 @@ type Store interface {
 	// GetCollectionStats returns statistics for a specific collection.
 	GetCollectionStats(ctx context.Context, name string) (*CollectionStats, error)
 
-	// TrainIndex learns cluster centroids for IVF indexes from existing data.
+	// CreateAlias creates a stable alias that resolves to an existing collection.
+	CreateAlias(ctx context.Context, alias, collection string) (*CollectionAlias, error)
+	// UpdateAlias atomically repoints an alias at another collection.
+	UpdateAlias(ctx context.Context, alias, collection string) error
+	// DeleteAlias removes an alias without touching its target collection.
+	DeleteAlias(ctx context.Context, alias string) error
+	// ListAliases lists all aliases with their current targets.
+	ListAliases(ctx context.Context) ([]*CollectionAlias, error)
+
+	// TrainIndex learns cluster centroids (and PQ codebooks) for IVF and IVF-PQ indexes from existing data.
 	TrainIndex(ctx context.Context, numCentroids int) error
+	// TrainProjection fits and saves a linear map from another embedding model's vectors into a collection's space.
+	TrainProjection(ctx context.Context, collection string, source, target [][]float32, method ProjectionMethod) (*Projection, error)
 	// TrainQuantizer learns value ranges for scalar quantization from existing data.
 	TrainQuantizer(ctx context.Context) error
+	// RebuildIndexAsync rebuilds the HNSW or IVF index, or retrains the quantizer, in the background and swaps it in.
+	RebuildIndexAsync(ctx context.Context, opts IndexBuildOptions) (*IndexBuild, error)
+	// IndexBuildStatus reports the running or most recent background index build.
+	IndexBuildStatus() (IndexBuildStatus, bool)
 
 	// CreateDocument creates a document record for source tracking and versioning.
 	CreateDocument(ctx context.Context, doc *Document) error
@@ -248,6 +448,18 @@ type Store interface {
 	DeleteDocument(ctx context.Context, id string) error
 	// ListDocumentsWithFilter lists documents matching specific criteria like author.
 	ListDocumentsWithFilter(ctx context.Context, author string, limit int) ([]*Document, error)
+	// ListDocumentVersions returns the archived and current versions of a document, oldest first.
+	ListDocumentVersions(ctx context.Context, docID string) ([]*DocumentVersion, error)
+	// GetDocumentVersion retrieves a document and its chunk IDs as of a version.
+	GetDocumentVersion(ctx context.Context, docID string, version int) (*DocumentVersion, error)
+	// GetDocumentAsOf retrieves the version of a document that was current at a time.
+	GetDocumentAsOf(ctx context.Context, docID string, t time.Time) (*DocumentVersion, error)
+	// SearchDocumentVersion ranks the chunks a document had at a version by similarity.
+	SearchDocumentVersion(ctx context.Context, docID string, version int, query []float32, topK int) ([]ScoredEmbedding, error)
+	// DiffDocumentVersions compares the fields, content and chunks of two versions.
+	DiffDocumentVersions(ctx context.Context, docID string, from, to int) (*DocumentDiff, error)
+	// PruneDocumentVersions removes archived versions by retention policy.
+	PruneDocumentVersions(ctx context.Context, docID string, policy VersionRetention) (int, error)
 
 	// CreateSession starts a new conversation thread for chat memory.
 	CreateSession(ctx context.Context, session *Session) error
@@ -24,6 +24,15 @@ var (
 	
 	// ErrEmptyQuery is returned when search query is empty
 	ErrEmptyQuery = errors.New("empty query vector")
+	
+	// ErrSchemaViolation is returned when metadata doesn't match the collection schema
+	ErrSchemaViolation = errors.New("metadata schema violation")
+	
+	// ErrConflict is returned when a conditional write's precondition fails
+	ErrConflict = errors.New("write conflict")
+	
+	// ErrIndexBuildRunning is returned when a background index build is already running
+	ErrIndexBuildRunning = errors.New("index build already running")
 )
 
 // StoreError wraps errors with operation context
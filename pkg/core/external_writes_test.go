@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestExternalWrites(t *testing.T) {
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "shared.db")
+	config.VectorDim = 16
+	config.HNSW.Enabled = true
+	config.AutoSave.Enabled = false
+
+	ctx := context.Background()
+	randomVec := newRandomVec(31, 16)
+
+	// The writer stands in for an ingestion process, the reader for a server
+	writer := openTestStore(t, config)
+	defer writer.Close()
+	for i := 0; i < 50; i++ {
+		if err := writer.Upsert(ctx, &Embedding{ID: fmt.Sprintf("doc_%d", i), Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+	reader := openTestStore(t, config)
+	defer reader.Close()
+
+	expectNearest := func(store *SQLiteStore, id string, vec []float32) {
+		t.Helper()
+		results, err := store.Search(ctx, vec, SearchOptions{TopK: 3})
+		if err != nil || len(results) == 0 || results[0].ID != id {
+			t.Fatalf("Expected %s as top result, got %v, %v", id, results, err)
+		}
+	}
+
+	t.Run("Inserts", func(t *testing.T) {
+		vec := randomVec()
+		if err := writer.Upsert(ctx, &Embedding{ID: "new_doc", Vector: vec}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		expectNearest(reader, "new_doc", vec)
+		if n := reader.hnswIndex.Size(); n != 51 {
+			t.Errorf("Expected 51 vectors in the reader's index, got %d", n)
+		}
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		vec := randomVec()
+		if err := writer.Upsert(ctx, &Embedding{ID: "doc_3", Vector: vec}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		expectNearest(reader, "doc_3", vec)
+	})
+
+	t.Run("Deletes", func(t *testing.T) {
+		doc, err := writer.GetByID(ctx, "doc_4")
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if err := writer.Delete(ctx, "doc_4"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		results, err := reader.Search(ctx, doc.Vector, SearchOptions{TopK: 50})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		for _, r := range results {
+			if r.ID == "doc_4" {
+				t.Error("Expected the deleted embedding gone from the reader's results")
+			}
+		}
+	})
+
+	t.Run("OwnWrites", func(t *testing.T) {
+		vec := randomVec()
+		if err := reader.Upsert(ctx, &Embedding{ID: "reader_doc", Vector: vec}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		if err := reader.Delete(ctx, "doc_5"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := reader.applyChanges(ctx); err != nil {
+			t.Fatalf("applyChanges failed: %v", err)
+		}
+		if n := reader.hnswIndex.Size(); n != 50 {
+			t.Errorf("Expected 50 vectors in the reader's index, got %d", n)
+		}
+
+		// The writer picks both up, the reader has nothing left to apply
+		expectNearest(writer, "reader_doc", vec)
+		seq, err := reader.latestChangeSeq(ctx)
+		if err != nil || reader.changeSeq.Load() != seq {
+			t.Errorf("Expected the reader caught up at %d, got %d, %v", seq, reader.changeSeq.Load(), err)
+		}
+	})
+
+	t.Run("PrunedLog", func(t *testing.T) {
+		pruned := config
+		pruned.ExternalWrites.LogRetention = 5
+		lagging := openTestStore(t, config)
+		defer lagging.Close()
+		pruner := openTestStore(t, pruned)
+		defer pruner.Close()
+
+		var last []float32
+		for i := 0; i < 20; i++ {
+			last = randomVec()
+			if err := writer.Upsert(ctx, &Embedding{ID: fmt.Sprintf("late_%d", i), Vector: last}); err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+		}
+		// The pruner applies and prunes, leaving the lagging store behind the log
+		expectNearest(pruner, "late_19", last)
+		expectNearest(lagging, "late_19", last)
+		if n := lagging.hnswIndex.Size(); n != 70 {
+			t.Errorf("Expected 70 vectors after the rebuild, got %d", n)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		disabled := config
+		disabled.ExternalWrites.Enabled = false
+		store := openTestStore(t, disabled)
+		defer store.Close()
+
+		if err := writer.Upsert(ctx, &Embedding{ID: "unseen", Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		if _, err := store.Search(ctx, randomVec(), SearchOptions{TopK: 3}); err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if n := store.hnswIndex.Size(); n != 70 {
+			t.Errorf("Expected external writes ignored, got %d vectors", n)
+		}
+	})
+}
+
+func TestChangeLogGrowth(t *testing.T) {
+	ctx := context.Background()
+	logRows := func(store *SQLiteStore) int {
+		var n int
+		if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM embedding_changes").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	writeMany := func(store *SQLiteStore) {
+		for i := 0; i < 500; i++ {
+			if err := store.Upsert(ctx, &Embedding{ID: fmt.Sprintf("doc_%d", i%10), Vector: []float32{float32(i), 1, 0, 0}}); err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+		}
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Path = filepath.Join(t.TempDir(), "disabled.db")
+		config.VectorDim = 4
+		config.AutoSave.Enabled = false
+		config.ExternalWrites.Enabled = false
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		writeMany(store)
+		if n := logRows(store); n != 0 {
+			t.Errorf("Expected no change log without ExternalWrites, got %d rows", n)
+		}
+	})
+
+	t.Run("WriteOnly", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Path = filepath.Join(t.TempDir(), "writer.db")
+		config.VectorDim = 4
+		config.AutoSave.Enabled = false
+		config.ExternalWrites.LogRetention = 50
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		writeMany(store)
+		if n := logRows(store); n > 55 {
+			t.Errorf("Expected the change log pruned without searches, got %d rows", n)
+		}
+	})
+}
@@ -81,6 +81,8 @@ func (s *SQLiteStore) SearchWithFacets(ctx context.Context, query []float32, opt
 	if s.closed {
 		return nil, nil, wrapError("search_faceted", ErrStoreClosed)
 	}
+
+	opts.Collection = s.resolveCollectionName(ctx, opts.Collection)
 	
 	// Build faceted query
 	whereClause, args := s.buildFacetedWhereClause(opts.Facets)
@@ -341,6 +343,8 @@ func (s *SQLiteStore) RangeSearch(ctx context.Context, query []float32, radius f
 	if s.closed {
 		return nil, wrapError("range_search", ErrStoreClosed)
 	}
+
+	opts.Collection = s.resolveCollectionName(ctx, opts.Collection)
 	
 	// Validate radius
 	if radius <= 0 {
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+// openTestStore creates and initializes a store with config. The caller
+// closes it, so tests can reopen the same database.
+func openTestStore(t *testing.T, config Config) *SQLiteStore {
+	t.Helper()
+	store, err := NewWithConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if err := store.Init(context.Background()); err != nil {
+		t.Fatalf("Failed to initialize store: %v", err)
+	}
+	return store
+}
+
+// newRandomVec returns a generator of dim-sized vectors with components in
+// [-1, 1), drawn from a seeded source so runs are repeatable
+func newRandomVec(seed int64, dim int) func() []float32 {
+	rng := rand.New(rand.NewSource(seed))
+	return func() []float32 {
+		vec := make([]float32, dim)
+		for j := range vec {
+			vec[j] = rng.Float32()*2 - 1
+		}
+		return vec
+	}
+}
+
+// expectTop searches with the stored vector of id and reports an error
+// unless id is the top result
+func expectTop(t *testing.T, store *SQLiteStore, id string) {
+	t.Helper()
+	ctx := context.Background()
+	emb, err := store.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID(%s) failed: %v", id, err)
+	}
+	results, err := store.Search(ctx, emb.Vector, SearchOptions{TopK: 3})
+	if err != nil || len(results) == 0 || results[0].ID != id {
+		t.Errorf("Expected %s as top result, got %v, %v", id, results, err)
+	}
+}
@@ -0,0 +1,550 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+	"github.com/liliang-cn/cortexdb/v2/pkg/index"
+)
+
+// IndexBuildKind selects the work a background index build performs
+type IndexBuildKind string
+
+const (
+	// IndexBuildHNSW rebuilds the HNSW graph from scratch
+	IndexBuildHNSW IndexBuildKind = "hnsw"
+	// IndexBuildIVF retrains the IVF centroids and refills the inverted lists
+	IndexBuildIVF IndexBuildKind = "ivf"
+	// IndexBuildQuantizer retrains the quantizer and rebuilds the HNSW graph with it
+	IndexBuildQuantizer IndexBuildKind = "quantizer"
+	// IndexBuildDiskANN writes a new DiskANN file that folds in the vectors
+	// added and deleted since the last one
+	IndexBuildDiskANN IndexBuildKind = "diskann"
+)
+
+// IndexBuildPhase identifies the stage a background index build is in
+type IndexBuildPhase string
+
+const (
+	// IndexBuildPhaseReading means vectors are read from a consistent snapshot
+	IndexBuildPhaseReading IndexBuildPhase = "reading"
+	// IndexBuildPhaseTraining means the quantizer or IVF centroids are being trained
+	IndexBuildPhaseTraining IndexBuildPhase = "training"
+	// IndexBuildPhaseBuilding means snapshot vectors are inserted into the new index
+	IndexBuildPhaseBuilding IndexBuildPhase = "building"
+	// IndexBuildPhaseReplaying means writes made during the build are applied
+	IndexBuildPhaseReplaying IndexBuildPhase = "replaying"
+	// IndexBuildPhaseDone means the new index has been swapped in
+	IndexBuildPhaseDone IndexBuildPhase = "done"
+	// IndexBuildPhaseFailed means the build stopped with an error; the old index stays
+	IndexBuildPhaseFailed IndexBuildPhase = "failed"
+	// IndexBuildPhaseCanceled means the build was canceled; the old index stays
+	IndexBuildPhaseCanceled IndexBuildPhase = "canceled"
+)
+
+// indexBuildChunkSize is the number of vectors inserted between progress
+// reports and cancellation checks
+const indexBuildChunkSize = 1000
+
+// maxIndexBuildReplayPasses bounds the replay passes made without the store
+// lock; whatever is left is replayed while swapping
+const maxIndexBuildReplayPasses = 3
+
+// IndexBuildOptions configures a background index build
+type IndexBuildOptions struct {
+	// Kind selects what to build (default IndexBuildHNSW, or IndexBuildIVF
+	// and IndexBuildDiskANN for IVF and DiskANN stores without HNSW)
+	Kind IndexBuildKind
+	// NumCentroids sets the IVF centroid count (default: IVF.NCentroids)
+	NumCentroids int
+	// OnProgress is called at each phase change and after every inserted chunk.
+	// It runs on the build goroutine and must not call back into the build.
+	OnProgress func(IndexBuildStatus)
+}
+
+// IndexBuildStatus reports the state of a background index build
+type IndexBuildStatus struct {
+	Kind      IndexBuildKind  `json:"kind"`
+	Phase     IndexBuildPhase `json:"phase"`
+	Total     int64           `json:"total"`     // Vectors in the snapshot
+	Processed int64           `json:"processed"` // Vectors read or inserted in the current phase
+	Replayed  int64           `json:"replayed"`  // Writes made during the build and replayed
+	StartedAt time.Time       `json:"started_at"`
+	Elapsed   time.Duration   `json:"elapsed"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Running reports whether the build has not reached a final phase yet
+func (st IndexBuildStatus) Running() bool {
+	switch st.Phase {
+	case IndexBuildPhaseDone, IndexBuildPhaseFailed, IndexBuildPhaseCanceled:
+		return false
+	}
+	return true
+}
+
+// IndexBuild is a running or finished background index build.
+//
+// The build reads vectors inside a read transaction, so it sees one
+// consistent snapshot while writes go on. IDs written after the build started
+// are recorded and replayed from the embeddings table into the new index,
+// the last of them while holding the store lock, and the new index is then
+// swapped in. Queries keep using the old index until the swap.
+type IndexBuild struct {
+	store  *SQLiteStore
+	opts   IndexBuildOptions
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	status IndexBuildStatus
+	err    error
+	dirty  map[string]struct{} // IDs written since the build started; nil once tracking stopped
+}
+
+// RebuildIndexAsync starts building a new index in the background and
+// returns immediately. The build stops when ctx is canceled or Cancel is
+// called. Only one build runs at a time.
+func (s *SQLiteStore) RebuildIndexAsync(ctx context.Context, opts IndexBuildOptions) (*IndexBuild, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("rebuild_index", ErrStoreClosed)
+	}
+	return s.startIndexBuild(ctx, opts)
+}
+
+// startIndexBuild validates opts and starts the build. The caller holds s.mu.
+func (s *SQLiteStore) startIndexBuild(ctx context.Context, opts IndexBuildOptions) (*IndexBuild, error) {
+	if opts.Kind == "" {
+		opts.Kind = IndexBuildHNSW
+		if s.config.IndexType == IndexTypeIVF && !s.config.HNSW.Enabled {
+			opts.Kind = IndexBuildIVF
+		} else if s.config.IndexType == IndexTypeDiskANN && !s.config.HNSW.Enabled {
+			opts.Kind = IndexBuildDiskANN
+		}
+	}
+	switch opts.Kind {
+	case IndexBuildHNSW:
+		if !s.config.HNSW.Enabled {
+			return nil, wrapError("rebuild_index", fmt.Errorf("%w: HNSW is not enabled", ErrInvalidConfig))
+		}
+	case IndexBuildQuantizer:
+		if !s.config.HNSW.Enabled || !s.config.Quantization.Enabled {
+			return nil, wrapError("rebuild_index", fmt.Errorf("%w: quantized HNSW is not enabled", ErrInvalidConfig))
+		}
+	case IndexBuildIVF:
+		if s.config.IndexType != IndexTypeIVF {
+			return nil, wrapError("rebuild_index", fmt.Errorf("%w: index type is not IVF", ErrInvalidConfig))
+		}
+		if opts.NumCentroids <= 0 {
+			opts.NumCentroids = s.config.IVF.NCentroids
+			if opts.NumCentroids <= 0 {
+				opts.NumCentroids = DefaultIVFConfig().NCentroids
+			}
+		}
+	case IndexBuildDiskANN:
+		if s.config.IndexType != IndexTypeDiskANN {
+			return nil, wrapError("rebuild_index", fmt.Errorf("%w: index type is not DiskANN", ErrInvalidConfig))
+		}
+	default:
+		return nil, wrapError("rebuild_index", fmt.Errorf("%w: unknown index build kind %q", ErrInvalidConfig, opts.Kind))
+	}
+	if s.config.VectorDim <= 0 {
+		return nil, wrapError("rebuild_index", fmt.Errorf("vector dimension not set"))
+	}
+
+	buildCtx, cancel := context.WithCancel(ctx)
+	b := &IndexBuild{
+		store:  s,
+		opts:   opts,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		dirty:  make(map[string]struct{}),
+		status: IndexBuildStatus{
+			Kind:      opts.Kind,
+			Phase:     IndexBuildPhaseReading,
+			StartedAt: time.Now(),
+		},
+	}
+
+	// Track writes before the snapshot is taken: a write either committed
+	// before the snapshot or is recorded for replay
+	prev := s.indexBuild.Load()
+	if prev != nil && prev.Status().Running() {
+		cancel()
+		return nil, wrapError("rebuild_index", ErrIndexBuildRunning)
+	}
+	if !s.indexBuild.CompareAndSwap(prev, b) {
+		cancel()
+		return nil, wrapError("rebuild_index", ErrIndexBuildRunning)
+	}
+
+	var tx *sql.Tx
+	var err error
+	if opts.Kind == IndexBuildDiskANN {
+		// DiskANN builds page through the table instead: a read transaction
+		// held for the whole build would block writers
+		err = s.db.QueryRowContext(buildCtx, "SELECT COUNT(*) FROM embeddings").Scan(&b.status.Total)
+	} else if tx, err = s.db.BeginTx(buildCtx, nil); err == nil {
+		// The first read pins the snapshot for the rest of the transaction
+		err = tx.QueryRowContext(buildCtx, "SELECT COUNT(*) FROM embeddings").Scan(&b.status.Total)
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}
+	if err != nil {
+		cancel()
+		b.finish(fmt.Errorf("failed to open snapshot: %w", err))
+		close(b.done)
+		return nil, wrapError("rebuild_index", b.err)
+	}
+
+	s.logger.Info("background index build started", "kind", opts.Kind, "vectors", b.status.Total)
+	go b.run(buildCtx, tx)
+
+	return b, nil
+}
+
+// IndexBuildStatus returns the status of the running or most recent
+// background index build, and false if none was started
+func (s *SQLiteStore) IndexBuildStatus() (IndexBuildStatus, bool) {
+	b := s.indexBuild.Load()
+	if b == nil {
+		return IndexBuildStatus{}, false
+	}
+	return b.Status(), true
+}
+
+// Status returns a snapshot of the build's progress
+func (b *IndexBuild) Status() IndexBuildStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := b.status
+	if st.Running() {
+		st.Elapsed = time.Since(st.StartedAt)
+	}
+	return st
+}
+
+// Cancel stops the build; the store keeps its current index
+func (b *IndexBuild) Cancel() {
+	b.cancel()
+}
+
+// Done is closed once the build has finished, failed or been canceled
+func (b *IndexBuild) Done() <-chan struct{} {
+	return b.done
+}
+
+// Wait blocks until the build ends and returns its error, if any.
+// A canceled build returns an error matching context.Canceled.
+func (b *IndexBuild) Wait() error {
+	<-b.done
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// track records IDs written while the build runs
+func (b *IndexBuild) track(ids []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dirty == nil {
+		return
+	}
+	for _, id := range ids {
+		b.dirty[id] = struct{}{}
+	}
+}
+
+// takeDirty returns the IDs recorded so far and starts a new set. With stop,
+// tracking ends and later writes are left to the live index.
+func (b *IndexBuild) takeDirty(stop bool) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ids := make([]string, 0, len(b.dirty))
+	for id := range b.dirty {
+		ids = append(ids, id)
+	}
+	if stop {
+		b.dirty = nil
+	} else {
+		b.dirty = make(map[string]struct{})
+	}
+	return ids
+}
+
+// report updates the status and notifies OnProgress
+func (b *IndexBuild) report(update func(*IndexBuildStatus)) {
+	b.mu.Lock()
+	update(&b.status)
+	b.mu.Unlock()
+
+	if b.opts.OnProgress != nil {
+		b.opts.OnProgress(b.Status())
+	}
+}
+
+// finish records the outcome of the build and stops tracking writes
+func (b *IndexBuild) finish(err error) {
+	b.mu.Lock()
+	b.err = err
+	b.dirty = nil
+	b.status.Elapsed = time.Since(b.status.StartedAt)
+	switch {
+	case err == nil:
+		b.status.Phase = IndexBuildPhaseDone
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		b.status.Phase = IndexBuildPhaseCanceled
+		b.status.Error = err.Error()
+	default:
+		b.status.Phase = IndexBuildPhaseFailed
+		b.status.Error = err.Error()
+	}
+	b.mu.Unlock()
+
+	if b.opts.OnProgress != nil {
+		b.opts.OnProgress(b.Status())
+	}
+}
+
+// run performs the build on its own goroutine
+func (b *IndexBuild) run(ctx context.Context, tx *sql.Tx) {
+	defer close(b.done)
+	defer b.cancel()
+
+	s := b.store
+	err := b.build(ctx, tx)
+	if err != nil {
+		s.logger.Warn("background index build stopped", "kind", b.opts.Kind, "error", err)
+	} else {
+		s.logger.Info("background index build complete", "kind", b.opts.Kind, "vectors", b.status.Total)
+	}
+	b.finish(err)
+
+	// A DiskANN build saved its state while swapping
+	if err == nil && b.opts.Kind != IndexBuildDiskANN {
+		// Persist the new index without blocking queries
+		saveCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.saveIndexSnapshot(saveCtx); err != nil {
+			s.logger.Warn("failed to save index snapshot after background build", "error", err)
+		}
+	}
+}
+
+// build reads the snapshot, builds the new index, replays concurrent writes
+// and swaps the result in
+func (b *IndexBuild) build(ctx context.Context, tx *sql.Tx) error {
+	s := b.store
+
+	if b.opts.Kind == IndexBuildDiskANN {
+		return b.buildDiskANN(ctx)
+	}
+
+	ids, vectors, err := b.readSnapshot(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		hnsw      *index.HNSW
+		ivf       *index.IVFIndex
+		quantizer index.Quantizer
+		add       func(id string, vec []float32) error
+		remove    func(id string)
+	)
+
+	switch b.opts.Kind {
+	case IndexBuildHNSW, IndexBuildQuantizer:
+		s.mu.RLock()
+		quantizer = s.quantizer
+		s.mu.RUnlock()
+
+		if b.opts.Kind == IndexBuildQuantizer {
+			b.report(func(st *IndexBuildStatus) { st.Phase = IndexBuildPhaseTraining; st.Processed = 0 })
+			if quantizer, err = s.newQuantizer(); err != nil {
+				return fmt.Errorf("failed to create quantizer: %w", err)
+			}
+			if len(vectors) == 0 {
+				return fmt.Errorf("no vectors available for quantizer training")
+			}
+			// Same sample size as TrainQuantizer
+			if err := trainQuantizer(quantizer, vectors[:min(len(vectors), 1000)]); err != nil {
+				return fmt.Errorf("failed to train quantizer: %w", err)
+			}
+		}
+
+		hnsw = index.NewHNSW(s.config.HNSW.M, s.config.HNSW.EfConstruction, index.CosineDistance)
+		if quantizer != nil {
+			hnsw.SetQuantizer(quantizer)
+		}
+		add = hnsw.Insert
+		remove = func(id string) { _ = hnsw.Delete(id) }
+
+	case IndexBuildIVF:
+		b.report(func(st *IndexBuildStatus) { st.Phase = IndexBuildPhaseTraining; st.Processed = 0 })
+		ivf = index.NewIVFIndex(s.config.VectorDim, b.opts.NumCentroids)
+		if s.config.IVF.NProbe > 0 {
+			ivf.SetNProbe(s.config.IVF.NProbe)
+		}
+		if len(vectors) == 0 {
+			return fmt.Errorf("no vectors found for training")
+		}
+		if err := ivf.Train(vectors); err != nil {
+			return fmt.Errorf("failed to train IVF index: %w", err)
+		}
+		add = ivf.Add
+		remove = func(id string) { _ = ivf.Delete(id) }
+	}
+
+	b.report(func(st *IndexBuildStatus) { st.Phase = IndexBuildPhaseBuilding; st.Processed = 0 })
+	for start := 0; start < len(ids); start += indexBuildChunkSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := min(start+indexBuildChunkSize, len(ids))
+		for i := start; i < end; i++ {
+			if err := add(ids[i], vectors[i]); err != nil {
+				s.logger.Warn("failed to add vector during background build", "id", ids[i], "error", err)
+			}
+		}
+		b.report(func(st *IndexBuildStatus) { st.Processed = int64(end) })
+	}
+
+	// Catch up with concurrent writes without holding the store lock
+	b.report(func(st *IndexBuildStatus) { st.Phase = IndexBuildPhaseReplaying; st.Processed = 0 })
+	replay := func(ids []string) error {
+		if err := b.replay(ctx, ids, add, remove); err != nil {
+			return err
+		}
+		b.report(func(st *IndexBuildStatus) { st.Replayed += int64(len(ids)) })
+		return nil
+	}
+	for pass := 0; pass < maxIndexBuildReplayPasses; pass++ {
+		pending := b.takeDirty(false)
+		if len(pending) == 0 {
+			break
+		}
+		if err := replay(pending); err != nil {
+			return err
+		}
+	}
+
+	// Replay the rest and swap while writers are held off
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := replay(b.takeDirty(true)); err != nil {
+		return err
+	}
+
+	switch b.opts.Kind {
+	case IndexBuildHNSW:
+		s.hnswIndex = hnsw
+	case IndexBuildQuantizer:
+		s.quantizer = quantizer
+		s.hnswIndex = hnsw
+	case IndexBuildIVF:
+		s.ivfIndex = ivf
+	}
+	return nil
+}
+
+// readSnapshot reads every vector visible to the build's read transaction
+func (b *IndexBuild) readSnapshot(ctx context.Context, tx *sql.Tx) ([]string, [][]float32, error) {
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, vector FROM embeddings")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query vectors: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	var vectors [][]float32
+	for rows.Next() {
+		var id string
+		var vectorBytes []byte
+		if err := rows.Scan(&id, &vectorBytes); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan vector: %w", err)
+		}
+		vec, err := encoding.DecodeVector(vectorBytes)
+		if err != nil {
+			b.store.logger.Warn("failed to decode vector during background build", "id", id, "error", err)
+			continue
+		}
+		ids = append(ids, id)
+		vectors = append(vectors, vec)
+
+		if len(ids)%indexBuildChunkSize == 0 {
+			n := int64(len(ids))
+			b.report(func(st *IndexBuildStatus) { st.Processed = n })
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return ids, vectors, nil
+}
+
+// replay applies the current state of the given IDs to the new index: rows
+// that still exist are (re)inserted and the rest removed
+func (b *IndexBuild) replay(ctx context.Context, ids []string, add func(string, []float32) error, remove func(string)) error {
+	const chunkSize = 500
+	for start := 0; start < len(ids); start += chunkSize {
+		end := min(start+chunkSize, len(ids))
+		embs, err := b.store.fetchEmbeddingsByIDs(ctx, ids[start:end])
+		if err != nil {
+			return fmt.Errorf("failed to fetch replayed vectors: %w", err)
+		}
+
+		found := make(map[string]bool, len(embs))
+		for _, emb := range embs {
+			found[emb.ID] = true
+			remove(emb.ID)
+			if err := add(emb.ID, emb.Vector); err != nil {
+				b.store.logger.Warn("failed to replay vector into new index", "id", emb.ID, "error", err)
+			}
+		}
+		for _, id := range ids[start:end] {
+			if !found[id] {
+				remove(id)
+			}
+		}
+	}
+	return nil
+}
+
+// trackIndexWrite records IDs written while a background build runs, so
+// they reach the new index before it is swapped in
+func (s *SQLiteStore) trackIndexWrite(ids ...string) {
+	if b := s.indexBuild.Load(); b != nil {
+		b.track(ids)
+	}
+}
+
+// stopIndexBuild cancels a running background build and waits for it.
+// The caller must not hold s.mu.
+func (s *SQLiteStore) stopIndexBuild() {
+	if b := s.indexBuild.Load(); b != nil {
+		b.Cancel()
+		<-b.done
+	}
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestRebuildIndexAsync(t *testing.T) {
+	ctx := context.Background()
+
+	newStore := func(t *testing.T, configure func(*Config)) *SQLiteStore {
+		config := DefaultConfig()
+		config.Path = filepath.Join(t.TempDir(), "index_build.db")
+		config.VectorDim = 16
+		config.AutoSave.Enabled = false
+		config.HNSW.Enabled = true
+		if configure != nil {
+			configure(&config)
+		}
+		store := openTestStore(t, config)
+		t.Cleanup(func() { store.Close() })
+		return store
+	}
+
+	randomVec := newRandomVec(5, 16)
+	fill := func(t *testing.T, store *SQLiteStore, n int) {
+		embs := make([]*Embedding, n)
+		for i := range embs {
+			embs[i] = &Embedding{ID: fmt.Sprintf("vec_%d", i), Vector: randomVec()}
+		}
+		if err := store.UpsertBatch(ctx, embs); err != nil {
+			t.Fatalf("UpsertBatch failed: %v", err)
+		}
+	}
+
+	t.Run("HNSWReplaysConcurrentWrites", func(t *testing.T) {
+		store := newStore(t, nil)
+		fill(t, store, 1500)
+		old := store.hnswIndex
+
+		var phases []IndexBuildPhase
+		var writeErr, busyErr error
+		opts := IndexBuildOptions{
+			OnProgress: func(st IndexBuildStatus) {
+				if len(phases) == 0 || phases[len(phases)-1] != st.Phase {
+					phases = append(phases, st.Phase)
+				}
+				// Write once while the snapshot is being indexed
+				if st.Phase == IndexBuildPhaseBuilding && st.Processed == indexBuildChunkSize {
+					writeErr = errors.Join(
+						store.Upsert(ctx, &Embedding{ID: "late", Vector: randomVec()}),
+						store.Upsert(ctx, &Embedding{ID: "vec_7", Vector: randomVec()}),
+						store.Delete(ctx, "vec_0"),
+					)
+					_, busyErr = store.RebuildIndexAsync(ctx, IndexBuildOptions{})
+				}
+			},
+		}
+
+		build, err := store.RebuildIndexAsync(ctx, opts)
+		if err != nil {
+			t.Fatalf("RebuildIndexAsync failed: %v", err)
+		}
+		if err := build.Wait(); err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if writeErr != nil {
+			t.Fatalf("Concurrent write failed: %v", writeErr)
+		}
+		if !errors.Is(busyErr, ErrIndexBuildRunning) {
+			t.Errorf("Expected ErrIndexBuildRunning for a second build, got %v", busyErr)
+		}
+
+		if store.hnswIndex == old {
+			t.Fatal("Expected the new index to be swapped in")
+		}
+		if size := store.hnswIndex.Size(); size != 1500 {
+			t.Errorf("Expected 1500 vectors after replay, got %d", size)
+		}
+		if node, ok := store.hnswIndex.Nodes["vec_0"]; ok && !node.Deleted {
+			t.Error("Deleted vector should not be in the new index")
+		}
+		expectTop(t, store, "late")
+		expectTop(t, store, "vec_7")
+
+		want := []IndexBuildPhase{IndexBuildPhaseReading, IndexBuildPhaseBuilding, IndexBuildPhaseReplaying, IndexBuildPhaseDone}
+		if fmt.Sprint(phases) != fmt.Sprint(want) {
+			t.Errorf("Expected phases %v, got %v", want, phases)
+		}
+
+		status, ok := store.IndexBuildStatus()
+		if !ok || status.Phase != IndexBuildPhaseDone || status.Running() {
+			t.Errorf("Expected finished status, got %+v", status)
+		}
+		if status.Total != 1500 || status.Replayed != 3 {
+			t.Errorf("Expected 1500 snapshot vectors and 3 replayed writes, got %+v", status)
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		store := newStore(t, nil)
+		fill(t, store, 1500)
+		old := store.hnswIndex
+
+		buildCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		build, err := store.RebuildIndexAsync(buildCtx, IndexBuildOptions{
+			OnProgress: func(st IndexBuildStatus) {
+				if st.Phase == IndexBuildPhaseBuilding {
+					cancel()
+				}
+			},
+		})
+		if err != nil {
+			t.Fatalf("RebuildIndexAsync failed: %v", err)
+		}
+		if err := build.Wait(); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		if store.hnswIndex != old {
+			t.Error("Canceled build should keep the old index")
+		}
+		if status, _ := store.IndexBuildStatus(); status.Phase != IndexBuildPhaseCanceled {
+			t.Errorf("Expected canceled status, got %+v", status)
+		}
+
+		// A new build may start once the previous one has ended
+		build, err = store.RebuildIndexAsync(ctx, IndexBuildOptions{})
+		if err != nil {
+			t.Fatalf("RebuildIndexAsync after cancel failed: %v", err)
+		}
+		if err := build.Wait(); err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		expectTop(t, store, "vec_42")
+	})
+
+	t.Run("IVF", func(t *testing.T) {
+		store := newStore(t, func(c *Config) {
+			c.IndexType = IndexTypeIVF
+			c.HNSW.Enabled = false
+			c.IVF.NProbe = 4
+		})
+		fill(t, store, 500)
+
+		build, err := store.RebuildIndexAsync(ctx, IndexBuildOptions{NumCentroids: 8})
+		if err != nil {
+			t.Fatalf("RebuildIndexAsync failed: %v", err)
+		}
+		if err := build.Wait(); err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if store.ivfIndex == nil || !store.ivfIndex.Trained || store.ivfIndex.NCentroids != 8 {
+			t.Fatal("Expected a trained IVF index with 8 centroids")
+		}
+		if size := store.ivfIndex.Size(); size != 500 {
+			t.Errorf("Expected 500 vectors, got %d", size)
+		}
+		expectTop(t, store, "vec_250")
+	})
+
+	t.Run("Quantizer", func(t *testing.T) {
+		store := newStore(t, func(c *Config) {
+			c.Quantization.Enabled = true
+		})
+		fill(t, store, 300)
+		old := store.quantizer
+
+		build, err := store.RebuildIndexAsync(ctx, IndexBuildOptions{Kind: IndexBuildQuantizer})
+		if err != nil {
+			t.Fatalf("RebuildIndexAsync failed: %v", err)
+		}
+		if err := build.Wait(); err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if store.quantizer == nil || store.quantizer == old {
+			t.Fatal("Expected a newly trained quantizer")
+		}
+		if store.hnswIndex.Quantizer != store.quantizer {
+			t.Error("Expected the new index to use the new quantizer")
+		}
+		expectTop(t, store, "vec_100")
+	})
+
+	t.Run("InvalidKind", func(t *testing.T) {
+		store := newStore(t, nil)
+		if _, err := store.RebuildIndexAsync(ctx, IndexBuildOptions{Kind: IndexBuildIVF}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Expected ErrInvalidConfig for IVF on an HNSW store, got %v", err)
+		}
+		if _, ok := store.IndexBuildStatus(); ok {
+			t.Error("Expected no build status before any build")
+		}
+	})
+}
@@ -425,6 +425,22 @@ func (s *SQLiteStore) ExportIndex(ctx context.Context, filepath string) error {
 		return nil
 	}
 
+	// Export IVF-PQ index if trained
+	if s.ivfpqIndex != nil {
+		if err := s.ivfpqIndex.Save(file); err != nil {
+			return wrapError("export_index", fmt.Errorf("failed to save IVF-PQ: %w", err))
+		}
+		return nil
+	}
+
+	// Export LSH, hybrid or multi-index if built
+	if s.vectorIndex != nil {
+		if err := s.vectorIndex.Save(file); err != nil {
+			return wrapError("export_index", fmt.Errorf("failed to save vector index: %w", err))
+		}
+		return nil
+	}
+
 	return wrapError("export_index", fmt.Errorf("no index to export"))
 }
 
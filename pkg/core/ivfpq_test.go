@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestIVFPQIndex(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "ivfpq.db")
+
+	config := DefaultConfig()
+	config.Path = dbPath
+	config.VectorDim = 16
+	config.IndexType = IndexTypeIVFPQ
+	config.IVFPQ.NCentroids = 8
+	config.IVFPQ.NProbe = 4
+	config.AutoSave.Enabled = false
+
+	ctx := context.Background()
+	randomVec := newRandomVec(9, config.VectorDim)
+
+	store := openTestStore(t, config)
+	for i := 0; i < 500; i++ {
+		if err := store.Upsert(ctx, &Embedding{ID: fmt.Sprintf("vec_%d", i), Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+	if store.ivfpqIndex != nil {
+		t.Fatal("IVF-PQ index should not exist before training")
+	}
+	expectTop(t, store, "vec_3") // Linear search until trained
+
+	t.Run("Train", func(t *testing.T) {
+		if err := store.TrainIndex(ctx, 0); err != nil {
+			t.Fatalf("TrainIndex failed: %v", err)
+		}
+		if store.ivfpqIndex == nil || store.ivfpqIndex.Size() != 500 {
+			t.Fatal("Expected all vectors encoded after training")
+		}
+		if store.ivfpqIndex.NCentroids != 8 {
+			t.Errorf("Expected 8 lists, got %d", store.ivfpqIndex.NCentroids)
+		}
+		expectTop(t, store, "vec_250")
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		if err := store.Upsert(ctx, &Embedding{ID: "extra", Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		expectTop(t, store, "extra")
+
+		if err := store.Delete(ctx, "vec_0"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if store.ivfpqIndex.Contains("vec_0") {
+			t.Error("Deleted vector should leave the index")
+		}
+	})
+
+	t.Run("WithoutRerank", func(t *testing.T) {
+		store.config.IVFPQ.Rerank = false
+		defer func() { store.config.IVFPQ.Rerank = true }()
+
+		emb, _ := store.GetByID(ctx, "vec_42")
+		results, err := store.Search(ctx, emb.Vector, SearchOptions{TopK: 5})
+		if err != nil || len(results) == 0 {
+			t.Fatalf("Search failed: %v, %v", results, err)
+		}
+		found := false
+		for i, r := range results {
+			found = found || r.ID == "vec_42"
+			if i > 0 && r.Score > results[i-1].Score {
+				t.Error("Expected results sorted by score")
+			}
+			exact := CosineSimilarity(emb.Vector, r.Vector)
+			if math.Abs(r.Score-exact) > 0.2 {
+				t.Errorf("Approximate score %.3f too far from exact %.3f", r.Score, exact)
+			}
+		}
+		if !found {
+			t.Errorf("Expected vec_42 among results, got %v", results)
+		}
+	})
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	t.Run("Snapshot", func(t *testing.T) {
+		store := openTestStore(t, config)
+		if store.ivfpqIndex == nil || store.ivfpqIndex.Size() != 500 {
+			t.Fatal("Expected IVF-PQ index restored from snapshot")
+		}
+		// Bypass the index so the snapshot no longer matches the table
+		if _, err := store.db.ExecContext(ctx, "DELETE FROM embeddings WHERE id = 'vec_1'"); err != nil {
+			t.Fatalf("Failed to delete row: %v", err)
+		}
+		store.ivfpqIndex = nil // Keep the stale snapshot on close
+		store.Close()
+
+		store = openTestStore(t, config)
+		defer store.Close()
+		if store.ivfpqIndex.Contains("vec_1") || store.ivfpqIndex.Size() != 499 {
+			t.Errorf("Expected reconciled index with 499 vectors, got %d", store.ivfpqIndex.Size())
+		}
+		expectTop(t, store, "extra")
+	})
+}
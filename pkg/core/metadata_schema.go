@@ -0,0 +1,464 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+)
+
+// MetadataFieldType is the value type a schema field accepts.
+// Metadata is stored as strings, so types describe what the string must parse as.
+type MetadataFieldType string
+
+const (
+	FieldTypeString MetadataFieldType = "string"
+	FieldTypeInt    MetadataFieldType = "int"
+	FieldTypeFloat  MetadataFieldType = "float"
+	FieldTypeBool   MetadataFieldType = "bool"
+	FieldTypeTime   MetadataFieldType = "time" // RFC 3339
+)
+
+// MetadataField declares a single metadata key
+type MetadataField struct {
+	Name     string            `json:"name"`
+	Type     MetadataFieldType `json:"type"`
+	Required bool              `json:"required,omitempty"`
+	Enum     []string          `json:"enum,omitempty"`
+	Default  *string           `json:"default,omitempty"`
+}
+
+// MetadataSchema declares the metadata shape of a collection.
+// Unless AllowExtraFields is set, keys that are not declared are rejected,
+// which catches typos that would otherwise make filters silently miss.
+type MetadataSchema struct {
+	Fields           []MetadataField `json:"fields"`
+	AllowExtraFields bool            `json:"allow_extra_fields,omitempty"`
+	Version          int             `json:"version,omitempty"` // Assigned by the store
+}
+
+// WithMetadataSchema attaches a metadata schema to a new collection
+func WithMetadataSchema(schema *MetadataSchema) CollectionOption {
+	return func(o *collectionOptions) {
+		o.schema = schema
+	}
+}
+
+// Field returns the declared field with the given name
+func (ms *MetadataSchema) Field(name string) (MetadataField, bool) {
+	for _, f := range ms.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return MetadataField{}, false
+}
+
+// Validate checks that the schema itself is well formed
+func (ms *MetadataSchema) Validate() error {
+	seen := make(map[string]bool, len(ms.Fields))
+	for i := range ms.Fields {
+		f := &ms.Fields[i]
+		if f.Name == "" {
+			return fmt.Errorf("schema field at index %d has no name", i)
+		}
+		if strings.ContainsAny(f.Name, ".[]\"' ") {
+			return fmt.Errorf("schema field '%s' contains unsupported characters", f.Name)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("schema field '%s' declared more than once", f.Name)
+		}
+		seen[f.Name] = true
+
+		if f.Type == "" {
+			f.Type = FieldTypeString
+		}
+		switch f.Type {
+		case FieldTypeString, FieldTypeInt, FieldTypeFloat, FieldTypeBool, FieldTypeTime:
+		default:
+			return fmt.Errorf("schema field '%s' has unknown type '%s'", f.Name, f.Type)
+		}
+
+		for _, v := range f.Enum {
+			if err := checkFieldType(f.Type, v); err != nil {
+				return fmt.Errorf("schema field '%s' enum value '%s': %w", f.Name, v, err)
+			}
+		}
+		if f.Default != nil {
+			if err := f.check(*f.Default); err != nil {
+				return fmt.Errorf("schema field '%s' default: %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply validates metadata against the schema and returns a copy with
+// defaults filled in. Violations wrap ErrSchemaViolation.
+func (ms *MetadataSchema) Apply(metadata map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(metadata)+len(ms.Fields))
+	for k, v := range metadata {
+		out[k] = v
+	}
+
+	var problems []string
+	for _, f := range ms.Fields {
+		value, ok := out[f.Name]
+		if !ok {
+			if f.Default != nil {
+				out[f.Name] = *f.Default
+			} else if f.Required {
+				problems = append(problems, fmt.Sprintf("missing required field '%s'", f.Name))
+			}
+			continue
+		}
+		if err := f.check(value); err != nil {
+			problems = append(problems, fmt.Sprintf("field '%s': %v", f.Name, err))
+		}
+	}
+
+	if !ms.AllowExtraFields {
+		var extra []string
+		for k := range metadata {
+			if _, ok := ms.Field(k); !ok {
+				extra = append(extra, k)
+			}
+		}
+		sort.Strings(extra)
+		for _, k := range extra {
+			msg := fmt.Sprintf("undeclared field '%s'", k)
+			if suggestion := ms.closestField(k); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean '%s'?)", suggestion)
+			}
+			problems = append(problems, msg)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSchemaViolation, strings.Join(problems, "; "))
+	}
+	if metadata == nil && len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// check validates a single value against the field's type and enum
+func (f MetadataField) check(value string) error {
+	if err := checkFieldType(f.Type, value); err != nil {
+		return err
+	}
+	if len(f.Enum) > 0 {
+		for _, allowed := range f.Enum {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("value '%s' not in [%s]", value, strings.Join(f.Enum, ", "))
+	}
+	return nil
+}
+
+func checkFieldType(t MetadataFieldType, value string) error {
+	var err error
+	switch t {
+	case FieldTypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case FieldTypeFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case FieldTypeBool:
+		_, err = strconv.ParseBool(value)
+	case FieldTypeTime:
+		_, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		return fmt.Errorf("value '%s' is not a valid %s", value, t)
+	}
+	return nil
+}
+
+// closestField suggests a declared field for a likely typo
+func (ms *MetadataSchema) closestField(name string) string {
+	best, bestDist := "", 3
+	for _, f := range ms.Fields {
+		if d := editDistance(name, f.Name); d < bestDist {
+			best, bestDist = f.Name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// MigrateCollectionSchema replaces a collection's schema with a compatible one.
+// Migrations may add optional or defaulted fields, make fields optional, widen
+// enums and types, or allow extra fields; anything that could invalidate stored
+// metadata is rejected. Defaults of new fields are backfilled into existing
+// embeddings. A collection without a schema accepts any schema its current
+// data satisfies.
+func (s *SQLiteStore) MigrateCollectionSchema(ctx context.Context, name string, schema *MetadataSchema) (*MetadataSchema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, wrapError("migrate_schema", ErrStoreClosed)
+	}
+	if schema == nil {
+		return nil, wrapError("migrate_schema", fmt.Errorf("schema cannot be nil"))
+	}
+
+	next := cloneMetadataSchema(schema)
+	if err := next.Validate(); err != nil {
+		return nil, wrapError("migrate_schema", err)
+	}
+
+	resolved, err := s.lookupAliasTarget(ctx, name)
+	if err != nil {
+		return nil, wrapError("migrate_schema", err)
+	}
+	collectionID, err := s.collectionIDByName(ctx, resolved)
+	if err != nil {
+		return nil, wrapError("migrate_schema", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, wrapError("migrate_schema", fmt.Errorf("failed to start transaction: %w", err))
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			s.logger.Warn("failed to rollback schema migration", "error", err)
+		}
+	}()
+
+	current, err := loadMetadataSchema(ctx, tx, collectionID)
+	if err != nil {
+		return nil, wrapError("migrate_schema", err)
+	}
+
+	if current != nil {
+		if err := checkSchemaCompatible(current, next); err != nil {
+			return nil, wrapError("migrate_schema", fmt.Errorf("%w: %v", ErrSchemaViolation, err))
+		}
+		next.Version = current.Version + 1
+	} else {
+		next.Version = 1
+	}
+
+	if err := backfillMetadataSchema(ctx, tx, collectionID, current, next); err != nil {
+		return nil, wrapError("migrate_schema", err)
+	}
+
+	if err := saveMetadataSchema(ctx, tx, collectionID, next); err != nil {
+		return nil, wrapError("migrate_schema", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapError("migrate_schema", fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	s.logger.Info("collection schema migrated", "collection", resolved, "version", next.Version)
+	return next, nil
+}
+
+// checkSchemaCompatible ensures next only adds to or relaxes prev
+func checkSchemaCompatible(prev, next *MetadataSchema) error {
+	if prev.AllowExtraFields && !next.AllowExtraFields {
+		return fmt.Errorf("cannot disallow extra fields once allowed")
+	}
+
+	for _, old := range prev.Fields {
+		nf, ok := next.Field(old.Name)
+		if !ok {
+			if !next.AllowExtraFields {
+				return fmt.Errorf("cannot drop field '%s' while extra fields are disallowed", old.Name)
+			}
+			continue
+		}
+		if nf.Type != old.Type && nf.Type != FieldTypeString && !(old.Type == FieldTypeInt && nf.Type == FieldTypeFloat) {
+			return fmt.Errorf("cannot change field '%s' from %s to %s", old.Name, old.Type, nf.Type)
+		}
+		if nf.Required && !old.Required && nf.Default == nil {
+			return fmt.Errorf("cannot make field '%s' required without a default", old.Name)
+		}
+		if len(nf.Enum) > 0 {
+			if len(old.Enum) == 0 {
+				return fmt.Errorf("cannot add an enum to existing field '%s'", old.Name)
+			}
+			for _, v := range old.Enum {
+				if nf.check(v) != nil {
+					return fmt.Errorf("cannot remove enum value '%s' from field '%s'", v, old.Name)
+				}
+			}
+		}
+	}
+
+	for _, f := range next.Fields {
+		if _, ok := prev.Field(f.Name); !ok && f.Required && f.Default == nil {
+			return fmt.Errorf("new field '%s' must be optional or have a default", f.Name)
+		}
+	}
+	return nil
+}
+
+// backfillMetadataSchema fills defaults into stored metadata and, for a
+// collection that had no schema, verifies the existing data conforms
+func backfillMetadataSchema(ctx context.Context, tx *sql.Tx, collectionID int, prev, next *MetadataSchema) error {
+	needsScan := prev == nil
+	for _, f := range next.Fields {
+		if f.Default != nil {
+			needsScan = true
+			break
+		}
+	}
+	if !needsScan {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, metadata FROM embeddings WHERE collection_id = ?", collectionID)
+	if err != nil {
+		return fmt.Errorf("failed to scan embeddings: %w", err)
+	}
+
+	updates := make(map[string]string)
+	for rows.Next() {
+		var id string
+		var metadataJSON sql.NullString
+		if err := rows.Scan(&id, &metadataJSON); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan embedding: %w", err)
+		}
+		metadata, err := encoding.DecodeMetadata(metadataJSON.String)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("embedding '%s': %w", id, err)
+		}
+		applied, err := next.Apply(metadata)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("existing embedding '%s' does not satisfy schema: %w", id, err)
+		}
+		if len(applied) != len(metadata) {
+			encoded, err := encoding.EncodeMetadata(applied)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			updates[id] = encoded
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to scan embeddings: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to scan embeddings: %w", err)
+	}
+
+	for id, metadataJSON := range updates {
+		if _, err := tx.ExecContext(ctx, "UPDATE embeddings SET metadata = ? WHERE id = ?", metadataJSON, id); err != nil {
+			return fmt.Errorf("failed to backfill defaults for '%s': %w", id, err)
+		}
+	}
+	return nil
+}
+
+// queryRower is satisfied by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// loadMetadataSchema returns the collection's schema, or nil if it has none
+func loadMetadataSchema(ctx context.Context, q queryRower, collectionID int) (*MetadataSchema, error) {
+	var data string
+	var version int
+	err := q.QueryRowContext(ctx, "SELECT schema, version FROM collection_schemas WHERE collection_id = ?", collectionID).Scan(&data, &version)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metadata schema: %w", err)
+	}
+	return decodeMetadataSchema(data, version)
+}
+
+func decodeMetadataSchema(data string, version int) (*MetadataSchema, error) {
+	schema := &MetadataSchema{}
+	if err := json.Unmarshal([]byte(data), schema); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata schema: %w", err)
+	}
+	schema.Version = version
+	return schema, nil
+}
+
+func saveMetadataSchema(ctx context.Context, tx *sql.Tx, collectionID int, schema *MetadataSchema) error {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata schema: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO collection_schemas (collection_id, schema, version, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	`, collectionID, string(data), schema.Version)
+	if err != nil {
+		return fmt.Errorf("failed to save metadata schema: %w", err)
+	}
+	return nil
+}
+
+func cloneMetadataSchema(schema *MetadataSchema) *MetadataSchema {
+	clone := &MetadataSchema{AllowExtraFields: schema.AllowExtraFields}
+	clone.Fields = make([]MetadataField, len(schema.Fields))
+	for i, f := range schema.Fields {
+		clone.Fields[i] = f
+		clone.Fields[i].Enum = append([]string(nil), f.Enum...)
+		if f.Default != nil {
+			def := *f.Default
+			clone.Fields[i].Default = &def
+		}
+	}
+	return clone
+}
+
+// applyMetadataSchema validates metadata for a write into collectionID.
+// The optional cache avoids reloading the schema for every row of a batch.
+func (s *SQLiteStore) applyMetadataSchema(ctx context.Context, collectionID int, metadata map[string]string, cache map[int]*MetadataSchema) (map[string]string, error) {
+	schema, ok := cache[collectionID]
+	if !ok {
+		var err error
+		schema, err = loadMetadataSchema(ctx, s.db, collectionID)
+		if err != nil {
+			return nil, err
+		}
+		if cache != nil {
+			cache[collectionID] = schema
+		}
+	}
+	if schema == nil {
+		return metadata, nil
+	}
+	return schema.Apply(metadata)
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMetadataSchema(t *testing.T) {
+	dbPath := "test_metadata_schema.db"
+	defer os.Remove(dbPath)
+
+	store, err := New(dbPath, 3)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.Init(ctx); err != nil {
+		t.Fatalf("Failed to initialize store: %v", err)
+	}
+
+	lang := "en"
+	schema := &MetadataSchema{
+		Fields: []MetadataField{
+			{Name: "category", Type: FieldTypeString, Required: true, Enum: []string{"news", "blog"}},
+			{Name: "views", Type: FieldTypeInt},
+			{Name: "lang", Type: FieldTypeString, Default: &lang},
+		},
+	}
+
+	t.Run("CreateWithSchema", func(t *testing.T) {
+		if _, err := store.CreateCollection(ctx, "articles", 3, WithMetadataSchema(schema)); err != nil {
+			t.Fatalf("Failed to create collection: %v", err)
+		}
+
+		collection, err := store.GetCollection(ctx, "articles")
+		if err != nil {
+			t.Fatalf("Failed to get collection: %v", err)
+		}
+		if collection.Schema == nil || len(collection.Schema.Fields) != 3 || collection.Schema.Version != 1 {
+			t.Fatalf("Expected schema v1 with 3 fields, got %+v", collection.Schema)
+		}
+
+		bad := &MetadataSchema{Fields: []MetadataField{{Name: "n", Type: FieldTypeInt, Enum: []string{"x"}}}}
+		if _, err := store.CreateCollection(ctx, "broken", 3, WithMetadataSchema(bad)); err == nil {
+			t.Error("Expected error for enum value that does not match field type")
+		}
+	})
+
+	t.Run("UpsertValidation", func(t *testing.T) {
+		emb := &Embedding{ID: "a1", Collection: "articles", Vector: []float32{1, 0, 0}, Content: "a",
+			Metadata: map[string]string{"category": "news", "views": "10"}}
+		if err := store.Upsert(ctx, emb); err != nil {
+			t.Fatalf("Valid upsert failed: %v", err)
+		}
+
+		stored, err := store.GetByID(ctx, "a1")
+		if err != nil {
+			t.Fatalf("Failed to get embedding: %v", err)
+		}
+		if stored.Metadata["lang"] != "en" {
+			t.Errorf("Expected default lang=en, got %q", stored.Metadata["lang"])
+		}
+
+		cases := map[string]map[string]string{
+			"typo":     {"categroy": "news"},
+			"enum":     {"category": "video"},
+			"type":     {"category": "news", "views": "many"},
+			"required": {"views": "1"},
+		}
+		for name, metadata := range cases {
+			err := store.Upsert(ctx, &Embedding{ID: "bad-" + name, Collection: "articles", Vector: []float32{1, 0, 0}, Content: "x", Metadata: metadata})
+			if !errors.Is(err, ErrSchemaViolation) {
+				t.Errorf("%s: expected ErrSchemaViolation, got %v", name, err)
+			}
+			var storeErr *StoreError
+			if !errors.As(err, &storeErr) || storeErr.Op != "upsert" {
+				t.Errorf("%s: expected StoreError for upsert, got %v", name, err)
+			}
+		}
+
+		err = store.Upsert(ctx, &Embedding{ID: "typo", Collection: "articles", Vector: []float32{1, 0, 0}, Content: "x",
+			Metadata: map[string]string{"category": "news", "categroy": "news"}})
+		if err == nil || !strings.Contains(err.Error(), "did you mean 'category'") {
+			t.Errorf("Expected typo suggestion, got %v", err)
+		}
+
+		batch := []*Embedding{
+			{ID: "b1", Collection: "articles", Vector: []float32{0, 1, 0}, Content: "b1", Metadata: map[string]string{"category": "blog"}},
+			{ID: "b2", Collection: "articles", Vector: []float32{0, 0, 1}, Content: "b2", Metadata: map[string]string{"category": "bogus"}},
+		}
+		if err := store.UpsertBatch(ctx, batch); !errors.Is(err, ErrSchemaViolation) {
+			t.Errorf("Expected batch to fail with ErrSchemaViolation, got %v", err)
+		}
+		if _, err := store.GetByID(ctx, "b1"); err == nil {
+			t.Error("Failed batch should not have written any rows")
+		}
+	})
+
+	t.Run("Migrate", func(t *testing.T) {
+		tier := "free"
+		relaxed := &MetadataSchema{
+			Fields: []MetadataField{
+				{Name: "category", Type: FieldTypeString, Enum: []string{"news", "blog", "video"}},
+				{Name: "views", Type: FieldTypeFloat},
+				{Name: "lang", Type: FieldTypeString, Default: &lang},
+				{Name: "tier", Type: FieldTypeString, Required: true, Default: &tier},
+			},
+		}
+		migrated, err := store.MigrateCollectionSchema(ctx, "articles", relaxed)
+		if err != nil {
+			t.Fatalf("Compatible migration failed: %v", err)
+		}
+		if migrated.Version != 2 {
+			t.Errorf("Expected version 2, got %d", migrated.Version)
+		}
+
+		stored, err := store.GetByID(ctx, "a1")
+		if err != nil {
+			t.Fatalf("Failed to get embedding: %v", err)
+		}
+		if stored.Metadata["tier"] != "free" {
+			t.Errorf("Expected backfilled tier=free, got %q", stored.Metadata["tier"])
+		}
+
+		if err := store.Upsert(ctx, &Embedding{ID: "v1", Collection: "articles", Vector: []float32{1, 0, 0}, Content: "v",
+			Metadata: map[string]string{"category": "video", "views": "1.5"}}); err != nil {
+			t.Errorf("Upsert under relaxed schema failed: %v", err)
+		}
+
+		incompatible := []*MetadataSchema{
+			{Fields: relaxed.Fields[1:]}, // drops category
+			{Fields: []MetadataField{relaxed.Fields[0], {Name: "views", Type: FieldTypeInt}, relaxed.Fields[2], relaxed.Fields[3]}},
+			{Fields: append(append([]MetadataField{}, relaxed.Fields...), MetadataField{Name: "owner", Required: true})},
+		}
+		for i, next := range incompatible {
+			if _, err := store.MigrateCollectionSchema(ctx, "articles", next); !errors.Is(err, ErrSchemaViolation) {
+				t.Errorf("case %d: expected incompatible migration to fail, got %v", i, err)
+			}
+		}
+	})
+
+	t.Run("MigrateUnschemaed", func(t *testing.T) {
+		if _, err := store.CreateCollection(ctx, "loose", 3); err != nil {
+			t.Fatalf("Failed to create collection: %v", err)
+		}
+		if err := store.Upsert(ctx, &Embedding{ID: "l1", Collection: "loose", Vector: []float32{1, 0, 0}, Content: "l",
+			Metadata: map[string]string{"kind": "x"}}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+
+		strict := &MetadataSchema{Fields: []MetadataField{{Name: "kind", Type: FieldTypeInt}}}
+		if _, err := store.MigrateCollectionSchema(ctx, "loose", strict); err == nil {
+			t.Error("Expected migration to fail when existing data violates schema")
+		}
+
+		fits := &MetadataSchema{Fields: []MetadataField{{Name: "kind", Enum: []string{"x", "y"}}}}
+		if _, err := store.MigrateCollectionSchema(ctx, "loose", fits); err != nil {
+			t.Errorf("Expected migration to succeed for conforming data: %v", err)
+		}
+	})
+}
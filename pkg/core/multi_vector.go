@@ -95,9 +95,9 @@ func (s *SQLiteStore) UpsertMultiVector(ctx context.Context, entity *MultiVector
 		}
 		
 		_, err = tx.ExecContext(ctx, `
-			INSERT OR REPLACE INTO embeddings (id, collection_id, vector, content, doc_id, metadata, created_at)
-			VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
-		`, compositeID, 1, vectorBytes, entity.Content, entity.ID, string(metadataJSON))
+			INSERT OR REPLACE INTO embeddings (id, collection_id, vector, content, doc_id, metadata, revision, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, COALESCE((SELECT revision FROM embeddings WHERE id = ?), 0) + 1, CURRENT_TIMESTAMP)
+		`, compositeID, 1, vectorBytes, entity.Content, entity.ID, string(metadataJSON), compositeID)
 		
 		if err != nil {
 			return wrapError("upsert_multi_vector", err)
@@ -117,6 +117,7 @@ func (s *SQLiteStore) UpsertMultiVector(ctx context.Context, entity *MultiVector
 
 // SearchMultiVector performs multi-vector search
 func (s *SQLiteStore) SearchMultiVector(ctx context.Context, queryVectors map[string][]float32, opts MultiVectorSearchOptions) ([]ScoredEmbedding, error) {
+	s.applyExternalWrites(ctx)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
@@ -150,7 +151,7 @@ func (s *SQLiteStore) SearchMultiVector(ctx context.Context, queryVectors map[st
 		}
 		fieldOpts.Filter["_vector_field"] = fieldName
 		
-		results, err := s.Search(ctx, queryVector, fieldOpts)
+		results, err := s.search(ctx, queryVector, fieldOpts)
 		if err != nil {
 			continue
 		}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrefixIndex(t *testing.T) {
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "prefix.db")
+	config.VectorDim = 64
+	config.Prefix.RecallSampleRate = 1
+	config.AutoSave.Enabled = false
+
+	ctx := context.Background()
+
+	// Like Matryoshka embeddings, the leading dimensions carry most of the signal
+	rng := rand.New(rand.NewSource(17))
+	randomVec := func() []float32 {
+		vec := make([]float32, 64)
+		for j := range vec {
+			scale := float32(0.1)
+			if j < 16 {
+				scale = 1
+			}
+			vec[j] = (rng.Float32()*2 - 1) * scale
+		}
+		return vec
+	}
+
+	store := openTestStore(t, config)
+	if _, err := store.CreateCollection(ctx, "wide", 64, WithPrefixIndex(64)); err == nil {
+		t.Error("Expected an error for a prefix as wide as the vectors")
+	}
+	if _, err := store.CreateCollection(ctx, "docs", 64, WithPrefixIndex(16)); err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+
+	batch := make([]*Embedding, 0, 400)
+	for i := 0; i < 400; i++ {
+		batch = append(batch, &Embedding{ID: fmt.Sprintf("doc_%d", i), Collection: "docs", Vector: randomVec()})
+	}
+	for i := 0; i < 50; i++ {
+		batch = append(batch, &Embedding{ID: fmt.Sprintf("other_%d", i), Vector: randomVec()})
+	}
+	if err := store.UpsertBatch(ctx, batch); err != nil {
+		t.Fatalf("UpsertBatch failed: %v", err)
+	}
+
+	expectDocsTop := func(store *SQLiteStore, id string) {
+		t.Helper()
+		emb, err := store.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID(%s) failed: %v", id, err)
+		}
+		results, err := store.Search(ctx, emb.Vector, SearchOptions{Collection: "docs", TopK: 5})
+		if err != nil || len(results) == 0 || results[0].ID != id {
+			t.Fatalf("Expected %s as top result, got %v, %v", id, results, err)
+		}
+		if results[0].Score < 0.9999 {
+			t.Errorf("Expected the full-dimension score of 1, got %f", results[0].Score)
+		}
+	}
+
+	t.Run("Search", func(t *testing.T) {
+		p := store.prefixIndexFor("docs")
+		if p == nil || p.hnsw.Size() != 400 {
+			t.Fatal("Expected only the collection's vectors in its prefix index")
+		}
+		expectDocsTop(store, "doc_0")
+		expectDocsTop(store, "doc_123")
+
+		results, err := store.Search(ctx, randomVec(), SearchOptions{Collection: "docs", TopK: 10, RescoreDepth: 10})
+		if err != nil || len(results) != 10 {
+			t.Fatalf("Expected 10 results, got %d, %v", len(results), err)
+		}
+		for _, r := range results {
+			if r.Collection != "docs" {
+				t.Errorf("Expected results from docs, got %s", r.Collection)
+			}
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			if _, err := store.Search(ctx, randomVec(), SearchOptions{Collection: "docs", TopK: 10, RescoreDepth: 100}); err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+		}
+		stats, err := store.Stats(ctx)
+		if err != nil {
+			t.Fatalf("Stats failed: %v", err)
+		}
+		if len(stats.PrefixSearch) != 1 {
+			t.Fatalf("Expected stats for one prefix index, got %v", stats.PrefixSearch)
+		}
+		ps := stats.PrefixSearch[0]
+		if ps.Collection != "docs" || ps.PrefixDims != 16 || ps.Vectors != 400 || ps.Queries != 23 {
+			t.Errorf("Unexpected prefix stats %+v", ps)
+		}
+		if ps.RecallSamples != 23 || ps.Recall < 0.9 {
+			t.Errorf("Expected high sampled recall, got %f over %d", ps.Recall, ps.RecallSamples)
+		}
+		if ps.AvgLatency <= 0 || ps.AvgRescoreLatency <= 0 || ps.AvgRescoreLatency > ps.AvgLatency {
+			t.Errorf("Unexpected latencies %v / %v", ps.AvgLatency, ps.AvgRescoreLatency)
+		}
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		if err := store.Upsert(ctx, &Embedding{ID: "doc_5", Collection: "docs", Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		expectDocsTop(store, "doc_5")
+
+		// Moving an embedding out of the collection drops it from the prefix index
+		if err := store.Upsert(ctx, &Embedding{ID: "doc_6", Vector: randomVec()}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		if err := store.Delete(ctx, "doc_7"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if n := store.prefixIndexFor("docs").hnsw.Size(); n != 398 {
+			t.Errorf("Expected 398 vectors in the prefix index, got %d", n)
+		}
+	})
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	t.Run("Reopen", func(t *testing.T) {
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		collection, err := store.GetCollection(ctx, "docs")
+		if err != nil || collection.PrefixDims != 16 {
+			t.Fatalf("Expected prefix dimensions restored, got %v, %v", collection, err)
+		}
+		if n := store.prefixIndexFor("docs").hnsw.Size(); n != 398 {
+			t.Errorf("Expected 398 vectors rebuilt, got %d", n)
+		}
+		expectDocsTop(store, "doc_250")
+
+		if err := store.DeleteCollection(ctx, "docs"); err != nil {
+			t.Fatalf("DeleteCollection failed: %v", err)
+		}
+		if stats, _ := store.Stats(ctx); len(stats.PrefixSearch) != 0 {
+			t.Error("Expected the prefix index dropped with its collection")
+		}
+	})
+}
@@ -0,0 +1,359 @@
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+// ProjectionMethod selects how a Projection is fitted
+type ProjectionMethod string
+
+const (
+	// ProjectionLeastSquares fits an unconstrained linear map by ridge-regularized least squares
+	ProjectionLeastSquares ProjectionMethod = "least_squares"
+	// ProjectionProcrustes fits the closest (semi-)orthogonal map, which
+	// preserves angles between source vectors and needs fewer samples
+	ProjectionProcrustes ProjectionMethod = "procrustes"
+)
+
+// minProjectionSamples is the fewest paired samples FitProjection accepts
+const minProjectionSamples = 2
+
+// Projection is a linear map from one embedding model's space into another's,
+// fitted from the same texts embedded by both models
+type Projection struct {
+	SourceDim  int              `json:"sourceDim"`
+	TargetDim  int              `json:"targetDim"`
+	Method     ProjectionMethod `json:"method"`
+	Weights    []float32        `json:"weights"`    // TargetDim x SourceDim, row-major
+	Samples    int              `json:"samples"`    // Paired samples the projection was fitted on
+	MeanCosine float64          `json:"meanCosine"` // Mean cosine between projected and target samples
+}
+
+// FitProjection fits a projection that maps each source vector onto the
+// target vector at the same index. Procrustes costs O(d^3) in the smaller
+// dimension and least squares O(d^3) in the source dimension.
+func FitProjection(source, target [][]float32, method ProjectionMethod) (*Projection, error) {
+	if len(source) != len(target) {
+		return nil, fmt.Errorf("got %d source and %d target samples", len(source), len(target))
+	}
+	if len(source) < minProjectionSamples {
+		return nil, fmt.Errorf("need at least %d paired samples, got %d", minProjectionSamples, len(source))
+	}
+
+	s, t := len(source[0]), len(target[0])
+	if s == 0 || t == 0 {
+		return nil, fmt.Errorf("samples must not be empty")
+	}
+	for i := range source {
+		if len(source[i]) != s || len(target[i]) != t {
+			return nil, fmt.Errorf("sample %d has dimensions %d -> %d, expected %d -> %d", i, len(source[i]), len(target[i]), s, t)
+		}
+	}
+
+	// Cross-covariance M = X^T Y (s x t)
+	m := make([]float64, s*t)
+	for i := range source {
+		for a, x := range source[i] {
+			if x == 0 {
+				continue
+			}
+			row := m[a*t : (a+1)*t]
+			for b, y := range target[i] {
+				row[b] += float64(x) * float64(y)
+			}
+		}
+	}
+
+	var w []float64 // s x t, maps a source row vector to a target row vector
+	switch method {
+	case ProjectionLeastSquares, "":
+		method = ProjectionLeastSquares
+		var err error
+		if w, err = fitLeastSquares(source, m, s, t); err != nil {
+			return nil, err
+		}
+	case ProjectionProcrustes:
+		w = fitProcrustes(m, s, t)
+	default:
+		return nil, fmt.Errorf("unknown projection method %q", method)
+	}
+
+	p := &Projection{
+		SourceDim: s,
+		TargetDim: t,
+		Method:    method,
+		Weights:   make([]float32, t*s),
+		Samples:   len(source),
+	}
+	for a := 0; a < s; a++ {
+		for b := 0; b < t; b++ {
+			p.Weights[b*s+a] = float32(w[a*t+b])
+		}
+	}
+
+	for i := range source {
+		p.MeanCosine += float64(CosineSimilarity(p.project(source[i]), target[i]))
+	}
+	p.MeanCosine /= float64(len(source))
+	return p, nil
+}
+
+// Apply maps a source vector into the target space and normalizes it
+func (p *Projection) Apply(vector []float32) ([]float32, error) {
+	if len(vector) != p.SourceDim {
+		return nil, fmt.Errorf("vector length %d doesn't match projection source dimension %d", len(vector), p.SourceDim)
+	}
+	return normalizeVector(p.project(vector)), nil
+}
+
+// project multiplies vector by the weights without normalizing
+func (p *Projection) project(vector []float32) []float32 {
+	out := make([]float32, p.TargetDim)
+	for b := range out {
+		row := p.Weights[b*p.SourceDim : (b+1)*p.SourceDim]
+		var sum float32
+		for a, x := range vector {
+			sum += row[a] * x
+		}
+		out[b] = sum
+	}
+	return out
+}
+
+// fitLeastSquares solves (X^T X + lambda I) W = X^T Y for W. The ridge term
+// keeps the system solvable with fewer samples than source dimensions.
+func fitLeastSquares(source [][]float32, m []float64, s, t int) ([]float64, error) {
+	gram := make([]float64, s*s)
+	for _, x := range source {
+		for a, xa := range x {
+			if xa == 0 {
+				continue
+			}
+			row := gram[a*s : (a+1)*s]
+			for b := a; b < s; b++ {
+				row[b] += float64(xa) * float64(x[b])
+			}
+		}
+	}
+	var trace float64
+	for a := 0; a < s; a++ {
+		trace += gram[a*s+a]
+		for b := a + 1; b < s; b++ {
+			gram[b*s+a] = gram[a*s+b]
+		}
+	}
+	lambda := 1e-3 * trace / float64(s)
+	if lambda == 0 {
+		lambda = 1e-9
+	}
+	for a := 0; a < s; a++ {
+		gram[a*s+a] += lambda
+	}
+
+	if err := choleskyDecompose(gram, s); err != nil {
+		return nil, err
+	}
+	w := make([]float64, s*t)
+	col := make([]float64, s)
+	for b := 0; b < t; b++ {
+		for a := 0; a < s; a++ {
+			col[a] = m[a*t+b]
+		}
+		choleskySolve(gram, s, col)
+		for a := 0; a < s; a++ {
+			w[a*t+b] = col[a]
+		}
+	}
+	return w, nil
+}
+
+// fitProcrustes returns the polar factor U V^T of M = U S V^T, computed as
+// M (M^T M)^-1/2, or (M M^T)^-1/2 M when the source side is smaller
+func fitProcrustes(m []float64, s, t int) []float64 {
+	w := make([]float64, s*t)
+	if t <= s {
+		g := make([]float64, t*t) // M^T M
+		for a := 0; a < s; a++ {
+			row := m[a*t : (a+1)*t]
+			for i := 0; i < t; i++ {
+				for j := i; j < t; j++ {
+					g[i*t+j] += row[i] * row[j]
+				}
+			}
+		}
+		invSqrt := symmetricInverseSqrt(g, t)
+		for a := 0; a < s; a++ {
+			row := m[a*t : (a+1)*t]
+			for j := 0; j < t; j++ {
+				var sum float64
+				for i := 0; i < t; i++ {
+					sum += row[i] * invSqrt[i*t+j]
+				}
+				w[a*t+j] = sum
+			}
+		}
+		return w
+	}
+
+	g := make([]float64, s*s) // M M^T
+	for i := 0; i < s; i++ {
+		for j := i; j < s; j++ {
+			var sum float64
+			for b := 0; b < t; b++ {
+				sum += m[i*t+b] * m[j*t+b]
+			}
+			g[i*s+j] = sum
+		}
+	}
+	invSqrt := symmetricInverseSqrt(g, s)
+	for i := 0; i < s; i++ {
+		for a := 0; a < s; a++ {
+			f := invSqrt[i*s+a]
+			if f == 0 {
+				continue
+			}
+			for b := 0; b < t; b++ {
+				w[i*t+b] += f * m[a*t+b]
+			}
+		}
+	}
+	return w
+}
+
+// symmetricInverseSqrt returns the pseudo-inverse square root of the
+// symmetric positive semi-definite n x n matrix whose upper triangle is in a
+func symmetricInverseSqrt(a []float64, n int) []float64 {
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			a[j*n+i] = a[i*n+j]
+		}
+	}
+	values, vectors := jacobiEigen(a, n)
+
+	var largest float64
+	for _, v := range values {
+		largest = math.Max(largest, v)
+	}
+	out := make([]float64, n*n)
+	for k, v := range values {
+		// Directions without signal stay out of the map rather than blowing up
+		if v <= largest*1e-10 {
+			continue
+		}
+		f := 1 / math.Sqrt(v)
+		for i := 0; i < n; i++ {
+			vi := vectors[i*n+k] * f
+			if vi == 0 {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				out[i*n+j] += vi * vectors[j*n+k]
+			}
+		}
+	}
+	return out
+}
+
+// jacobiEigen diagonalizes the symmetric n x n matrix a in place with cyclic
+// Jacobi rotations. It returns the eigenvalues and the eigenvectors as the
+// columns of a row-major matrix.
+func jacobiEigen(a []float64, n int) ([]float64, []float64) {
+	v := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		v[i*n+i] = 1
+	}
+
+	for sweep := 0; sweep < 50; sweep++ {
+		var off, diag float64
+		for i := 0; i < n; i++ {
+			diag += a[i*n+i] * a[i*n+i]
+			for j := i + 1; j < n; j++ {
+				off += a[i*n+j] * a[i*n+j]
+			}
+		}
+		if off <= 1e-22*diag || off == 0 {
+			break
+		}
+
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := a[p*n+q]
+				if math.Abs(apq) < 1e-300 {
+					continue
+				}
+				theta := (a[q*n+q] - a[p*n+p]) / (2 * apq)
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+
+				for k := 0; k < n; k++ {
+					akp, akq := a[k*n+p], a[k*n+q]
+					a[k*n+p] = c*akp - s*akq
+					a[k*n+q] = s*akp + c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p*n+k], a[q*n+k]
+					a[p*n+k] = c*apk - s*aqk
+					a[q*n+k] = s*apk + c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k*n+p], v[k*n+q]
+					v[k*n+p] = c*vkp - s*vkq
+					v[k*n+q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = a[i*n+i]
+	}
+	return values, v
+}
+
+// choleskyDecompose replaces the symmetric positive definite n x n matrix a
+// with its lower-triangular Cholesky factor
+func choleskyDecompose(a []float64, n int) error {
+	for j := 0; j < n; j++ {
+		d := a[j*n+j]
+		for k := 0; k < j; k++ {
+			d -= a[j*n+k] * a[j*n+k]
+		}
+		if d <= 0 {
+			return fmt.Errorf("projection system is not positive definite")
+		}
+		d = math.Sqrt(d)
+		a[j*n+j] = d
+		for i := j + 1; i < n; i++ {
+			sum := a[i*n+j]
+			for k := 0; k < j; k++ {
+				sum -= a[i*n+k] * a[j*n+k]
+			}
+			a[i*n+j] = sum / d
+		}
+	}
+	return nil
+}
+
+// choleskySolve solves L L^T x = b in place, with L from choleskyDecompose
+func choleskySolve(l []float64, n int, b []float64) {
+	for i := 0; i < n; i++ {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= l[i*n+k] * b[k]
+		}
+		b[i] = sum / l[i*n+i]
+	}
+	for i := n - 1; i >= 0; i-- {
+		sum := b[i]
+		for k := i + 1; k < n; k++ {
+			sum -= l[k*n+i] * b[k]
+		}
+		b[i] = sum / l[i*n+i]
+	}
+}
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// modelPair simulates two embedding models: the new model emits sourceDim
+// vectors and the old one a fixed linear map of them into targetDim
+type modelPair struct {
+	rng       *rand.Rand
+	sourceDim int
+	targetDim int
+	weights   [][]float32
+}
+
+func newModelPair(seed int64, sourceDim, targetDim int, orthogonal bool) *modelPair {
+	rng := rand.New(rand.NewSource(seed))
+	weights := make([][]float32, targetDim)
+	for i := range weights {
+		weights[i] = make([]float32, sourceDim)
+		for j := range weights[i] {
+			weights[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	if orthogonal {
+		// Gram-Schmidt over the columns gives a map that preserves angles
+		for j := 0; j < sourceDim; j++ {
+			for k := 0; k < j; k++ {
+				var dot float64
+				for i := range weights {
+					dot += float64(weights[i][j] * weights[i][k])
+				}
+				for i := range weights {
+					weights[i][j] -= float32(dot) * weights[i][k]
+				}
+			}
+			var norm float64
+			for i := range weights {
+				norm += float64(weights[i][j] * weights[i][j])
+			}
+			for i := range weights {
+				weights[i][j] /= float32(math.Sqrt(norm))
+			}
+		}
+	}
+	return &modelPair{rng: rng, sourceDim: sourceDim, targetDim: targetDim, weights: weights}
+}
+
+// sample returns the embeddings of one text by both models
+func (m *modelPair) sample() (source, target []float32) {
+	source = make([]float32, m.sourceDim)
+	for j := range source {
+		source[j] = float32(m.rng.NormFloat64())
+	}
+	target = make([]float32, m.targetDim)
+	for i, row := range m.weights {
+		for j, w := range row {
+			target[i] += w * source[j]
+		}
+	}
+	return source, target
+}
+
+func (m *modelPair) samples(n int) (source, target [][]float32) {
+	for i := 0; i < n; i++ {
+		s, t := m.sample()
+		source = append(source, s)
+		target = append(target, t)
+	}
+	return source, target
+}
+
+func TestFitProjection(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     ProjectionMethod
+		sourceDim  int
+		targetDim  int
+		orthogonal bool
+	}{
+		{"LeastSquares", ProjectionLeastSquares, 12, 16, false},
+		{"LeastSquaresShrink", ProjectionLeastSquares, 16, 12, false},
+		{"Procrustes", ProjectionProcrustes, 12, 16, true},
+		{"ProcrustesSquare", ProjectionProcrustes, 16, 16, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := newModelPair(21, tt.sourceDim, tt.targetDim, tt.orthogonal)
+			source, target := models.samples(200)
+
+			p, err := FitProjection(source, target, tt.method)
+			if err != nil {
+				t.Fatalf("FitProjection failed: %v", err)
+			}
+			if p.SourceDim != tt.sourceDim || p.TargetDim != tt.targetDim || p.Samples != 200 || p.Method != tt.method {
+				t.Errorf("Unexpected projection shape %+v", p)
+			}
+
+			// Shrinking loses directions, so only expanding maps are exact
+			want := 0.999
+			if tt.sourceDim > tt.targetDim {
+				want = 0.9
+			}
+			if p.MeanCosine < want {
+				t.Errorf("Expected mean cosine above %.3f, got %f", want, p.MeanCosine)
+			}
+
+			s, target1 := models.sample()
+			projected, err := p.Apply(s)
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if sim := CosineSimilarity(projected, target1); sim < want {
+				t.Errorf("Expected a held-out cosine above %.3f, got %f", want, sim)
+			}
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		models := newModelPair(22, 4, 6, false)
+		source, target := models.samples(10)
+		if _, err := FitProjection(source, target[:9], ProjectionLeastSquares); err == nil {
+			t.Error("Expected an error for unpaired samples")
+		}
+		if _, err := FitProjection(source, target, "svd"); err == nil {
+			t.Error("Expected an error for an unknown method")
+		}
+		if _, err := (&Projection{SourceDim: 4, TargetDim: 6}).Apply(make([]float32, 5)); err == nil {
+			t.Error("Expected an error for a wrong-length vector")
+		}
+	})
+}
+
+func TestLearnedProjectionPolicy(t *testing.T) {
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "projection.db")
+	config.VectorDim = 16
+	config.AutoDimAdapt = LearnedProjection
+	config.AutoSave.Enabled = false
+
+	ctx := context.Background()
+
+	// The collection holds old-model vectors; queries come from the new model
+	models := newModelPair(23, 12, 16, false)
+	store := openTestStore(t, config)
+	if _, err := store.CreateCollection(ctx, "docs", 16); err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+	var newModel [][]float32
+	for i := 0; i < 100; i++ {
+		s, target := models.sample()
+		newModel = append(newModel, s)
+		if err := store.Upsert(ctx, &Embedding{ID: fmt.Sprintf("doc_%d", i), Collection: "docs", Vector: target}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+
+	expectDocTop := func(store *SQLiteStore, i int) {
+		t.Helper()
+		results, err := store.Search(ctx, newModel[i], SearchOptions{Collection: "docs", TopK: 3})
+		if err != nil || len(results) == 0 || results[0].ID != fmt.Sprintf("doc_%d", i) {
+			t.Fatalf("Expected doc_%d as top result, got %v, %v", i, results, err)
+		}
+	}
+
+	if _, err := store.Search(ctx, newModel[0], SearchOptions{Collection: "docs", TopK: 3}); err == nil {
+		t.Fatal("Expected an error before a projection is trained")
+	}
+
+	source, target := models.samples(60)
+	p, err := store.TrainProjection(ctx, "docs", source, target, ProjectionLeastSquares)
+	if err != nil {
+		t.Fatalf("TrainProjection failed: %v", err)
+	}
+	if p.MeanCosine < 0.999 {
+		t.Errorf("Expected a near-exact fit, got mean cosine %f", p.MeanCosine)
+	}
+	if _, err := store.TrainProjection(ctx, "docs", source, source, ProjectionLeastSquares); err == nil {
+		t.Error("Expected an error for a same-dimension projection")
+	}
+
+	expectDocTop(store, 0)
+	expectDocTop(store, 42)
+
+	// New-model vectors are written through the projection too
+	s, want := models.sample()
+	if err := store.Upsert(ctx, &Embedding{ID: "new_doc", Collection: "docs", Vector: s}); err != nil {
+		t.Fatalf("Upsert with a new-model vector failed: %v", err)
+	}
+	stored, err := store.GetByID(ctx, "new_doc")
+	if err != nil || len(stored.Vector) != 16 || CosineSimilarity(stored.Vector, want) < 0.999 {
+		t.Errorf("Expected the projected vector stored, got %v, %v", stored, err)
+	}
+
+	// Projections are per collection
+	if err := store.Upsert(ctx, &Embedding{ID: "default_doc", Vector: s}); err == nil {
+		t.Error("Expected an error for the default collection without a projection")
+	}
+	store.Close()
+
+	store = openTestStore(t, config)
+	defer store.Close()
+	expectDocTop(store, 7)
+
+	loaded, err := store.GetProjection(ctx, "docs", 12)
+	if err != nil || loaded.TargetDim != 16 || len(loaded.Weights) != 16*12 || loaded.Method != ProjectionLeastSquares {
+		t.Fatalf("Expected the stored projection, got %+v, %v", loaded, err)
+	}
+
+	if err := store.DeleteProjection(ctx, "docs", 12); err != nil {
+		t.Fatalf("DeleteProjection failed: %v", err)
+	}
+	if _, err := store.Search(ctx, newModel[0], SearchOptions{Collection: "docs", TopK: 3}); err == nil {
+		t.Error("Expected an error after the projection is deleted")
+	}
+}
@@ -46,6 +46,7 @@ func DefaultRerankOptions() RerankOptions {
 
 // SearchWithReranker performs vector search and then reranks the results
 func (s *SQLiteStore) SearchWithReranker(ctx context.Context, queryVec []float32, queryText string, reranker Reranker, opts RerankOptions) ([]ScoredEmbedding, error) {
+	s.applyExternalWrites(ctx)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -55,7 +56,7 @@ func (s *SQLiteStore) SearchWithReranker(ctx context.Context, queryVec []float32
 
 	if reranker == nil {
 		// No reranker, return normal search results
-		return s.Search(ctx, queryVec, SearchOptions{TopK: opts.TopK})
+		return s.search(ctx, queryVec, SearchOptions{TopK: opts.TopK})
 	}
 
 	// Perform initial search with more candidates than needed
@@ -65,7 +66,7 @@ func (s *SQLiteStore) SearchWithReranker(ctx context.Context, queryVec []float32
 		initialTopK = 50
 	}
 
-	initialResults, err := s.Search(ctx, queryVec, SearchOptions{TopK: initialTopK})
+	initialResults, err := s.search(ctx, queryVec, SearchOptions{TopK: initialTopK})
 	if err != nil {
 		return nil, wrapError("search_rerank", fmt.Errorf("initial search failed: %w", err))
 	}
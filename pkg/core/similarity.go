@@ -34,6 +34,9 @@ var (
 
 // cosineSimilarity calculates cosine similarity between two vectors.
 // Returns a value between -1 and 1, where 1 means identical direction.
+// Sums are accumulated in float64: these scores are returned to callers, so
+// they keep full precision, while the float32 kernels in internal/simd only
+// rank candidates inside the indexes.
 func cosineSimilarity(a, b []float32) float64 {
 	if len(a) != len(b) {
 		return 0.0
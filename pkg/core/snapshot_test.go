@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestHNSWSnapshotPersistence(t *testing.T) {
+	dbPath := "test_hnsw_snapshot.db"
+	defer os.Remove(dbPath)
+
+	config := DefaultConfig()
+	config.Path = dbPath
+	config.VectorDim = 8
+	config.HNSW.Enabled = true
+
+	ctx := context.Background()
+	count := func(store *SQLiteStore, table string) int {
+		var n int
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE type = 'HNSW'", table)
+		if err := store.db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		return n
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	insert := func(store *SQLiteStore, from, to int) {
+		for i := from; i < to; i++ {
+			vec := make([]float32, config.VectorDim)
+			for j := range vec {
+				vec[j] = rng.Float32()
+			}
+			if err := store.Upsert(ctx, &Embedding{ID: fmt.Sprintf("vec_%d", i), Vector: vec}); err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+		}
+	}
+
+	store := openTestStore(t, config)
+	insert(store, 0, 400)
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	t.Run("FullSnapshot", func(t *testing.T) {
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		if count(store, "index_snapshots") != 1 {
+			t.Fatal("Expected a full HNSW snapshot")
+		}
+		if store.hnswIndex.PendingChanges() != 0 {
+			t.Errorf("Expected index restored from snapshot, got %d pending changes", store.hnswIndex.PendingChanges())
+		}
+		if store.hnswIndex.Size() != 400 {
+			t.Errorf("Expected 400 vectors, got %d", store.hnswIndex.Size())
+		}
+		for _, node := range store.hnswIndex.Nodes {
+			if node.Vector == nil {
+				t.Fatalf("Node %s has no vector after load", node.ID)
+			}
+		}
+
+		insert(store, 400, 402)
+		if err := store.Delete(ctx, "vec_0"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	})
+
+	t.Run("DeltaSnapshot", func(t *testing.T) {
+		store := openTestStore(t, config)
+		defer store.Close()
+
+		if count(store, "index_snapshot_deltas") != 1 {
+			t.Fatal("Expected small change set to be saved as a delta")
+		}
+		if store.hnswIndex.Size() != 401 {
+			t.Errorf("Expected 401 vectors, got %d", store.hnswIndex.Size())
+		}
+		if _, ok := store.hnswIndex.Nodes["vec_0"]; ok {
+			t.Error("Deleted vector should not be restored")
+		}
+
+		emb, err := store.GetByID(ctx, "vec_401")
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		results, err := store.Search(ctx, emb.Vector, SearchOptions{TopK: 1})
+		if err != nil || len(results) == 0 || results[0].ID != "vec_401" {
+			t.Errorf("Expected vec_401 from restored index, got %v, %v", results, err)
+		}
+	})
+
+	t.Run("ExternalWrites", func(t *testing.T) {
+		store := openTestStore(t, config)
+		// Bypass the index so the snapshot no longer matches the table
+		if _, err := store.db.ExecContext(ctx, "DELETE FROM embeddings WHERE id = 'vec_1'"); err != nil {
+			t.Fatalf("Failed to delete row: %v", err)
+		}
+		store.Close()
+
+		store = openTestStore(t, config)
+		defer store.Close()
+		if store.hnswIndex.Size() != 400 {
+			t.Errorf("Expected 400 vectors after reconciling, got %d", store.hnswIndex.Size())
+		}
+		if _, ok := store.hnswIndex.Nodes["vec_1"]; ok {
+			t.Error("Node without embedding should be dropped")
+		}
+	})
+
+	t.Run("CorruptSnapshot", func(t *testing.T) {
+		store := openTestStore(t, config)
+		var data []byte
+		if err := store.db.QueryRowContext(ctx, "SELECT data FROM index_snapshots WHERE type = 'HNSW'").Scan(&data); err != nil {
+			t.Fatalf("Failed to read snapshot: %v", err)
+		}
+		data[len(data)/2] ^= 0xFF
+		if _, err := store.db.ExecContext(ctx, "UPDATE index_snapshots SET data = ? WHERE type = 'HNSW'", data); err != nil {
+			t.Fatalf("Failed to corrupt snapshot: %v", err)
+		}
+		// Keep the corrupted snapshot: nothing changed, so Close writes nothing
+		store.Close()
+
+		store = openTestStore(t, config)
+		defer store.Close()
+		if store.hnswIndex.Size() != 400 {
+			t.Errorf("Expected rebuild with 400 vectors, got %d", store.hnswIndex.Size())
+		}
+		if store.hnswIndex.PendingChanges() != -1 {
+			t.Error("Expected rebuilt index to have no base snapshot")
+		}
+		if count(store, "index_snapshots")+count(store, "index_snapshot_deltas") != 0 {
+			t.Error("Expected corrupt snapshot to be discarded")
+		}
+	})
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"time"
 	"sync"
+	"sync/atomic"
 
 	"github.com/liliang-cn/cortexdb/v2/pkg/index"
+	"github.com/liliang-cn/cortexdb/v2/pkg/quantization"
 
 	_ "modernc.org/sqlite" // SQLite driver
 )
@@ -24,10 +26,21 @@ type SQLiteStore struct {
 	similarityFn   SimilarityFunc
 	hnswIndex      *index.HNSW            // HNSW index for fast search
 	ivfIndex       *index.IVFIndex        // IVF index for partitioned search
+	ivfpqIndex     *index.IVFPQIndex      // IVF-PQ index for compressed partitioned search
+	diskANN        *index.DiskANN         // Disk-resident DiskANN index
+	vectorIndex    index.PersistentIndex  // LSH, hybrid or multi-index ensemble
+	binaryIndex    *index.BinaryIndex     // Packed bit codes for binary retrieval
+	binaryQuantizer *quantization.BinaryQuantizer // Thresholds that produce the binary codes
+	prefixIndexes  map[int]*prefixIndex   // Truncated-vector indexes by collection ID
 	quantizer      index.Quantizer        // Vector quantizer
 	adapter        *DimensionAdapter      // Dimension adaptation handler
 	textSimilarity TextSimilarity         // Text similarity calculator
 	logger         Logger                 // Logger instance
+	indexBuild     atomic.Pointer[IndexBuild] // Running or most recent background index build
+	changeSeq      atomic.Int64           // Last embedding_changes row reflected in the indexes
+	lastChangeCheck atomic.Int64          // Unix nanos of the last change log check
+	writesSincePrune atomic.Int64         // Embedding writes since the change log was last pruned
+	localChanges   localChanges           // Writes applied here but not yet seen in the change log
 }
 
 // New creates a new SQLite vector store with the given configuration
@@ -84,6 +97,7 @@ func (s *SQLiteStore) SetAutoDimAdapt(policy AdaptPolicy) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.config.AutoDimAdapt = policy
+	s.adapter.SetPolicy(policy)
 }
 
 // GetDB returns the underlying database connection
@@ -126,8 +140,9 @@ func (s *SQLiteStore) Stats(ctx context.Context) (StoreStats, error) {
 	}
 
 	return StoreStats{
-		Count:      count,
-		Dimensions: s.config.VectorDim,
-		Size:       size,
+		Count:        count,
+		Dimensions:   s.config.VectorDim,
+		Size:         size,
+		PrefixSearch: s.prefixSearchStats(),
 	}, nil
 }
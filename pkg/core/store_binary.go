@@ -0,0 +1,289 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+	"github.com/liliang-cn/cortexdb/v2/pkg/index"
+	"github.com/liliang-cn/cortexdb/v2/pkg/quantization"
+)
+
+// Binary retrieval keeps one bit per dimension for every embedding in the
+// binary_code column and in a BinaryIndex. Bits are set where a component
+// exceeds its threshold: zero until TrainIndex learns per-dimension means,
+// which are saved in index_snapshots so codes stay comparable across restarts.
+
+// initBinaryIndex restores the binary thresholds and loads every stored code,
+// encoding rows that have none yet
+func (s *SQLiteStore) initBinaryIndex(ctx context.Context) error {
+	if s.config.IndexType != IndexTypeBinary || s.config.VectorDim <= 0 {
+		return nil
+	}
+
+	bq := quantization.NewSignBinaryQuantizer(s.config.VectorDim)
+	var data []byte
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM index_snapshots WHERE type = ?", "BINARY").Scan(&data)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return fmt.Errorf("failed to query index snapshot: %w", err)
+	default:
+		trained := &quantization.BinaryQuantizer{}
+		if err := trained.Load(bytes.NewReader(data)); err != nil || trained.Dimension != s.config.VectorDim {
+			// Codes made with the lost thresholds no longer match new queries
+			s.logger.Warn("discarding binary thresholds, re-encoding codes", "error", err)
+			if _, err := s.db.ExecContext(ctx, "UPDATE embeddings SET binary_code = NULL"); err != nil {
+				return fmt.Errorf("failed to reset binary codes: %w", err)
+			}
+			if err := s.discardIndexSnapshot(ctx, "BINARY"); err != nil {
+				return err
+			}
+		} else {
+			bq = trained
+		}
+	}
+
+	s.binaryQuantizer = bq
+	return s.buildBinaryIndex(ctx)
+}
+
+// ensureBinaryIndex sets up binary retrieval once the vector dimension has
+// been detected. The caller holds s.mu.
+func (s *SQLiteStore) ensureBinaryIndex(ctx context.Context) {
+	if s.config.IndexType != IndexTypeBinary || s.binaryIndex != nil || s.config.VectorDim <= 0 {
+		return
+	}
+	if err := s.initBinaryIndex(ctx); err != nil {
+		s.logger.Warn("failed to build binary index", "error", err)
+	}
+}
+
+// buildBinaryIndex encodes rows without a valid code and loads all codes into
+// a new BinaryIndex. The caller holds s.mu.
+func (s *SQLiteStore) buildBinaryIndex(ctx context.Context) error {
+	codeBytes := (s.config.VectorDim + 7) / 8
+
+	// Codes are 32x smaller than the vectors, so they are collected before writing
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, vector FROM embeddings WHERE binary_code IS NULL OR length(binary_code) != ?", codeBytes)
+	if err != nil {
+		return fmt.Errorf("failed to query unencoded vectors: %w", err)
+	}
+	var ids []string
+	var codes [][]byte
+	for rows.Next() {
+		var id string
+		var vectorBytes []byte
+		if err := rows.Scan(&id, &vectorBytes); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan vector: %w", err)
+		}
+		vec, err := encoding.DecodeVector(vectorBytes)
+		if err != nil {
+			s.logger.Warn("failed to decode vector during binary encoding", "id", id, "error", err)
+			continue
+		}
+		code, err := s.binaryQuantizer.Encode(vec)
+		if err != nil {
+			s.logger.Warn("failed to encode binary code", "id", id, "error", err)
+			continue
+		}
+		ids = append(ids, id)
+		codes = append(codes, code)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if len(ids) > 0 {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+		for i, id := range ids {
+			if _, err := tx.ExecContext(ctx, "UPDATE embeddings SET binary_code = ? WHERE id = ?", codes[i], id); err != nil {
+				return fmt.Errorf("failed to store binary code: %w", err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit binary codes: %w", err)
+		}
+		s.logger.Info("binary codes encoded", "vectors", len(ids))
+	}
+
+	idx := index.NewBinaryIndex(s.config.VectorDim, s.config.Binary.Substrings)
+	rows, err = s.db.QueryContext(ctx, "SELECT id, binary_code FROM embeddings WHERE binary_code IS NOT NULL")
+	if err != nil {
+		return fmt.Errorf("failed to query binary codes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var code []byte
+		if err := rows.Scan(&id, &code); err != nil {
+			return fmt.Errorf("failed to scan binary code: %w", err)
+		}
+		if err := idx.Add(id, code); err != nil {
+			s.logger.Warn("failed to add binary code", "id", id, "error", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	s.binaryIndex = idx
+	s.logger.Info("binary index loaded", "vectors", idx.Size(), "bytes", idx.MemoryUsage())
+	return nil
+}
+
+// trainBinaryIndex sets each dimension's threshold to its mean over the
+// stored vectors, then re-encodes every code. The caller holds s.mu.
+func (s *SQLiteStore) trainBinaryIndex(ctx context.Context) error {
+	if s.config.VectorDim <= 0 {
+		return fmt.Errorf("vector dimension not set")
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, vector FROM embeddings")
+	if err != nil {
+		return fmt.Errorf("failed to fetch vectors: %w", err)
+	}
+	defer rows.Close()
+
+	// Running sums keep training memory independent of the collection size
+	sums := make([]float64, s.config.VectorDim)
+	count := 0
+	for rows.Next() {
+		var id string
+		var vectorBytes []byte
+		if err := rows.Scan(&id, &vectorBytes); err != nil {
+			return fmt.Errorf("failed to scan vector: %w", err)
+		}
+		vec, err := encoding.DecodeVector(vectorBytes)
+		if err != nil || len(vec) != len(sums) {
+			s.logger.Warn("skipping vector during binary training", "id", id, "error", err)
+			continue
+		}
+		for d, v := range vec {
+			sums[d] += float64(v)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("no vectors found for training")
+	}
+
+	bq := quantization.NewSignBinaryQuantizer(s.config.VectorDim)
+	for d, sum := range sums {
+		bq.Threshold[d] = float32(sum / float64(count))
+	}
+
+	var buf bytes.Buffer
+	if err := bq.Save(&buf); err != nil {
+		return fmt.Errorf("failed to serialize binary thresholds: %w", err)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO index_snapshots (type, data, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+	`, "BINARY", buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to save binary thresholds: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE embeddings SET binary_code = NULL"); err != nil {
+		return fmt.Errorf("failed to reset binary codes: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit binary thresholds: %w", err)
+	}
+
+	s.binaryQuantizer = bq
+	s.logger.Info("binary thresholds trained", "vectors", count)
+	return s.buildBinaryIndex(ctx)
+}
+
+// writeBinaryCode stores the binary code of a written embedding through q,
+// in the same transaction as the vector
+func (s *SQLiteStore) writeBinaryCode(ctx context.Context, q sqlExecutor, emb *Embedding) error {
+	if s.config.IndexType != IndexTypeBinary || s.binaryQuantizer == nil {
+		return nil
+	}
+	code, err := s.binaryQuantizer.Encode(emb.Vector)
+	if err != nil {
+		return fmt.Errorf("failed to encode binary code: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE embeddings SET binary_code = ? WHERE id = ?", code, emb.ID); err != nil {
+		return fmt.Errorf("failed to store binary code: %w", err)
+	}
+	return nil
+}
+
+// addToBinaryIndex inserts or replaces a stored embedding's code in s.binaryIndex
+func (s *SQLiteStore) addToBinaryIndex(emb *Embedding) {
+	code, err := s.binaryQuantizer.Encode(emb.Vector)
+	if err == nil {
+		err = s.binaryIndex.Add(emb.ID, code)
+	}
+	if err != nil {
+		s.logger.Warn("failed to add vector to binary index", "id", emb.ID, "error", err)
+	}
+}
+
+// searchWithBinary finds candidates by Hamming distance and rescores the
+// top k x oversample with the exact vectors
+func (s *SQLiteStore) searchWithBinary(ctx context.Context, query []float32, opts SearchOptions) ([]ScoredEmbedding, error) {
+	if opts.TopK <= 0 {
+		opts.TopK = 10
+	}
+
+	factor := s.config.Binary.Oversample
+	if factor <= 0 {
+		factor = DefaultBinaryConfig().Oversample
+	}
+	if !s.config.Binary.Rescore {
+		factor = 2 // Headroom for collection and metadata filters
+	}
+
+	code, err := s.binaryQuantizer.Encode(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+	candidateIDs, distances, err := s.binaryIndex.Search(code, opts.TopK*factor)
+	if err != nil {
+		s.logger.Warn("binary search failed, falling back to linear search", "error", err)
+		return s.searchLinear(ctx, query, opts)
+	}
+	if len(candidateIDs) == 0 {
+		return s.searchLinear(ctx, query, opts)
+	}
+
+	candidates, err := s.fetchEmbeddingsByIDs(ctx, candidateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch candidates: %w", err)
+	}
+
+	if s.config.Binary.Rescore {
+		return s.processCandidates(query, candidates, opts)
+	}
+
+	// For sign codes a bit differs with probability angle/pi, so the share of
+	// differing bits estimates the cosine similarity without the vectors
+	approx := make(map[string]float64, len(candidateIDs))
+	for i, id := range candidateIDs {
+		approx[id] = math.Cos(math.Pi * float64(distances[i]) / float64(s.binaryIndex.Bits))
+	}
+	return s.rankCandidates(candidates, opts, func(emb *Embedding) float64 {
+		return approx[emb.ID]
+	}), nil
+}
@@ -7,6 +7,9 @@ import (
 
 // Close closes the database connection and releases resources
 func (s *SQLiteStore) Close() error {
+	// The build swaps its index under s.mu, so stop it before taking the lock
+	s.stopIndexBuild()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -26,6 +29,12 @@ func (s *SQLiteStore) Close() error {
 
 	s.closed = true
 
+	if s.diskANN != nil {
+		if err := s.diskANN.Close(); err != nil {
+			s.logger.Warn("failed to close DiskANN index", "error", err)
+		}
+	}
+
 	if s.db != nil {
 		if err := s.db.Close(); err != nil {
 			return err
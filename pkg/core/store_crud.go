@@ -13,43 +13,83 @@ import (
 
 // Upsert inserts or updates a single embedding
 func (s *SQLiteStore) Upsert(ctx context.Context, emb *Embedding) error {
+	return s.upsert(ctx, emb, WriteCondition{})
+}
+
+// UpsertWithCondition writes a single embedding only if cond holds.
+// A failed precondition returns an error matching ErrConflict.
+func (s *SQLiteStore) UpsertWithCondition(ctx context.Context, emb *Embedding, cond WriteCondition) error {
+	if err := cond.validate(); err != nil {
+		return wrapError("upsert", err)
+	}
+	return s.upsert(ctx, emb, cond)
+}
+
+func (s *SQLiteStore) upsert(ctx context.Context, emb *Embedding, cond WriteCondition) error {
+	if err := s.prepareEmbedding(ctx, emb); err != nil {
+		return wrapError("upsert", err)
+	}
+
+	// Re-acquire read lock for database operations
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.writeEmbedding(ctx, s.db, emb, cond); err != nil {
+		return wrapError("upsert", err)
+	}
+
+	s.indexEmbedding(emb)
+
+	return nil
+}
+
+// detectVectorDim sets the store dimension from the first vector written
+// and sets up what depends on it. It returns the store dimension, which
+// another writer may have set first.
+func (s *SQLiteStore) detectVectorDim(ctx context.Context, incomingDim int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.VectorDim != 0 { // Double-check after acquiring write lock
+		return s.config.VectorDim
+	}
+	s.config.VectorDim = incomingDim
+
+	// Initialize quantizer now that we know the dimension
+	if s.config.Quantization.Enabled && s.quantizer == nil {
+		q, err := s.newQuantizer()
+		if err != nil {
+			s.logger.Warn("failed to create scalar quantizer", "error", err)
+		} else {
+			s.quantizer = q
+		}
+		if s.hnswIndex != nil {
+			s.hnswIndex.SetQuantizer(s.quantizer)
+		}
+	}
+
+	// Build an LSH or multi-index that needs no training
+	s.ensureVectorIndex(ctx)
+	s.ensureBinaryIndex(ctx)
+	return incomingDim
+}
+
+// prepareEmbedding detects the store dimension on first insert, trains the
+// quantizer if needed and adapts the vector to the store dimension
+func (s *SQLiteStore) prepareEmbedding(ctx context.Context, emb *Embedding) error {
 	s.mu.RLock()
 	currentDim := s.config.VectorDim
 	s.mu.RUnlock()
 
 	if s.closed {
-		return wrapError("upsert", ErrStoreClosed)
+		return ErrStoreClosed
 	}
 
 	incomingDim := len(emb.Vector)
 
 	// Auto-detect dimension on first insert
 	if currentDim == 0 {
-		s.mu.Lock()
-		if s.config.VectorDim == 0 { // Double-check after acquiring write lock
-			s.config.VectorDim = incomingDim
-			currentDim = incomingDim
-
-			// Initialize quantizer now that we know the dimension
-			if s.config.Quantization.Enabled && s.quantizer == nil {
-				if s.config.Quantization.Type == "binary" {
-					s.quantizer = quantization.NewBinaryQuantizer(currentDim)
-				} else {
-					sq, err := quantization.NewScalarQuantizer(currentDim, s.config.Quantization.NBits)
-					if err != nil {
-						s.logger.Warn("failed to create scalar quantizer", "error", err)
-					} else {
-						s.quantizer = sq
-					}
-				}
-				if s.hnswIndex != nil {
-					s.hnswIndex.SetQuantizer(s.quantizer)
-				}
-			}
-		} else {
-			currentDim = s.config.VectorDim
-		}
-		s.mu.Unlock()
+		currentDim = s.detectVectorDim(ctx, incomingDim)
 	}
 
 	// Auto-train quantizer if not trained
@@ -70,23 +110,21 @@ func (s *SQLiteStore) Upsert(ctx context.Context, emb *Embedding) error {
 
 	// Handle dimension mismatch
 	if incomingDim != currentDim {
-		adaptedVector, err := s.adapter.AdaptVector(emb.Vector, incomingDim, currentDim)
+		adaptedVector, err := s.adaptForCollection(ctx, emb.CollectionID, emb.Collection, emb.Vector, currentDim)
 		if err != nil {
-			return wrapError("upsert", err)
+			return err
 		}
 		s.adapter.logDimensionEvent("adapt", incomingDim, currentDim, emb.ID)
 		emb.Vector = adaptedVector
 	}
 
 	// Validate adapted embedding
-	if err := encoding.ValidateEmbedding(*emb, currentDim); err != nil {
-		return wrapError("upsert", err)
-	}
-
-	// Re-acquire read lock for database operations
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	return encoding.ValidateEmbedding(*emb, currentDim)
+}
 
+// writeEmbedding stores a prepared embedding through q, honoring any
+// precondition atomically, and records the new revision on emb
+func (s *SQLiteStore) writeEmbedding(ctx context.Context, q sqlExecutor, emb *Embedding, cond WriteCondition) error {
 	// Determine collection ID
 	collectionID := emb.CollectionID
 	if collectionID == 0 {
@@ -94,7 +132,7 @@ func (s *SQLiteStore) Upsert(ctx context.Context, emb *Embedding) error {
 		if emb.Collection != "" {
 			collection, err := s.GetCollection(ctx, emb.Collection)
 			if err != nil {
-				return wrapError("upsert", fmt.Errorf("collection '%s' not found: %w", emb.Collection, err))
+				return fmt.Errorf("collection '%s' not found: %w", emb.Collection, err)
 			}
 			collectionID = collection.ID
 		} else {
@@ -102,15 +140,21 @@ func (s *SQLiteStore) Upsert(ctx context.Context, emb *Embedding) error {
 		}
 	}
 
+	// Validate metadata against the collection schema
+	metadata, err := s.applyMetadataSchema(ctx, collectionID, emb.Metadata, nil)
+	if err != nil {
+		return fmt.Errorf("embedding '%s': %w", emb.ID, err)
+	}
+
 	// Encode vector and metadata
 	vectorBytes, err := encoding.EncodeVector(emb.Vector)
 	if err != nil {
-		return wrapError("upsert", err)
+		return err
 	}
 
-	metadataJSON, err := encoding.EncodeMetadata(emb.Metadata)
+	metadataJSON, err := encoding.EncodeMetadata(metadata)
 	if err != nil {
-		return wrapError("upsert", err)
+		return err
 	}
 
 	// Encode ACL
@@ -118,7 +162,7 @@ func (s *SQLiteStore) Upsert(ctx context.Context, emb *Embedding) error {
 	if len(emb.ACL) > 0 {
 		aclJSON, err = json.Marshal(emb.ACL)
 		if err != nil {
-			return wrapError("upsert", fmt.Errorf("failed to marshal ACL: %w", err))
+			return fmt.Errorf("failed to marshal ACL: %w", err)
 		}
 	}
 
@@ -129,16 +173,31 @@ func (s *SQLiteStore) Upsert(ctx context.Context, emb *Embedding) error {
 		docID.Valid = true
 	}
 
-	// Insert or replace
-	query := `
-	INSERT OR REPLACE INTO embeddings (id, collection_id, vector, content, doc_id, metadata, acl, created_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
-	`
+	// Insert or replace, honoring any precondition atomically
+	query, args := cond.embeddingWriteQuery(emb.ID, collectionID, vectorBytes, emb.Content, docID, metadataJSON, aclJSON, nullString(emb.ModelID))
 
-	_, err = s.db.ExecContext(ctx, query, emb.ID, collectionID, vectorBytes, emb.Content, docID, metadataJSON, aclJSON)
+	var revision int64
+	err = q.QueryRowContext(ctx, query, args...).Scan(&revision)
+	if err == sql.ErrNoRows {
+		return embeddingConflict(ctx, q, emb.ID, cond)
+	}
 	if err != nil {
-		return wrapError("upsert", fmt.Errorf("failed to insert embedding: %w", err))
+		return fmt.Errorf("failed to insert embedding: %w", err)
 	}
+	emb.Revision = revision
+
+	return s.writeBinaryCode(ctx, q, emb)
+}
+
+// nullString maps an empty string to NULL
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
+// indexEmbedding adds a stored embedding to the in-memory indexes
+func (s *SQLiteStore) indexEmbedding(emb *Embedding) {
+	s.trackIndexWrite(emb.ID)
+	s.noteLocalUpserts(emb)
 
 	// Update HNSW index if enabled
 	if s.config.HNSW.Enabled && s.hnswIndex != nil {
@@ -154,7 +213,87 @@ func (s *SQLiteStore) Upsert(ctx context.Context, emb *Embedding) error {
 		}
 	}
 
-	return nil
+	// Update IVF-PQ index if trained
+	if s.config.IndexType == IndexTypeIVFPQ && s.ivfpqIndex != nil {
+		if err := s.ivfpqIndex.Add(emb.ID, emb.Vector); err != nil {
+			s.logger.Warn("failed to add vector to IVF-PQ index", "id", emb.ID, "error", err)
+		}
+	}
+
+	// Update DiskANN index once it has been built, and build or merge the
+	// file when enough has changed
+	if s.config.IndexType == IndexTypeDiskANN {
+		if s.diskANN != nil {
+			if err := s.diskANN.Add(emb.ID, emb.Vector); err != nil {
+				s.logger.Warn("failed to add vector to DiskANN index", "id", emb.ID, "error", err)
+			}
+		}
+		s.mergeDiskANNIfNeeded()
+	}
+
+	// Update LSH, hybrid or multi-index once it has been built
+	if s.usesVectorIndex() && s.vectorIndex != nil {
+		s.addToVectorIndex(emb)
+	}
+
+	// Update binary codes
+	if s.config.IndexType == IndexTypeBinary && s.binaryIndex != nil {
+		s.addToBinaryIndex(emb)
+	}
+
+	// Update the prefix index of the embedding's collection
+	s.addToPrefixIndex(emb)
+}
+
+// unindexEmbedding removes a deleted embedding from the in-memory indexes
+func (s *SQLiteStore) unindexEmbedding(id string) {
+	s.trackIndexWrite(id)
+	s.noteLocalDeletes(id)
+
+	// Update HNSW index if enabled
+	if s.config.HNSW.Enabled && s.hnswIndex != nil {
+		if err := s.hnswIndex.Delete(id); err != nil {
+			s.logger.Warn("failed to delete vector from HNSW index", "id", id, "error", err)
+		}
+	}
+
+	// Update IVF index if enabled
+	if s.ivfIndex != nil {
+		if err := s.ivfIndex.Delete(id); err != nil {
+			s.logger.Warn("failed to delete vector from IVF index", "id", id, "error", err)
+		}
+	}
+
+	// Update IVF-PQ index if trained
+	if s.ivfpqIndex != nil && s.ivfpqIndex.Contains(id) {
+		if err := s.ivfpqIndex.Delete(id); err != nil {
+			s.logger.Warn("failed to delete vector from IVF-PQ index", "id", id, "error", err)
+		}
+	}
+
+	// Update DiskANN index if built
+	if s.diskANN != nil {
+		if err := s.diskANN.Delete(id); err != nil {
+			s.logger.Warn("failed to delete vector from DiskANN index", "id", id, "error", err)
+		}
+		s.mergeDiskANNIfNeeded()
+	}
+
+	// Update LSH, hybrid or multi-index if built
+	if s.vectorIndex != nil {
+		if err := s.vectorIndex.Delete(id); err != nil {
+			s.logger.Warn("failed to delete vector from vector index", "id", id, "error", err)
+		}
+	}
+
+	// Update binary codes
+	if s.binaryIndex != nil && s.binaryIndex.Contains(id) {
+		if err := s.binaryIndex.Delete(id); err != nil {
+			s.logger.Warn("failed to delete vector from binary index", "id", id, "error", err)
+		}
+	}
+
+	s.deleteFromPrefixIndexes(id)
 }
 
 // UpsertBatch inserts or updates multiple embeddings in a transaction
@@ -209,10 +348,7 @@ func (s *SQLiteStore) UpsertBatch(ctx context.Context, embs []*Embedding) error
 	}()
 
 	// Prepare statement
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT OR REPLACE INTO embeddings (id, collection_id, vector, content, doc_id, metadata, acl, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
-	`)
+	stmt, err := tx.PrepareContext(ctx, upsertEmbeddingSQL)
 	if err != nil {
 		return wrapError("upsert_batch", fmt.Errorf("failed to prepare statement: %w", err))
 	}
@@ -222,6 +358,9 @@ func (s *SQLiteStore) UpsertBatch(ctx context.Context, embs []*Embedding) error
 		}
 	}()
 
+	// Schemas are looked up once per collection in the batch
+	schemas := make(map[int]*MetadataSchema)
+
 	// Execute for each embedding
 	for i, emb := range embs {
 		if err := encoding.ValidateEmbedding(*emb, s.config.VectorDim); err != nil {
@@ -243,12 +382,17 @@ func (s *SQLiteStore) UpsertBatch(ctx context.Context, embs []*Embedding) error
 			}
 		}
 
+		metadata, err := s.applyMetadataSchema(ctx, collectionID, emb.Metadata, schemas)
+		if err != nil {
+			return wrapError("upsert_batch", fmt.Errorf("embedding '%s' at index %d: %w", emb.ID, i, err))
+		}
+
 		vectorBytes, err := encoding.EncodeVector(emb.Vector)
 		if err != nil {
 			return wrapError("upsert_batch", fmt.Errorf("failed to encode vector at index %d: %w", i, err))
 		}
 
-		metadataJSON, err := encoding.EncodeMetadata(emb.Metadata)
+		metadataJSON, err := encoding.EncodeMetadata(metadata)
 		if err != nil {
 			return wrapError("upsert_batch", fmt.Errorf("failed to encode metadata at index %d: %w", i, err))
 		}
@@ -269,10 +413,13 @@ func (s *SQLiteStore) UpsertBatch(ctx context.Context, embs []*Embedding) error
 			docID.Valid = true
 		}
 
-		_, err = stmt.ExecContext(ctx, emb.ID, collectionID, vectorBytes, emb.Content, docID, metadataJSON, aclJSON)
+		err = stmt.QueryRowContext(ctx, emb.ID, collectionID, vectorBytes, emb.Content, docID, metadataJSON, aclJSON, nullString(emb.ModelID), emb.ID).Scan(&emb.Revision)
 		if err != nil {
 			return wrapError("upsert_batch", fmt.Errorf("failed to insert embedding at index %d: %w", i, err))
 		}
+		if err := s.writeBinaryCode(ctx, tx, emb); err != nil {
+			return wrapError("upsert_batch", err)
+		}
 	}
 
 	// Commit transaction
@@ -282,6 +429,11 @@ func (s *SQLiteStore) UpsertBatch(ctx context.Context, embs []*Embedding) error
 
 	s.logger.Debug("batch upsert completed", "count", len(embs))
 
+	for _, emb := range embs {
+		s.trackIndexWrite(emb.ID)
+	}
+	s.noteLocalUpserts(embs...)
+
 	// Update HNSW index if enabled
 	if s.config.HNSW.Enabled && s.hnswIndex != nil {
 		var errorCount int
@@ -310,6 +462,46 @@ func (s *SQLiteStore) UpsertBatch(ctx context.Context, embs []*Embedding) error
 		}
 	}
 
+	// Update IVF-PQ index if trained
+	if s.config.IndexType == IndexTypeIVFPQ && s.ivfpqIndex != nil {
+		for _, emb := range embs {
+			if err := s.ivfpqIndex.Add(emb.ID, emb.Vector); err != nil {
+				s.logger.Warn("failed to add vector to IVF-PQ index during batch upsert", "id", emb.ID, "error", err)
+			}
+		}
+	}
+
+	// Update DiskANN index once it has been built
+	if s.config.IndexType == IndexTypeDiskANN {
+		if s.diskANN != nil {
+			for _, emb := range embs {
+				if err := s.diskANN.Add(emb.ID, emb.Vector); err != nil {
+					s.logger.Warn("failed to add vector to DiskANN index during batch upsert", "id", emb.ID, "error", err)
+				}
+			}
+		}
+		s.mergeDiskANNIfNeeded()
+	}
+
+	// Update LSH, hybrid or multi-index once it has been built
+	if s.usesVectorIndex() && s.vectorIndex != nil {
+		for _, emb := range embs {
+			s.addToVectorIndex(emb)
+		}
+	}
+
+	// Update binary codes
+	if s.config.IndexType == IndexTypeBinary && s.binaryIndex != nil {
+		for _, emb := range embs {
+			s.addToBinaryIndex(emb)
+		}
+	}
+
+	// Update prefix indexes
+	for _, emb := range embs {
+		s.addToPrefixIndex(emb)
+	}
+
 	return nil
 }
 
@@ -340,19 +532,7 @@ func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
 		return wrapError("delete", ErrNotFound)
 	}
 
-	// Update HNSW index if enabled
-	if s.config.HNSW.Enabled && s.hnswIndex != nil {
-		if err := s.hnswIndex.Delete(id); err != nil {
-			s.logger.Warn("failed to delete vector from HNSW index", "id", id, "error", err)
-		}
-	}
-
-	// Update IVF index if enabled
-	if s.ivfIndex != nil {
-		if err := s.ivfIndex.Delete(id); err != nil {
-			s.logger.Warn("failed to delete vector from IVF index", "id", id, "error", err)
-		}
-	}
+	s.unindexEmbedding(id)
 
 	return nil
 }
@@ -440,20 +620,8 @@ func (s *SQLiteStore) DeleteBatch(ctx context.Context, ids []string) error {
 	}
 
 	// 2. Delete from Memory Indexes
-	if s.hnswIndex != nil {
-		for _, id := range validIDs {
-			if err := s.hnswIndex.Delete(id); err != nil {
-				s.logger.Warn("failed to delete vector from HNSW index during batch delete", "id", id, "error", err)
-			}
-		}
-	}
-
-	if s.ivfIndex != nil {
-		for _, id := range validIDs {
-			if err := s.ivfIndex.Delete(id); err != nil {
-				s.logger.Warn("failed to delete vector from IVF index during batch delete", "id", id, "error", err)
-			}
-		}
+	for _, id := range validIDs {
+		s.unindexEmbedding(id)
 	}
 
 	s.logger.Debug("batch delete completed", "deleted", totalRowsAffected)
@@ -510,20 +678,8 @@ func (s *SQLiteStore) DeleteByFilter(ctx context.Context, filter *MetadataFilter
 	}
 
 	// Update Memory Indexes
-	if s.hnswIndex != nil {
-		for _, id := range idsToDelete {
-			if err := s.hnswIndex.Delete(id); err != nil {
-				s.logger.Warn("failed to delete vector from HNSW index during filter delete", "id", id, "error", err)
-			}
-		}
-	}
-
-	if s.ivfIndex != nil {
-		for _, id := range idsToDelete {
-			if err := s.ivfIndex.Delete(id); err != nil {
-				s.logger.Warn("failed to delete vector from IVF index during filter delete", "id", id, "error", err)
-			}
-		}
+	for _, id := range idsToDelete {
+		s.unindexEmbedding(id)
 	}
 
 	s.logger.Debug("delete by filter completed", "deleted", len(idsToDelete))
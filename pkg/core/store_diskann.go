@@ -0,0 +1,465 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+	"github.com/liliang-cn/cortexdb/v2/pkg/index"
+)
+
+// diskANNState is the part of a DiskANN index that is not in its file. It is
+// saved in index_snapshots so tombstones survive a restart; vectors added
+// since the build are reloaded from the embeddings table.
+type diskANNState struct {
+	BuildID uint64
+	Deleted []string
+}
+
+// diskANNDirtySQL records every ID written since the DiskANN file was
+// built, in the writing transaction. Tombstones are only saved by autosave
+// and Close, so after a crash the file may still hold a replaced vector;
+// loading re-adds dirty IDs from the embeddings table.
+const diskANNDirtySQL = `
+	CREATE TABLE IF NOT EXISTS diskann_dirty (
+		embedding_id TEXT PRIMARY KEY,
+		seq INTEGER NOT NULL
+	);
+	CREATE TRIGGER IF NOT EXISTS embeddings_diskann_ai AFTER INSERT ON embeddings BEGIN
+	  INSERT OR REPLACE INTO diskann_dirty(embedding_id, seq)
+	  VALUES (new.id, COALESCE((SELECT MAX(seq) FROM diskann_dirty), 0) + 1);
+	END;
+	CREATE TRIGGER IF NOT EXISTS embeddings_diskann_au AFTER UPDATE OF vector ON embeddings BEGIN
+	  INSERT OR REPLACE INTO diskann_dirty(embedding_id, seq)
+	  VALUES (new.id, COALESCE((SELECT MAX(seq) FROM diskann_dirty), 0) + 1);
+	END;
+`
+
+// diskANNPath returns the DiskANN index file path
+func (s *SQLiteStore) diskANNPath() string {
+	if s.config.DiskANN.Path != "" {
+		return s.config.DiskANN.Path
+	}
+	return s.config.Path + ".diskann"
+}
+
+// initDiskANNIndex opens the DiskANN index file, or builds it when it is
+// missing or corrupt
+func (s *SQLiteStore) initDiskANNIndex(ctx context.Context) error {
+	if s.config.IndexType != IndexTypeDiskANN {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, diskANNDirtySQL); err != nil {
+		return fmt.Errorf("failed to create DiskANN write log: %w", err)
+	}
+
+	idx, err := index.OpenDiskANN(s.diskANNPath(), s.config.DiskANN.SearchL)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.logger.Warn("failed to open DiskANN index, rebuilding", "error", err)
+		}
+		return s.rebuildDiskANNIndex(ctx)
+	}
+
+	if s.config.VectorDim > 0 && idx.Dimension() != s.config.VectorDim {
+		s.logger.Warn("DiskANN index dimension mismatch, rebuilding", "index", idx.Dimension(), "store", s.config.VectorDim)
+		_ = idx.Close()
+		return s.rebuildDiskANNIndex(ctx)
+	}
+
+	s.diskANN = idx
+	if err := s.loadDiskANNState(ctx); err != nil {
+		return fmt.Errorf("failed to load DiskANN state: %w", err)
+	}
+
+	s.logger.Info("DiskANN index opened", "path", idx.Path(), "vectors", idx.Size(), "pending", idx.PendingChanges())
+	return nil
+}
+
+// diskANNInitialBuildSize is the number of vectors a store without a DiskANN
+// file collects before it builds one in the background. Searches are linear
+// until then.
+const diskANNInitialBuildSize = 256
+
+// diskANNBuildConfig returns the builder settings for a new DiskANN file
+func (s *SQLiteStore) diskANNBuildConfig() index.DiskANNConfig {
+	return index.DiskANNConfig{
+		R:           s.config.DiskANN.R,
+		L:           s.config.DiskANN.L,
+		Alpha:       s.config.DiskANN.Alpha,
+		SearchL:     s.config.DiskANN.SearchL,
+		PQSubspaces: s.config.DiskANN.PQSubspaces,
+		Normalize:   true, // L2 on unit vectors ranks like cosine similarity
+	}
+}
+
+// fillDiskANNBuilder streams every stored vector into a new DiskANN builder,
+// a page at a time in ID order, calling progress with the running count
+// after each page. Unless the caller holds s.mu, each page is read under
+// s.mu.Lock: writers share s.mu.RLock, and a read that overlaps their
+// commit would fail it with SQLITE_BUSY. It returns nil
+// when there are no vectors; otherwise the caller builds or aborts the
+// builder.
+func (s *SQLiteStore) fillDiskANNBuilder(ctx context.Context, locked bool, progress func(int)) (*index.DiskANNBuilder, error) {
+	var builder *index.DiskANNBuilder
+	fail := func(err error) (*index.DiskANNBuilder, error) {
+		if builder != nil {
+			builder.Abort()
+		}
+		return nil, err
+	}
+
+	// readPage adds up to indexBuildChunkSize vectors after the given ID
+	// and returns the last ID read, or "" at the end of the table
+	readPage := func(after string) (string, error) {
+		if !locked {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.closed {
+				return "", ErrStoreClosed
+			}
+		}
+
+		rows, err := s.db.QueryContext(ctx, "SELECT id, vector FROM embeddings WHERE id > ? ORDER BY id LIMIT ?", after, indexBuildChunkSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to query existing vectors: %w", err)
+		}
+		defer func() {
+			if closeErr := rows.Close(); closeErr != nil {
+				s.logger.Warn("failed to close rows during DiskANN build", "error", closeErr)
+			}
+		}()
+
+		last := ""
+		for rows.Next() {
+			var id string
+			var vectorBytes []byte
+			if err := rows.Scan(&id, &vectorBytes); err != nil {
+				return "", fmt.Errorf("failed to scan vector: %w", err)
+			}
+			last = id
+
+			vec, err := encoding.DecodeVector(vectorBytes)
+			if err != nil {
+				s.logger.Warn("failed to decode vector during DiskANN build", "id", id, "error", err)
+				continue
+			}
+
+			if builder == nil {
+				builder, err = index.NewDiskANNBuilder(s.diskANNPath(), len(vec), s.diskANNBuildConfig())
+				if err != nil {
+					return "", fmt.Errorf("failed to create DiskANN builder: %w", err)
+				}
+			}
+			if err := builder.Add(id, vec); err != nil {
+				s.logger.Warn("failed to add vector to DiskANN build", "id", id, "error", err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return "", fmt.Errorf("error iterating rows: %w", err)
+		}
+		return last, nil
+	}
+
+	for after := ""; ; {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		last, err := readPage(after)
+		if err != nil {
+			return fail(err)
+		}
+		if last == "" {
+			break
+		}
+		after = last
+		if progress != nil && builder != nil {
+			progress(builder.Len())
+		}
+	}
+
+	if builder != nil && builder.Len() == 0 {
+		return fail(nil)
+	}
+	return builder, nil
+}
+
+// rebuildDiskANNIndex writes a new DiskANN file from the embeddings table and
+// swaps it in; the old index serves until the build succeeds. With no
+// embeddings the store searches linearly until the index can be built. The
+// caller holds s.mu; writes go on during a merge, which uses IndexBuildDiskANN.
+func (s *SQLiteStore) rebuildDiskANNIndex(ctx context.Context) error {
+	s.logger.Info("building DiskANN index from database")
+
+	// Writes from here on may be missing from the new file, so only earlier
+	// ones are cleared once it is built
+	var dirtySeq int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM diskann_dirty").Scan(&dirtySeq); err != nil {
+		return fmt.Errorf("failed to read DiskANN write log: %w", err)
+	}
+
+	builder, err := s.fillDiskANNBuilder(ctx, true, nil)
+	if err != nil {
+		return err
+	}
+	if builder == nil {
+		s.logger.Info("DiskANN index not built, no vectors yet")
+		return nil
+	}
+
+	idx, err := builder.Build()
+	if err != nil {
+		return fmt.Errorf("failed to build DiskANN index: %w", err)
+	}
+	return s.swapDiskANNIndex(ctx, idx, dirtySeq)
+}
+
+// swapDiskANNIndex replaces the live DiskANN index with a new build, saves
+// its state and clears the write log up to dirtySeq, the last write the
+// build's snapshot contains. The caller holds s.mu.
+func (s *SQLiteStore) swapDiskANNIndex(ctx context.Context, idx *index.DiskANN, dirtySeq int64) error {
+	if s.diskANN != nil {
+		if err := s.diskANN.Close(); err != nil {
+			s.logger.Warn("failed to close DiskANN index", "error", err)
+		}
+	}
+	s.diskANN = idx
+
+	s.logger.Info("DiskANN index build complete", "vectors", idx.Size(), "path", idx.Path())
+
+	if err := s.saveDiskANNState(ctx); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM diskann_dirty WHERE seq <= ?", dirtySeq); err != nil {
+		return fmt.Errorf("failed to clear DiskANN write log: %w", err)
+	}
+	return nil
+}
+
+// mergeDiskANNIfNeeded starts a background DiskANN build once the vectors
+// added or deleted since the last build exceed MergeRatio of the index, or
+// once a store without a file holds diskANNInitialBuildSize vectors. It is
+// called on every write and returns the started build, if any. The caller
+// holds s.mu.
+func (s *SQLiteStore) mergeDiskANNIfNeeded() *IndexBuild {
+	if s.config.IndexType != IndexTypeDiskANN || s.closed {
+		return nil
+	}
+	if b := s.indexBuild.Load(); b != nil && b.Status().Running() {
+		return nil
+	}
+
+	if s.diskANN == nil {
+		var count int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM embeddings").Scan(&count); err != nil {
+			s.logger.Warn("failed to count vectors for DiskANN build", "error", err)
+			return nil
+		}
+		if count < diskANNInitialBuildSize {
+			return nil
+		}
+	} else {
+		ratio := s.config.DiskANN.MergeRatio
+		if ratio <= 0 {
+			ratio = DefaultDiskANNConfig().MergeRatio
+		}
+		if float64(s.diskANN.PendingChanges()) <= ratio*float64(s.diskANN.Size()) {
+			return nil
+		}
+	}
+
+	b, err := s.startIndexBuild(context.Background(), IndexBuildOptions{Kind: IndexBuildDiskANN})
+	if err != nil {
+		if !errors.Is(err, ErrIndexBuildRunning) {
+			s.logger.Warn("failed to start DiskANN merge", "error", err)
+		}
+		return nil
+	}
+	return b
+}
+
+// buildDiskANN writes a new DiskANN file from the stored vectors, holding
+// the store lock only while each page is read, then replays concurrent writes
+// into it and swaps it in. Until the swap the old index keeps serving.
+func (b *IndexBuild) buildDiskANN(ctx context.Context) error {
+	s := b.store
+
+	// Writes are tracked from here on. They stay in the write log and are
+	// replayed into the new index as fresh vectors, even if the scan below
+	// also wrote them to the file.
+	var dirtySeq int64
+	s.mu.Lock()
+	err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM diskann_dirty").Scan(&dirtySeq)
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to read DiskANN write log: %w", err)
+	}
+
+	builder, err := s.fillDiskANNBuilder(ctx, false, func(n int) {
+		b.report(func(st *IndexBuildStatus) { st.Processed = int64(n) })
+	})
+	if err != nil {
+		return err
+	}
+	if builder == nil {
+		return fmt.Errorf("no vectors found to build the DiskANN index")
+	}
+	if err := ctx.Err(); err != nil {
+		builder.Abort()
+		return err
+	}
+
+	b.report(func(st *IndexBuildStatus) { st.Phase = IndexBuildPhaseBuilding; st.Processed = 0 })
+	idx, err := builder.Build()
+	if err != nil {
+		return fmt.Errorf("failed to build DiskANN index: %w", err)
+	}
+	b.report(func(st *IndexBuildStatus) { st.Processed = int64(idx.Size()) })
+
+	swapped := false
+	defer func() {
+		if !swapped {
+			_ = idx.Close()
+		}
+	}()
+
+	// Replay and swap while writers are held off; replaying without the
+	// lock would race their commits the same way
+	b.report(func(st *IndexBuildStatus) { st.Phase = IndexBuildPhaseReplaying; st.Processed = 0 })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	pending := b.takeDirty(true)
+	if err := b.replay(ctx, pending, idx.Add, func(id string) { _ = idx.Delete(id) }); err != nil {
+		return err
+	}
+	b.report(func(st *IndexBuildStatus) { st.Replayed = int64(len(pending)) })
+
+	swapped = true
+	return s.swapDiskANNIndex(ctx, idx, dirtySeq)
+}
+
+// saveDiskANNState persists the tombstones of the current DiskANN build
+func (s *SQLiteStore) saveDiskANNState(ctx context.Context) error {
+	state := diskANNState{BuildID: s.diskANN.BuildID(), Deleted: s.diskANN.DeletedIDs()}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return fmt.Errorf("failed to serialize DiskANN state: %w", err)
+	}
+
+	query := `
+		INSERT OR REPLACE INTO index_snapshots (type, data, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+	`
+	if _, err := s.db.ExecContext(ctx, query, "DISKANN", buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to save DiskANN state: %w", err)
+	}
+
+	s.logger.Info("index snapshot saved", "type", "DISKANN", "deleted", len(state.Deleted))
+	return nil
+}
+
+// loadDiskANNState restores tombstones saved for the opened build and then
+// reconciles the index with the embeddings table: IDs written since the
+// build are dropped from the file, rows the index does not know are added,
+// and indexed IDs without a row are deleted.
+func (s *SQLiteStore) loadDiskANNState(ctx context.Context) error {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM index_snapshots WHERE type = ?", "DISKANN").Scan(&data)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if err == nil {
+		var state diskANNState
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&state); err != nil {
+			s.logger.Warn("failed to decode DiskANN state", "error", err)
+		} else if state.BuildID != s.diskANN.BuildID() {
+			s.logger.Warn("DiskANN state belongs to another build, ignoring")
+		} else {
+			for _, id := range state.Deleted {
+				_ = s.diskANN.Delete(id)
+			}
+		}
+	}
+
+	dirty, err := s.db.QueryContext(ctx, "SELECT embedding_id FROM diskann_dirty")
+	if err != nil {
+		return err
+	}
+	stale := 0
+	for dirty.Next() {
+		var id string
+		if err := dirty.Scan(&id); err != nil {
+			_ = dirty.Close()
+			return err
+		}
+		if s.diskANN.Contains(id) {
+			_ = s.diskANN.Delete(id)
+			stale++
+		}
+	}
+	if err := dirty.Close(); err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM embeddings")
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{})
+	var missing []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		seen[id] = struct{}{}
+		if !s.diskANN.Contains(id) {
+			missing = append(missing, id)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	removed := 0
+	for _, id := range s.diskANN.IDs() {
+		if _, ok := seen[id]; !ok {
+			_ = s.diskANN.Delete(id)
+			removed++
+		}
+	}
+
+	const chunkSize = 500
+	for start := 0; start < len(missing); start += chunkSize {
+		end := min(start+chunkSize, len(missing))
+		embs, err := s.fetchEmbeddingsByIDs(ctx, missing[start:end])
+		if err != nil {
+			return err
+		}
+		for _, emb := range embs {
+			if err := s.diskANN.Add(emb.ID, emb.Vector); err != nil {
+				s.logger.Warn("failed to add vector to DiskANN index", "id", emb.ID, "error", err)
+			}
+		}
+	}
+
+	if len(missing) > 0 || removed > 0 {
+		s.logger.Info("DiskANN index reconciled", "added", len(missing), "removed", removed, "stale", stale)
+	}
+	return nil
+}
@@ -13,6 +13,13 @@ import (
 	"github.com/liliang-cn/cortexdb/v2/pkg/quantization"
 )
 
+const (
+	// maxHNSWSnapshotDeltas bounds the delta segments kept on top of a full snapshot
+	maxHNSWSnapshotDeltas = 8
+	// hnswDeltaRatio is the share of changed nodes above which a full snapshot is written instead of a delta
+	hnswDeltaRatio = 0.25
+)
+
 // initHNSWIndex initializes the HNSW index if enabled in configuration
 func (s *SQLiteStore) initHNSWIndex(ctx context.Context) error {
 	if !s.config.HNSW.Enabled {
@@ -21,38 +28,25 @@ func (s *SQLiteStore) initHNSWIndex(ctx context.Context) error {
 
 	// Initialize Quantizer if enabled
 	if s.config.Quantization.Enabled && s.config.VectorDim > 0 {
-		if s.config.Quantization.Type == "binary" {
-			s.quantizer = quantization.NewBinaryQuantizer(s.config.VectorDim)
+		q, err := s.newQuantizer()
+		if err != nil {
+			s.logger.Warn("failed to create scalar quantizer", "error", err)
 		} else {
-			sq, err := quantization.NewScalarQuantizer(s.config.VectorDim, s.config.Quantization.NBits)
-			if err != nil {
-				s.logger.Warn("failed to create scalar quantizer", "error", err)
-			} else {
-				s.quantizer = sq
-			}
+			s.quantizer = q
 		}
 	}
 
-	// Create HNSW index with appropriate distance function
-	// Since we can't compare functions directly, we'll use cosine distance as default
-	// which works well for most similarity functions
-	distFunc := index.CosineDistance
-
-	s.hnswIndex = index.NewHNSW(
-		s.config.HNSW.M,
-		s.config.HNSW.EfConstruction,
-		distFunc,
-	)
-
-	// Set quantizer to HNSW index if available
-	if s.quantizer != nil {
-		s.hnswIndex.SetQuantizer(s.quantizer)
-	}
+	s.hnswIndex = s.newHNSWIndex()
 
 	// Try to load from snapshot first
 	loaded, err := s.loadIndexSnapshot(ctx, "HNSW")
 	if err != nil {
+		// A partially loaded graph is unusable; start over from the embeddings
 		s.logger.Warn("failed to load HNSW snapshot, rebuilding", "error", err)
+		s.hnswIndex = s.newHNSWIndex()
+		if err := s.discardIndexSnapshot(ctx, "HNSW"); err != nil {
+			s.logger.Warn("failed to discard HNSW snapshot", "error", err)
+		}
 	}
 
 	if loaded {
@@ -71,6 +65,45 @@ func (s *SQLiteStore) initHNSWIndex(ctx context.Context) error {
 	return s.rebuildHNSWIndex(ctx)
 }
 
+// newQuantizer creates an untrained quantizer from the store configuration
+func (s *SQLiteStore) newQuantizer() (index.Quantizer, error) {
+	if s.config.Quantization.Type == "binary" {
+		return quantization.NewBinaryQuantizer(s.config.VectorDim), nil
+	}
+	return quantization.NewScalarQuantizer(s.config.VectorDim, s.config.Quantization.NBits)
+}
+
+// trainQuantizer fits q to the given sample vectors
+func trainQuantizer(q index.Quantizer, vectors [][]float32) error {
+	if sq, ok := q.(*quantization.ScalarQuantizer); ok {
+		return sq.Train(vectors)
+	} else if bq, ok := q.(*quantization.BinaryQuantizer); ok {
+		return bq.Train(vectors)
+	}
+	return nil
+}
+
+// newHNSWIndex creates an empty HNSW index from the store configuration
+func (s *SQLiteStore) newHNSWIndex() *index.HNSW {
+	// Create HNSW index with appropriate distance function
+	// Since we can't compare functions directly, we'll use cosine distance as default
+	// which works well for most similarity functions
+	distFunc := index.CosineDistance
+
+	hnsw := index.NewHNSW(
+		s.config.HNSW.M,
+		s.config.HNSW.EfConstruction,
+		distFunc,
+	)
+
+	// Set quantizer to HNSW index if available
+	if s.quantizer != nil {
+		hnsw.SetQuantizer(s.quantizer)
+	}
+
+	return hnsw
+}
+
 // rebuildHNSWIndex rebuilds the HNSW index from existing vectors in the database
 func (s *SQLiteStore) rebuildHNSWIndex(ctx context.Context) error {
 	if s.hnswIndex == nil {
@@ -221,9 +254,35 @@ func (s *SQLiteStore) TrainIndex(ctx context.Context, numCentroids int) error {
 		return wrapError("train_index", ErrStoreClosed)
 	}
 
+	if s.config.IndexType == IndexTypeIVFPQ {
+		if err := s.trainIVFPQIndex(ctx, numCentroids); err != nil {
+			return wrapError("train_index", err)
+		}
+		return nil
+	}
+
+	// Binary retrieval learns per-dimension thresholds
+	if s.config.IndexType == IndexTypeBinary {
+		if err := s.trainBinaryIndex(ctx); err != nil {
+			return wrapError("train_index", err)
+		}
+		return nil
+	}
+
+	// LSH, hybrid and multi-indexes are rebuilt, retraining any IVF members
+	if s.usesVectorIndex() {
+		if numCentroids > 0 {
+			s.config.IVF.NCentroids = numCentroids
+		}
+		if err := s.buildVectorIndex(ctx); err != nil {
+			return wrapError("train_index", err)
+		}
+		return nil
+	}
+
 	// Ensure we are in IVF mode
 	if s.config.IndexType != IndexTypeIVF {
-		return wrapError("train_index", fmt.Errorf("index type is not IVF"))
+		return wrapError("train_index", fmt.Errorf("index type is not IVF, IVF-PQ, LSH, hybrid, multi or binary"))
 	}
 
 	// Use config value if numCentroids is 0
@@ -234,9 +293,18 @@ func (s *SQLiteStore) TrainIndex(ctx context.Context, numCentroids int) error {
 		}
 	}
 
+	if err := s.trainIVFIndex(ctx, numCentroids); err != nil {
+		return wrapError("train_index", err)
+	}
+	return nil
+}
+
+// trainIVFIndex trains the IVF index on every stored vector and adds them.
+// The caller must hold s.mu.
+func (s *SQLiteStore) trainIVFIndex(ctx context.Context, numCentroids int) error {
 	if s.ivfIndex == nil {
 		if s.config.VectorDim <= 0 {
-			return wrapError("train_index", fmt.Errorf("vector dimension not set"))
+			return fmt.Errorf("vector dimension not set")
 		}
 		s.ivfIndex = index.NewIVFIndex(s.config.VectorDim, numCentroids)
 	} else {
@@ -253,7 +321,7 @@ func (s *SQLiteStore) TrainIndex(ctx context.Context, numCentroids int) error {
 	// Fetch all vectors for training
 	rows, err := s.db.QueryContext(ctx, "SELECT id, vector FROM embeddings")
 	if err != nil {
-		return wrapError("train_index", fmt.Errorf("failed to fetch vectors: %w", err))
+		return fmt.Errorf("failed to fetch vectors: %w", err)
 	}
 	defer func() {
 		if closeErr := rows.Close(); closeErr != nil {
@@ -281,12 +349,12 @@ func (s *SQLiteStore) TrainIndex(ctx context.Context, numCentroids int) error {
 	}
 
 	if len(vectors) == 0 {
-		return wrapError("train_index", fmt.Errorf("no vectors found for training"))
+		return fmt.Errorf("no vectors found for training")
 	}
 
 	// Train the index
 	if err := s.ivfIndex.Train(vectors); err != nil {
-		return wrapError("train_index", err)
+		return err
 	}
 
 	s.logger.Info("IVF index training complete", "vectors", len(vectors))
@@ -342,52 +410,54 @@ func (s *SQLiteStore) TrainQuantizer(ctx context.Context) error {
 		return fmt.Errorf("no vectors available for quantizer training")
 	}
 
-	if sq, ok := s.quantizer.(*quantization.ScalarQuantizer); ok {
-		if err := sq.Train(trainingVectors); err != nil {
-			return err
-		}
-		s.logger.Info("scalar quantizer trained", "vectors", len(trainingVectors))
-	} else if bq, ok := s.quantizer.(*quantization.BinaryQuantizer); ok {
-		if err := bq.Train(trainingVectors); err != nil {
-			return err
-		}
-		s.logger.Info("binary quantizer trained", "vectors", len(trainingVectors))
+	if err := trainQuantizer(s.quantizer, trainingVectors); err != nil {
+		return err
 	}
+	s.logger.Info("quantizer trained", "type", s.config.Quantization.Type, "vectors", len(trainingVectors))
 
 	return nil
 }
 
 // saveIndexSnapshot saves the current index to the database
 func (s *SQLiteStore) saveIndexSnapshot(ctx context.Context) error {
-	var buf bytes.Buffer
-	var indexType string
+	var err error
 
 	if s.config.IndexType == IndexTypeHNSW && s.hnswIndex != nil {
-		indexType = "HNSW"
-		if err := s.hnswIndex.Save(&buf); err != nil {
-			return fmt.Errorf("failed to serialize HNSW index: %w", err)
+		if err := s.saveHNSWSnapshot(ctx); err != nil {
+			return err
 		}
 	} else if s.config.IndexType == IndexTypeIVF && s.ivfIndex != nil && s.ivfIndex.Trained {
-		indexType = "IVF"
+		var buf bytes.Buffer
 		if err := s.ivfIndex.Save(&buf); err != nil {
 			return fmt.Errorf("failed to serialize IVF index: %w", err)
 		}
+
+		// Save to database
+		query := `
+			INSERT OR REPLACE INTO index_snapshots (type, data, created_at)
+			VALUES (?, ?, CURRENT_TIMESTAMP)
+		`
+		if _, err := s.db.ExecContext(ctx, query, "IVF", buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to save index snapshot: %w", err)
+		}
+
+		s.logger.Info("index snapshot saved", "type", "IVF")
+	} else if s.config.IndexType == IndexTypeIVFPQ && s.ivfpqIndex != nil {
+		if err := s.saveIVFPQSnapshot(ctx); err != nil {
+			return err
+		}
+	} else if s.config.IndexType == IndexTypeDiskANN && s.diskANN != nil {
+		if err := s.saveDiskANNState(ctx); err != nil {
+			return err
+		}
+	} else if s.usesVectorIndex() && s.vectorIndex != nil {
+		if err := s.saveVectorIndexSnapshot(ctx); err != nil {
+			return err
+		}
 	} else {
 		return nil // No index to save
 	}
 
-	// Save to database
-	query := `
-		INSERT OR REPLACE INTO index_snapshots (type, data, created_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP)
-	`
-	_, err := s.db.ExecContext(ctx, query, indexType, buf.Bytes())
-	if err != nil {
-		return fmt.Errorf("failed to save index snapshot: %w", err)
-	}
-
-	s.logger.Info("index snapshot saved", "type", indexType)
-
 	// Also save quantizer if available
 	if s.quantizer != nil {
 		var qBuf bytes.Buffer
@@ -448,8 +518,8 @@ func (s *SQLiteStore) loadIndexSnapshot(ctx context.Context, indexType string) (
 	buf := bytes.NewReader(data)
 
 	if indexType == "HNSW" && s.hnswIndex != nil {
-		if err := s.hnswIndex.Load(buf); err != nil {
-			return false, fmt.Errorf("failed to deserialize HNSW index: %w", err)
+		if err := s.loadHNSWSnapshot(ctx, data); err != nil {
+			return false, fmt.Errorf("failed to load HNSW snapshot: %w", err)
 		}
 		return true, nil
 	} else if indexType == "IVF" && s.ivfIndex != nil {
@@ -462,6 +532,160 @@ func (s *SQLiteStore) loadIndexSnapshot(ctx context.Context, indexType string) (
 	return false, nil
 }
 
+// saveHNSWSnapshot persists the HNSW graph. When few nodes changed since the
+// last snapshot only a delta segment is appended; otherwise a new full
+// snapshot replaces the old one and its deltas.
+func (s *SQLiteStore) saveHNSWSnapshot(ctx context.Context) error {
+	pending := s.hnswIndex.PendingChanges()
+	if pending == 0 {
+		return nil // Snapshot is up to date
+	}
+
+	var deltas int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM index_snapshot_deltas WHERE type = ?", "HNSW").Scan(&deltas)
+	if err != nil {
+		return fmt.Errorf("failed to count snapshot deltas: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	if pending > 0 && deltas < maxHNSWSnapshotDeltas && float64(pending) < hnswDeltaRatio*float64(s.hnswIndex.Size()) {
+		nodes, err := s.hnswIndex.WriteDelta(&buf)
+		if err != nil {
+			return fmt.Errorf("failed to serialize HNSW delta: %w", err)
+		}
+		_, err = s.db.ExecContext(ctx, "INSERT INTO index_snapshot_deltas (type, data) VALUES (?, ?)", "HNSW", buf.Bytes())
+		if err != nil {
+			s.hnswIndex.ResetSnapshotTracking()
+			return fmt.Errorf("failed to save HNSW delta: %w", err)
+		}
+		s.logger.Info("index snapshot delta saved", "type", "HNSW", "nodes", nodes, "bytes", buf.Len())
+		return nil
+	}
+
+	if err := s.hnswIndex.WriteSnapshot(&buf); err != nil {
+		return fmt.Errorf("failed to serialize HNSW index: %w", err)
+	}
+
+	if err := s.replaceHNSWSnapshot(ctx, buf.Bytes()); err != nil {
+		s.hnswIndex.ResetSnapshotTracking()
+		return fmt.Errorf("failed to save index snapshot: %w", err)
+	}
+
+	s.logger.Info("index snapshot saved", "type", "HNSW", "bytes", buf.Len())
+	return nil
+}
+
+// replaceHNSWSnapshot stores a full HNSW snapshot and drops its old deltas
+func (s *SQLiteStore) replaceHNSWSnapshot(ctx context.Context, data []byte) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		INSERT OR REPLACE INTO index_snapshots (type, data, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+	`
+	if _, err := tx.ExecContext(ctx, query, "HNSW", data); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM index_snapshot_deltas WHERE type = ?", "HNSW"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// loadHNSWSnapshot restores the HNSW graph from a full snapshot and its delta
+// segments, then attaches vectors from the embeddings table. Embeddings the
+// graph does not know yet are inserted, and nodes without an embedding are
+// dropped.
+func (s *SQLiteStore) loadHNSWSnapshot(ctx context.Context, data []byte) error {
+	if err := s.hnswIndex.ReadSnapshot(bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT data FROM index_snapshot_deltas WHERE type = ? ORDER BY seq", "HNSW")
+	if err != nil {
+		return fmt.Errorf("failed to query snapshot deltas: %w", err)
+	}
+	deltas := 0
+	for rows.Next() {
+		var delta []byte
+		if err := rows.Scan(&delta); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if err := s.hnswIndex.ApplyDelta(bytes.NewReader(delta)); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("delta %d: %w", deltas+1, err)
+		}
+		deltas++
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.QueryContext(ctx, "SELECT id, vector FROM embeddings")
+	if err != nil {
+		return fmt.Errorf("failed to query existing vectors: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			s.logger.Warn("failed to close rows during HNSW snapshot load", "error", closeErr)
+		}
+	}()
+
+	type vecData struct {
+		id     string
+		vector []float32
+	}
+	var missing []vecData
+
+	for rows.Next() {
+		var id string
+		var vectorBytes []byte
+
+		if err := rows.Scan(&id, &vectorBytes); err != nil {
+			return fmt.Errorf("failed to scan vector: %w", err)
+		}
+
+		vec, err := encoding.DecodeVector(vectorBytes)
+		if err != nil {
+			s.logger.Warn("failed to decode vector during HNSW snapshot load", "id", id, "error", err)
+			continue
+		}
+
+		if !s.hnswIndex.SetVector(id, vec) {
+			missing = append(missing, vecData{id: id, vector: vec})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	dropped := s.hnswIndex.DropUnresolved()
+	for _, v := range missing {
+		if err := s.hnswIndex.Insert(v.id, v.vector); err != nil {
+			s.logger.Warn("failed to insert vector", "id", v.id, "error", err)
+		}
+	}
+
+	s.logger.Info("HNSW snapshot applied", "deltas", deltas, "inserted", len(missing), "dropped", dropped)
+	return nil
+}
+
+// discardIndexSnapshot removes the stored snapshot and deltas of an index type
+func (s *SQLiteStore) discardIndexSnapshot(ctx context.Context, indexType string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM index_snapshots WHERE type = ?", indexType); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM index_snapshot_deltas WHERE type = ?", indexType)
+	return err
+}
+
 // startAutoSave starts the periodic auto-save timer
 func (s *SQLiteStore) startAutoSave() {
 	interval := s.config.AutoSave.Interval
@@ -48,6 +48,16 @@ func (s *SQLiteStore) Init(ctx context.Context) error {
 		return wrapError("init", err)
 	}
 
+	// Surface bulk loads that were interrupted before finishing
+	s.checkInterruptedBulkLoads(ctx)
+
+	// Indexes load everything up to here; later changes are applied on search
+	seq, err := s.latestChangeSeq(ctx)
+	if err != nil {
+		return wrapError("init", err)
+	}
+	s.changeSeq.Store(seq)
+
 	// Initialize HNSW index if enabled
 	if err := s.initHNSWIndex(ctx); err != nil {
 		return wrapError("init", err)
@@ -58,6 +68,36 @@ func (s *SQLiteStore) Init(ctx context.Context) error {
 		return wrapError("init", err)
 	}
 
+	// Restore IVF-PQ index if selected
+	if err := s.initIVFPQIndex(ctx); err != nil {
+		return wrapError("init", err)
+	}
+
+	// Open or build DiskANN index if selected
+	if err := s.initDiskANNIndex(ctx); err != nil {
+		return wrapError("init", err)
+	}
+
+	// Restore or build LSH, hybrid or multi-index if selected
+	if err := s.initVectorIndex(ctx); err != nil {
+		return wrapError("init", err)
+	}
+
+	// Load binary codes if selected
+	if err := s.initBinaryIndex(ctx); err != nil {
+		return wrapError("init", err)
+	}
+
+	// Register learned projections between embedding models
+	if err := s.initProjections(ctx); err != nil {
+		return wrapError("init", err)
+	}
+
+	// Build prefix indexes of collections created with WithPrefixIndex
+	if err := s.initPrefixIndexes(ctx); err != nil {
+		return wrapError("init", err)
+	}
+
 	s.logger.Info("database initialized", "path", s.config.Path)
 
 	// Start auto-save if enabled
@@ -68,6 +108,26 @@ func (s *SQLiteStore) Init(ctx context.Context) error {
 	return nil
 }
 
+// embeddingsFTSTriggersSQL keeps chunks_fts in sync with embeddings. Rows
+// written by a bulk load carry fts_deferred and are skipped until the
+// load's Finish clears the flag, which indexes them through embeddings_au.
+// The triggers are recreated on every open to replace older definitions.
+const embeddingsFTSTriggersSQL = `
+	DROP TRIGGER IF EXISTS embeddings_ai;
+	DROP TRIGGER IF EXISTS embeddings_ad;
+	DROP TRIGGER IF EXISTS embeddings_au;
+	CREATE TRIGGER embeddings_ai AFTER INSERT ON embeddings WHEN new.fts_deferred = 0 BEGIN
+	  INSERT INTO chunks_fts(rowid, content) VALUES (new.rowid, new.content);
+	END;
+	CREATE TRIGGER embeddings_ad AFTER DELETE ON embeddings WHEN old.fts_deferred = 0 BEGIN
+	  INSERT INTO chunks_fts(chunks_fts, rowid, content) VALUES('delete', old.rowid, old.content);
+	END;
+	CREATE TRIGGER embeddings_au AFTER UPDATE ON embeddings BEGIN
+	  INSERT INTO chunks_fts(chunks_fts, rowid, content) SELECT 'delete', old.rowid, old.content WHERE old.fts_deferred = 0;
+	  INSERT INTO chunks_fts(rowid, content) SELECT new.rowid, new.content WHERE new.fts_deferred = 0;
+	END;
+`
+
 // createTables creates the necessary database tables
 func (s *SQLiteStore) createTables(ctx context.Context) error {
 	createTableSQL := `
@@ -75,6 +135,7 @@ func (s *SQLiteStore) createTables(ctx context.Context) error {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		name TEXT UNIQUE NOT NULL,
 		dimensions INTEGER NOT NULL DEFAULT 0,
+		prefix_dims INTEGER NOT NULL DEFAULT 0,
 		description TEXT,
 		metadata TEXT,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -91,7 +152,35 @@ func (s *SQLiteStore) createTables(ctx context.Context) error {
 		metadata TEXT,
 		acl TEXT, -- JSON list of allowed users/groups
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		valid_from DATETIME -- When the current version was written
+	);
+
+	CREATE TABLE IF NOT EXISTS document_versions (
+		doc_id TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		title TEXT,
+		content TEXT,
+		source_url TEXT,
+		author TEXT,
+		metadata TEXT,
+		acl TEXT,
+		created_at DATETIME,
+		updated_at DATETIME,
+		valid_from DATETIME, -- When the version became current
+		archived_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- When the version stopped being current
+		PRIMARY KEY (doc_id, version)
+	);
+
+	CREATE TABLE IF NOT EXISTS document_version_chunks (
+		doc_id TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		embedding_id TEXT NOT NULL,
+		collection_id INTEGER,
+		content TEXT,
+		vector BLOB,
+		metadata TEXT,
+		PRIMARY KEY (doc_id, version, embedding_id)
 	);
 
 	CREATE TABLE IF NOT EXISTS embeddings (
@@ -102,6 +191,12 @@ func (s *SQLiteStore) createTables(ctx context.Context) error {
 		doc_id TEXT,
 		metadata TEXT,
 		acl TEXT, -- JSON list of allowed users/groups (inherits from doc if null)
+		revision INTEGER NOT NULL DEFAULT 1,
+		binary_code BLOB, -- Packed bit code for binary retrieval
+		model_id TEXT, -- Embedding model that produced the vector
+		shadow_vector BLOB, -- Re-embedded vector awaiting promotion
+		shadow_model_id TEXT,
+		fts_deferred INTEGER NOT NULL DEFAULT 0, -- Written by an unfinished bulk load, not yet in chunks_fts
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
 		FOREIGN KEY (doc_id) REFERENCES documents(id) ON DELETE CASCADE
@@ -112,12 +207,57 @@ func (s *SQLiteStore) createTables(ctx context.Context) error {
 	CREATE INDEX IF NOT EXISTS idx_embeddings_created_at ON embeddings(created_at);
 	CREATE INDEX IF NOT EXISTS idx_collections_name ON collections(name);
 
+	CREATE TABLE IF NOT EXISTS collection_aliases (
+		alias TEXT PRIMARY KEY,
+		collection_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_collection_aliases_collection_id ON collection_aliases(collection_id);
+
+	CREATE TABLE IF NOT EXISTS collection_schemas (
+		collection_id INTEGER PRIMARY KEY,
+		schema TEXT NOT NULL, -- JSON encoded MetadataSchema
+		version INTEGER NOT NULL DEFAULT 1,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS dimension_projections (
+		collection_id INTEGER NOT NULL,
+		source_dim INTEGER NOT NULL,
+		target_dim INTEGER NOT NULL,
+		method TEXT NOT NULL,
+		weights BLOB NOT NULL, -- target_dim x source_dim, row-major
+		samples INTEGER NOT NULL,
+		mean_cosine REAL NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (collection_id, source_dim),
+		FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS embedding_changes (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		embedding_id TEXT NOT NULL,
+		op TEXT NOT NULL, -- 'upsert' or 'delete'
+		revision INTEGER NOT NULL DEFAULT 0
+	);
+
 	CREATE TABLE IF NOT EXISTS index_snapshots (
 		type TEXT PRIMARY KEY,
 		data BLOB NOT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE TABLE IF NOT EXISTS index_snapshot_deltas (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		data BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
 	CREATE TABLE IF NOT EXISTS sessions (
 		id TEXT PRIMARY KEY,
 		user_id TEXT,
@@ -133,6 +273,7 @@ func (s *SQLiteStore) createTables(ctx context.Context) error {
 		content TEXT NOT NULL,
 		vector BLOB, -- Optional embedding for long-term memory
 		metadata TEXT,
+		revision INTEGER NOT NULL DEFAULT 1,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
 	);
@@ -162,17 +303,27 @@ func (s *SQLiteStore) createTables(ctx context.Context) error {
 	-- Note: Triggers are needed to keep FTS index in sync
 	CREATE VIRTUAL TABLE IF NOT EXISTS chunks_fts USING fts5(content, content='embeddings', content_rowid='rowid');
 
-	-- Triggers to keep FTS index in sync
-	CREATE TRIGGER IF NOT EXISTS embeddings_ai AFTER INSERT ON embeddings BEGIN
-	  INSERT INTO chunks_fts(rowid, content) VALUES (new.rowid, new.content);
-	END;
-	CREATE TRIGGER IF NOT EXISTS embeddings_ad AFTER DELETE ON embeddings BEGIN
-	  INSERT INTO chunks_fts(chunks_fts, rowid, content) VALUES('delete', old.rowid, old.content);
-	END;
-	CREATE TRIGGER IF NOT EXISTS embeddings_au AFTER UPDATE ON embeddings BEGIN
-	  INSERT INTO chunks_fts(chunks_fts, rowid, content) VALUES('delete', old.rowid, old.content);
-	  INSERT INTO chunks_fts(rowid, content) VALUES (new.rowid, new.content);
-	END;
+	CREATE TABLE IF NOT EXISTS bulk_loads (
+		id TEXT PRIMARY KEY,
+		status TEXT NOT NULL,
+		rows_loaded INTEGER NOT NULL DEFAULT 0,
+		last_id TEXT,
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS reembed_jobs (
+		collection_id INTEGER NOT NULL,
+		model_id TEXT NOT NULL,
+		status TEXT NOT NULL, -- 'running' or 'done'
+		last_id TEXT NOT NULL DEFAULT '',
+		processed INTEGER NOT NULL DEFAULT 0,
+		total INTEGER NOT NULL DEFAULT 0,
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (collection_id, model_id),
+		FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE
+	);
 	`
 
 	_, err := s.db.ExecContext(ctx, createTableSQL)
@@ -180,6 +331,20 @@ func (s *SQLiteStore) createTables(ctx context.Context) error {
 		return fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	if err := s.migrateColumns(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, embeddingsFTSTriggersSQL); err != nil {
+		return fmt.Errorf("failed to create full-text triggers: %w", err)
+	}
+
+	if s.config.ExternalWrites.Enabled {
+		if _, err := s.db.ExecContext(ctx, embeddingChangesTriggersSQL); err != nil {
+			return fmt.Errorf("failed to create change log triggers: %w", err)
+		}
+	}
+
 	// Create default collection if it doesn't exist
 	_, err = s.db.ExecContext(ctx, `
 		INSERT OR IGNORE INTO collections (id, name, dimensions, description, created_at, updated_at)
@@ -191,3 +356,45 @@ func (s *SQLiteStore) createTables(ctx context.Context) error {
 
 	return nil
 }
+
+// migrateColumns adds columns introduced after a database file was created
+func (s *SQLiteStore) migrateColumns(ctx context.Context) error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"embeddings", "revision", "INTEGER NOT NULL DEFAULT 1"},
+		{"messages", "revision", "INTEGER NOT NULL DEFAULT 1"},
+		{"embeddings", "binary_code", "BLOB"},
+		{"collections", "prefix_dims", "INTEGER NOT NULL DEFAULT 0"},
+		{"embeddings", "model_id", "TEXT"},
+		{"embeddings", "shadow_vector", "BLOB"},
+		{"embeddings", "shadow_model_id", "TEXT"},
+		{"collections", "model_id", "TEXT"},
+		{"embeddings", "fts_deferred", "INTEGER NOT NULL DEFAULT 0"},
+		{"documents", "valid_from", "DATETIME"},
+		{"document_versions", "valid_from", "DATETIME"},
+	}
+
+	for _, c := range columns {
+		var exists bool
+		err := s.db.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM pragma_table_info(?) WHERE name = ?)", c.table, c.column,
+		).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", c.table, err)
+		}
+		if exists {
+			continue
+		}
+
+		_, err = s.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition))
+		if err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.column, err)
+		}
+		s.logger.Info("migrated schema", "table", c.table, "column", c.column)
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+	"github.com/liliang-cn/cortexdb/v2/pkg/index"
+)
+
+// ivfpqTrainSampleSize caps the vectors held in memory while training IVF-PQ
+const ivfpqTrainSampleSize = 65536
+
+// newIVFPQIndex creates an untrained IVF-PQ index from the store config
+func (s *SQLiteStore) newIVFPQIndex(numCentroids int) (*index.IVFPQIndex, error) {
+	if numCentroids <= 0 {
+		numCentroids = s.config.IVFPQ.NCentroids
+	}
+	return index.NewIVFPQIndex(s.config.VectorDim, index.IVFPQConfig{
+		NCentroids:  numCentroids,
+		NProbe:      s.config.IVFPQ.NProbe,
+		PQSubspaces: s.config.IVFPQ.PQSubspaces,
+		PQCentroids: s.config.IVFPQ.PQCentroids,
+		Normalize:   true, // L2 on unit vectors ranks like cosine similarity
+	})
+}
+
+// initIVFPQIndex restores the IVF-PQ index from its snapshot. Like IVF, an
+// index without a snapshot stays untrained until TrainIndex is called.
+func (s *SQLiteStore) initIVFPQIndex(ctx context.Context) error {
+	if s.config.IndexType != IndexTypeIVFPQ || s.config.VectorDim <= 0 {
+		return nil
+	}
+
+	var data []byte
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM index_snapshots WHERE type = ?", "IVFPQ").Scan(&data)
+	if err == sql.ErrNoRows {
+		s.logger.Info("IVF-PQ index not trained yet")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query index snapshot: %w", err)
+	}
+
+	idx := &index.IVFPQIndex{}
+	if err := idx.Load(bytes.NewReader(data)); err != nil {
+		s.logger.Warn("failed to load IVF-PQ snapshot, discarding it", "error", err)
+		return s.discardIndexSnapshot(ctx, "IVFPQ")
+	}
+	if idx.Dimension != s.config.VectorDim {
+		s.logger.Warn("IVF-PQ snapshot dimension mismatch, discarding it", "index", idx.Dimension, "store", s.config.VectorDim)
+		return s.discardIndexSnapshot(ctx, "IVFPQ")
+	}
+	if s.config.IVFPQ.NProbe > 0 {
+		idx.SetNProbe(s.config.IVFPQ.NProbe)
+	}
+
+	s.ivfpqIndex = idx
+	if err := s.reconcileIVFPQIndex(ctx); err != nil {
+		return fmt.Errorf("failed to reconcile IVF-PQ index: %w", err)
+	}
+
+	s.logger.Info("IVF-PQ index loaded from snapshot", "vectors", idx.Size())
+	return nil
+}
+
+// trainIVFPQIndex trains a new IVF-PQ index on a sample of the stored
+// vectors and then encodes all of them. The caller holds s.mu.
+func (s *SQLiteStore) trainIVFPQIndex(ctx context.Context, numCentroids int) error {
+	if s.config.VectorDim <= 0 {
+		return fmt.Errorf("vector dimension not set")
+	}
+
+	idx, err := s.newIVFPQIndex(numCentroids)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, vector FROM embeddings")
+	if err != nil {
+		return fmt.Errorf("failed to fetch vectors: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			s.logger.Warn("failed to close rows during IVF-PQ training", "error", closeErr)
+		}
+	}()
+
+	// Reservoir sample so training memory does not grow with the collection
+	var sample [][]float32
+	seen := 0
+	for rows.Next() {
+		var id string
+		var vectorBytes []byte
+		if err := rows.Scan(&id, &vectorBytes); err != nil {
+			s.logger.Warn("failed to scan row during IVF-PQ training", "error", err)
+			continue
+		}
+		vec, err := encoding.DecodeVector(vectorBytes)
+		if err != nil {
+			s.logger.Warn("failed to decode vector during IVF-PQ training", "id", id, "error", err)
+			continue
+		}
+
+		seen++
+		if len(sample) < ivfpqTrainSampleSize {
+			sample = append(sample, vec)
+		} else if j := rand.Intn(seen); j < ivfpqTrainSampleSize {
+			sample[j] = vec
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if len(sample) == 0 {
+		return fmt.Errorf("no vectors found for training")
+	}
+
+	s.logger.Info("training IVF-PQ index", "nCentroids", idx.NCentroids, "sample", len(sample))
+	if err := idx.Train(sample); err != nil {
+		return err
+	}
+
+	s.ivfpqIndex = idx
+	if err := s.refillIVFPQIndex(ctx); err != nil {
+		return err
+	}
+
+	s.logger.Info("IVF-PQ index training complete", "vectors", idx.Size())
+	return nil
+}
+
+// refillIVFPQIndex re-encodes every stored vector into a trained IVF-PQ index
+func (s *SQLiteStore) refillIVFPQIndex(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, vector FROM embeddings")
+	if err != nil {
+		return fmt.Errorf("failed to query vectors: %w", err)
+	}
+	defer rows.Close()
+
+	s.ivfpqIndex.Clear()
+	var errorCount int
+	for rows.Next() {
+		var id string
+		var vectorBytes []byte
+		if err := rows.Scan(&id, &vectorBytes); err != nil {
+			return fmt.Errorf("failed to scan vector: %w", err)
+		}
+		vec, err := encoding.DecodeVector(vectorBytes)
+		if err != nil {
+			s.logger.Warn("failed to decode vector during IVF-PQ refill", "id", id, "error", err)
+			continue
+		}
+		if err := s.ivfpqIndex.Add(id, vec); err != nil {
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		s.logger.Warn("some vectors failed to add to IVF-PQ index", "count", errorCount)
+	}
+	return rows.Err()
+}
+
+// reconcileIVFPQIndex brings a restored index in line with the embeddings
+// table: rows written after the snapshot are encoded, and indexed IDs without
+// a row are removed.
+func (s *SQLiteStore) reconcileIVFPQIndex(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM embeddings")
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{})
+	var missing []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		seen[id] = struct{}{}
+		if !s.ivfpqIndex.Contains(id) {
+			missing = append(missing, id)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	removed := 0
+	for _, id := range s.ivfpqIndex.IDs() {
+		if _, ok := seen[id]; !ok {
+			_ = s.ivfpqIndex.Delete(id)
+			removed++
+		}
+	}
+
+	const chunkSize = 500
+	for start := 0; start < len(missing); start += chunkSize {
+		end := min(start+chunkSize, len(missing))
+		embs, err := s.fetchEmbeddingsByIDs(ctx, missing[start:end])
+		if err != nil {
+			return err
+		}
+		for _, emb := range embs {
+			if err := s.ivfpqIndex.Add(emb.ID, emb.Vector); err != nil {
+				s.logger.Warn("failed to add vector to IVF-PQ index", "id", emb.ID, "error", err)
+			}
+		}
+	}
+
+	if len(missing) > 0 || removed > 0 {
+		s.logger.Info("IVF-PQ index reconciled", "added", len(missing), "removed", removed)
+	}
+	return nil
+}
+
+// saveIVFPQSnapshot stores the trained IVF-PQ index in index_snapshots
+func (s *SQLiteStore) saveIVFPQSnapshot(ctx context.Context) error {
+	var buf bytes.Buffer
+	if err := s.ivfpqIndex.Save(&buf); err != nil {
+		return fmt.Errorf("failed to serialize IVF-PQ index: %w", err)
+	}
+
+	query := `
+		INSERT OR REPLACE INTO index_snapshots (type, data, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+	`
+	if _, err := s.db.ExecContext(ctx, query, "IVFPQ", buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to save index snapshot: %w", err)
+	}
+
+	s.logger.Info("index snapshot saved", "type", "IVFPQ")
+	return nil
+}
@@ -0,0 +1,305 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+	"github.com/liliang-cn/cortexdb/v2/pkg/index"
+)
+
+// Models trained with Matryoshka representation learning pack most of the
+// signal into the leading dimensions, so a collection can search an HNSW
+// graph over a short prefix of each vector and rescore the shortlist with the
+// full vectors stored in SQLite. Prefix indexes are rebuilt from the
+// embeddings table on Init and live alongside the store-wide index.
+
+// WithPrefixIndex indexes only the first dims dimensions of each vector in a
+// new collection. Searches scoped to the collection rescore the candidates
+// with the full vectors; SearchOptions.RescoreDepth sets how many.
+func WithPrefixIndex(dims int) CollectionOption {
+	return func(o *collectionOptions) {
+		o.prefixDims = dims
+	}
+}
+
+// prefixIndex is the HNSW graph over truncated vectors of one collection
+type prefixIndex struct {
+	collectionID int
+	name         string
+	dims         int
+	hnsw         *index.HNSW
+
+	mu            sync.Mutex
+	queries       int64
+	latency       time.Duration
+	rescore       time.Duration
+	rescored      int64
+	recallSamples int64
+	recallSum     float64
+}
+
+// record adds one query to the running latency statistics
+func (p *prefixIndex) record(total, rescore time.Duration, rescored int) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queries++
+	p.latency += total
+	p.rescore += rescore
+	p.rescored += int64(rescored)
+	return p.queries
+}
+
+// recordRecall adds one sampled recall@k measurement
+func (p *prefixIndex) recordRecall(recall float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recallSamples++
+	p.recallSum += recall
+}
+
+// stats returns the running statistics of the index
+func (p *prefixIndex) stats() PrefixSearchStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := PrefixSearchStats{
+		Collection:    p.name,
+		PrefixDims:    p.dims,
+		Vectors:       p.hnsw.Size(),
+		Queries:       p.queries,
+		RecallSamples: p.recallSamples,
+	}
+	if p.queries > 0 {
+		st.AvgLatency = p.latency / time.Duration(p.queries)
+		st.AvgRescoreLatency = p.rescore / time.Duration(p.queries)
+		st.AvgRescored = float64(p.rescored) / float64(p.queries)
+	}
+	if p.recallSamples > 0 {
+		st.Recall = p.recallSum / float64(p.recallSamples)
+	}
+	return st
+}
+
+// addPrefixIndex registers an empty prefix index for a collection. The
+// caller holds s.mu.
+func (s *SQLiteStore) addPrefixIndex(collectionID int, name string, dims int) *prefixIndex {
+	if s.prefixIndexes == nil {
+		s.prefixIndexes = make(map[int]*prefixIndex)
+	}
+	p := &prefixIndex{
+		collectionID: collectionID,
+		name:         name,
+		dims:         dims,
+		hnsw:         index.NewHNSW(s.config.HNSW.M, s.config.HNSW.EfConstruction, index.CosineDistance),
+	}
+	s.prefixIndexes[collectionID] = p
+	return p
+}
+
+// initPrefixIndexes builds a prefix index for every collection created with
+// WithPrefixIndex
+func (s *SQLiteStore) initPrefixIndexes(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, prefix_dims FROM collections WHERE prefix_dims > 0")
+	if err != nil {
+		return fmt.Errorf("failed to query prefix collections: %w", err)
+	}
+	type prefixCollection struct {
+		id   int
+		name string
+		dims int
+	}
+	var collections []prefixCollection
+	for rows.Next() {
+		var c prefixCollection
+		if err := rows.Scan(&c.id, &c.name, &c.dims); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan prefix collection: %w", err)
+		}
+		collections = append(collections, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	s.prefixIndexes = nil
+	for _, c := range collections {
+		if err := s.buildPrefixIndex(ctx, s.addPrefixIndex(c.id, c.name, c.dims)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildPrefixIndexes replaces every prefix index with one built from the
+// embeddings table. The caller holds s.mu.
+func (s *SQLiteStore) rebuildPrefixIndexes(ctx context.Context) error {
+	for _, p := range s.prefixIndexes {
+		p.hnsw = index.NewHNSW(s.config.HNSW.M, s.config.HNSW.EfConstruction, index.CosineDistance)
+		if err := s.buildPrefixIndex(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildPrefixIndex inserts the truncated vectors of p's collection into p
+func (s *SQLiteStore) buildPrefixIndex(ctx context.Context, p *prefixIndex) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, vector FROM embeddings WHERE collection_id = ?", p.collectionID)
+	if err != nil {
+		return fmt.Errorf("failed to query vectors for prefix index: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var vectorBytes []byte
+		if err := rows.Scan(&id, &vectorBytes); err != nil {
+			return fmt.Errorf("failed to scan vector: %w", err)
+		}
+		vec, err := encoding.DecodeVector(vectorBytes)
+		if err != nil {
+			s.logger.Warn("failed to decode vector during prefix index build", "id", id, "error", err)
+			continue
+		}
+		if err := p.hnsw.Insert(id, s.adapter.truncateVector(vec, p.dims)); err != nil {
+			s.logger.Warn("failed to add vector to prefix index", "id", id, "error", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	s.logger.Info("prefix index built", "collection", p.name, "dims", p.dims, "vectors", p.hnsw.Size())
+	return nil
+}
+
+// prefixIndexFor returns the prefix index of a resolved collection name
+func (s *SQLiteStore) prefixIndexFor(collection string) *prefixIndex {
+	if collection == "" {
+		return nil
+	}
+	for _, p := range s.prefixIndexes {
+		if p.name == collection {
+			return p
+		}
+	}
+	return nil
+}
+
+// addToPrefixIndex inserts or replaces a stored embedding in the prefix
+// index of its collection, dropping it from any other collection's index
+func (s *SQLiteStore) addToPrefixIndex(emb *Embedding) {
+	if len(s.prefixIndexes) == 0 {
+		return
+	}
+
+	var target *prefixIndex
+	switch {
+	case emb.CollectionID != 0:
+		target = s.prefixIndexes[emb.CollectionID]
+	case emb.Collection == "":
+		target = s.prefixIndexes[1] // Default collection
+	default:
+		if target = s.prefixIndexFor(emb.Collection); target == nil {
+			target = s.prefixIndexFor(s.resolveCollectionName(context.Background(), emb.Collection))
+		}
+	}
+
+	s.deleteFromPrefixIndexes(emb.ID)
+	if target == nil {
+		return
+	}
+	if err := target.hnsw.Insert(emb.ID, s.adapter.truncateVector(emb.Vector, target.dims)); err != nil {
+		s.logger.Warn("failed to add vector to prefix index", "id", emb.ID, "error", err)
+	}
+}
+
+// deleteFromPrefixIndexes removes deleted embeddings from every prefix index
+func (s *SQLiteStore) deleteFromPrefixIndexes(ids ...string) {
+	for _, p := range s.prefixIndexes {
+		for _, id := range ids {
+			_ = p.hnsw.Delete(id) // Only the owning collection holds the ID
+		}
+	}
+}
+
+// prefixSearchStats returns the statistics of every prefix index by collection name
+func (s *SQLiteStore) prefixSearchStats() []PrefixSearchStats {
+	if len(s.prefixIndexes) == 0 {
+		return nil
+	}
+	stats := make([]PrefixSearchStats, 0, len(s.prefixIndexes))
+	for _, p := range s.prefixIndexes {
+		stats = append(stats, p.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Collection < stats[j].Collection })
+	return stats
+}
+
+// searchWithPrefix finds candidates by the leading dimensions of the query
+// and rescores the top RescoreDepth with the full vectors
+func (s *SQLiteStore) searchWithPrefix(ctx context.Context, p *prefixIndex, query []float32, opts SearchOptions) ([]ScoredEmbedding, error) {
+	start := time.Now()
+	if opts.TopK <= 0 {
+		opts.TopK = 10
+	}
+
+	depth := opts.RescoreDepth
+	if depth <= 0 {
+		factor := s.config.Prefix.Oversample
+		if factor <= 0 {
+			factor = DefaultPrefixConfig().Oversample
+		}
+		depth = opts.TopK * factor
+	}
+	if depth < opts.TopK {
+		depth = opts.TopK
+	}
+	ef := s.config.HNSW.EfSearch
+	if ef < depth {
+		ef = depth
+	}
+
+	candidateIDs, _ := p.hnsw.Search(s.adapter.truncateVector(query, p.dims), depth, ef)
+	if len(candidateIDs) == 0 {
+		return s.searchLinear(ctx, query, opts)
+	}
+
+	rescoreStart := time.Now()
+	candidates, err := s.fetchEmbeddingsByIDs(ctx, candidateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch candidates: %w", err)
+	}
+	results, err := s.processCandidates(query, candidates, opts)
+	if err != nil {
+		return nil, err
+	}
+	n := p.record(time.Since(start), time.Since(rescoreStart), len(candidateIDs))
+
+	// Sampled queries are repeated as an exact scan of the full vectors
+	if rate := int64(s.config.Prefix.RecallSampleRate); rate > 0 && n%rate == 0 {
+		exact, err := s.searchLinear(ctx, query, opts)
+		if err != nil {
+			s.logger.Warn("failed to measure prefix search recall", "collection", p.name, "error", err)
+		} else if len(exact) > 0 {
+			found := make(map[string]bool, len(results))
+			for _, r := range results {
+				found[r.ID] = true
+			}
+			hits := 0
+			for _, r := range exact {
+				if found[r.ID] {
+					hits++
+				}
+			}
+			p.recordRecall(float64(hits) / float64(len(exact)))
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+)
+
+// Learned projections let a collection accept vectors from a second embedding
+// model while its stored vectors still come from the first, so models can be
+// migrated without re-embedding everything at once. Projections are stored in
+// dimension_projections, one per collection and source dimension, and are
+// applied by the DimensionAdapter under the LearnedProjection policy.
+
+// projectionCollectionKey returns the adapter key of a resolved collection name.
+// The default collection is registered under the empty name.
+func projectionCollectionKey(name string) string {
+	if name == "default" {
+		return ""
+	}
+	return name
+}
+
+// TrainProjection fits a projection from paired embeddings of the same texts,
+// source from the new model and target from the model the collection was
+// built with, then saves it for the collection. Vectors of the source
+// dimension are mapped through it when AutoDimAdapt is LearnedProjection.
+func (s *SQLiteStore) TrainProjection(ctx context.Context, collection string, source, target [][]float32, method ProjectionMethod) (*Projection, error) {
+	p, err := FitProjection(source, target, method)
+	if err != nil {
+		return nil, wrapError("train_projection", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("train_projection", ErrStoreClosed)
+	}
+	if s.config.VectorDim > 0 && p.TargetDim != s.config.VectorDim {
+		return nil, wrapError("train_projection", fmt.Errorf("target dimension %d doesn't match store dimension %d", p.TargetDim, s.config.VectorDim))
+	}
+	if p.SourceDim == p.TargetDim {
+		return nil, wrapError("train_projection", fmt.Errorf("source and target dimensions are both %d; projections apply only across dimensions", p.SourceDim))
+	}
+
+	name, collectionID, err := s.projectionCollection(ctx, collection)
+	if err != nil {
+		return nil, wrapError("train_projection", err)
+	}
+
+	weights, err := encoding.EncodeVector(p.Weights)
+	if err != nil {
+		return nil, wrapError("train_projection", fmt.Errorf("failed to encode projection: %w", err))
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO dimension_projections (collection_id, source_dim, target_dim, method, weights, samples, mean_cosine, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`, collectionID, p.SourceDim, p.TargetDim, string(p.Method), weights, p.Samples, p.MeanCosine)
+	if err != nil {
+		return nil, wrapError("train_projection", fmt.Errorf("failed to save projection: %w", err))
+	}
+
+	s.adapter.SetProjection(projectionCollectionKey(name), p.SourceDim, p)
+	s.logger.Info("projection trained", "collection", name, "sourceDim", p.SourceDim, "targetDim", p.TargetDim,
+		"method", p.Method, "samples", p.Samples, "meanCosine", p.MeanCosine)
+	return p, nil
+}
+
+// GetProjection returns the projection a collection applies to vectors of sourceDim dimensions
+func (s *SQLiteStore) GetProjection(ctx context.Context, collection string, sourceDim int) (*Projection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("get_projection", ErrStoreClosed)
+	}
+
+	_, collectionID, err := s.projectionCollection(ctx, collection)
+	if err != nil {
+		return nil, wrapError("get_projection", err)
+	}
+
+	p := &Projection{SourceDim: sourceDim}
+	var method string
+	var weights []byte
+	err = s.db.QueryRowContext(ctx, `
+		SELECT target_dim, method, weights, samples, mean_cosine
+		FROM dimension_projections WHERE collection_id = ? AND source_dim = ?
+	`, collectionID, sourceDim).Scan(&p.TargetDim, &method, &weights, &p.Samples, &p.MeanCosine)
+	if err == sql.ErrNoRows {
+		return nil, wrapError("get_projection", fmt.Errorf("no projection from %d dimensions for collection '%s'", sourceDim, collection))
+	}
+	if err != nil {
+		return nil, wrapError("get_projection", fmt.Errorf("failed to get projection: %w", err))
+	}
+	if err := decodeProjection(p, method, weights); err != nil {
+		return nil, wrapError("get_projection", err)
+	}
+	return p, nil
+}
+
+// DeleteProjection removes a collection's projection for sourceDim dimensions
+func (s *SQLiteStore) DeleteProjection(ctx context.Context, collection string, sourceDim int) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return wrapError("delete_projection", ErrStoreClosed)
+	}
+
+	name, collectionID, err := s.projectionCollection(ctx, collection)
+	if err != nil {
+		return wrapError("delete_projection", err)
+	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM dimension_projections WHERE collection_id = ? AND source_dim = ?", collectionID, sourceDim)
+	if err != nil {
+		return wrapError("delete_projection", fmt.Errorf("failed to delete projection: %w", err))
+	}
+	s.adapter.SetProjection(projectionCollectionKey(name), sourceDim, nil)
+	return nil
+}
+
+// projectionCollection resolves a collection name or alias, where empty
+// means the default collection, to its name and ID
+func (s *SQLiteStore) projectionCollection(ctx context.Context, collection string) (string, int, error) {
+	if collection == "" {
+		return "default", 1, nil
+	}
+	name, err := s.lookupAliasTarget(ctx, collection)
+	if err != nil {
+		return "", 0, err
+	}
+	id, err := s.collectionIDByName(ctx, name)
+	if err != nil {
+		return "", 0, err
+	}
+	return name, id, nil
+}
+
+// initProjections registers every stored projection with the adapter
+func (s *SQLiteStore) initProjections(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.name, p.source_dim, p.target_dim, p.method, p.weights, p.samples, p.mean_cosine
+		FROM dimension_projections p
+		JOIN collections c ON c.id = p.collection_id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query projections: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, method string
+		var weights []byte
+		p := &Projection{}
+		if err := rows.Scan(&name, &p.SourceDim, &p.TargetDim, &method, &weights, &p.Samples, &p.MeanCosine); err != nil {
+			return fmt.Errorf("failed to scan projection: %w", err)
+		}
+		if err := decodeProjection(p, method, weights); err != nil {
+			s.logger.Warn("skipping unreadable projection", "collection", name, "sourceDim", p.SourceDim, "error", err)
+			continue
+		}
+		s.adapter.SetProjection(projectionCollectionKey(name), p.SourceDim, p)
+	}
+	return rows.Err()
+}
+
+// decodeProjection fills in the method and weights of a stored projection
+func decodeProjection(p *Projection, method string, weights []byte) error {
+	w, err := encoding.DecodeVector(weights)
+	if err != nil {
+		return fmt.Errorf("failed to decode projection: %w", err)
+	}
+	if len(w) != p.SourceDim*p.TargetDim {
+		return fmt.Errorf("projection has %d weights, expected %d", len(w), p.SourceDim*p.TargetDim)
+	}
+	p.Method = ProjectionMethod(method)
+	p.Weights = w
+	return nil
+}
+
+// adaptForCollection adapts a vector written to or searched in a collection
+// given by ID or name. Names are only resolved for LearnedProjection.
+func (s *SQLiteStore) adaptForCollection(ctx context.Context, collectionID int, collection string, vector []float32, targetDim int) ([]float32, error) {
+	key := ""
+	if s.adapter.usesProjections() {
+		switch {
+		case collectionID > 1:
+			if err := s.db.QueryRowContext(ctx, "SELECT name FROM collections WHERE id = ?", collectionID).Scan(&key); err != nil {
+				return nil, fmt.Errorf("failed to find collection %d: %w", collectionID, err)
+			}
+		case collectionID == 0 && collection != "":
+			key = s.resolveCollectionName(ctx, collection)
+		}
+		key = projectionCollectionKey(key)
+	}
+	return s.adapter.AdaptCollectionVector(key, vector, len(vector), targetDim)
+}
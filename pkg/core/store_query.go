@@ -22,7 +22,7 @@ func (s *SQLiteStore) GetByID(ctx context.Context, id string) (*Embedding, error
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT
 			e.id, e.vector, e.content, e.doc_id, e.metadata, e.acl, e.created_at,
-			COALESCE(c.name, '') as collection_name
+			COALESCE(c.name, '') as collection_name, e.revision, COALESCE(e.model_id, '')
 		FROM embeddings e
 		LEFT JOIN collections c ON e.collection_id = c.id
 		WHERE e.id = ?
@@ -61,8 +61,15 @@ func (s *SQLiteStore) GetByDocID(ctx context.Context, docID string) ([]*Embeddin
 		return nil, wrapError("get_by_doc_id", fmt.Errorf("doc ID cannot be empty"))
 	}
 
-	query := "SELECT id, vector, content, doc_id, metadata FROM embeddings WHERE doc_id = ? ORDER BY created_at"
-	rows, err := s.db.QueryContext(ctx, query, docID)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			e.id, e.vector, e.content, e.doc_id, e.metadata, e.acl, e.created_at,
+			COALESCE(c.name, '') as collection_name, e.revision, COALESCE(e.model_id, '')
+		FROM embeddings e
+		LEFT JOIN collections c ON e.collection_id = c.id
+		WHERE e.doc_id = ?
+		ORDER BY e.created_at, e.rowid
+	`, docID)
 	if err != nil {
 		return nil, wrapError("get_by_doc_id", fmt.Errorf("failed to query embeddings: %w", err))
 	}
@@ -239,11 +246,13 @@ func (s *SQLiteStore) scanEmbeddingForGet(rows *sql.Rows) (*Embedding, error) {
 
 	var collectionName string
 	var createdAt time.Time
+	var revision int64
+	var modelID string
 
 	var err error
 
-	if len(cols) == 8 { // GetByID format
-		err = rows.Scan(&id, &vectorBytes, &content, &docID, &metadataJSON, &aclJSON, &createdAt, &collectionName)
+	if len(cols) == 10 { // GetByID format
+		err = rows.Scan(&id, &vectorBytes, &content, &docID, &metadataJSON, &aclJSON, &createdAt, &collectionName, &revision, &modelID)
 	} else if len(cols) == 5 { // Old format (GetByDocID)
 		err = rows.Scan(&id, &vectorBytes, &content, &docID, &metadataJSON)
 	} else {
@@ -279,5 +288,7 @@ func (s *SQLiteStore) scanEmbeddingForGet(rows *sql.Rows) (*Embedding, error) {
 		DocID:     docID.String,
 		Metadata:  metadata,
 		ACL:       acl,
+		Revision:  revision,
+		ModelID:   modelID,
 	}, nil
 }
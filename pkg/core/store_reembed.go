@@ -0,0 +1,350 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+)
+
+// Re-embedding moves a collection to a new embedding model without a window
+// where old and new vectors are mixed. New vectors are written to the
+// shadow_vector column while searches keep using the old ones; a checkpoint
+// row in reembed_jobs makes the walk resumable. PromoteReembed then swaps the
+// shadow vectors in, records the collection's new model and rebuilds the
+// indexes. Rows written while the job runs clear their shadow vector and are
+// picked up again before promotion.
+
+const (
+	// ReembedRunning means shadow vectors are being written
+	ReembedRunning = "running"
+	// ReembedDone means the shadow vectors were promoted
+	ReembedDone = "done"
+)
+
+// ReembedState is the persisted checkpoint of a re-embedding job
+type ReembedState struct {
+	Collection string    `json:"collection"`
+	ModelID    string    `json:"model_id"`
+	Status     string    `json:"status"`
+	LastID     string    `json:"last_id,omitempty"` // Last embedding ID written, in ID order
+	Processed  int64     `json:"processed"`         // Shadow vectors written so far
+	Total      int64     `json:"total"`             // Embeddings with content in the collection at the last checkpoint
+	StartedAt  time.Time `json:"started_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BeginReembed starts a job moving collection to modelID, or resumes the
+// unfinished one. A finished job for the same model is started over.
+func (s *SQLiteStore) BeginReembed(ctx context.Context, collection, modelID string) (*ReembedState, error) {
+	if modelID == "" {
+		return nil, wrapError("begin_reembed", fmt.Errorf("model ID is required"))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("begin_reembed", ErrStoreClosed)
+	}
+
+	collectionID, _, err := s.reembedCollection(ctx, collection)
+	if err != nil {
+		return nil, wrapError("begin_reembed", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO reembed_jobs (collection_id, model_id, status, last_id, processed, total)
+		VALUES (?, ?, ?, '', 0, (SELECT COUNT(*) FROM embeddings WHERE collection_id = ? AND content != ''))
+		ON CONFLICT(collection_id, model_id) DO UPDATE SET
+			status = excluded.status,
+			last_id = CASE WHEN reembed_jobs.status = ? THEN reembed_jobs.last_id ELSE '' END,
+			processed = CASE WHEN reembed_jobs.status = ? THEN reembed_jobs.processed ELSE 0 END,
+			started_at = CASE WHEN reembed_jobs.status = ? THEN reembed_jobs.started_at ELSE CURRENT_TIMESTAMP END,
+			total = excluded.total,
+			updated_at = CURRENT_TIMESTAMP
+	`, collectionID, modelID, ReembedRunning, collectionID, ReembedRunning, ReembedRunning, ReembedRunning)
+	if err != nil {
+		return nil, wrapError("begin_reembed", fmt.Errorf("failed to save checkpoint: %w", err))
+	}
+
+	state, err := s.reembedState(ctx, collectionID, modelID)
+	if err != nil {
+		return nil, wrapError("begin_reembed", err)
+	}
+	return state, nil
+}
+
+// GetReembedState returns the checkpoint of the job moving collection to modelID
+func (s *SQLiteStore) GetReembedState(ctx context.Context, collection, modelID string) (*ReembedState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("get_reembed_state", ErrStoreClosed)
+	}
+
+	collectionID, _, err := s.reembedCollection(ctx, collection)
+	if err != nil {
+		return nil, wrapError("get_reembed_state", err)
+	}
+	state, err := s.reembedState(ctx, collectionID, modelID)
+	if err != nil {
+		return nil, wrapError("get_reembed_state", err)
+	}
+	return state, nil
+}
+
+// PendingReembed returns up to limit embeddings after afterID, in ID order,
+// that have content but no shadow vector from modelID. Only ID and Content
+// are set.
+func (s *SQLiteStore) PendingReembed(ctx context.Context, collection, modelID, afterID string, limit int) ([]*Embedding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("pending_reembed", ErrStoreClosed)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	collectionID, _, err := s.reembedCollection(ctx, collection)
+	if err != nil {
+		return nil, wrapError("pending_reembed", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, content FROM embeddings
+		WHERE collection_id = ? AND id > ? AND content != ''
+			AND (shadow_model_id IS NULL OR shadow_model_id != ?)
+		ORDER BY id
+		LIMIT ?
+	`, collectionID, afterID, modelID, limit)
+	if err != nil {
+		return nil, wrapError("pending_reembed", fmt.Errorf("failed to query embeddings: %w", err))
+	}
+	defer rows.Close()
+
+	var pending []*Embedding
+	for rows.Next() {
+		emb := &Embedding{CollectionID: collectionID}
+		if err := rows.Scan(&emb.ID, &emb.Content); err != nil {
+			return nil, wrapError("pending_reembed", fmt.Errorf("failed to scan embedding: %w", err))
+		}
+		pending = append(pending, emb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("pending_reembed", err)
+	}
+	return pending, nil
+}
+
+// WriteShadowVectors stores re-embedded vectors for embeddings returned by
+// PendingReembed and advances the checkpoint past the last of them. Searches
+// keep using the current vectors until PromoteReembed.
+func (s *SQLiteStore) WriteShadowVectors(ctx context.Context, collection, modelID string, embs []*Embedding) (*ReembedState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("write_shadow_vectors", ErrStoreClosed)
+	}
+
+	collectionID, dims, err := s.reembedCollection(ctx, collection)
+	if err != nil {
+		return nil, wrapError("write_shadow_vectors", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, wrapError("write_shadow_vectors", fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer tx.Rollback()
+
+	var lastID string
+	var written int64
+	for _, emb := range embs {
+		if dims > 0 && len(emb.Vector) != dims {
+			return nil, wrapError("write_shadow_vectors", fmt.Errorf("embedding '%s' has %d dimensions, collection expects %d", emb.ID, len(emb.Vector), dims))
+		}
+		vectorBytes, err := encoding.EncodeVector(emb.Vector)
+		if err != nil {
+			return nil, wrapError("write_shadow_vectors", err)
+		}
+		res, err := tx.ExecContext(ctx, `
+			UPDATE embeddings SET shadow_vector = ?, shadow_model_id = ? WHERE id = ? AND collection_id = ?
+		`, vectorBytes, modelID, emb.ID, collectionID)
+		if err != nil {
+			return nil, wrapError("write_shadow_vectors", fmt.Errorf("failed to write shadow vector: %w", err))
+		}
+		// Rows deleted since PendingReembed are skipped
+		if n, _ := res.RowsAffected(); n > 0 {
+			written++
+		}
+		if emb.ID > lastID {
+			lastID = emb.ID
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE reembed_jobs
+		SET last_id = MAX(last_id, ?), processed = processed + ?, updated_at = CURRENT_TIMESTAMP
+		WHERE collection_id = ? AND model_id = ? AND status = ?
+	`, lastID, written, collectionID, modelID, ReembedRunning)
+	if err != nil {
+		return nil, wrapError("write_shadow_vectors", fmt.Errorf("failed to save checkpoint: %w", err))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, wrapError("write_shadow_vectors", fmt.Errorf("failed to commit shadow vectors: %w", err))
+	}
+
+	state, err := s.reembedState(ctx, collectionID, modelID)
+	if err != nil {
+		return nil, wrapError("write_shadow_vectors", err)
+	}
+	return state, nil
+}
+
+// PromoteReembed replaces the collection's vectors with their shadow vectors
+// from modelID, makes modelID the collection's model and rebuilds the
+// indexes, retraining IVF centroids and IVF-PQ codebooks on the new vectors. It returns how many vectors were promoted and how many embeddings
+// in the collection still come from another model.
+func (s *SQLiteStore) PromoteReembed(ctx context.Context, collection, modelID string) (promoted, stale int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, 0, wrapError("promote_reembed", ErrStoreClosed)
+	}
+
+	collectionID, _, err := s.reembedCollection(ctx, collection)
+	if err != nil {
+		return 0, 0, wrapError("promote_reembed", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, wrapError("promote_reembed", fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE embeddings
+		SET vector = shadow_vector, model_id = shadow_model_id, binary_code = NULL,
+			shadow_vector = NULL, shadow_model_id = NULL, revision = revision + 1
+		WHERE collection_id = ? AND shadow_model_id = ?
+	`, collectionID, modelID)
+	if err != nil {
+		return 0, 0, wrapError("promote_reembed", fmt.Errorf("failed to promote shadow vectors: %w", err))
+	}
+	promoted, _ = res.RowsAffected()
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM embeddings WHERE collection_id = ? AND (model_id IS NULL OR model_id != ?)
+	`, collectionID, modelID).Scan(&stale)
+	if err != nil {
+		return 0, 0, wrapError("promote_reembed", fmt.Errorf("failed to count stale embeddings: %w", err))
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE collections SET model_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", modelID, collectionID); err != nil {
+		return 0, 0, wrapError("promote_reembed", fmt.Errorf("failed to switch collection model: %w", err))
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE reembed_jobs SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE collection_id = ? AND model_id = ?
+	`, ReembedDone, collectionID, modelID)
+	if err != nil {
+		return 0, 0, wrapError("promote_reembed", fmt.Errorf("failed to save checkpoint: %w", err))
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, wrapError("promote_reembed", fmt.Errorf("failed to commit promotion: %w", err))
+	}
+
+	// The rebuild reads every change logged so far, including the promotion
+	latest, err := s.latestChangeSeq(ctx)
+	if err != nil {
+		return promoted, stale, wrapError("promote_reembed", err)
+	}
+	if err := s.rebuildIndexes(ctx, true); err != nil {
+		return promoted, stale, wrapError("promote_reembed", err)
+	}
+	s.changeSeq.Store(latest)
+	s.localChanges.reset()
+
+	// A restart must not reload centroids trained on the old model
+	if err := s.saveIndexSnapshot(ctx); err != nil {
+		s.logger.Warn("failed to save index snapshot after promotion", "error", err)
+	}
+
+	s.logger.Info("re-embedding promoted", "collection", collection, "model", modelID, "promoted", promoted, "stale", stale)
+	return promoted, stale, nil
+}
+
+// CollectionModel returns the embedding model a collection's vectors were
+// promoted to, or "" when the collection has none recorded or doesn't exist.
+// Empty means the default collection.
+func (s *SQLiteStore) CollectionModel(ctx context.Context, collection string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return "", wrapError("collection_model", ErrStoreClosed)
+	}
+
+	if collection == "" {
+		collection = "default"
+	}
+	name, err := s.lookupAliasTarget(ctx, collection)
+	if err != nil {
+		return "", wrapError("collection_model", err)
+	}
+	var modelID string
+	err = s.db.QueryRowContext(ctx, "SELECT COALESCE(model_id, '') FROM collections WHERE name = ?", name).Scan(&modelID)
+	if err != nil && err != sql.ErrNoRows {
+		return "", wrapError("collection_model", fmt.Errorf("failed to read collection model: %w", err))
+	}
+	return modelID, nil
+}
+
+// reembedCollection resolves a collection name or alias, where empty means
+// the default collection, to its ID and the dimension its vectors must have
+func (s *SQLiteStore) reembedCollection(ctx context.Context, collection string) (int, int, error) {
+	if collection == "" {
+		collection = "default"
+	}
+	name, err := s.lookupAliasTarget(ctx, collection)
+	if err != nil {
+		return 0, 0, err
+	}
+	var id, dims int
+	err = s.db.QueryRowContext(ctx, "SELECT id, dimensions FROM collections WHERE name = ?", name).Scan(&id, &dims)
+	if err == sql.ErrNoRows {
+		return 0, 0, fmt.Errorf("%w: collection '%s'", ErrNotFound, collection)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find collection: %w", err)
+	}
+	if dims == 0 {
+		dims = s.config.VectorDim
+	}
+	return id, dims, nil
+}
+
+// reembedState loads a job checkpoint
+func (s *SQLiteStore) reembedState(ctx context.Context, collectionID int, modelID string) (*ReembedState, error) {
+	state := &ReembedState{ModelID: modelID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT c.name, j.status, j.last_id, j.processed, j.total, j.started_at, j.updated_at
+		FROM reembed_jobs j JOIN collections c ON c.id = j.collection_id
+		WHERE j.collection_id = ? AND j.model_id = ?
+	`, collectionID, modelID).Scan(&state.Collection, &state.Status, &state.LastID, &state.Processed, &state.Total, &state.StartedAt, &state.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: no re-embedding job for model '%s'", ErrNotFound, modelID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestPromoteReembedRetrains(t *testing.T) {
+	ctx := context.Background()
+
+	// The old model only uses the first half of the dimensions, the new
+	// one only the second half
+	const dim = 16
+	rng := rand.New(rand.NewSource(11))
+	modelVec := func(offset int) []float32 {
+		vec := make([]float32, dim)
+		for j := offset; j < offset+dim/2; j++ {
+			vec[j] = rng.Float32()*2 - 1
+		}
+		return vec
+	}
+	oldMass := func(centroids [][]float32) float64 {
+		var mass float64
+		for _, c := range centroids {
+			for _, v := range c[:dim/2] {
+				mass += float64(v * v)
+			}
+		}
+		return mass
+	}
+
+	for name, indexType := range map[string]IndexType{"IVF": IndexTypeIVF, "IVFPQ": IndexTypeIVFPQ} {
+		t.Run(name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Path = filepath.Join(t.TempDir(), "reembed.db")
+			config.VectorDim = dim
+			config.IndexType = indexType
+			config.HNSW.Enabled = false
+			config.IVFPQ.NCentroids = 8
+			config.AutoSave.Enabled = false
+
+			store, err := NewWithConfig(config)
+			if err != nil {
+				t.Fatalf("Failed to create store: %v", err)
+			}
+			if err := store.Init(ctx); err != nil {
+				t.Fatalf("Failed to initialize store: %v", err)
+			}
+			defer store.Close()
+
+			for i := 0; i < 400; i++ {
+				emb := &Embedding{ID: fmt.Sprintf("vec_%03d", i), Vector: modelVec(0), Content: fmt.Sprintf("text %d", i)}
+				if err := store.Upsert(ctx, emb); err != nil {
+					t.Fatalf("Upsert failed: %v", err)
+				}
+			}
+			if err := store.TrainIndex(ctx, 8); err != nil {
+				t.Fatalf("TrainIndex failed: %v", err)
+			}
+
+			if _, err := store.BeginReembed(ctx, "", "v2"); err != nil {
+				t.Fatalf("BeginReembed failed: %v", err)
+			}
+			pending, err := store.PendingReembed(ctx, "", "v2", "", 1000)
+			if err != nil {
+				t.Fatalf("PendingReembed failed: %v", err)
+			}
+			for _, emb := range pending {
+				emb.Vector = modelVec(dim / 2)
+			}
+			if _, err := store.WriteShadowVectors(ctx, "", "v2", pending); err != nil {
+				t.Fatalf("WriteShadowVectors failed: %v", err)
+			}
+			if promoted, _, err := store.PromoteReembed(ctx, "", "v2"); err != nil || promoted != 400 {
+				t.Fatalf("PromoteReembed promoted %d, %v", promoted, err)
+			}
+
+			var centroids [][]float32
+			if indexType == IndexTypeIVF {
+				centroids = store.ivfIndex.Centroids
+			} else {
+				centroids = store.ivfpqIndex.Centroids
+			}
+			if mass := oldMass(centroids); mass > 1e-6 {
+				t.Errorf("Expected centroids retrained on the new vectors, old dimensions hold %f", mass)
+			}
+
+			emb, err := store.GetByID(ctx, "vec_123")
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			results, err := store.Search(ctx, emb.Vector, SearchOptions{TopK: 3})
+			if err != nil || len(results) == 0 || results[0].ID != "vec_123" {
+				t.Errorf("Expected vec_123 as top result, got %v, %v", results, err)
+			}
+
+			if model, err := store.CollectionModel(ctx, ""); err != nil || model != "v2" {
+				t.Errorf("Expected the collection model v2, got %q, %v", model, err)
+			}
+		})
+	}
+}
@@ -13,6 +13,13 @@ import (
 
 // Search performs vector similarity search
 func (s *SQLiteStore) Search(ctx context.Context, query []float32, opts SearchOptions) ([]ScoredEmbedding, error) {
+	s.applyExternalWrites(ctx)
+	return s.search(ctx, query, opts)
+}
+
+// search is Search without catching up on external writes, for callers that
+// already hold s.mu
+func (s *SQLiteStore) search(ctx context.Context, query []float32, opts SearchOptions) ([]ScoredEmbedding, error) {
 	s.mu.RLock()
 	storeDim := s.config.VectorDim
 	s.mu.RUnlock()
@@ -25,7 +32,7 @@ func (s *SQLiteStore) Search(ctx context.Context, query []float32, opts SearchOp
 
 	// Auto-adapt query vector if dimensions don't match
 	if storeDim > 0 && queryDim != storeDim {
-		adaptedQuery, err := s.adapter.AdaptVector(query, queryDim, storeDim)
+		adaptedQuery, err := s.adaptForCollection(ctx, 0, opts.Collection, query, storeDim)
 		if err != nil {
 			return nil, wrapError("search", fmt.Errorf("query adaptation failed: %w", err))
 		}
@@ -40,6 +47,13 @@ func (s *SQLiteStore) Search(ctx context.Context, query []float32, opts SearchOp
 		return nil, wrapError("search", err)
 	}
 
+	opts.Collection = s.resolveCollectionName(ctx, opts.Collection)
+
+	// Collections created with WithPrefixIndex search their own prefix index
+	if p := s.prefixIndexFor(opts.Collection); p != nil {
+		return s.searchWithPrefix(ctx, p, query, opts)
+	}
+
 	// Use HNSW index if available and enabled
 	if s.config.HNSW.Enabled && s.hnswIndex != nil {
 		return s.searchWithHNSW(ctx, query, opts)
@@ -50,6 +64,26 @@ func (s *SQLiteStore) Search(ctx context.Context, query []float32, opts SearchOp
 		return s.searchWithIVF(ctx, query, opts)
 	}
 
+	// Use IVF-PQ index once it has been trained
+	if s.config.IndexType == IndexTypeIVFPQ && s.ivfpqIndex != nil {
+		return s.searchWithIVFPQ(ctx, query, opts)
+	}
+
+	// Use DiskANN index once it has been built
+	if s.config.IndexType == IndexTypeDiskANN && s.diskANN != nil {
+		return s.searchWithDiskANN(ctx, query, opts)
+	}
+
+	// Use LSH, hybrid or multi-index once it has been built
+	if s.usesVectorIndex() && s.vectorIndex != nil {
+		return s.searchWithVectorIndex(ctx, query, opts)
+	}
+
+	// Use binary codes once the dimension is known
+	if s.config.IndexType == IndexTypeBinary && s.binaryIndex != nil {
+		return s.searchWithBinary(ctx, query, opts)
+	}
+
 	// Fallback to linear search
 	candidates, err := s.fetchCandidates(ctx, opts)
 	if err != nil {
@@ -70,11 +104,13 @@ func (s *SQLiteStore) SearchWithFilter(ctx context.Context, query []float32, opt
 		return nil, wrapError("searchWithFilter", ErrStoreClosed)
 	}
 
+	s.applyExternalWrites(ctx)
+
 	queryDim := len(query)
 
 	// Auto-adapt query vector if dimensions don't match
 	if storeDim > 0 && queryDim != storeDim {
-		adaptedQuery, err := s.adapter.AdaptVector(query, queryDim, storeDim)
+		adaptedQuery, err := s.adaptForCollection(ctx, 0, opts.Collection, query, storeDim)
 		if err != nil {
 			return nil, wrapError("searchWithFilter", fmt.Errorf("query adaptation failed: %w", err))
 		}
@@ -89,16 +125,32 @@ func (s *SQLiteStore) SearchWithFilter(ctx context.Context, query []float32, opt
 		return nil, wrapError("searchWithFilter", err)
 	}
 
+	opts.Collection = s.resolveCollectionName(ctx, opts.Collection)
+
 	// First perform the standard search
 	var candidates []ScoredEmbedding
 	var err error
 
-	// Use HNSW index if available and enabled
-	if s.config.HNSW.Enabled && s.hnswIndex != nil {
+	// Use the collection's prefix index, then HNSW index if available and enabled
+	if p := s.prefixIndexFor(opts.Collection); p != nil {
+		candidates, err = s.searchWithPrefix(ctx, p, query, opts)
+	} else if s.config.HNSW.Enabled && s.hnswIndex != nil {
 		candidates, err = s.searchWithHNSW(ctx, query, opts)
 	} else if s.config.IndexType == IndexTypeIVF && s.ivfIndex != nil && s.ivfIndex.Trained {
 		// Use IVF index
 		candidates, err = s.searchWithIVF(ctx, query, opts)
+	} else if s.config.IndexType == IndexTypeIVFPQ && s.ivfpqIndex != nil {
+		// Use IVF-PQ index
+		candidates, err = s.searchWithIVFPQ(ctx, query, opts)
+	} else if s.config.IndexType == IndexTypeDiskANN && s.diskANN != nil {
+		// Use DiskANN index
+		candidates, err = s.searchWithDiskANN(ctx, query, opts)
+	} else if s.usesVectorIndex() && s.vectorIndex != nil {
+		// Use LSH, hybrid or multi-index
+		candidates, err = s.searchWithVectorIndex(ctx, query, opts)
+	} else if s.config.IndexType == IndexTypeBinary && s.binaryIndex != nil {
+		// Use binary codes
+		candidates, err = s.searchWithBinary(ctx, query, opts)
 	} else {
 		// Fallback to linear search
 		candidates, err = s.fetchCandidates(ctx, opts)
@@ -178,6 +230,111 @@ func (s *SQLiteStore) searchWithIVF(ctx context.Context, query []float32, opts S
 	return s.processCandidates(query, candidates, opts)
 }
 
+// searchWithIVFPQ performs vector search using the IVF-PQ index. With
+// reranking, extra candidates are rescored with the exact vectors fetched
+// from SQLite; otherwise results are ranked by the PQ distances.
+func (s *SQLiteStore) searchWithIVFPQ(ctx context.Context, query []float32, opts SearchOptions) ([]ScoredEmbedding, error) {
+	if opts.TopK <= 0 {
+		opts.TopK = 10
+	}
+
+	factor := 2 // Headroom for collection and metadata filters
+	if s.config.IVFPQ.Rerank {
+		factor = s.config.IVFPQ.RerankFactor
+		if factor <= 0 {
+			factor = DefaultIVFPQConfig().RerankFactor
+		}
+	}
+
+	candidateIDs, distances, err := s.ivfpqIndex.Search(query, opts.TopK*factor)
+	if err != nil {
+		s.logger.Warn("IVF-PQ search failed, falling back to linear search", "error", err)
+		return s.searchLinear(ctx, query, opts)
+	}
+
+	if len(candidateIDs) == 0 {
+		return s.searchLinear(ctx, query, opts)
+	}
+
+	candidates, err := s.fetchEmbeddingsByIDs(ctx, candidateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch candidates: %w", err)
+	}
+
+	if s.config.IVFPQ.Rerank {
+		return s.processCandidates(query, candidates, opts)
+	}
+
+	// The index holds unit vectors, where squared L2 = 2 - 2*cosine
+	approx := make(map[string]float64, len(candidateIDs))
+	for i, id := range candidateIDs {
+		approx[id] = 1 - float64(distances[i])/2
+	}
+	return s.rankCandidates(candidates, opts, func(emb *Embedding) float64 {
+		return approx[emb.ID]
+	}), nil
+}
+
+// diskANNFilterOversample is the candidate multiple fetched from DiskANN
+// when a collection or metadata filter narrows the results
+const diskANNFilterOversample = 8
+
+// searchWithDiskANN performs vector search using the DiskANN index
+func (s *SQLiteStore) searchWithDiskANN(ctx context.Context, query []float32, opts SearchOptions) ([]ScoredEmbedding, error) {
+	if opts.TopK <= 0 {
+		opts.TopK = 10
+	}
+
+	// Candidates are already reranked with the full vectors from disk;
+	// fetch extra to allow for filtering
+	filtered := opts.Collection != "" || len(opts.Filter) > 0
+	depth := opts.TopK * 2
+	if filtered {
+		depth = opts.TopK * diskANNFilterOversample
+	}
+	candidateIDs, _ := s.diskANN.Search(query, depth)
+	if len(candidateIDs) == 0 {
+		return s.searchLinear(ctx, query, opts)
+	}
+
+	candidates, err := s.fetchEmbeddingsByIDs(ctx, candidateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch candidates: %w", err)
+	}
+
+	results, err := s.processCandidates(query, candidates, opts)
+	if err != nil {
+		return nil, err
+	}
+	// A selective filter can reject most of the nearest neighbours; scan
+	// exactly rather than return fewer matches than exist
+	if filtered && len(results) < opts.TopK && len(candidateIDs) == depth {
+		return s.searchLinear(ctx, query, opts)
+	}
+	return results, nil
+}
+
+// searchWithVectorIndex performs vector search using the LSH, hybrid or
+// multi-index, rescoring its candidates with the configured similarity
+func (s *SQLiteStore) searchWithVectorIndex(ctx context.Context, query []float32, opts SearchOptions) ([]ScoredEmbedding, error) {
+	if opts.TopK <= 0 {
+		opts.TopK = 10
+	}
+
+	// Fetch 4x candidates to allow for filtering
+	candidateIDs, _ := s.vectorIndex.Search(normalizeVector(query), opts.TopK*4)
+	if len(candidateIDs) == 0 {
+		return s.searchLinear(ctx, query, opts)
+	}
+
+	candidates, err := s.fetchEmbeddingsByIDs(ctx, candidateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch candidates: %w", err)
+	}
+
+	return s.processCandidates(query, candidates, opts)
+}
+
 // searchLinear performs linear vector search without HNSW index
 func (s *SQLiteStore) searchLinear(ctx context.Context, query []float32, opts SearchOptions) ([]ScoredEmbedding, error) {
 	candidates, err := s.fetchCandidates(ctx, opts)
@@ -191,6 +348,14 @@ func (s *SQLiteStore) searchLinear(ctx context.Context, query []float32, opts Se
 
 // processCandidates applies scoring and filtering to candidates
 func (s *SQLiteStore) processCandidates(query []float32, candidates []ScoredEmbedding, opts SearchOptions) ([]ScoredEmbedding, error) {
+	return s.rankCandidates(candidates, opts, func(emb *Embedding) float64 {
+		return s.similarityFn(query, emb.Vector)
+	}), nil
+}
+
+// rankCandidates filters and scores candidates using vectorScore for the
+// vector part of the score, and returns the top-k
+func (s *SQLiteStore) rankCandidates(candidates []ScoredEmbedding, opts SearchOptions, vectorScore func(*Embedding) float64) []ScoredEmbedding {
 	textWeight := s.getTextWeight(opts)
 	vectorWeight := 1.0 - textWeight
 
@@ -207,7 +372,7 @@ func (s *SQLiteStore) processCandidates(query []float32, candidates []ScoredEmbe
 		}
 
 		// Calculate vector similarity score
-		vectorScore := s.similarityFn(query, candidate.Vector)
+		vecScore := vectorScore(&candidate.Embedding)
 
 		// Calculate text similarity score (if enabled and query text provided)
 		textScore := 0.0
@@ -216,9 +381,9 @@ func (s *SQLiteStore) processCandidates(query []float32, candidates []ScoredEmbe
 		}
 
 		// Combine scores
-		finalScore := vectorScore
+		finalScore := vecScore
 		if textWeight > 0 && textScore > 0 {
-			finalScore = vectorScore*vectorWeight + textScore*textWeight
+			finalScore = vecScore*vectorWeight + textScore*textWeight
 		}
 
 		// Apply threshold filter
@@ -240,7 +405,7 @@ func (s *SQLiteStore) processCandidates(query []float32, candidates []ScoredEmbe
 		results = results[:opts.TopK]
 	}
 
-	return results, nil
+	return results
 }
 
 // fetchEmbeddingsByIDs fetches embeddings by their IDs
@@ -0,0 +1,393 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+)
+
+// Several processes may open the same database file, for example an
+// ingestion job and an MCP server. Once a store with ExternalWrites enabled
+// has opened the file, triggers record every embedding write in
+// embedding_changes, whichever process made it, and each store remembers the
+// last change its in-memory indexes reflect. Before a search the store reads
+// MAX(seq) and, when another process has written, re-reads the changed rows
+// and applies them to its indexes. Changes this store made itself are
+// recognized and skipped.
+
+// embeddingChangesTriggersSQL logs inserts, vector or collection updates and
+// deletes of embeddings. Created after migrateColumns, as it reads revision.
+const embeddingChangesTriggersSQL = `
+	CREATE TRIGGER IF NOT EXISTS embeddings_changes_ai AFTER INSERT ON embeddings BEGIN
+	  INSERT INTO embedding_changes(embedding_id, op, revision) VALUES (new.id, 'upsert', new.revision);
+	END;
+	CREATE TRIGGER IF NOT EXISTS embeddings_changes_au AFTER UPDATE OF vector, collection_id ON embeddings BEGIN
+	  INSERT INTO embedding_changes(embedding_id, op, revision) VALUES (new.id, 'upsert', new.revision);
+	END;
+	CREATE TRIGGER IF NOT EXISTS embeddings_changes_ad AFTER DELETE ON embeddings BEGIN
+	  INSERT INTO embedding_changes(embedding_id, op, revision) VALUES (old.id, 'delete', old.revision);
+	END;
+`
+
+const (
+	changeOpUpsert = "upsert"
+	changeOpDelete = "delete"
+)
+
+// localChange is a write this store already applied to its indexes
+type localChange struct {
+	op       string
+	revision int64 // Ignored for deletes
+}
+
+// maxLocalChanges bounds the writes remembered between syncs. A store that
+// writes without searching stops remembering, and its next sync re-reads
+// every changed row instead.
+const maxLocalChanges = 100000
+
+// localChanges remembers writes applied by this store until the next sync
+// consumes their change log rows
+type localChanges struct {
+	mu         sync.Mutex
+	changes    map[string][]localChange
+	size       int
+	overflowed bool
+}
+
+// add records applied writes
+func (lc *localChanges) add(op string, revision int64, ids ...string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.overflowed {
+		return
+	}
+	if lc.size+len(ids) > maxLocalChanges {
+		lc.changes, lc.size, lc.overflowed = nil, 0, true
+		return
+	}
+	if lc.changes == nil {
+		lc.changes = make(map[string][]localChange)
+	}
+	for _, id := range ids {
+		lc.changes[id] = append(lc.changes[id], localChange{op: op, revision: revision})
+	}
+	lc.size += len(ids)
+}
+
+// consume reports whether a logged change was made by this store
+func (lc *localChanges) consume(id, op string, revision int64) bool {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for i, c := range lc.changes[id] {
+		if c.op == op && (op == changeOpDelete || c.revision == revision) {
+			lc.changes[id] = append(lc.changes[id][:i], lc.changes[id][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// reset forgets all recorded writes
+func (lc *localChanges) reset() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.changes, lc.size, lc.overflowed = nil, 0, false
+}
+
+// noteLocalUpserts records embeddings this store wrote and indexed
+func (s *SQLiteStore) noteLocalUpserts(embs ...*Embedding) {
+	if s.config.ExternalWrites.Enabled {
+		for _, emb := range embs {
+			s.localChanges.add(changeOpUpsert, emb.Revision, emb.ID)
+		}
+	}
+	s.notePrunableWrites(len(embs))
+}
+
+// noteLocalDeletes records embeddings this store deleted and unindexed
+func (s *SQLiteStore) noteLocalDeletes(ids ...string) {
+	if s.config.ExternalWrites.Enabled {
+		s.localChanges.add(changeOpDelete, 0, ids...)
+	}
+	s.notePrunableWrites(len(ids))
+}
+
+// notePrunableWrites prunes the change log every LogRetention/10 writes.
+// The triggers log writes from every process once any process enabled
+// ExternalWrites, so a store that only writes, or has the feature off, must
+// prune as well.
+func (s *SQLiteStore) notePrunableWrites(n int) {
+	retention := int64(s.config.ExternalWrites.LogRetention)
+	if retention <= 0 {
+		return
+	}
+	if s.writesSincePrune.Add(int64(n)) < max(retention/10, 1) {
+		return
+	}
+	s.writesSincePrune.Store(0)
+	s.pruneChangeLog(context.Background())
+}
+
+// pruneChangeLog keeps the newest LogRetention change log rows
+func (s *SQLiteStore) pruneChangeLog(ctx context.Context) {
+	retention := s.config.ExternalWrites.LogRetention
+	if retention <= 0 {
+		return
+	}
+	_, err := s.db.ExecContext(ctx,
+		"DELETE FROM embedding_changes WHERE seq <= (SELECT COALESCE(MAX(seq), 0) FROM embedding_changes) - ?", retention)
+	if err != nil {
+		s.logger.Warn("failed to prune change log", "error", err)
+	}
+}
+
+// latestChangeSeq returns the sequence number of the newest logged change
+func (s *SQLiteStore) latestChangeSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM embedding_changes").Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to read change log: %w", err)
+	}
+	return seq, nil
+}
+
+// applyExternalWrites catches the indexes up before a search. Failures are
+// logged, as the search can still run on the indexes as they are. Callers
+// must not hold s.mu.
+func (s *SQLiteStore) applyExternalWrites(ctx context.Context) {
+	if err := s.syncExternalWrites(ctx); err != nil {
+		s.logger.Warn("failed to apply external writes", "error", err)
+	}
+}
+
+// syncExternalWrites applies embedding writes made by other processes to the
+// in-memory indexes. It is cheap when nothing changed.
+func (s *SQLiteStore) syncExternalWrites(ctx context.Context) error {
+	if !s.config.ExternalWrites.Enabled {
+		return nil
+	}
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		return nil
+	}
+	if interval := s.config.ExternalWrites.CheckInterval; interval > 0 {
+		now := time.Now().UnixNano()
+		last := s.lastChangeCheck.Load()
+		if now-last < int64(interval) || !s.lastChangeCheck.CompareAndSwap(last, now) {
+			return nil
+		}
+	}
+
+	latest, err := s.latestChangeSeq(ctx)
+	if err != nil {
+		return err
+	}
+	if latest <= s.changeSeq.Load() {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	return s.applyChanges(ctx)
+}
+
+// applyChanges reads the change log past s.changeSeq and resyncs every
+// embedding another process wrote. The caller holds s.mu.
+func (s *SQLiteStore) applyChanges(ctx context.Context) error {
+	from := s.changeSeq.Load()
+
+	// Rows this store needs may have been pruned; rebuild instead
+	var oldest int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MIN(seq), 0) FROM embedding_changes").Scan(&oldest); err != nil {
+		return fmt.Errorf("failed to read change log: %w", err)
+	}
+	if oldest > from+1 {
+		latest, err := s.latestChangeSeq(ctx)
+		if err != nil {
+			return err
+		}
+		s.logger.Warn("change log pruned past this store, rebuilding indexes", "from", from, "oldest", oldest)
+		if err := s.rebuildIndexes(ctx, false); err != nil {
+			return err
+		}
+		s.changeSeq.Store(latest)
+		s.localChanges.reset()
+		return nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT seq, embedding_id, op, revision FROM embedding_changes WHERE seq > ? ORDER BY seq", from)
+	if err != nil {
+		return fmt.Errorf("failed to read change log: %w", err)
+	}
+	last := from
+	var changed []string
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var id, op string
+		var revision int64
+		if err := rows.Scan(&last, &id, &op, &revision); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan change: %w", err)
+		}
+		if s.localChanges.consume(id, op, revision) || seen[id] {
+			continue
+		}
+		seen[id] = true
+		changed = append(changed, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating change log: %w", err)
+	}
+
+	if err := s.resyncEmbeddings(ctx, changed); err != nil {
+		return err
+	}
+	s.changeSeq.Store(last)
+	s.localChanges.reset()
+	if len(changed) > 0 {
+		s.logger.Info("applied external writes", "embeddings", len(changed), "seq", last)
+	}
+
+	s.pruneChangeLog(ctx)
+	return nil
+}
+
+// resyncEmbeddings replaces the indexed state of ids with the rows currently
+// in the database. The caller holds s.mu.
+func (s *SQLiteStore) resyncEmbeddings(ctx context.Context, ids []string) error {
+	const chunkSize = 500
+	for start := 0; start < len(ids); start += chunkSize {
+		chunk := ids[start:min(start+chunkSize, len(ids))]
+
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			placeholders[i] = "?"
+			args[i] = id
+		}
+		rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+			"SELECT id, collection_id, vector, revision FROM embeddings WHERE id IN (%s)", strings.Join(placeholders, ",")), args...)
+		if err != nil {
+			return fmt.Errorf("failed to fetch changed embeddings: %w", err)
+		}
+		present := make(map[string]*Embedding, len(chunk))
+		for rows.Next() {
+			emb := &Embedding{}
+			var vectorBytes []byte
+			if err := rows.Scan(&emb.ID, &emb.CollectionID, &vectorBytes, &emb.Revision); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan changed embedding: %w", err)
+			}
+			if emb.Vector, err = encoding.DecodeVector(vectorBytes); err != nil {
+				s.logger.Warn("failed to decode changed embedding", "id", emb.ID, "error", err)
+				continue
+			}
+			present[emb.ID] = emb
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating changed embeddings: %w", err)
+		}
+
+		for _, id := range chunk {
+			s.trackIndexWrite(id)
+			s.dropFromIndexes(id)
+			emb, ok := present[id]
+			if !ok {
+				continue
+			}
+			if s.config.VectorDim > 0 && len(emb.Vector) != s.config.VectorDim {
+				s.logger.Warn("skipping external write with mismatched dimension", "id", id, "dim", len(emb.Vector))
+				continue
+			}
+			s.indexEmbedding(emb)
+		}
+	}
+	return nil
+}
+
+// dropFromIndexes removes an embedding from every in-memory index, ignoring
+// indexes that do not hold it
+func (s *SQLiteStore) dropFromIndexes(id string) {
+	if s.hnswIndex != nil {
+		_ = s.hnswIndex.Delete(id)
+	}
+	if s.ivfIndex != nil {
+		_ = s.ivfIndex.Delete(id)
+	}
+	if s.ivfpqIndex != nil {
+		_ = s.ivfpqIndex.Delete(id)
+	}
+	if s.diskANN != nil {
+		_ = s.diskANN.Delete(id)
+	}
+	if s.vectorIndex != nil {
+		_ = s.vectorIndex.Delete(id)
+	}
+	if s.binaryIndex != nil {
+		_ = s.binaryIndex.Delete(id)
+	}
+	s.deleteFromPrefixIndexes(id)
+}
+
+// rebuildIndexes rebuilds every in-memory index from the embeddings table.
+// With retrain, trained IVF and IVF-PQ indexes learn new centroids and
+// codebooks instead of refilling the old ones, for when the vectors now come
+// from a different model. The caller holds s.mu.
+func (s *SQLiteStore) rebuildIndexes(ctx context.Context, retrain bool) error {
+	if s.config.HNSW.Enabled && s.hnswIndex != nil {
+		if s.quantizer != nil {
+			if err := s.TrainQuantizer(ctx); err != nil {
+				s.logger.Warn("failed to train quantizer during index rebuild", "error", err)
+			}
+		}
+		s.hnswIndex = s.newHNSWIndex()
+		if err := s.rebuildHNSWIndex(ctx); err != nil {
+			return err
+		}
+	}
+	if s.config.IndexType == IndexTypeIVF && s.ivfIndex != nil && s.ivfIndex.Trained {
+		if retrain {
+			if err := s.trainIVFIndex(ctx, s.ivfIndex.NCentroids); err != nil {
+				return err
+			}
+		} else if err := s.refillIVFIndex(ctx); err != nil {
+			return err
+		}
+	}
+	if s.config.IndexType == IndexTypeIVFPQ && s.ivfpqIndex != nil {
+		if retrain {
+			if err := s.trainIVFPQIndex(ctx, s.ivfpqIndex.NCentroids); err != nil {
+				return err
+			}
+		} else if err := s.refillIVFPQIndex(ctx); err != nil {
+			return err
+		}
+	}
+	if s.config.IndexType == IndexTypeDiskANN {
+		if err := s.rebuildDiskANNIndex(ctx); err != nil {
+			return err
+		}
+	}
+	if s.usesVectorIndex() {
+		if err := s.buildVectorIndex(ctx); err != nil {
+			s.logger.Warn("failed to build vector index during index rebuild", "error", err)
+		}
+	}
+	if s.config.IndexType == IndexTypeBinary && s.binaryQuantizer != nil {
+		if err := s.buildBinaryIndex(ctx); err != nil {
+			return err
+		}
+	}
+	return s.rebuildPrefixIndexes(ctx)
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+	"github.com/liliang-cn/cortexdb/v2/internal/simd"
 )
 
 func createDummyDoc(ctx context.Context, s *SQLiteStore, id string) error {
@@ -55,6 +56,17 @@ func TestSimilarityFunctions(t *testing.T) {
 			},
 			epsilon: 1e-6,
 		},
+		{
+			// float32 sums lose the 1 next to 1e8
+			name:    "cancelling magnitudes",
+			vectorA: []float32{1e8, 1.0, -1e8},
+			vectorB: []float32{1.0, 1.0, 1.0},
+			expected: map[string]float64{
+				"cosine": 1 / (math.Sqrt(2e16+1) * math.Sqrt(3)),
+				"dot":    1.0,
+			},
+			epsilon: 1e-12,
+		},
 	}
 
 	for _, tt := range tests {
@@ -983,6 +995,23 @@ func BenchmarkCosineSimilarity(b *testing.B) {
 	}
 }
 
+// BenchmarkCosineSimilaritySIMD scores the same vectors as
+// BenchmarkCosineSimilarity with the float32 kernels the indexes use
+func BenchmarkCosineSimilaritySIMD(b *testing.B) {
+	vector1 := make([]float32, 768)
+	vector2 := make([]float32, 768)
+
+	for i := range vector1 {
+		vector1[i] = float32(i) * 0.1
+		vector2[i] = float32(i) * 0.2
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = simd.Cosine(vector1, vector2)
+	}
+}
+
 func BenchmarkVectorEncoding(b *testing.B) {
 	vector := make([]float32, 768)
 	for i := range vector {
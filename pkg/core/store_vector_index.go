@@ -0,0 +1,272 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+	"github.com/liliang-cn/cortexdb/v2/pkg/index"
+)
+
+// usesVectorIndex reports whether the configured index type is served by
+// s.vectorIndex: LSH, hybrid and multi-index ensembles
+func (s *SQLiteStore) usesVectorIndex() bool {
+	switch s.config.IndexType {
+	case IndexTypeLSH, IndexTypeHybrid, IndexTypeMulti:
+		return true
+	}
+	return false
+}
+
+// vectorIndexSnapshotType returns the index_snapshots key for s.vectorIndex
+func (s *SQLiteStore) vectorIndexSnapshotType() string {
+	switch s.config.IndexType {
+	case IndexTypeLSH:
+		return "LSH"
+	case IndexTypeHybrid:
+		return "HYBRID"
+	default:
+		return "MULTI"
+	}
+}
+
+// newVectorIndex creates an empty index for the configured index type.
+// Vectors are normalized before they reach the index, so every member
+// ranks by Euclidean distance consistently with cosine similarity.
+func (s *SQLiteStore) newVectorIndex() (index.PersistentIndex, error) {
+	switch s.config.IndexType {
+	case IndexTypeLSH:
+		return s.newMemberIndex(index.IndexTypeLSH)
+	case IndexTypeHybrid:
+		return s.newMemberIndex(index.IndexTypeHybrid)
+	}
+
+	cfg := s.config.MultiIndex
+	if cfg.PrimaryIndex == "" {
+		cfg.PrimaryIndex = DefaultMultiIndexConfig().PrimaryIndex
+	}
+	if cfg.CombineStrategy == "" {
+		cfg.CombineStrategy = DefaultMultiIndexConfig().CombineStrategy
+	}
+
+	multi := index.NewMultiIndex(index.MultiIndexConfig{
+		PrimaryIndex:     cfg.PrimaryIndex,
+		SecondaryIndices: cfg.SecondaryIndices,
+		CombineStrategy:  cfg.CombineStrategy,
+		RerankTopK:       cfg.RerankTopK,
+		Parallel:         cfg.Parallel,
+	})
+	for _, indexType := range append([]index.IndexType{cfg.PrimaryIndex}, cfg.SecondaryIndices...) {
+		member, err := s.newMemberIndex(indexType)
+		if err != nil {
+			return nil, err
+		}
+		multi.AddIndex(indexType, member)
+	}
+	return multi, nil
+}
+
+// newMemberIndex creates one index of the given type from the store config
+func (s *SQLiteStore) newMemberIndex(indexType index.IndexType) (index.PersistentIndex, error) {
+	dim := s.config.VectorDim
+	nCentroids := s.config.IVF.NCentroids
+	if nCentroids <= 0 {
+		nCentroids = DefaultIVFConfig().NCentroids
+	}
+
+	switch indexType {
+	case index.IndexTypeHNSW:
+		adapter := &index.HNSWAdapter{
+			HNSW: index.NewHNSW(s.config.HNSW.M, s.config.HNSW.EfConstruction, index.EuclideanDistance),
+		}
+		adapter.SetEf(s.config.HNSW.EfSearch)
+		return adapter, nil
+	case index.IndexTypeIVF:
+		adapter := index.NewIVFAdapter(dim, nCentroids)
+		if s.config.IVF.NProbe > 0 {
+			adapter.SetNProbe(s.config.IVF.NProbe)
+		}
+		return adapter, nil
+	case index.IndexTypeFlat:
+		return index.NewFlatAdapter(dim, index.EuclideanDistance), nil
+	case index.IndexTypeLSH:
+		return index.NewLSHAdapter(index.LSHConfig{
+			NumTables:    s.config.LSH.NumTables,
+			NumHashFuncs: s.config.LSH.NumHashFuncs,
+			Dimension:    dim,
+			Seed:         s.config.LSH.Seed,
+		}, s.config.LSH.NumProbes), nil
+	case index.IndexTypeHybrid:
+		hybrid := index.NewHybridIndex(dim, s.config.HNSW.M, nCentroids)
+		if s.config.IVF.NProbe > 0 {
+			hybrid.SetNProbe(s.config.IVF.NProbe)
+		}
+		return hybrid, nil
+	}
+	return nil, fmt.Errorf("%w: unknown index type %q", ErrInvalidConfig, indexType)
+}
+
+// vectorIndexNeedsTraining reports whether idx must be trained before inserts
+func vectorIndexNeedsTraining(idx index.PersistentIndex) bool {
+	if multi, ok := idx.(*index.MultiIndex); ok {
+		return multi.NeedsTraining()
+	}
+	_, ok := idx.(index.Trainable)
+	return ok
+}
+
+// initVectorIndex restores the LSH, hybrid or multi-index from its snapshot,
+// rebuilding it when the snapshot is missing or no longer matches the
+// embeddings table. Like IVF, indexes with IVF members stay untrained until
+// TrainIndex is called.
+func (s *SQLiteStore) initVectorIndex(ctx context.Context) error {
+	if !s.usesVectorIndex() || s.config.VectorDim <= 0 {
+		return nil
+	}
+
+	idx, err := s.newVectorIndex()
+	if err != nil {
+		return err
+	}
+	snapshotType := s.vectorIndexSnapshotType()
+
+	var data []byte
+	err = s.db.QueryRowContext(ctx, "SELECT data FROM index_snapshots WHERE type = ?", snapshotType).Scan(&data)
+	switch {
+	case err == sql.ErrNoRows:
+		if vectorIndexNeedsTraining(idx) {
+			s.logger.Info("vector index not trained yet", "type", snapshotType)
+			return nil
+		}
+	case err != nil:
+		return fmt.Errorf("failed to query index snapshot: %w", err)
+	default:
+		if err := idx.Load(bytes.NewReader(data)); err != nil {
+			s.logger.Warn("failed to load vector index snapshot, rebuilding", "type", snapshotType, "error", err)
+			break
+		}
+		var count int
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM embeddings").Scan(&count); err != nil {
+			return fmt.Errorf("failed to count embeddings: %w", err)
+		}
+		if idx.Size() == count {
+			s.vectorIndex = idx
+			s.logger.Info("vector index loaded from snapshot", "type", snapshotType, "vectors", count)
+			return nil
+		}
+		s.logger.Info("vector index snapshot is stale, rebuilding", "type", snapshotType, "index", idx.Size(), "table", count)
+	}
+
+	if err := s.buildVectorIndex(ctx); err != nil {
+		s.logger.Warn("failed to build vector index", "type", snapshotType, "error", err)
+	}
+	return nil
+}
+
+// buildVectorIndex builds a new LSH, hybrid or multi-index from every stored
+// vector, training IVF members on them first. The caller holds s.mu.
+func (s *SQLiteStore) buildVectorIndex(ctx context.Context) error {
+	if s.config.VectorDim <= 0 {
+		return fmt.Errorf("vector dimension not set")
+	}
+
+	idx, err := s.newVectorIndex()
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, vector FROM embeddings")
+	if err != nil {
+		return fmt.Errorf("failed to fetch vectors: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	var vectors [][]float32
+	for rows.Next() {
+		var id string
+		var vectorBytes []byte
+		if err := rows.Scan(&id, &vectorBytes); err != nil {
+			return fmt.Errorf("failed to scan vector: %w", err)
+		}
+		vec, err := encoding.DecodeVector(vectorBytes)
+		if err != nil {
+			s.logger.Warn("failed to decode vector during vector index build", "id", id, "error", err)
+			continue
+		}
+		ids = append(ids, id)
+		vectors = append(vectors, normalizeVector(vec))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if vectorIndexNeedsTraining(idx) {
+		if err := idx.(index.Trainable).Train(vectors); err != nil {
+			return fmt.Errorf("failed to train vector index: %w", err)
+		}
+	}
+
+	var errorCount int
+	for i, vec := range vectors {
+		if err := idx.Insert(ids[i], vec); err != nil {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		s.logger.Warn("some vectors failed to add to vector index", "count", errorCount)
+	}
+
+	s.vectorIndex = idx
+	s.logger.Info("vector index built", "type", s.vectorIndexSnapshotType(), "vectors", len(vectors))
+	return nil
+}
+
+// ensureVectorIndex builds an index without IVF members once the vector
+// dimension has been detected. The caller holds s.mu.
+func (s *SQLiteStore) ensureVectorIndex(ctx context.Context) {
+	if !s.usesVectorIndex() || s.vectorIndex != nil || s.config.VectorDim <= 0 {
+		return
+	}
+	idx, err := s.newVectorIndex()
+	if err != nil || vectorIndexNeedsTraining(idx) {
+		return
+	}
+	if err := s.buildVectorIndex(ctx); err != nil {
+		s.logger.Warn("failed to build vector index", "type", s.vectorIndexSnapshotType(), "error", err)
+	}
+}
+
+// addToVectorIndex inserts or replaces a stored embedding in s.vectorIndex.
+// A revision of 1 marks a brand-new row, which skips the delete: removing
+// an absent ID is a full scan for IVF members.
+func (s *SQLiteStore) addToVectorIndex(emb *Embedding) {
+	if emb.Revision != 1 {
+		_ = s.vectorIndex.Delete(emb.ID)
+	}
+	if err := s.vectorIndex.Insert(emb.ID, normalizeVector(emb.Vector)); err != nil {
+		s.logger.Warn("failed to add vector to vector index", "id", emb.ID, "error", err)
+	}
+}
+
+// saveVectorIndexSnapshot stores s.vectorIndex in index_snapshots
+func (s *SQLiteStore) saveVectorIndexSnapshot(ctx context.Context) error {
+	var buf bytes.Buffer
+	if err := s.vectorIndex.Save(&buf); err != nil {
+		return fmt.Errorf("failed to serialize vector index: %w", err)
+	}
+
+	snapshotType := s.vectorIndexSnapshotType()
+	query := `
+		INSERT OR REPLACE INTO index_snapshots (type, data, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+	`
+	if _, err := s.db.ExecContext(ctx, query, snapshotType, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to save index snapshot: %w", err)
+	}
+
+	s.logger.Info("index snapshot saved", "type", snapshotType)
+	return nil
+}
@@ -0,0 +1,308 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx, so write helpers can
+// run either directly against the database or inside a transaction
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Tx groups writes to embeddings, documents and any other table in the
+// database file into a single SQLite transaction.
+//
+// In-memory index updates are buffered and applied only after a successful
+// Commit, so a rolled back transaction leaves the indexes untouched.
+// Other packages can write through SQL and register their own index updates
+// with OnCommit.
+//
+// While a Tx is open it holds the SQLite write lock once it has written;
+// writes issued outside the Tx wait for it to finish. A Tx is not safe for
+// concurrent use.
+type Tx struct {
+	store    *SQLiteStore
+	tx       *sql.Tx
+	onCommit []func()
+	done     bool
+}
+
+// BeginTx starts a transaction spanning all tables of the store
+func (s *SQLiteStore) BeginTx(ctx context.Context) (*Tx, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, wrapError("begin_tx", ErrStoreClosed)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, wrapError("begin_tx", fmt.Errorf("failed to begin transaction: %w", err))
+	}
+
+	return &Tx{store: s, tx: tx}, nil
+}
+
+// SQL returns the underlying SQL transaction for writes to other tables
+func (t *Tx) SQL() *sql.Tx {
+	return t.tx
+}
+
+// OnCommit registers fn to run after the transaction commits.
+// Registered functions run in order and are discarded on rollback.
+func (t *Tx) OnCommit(fn func()) {
+	t.onCommit = append(t.onCommit, fn)
+}
+
+// Commit commits the transaction and then applies buffered index updates
+func (t *Tx) Commit() error {
+	if t.done {
+		return wrapError("commit", sql.ErrTxDone)
+	}
+	t.done = true
+
+	if err := t.tx.Commit(); err != nil {
+		return wrapError("commit", fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	for _, fn := range t.onCommit {
+		fn()
+	}
+	t.onCommit = nil
+
+	return nil
+}
+
+// Rollback aborts the transaction and discards buffered index updates.
+// Calling Rollback after Commit is a no-op, so it is safe to defer.
+func (t *Tx) Rollback() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	t.onCommit = nil
+
+	if err := t.tx.Rollback(); err != nil {
+		return wrapError("rollback", fmt.Errorf("failed to rollback transaction: %w", err))
+	}
+
+	return nil
+}
+
+func (t *Tx) check(op string) error {
+	if t.done {
+		return wrapError(op, sql.ErrTxDone)
+	}
+	t.store.mu.RLock()
+	closed := t.store.closed
+	t.store.mu.RUnlock()
+	if closed {
+		return wrapError(op, ErrStoreClosed)
+	}
+	return nil
+}
+
+// Upsert inserts or updates a single embedding within the transaction
+func (t *Tx) Upsert(ctx context.Context, emb *Embedding) error {
+	return t.upsert(ctx, emb, WriteCondition{})
+}
+
+// UpsertWithCondition writes a single embedding only if cond holds
+func (t *Tx) UpsertWithCondition(ctx context.Context, emb *Embedding, cond WriteCondition) error {
+	if err := cond.validate(); err != nil {
+		return wrapError("upsert", err)
+	}
+	return t.upsert(ctx, emb, cond)
+}
+
+func (t *Tx) upsert(ctx context.Context, emb *Embedding, cond WriteCondition) error {
+	if err := t.check("upsert"); err != nil {
+		return err
+	}
+
+	s := t.store
+	if err := s.prepareEmbedding(ctx, emb); err != nil {
+		return wrapError("upsert", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.writeEmbedding(ctx, t.tx, emb, cond); err != nil {
+		return wrapError("upsert", err)
+	}
+
+	t.OnCommit(func() { s.indexEmbedding(emb) })
+
+	return nil
+}
+
+// UpsertBatch inserts or updates multiple embeddings within the transaction
+func (t *Tx) UpsertBatch(ctx context.Context, embs []*Embedding) error {
+	for i, emb := range embs {
+		if err := t.upsert(ctx, emb, WriteCondition{}); err != nil {
+			return wrapError("upsert_batch", fmt.Errorf("embedding at index %d: %w", i, err))
+		}
+	}
+	return nil
+}
+
+// Delete removes an embedding by ID within the transaction
+func (t *Tx) Delete(ctx context.Context, id string) error {
+	if err := t.check("delete"); err != nil {
+		return err
+	}
+
+	if id == "" {
+		return wrapError("delete", fmt.Errorf("ID cannot be empty"))
+	}
+
+	result, err := t.tx.ExecContext(ctx, "DELETE FROM embeddings WHERE id = ?", id)
+	if err != nil {
+		return wrapError("delete", fmt.Errorf("failed to delete embedding: %w", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return wrapError("delete", fmt.Errorf("failed to get rows affected: %w", err))
+	}
+	if rowsAffected == 0 {
+		return wrapError("delete", ErrNotFound)
+	}
+
+	t.OnCommit(func() { t.store.unindexEmbedding(id) })
+
+	return nil
+}
+
+// DeleteByDocID removes all embeddings for a document within the transaction
+func (t *Tx) DeleteByDocID(ctx context.Context, docID string) error {
+	if err := t.check("delete_by_doc_id"); err != nil {
+		return err
+	}
+
+	if docID == "" {
+		return wrapError("delete_by_doc_id", fmt.Errorf("doc ID cannot be empty"))
+	}
+
+	if err := t.deleteEmbeddingsByDocID(ctx, docID); err != nil {
+		return wrapError("delete_by_doc_id", err)
+	}
+
+	return nil
+}
+
+// CreateDocument creates a new document record within the transaction
+func (t *Tx) CreateDocument(ctx context.Context, doc *Document) error {
+	if err := t.check("create_document"); err != nil {
+		return err
+	}
+
+	if err := createDocument(ctx, t.tx, doc); err != nil {
+		return wrapError("create_document", err)
+	}
+
+	return nil
+}
+
+// GetDocument retrieves a document by ID, including uncommitted changes
+func (t *Tx) GetDocument(ctx context.Context, id string) (*Document, error) {
+	if err := t.check("get_document"); err != nil {
+		return nil, err
+	}
+
+	doc, err := getDocument(ctx, t.tx, id)
+	if err != nil {
+		return nil, wrapError("get_document", err)
+	}
+
+	return doc, nil
+}
+
+// UpdateDocument updates an existing document within the transaction
+func (t *Tx) UpdateDocument(ctx context.Context, doc *Document) error {
+	if err := t.check("update_document"); err != nil {
+		return err
+	}
+
+	if err := updateDocument(ctx, t.tx, doc); err != nil {
+		return wrapError("update_document", err)
+	}
+
+	return nil
+}
+
+// UpsertDocumentWithCondition creates or updates a document only if cond holds
+func (t *Tx) UpsertDocumentWithCondition(ctx context.Context, doc *Document, cond WriteCondition) error {
+	if err := t.check("upsert_document"); err != nil {
+		return err
+	}
+
+	if err := cond.validate(); err != nil {
+		return wrapError("upsert_document", err)
+	}
+
+	if err := upsertDocument(ctx, t.tx, doc, cond); err != nil {
+		return wrapError("upsert_document", err)
+	}
+
+	return nil
+}
+
+// DeleteDocument deletes a document and its embeddings within the transaction.
+// Embeddings are removed explicitly rather than relying on ON DELETE CASCADE.
+func (t *Tx) DeleteDocument(ctx context.Context, id string) error {
+	if err := t.check("delete_document"); err != nil {
+		return err
+	}
+
+	if err := archiveDocument(ctx, t.tx, id, ""); err != nil {
+		return wrapError("delete_document", err)
+	}
+	if err := t.deleteEmbeddingsByDocID(ctx, id); err != nil {
+		return wrapError("delete_document", err)
+	}
+
+	if _, err := t.tx.ExecContext(ctx, "DELETE FROM documents WHERE id = ?", id); err != nil {
+		return wrapError("delete_document", fmt.Errorf("failed to delete document: %w", err))
+	}
+
+	return nil
+}
+
+func (t *Tx) deleteEmbeddingsByDocID(ctx context.Context, docID string) error {
+	rows, err := t.tx.QueryContext(ctx, "DELETE FROM embeddings WHERE doc_id = ? RETURNING id", docID)
+	if err != nil {
+		return fmt.Errorf("failed to delete embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan deleted embedding: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to delete embeddings: %w", err)
+	}
+
+	t.OnCommit(func() {
+		for _, id := range ids {
+			t.store.unindexEmbedding(id)
+		}
+	})
+
+	return nil
+}
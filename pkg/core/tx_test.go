@@ -0,0 +1,170 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTx(t *testing.T) {
+	dbPath := "test_tx.db"
+	defer os.Remove(dbPath)
+
+	config := DefaultConfig()
+	config.Path = dbPath
+	config.VectorDim = 3
+	config.HNSW.Enabled = true
+
+	store, err := NewWithConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.Init(ctx); err != nil {
+		t.Fatalf("Failed to initialize store: %v", err)
+	}
+
+	t.Run("Rollback", func(t *testing.T) {
+		tx, err := store.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		if err := tx.CreateDocument(ctx, &Document{ID: "doc-r", Title: "rolled back", Version: 1}); err != nil {
+			t.Fatalf("CreateDocument failed: %v", err)
+		}
+		if err := tx.Upsert(ctx, &Embedding{ID: "r1", Vector: []float32{1, 0, 0}, Content: "r", DocID: "doc-r"}); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		if store.hnswIndex.Size() != 0 {
+			t.Errorf("Index must not change before commit, got %d vectors", store.hnswIndex.Size())
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+
+		if _, err := store.GetByID(ctx, "r1"); err == nil {
+			t.Error("Expected embedding to be rolled back")
+		}
+		if _, err := store.GetDocument(ctx, "doc-r"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected document to be rolled back, got %v", err)
+		}
+		if store.hnswIndex.Size() != 0 {
+			t.Errorf("Rollback must not touch the index, got %d vectors", store.hnswIndex.Size())
+		}
+		if err := tx.Upsert(ctx, &Embedding{ID: "r2", Vector: []float32{1, 0, 0}}); err == nil {
+			t.Error("Expected error when using a finished transaction")
+		}
+	})
+
+	t.Run("Commit", func(t *testing.T) {
+		tx, err := store.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if err := tx.CreateDocument(ctx, &Document{ID: "doc-c", Title: "committed", Version: 1}); err != nil {
+			t.Fatalf("CreateDocument failed: %v", err)
+		}
+		embs := []*Embedding{
+			{ID: "c1", Vector: []float32{1, 0, 0}, Content: "one", DocID: "doc-c"},
+			{ID: "c2", Vector: []float32{0, 1, 0}, Content: "two", DocID: "doc-c"},
+		}
+		if err := tx.UpsertBatch(ctx, embs); err != nil {
+			t.Fatalf("UpsertBatch failed: %v", err)
+		}
+		if embs[0].Revision != 1 {
+			t.Errorf("Expected revision 1, got %d", embs[0].Revision)
+		}
+
+		doc, err := tx.GetDocument(ctx, "doc-c")
+		if err != nil || doc.Title != "committed" {
+			t.Fatalf("Expected to read uncommitted document, got %v, %v", doc, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		if store.hnswIndex.Size() != 2 {
+			t.Errorf("Expected 2 vectors indexed on commit, got %d", store.hnswIndex.Size())
+		}
+		if _, err := store.GetByID(ctx, "c2"); err != nil {
+			t.Errorf("Expected committed embedding: %v", err)
+		}
+	})
+
+	t.Run("DeleteDocument", func(t *testing.T) {
+		tx, err := store.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		if err := tx.DeleteDocument(ctx, "doc-c"); err != nil {
+			t.Fatalf("DeleteDocument failed: %v", err)
+		}
+		if store.hnswIndex.Size() != 2 {
+			t.Errorf("Index must not change before commit, got %d vectors", store.hnswIndex.Size())
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		if store.hnswIndex.Size() != 0 {
+			t.Errorf("Expected chunks removed from index on commit, got %d", store.hnswIndex.Size())
+		}
+		if _, err := store.GetByID(ctx, "c1"); err == nil {
+			t.Error("Expected document chunks to be deleted")
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		tx, err := store.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if err := tx.UpsertWithCondition(ctx, &Embedding{ID: "x1", Vector: []float32{1, 1, 0}}, WriteCondition{IfNotExists: true}); err != nil {
+			t.Fatalf("Conditional insert failed: %v", err)
+		}
+		err = tx.UpsertWithCondition(ctx, &Embedding{ID: "x1", Vector: []float32{1, 1, 0}}, WriteCondition{IfNotExists: true})
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected conflict against uncommitted row, got %v", err)
+		}
+	})
+
+	t.Run("StoreClosed", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Path = filepath.Join(t.TempDir(), "closed.db")
+		config.VectorDim = 3
+		closing, err := NewWithConfig(config)
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		if err := closing.Init(ctx); err != nil {
+			t.Fatalf("Failed to initialize store: %v", err)
+		}
+		tx, err := closing.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		// Run with -race: the check reads the flag Close sets
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			closing.Close()
+		}()
+		for i := 0; i < 100; i++ {
+			_ = tx.check("upsert")
+		}
+		<-done
+
+		if err := tx.Upsert(ctx, &Embedding{ID: "late", Vector: []float32{1, 0, 0}}); !errors.Is(err, ErrStoreClosed) {
+			t.Errorf("Expected ErrStoreClosed, got %v", err)
+		}
+	})
+}
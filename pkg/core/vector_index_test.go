@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/index"
+)
+
+func TestVectorIndexTypes(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		indexType IndexType
+		configure func(*Config)
+		trained   bool // Needs TrainIndex before the index exists
+	}{
+		{name: "LSH", indexType: IndexTypeLSH},
+		{name: "Hybrid", indexType: IndexTypeHybrid, trained: true},
+		{name: "MultiMergeAll", indexType: IndexTypeMulti},
+		{
+			name:      "MultiRerank",
+			indexType: IndexTypeMulti,
+			configure: func(c *Config) {
+				c.MultiIndex.SecondaryIndices = []index.IndexType{index.IndexTypeLSH, index.IndexTypeIVF}
+				c.MultiIndex.CombineStrategy = index.StrategyRerank
+			},
+			trained: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Path = filepath.Join(t.TempDir(), "vector_index.db")
+			config.IndexType = tt.indexType
+			config.IVF.NCentroids = 8
+			config.IVF.NProbe = 4
+			config.AutoSave.Enabled = false
+			if tt.configure != nil {
+				tt.configure(&config)
+			}
+
+			randomVec := newRandomVec(3, 16)
+
+			// The dimension is detected on the first insert
+			store := openTestStore(t, config)
+			for i := 0; i < 200; i++ {
+				if err := store.Upsert(ctx, &Embedding{ID: fmt.Sprintf("vec_%d", i), Vector: randomVec()}); err != nil {
+					t.Fatalf("Upsert failed: %v", err)
+				}
+			}
+			if tt.trained {
+				if store.vectorIndex != nil {
+					t.Fatal("Index with IVF members should not exist before training")
+				}
+				expectTop(t, store, "vec_3") // Linear search until trained
+				if err := store.TrainIndex(ctx, 0); err != nil {
+					t.Fatalf("TrainIndex failed: %v", err)
+				}
+			}
+			if store.vectorIndex == nil || store.vectorIndex.Size() != 200 {
+				t.Fatal("Expected every vector in the index")
+			}
+			expectTop(t, store, "vec_150")
+
+			// Replacing a vector moves it rather than duplicating it
+			if err := store.Upsert(ctx, &Embedding{ID: "vec_5", Vector: randomVec()}); err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+			if err := store.Delete(ctx, "vec_0"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if store.vectorIndex.Size() != 199 {
+				t.Errorf("Expected 199 vectors, got %d", store.vectorIndex.Size())
+			}
+			expectTop(t, store, "vec_5")
+			if err := store.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			// Reopening restores the index from its snapshot once the
+			// dimension is configured
+			config.VectorDim = 16
+			store = openTestStore(t, config)
+			if store.vectorIndex == nil || store.vectorIndex.Size() != 199 {
+				t.Fatal("Expected index restored from snapshot")
+			}
+			expectTop(t, store, "vec_5")
+
+			// Bypass the index so the snapshot no longer matches the table
+			if _, err := store.db.ExecContext(ctx, "DELETE FROM embeddings WHERE id = 'vec_1'"); err != nil {
+				t.Fatalf("Failed to delete row: %v", err)
+			}
+			store.vectorIndex = nil // Keep the stale snapshot on close
+			store.Close()
+
+			store = openTestStore(t, config)
+			defer store.Close()
+			if store.vectorIndex == nil || store.vectorIndex.Size() != 198 {
+				t.Fatal("Expected stale snapshot to be rebuilt")
+			}
+			expectTop(t, store, "vec_99")
+		})
+	}
+}
+
+func TestVectorIndexInvalidMember(t *testing.T) {
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "invalid.db")
+	config.VectorDim = 4
+	config.IndexType = IndexTypeMulti
+	config.MultiIndex.SecondaryIndices = []index.IndexType{"bogus"}
+	config.AutoSave.Enabled = false
+
+	store, err := NewWithConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	if err := store.Init(context.Background()); err == nil {
+		t.Error("Expected Init to reject an unknown member index type")
+	}
+}
@@ -16,6 +16,9 @@ type DB struct {
 	store    *core.SQLiteStore
 	graph    *graph.GraphStore
 	embedder Embedder // Optional embedder for text operations
+
+	cacheConfig *EmbeddingCacheConfig // Set by WithEmbeddingCache
+	cache       *CachedEmbedder       // Caches embedder vectors for ingestion
 }
 
 // Config represents database configuration
@@ -23,7 +26,7 @@ type Config struct {
 	Path         string              // Database file path
 	Dimensions   int                 // Vector dimensions (0 for auto-detect)
 	SimilarityFn core.SimilarityFunc // Similarity function (default: cosine)
-	IndexType    core.IndexType      // Index type (HNSW, IVF, Flat)
+	IndexType    core.IndexType      // Index type (HNSW, IVF, Flat, DiskANN, IVFPQ, LSH, Hybrid, Multi, Binary)
 }
 
 // DefaultConfig returns default configuration
@@ -47,6 +50,15 @@ func WithEmbedder(e Embedder) Option {
 	}
 }
 
+// WithEmbeddingCache keeps the embedder's vectors in the database, so
+// InsertText, InsertTextBatch, SaveKnowledge and InsertGraphDocument only
+// embed texts no earlier ingestion has seen. Requires WithEmbedder.
+func WithEmbeddingCache(config EmbeddingCacheConfig) Option {
+	return func(db *DB) {
+		db.cacheConfig = &config
+	}
+}
+
 // Open opens or creates a vector database.
 // Additional options can be passed to configure the database, such as WithEmbedder.
 func Open(config Config, opts ...Option) (*DB, error) {
@@ -68,6 +80,12 @@ func Open(config Config, opts ...Option) (*DB, error) {
 		IndexType:      config.IndexType,
 		HNSW:           hnswConfig,
 		IVF:            ivfConfig,
+		IVFPQ:          core.DefaultIVFPQConfig(),
+		DiskANN:        core.DefaultDiskANNConfig(),
+		LSH:            core.DefaultLSHConfig(),
+		MultiIndex:     core.DefaultMultiIndexConfig(),
+		Binary:         core.DefaultBinaryConfig(),
+		ExternalWrites: core.DefaultExternalWritesConfig(),
 		TextSimilarity: core.DefaultTextSimilarityConfig(),
 	}
 
@@ -82,6 +100,11 @@ func Open(config Config, opts ...Option) (*DB, error) {
 		return nil, fmt.Errorf("failed to initialize store: %w", err)
 	}
 
+	if err := initEntityResolutionSchema(ctx, store.GetDB()); err != nil {
+		store.Close()
+		return nil, err
+	}
+
 	// Create graph store
 	graphStore := graph.NewGraphStore(store)
 
@@ -95,6 +118,15 @@ func Open(config Config, opts ...Option) (*DB, error) {
 		opt(db)
 	}
 
+	if db.cacheConfig != nil && db.embedder != nil {
+		cache, err := NewCachedEmbedder(ctx, store.GetDB(), db.embedder, *db.cacheConfig)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		db.cache = cache
+	}
+
 	return db, nil
 }
 
@@ -117,6 +149,11 @@ type DBInfo struct {
 	Embedder       string                    `json:"embedder,omitempty"`
 	HNSW           core.HNSWConfig           `json:"hnsw,omitempty"`
 	IVF            core.IVFConfig            `json:"ivf,omitempty"`
+	IVFPQ          core.IVFPQConfig          `json:"ivfpq,omitempty"`
+	DiskANN        core.DiskANNConfig        `json:"diskann,omitempty"`
+	LSH            core.LSHConfig            `json:"lsh,omitempty"`
+	MultiIndex     core.MultiIndexConfig     `json:"multiIndex,omitempty"`
+	Binary         core.BinaryConfig         `json:"binary,omitempty"`
 	TextSimilarity core.TextSimilarityConfig `json:"textSimilarity,omitempty"`
 	Quantization   core.QuantizationConfig   `json:"quantization,omitempty"`
 }
@@ -132,6 +169,11 @@ func (db *DB) Info() DBInfo {
 		Dimensions:     config.VectorDim,
 		HNSW:           config.HNSW,
 		IVF:            config.IVF,
+		IVFPQ:          config.IVFPQ,
+		DiskANN:        config.DiskANN,
+		LSH:            config.LSH,
+		MultiIndex:     config.MultiIndex,
+		Binary:         config.Binary,
 		TextSimilarity: config.TextSimilarity,
 		Quantization:   config.Quantization,
 	}
@@ -144,6 +186,18 @@ func (db *DB) Info() DBInfo {
 		info.IndexType = "IVF"
 	case core.IndexTypeFlat:
 		info.IndexType = "Flat"
+	case core.IndexTypeDiskANN:
+		info.IndexType = "DiskANN"
+	case core.IndexTypeIVFPQ:
+		info.IndexType = "IVFPQ"
+	case core.IndexTypeLSH:
+		info.IndexType = "LSH"
+	case core.IndexTypeHybrid:
+		info.IndexType = "Hybrid"
+	case core.IndexTypeMulti:
+		info.IndexType = "Multi"
+	case core.IndexTypeBinary:
+		info.IndexType = "Binary"
 	default:
 		info.IndexType = "Unknown"
 	}
@@ -235,6 +289,9 @@ func (q *Quick) AddTextToCollection(ctx context.Context, collection string, text
 	if q.db.embedder == nil {
 		return "", ErrEmbedderNotConfigured
 	}
+	if err := q.db.checkCollectionModel(ctx, collection); err != nil {
+		return "", err
+	}
 
 	vec, err := q.db.embedder.Embed(ctx, text)
 	if err != nil {
@@ -247,6 +304,7 @@ func (q *Quick) AddTextToCollection(ctx context.Context, collection string, text
 		Vector:     vec,
 		Content:    text,
 		Metadata:   metadata,
+		ModelID:    q.db.ingestModelID(),
 	}
 
 	err = q.db.store.Upsert(ctx, embedding)
@@ -280,6 +338,50 @@ func generateID() string {
 // High-level Text Operations (require embedder)
 // ==========================================
 
+// ingestEmbedder returns the embedder for stored content, which goes
+// through the embedding cache when one is configured
+func (db *DB) ingestEmbedder() Embedder {
+	if db.cache != nil {
+		return db.cache
+	}
+	return db.embedder
+}
+
+// ingestModelID names the model behind ingestEmbedder, recorded on each
+// embedding it produces; empty when the embedder doesn't say
+func (db *DB) ingestModelID() string {
+	if m, ok := db.ingestEmbedder().(ModelIdentifier); ok {
+		return m.ModelID()
+	}
+	return ""
+}
+
+// checkCollectionModel fails with ErrModelMismatch when the collection was
+// re-embedded with a model other than ingestModelID. Collections without a
+// recorded model and embedders that don't name theirs are not checked.
+func (db *DB) checkCollectionModel(ctx context.Context, collection string) error {
+	modelID := db.ingestModelID()
+	if modelID == "" {
+		return nil
+	}
+	current, err := db.store.CollectionModel(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if current != "" && current != modelID {
+		return fmt.Errorf("%w: collection uses %q, embedder is %q", ErrModelMismatch, current, modelID)
+	}
+	return nil
+}
+
+// EmbeddingCacheStats reports the embedding cache's hits, misses and size.
+func (db *DB) EmbeddingCacheStats(ctx context.Context) (EmbeddingCacheStats, error) {
+	if db.cache == nil {
+		return EmbeddingCacheStats{}, fmt.Errorf("cortexdb: embedding cache not configured, use WithEmbeddingCache")
+	}
+	return db.cache.Stats(ctx)
+}
+
 // InsertText inserts text with automatic embedding generation.
 // Requires an embedder to be configured via WithEmbedder option.
 func (db *DB) InsertText(ctx context.Context, id string, text string, metadata map[string]string) error {
@@ -289,8 +391,11 @@ func (db *DB) InsertText(ctx context.Context, id string, text string, metadata m
 	if text == "" {
 		return ErrEmptyText
 	}
+	if err := db.checkCollectionModel(ctx, ""); err != nil {
+		return err
+	}
 
-	vec, err := db.embedder.Embed(ctx, text)
+	vec, err := db.ingestEmbedder().Embed(ctx, text)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
 	}
@@ -300,6 +405,7 @@ func (db *DB) InsertText(ctx context.Context, id string, text string, metadata m
 		Vector:   vec,
 		Content:  text,
 		Metadata: metadata,
+		ModelID:  db.ingestModelID(),
 	}
 
 	return db.store.Upsert(ctx, embedding)
@@ -325,12 +431,16 @@ func (db *DB) InsertTextBatch(ctx context.Context, texts map[string]string, meta
 	if len(textList) == 0 {
 		return nil
 	}
+	if err := db.checkCollectionModel(ctx, ""); err != nil {
+		return err
+	}
 
-	vectors, err := db.embedder.EmbedBatch(ctx, textList)
+	vectors, err := db.ingestEmbedder().EmbedBatch(ctx, textList)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
 	}
 
+	modelID := db.ingestModelID()
 	embeddings := make([]*core.Embedding, len(ids))
 	for i, id := range ids {
 		embeddings[i] = &core.Embedding{
@@ -338,6 +448,7 @@ func (db *DB) InsertTextBatch(ctx context.Context, texts map[string]string, meta
 			Vector:   vectors[i],
 			Content:  textList[i],
 			Metadata: metadata,
+			ModelID:  modelID,
 		}
 	}
 
@@ -359,6 +470,9 @@ func (db *DB) SearchTextInCollection(ctx context.Context, collection string, que
 	if query == "" {
 		return nil, ErrEmptyText
 	}
+	if err := db.checkCollectionModel(ctx, collection); err != nil {
+		return nil, err
+	}
 
 	vec, err := db.embedder.Embed(ctx, query)
 	if err != nil {
@@ -383,6 +497,9 @@ func (db *DB) HybridSearchText(ctx context.Context, query string, topK int) ([]c
 	if query == "" {
 		return nil, ErrEmptyText
 	}
+	if err := db.checkCollectionModel(ctx, ""); err != nil {
+		return nil, err
+	}
 
 	vec, err := db.embedder.Embed(ctx, query)
 	if err != nil {
@@ -457,8 +574,12 @@ func (db *DB) ftsSearch(ctx context.Context, query string, opts TextSearchOption
 	args := []interface{}{query}
 
 	if opts.Collection != "" {
+		collection, err := db.store.ResolveCollection(ctx, opts.Collection)
+		if err != nil {
+			return nil, fmt.Errorf("FTS search failed: %w", err)
+		}
 		ftsQuery += " AND c.name = ?"
-		args = append(args, opts.Collection)
+		args = append(args, collection)
 	}
 
 	ftsQuery += " ORDER BY score LIMIT ?"
@@ -31,6 +31,10 @@ var (
 
 	// ErrEmbeddingFailed is returned when the embedder fails to produce a vector.
 	ErrEmbeddingFailed = errors.New("cortexdb: embedding failed")
+
+	// ErrModelMismatch is returned when the configured embedder's model is
+	// not the one a collection was re-embedded with.
+	ErrModelMismatch = errors.New("cortexdb: embedder model does not match the collection's model")
 )
 
 // BaseEmbedder provides a default implementation of EmbedBatch that calls Embed for each text.
@@ -2,9 +2,16 @@ package cortexdb_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	mrand "math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 
 	"github.com/liliang-cn/cortexdb/v2/pkg/cortexdb"
 )
@@ -134,57 +141,73 @@ func Example_textOnly() {
 	}
 }
 
-// OpenAIEmbedder shows how to implement an OpenAI embedder
-// This is a template - you need to add actual OpenAI API calls
-type OpenAIEmbedder struct {
-	apiKey string
-	model  string
-	dim    int
-}
+// Example_openAIEmbedder shows how to use a shipped HTTP embedder. A local
+// server stands in for the OpenAI API; in production leave BaseURL empty
+// and pass os.Getenv("OPENAI_API_KEY") as the key.
+func Example_openAIEmbedder() {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var req struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// One dimension per keyword, plus a constant so no vector is zero
+		data := make([]map[string]any, len(req.Input))
+		for i, text := range req.Input {
+			vector := []float32{0.1, 0, 0, 0}
+			for j, word := range []string{"fox", "dog", "cat"} {
+				if strings.Contains(text, word) {
+					vector[j+1] = 1
+				}
+			}
+			data[i] = map[string]any{"index": i, "embedding": vector}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	embedder, err := cortexdb.NewOpenAIEmbedder(cortexdb.HTTPEmbedderConfig{
+		BaseURL:    server.URL + "/v1",
+		APIKey:     "sk-example",
+		Model:      "text-embedding-3-small",
+		Dimensions: 4,
+	})
+	if err != nil {
+		panic(err)
+	}
 
-func NewOpenAIEmbedder(apiKey, model string, dim int) *OpenAIEmbedder {
-	return &OpenAIEmbedder{apiKey: apiKey, model: model, dim: dim}
-}
+	dir, err := os.MkdirTemp("", "cortexdb-example")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
 
-func (o *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
-	// TODO: Call OpenAI API
-	// Example: POST https://api.openai.com/v1/embeddings
-	// Body: {"input": text, "model": o.model}
-	//
-	// import (
-	//     "bytes"
-	//     "encoding/json"
-	//     "net/http"
-	// )
-	//
-	// body, _ := json.Marshal(map[string]string{"input": text, "model": o.model})
-	// req, _ := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/embeddings", bytes.NewReader(body))
-	// req.Header.Set("Authorization", "Bearer "+o.apiKey)
-	// req.Header.Set("Content-Type", "application/json")
-	// resp, err := http.DefaultClient.Do(req)
-	// ... parse response
-
-	// Placeholder implementation
-	vector := make([]float32, o.dim)
-	for i := range vector {
-		vector[i] = mrand.Float32()
+	db, err := cortexdb.Open(cortexdb.DefaultConfig(filepath.Join(dir, "example.db")), cortexdb.WithEmbedder(embedder))
+	if err != nil {
+		panic(err)
 	}
-	return vector, nil
-}
+	defer db.Close()
 
-func (o *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
-	// TODO: Call OpenAI batch embedding API for better performance
-	vectors := make([][]float32, len(texts))
-	for i, text := range texts {
-		vec, err := o.Embed(ctx, text)
-		if err != nil {
-			return nil, err
-		}
-		vectors[i] = vec
+	// InsertTextBatch sends one request per MaxBatchSize texts
+	ctx := context.Background()
+	if err := db.InsertTextBatch(ctx, map[string]string{
+		"doc1": "The quick brown fox",
+		"doc2": "jumps over the lazy dog",
+	}, nil); err != nil {
+		panic(err)
 	}
-	return vectors, nil
-}
+	fmt.Println("Requests:", requests.Load())
 
-func (o *OpenAIEmbedder) Dim() int {
-	return o.dim
+	results, err := db.SearchText(ctx, "a fox", 1)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("Top:", results[0].ID)
+	// Output:
+	// Requests: 1
+	// Top: doc1
 }
@@ -0,0 +1,335 @@
+package cortexdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPEmbedderConfig holds the settings shared by the HTTP embedders
+// (OpenAIEmbedder, OllamaEmbedder and TEIEmbedder). Zero values take the
+// provider's defaults.
+type HTTPEmbedderConfig struct {
+	BaseURL        string            // Endpoint root, e.g. https://api.openai.com/v1
+	APIKey         string            // Sent as a bearer token when set
+	Model          string            // Model name sent with each request
+	Dimensions     int               // Expected vector size, 0 = take it from the first response
+	MaxBatchSize   int               // Texts per request (default: provider specific)
+	MaxBatchTokens int               // Estimated tokens per request, 0 = unlimited
+	MaxInputTokens int               // Longer texts are truncated to this many estimated tokens, 0 = no limit
+	MaxRetries     int               // Retries on 429, 5xx and network errors (default: 3, -1 = none)
+	RetryBackoff   time.Duration     // Delay before the first retry, doubled after each (default: 500ms)
+	MaxBackoff     time.Duration     // Upper bound for retry delays (default: 30s)
+	Concurrency    int               // Requests in flight at once (default: 4)
+	Timeout        time.Duration     // Per-request timeout (default: 60s)
+	Headers        map[string]string // Extra request headers
+	HTTPClient     *http.Client      // Client to use (default: a new client)
+}
+
+// charsPerToken approximates tokenizer output for token limits; real
+// tokenizers average about four characters per token on English text
+const charsPerToken = 4
+
+// estimateTokens approximates the token count of text
+func estimateTokens(text string) int {
+	return (len([]rune(text)) + charsPerToken - 1) / charsPerToken
+}
+
+// truncateTokens shortens text to roughly maxTokens tokens
+func truncateTokens(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return text
+	}
+	runes := []rune(text)
+	if limit := maxTokens * charsPerToken; len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return text
+}
+
+// httpEmbedder implements batching, retries, the concurrency limit and
+// dimension checks; providers supply the path and wire format
+type httpEmbedder struct {
+	config   HTTPEmbedderConfig
+	endpoint string
+	modelID  string
+	encode   func(texts []string) any
+	decode   func(body []byte) ([][]float32, error)
+	sem      chan struct{}
+	dim      atomic.Int64
+}
+
+// newHTTPEmbedder fills in defaults and validates the configuration
+func newHTTPEmbedder(config HTTPEmbedderConfig, path string, defaultBatch int) (*httpEmbedder, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("cortexdb: embedder base URL is required")
+	}
+	if config.Dimensions < 0 || config.MaxBatchSize < 0 || config.MaxBatchTokens < 0 || config.MaxInputTokens < 0 {
+		return nil, fmt.Errorf("cortexdb: embedder limits must not be negative")
+	}
+	if config.MaxBatchSize == 0 {
+		config.MaxBatchSize = defaultBatch
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = 500 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 4
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 60 * time.Second
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{}
+	}
+
+	e := &httpEmbedder{
+		config:   config,
+		endpoint: strings.TrimRight(config.BaseURL, "/") + path,
+		sem:      make(chan struct{}, config.Concurrency),
+	}
+	e.dim.Store(int64(config.Dimensions))
+	return e, nil
+}
+
+// Embed converts a single text into a vector.
+func (e *httpEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch embeds texts in as few requests as the batch limits allow,
+// sending up to Concurrency requests at once. Vectors are returned in input order.
+func (e *httpEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	inputs := make([]string, len(texts))
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			return nil, ErrEmptyText
+		}
+		inputs[i] = truncateTokens(text, e.config.MaxInputTokens)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]float32, len(inputs))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+batches:
+	for _, batch := range e.batches(inputs) {
+		select {
+		case e.sem <- struct{}{}:
+		case <-ctx.Done():
+			break batches
+		}
+		if ctx.Err() != nil {
+			// The slot was free as the context ended
+			<-e.sem
+			break
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-e.sem }()
+
+			vectors, err := e.post(ctx, inputs[start:end])
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			copy(results[start:end], vectors)
+		}(batch[0], batch[1])
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ModelID names the provider and model, for keying cached vectors.
+func (e *httpEmbedder) ModelID() string {
+	return e.modelID
+}
+
+// Dim returns the configured dimension, or the one seen in the first
+// response when none was configured.
+func (e *httpEmbedder) Dim() int {
+	return int(e.dim.Load())
+}
+
+// batches splits inputs into [start, end) ranges within the batch size and token limits
+func (e *httpEmbedder) batches(inputs []string) [][2]int {
+	var batches [][2]int
+	start, tokens := 0, 0
+	for i, text := range inputs {
+		n := estimateTokens(text)
+		full := i-start >= e.config.MaxBatchSize
+		if e.config.MaxBatchTokens > 0 && i > start && tokens+n > e.config.MaxBatchTokens {
+			full = true
+		}
+		if full {
+			batches = append(batches, [2]int{start, i})
+			start, tokens = i, 0
+		}
+		tokens += n
+	}
+	return append(batches, [2]int{start, len(inputs)})
+}
+
+// post sends one batch and checks the vectors that come back
+func (e *httpEmbedder) post(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(e.encode(texts))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to encode request: %v", ErrEmbeddingFailed, err)
+	}
+
+	respBody, err := e.send(ctx, body)
+	if err != nil {
+		if err == ctx.Err() {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
+	}
+	return e.verify(respBody, len(texts))
+}
+
+// send posts body, retrying on rate limits, server errors and network
+// failures. It returns ctx.Err() if the context ends while waiting to retry.
+func (e *httpEmbedder) send(ctx context.Context, body []byte) ([]byte, error) {
+	backoff := e.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		respBody, retryAfter, err := e.do(ctx, body)
+		if err == nil {
+			return respBody, nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) || attempt >= e.config.MaxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := backoff
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if delay > e.config.MaxBackoff {
+			delay = e.config.MaxBackoff
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// permanentError is a failed request that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string { return p.err.Error() }
+
+// do sends a single request and returns the response body, or an error with
+// the server's Retry-After delay when it sent one
+func (e *httpEmbedder) do(ctx context.Context, body []byte) ([]byte, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+	}
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return respBody, 0, nil
+	}
+
+	err = fmt.Errorf("%s returned %d: %s", e.endpoint, resp.StatusCode, truncateTokens(strings.TrimSpace(string(respBody)), 50))
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return nil, 0, &permanentError{err}
+	}
+	var retryAfter time.Duration
+	if secs, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil && secs > 0 {
+		retryAfter = time.Duration(secs) * time.Second
+	}
+	return nil, retryAfter, err
+}
+
+// verify decodes a response and checks the vector count and dimensions
+func (e *httpEmbedder) verify(body []byte, n int) ([][]float32, error) {
+	vectors, err := e.decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode response: %v", ErrEmbeddingFailed, err)
+	}
+	if len(vectors) != n {
+		return nil, fmt.Errorf("%w: sent %d texts, got %d vectors", ErrEmbeddingFailed, n, len(vectors))
+	}
+	for _, vec := range vectors {
+		if len(vec) == 0 {
+			return nil, fmt.Errorf("%w: got an empty vector", ErrEmbeddingFailed)
+		}
+		want := e.dim.Load()
+		if want == 0 && e.dim.CompareAndSwap(0, int64(len(vec))) {
+			continue
+		}
+		if want == 0 {
+			want = e.dim.Load()
+		}
+		if int64(len(vec)) != want {
+			return nil, fmt.Errorf("%w: got a %d-dimensional vector, expected %d", ErrEmbeddingFailed, len(vec), want)
+		}
+	}
+	return vectors, nil
+}
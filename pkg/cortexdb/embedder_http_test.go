@@ -0,0 +1,307 @@
+package cortexdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubVector embeds a text as [len(text), first byte, 1, ...]
+func stubVector(text string, dim int) []float32 {
+	vec := make([]float32, dim)
+	for i := range vec {
+		vec[i] = 1
+	}
+	vec[0] = float32(len(text))
+	vec[1] = float32(text[0])
+	return vec
+}
+
+// embedServer stands in for an embedding API; handle returns a status code
+// for each request before the normal response is written
+type embedServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	batches  [][]string
+	auth     atomic.Value
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+	handle   func(n int) int
+}
+
+func newEmbedServer(t *testing.T, path string, dim int, respond func(texts []string, vectors [][]float32) any, inputs func(body map[string]any) []string) *embedServer {
+	s := &embedServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		s.auth.Store(r.Header.Get("Authorization"))
+		n := s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		for {
+			seen := s.maxSeen.Load()
+			if n <= seen || s.maxSeen.CompareAndSwap(seen, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		texts := inputs(body)
+
+		s.mu.Lock()
+		s.batches = append(s.batches, texts)
+		count := len(s.batches)
+		s.mu.Unlock()
+		if s.handle != nil {
+			if code := s.handle(count); code != http.StatusOK {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "try again", code)
+				return
+			}
+		}
+
+		vectors := make([][]float32, len(texts))
+		for i, text := range texts {
+			vectors[i] = stubVector(text, dim)
+		}
+		_ = json.NewEncoder(w).Encode(respond(texts, vectors))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func stringList(v any) []string {
+	var out []string
+	for _, item := range v.([]any) {
+		out = append(out, item.(string))
+	}
+	return out
+}
+
+func newOpenAIServer(t *testing.T, dim int) *embedServer {
+	return newEmbedServer(t, "/v1/embeddings", dim,
+		func(texts []string, vectors [][]float32) any {
+			// Out of order, as the API doesn't promise ordering
+			data := make([]map[string]any, len(vectors))
+			for i := range vectors {
+				j := len(vectors) - 1 - i
+				data[i] = map[string]any{"index": j, "embedding": vectors[j]}
+			}
+			return map[string]any{"data": data}
+		},
+		func(body map[string]any) []string { return stringList(body["input"]) })
+}
+
+func TestHTTPEmbedders(t *testing.T) {
+	ctx := context.Background()
+	texts := []string{"alpha", "bravo", "charlie", "delta", "echo"}
+
+	check := func(t *testing.T, e Embedder, dim int) {
+		t.Helper()
+		vectors, err := e.EmbedBatch(ctx, texts)
+		if err != nil {
+			t.Fatalf("EmbedBatch failed: %v", err)
+		}
+		if len(vectors) != len(texts) {
+			t.Fatalf("Expected %d vectors, got %d", len(texts), len(vectors))
+		}
+		for i, vec := range vectors {
+			if len(vec) != dim || vec[0] != float32(len(texts[i])) || vec[1] != float32(texts[i][0]) {
+				t.Errorf("Vector %d doesn't match %q: %v", i, texts[i], vec)
+			}
+		}
+		if e.Dim() != dim {
+			t.Errorf("Expected Dim() %d, got %d", dim, e.Dim())
+		}
+	}
+
+	t.Run("OpenAI", func(t *testing.T) {
+		server := newOpenAIServer(t, 8)
+		e, err := NewOpenAIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL + "/v1", APIKey: "secret", Model: "m", MaxBatchSize: 2})
+		if err != nil {
+			t.Fatalf("NewOpenAIEmbedder failed: %v", err)
+		}
+		if e.Dim() != 0 {
+			t.Errorf("Expected an unknown dimension before the first call, got %d", e.Dim())
+		}
+		check(t, e, 8)
+		if len(server.batches) != 3 {
+			t.Errorf("Expected 3 requests of at most 2 texts, got %v", server.batches)
+		}
+		if auth := server.auth.Load(); auth != "Bearer secret" {
+			t.Errorf("Expected the API key as a bearer token, got %v", auth)
+		}
+		if _, err := NewOpenAIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL}); err == nil {
+			t.Error("Expected an error without a model")
+		}
+	})
+
+	t.Run("Ollama", func(t *testing.T) {
+		server := newEmbedServer(t, "/api/embed", 6,
+			func(texts []string, vectors [][]float32) any { return map[string]any{"embeddings": vectors} },
+			func(body map[string]any) []string {
+				if body["model"] != "nomic-embed-text" {
+					t.Errorf("Expected the model in the request, got %v", body["model"])
+				}
+				return stringList(body["input"])
+			})
+		e, err := NewOllamaEmbedder(HTTPEmbedderConfig{BaseURL: server.URL, Model: "nomic-embed-text", Dimensions: 6})
+		if err != nil {
+			t.Fatalf("NewOllamaEmbedder failed: %v", err)
+		}
+		check(t, e, 6)
+		if len(server.batches) != 1 {
+			t.Errorf("Expected a single request, got %d", len(server.batches))
+		}
+	})
+
+	t.Run("TEI", func(t *testing.T) {
+		server := newEmbedServer(t, "/embed", 4,
+			func(texts []string, vectors [][]float32) any { return vectors },
+			func(body map[string]any) []string { return stringList(body["inputs"]) })
+		e, err := NewTEIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL})
+		if err != nil {
+			t.Fatalf("NewTEIEmbedder failed: %v", err)
+		}
+		check(t, e, 4)
+
+		vec, err := e.Embed(ctx, "single")
+		if err != nil || len(vec) != 4 || vec[0] != 6 {
+			t.Errorf("Unexpected single embedding %v, %v", vec, err)
+		}
+		if _, err := e.Embed(ctx, "  "); !errors.Is(err, ErrEmptyText) {
+			t.Errorf("Expected ErrEmptyText, got %v", err)
+		}
+	})
+}
+
+func TestHTTPEmbedderLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("TokenBatching", func(t *testing.T) {
+		server := newOpenAIServer(t, 4)
+		e, err := NewOpenAIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL + "/v1", Model: "m", MaxBatchTokens: 10, MaxInputTokens: 8})
+		if err != nil {
+			t.Fatalf("NewOpenAIEmbedder failed: %v", err)
+		}
+		// 5 + 5 tokens fill one request; the 100-token text is cut to 8 and shares the next
+		texts := []string{strings.Repeat("a", 20), strings.Repeat("b", 20), strings.Repeat("c", 400), "d"}
+		vectors, err := e.EmbedBatch(ctx, texts)
+		if err != nil {
+			t.Fatalf("EmbedBatch failed: %v", err)
+		}
+		if len(server.batches) != 2 || len(server.batches[0]) != 2 || len(server.batches[1]) != 2 {
+			t.Errorf("Expected two batches of 2 texts, got %d", len(server.batches))
+		}
+		if vectors[2][0] != 32 {
+			t.Errorf("Expected the long text truncated to 32 characters, got %v", vectors[2][0])
+		}
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		server := newOpenAIServer(t, 4)
+		server.handle = func(n int) int {
+			switch n {
+			case 1:
+				return http.StatusTooManyRequests
+			case 2:
+				return http.StatusBadGateway
+			}
+			return http.StatusOK
+		}
+		e, err := NewOpenAIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL + "/v1", Model: "m", RetryBackoff: time.Millisecond})
+		if err != nil {
+			t.Fatalf("NewOpenAIEmbedder failed: %v", err)
+		}
+		if _, err := e.Embed(ctx, "hello"); err != nil {
+			t.Fatalf("Expected success after retries, got %v", err)
+		}
+		if len(server.batches) != 3 {
+			t.Errorf("Expected 3 attempts, got %d", len(server.batches))
+		}
+	})
+
+	t.Run("NoRetryOnClientError", func(t *testing.T) {
+		server := newOpenAIServer(t, 4)
+		server.handle = func(int) int { return http.StatusBadRequest }
+		e, _ := NewOpenAIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL + "/v1", Model: "m", RetryBackoff: time.Millisecond})
+		if _, err := e.Embed(ctx, "hello"); !errors.Is(err, ErrEmbeddingFailed) {
+			t.Errorf("Expected ErrEmbeddingFailed, got %v", err)
+		}
+		if len(server.batches) != 1 {
+			t.Errorf("Expected a single attempt, got %d", len(server.batches))
+		}
+	})
+
+	t.Run("RetriesExhausted", func(t *testing.T) {
+		server := newOpenAIServer(t, 4)
+		server.handle = func(int) int { return http.StatusServiceUnavailable }
+		e, _ := NewOpenAIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL + "/v1", Model: "m", MaxRetries: 2, RetryBackoff: time.Millisecond})
+		if _, err := e.Embed(ctx, "hello"); !errors.Is(err, ErrEmbeddingFailed) {
+			t.Errorf("Expected ErrEmbeddingFailed, got %v", err)
+		}
+		if len(server.batches) != 3 {
+			t.Errorf("Expected 3 attempts, got %d", len(server.batches))
+		}
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		server := newOpenAIServer(t, 4)
+		e, _ := NewOpenAIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL + "/v1", Model: "m", MaxBatchSize: 1, Concurrency: 2})
+		texts := make([]string, 12)
+		for i := range texts {
+			texts[i] = strings.Repeat("x", i+1)
+		}
+		vectors, err := e.EmbedBatch(ctx, texts)
+		if err != nil {
+			t.Fatalf("EmbedBatch failed: %v", err)
+		}
+		for i, vec := range vectors {
+			if vec[0] != float32(i+1) {
+				t.Errorf("Vector %d out of order: %v", i, vec)
+			}
+		}
+		if n := server.maxSeen.Load(); n > 2 {
+			t.Errorf("Expected at most 2 requests in flight, saw %d", n)
+		}
+	})
+
+	t.Run("CancelledReleasesSlot", func(t *testing.T) {
+		server := newOpenAIServer(t, 4)
+		e, _ := NewOpenAIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL + "/v1", Model: "m", Concurrency: 1})
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		for i := 0; i < 20; i++ {
+			if _, err := e.Embed(cancelled, "hello"); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected context.Canceled, got %v", err)
+			}
+		}
+
+		timeout, stop := context.WithTimeout(ctx, 5*time.Second)
+		defer stop()
+		if _, err := e.Embed(timeout, "hello"); err != nil {
+			t.Errorf("Expected the slot to be free after cancelled calls, got %v", err)
+		}
+	})
+
+	t.Run("DimensionMismatch", func(t *testing.T) {
+		server := newOpenAIServer(t, 4)
+		e, _ := NewOpenAIEmbedder(HTTPEmbedderConfig{BaseURL: server.URL + "/v1", Model: "m", Dimensions: 8})
+		if _, err := e.Embed(ctx, "hello"); !errors.Is(err, ErrEmbeddingFailed) || !strings.Contains(err.Error(), "expected 8") {
+			t.Errorf("Expected a dimension error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package cortexdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint, as served
+// by OpenAI, Azure OpenAI, vLLM, LocalAI, LM Studio and others.
+type OpenAIEmbedder struct {
+	*httpEmbedder
+}
+
+// NewOpenAIEmbedder creates an embedder for an OpenAI-compatible API.
+// BaseURL defaults to https://api.openai.com/v1, MaxBatchSize to 256 and
+// MaxInputTokens to 8191.
+func NewOpenAIEmbedder(config HTTPEmbedderConfig) (*OpenAIEmbedder, error) {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.openai.com/v1"
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("cortexdb: OpenAI embedder model is required")
+	}
+	if config.MaxInputTokens == 0 {
+		config.MaxInputTokens = 8191
+	}
+	e, err := newHTTPEmbedder(config, "/embeddings", 256)
+	if err != nil {
+		return nil, err
+	}
+
+	e.modelID = "openai:" + config.Model
+	e.encode = func(texts []string) any {
+		return map[string]any{"model": config.Model, "input": texts}
+	}
+	e.decode = func(body []byte) ([][]float32, error) {
+		var resp struct {
+			Data []struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+		vectors := make([][]float32, len(resp.Data))
+		for i, d := range resp.Data {
+			vectors[i] = d.Embedding
+		}
+		return vectors, nil
+	}
+	return &OpenAIEmbedder{e}, nil
+}
+
+// OllamaEmbedder calls the /api/embed endpoint of an Ollama server.
+type OllamaEmbedder struct {
+	*httpEmbedder
+}
+
+// NewOllamaEmbedder creates an embedder for an Ollama server. BaseURL
+// defaults to http://localhost:11434 and MaxBatchSize to 64. Ollama truncates
+// inputs to the model's context length.
+func NewOllamaEmbedder(config HTTPEmbedderConfig) (*OllamaEmbedder, error) {
+	if config.BaseURL == "" {
+		config.BaseURL = "http://localhost:11434"
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("cortexdb: Ollama embedder model is required")
+	}
+	e, err := newHTTPEmbedder(config, "/api/embed", 64)
+	if err != nil {
+		return nil, err
+	}
+
+	e.modelID = "ollama:" + config.Model
+	e.encode = func(texts []string) any {
+		return map[string]any{"model": config.Model, "input": texts, "truncate": true}
+	}
+	e.decode = func(body []byte) ([][]float32, error) {
+		var resp struct {
+			Embeddings [][]float32 `json:"embeddings"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		return resp.Embeddings, nil
+	}
+	return &OllamaEmbedder{e}, nil
+}
+
+// TEIEmbedder calls the /embed endpoint of a HuggingFace Text Embeddings
+// Inference server. The server serves a single model, so Model is unused.
+type TEIEmbedder struct {
+	*httpEmbedder
+}
+
+// NewTEIEmbedder creates an embedder for a TEI server. BaseURL defaults to
+// http://localhost:8080 and MaxBatchSize to 32, TEI's default client batch
+// limit. The server truncates inputs to the model's maximum length.
+func NewTEIEmbedder(config HTTPEmbedderConfig) (*TEIEmbedder, error) {
+	if config.BaseURL == "" {
+		config.BaseURL = "http://localhost:8080"
+	}
+	e, err := newHTTPEmbedder(config, "/embed", 32)
+	if err != nil {
+		return nil, err
+	}
+
+	e.modelID = "tei:" + e.endpoint
+	e.encode = func(texts []string) any {
+		return map[string]any{"inputs": texts, "truncate": true}
+	}
+	e.decode = func(body []byte) ([][]float32, error) {
+		var vectors [][]float32
+		if err := json.Unmarshal(body, &vectors); err != nil {
+			return nil, err
+		}
+		return vectors, nil
+	}
+	return &TEIEmbedder{e}, nil
+}
@@ -0,0 +1,338 @@
+package cortexdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/encoding"
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+)
+
+// EmbeddingCacheConfig configures a CachedEmbedder
+type EmbeddingCacheConfig struct {
+	ModelID    string // Identifies the model; vectors cached under other IDs are never returned (default: the embedder's ModelID, or its type and dimension)
+	MaxEntries int    // Least recently used vectors are evicted beyond this many, 0 = unlimited
+	MaxBytes   int64  // Least recently used vectors are evicted beyond this many vector bytes, 0 = unlimited
+
+	// Logger receives cache read and write failures, which fall back to the
+	// wrapped embedder instead of failing the call (default: no logging)
+	Logger core.Logger
+}
+
+// EmbeddingCacheStats reports cache effectiveness
+type EmbeddingCacheStats struct {
+	ModelID   string `json:"model_id"`
+	Hits      int64  `json:"hits"`      // Texts served from the cache by this process
+	Misses    int64  `json:"misses"`    // Texts sent to the embedder by this process
+	Evictions int64  `json:"evictions"` // Vectors evicted by this process
+	Entries   int    `json:"entries"`   // Vectors cached for all models
+	Bytes     int64  `json:"bytes"`     // Vector bytes cached for all models
+}
+
+// ModelIdentifier is implemented by embedders that can name the model they
+// call. The HTTP embedders return provider:model.
+type ModelIdentifier interface {
+	ModelID() string
+}
+
+// CachedEmbedder wraps an Embedder and keeps its vectors in the
+// embedding_cache table, so re-embedding the same text is free in this and
+// every other process sharing the database file.
+type CachedEmbedder struct {
+	embedder  Embedder
+	db        *sql.DB
+	config    EmbeddingCacheConfig
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+const embeddingCacheSchema = `
+	CREATE TABLE IF NOT EXISTS embedding_cache (
+		model_id TEXT NOT NULL,
+		text_hash TEXT NOT NULL,
+		vector BLOB NOT NULL,
+		last_used INTEGER NOT NULL,
+		PRIMARY KEY (model_id, text_hash)
+	);
+	CREATE INDEX IF NOT EXISTS idx_embedding_cache_last_used ON embedding_cache(last_used);
+`
+
+// NewCachedEmbedder wraps embedder with a cache stored in db.
+func NewCachedEmbedder(ctx context.Context, db *sql.DB, embedder Embedder, config EmbeddingCacheConfig) (*CachedEmbedder, error) {
+	if embedder == nil {
+		return nil, ErrEmbedderNotConfigured
+	}
+	if config.MaxEntries < 0 || config.MaxBytes < 0 {
+		return nil, fmt.Errorf("cortexdb: embedding cache limits must not be negative")
+	}
+	if config.ModelID == "" {
+		if m, ok := embedder.(ModelIdentifier); ok {
+			config.ModelID = m.ModelID()
+		} else {
+			config.ModelID = fmt.Sprintf("%T/%d", embedder, embedder.Dim())
+		}
+	}
+	if config.Logger == nil {
+		config.Logger = core.NopLogger()
+	}
+	if _, err := db.ExecContext(ctx, embeddingCacheSchema); err != nil {
+		return nil, fmt.Errorf("create embedding cache: %w", err)
+	}
+	return &CachedEmbedder{embedder: embedder, db: db, config: config}, nil
+}
+
+// Embed returns the cached vector for text, embedding it on a miss.
+func (c *CachedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch returns cached vectors and embeds the missing texts in one
+// batch, each distinct text once. A cache that can't be read or written,
+// e.g. while another process holds the write lock, is logged and bypassed.
+func (c *CachedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	hashes := make([]string, len(texts))
+	for i, text := range texts {
+		hashes[i] = embeddingCacheKey(text)
+	}
+	cached, err := c.lookup(ctx, hashes)
+	if err != nil {
+		c.config.Logger.Warn("embedding cache lookup failed, embedding every text", "model", c.config.ModelID, "error", err)
+		cached = nil
+	}
+
+	results := make([][]float32, len(texts))
+	var missing []string
+	missingIdx := make(map[string][]int)
+	for i, hash := range hashes {
+		if vec, ok := cached[hash]; ok {
+			results[i] = vec
+			continue
+		}
+		if _, ok := missingIdx[hash]; !ok {
+			missing = append(missing, texts[i])
+		}
+		missingIdx[hash] = append(missingIdx[hash], i)
+	}
+	c.hits.Add(int64(len(texts) - len(missingIdx)))
+	c.misses.Add(int64(len(missing)))
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	vectors, err := c.embedder.EmbedBatch(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(missing) {
+		return nil, fmt.Errorf("%w: sent %d texts, got %d vectors", ErrEmbeddingFailed, len(missing), len(vectors))
+	}
+	fresh := make(map[string][]float32, len(missing))
+	for i, text := range missing {
+		hash := embeddingCacheKey(text)
+		fresh[hash] = vectors[i]
+		for _, idx := range missingIdx[hash] {
+			results[idx] = vectors[i]
+		}
+	}
+	if err := c.store(ctx, fresh); err != nil {
+		c.config.Logger.Warn("embedding cache store failed", "model", c.config.ModelID, "texts", len(fresh), "error", err)
+	}
+	return results, nil
+}
+
+// Dim returns the dimension of the wrapped embedder.
+func (c *CachedEmbedder) Dim() int {
+	return c.embedder.Dim()
+}
+
+// ModelID returns the identifier vectors are cached under.
+func (c *CachedEmbedder) ModelID() string {
+	return c.config.ModelID
+}
+
+// Stats returns hit and miss counts and the cache size.
+func (c *CachedEmbedder) Stats(ctx context.Context) (EmbeddingCacheStats, error) {
+	stats := EmbeddingCacheStats{
+		ModelID:   c.config.ModelID,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+	err := c.db.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(SUM(LENGTH(vector)), 0) FROM embedding_cache").Scan(&stats.Entries, &stats.Bytes)
+	if err != nil {
+		return stats, fmt.Errorf("read embedding cache size: %w", err)
+	}
+	return stats, nil
+}
+
+// Clear removes every vector cached for this model.
+func (c *CachedEmbedder) Clear(ctx context.Context) error {
+	if _, err := c.db.ExecContext(ctx, "DELETE FROM embedding_cache WHERE model_id = ?", c.config.ModelID); err != nil {
+		return fmt.Errorf("clear embedding cache: %w", err)
+	}
+	return nil
+}
+
+// embeddingCacheKey hashes text with whitespace runs collapsed and trimmed,
+// which doesn't change what embedding models see
+func embeddingCacheKey(text string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup loads cached vectors by hash and marks them used
+func (c *CachedEmbedder) lookup(ctx context.Context, hashes []string) (map[string][]float32, error) {
+	found := make(map[string][]float32)
+	const chunkSize = 500
+	for start := 0; start < len(hashes); start += chunkSize {
+		chunk := hashes[start:min(start+chunkSize, len(hashes))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		args := make([]interface{}, 0, len(chunk)+1)
+		args = append(args, c.config.ModelID)
+		for _, h := range chunk {
+			args = append(args, h)
+		}
+
+		rows, err := c.db.QueryContext(ctx, fmt.Sprintf(
+			"SELECT text_hash, vector FROM embedding_cache WHERE model_id = ? AND text_hash IN (%s)", placeholders), args...)
+		if err != nil {
+			return nil, fmt.Errorf("read embedding cache: %w", err)
+		}
+		for rows.Next() {
+			var hash string
+			var blob []byte
+			if err := rows.Scan(&hash, &blob); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan embedding cache: %w", err)
+			}
+			if vec, err := encoding.DecodeVector(blob); err == nil && len(vec) > 0 {
+				found[hash] = vec
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("read embedding cache: %w", err)
+		}
+
+		if len(found) == 0 {
+			continue
+		}
+		args[0] = time.Now().UnixNano()
+		args = append(args, c.config.ModelID)
+		// Refresh recency for LRU eviction; the vectors are usable without it
+		if _, err := c.db.ExecContext(ctx, fmt.Sprintf(
+			"UPDATE embedding_cache SET last_used = ? WHERE text_hash IN (%s) AND model_id = ?", placeholders), args...); err != nil {
+			c.config.Logger.Warn("failed to touch embedding cache", "model", c.config.ModelID, "error", err)
+		}
+	}
+	return found, nil
+}
+
+// store saves fresh vectors and evicts down to the size limits
+func (c *CachedEmbedder) store(ctx context.Context, vectors map[string][]float32) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("write embedding cache: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO embedding_cache (model_id, text_hash, vector, last_used) VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("write embedding cache: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UnixNano()
+	for hash, vec := range vectors {
+		blob, err := encoding.EncodeVector(vec)
+		if err != nil {
+			return fmt.Errorf("encode cached vector: %w", err)
+		}
+		if _, err := stmt.ExecContext(ctx, c.config.ModelID, hash, blob, now); err != nil {
+			return fmt.Errorf("write embedding cache: %w", err)
+		}
+	}
+
+	if c.config.MaxEntries > 0 || c.config.MaxBytes > 0 {
+		if err := c.evict(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("write embedding cache: %w", err)
+	}
+	return nil
+}
+
+// evict deletes the least recently used rows once the cache is over a size
+// limit. Rows are read oldest first through the last_used index and the
+// scan stops as soon as enough have been found, so nothing is sorted.
+func (c *CachedEmbedder) evict(ctx context.Context, tx *sql.Tx) error {
+	var entries, size int64
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(SUM(LENGTH(vector)), 0) FROM embedding_cache").Scan(&entries, &size)
+	if err != nil {
+		return fmt.Errorf("read embedding cache size: %w", err)
+	}
+	var overEntries, overBytes int64
+	if c.config.MaxEntries > 0 {
+		overEntries = entries - int64(c.config.MaxEntries)
+	}
+	if c.config.MaxBytes > 0 {
+		overBytes = size - c.config.MaxBytes
+	}
+	if overEntries <= 0 && overBytes <= 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT rowid, LENGTH(vector) FROM embedding_cache ORDER BY last_used, rowid")
+	if err != nil {
+		return fmt.Errorf("evict embedding cache: %w", err)
+	}
+	var victims []interface{}
+	for (overEntries > 0 || overBytes > 0) && rows.Next() {
+		var rowid, n int64
+		if err := rows.Scan(&rowid, &n); err != nil {
+			rows.Close()
+			return fmt.Errorf("evict embedding cache: %w", err)
+		}
+		victims = append(victims, rowid)
+		overEntries--
+		overBytes -= n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("evict embedding cache: %w", err)
+	}
+
+	const chunkSize = 500
+	for start := 0; start < len(victims); start += chunkSize {
+		chunk := victims[start:min(start+chunkSize, len(victims))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		res, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM embedding_cache WHERE rowid IN (%s)", placeholders), chunk...)
+		if err != nil {
+			return fmt.Errorf("evict embedding cache: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			c.evictions.Add(n)
+		}
+	}
+	return nil
+}
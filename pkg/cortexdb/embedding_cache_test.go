@@ -0,0 +1,210 @@
+package cortexdb
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// countingEmbedder counts the texts it is asked to embed
+type countingEmbedder struct {
+	*keywordEmbedder
+	texts atomic.Int64
+}
+
+func (c *countingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	c.texts.Add(int64(len(texts)))
+	return c.keywordEmbedder.EmbedBatch(ctx, texts)
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	c.texts.Add(1)
+	return c.keywordEmbedder.Embed(ctx, text)
+}
+
+func TestCachedEmbedder(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(DefaultConfig(filepath.Join(t.TempDir(), "cache.db")))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	sqlDB := db.store.GetDB()
+
+	inner := &countingEmbedder{keywordEmbedder: newKeywordEmbedder("alice", "acme", "graph")}
+	cache, err := NewCachedEmbedder(ctx, sqlDB, inner, EmbeddingCacheConfig{ModelID: "kw", MaxEntries: 3})
+	if err != nil {
+		t.Fatalf("NewCachedEmbedder: %v", err)
+	}
+
+	vectors, err := cache.EmbedBatch(ctx, []string{"alice", "acme", "alice", "  alice "})
+	if err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	if inner.texts.Load() != 2 {
+		t.Errorf("Expected 2 distinct texts embedded, got %d", inner.texts.Load())
+	}
+	if vectors[2][0] != 1 || vectors[3][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("Unexpected vectors %v", vectors)
+	}
+
+	if _, err := cache.Embed(ctx, "alice"); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	stats, err := cache.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Hits != 3 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	t.Run("Eviction", func(t *testing.T) {
+		// acme is now the least recently used and goes first
+		if _, err := cache.EmbedBatch(ctx, []string{"graph", "alice acme"}); err != nil {
+			t.Fatalf("EmbedBatch: %v", err)
+		}
+		stats, _ := cache.Stats(ctx)
+		if stats.Entries != 3 || stats.Evictions != 1 {
+			t.Errorf("Expected 3 entries after 1 eviction, got %+v", stats)
+		}
+		before := inner.texts.Load()
+		if _, err := cache.EmbedBatch(ctx, []string{"alice", "acme"}); err != nil {
+			t.Fatalf("EmbedBatch: %v", err)
+		}
+		if n := inner.texts.Load() - before; n != 1 {
+			t.Errorf("Expected only the evicted text re-embedded, got %d", n)
+		}
+	})
+
+	t.Run("ByteLimit", func(t *testing.T) {
+		raw, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "bytes.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer raw.Close()
+		probe, err := NewCachedEmbedder(ctx, raw, inner, EmbeddingCacheConfig{ModelID: "probe"})
+		if err != nil {
+			t.Fatalf("NewCachedEmbedder: %v", err)
+		}
+		if _, err := probe.Embed(ctx, "alice"); err != nil {
+			t.Fatalf("Embed: %v", err)
+		}
+		stats, _ := probe.Stats(ctx)
+		if err := probe.Clear(ctx); err != nil {
+			t.Fatalf("Clear: %v", err)
+		}
+
+		limited, err := NewCachedEmbedder(ctx, raw, inner, EmbeddingCacheConfig{ModelID: "kw", MaxBytes: 2 * stats.Bytes})
+		if err != nil {
+			t.Fatalf("NewCachedEmbedder: %v", err)
+		}
+		for _, text := range []string{"alice", "acme", "graph", "alice graph"} {
+			if _, err := limited.Embed(ctx, text); err != nil {
+				t.Fatalf("Embed: %v", err)
+			}
+		}
+		stats, _ = limited.Stats(ctx)
+		if stats.Entries != 2 || stats.Evictions != 2 {
+			t.Errorf("Expected the byte limit to keep 2 vectors, got %+v", stats)
+		}
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		raw, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "closed.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		broken, err := NewCachedEmbedder(ctx, raw, inner, EmbeddingCacheConfig{ModelID: "kw"})
+		if err != nil {
+			t.Fatalf("NewCachedEmbedder: %v", err)
+		}
+		raw.Close()
+
+		vec, err := broken.Embed(ctx, "graph")
+		if err != nil || vec[2] != 1 {
+			t.Errorf("Expected the wrapped embedder to be used, got %v, %v", vec, err)
+		}
+	})
+
+	t.Run("ModelIsolation", func(t *testing.T) {
+		other := &countingEmbedder{keywordEmbedder: newKeywordEmbedder("alice", "acme", "graph")}
+		otherCache, err := NewCachedEmbedder(ctx, sqlDB, other, EmbeddingCacheConfig{})
+		if err != nil {
+			t.Fatalf("NewCachedEmbedder: %v", err)
+		}
+		if otherCache.ModelID() == "kw" {
+			t.Fatal("Expected a derived model ID")
+		}
+		if _, err := otherCache.Embed(ctx, "alice"); err != nil {
+			t.Fatalf("Embed: %v", err)
+		}
+		if other.texts.Load() != 1 {
+			t.Error("Expected vectors of another model not to be reused")
+		}
+	})
+}
+
+func TestEmbeddingCacheIngestion(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "ingest.db")
+	inner := &countingEmbedder{keywordEmbedder: newKeywordEmbedder("alice", "acme", "graphrag", "research")}
+
+	open := func() *DB {
+		db, err := Open(DefaultConfig(dbPath), WithEmbedder(inner), WithEmbeddingCache(EmbeddingCacheConfig{ModelID: "kw"}))
+		if err != nil {
+			t.Fatalf("open db: %v", err)
+		}
+		return db
+	}
+
+	doc := GraphRAGDocument{ID: "doc-1", Title: "Alice", Content: "Alice works at Acme.\nAcme does GraphRAG research."}
+	texts := map[string]string{"t1": "alice and acme", "t2": "graphrag research"}
+
+	db := open()
+	if _, err := db.InsertGraphDocument(ctx, doc, GraphRAGIngestOptions{Extractor: fixtureExtractor{}}); err != nil {
+		t.Fatalf("InsertGraphDocument: %v", err)
+	}
+	if err := db.InsertTextBatch(ctx, texts, nil); err != nil {
+		t.Fatalf("InsertTextBatch: %v", err)
+	}
+	if err := db.InsertText(ctx, "t3", "alice research", nil); err != nil {
+		t.Fatalf("InsertText: %v", err)
+	}
+	_ = db.Close()
+	embedded := inner.texts.Load()
+
+	// A second process re-ingesting the same content embeds nothing
+	db = open()
+	defer func() { _ = db.Close() }()
+	if _, err := db.InsertGraphDocument(ctx, doc, GraphRAGIngestOptions{Extractor: fixtureExtractor{}}); err != nil {
+		t.Fatalf("InsertGraphDocument: %v", err)
+	}
+	if err := db.InsertTextBatch(ctx, texts, nil); err != nil {
+		t.Fatalf("InsertTextBatch: %v", err)
+	}
+	if err := db.InsertText(ctx, "t3", "alice research", nil); err != nil {
+		t.Fatalf("InsertText: %v", err)
+	}
+	if n := inner.texts.Load(); n != embedded {
+		t.Errorf("Expected no texts re-embedded, got %d more", n-embedded)
+	}
+
+	stats, err := db.EmbeddingCacheStats(ctx)
+	if err != nil {
+		t.Fatalf("EmbeddingCacheStats: %v", err)
+	}
+	if stats.Misses != 0 || stats.Hits == 0 {
+		t.Errorf("Expected only hits after reopening, got %+v", stats)
+	}
+
+	// Queries bypass the cache
+	if _, err := db.SearchText(ctx, "alice", 3); err != nil {
+		t.Fatalf("SearchText: %v", err)
+	}
+	if after, _ := db.EmbeddingCacheStats(ctx); after.Hits != stats.Hits {
+		t.Errorf("Expected searches not to touch the cache, got %+v", after)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/liliang-cn/cortexdb/v2/pkg/chunking"
 	"github.com/liliang-cn/cortexdb/v2/pkg/core"
 	"github.com/liliang-cn/cortexdb/v2/pkg/graph"
 )
@@ -22,6 +23,10 @@ const (
 	RetrievalModeLexical = "lexical"
 	// RetrievalModeGraph always enables graph expansion and entity enrichment.
 	RetrievalModeGraph = "graph"
+	// RetrievalModeGlobal answers corpus-wide questions from community reports
+	// (see BuildCommunityReports) instead of chunks. Tools without an embedder
+	// treat it as graph.
+	RetrievalModeGlobal = "global"
 )
 
 // GraphRAGDocument is the source unit ingested into the GraphRAG workflow.
@@ -34,16 +39,18 @@ type GraphRAGDocument struct {
 
 // GraphEntity describes an extracted entity.
 type GraphEntity struct {
-	Name string
-	Type string
+	Name        string
+	Type        string
+	Description string // Optional; stored as the entity node's "description" property
 }
 
 // GraphRelationship describes a directed relationship between entities.
 type GraphRelationship struct {
 	From   string
 	To     string
-	Type   string
-	Weight float64
+	Type        string
+	Weight      float64
+	Description string // Optional; stored as the edge's "description" property
 }
 
 // GraphExtraction holds entities and relationships extracted from text.
@@ -62,14 +69,20 @@ type GraphRAGIngestOptions struct {
 	Collection   string
 	ChunkSize    int
 	ChunkOverlap int
+	Chunker      chunking.Chunker // Splits the content (default: chunking.Words with ChunkSize and ChunkOverlap)
 	Extractor    GraphRAGExtractor
 }
 
 // GraphRAGIngestResult summarizes the graph artifacts created during ingestion.
+// EntityNodeIDs lists the entities the whole document mentions, including
+// those of unchanged chunks; AddedEntityNodeIDs only those extracted from
+// added chunks.
 type GraphRAGIngestResult struct {
-	DocumentNodeID string
-	ChunkNodeIDs   []string
-	EntityNodeIDs  []string
+	DocumentNodeID     string
+	ChunkNodeIDs       []string
+	EntityNodeIDs      []string
+	AddedEntityNodeIDs []string
+	Chunks             ChunkChanges
 }
 
 // GraphRAGQueryOptions controls GraphRAG retrieval behavior.
@@ -85,6 +98,9 @@ type GraphRAGQueryOptions struct {
 	DiversityLambda  float64
 	DisableGraph     bool
 	RetrievalMode    string
+
+	CommunityLevel  int             // Report level searched by RetrievalModeGlobal (default: 0, the finest)
+	CommunityMapper CommunityMapper // Picks points from each report for RetrievalModeGlobal (default: embedding similarity)
 }
 
 // GraphRAGChunkResult is a retrieved chunk plus graph context.
@@ -100,13 +116,20 @@ type GraphRAGChunkResult struct {
 
 // GraphRAGQueryResult contains the assembled GraphRAG retrieval output.
 type GraphRAGQueryResult struct {
-	Query    string
-	Chunks   []GraphRAGChunkResult
-	Entities []string
-	Context  string
+	Query       string
+	Chunks      []GraphRAGChunkResult
+	Entities    []string
+	Context     string
+	Communities []GraphRAGCommunityResult // Reports used by RetrievalModeGlobal
 }
 
 // InsertGraphDocument ingests a document into the vector store and graph store for GraphRAG retrieval.
+//
+// Re-ingesting a document is incremental: each chunk stores a hash of its
+// text, and stored chunks whose text is unchanged keep their IDs, vectors
+// and graph edges without being embedded or extracted again. Chunks that
+// are no longer present are deleted, along with the relationships extracted
+// from them and any entity they leave without edges.
 func (db *DB) InsertGraphDocument(ctx context.Context, doc GraphRAGDocument, opts GraphRAGIngestOptions) (*GraphRAGIngestResult, error) {
 	if db.embedder == nil {
 		return nil, ErrEmbedderNotConfigured
@@ -119,21 +142,67 @@ func (db *DB) InsertGraphDocument(ctx context.Context, doc GraphRAGDocument, opt
 	}
 
 	applyGraphRAGIngestDefaults(&opts)
+	// Stored chunks record the concrete collection; compare against that
+	collection, err := db.store.ResolveCollection(ctx, opts.Collection)
+	if err != nil {
+		return nil, err
+	}
+	opts.Collection = collection
 	if err := db.graph.InitGraphSchema(ctx); err != nil {
 		return nil, fmt.Errorf("init graph schema: %w", err)
 	}
 	if err := db.ensureGraphRAGCollection(ctx, opts.Collection); err != nil {
 		return nil, err
 	}
+	if err := db.checkCollectionModel(ctx, opts.Collection); err != nil {
+		return nil, err
+	}
 
-	chunks := splitGraphRAGText(doc.Content, opts.ChunkSize, opts.ChunkOverlap)
+	chunks := splitGraphRAGChunks(doc.Content, opts)
 	if len(chunks) == 0 {
 		return nil, ErrEmptyText
 	}
 
-	chunkVectors, err := db.embedder.EmbedBatch(ctx, chunks)
+	stored, err := db.storedGraphRAGChunks(ctx, doc.ID)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
+		return nil, err
+	}
+	modelID := db.ingestModelID()
+	plan := planGraphRAGChunks(doc.ID, opts.Collection, modelID, db.embedder.Dim(), chunks, stored)
+	orphanCandidates, err := db.chunkEntities(ctx, plan.removed)
+	if err != nil {
+		return nil, err
+	}
+	var kept []*core.Embedding
+	for _, emb := range plan.reused {
+		if emb != nil {
+			kept = append(kept, emb)
+		}
+	}
+	keptEntities, err := db.chunkEntities(ctx, kept)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkVectors := make([][]float32, len(chunks))
+	var addedTexts []string
+	for i, chunk := range chunks {
+		if plan.reused[i] != nil {
+			chunkVectors[i] = plan.reused[i].Vector
+		} else {
+			addedTexts = append(addedTexts, chunk.Text)
+		}
+	}
+	if len(addedTexts) > 0 {
+		vectors, err := db.ingestEmbedder().EmbedBatch(ctx, addedTexts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
+		}
+		for i := range chunks {
+			if plan.reused[i] == nil {
+				chunkVectors[i], vectors = vectors[0], vectors[1:]
+			}
+		}
 	}
 
 	documentNodeID := graphDocumentNodeID(doc.ID)
@@ -145,10 +214,6 @@ func (db *DB) InsertGraphDocument(ctx context.Context, doc GraphRAGDocument, opt
 		Content: doc.Content,
 		Version: 1,
 	}
-	if err := db.upsertGraphRAGDocumentRecord(ctx, documentRecord); err != nil {
-		return nil, err
-	}
-
 	documentNode := &graph.GraphNode{
 		ID:       documentNodeID,
 		Vector:   documentVector,
@@ -159,9 +224,6 @@ func (db *DB) InsertGraphDocument(ctx context.Context, doc GraphRAGDocument, opt
 			"title":       doc.Title,
 		},
 	}
-	if err := db.graph.UpsertNode(ctx, documentNode); err != nil {
-		return nil, fmt.Errorf("upsert document node: %w", err)
-	}
 
 	embeddings := make([]*core.Embedding, 0, len(chunks))
 	chunkNodes := make([]*graph.GraphNode, 0, len(chunks))
@@ -171,6 +233,7 @@ func (db *DB) InsertGraphDocument(ctx context.Context, doc GraphRAGDocument, opt
 	entityTexts := make(map[string]GraphEntity)
 	entityMentions := make(map[string]map[string]struct{})
 	relationshipKeys := make(map[string]graph.GraphEdge)
+	aliased := make(map[string]bool) // Entities reached through a merged-away name
 
 	extractor := opts.Extractor
 	if extractor == nil {
@@ -178,36 +241,32 @@ func (db *DB) InsertGraphDocument(ctx context.Context, doc GraphRAGDocument, opt
 	}
 
 	for i, chunk := range chunks {
-		chunkID := graphChunkNodeID(doc.ID, i)
+		chunkID := plan.ids[i]
 		chunkNodeIDs = append(chunkNodeIDs, chunkID)
 
-		metadata := map[string]string{
-			"graph_kind":  "chunk",
-			"document_id": doc.ID,
-			"chunk_index": fmt.Sprintf("%d", i),
-			"title":       doc.Title,
-		}
-		for k, v := range doc.Metadata {
-			metadata[k] = v
-		}
+		metadata := graphRAGChunkMetadata(doc.ID, doc.Title, i, chunk, doc.Metadata)
+		metadata["chunk_hash"] = plan.hashes[i]
 
 		embeddings = append(embeddings, &core.Embedding{
 			ID:         chunkID,
 			Collection: opts.Collection,
 			Vector:     chunkVectors[i],
-			Content:    chunk,
+			Content:    chunk.Text,
 			DocID:      doc.ID,
 			Metadata:   metadata,
+			ModelID:    modelID,
 		})
 
 		chunkNodes = append(chunkNodes, &graph.GraphNode{
 			ID:       chunkID,
 			Vector:   chunkVectors[i],
-			Content:  chunk,
+			Content:  chunk.Text,
 			NodeType: "chunk",
 			Properties: map[string]interface{}{
 				"document_id": doc.ID,
 				"chunk_index": i,
+				"chunk_start": chunk.Start,
+				"chunk_end":   chunk.End,
 				"title":       doc.Title,
 			},
 		})
@@ -222,29 +281,46 @@ func (db *DB) InsertGraphDocument(ctx context.Context, doc GraphRAGDocument, opt
 		if i > 0 {
 			edges = append(edges, &graph.GraphEdge{
 				ID:         fmt.Sprintf("edge:chunk_next:%s:%d", doc.ID, i),
-				FromNodeID: graphChunkNodeID(doc.ID, i-1),
+				FromNodeID: plan.ids[i-1],
 				ToNodeID:   chunkID,
 				EdgeType:   "next",
 				Weight:     1.0,
 			})
 		}
 
-		extraction, err := extractor.Extract(ctx, chunk)
+		if plan.reused[i] != nil {
+			// Unchanged: its mentions and relationships are already stored
+			continue
+		}
+		extraction, err := extractor.Extract(ctx, chunk.Text)
 		if err != nil {
 			return nil, fmt.Errorf("extract graph entities: %w", err)
 		}
 		if extraction == nil {
 			continue
 		}
+		aliases, err := db.entityAliases(ctx, extractionEntityIDs(extraction))
+		if err != nil {
+			return nil, err
+		}
+		resolve := func(name string) string {
+			id := graphEntityNodeID(name)
+			if canonical, ok := aliases[id]; ok {
+				aliased[canonical] = true
+				return canonical
+			}
+			return id
+		}
 
 		for _, entity := range extraction.Entities {
 			if strings.TrimSpace(entity.Name) == "" {
 				continue
 			}
-			entityID := graphEntityNodeID(entity.Name)
+			entityID := resolve(entity.Name)
 			entityTexts[entityID] = GraphEntity{
-				Name: entity.Name,
-				Type: firstNonEmpty(entity.Type, "entity"),
+				Name:        entity.Name,
+				Type:        firstNonEmpty(entity.Type, "entity"),
+				Description: firstNonEmpty(entityTexts[entityID].Description, entity.Description),
 			}
 			if entityMentions[chunkID] == nil {
 				entityMentions[chunkID] = make(map[string]struct{})
@@ -256,72 +332,95 @@ func (db *DB) InsertGraphDocument(ctx context.Context, doc GraphRAGDocument, opt
 			if strings.TrimSpace(rel.From) == "" || strings.TrimSpace(rel.To) == "" {
 				continue
 			}
-			fromID := graphEntityNodeID(rel.From)
-			toID := graphEntityNodeID(rel.To)
+			fromID := resolve(rel.From)
+			toID := resolve(rel.To)
 			relType := firstNonEmpty(rel.Type, "related_to")
 			weight := rel.Weight
 			if weight == 0 {
 				weight = 1.0
 			}
 			key := fmt.Sprintf("%s|%s|%s|%s", chunkID, fromID, toID, relType)
+			properties := map[string]interface{}{
+				"source_chunk_id": chunkID,
+				"document_id":     doc.ID,
+			}
+			if rel.Description != "" {
+				properties["description"] = rel.Description
+			}
 			relationshipKeys[key] = graph.GraphEdge{
-				ID:         fmt.Sprintf("edge:rel:%s:%s:%s:%s", chunkID, fromID, toID, relType),
+				ID:         graphRelationEdgeID(chunkID, fromID, toID, relType),
 				FromNodeID: fromID,
 				ToNodeID:   toID,
 				EdgeType:   relType,
 				Weight:     weight,
-				Properties: map[string]interface{}{
-					"source_chunk_id": chunkID,
-					"document_id":     doc.ID,
-				},
+				Properties: properties,
 			}
 		}
 	}
 
-	if err := db.store.UpsertBatch(ctx, embeddings); err != nil {
-		return nil, fmt.Errorf("upsert graphrag embeddings: %w", err)
-	}
-	if _, err := db.graph.UpsertNodesBatch(ctx, chunkNodes); err != nil {
-		return nil, fmt.Errorf("upsert chunk graph nodes: %w", err)
-	}
-
 	entityNodeIDs := make([]string, 0, len(entityTexts))
+	var entityNodes []*graph.GraphNode
 	if len(entityTexts) > 0 {
+		allIDs := make([]string, 0, len(entityTexts))
+		for entityID := range entityTexts {
+			allIDs = append(allIDs, entityID)
+		}
+		existingNodes, err := db.graph.GetNodesBatch(ctx, allIDs)
+		if err != nil {
+			return nil, fmt.Errorf("load entities: %w", err)
+		}
+		existing := make(map[string]*graph.GraphNode, len(existingNodes))
+		for _, node := range existingNodes {
+			existing[node.ID] = node
+		}
+
 		entityNames := make([]string, 0, len(entityTexts))
 		idOrder := make([]string, 0, len(entityTexts))
 		for entityID, entity := range entityTexts {
+			if aliased[entityID] && existing[entityID] != nil {
+				// Merged entities keep their canonical name
+				entityNodeIDs = append(entityNodeIDs, entityID)
+				continue
+			}
 			idOrder = append(idOrder, entityID)
 			entityNames = append(entityNames, entity.Name)
 		}
 
-		entityVectors, err := db.embedder.EmbedBatch(ctx, entityNames)
-		if err != nil {
-			return nil, fmt.Errorf("embed entities: %w", err)
+		var entityVectors [][]float32
+		if len(entityNames) > 0 {
+			entityVectors, err = db.ingestEmbedder().EmbedBatch(ctx, entityNames)
+			if err != nil {
+				return nil, fmt.Errorf("embed entities: %w", err)
+			}
 		}
 
-		entityNodes := make([]*graph.GraphNode, 0, len(entityNames))
+		entityNodes = make([]*graph.GraphNode, 0, len(entityNames))
 		for i, entityID := range idOrder {
 			entity := entityTexts[entityID]
 			entityNodeIDs = append(entityNodeIDs, entityID)
+			properties := map[string]interface{}{
+				"name": entity.Name,
+				"type": entity.Type,
+			}
+			if entity.Description != "" {
+				properties["description"] = entity.Description
+			}
+			if prior := existing[entityID]; prior != nil && prior.Properties["aliases"] != nil {
+				properties["aliases"] = prior.Properties["aliases"]
+			}
 			entityNodes = append(entityNodes, &graph.GraphNode{
-				ID:       entityID,
-				Vector:   entityVectors[i],
-				Content:  entity.Name,
-				NodeType: entity.Type,
-				Properties: map[string]interface{}{
-					"name": entity.Name,
-					"type": entity.Type,
-				},
+				ID:         entityID,
+				Vector:     entityVectors[i],
+				Content:    entity.Name,
+				NodeType:   entity.Type,
+				Properties: properties,
 			})
 		}
-		if _, err := db.graph.UpsertNodesBatch(ctx, entityNodes); err != nil {
-			return nil, fmt.Errorf("upsert entity nodes: %w", err)
-		}
 
 		for chunkID, mentioned := range entityMentions {
 			for entityID := range mentioned {
 				edges = append(edges, &graph.GraphEdge{
-					ID:         fmt.Sprintf("edge:mention:%s:%s", chunkID, entityID),
+					ID:         graphMentionEdgeID(chunkID, entityID),
 					FromNodeID: chunkID,
 					ToNodeID:   entityID,
 					EdgeType:   "mentions",
@@ -344,16 +443,47 @@ func (db *DB) InsertGraphDocument(ctx context.Context, doc GraphRAGDocument, opt
 		}
 	}
 
-	if len(edges) > 0 {
-		if _, err := db.graph.UpsertEdgesBatch(ctx, edges); err != nil {
-			return nil, fmt.Errorf("upsert graph edges: %w", err)
+	// Embedding and extraction are done; write everything in one transaction
+	// so a failure cannot leave a partially ingested document behind.
+	err = db.Update(ctx, func(tx *Tx) error {
+		if err := upsertGraphRAGDocumentRecord(ctx, tx, documentRecord); err != nil {
+			return err
+		}
+		related, err := deleteRemovedGraphRAGChunks(ctx, tx, doc.ID, plan)
+		if err != nil {
+			return err
+		}
+		orphanCandidates = append(orphanCandidates, related...)
+		if err := tx.Graph().UpsertNode(ctx, documentNode); err != nil {
+			return fmt.Errorf("upsert document node: %w", err)
+		}
+		if err := tx.Vector().UpsertBatch(ctx, embeddings); err != nil {
+			return fmt.Errorf("upsert graphrag embeddings: %w", err)
 		}
+		if _, err := tx.Graph().UpsertNodesBatch(ctx, chunkNodes); err != nil {
+			return fmt.Errorf("upsert chunk graph nodes: %w", err)
+		}
+		if _, err := tx.Graph().UpsertNodesBatch(ctx, entityNodes); err != nil {
+			return fmt.Errorf("upsert entity nodes: %w", err)
+		}
+		if _, err := tx.Graph().UpsertEdgesBatch(ctx, edges); err != nil {
+			return fmt.Errorf("upsert graph edges: %w", err)
+		}
+		if _, err := tx.Graph().DeleteOrphanNodes(ctx, entityCandidates(orphanCandidates)); err != nil {
+			return fmt.Errorf("delete orphaned entities: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &GraphRAGIngestResult{
-		DocumentNodeID: documentNodeID,
-		ChunkNodeIDs:   chunkNodeIDs,
-		EntityNodeIDs:  entityNodeIDs,
+		DocumentNodeID:     documentNodeID,
+		ChunkNodeIDs:       chunkNodeIDs,
+		EntityNodeIDs:      uniqueSortedStrings(append(keptEntities, entityNodeIDs...)),
+		AddedEntityNodeIDs: uniqueSortedStrings(entityNodeIDs),
+		Chunks:             plan.changes(),
 	}, nil
 }
 
@@ -367,10 +497,16 @@ func (db *DB) SearchGraphRAG(ctx context.Context, query string, opts GraphRAGQue
 	}
 
 	applyGraphRAGQueryDefaults(&opts)
+	if err := db.checkCollectionModel(ctx, opts.Collection); err != nil {
+		return nil, err
+	}
 	queryVector, err := db.embedder.Embed(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
 	}
+	if opts.RetrievalMode == RetrievalModeGlobal {
+		return db.searchGraphRAGGlobal(ctx, query, queryVector, opts)
+	}
 
 	seeds, err := db.store.Search(ctx, queryVector, core.SearchOptions{
 		Collection: opts.Collection,
@@ -586,60 +722,54 @@ func (db *DB) ensureGraphRAGCollection(ctx context.Context, name string) error {
 	return nil
 }
 
-func (db *DB) upsertGraphRAGDocumentRecord(ctx context.Context, doc *core.Document) error {
-	existing, err := db.store.GetDocument(ctx, doc.ID)
+func upsertGraphRAGDocumentRecord(ctx context.Context, tx *Tx, doc *core.Document) error {
+	existing, err := tx.Vector().GetDocument(ctx, doc.ID)
 	if err != nil {
-		if err := db.store.CreateDocument(ctx, doc); err != nil {
+		if err := tx.Vector().CreateDocument(ctx, doc); err != nil {
 			return fmt.Errorf("create document record: %w", err)
 		}
 		return nil
 	}
+	if existing.Title == doc.Title && existing.Content == doc.Content {
+		// Re-ingesting the same text doesn't start a new version
+		return nil
+	}
 	existing.Title = doc.Title
 	existing.Content = doc.Content
 	existing.Version++
-	if err := db.store.UpdateDocument(ctx, existing); err != nil {
+	if err := tx.Vector().UpdateDocument(ctx, existing); err != nil {
 		return fmt.Errorf("update document record: %w", err)
 	}
 	return nil
 }
 
-func splitGraphRAGText(text string, chunkSize, chunkOverlap int) []string {
-	paragraphs := strings.FieldsFunc(text, func(r rune) bool {
-		return r == '\n' || r == '\r'
-	})
-
-	var chunks []string
-	for _, paragraph := range paragraphs {
-		paragraph = strings.TrimSpace(paragraph)
-		if paragraph == "" {
-			continue
-		}
-		words := strings.Fields(paragraph)
-		if len(words) == 0 {
-			continue
-		}
-		if len(words) <= chunkSize {
-			chunks = append(chunks, strings.Join(words, " "))
-			continue
-		}
-
-		step := chunkSize - chunkOverlap
-		if step <= 0 {
-			step = chunkSize
-		}
-		for start := 0; start < len(words); start += step {
-			end := start + chunkSize
-			if end > len(words) {
-				end = len(words)
-			}
-			chunks = append(chunks, strings.Join(words[start:end], " "))
-			if end == len(words) {
-				break
-			}
-		}
+// splitGraphRAGChunks splits content with the configured chunker
+func splitGraphRAGChunks(content string, opts GraphRAGIngestOptions) []chunking.Chunk {
+	chunker := opts.Chunker
+	if chunker == nil {
+		chunker = chunking.Words{Size: opts.ChunkSize, Overlap: opts.ChunkOverlap}
 	}
+	return chunker.Chunk(content)
+}
 
-	return chunks
+// graphRAGChunkMetadata builds a chunk's metadata: its position in the
+// document, then what the chunker recorded, then the document's metadata
+func graphRAGChunkMetadata(documentID, title string, index int, chunk chunking.Chunk, docMetadata map[string]string) map[string]string {
+	metadata := map[string]string{
+		"graph_kind":  "chunk",
+		"document_id": documentID,
+		"chunk_index": fmt.Sprintf("%d", index),
+		"chunk_start": fmt.Sprintf("%d", chunk.Start),
+		"chunk_end":   fmt.Sprintf("%d", chunk.End),
+		"title":       title,
+	}
+	for k, v := range chunk.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range docMetadata {
+		metadata[k] = v
+	}
+	return metadata
 }
 
 func averageVectors(vectors [][]float32, dim int) []float32 {
@@ -666,6 +796,19 @@ func graphChunkNodeID(documentID string, index int) string {
 	return fmt.Sprintf("chunk:%s:%03d", documentID, index)
 }
 
+func graphMentionEdgeID(chunkID, entityID string) string {
+	return fmt.Sprintf("edge:mention:%s:%s", chunkID, entityID)
+}
+
+func graphRelationEdgeID(chunkID, fromID, toID, relType string) string {
+	return fmt.Sprintf("edge:rel:%s:%s:%s:%s", chunkID, fromID, toID, relType)
+}
+
+// toolRelationEdgeID identifies the i-th relation of an UpsertRelations call
+func toolRelationEdgeID(fromID, toID, relType string, i int) string {
+	return fmt.Sprintf("edge:relation:%s:%s:%s:%d", fromID, toID, relType, i)
+}
+
 func graphEntityNodeID(name string) string {
 	normalized := strings.ToLower(strings.TrimSpace(name))
 	var b strings.Builder
@@ -860,6 +1003,8 @@ func normalizeRetrievalMode(mode string) string {
 		return RetrievalModeLexical
 	case RetrievalModeGraph:
 		return RetrievalModeGraph
+	case RetrievalModeGlobal:
+		return RetrievalModeGlobal
 	default:
 		return RetrievalModeAuto
 	}
@@ -873,7 +1018,7 @@ func shouldUseGraphRetrieval(mode string, disableGraph bool, query string, entit
 	switch normalizeRetrievalMode(mode) {
 	case RetrievalModeLexical:
 		return false
-	case RetrievalModeGraph:
+	case RetrievalModeGraph, RetrievalModeGlobal:
 		return true
 	default:
 		if len(entityNames) > 0 {
@@ -891,7 +1036,7 @@ func shouldLoadChunkEntities(mode string, disableGraph bool, query string) bool
 	switch normalizeRetrievalMode(mode) {
 	case RetrievalModeLexical:
 		return false
-	case RetrievalModeGraph:
+	case RetrievalModeGraph, RetrievalModeGlobal:
 		return true
 	default:
 		if strings.TrimSpace(query) == "" {
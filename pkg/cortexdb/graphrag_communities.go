@@ -0,0 +1,540 @@
+package cortexdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+	"github.com/liliang-cn/cortexdb/v2/pkg/graph"
+)
+
+// ErrNoCommunityReports is returned by global retrieval before
+// BuildCommunityReports has run.
+var ErrNoCommunityReports = errors.New("cortexdb: no community reports, call BuildCommunityReports first")
+
+const communityReportNodeType = "community_report"
+
+// CommunitySummarizer writes the report for one community of entities
+type CommunitySummarizer interface {
+	SummarizeCommunity(ctx context.Context, community CommunityInput) (*CommunitySummary, error)
+}
+
+// CommunityMapper is the map step of global retrieval: it picks the points
+// of a report that help answer query and scores them from 0 to 100
+type CommunityMapper interface {
+	MapCommunity(ctx context.Context, query string, report CommunityReport) ([]CommunityPoint, error)
+}
+
+// CommunityInput is what a summarizer sees of a community. Entities are
+// ordered by how connected they are; SubReports holds the reports of the
+// community's children on the level below.
+type CommunityInput struct {
+	Level         int
+	Entities      []GraphEntity
+	Relationships []GraphRelationship
+	SubReports    []CommunitySummary
+}
+
+// CommunitySummary is a summarizer's report
+type CommunitySummary struct {
+	Title    string   `json:"title"`
+	Summary  string   `json:"summary"`
+	Findings []string `json:"findings,omitempty"`
+	Rating   float64  `json:"rating"` // Importance from 0 to 10
+}
+
+// CommunityReport is a stored report with its place in the hierarchy
+type CommunityReport struct {
+	CommunitySummary
+	ID          string   `json:"id"`
+	Level       int      `json:"level"`
+	ParentID    string   `json:"parent_id,omitempty"`
+	ChildIDs    []string `json:"child_ids,omitempty"`
+	EntityIDs   []string `json:"entity_ids"`
+	EntityNames []string `json:"entity_names"`
+	vector      []float32
+}
+
+// CommunityPoint is one point of a report selected for a query
+type CommunityPoint struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"` // 0 to 100; points at or below 0 are dropped
+}
+
+// GraphRAGCommunityResult is a report that contributed to a global answer
+type GraphRAGCommunityResult struct {
+	ID     string
+	Title  string
+	Level  int
+	Score  float64 // Best score among the report's points
+	Points []CommunityPoint
+}
+
+// CommunityReportOptions controls BuildCommunityReports.
+type CommunityReportOptions struct {
+	MaxLevels        int                 // Hierarchy levels to build at most (default: 3)
+	MinCommunitySize int                 // Smaller communities get no report (default: 2)
+	MaxEntities      int                 // Most connected entities passed to the summarizer (default: 40)
+	MaxRelationships int                 // Relationships passed to the summarizer (default: 60)
+	Summarizer       CommunitySummarizer // Writes the reports (default: an extractive summary)
+}
+
+// CommunityReportResult summarizes a BuildCommunityReports run
+type CommunityReportResult struct {
+	Levels  int `json:"levels"`
+	Reports int `json:"reports"`
+	Removed int `json:"removed"` // Reports from the previous run replaced
+}
+
+// BuildCommunityReports groups the knowledge graph's entities into a
+// hierarchy of communities, linked by extracted relationships and by being
+// mentioned in the same chunk, and stores a report per community as an
+// embedded graph node. Reports are written bottom-up, so each level's
+// summarizer sees the reports of the level below. Previous reports are
+// replaced.
+func (db *DB) BuildCommunityReports(ctx context.Context, opts CommunityReportOptions) (*CommunityReportResult, error) {
+	if db.embedder == nil {
+		return nil, ErrEmbedderNotConfigured
+	}
+	if opts.MaxLevels <= 0 {
+		opts.MaxLevels = 3
+	}
+	if opts.MinCommunitySize <= 0 {
+		opts.MinCommunitySize = 2
+	}
+	if opts.MaxEntities <= 0 {
+		opts.MaxEntities = 40
+	}
+	if opts.MaxRelationships <= 0 {
+		opts.MaxRelationships = 60
+	}
+	summarizer := opts.Summarizer
+	if summarizer == nil {
+		summarizer = extractiveSummarizer{}
+	}
+
+	levels, err := db.graph.CommunityHierarchy(ctx, graph.HierarchyOptions{
+		NodeIDPrefix: "entity:",
+		MaxLevels:    opts.MaxLevels,
+		Project:      []string{"mentions"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("detect communities: %w", err)
+	}
+
+	entities, relationships, degree, err := db.loadEntityGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// reports[l][i] is the report of community i on level l, nil if too small
+	reports := make([][]*CommunityReport, len(levels))
+	var nodes []*graph.GraphNode
+	var contents []string
+	for l, level := range levels {
+		reports[l] = make([]*CommunityReport, len(level.Communities))
+		for i, community := range level.Communities {
+			if len(community.Nodes) < opts.MinCommunitySize {
+				continue
+			}
+			report := &CommunityReport{ID: fmt.Sprintf("community:%d:%d", l, i), Level: l}
+			input := CommunityInput{Level: l}
+
+			members := append([]string(nil), community.Nodes...)
+			sort.SliceStable(members, func(a, b int) bool { return degree[members[a]] > degree[members[b]] })
+			inCommunity := make(map[string]bool, len(members))
+			for _, id := range members {
+				inCommunity[id] = true
+				if entity, ok := entities[id]; ok {
+					report.EntityIDs = append(report.EntityIDs, id)
+					report.EntityNames = append(report.EntityNames, entity.Name)
+					if len(input.Entities) < opts.MaxEntities {
+						input.Entities = append(input.Entities, entity)
+					}
+				}
+			}
+			for _, rel := range relationships {
+				if len(input.Relationships) >= opts.MaxRelationships {
+					break
+				}
+				if inCommunity[graphEntityNodeID(rel.From)] && inCommunity[graphEntityNodeID(rel.To)] {
+					input.Relationships = append(input.Relationships, rel)
+				}
+			}
+			if l > 0 {
+				for c, parent := range levels[l-1].Parents {
+					if child := reports[l-1][c]; parent == i && child != nil {
+						report.ChildIDs = append(report.ChildIDs, child.ID)
+						child.ParentID = report.ID
+						input.SubReports = append(input.SubReports, child.CommunitySummary)
+					}
+				}
+			}
+
+			summary, err := summarizer.SummarizeCommunity(ctx, input)
+			if err != nil {
+				return nil, fmt.Errorf("summarize community %s: %w", report.ID, err)
+			}
+			if summary == nil {
+				continue
+			}
+			report.CommunitySummary = *summary
+			reports[l][i] = report
+		}
+	}
+
+	var all []*CommunityReport
+	for _, level := range reports {
+		for _, report := range level {
+			if report != nil {
+				all = append(all, report)
+				contents = append(contents, communityReportText(report.CommunitySummary))
+			}
+		}
+	}
+	if len(all) > 0 {
+		vectors, err := db.ingestEmbedder().EmbedBatch(ctx, contents)
+		if err != nil {
+			return nil, fmt.Errorf("embed community reports: %w", err)
+		}
+		for i, report := range all {
+			nodes = append(nodes, &graph.GraphNode{
+				ID:         report.ID,
+				Vector:     vectors[i],
+				Content:    contents[i],
+				NodeType:   communityReportNodeType,
+				Properties: communityReportProperties(report),
+			})
+		}
+	}
+
+	old, err := db.graph.GetAllNodes(ctx, &graph.GraphFilter{NodeTypes: []string{communityReportNodeType}})
+	if err != nil {
+		return nil, fmt.Errorf("load community reports: %w", err)
+	}
+	oldIDs := make([]string, 0, len(old))
+	for _, node := range old {
+		oldIDs = append(oldIDs, node.ID)
+	}
+
+	err = db.Update(ctx, func(tx *Tx) error {
+		if _, err := tx.Graph().DeleteNodesBatch(ctx, oldIDs); err != nil {
+			return fmt.Errorf("delete community reports: %w", err)
+		}
+		if _, err := tx.Graph().UpsertNodesBatch(ctx, nodes); err != nil {
+			return fmt.Errorf("store community reports: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CommunityReportResult{Levels: len(levels), Reports: len(all), Removed: len(oldIDs)}, nil
+}
+
+// CommunityReports returns the stored reports of one level, or of every
+// level when level is negative, most important first.
+func (db *DB) CommunityReports(ctx context.Context, level int) ([]CommunityReport, error) {
+	nodes, err := db.graph.GetAllNodes(ctx, &graph.GraphFilter{NodeTypes: []string{communityReportNodeType}})
+	if err != nil {
+		return nil, fmt.Errorf("load community reports: %w", err)
+	}
+	reports := make([]CommunityReport, 0, len(nodes))
+	for _, node := range nodes {
+		report := communityReportFromNode(node)
+		if level < 0 || report.Level == level {
+			reports = append(reports, report)
+		}
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Level != reports[j].Level {
+			return reports[i].Level < reports[j].Level
+		}
+		if reports[i].Rating != reports[j].Rating {
+			return reports[i].Rating > reports[j].Rating
+		}
+		return reports[i].ID < reports[j].ID
+	})
+	return reports, nil
+}
+
+// searchGraphRAGGlobal map-reduces over the reports of one level: each
+// report contributes its scored points, and the best points across all
+// reports fill the context.
+func (db *DB) searchGraphRAGGlobal(ctx context.Context, query string, queryVector []float32, opts GraphRAGQueryOptions) (*GraphRAGQueryResult, error) {
+	all, err := db.CommunityReports(ctx, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(all) == 0 {
+		return nil, ErrNoCommunityReports
+	}
+	var reports []CommunityReport
+	for _, report := range all {
+		if report.Level == opts.CommunityLevel {
+			reports = append(reports, report)
+		}
+	}
+
+	mapper := opts.CommunityMapper
+	if mapper == nil {
+		mapper = similarityMapper{queryVector: queryVector}
+	}
+
+	type scoredPoint struct {
+		CommunityPoint
+		report int
+	}
+	var points []scoredPoint
+	results := make([]GraphRAGCommunityResult, len(reports))
+	for i, report := range reports {
+		mapped, err := mapper.MapCommunity(ctx, query, report)
+		if err != nil {
+			return nil, fmt.Errorf("map community %s: %w", report.ID, err)
+		}
+		results[i] = GraphRAGCommunityResult{ID: report.ID, Title: report.Title, Level: report.Level}
+		for _, p := range mapped {
+			if p.Score > 0 && strings.TrimSpace(p.Text) != "" {
+				points = append(points, scoredPoint{CommunityPoint: p, report: i})
+			}
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Score > points[j].Score })
+
+	result := &GraphRAGQueryResult{Query: query}
+	var lines []string
+	used, chars := 0, 0
+	seen := make(map[string]bool)
+	entitySet := make(map[string]struct{})
+	for _, p := range points {
+		if used >= opts.MaxContextChunks {
+			break
+		}
+		if seen[p.Text] {
+			continue
+		}
+		line := fmt.Sprintf("[%s] %s", reports[p.report].ID, p.Text)
+		if used > 0 && chars+len(line) > opts.MaxContextChars {
+			continue
+		}
+		seen[p.Text] = true
+		lines = append(lines, line)
+		used++
+		chars += len(line) + 1
+
+		r := &results[p.report]
+		if len(r.Points) == 0 {
+			for _, name := range reports[p.report].EntityNames {
+				entitySet[name] = struct{}{}
+			}
+		}
+		r.Points = append(r.Points, p.CommunityPoint)
+		r.Score = math.Max(r.Score, p.Score)
+	}
+
+	for _, r := range results {
+		if len(r.Points) > 0 {
+			result.Communities = append(result.Communities, r)
+		}
+	}
+	sort.SliceStable(result.Communities, func(i, j int) bool { return result.Communities[i].Score > result.Communities[j].Score })
+	result.Entities = sortedKeys(entitySet)
+	result.Context = strings.Join(lines, "\n")
+	return result, nil
+}
+
+// similarityMapper scores a report's summary and findings by the report's
+// embedding similarity to the query, its rating and each point's term
+// overlap with the query
+type similarityMapper struct {
+	queryVector []float32
+}
+
+func (m similarityMapper) MapCommunity(_ context.Context, query string, report CommunityReport) ([]CommunityPoint, error) {
+	relevance := core.CosineSimilarity(m.queryVector, report.vector)
+	if relevance <= 0 {
+		return nil, nil
+	}
+	importance := 0.5 + math.Min(math.Max(report.Rating, 0), 10)/20
+	terms := tokenSet(query)
+
+	var points []CommunityPoint
+	for _, text := range append([]string{report.Summary}, report.Findings...) {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		overlap := overlapScore(terms, tokenSet(text))
+		points = append(points, CommunityPoint{Text: text, Score: 100 * relevance * importance * (0.75 + 0.25*overlap)})
+	}
+	return points, nil
+}
+
+// extractiveSummarizer reports a community's entities and relationships
+// without a language model
+type extractiveSummarizer struct{}
+
+func (extractiveSummarizer) SummarizeCommunity(_ context.Context, c CommunityInput) (*CommunitySummary, error) {
+	if len(c.Entities) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, 3)
+	described := make([]string, 0, len(c.Entities))
+	for _, e := range c.Entities {
+		if len(names) < 3 {
+			names = append(names, e.Name)
+		}
+		described = append(described, fmt.Sprintf("%s (%s)", e.Name, firstNonEmpty(e.Type, "entity")))
+	}
+
+	summary := fmt.Sprintf("%d entities: %s.", len(c.Entities), strings.Join(described, ", "))
+	if len(c.SubReports) > 0 {
+		titles := make([]string, 0, len(c.SubReports))
+		for _, sub := range c.SubReports {
+			titles = append(titles, sub.Title)
+		}
+		summary += " Groups: " + strings.Join(titles, "; ") + "."
+	}
+
+	var findings []string
+	for _, rel := range c.Relationships {
+		finding := fmt.Sprintf("%s %s %s", rel.From, strings.ReplaceAll(firstNonEmpty(rel.Type, "related_to"), "_", " "), rel.To)
+		if rel.Description != "" {
+			finding += ": " + rel.Description
+		}
+		findings = append(findings, finding)
+	}
+	for _, e := range c.Entities {
+		if e.Description != "" {
+			findings = append(findings, e.Name+": "+e.Description)
+		}
+	}
+	if len(findings) > 20 {
+		findings = findings[:20]
+	}
+
+	return &CommunitySummary{
+		Title:    strings.Join(names, ", "),
+		Summary:  summary,
+		Findings: findings,
+		Rating:   math.Min(10, 1+float64(len(c.Relationships))/2),
+	}, nil
+}
+
+// loadEntityGraph loads the entities, the relationships between them, and
+// each entity's relationship count
+func (db *DB) loadEntityGraph(ctx context.Context) (map[string]GraphEntity, []GraphRelationship, map[string]int, error) {
+	nodes, err := db.graph.GetAllNodes(ctx, nil)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("load entities: %w", err)
+	}
+	entities := make(map[string]GraphEntity)
+	for _, node := range nodes {
+		if !strings.HasPrefix(node.ID, "entity:") {
+			continue
+		}
+		description, _ := stringProperty(node.Properties, "description")
+		entities[node.ID] = GraphEntity{Name: node.Content, Type: node.NodeType, Description: description}
+	}
+
+	ids := make([]string, 0, len(entities))
+	for id := range entities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var relationships []GraphRelationship
+	degree := make(map[string]int, len(entities))
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		edges, err := db.graph.GetEdges(ctx, id, "out")
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("load relationships: %w", err)
+		}
+		for _, edge := range edges {
+			to, ok := entities[edge.ToNodeID]
+			if !ok {
+				continue
+			}
+			degree[id]++
+			degree[edge.ToNodeID]++
+			key := id + "|" + edge.ToNodeID + "|" + edge.EdgeType
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			description, _ := stringProperty(edge.Properties, "description")
+			relationships = append(relationships, GraphRelationship{
+				From:        entities[id].Name,
+				To:          to.Name,
+				Type:        edge.EdgeType,
+				Weight:      edge.Weight,
+				Description: description,
+			})
+		}
+	}
+	sort.SliceStable(relationships, func(i, j int) bool { return relationships[i].Weight > relationships[j].Weight })
+	return entities, relationships, degree, nil
+}
+
+// communityReportText is the embedded text of a report
+func communityReportText(s CommunitySummary) string {
+	var b strings.Builder
+	b.WriteString(s.Title)
+	if s.Summary != "" {
+		b.WriteString("\n\n")
+		b.WriteString(s.Summary)
+	}
+	for _, f := range s.Findings {
+		b.WriteString("\n- ")
+		b.WriteString(f)
+	}
+	return b.String()
+}
+
+func communityReportProperties(r *CommunityReport) map[string]interface{} {
+	return map[string]interface{}{
+		"level":        r.Level,
+		"title":        r.Title,
+		"summary":      r.Summary,
+		"findings":     r.Findings,
+		"rating":       r.Rating,
+		"parent_id":    r.ParentID,
+		"child_ids":    r.ChildIDs,
+		"entity_ids":   r.EntityIDs,
+		"entity_names": r.EntityNames,
+	}
+}
+
+func communityReportFromNode(node *graph.GraphNode) CommunityReport {
+	p := node.Properties
+	report := CommunityReport{ID: node.ID, vector: node.Vector}
+	report.Title, _ = stringProperty(p, "title")
+	report.Summary, _ = stringProperty(p, "summary")
+	report.ParentID, _ = stringProperty(p, "parent_id")
+	if v, ok := p["level"].(float64); ok {
+		report.Level = int(v)
+	}
+	if v, ok := p["rating"].(float64); ok {
+		report.Rating = v
+	}
+	report.Findings = stringListProperty(p, "findings")
+	report.ChildIDs = stringListProperty(p, "child_ids")
+	report.EntityIDs = stringListProperty(p, "entity_ids")
+	report.EntityNames = stringListProperty(p, "entity_names")
+	return report
+}
+
+func stringListProperty(properties map[string]interface{}, key string) []string {
+	items, _ := properties[key].([]interface{})
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
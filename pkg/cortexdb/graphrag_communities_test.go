@@ -0,0 +1,164 @@
+package cortexdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tripleExtractor reads sentences of the form "Subject relation Object."
+type tripleExtractor struct{}
+
+func (tripleExtractor) Extract(_ context.Context, text string) (*GraphExtraction, error) {
+	extraction := &GraphExtraction{}
+	seen := make(map[string]bool)
+	for _, sentence := range strings.Split(text, ".") {
+		fields := strings.Fields(sentence)
+		if len(fields) != 3 {
+			continue
+		}
+		for _, name := range []string{fields[0], fields[2]} {
+			if !seen[name] {
+				seen[name] = true
+				extraction.Entities = append(extraction.Entities, GraphEntity{Name: name, Type: "thing"})
+			}
+		}
+		extraction.Relationships = append(extraction.Relationships, GraphRelationship{From: fields[0], To: fields[2], Type: fields[1], Weight: 1})
+	}
+	return extraction, nil
+}
+
+func openCommunityTestDB(t *testing.T) *DB {
+	t.Helper()
+	ctx := context.Background()
+	db, err := Open(DefaultConfig(filepath.Join(t.TempDir(), "communities.db")),
+		WithEmbedder(newKeywordEmbedder("alice", "bob", "acme", "paris", "lyon", "france")))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	docs := []GraphRAGDocument{
+		{ID: "staff", Content: "Alice works_at Acme. Bob works_at Acme. Alice knows Bob."},
+		{ID: "geo", Content: "Paris capital_of France. Lyon city_in France. Paris near Lyon."},
+	}
+	for _, doc := range docs {
+		if _, err := db.InsertGraphDocument(ctx, doc, GraphRAGIngestOptions{Extractor: tripleExtractor{}}); err != nil {
+			t.Fatalf("InsertGraphDocument failed: %v", err)
+		}
+	}
+	return db
+}
+
+func TestCommunityReports(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GlobalSearch", func(t *testing.T) {
+		db := openCommunityTestDB(t)
+
+		_, err := db.SearchGraphRAG(ctx, "alice", GraphRAGQueryOptions{RetrievalMode: RetrievalModeGlobal})
+		if !errors.Is(err, ErrNoCommunityReports) {
+			t.Fatalf("Expected ErrNoCommunityReports before building, got %v", err)
+		}
+
+		built, err := db.BuildCommunityReports(ctx, CommunityReportOptions{})
+		if err != nil {
+			t.Fatalf("BuildCommunityReports failed: %v", err)
+		}
+		if built.Reports < 2 || built.Levels < 1 || built.Removed != 0 {
+			t.Fatalf("Unexpected build result %+v", built)
+		}
+
+		reports, err := db.CommunityReports(ctx, 0)
+		if err != nil {
+			t.Fatalf("CommunityReports failed: %v", err)
+		}
+		var groups []string
+		for _, r := range reports {
+			groups = append(groups, strings.Join(r.EntityNames, " "))
+			if r.Summary == "" || len(r.Findings) == 0 {
+				t.Errorf("Expected a summary and findings, got %+v", r)
+			}
+		}
+		if len(groups) != 2 {
+			t.Errorf("Expected two level 0 communities, got %v", groups)
+		}
+		for _, r := range reports {
+			names := strings.Join(r.EntityNames, " ")
+			if strings.Contains(names, "Alice") && strings.Contains(names, "Paris") {
+				t.Errorf("Expected staff and places in separate communities, got %v", groups)
+			}
+		}
+
+		result, err := db.SearchGraphRAG(ctx, "Who are Alice and Bob?", GraphRAGQueryOptions{RetrievalMode: RetrievalModeGlobal})
+		if err != nil {
+			t.Fatalf("SearchGraphRAG failed: %v", err)
+		}
+		if len(result.Communities) != 1 || len(result.Communities[0].Points) == 0 {
+			t.Fatalf("Expected points from the staff community only, got %+v", result.Communities)
+		}
+		if !strings.Contains(result.Context, "["+result.Communities[0].ID+"]") || !strings.Contains(result.Context, "works at") {
+			t.Errorf("Unexpected context:\n%s", result.Context)
+		}
+		if strings.Join(result.Entities, ",") != "Acme,Alice,Bob" {
+			t.Errorf("Expected the staff entities, got %v", result.Entities)
+		}
+
+		rebuilt, err := db.BuildCommunityReports(ctx, CommunityReportOptions{})
+		if err != nil {
+			t.Fatalf("BuildCommunityReports failed: %v", err)
+		}
+		if rebuilt.Removed != built.Reports || rebuilt.Reports != built.Reports {
+			t.Errorf("Expected the rebuild to replace %d reports, got %+v", built.Reports, rebuilt)
+		}
+	})
+
+	t.Run("LLMSummarizer", func(t *testing.T) {
+		db := openCommunityTestDB(t)
+		server := newChatServer(t, func(messages []chatMessage) string {
+			prompt := messages[len(messages)-1].Content
+			switch {
+			case strings.Contains(prompt, "Question: "):
+				if strings.Contains(prompt, "Acme staff") {
+					return `{"points": [{"text": "Alice and Bob both work at Acme", "score": "90"}]}`
+				}
+				return `{"points": []}`
+			case strings.Contains(prompt, "- Alice (thing)"):
+				return `{"title": "Acme staff", "summary": "People working at Acme.", "findings": ["Alice knows Bob"], "rating": 7}`
+			default:
+				return `{"title": "French cities", "summary": "Cities in France.", "rating": 3}`
+			}
+		})
+		s, err := NewLLMSummarizer(LLMSummarizerConfig{BaseURL: server.URL + "/v1", Model: "stub"})
+		if err != nil {
+			t.Fatalf("NewLLMSummarizer failed: %v", err)
+		}
+
+		if _, err := db.BuildCommunityReports(ctx, CommunityReportOptions{MaxLevels: 1, Summarizer: s}); err != nil {
+			t.Fatalf("BuildCommunityReports failed: %v", err)
+		}
+		reports, err := db.CommunityReports(ctx, -1)
+		if err != nil {
+			t.Fatalf("CommunityReports failed: %v", err)
+		}
+		if len(reports) != 2 || reports[0].Title != "Acme staff" || reports[0].Rating != 7 {
+			t.Fatalf("Expected the staff report rated first, got %+v", reports)
+		}
+
+		result, err := db.SearchGraphRAG(ctx, "Who works at Acme?", GraphRAGQueryOptions{RetrievalMode: RetrievalModeGlobal, CommunityMapper: s})
+		if err != nil {
+			t.Fatalf("SearchGraphRAG failed: %v", err)
+		}
+		if result.Context != "["+reports[0].ID+"] Alice and Bob both work at Acme" {
+			t.Errorf("Unexpected context %q", result.Context)
+		}
+		if len(result.Communities) != 1 || result.Communities[0].Score != 90 {
+			t.Errorf("Unexpected communities %+v", result.Communities)
+		}
+		if s.Requests() != 4 {
+			t.Errorf("Expected two reports and two map requests, got %d", s.Requests())
+		}
+	})
+}
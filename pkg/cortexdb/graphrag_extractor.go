@@ -0,0 +1,634 @@
+package cortexdb
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// DefaultEntityTypes are the entity types LLMExtractor asks for when none
+// are configured
+var DefaultEntityTypes = []string{"person", "organization", "location", "event", "product", "concept"}
+
+// DefaultExtractionPrompt is the user prompt template for the first pass.
+// The template sees .Text, the chunk, and .EntityTypes, a comma-separated list.
+const DefaultExtractionPrompt = `Extract the entities and the relationships between them from the text below.
+
+Entity types: {{.EntityTypes}}
+
+Return a JSON object of this form:
+{"entities": [{"name": "...", "type": "...", "description": "..."}],
+ "relationships": [{"source": "...", "target": "...", "type": "...", "description": "...", "weight": 1.0}]}
+
+Give each entity one of the entity types and a one-sentence description based on the text. Relationship sources and targets must be entity names from the list. Use a short snake_case verb phrase as the relationship type, e.g. "works_for", and a weight from 0 to 1 for how strongly the text supports it. Keep names in the language of the text. Return only the JSON.
+
+Text:
+{{.Text}}`
+
+// DefaultGleaningPrompt asks for what earlier passes missed
+const DefaultGleaningPrompt = `Some entities or relationships in the text may have been missed. Return only the missing ones in the same JSON format, or {"entities": [], "relationships": []} if there are none.`
+
+const defaultExtractionSystemPrompt = "You extract knowledge graphs from text and answer with JSON only."
+
+const extractionRepairPrompt = "That response was not valid JSON. Return the same content as a single valid JSON object and nothing else."
+
+// LLMExtractorConfig configures an LLMExtractor. Zero values take the
+// defaults noted on each field.
+type LLMExtractorConfig struct {
+	BaseURL     string  // Endpoint root (default: https://api.openai.com/v1)
+	APIKey      string  // Sent as a bearer token when set
+	Model       string  // Chat model, required
+	Temperature float64 // Sampling temperature (default: 0)
+	JSONMode    bool    // Request response_format json_object, for servers that support it
+
+	EntityTypes    []string // Types to extract (default: DefaultEntityTypes)
+	SystemPrompt   string   // System message
+	Prompt         string   // First-pass template (default: DefaultExtractionPrompt)
+	GleaningPrompt string   // Template for each further pass (default: DefaultGleaningPrompt)
+	MaxGleanings   int      // Further passes asking for missed items; stops early when one adds nothing
+
+	// Results are cached per chunk text and prompt settings. With CacheDB
+	// they are kept in its graphrag_extraction_cache table and shared by
+	// every process using the file; otherwise up to CacheSize results are
+	// kept in memory (default: 1024, -1 = no cache).
+	CacheDB   *sql.DB
+	CacheSize int
+
+	MaxRetries   int               // Retries on 429, 5xx and network errors (default: 3, -1 = none)
+	RetryBackoff time.Duration     // Delay before the first retry, doubled after each (default: 500ms)
+	MaxBackoff   time.Duration     // Upper bound for retry delays (default: 30s)
+	Timeout      time.Duration     // Per-request timeout (default: 120s)
+	Headers      map[string]string // Extra request headers
+	HTTPClient   *http.Client      // Client to use (default: a new client)
+}
+
+// LLMExtractorStats counts an LLMExtractor's work
+type LLMExtractorStats struct {
+	Requests  int64 `json:"requests"`   // Chat completions sent
+	CacheHits int64 `json:"cache_hits"` // Chunks answered from the cache
+	Repairs   int64 `json:"repairs"`    // Responses that needed repair to parse
+}
+
+// LLMExtractor is a GraphRAGExtractor that prompts an OpenAI-compatible
+// /chat/completions endpoint for entities and relationships as JSON.
+// Malformed JSON is repaired locally where possible, otherwise the model is
+// asked once to correct it.
+type LLMExtractor struct {
+	config   LLMExtractorConfig
+	chat     *chatClient
+	prompt   *template.Template
+	gleaning *template.Template
+	cacheKey string // Hash of the settings that affect results
+
+	mu    sync.Mutex
+	lru   *list.List
+	cache map[string]*list.Element
+
+	cacheHits atomic.Int64
+	repairs   atomic.Int64
+}
+
+type extractionCacheEntry struct {
+	key        string
+	extraction *GraphExtraction
+}
+
+const extractionCacheSchema = `
+	CREATE TABLE IF NOT EXISTS graphrag_extraction_cache (
+		cache_key TEXT PRIMARY KEY,
+		extraction TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+// NewLLMExtractor creates an extractor for an OpenAI-compatible chat API.
+func NewLLMExtractor(config LLMExtractorConfig) (*LLMExtractor, error) {
+	if config.Model == "" {
+		return nil, fmt.Errorf("cortexdb: extractor model is required")
+	}
+	if config.MaxGleanings < 0 {
+		return nil, fmt.Errorf("cortexdb: extractor gleanings must not be negative")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.openai.com/v1"
+	}
+	if len(config.EntityTypes) == 0 {
+		config.EntityTypes = DefaultEntityTypes
+	}
+	if config.SystemPrompt == "" {
+		config.SystemPrompt = defaultExtractionSystemPrompt
+	}
+	if config.Prompt == "" {
+		config.Prompt = DefaultExtractionPrompt
+	}
+	if config.GleaningPrompt == "" {
+		config.GleaningPrompt = DefaultGleaningPrompt
+	}
+	if config.CacheSize == 0 {
+		config.CacheSize = 1024
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 120 * time.Second
+	}
+
+	prompt, err := template.New("prompt").Parse(config.Prompt)
+	if err != nil {
+		return nil, fmt.Errorf("cortexdb: parse extraction prompt: %w", err)
+	}
+	gleaning, err := template.New("gleaning").Parse(config.GleaningPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("cortexdb: parse gleaning prompt: %w", err)
+	}
+
+	chat, err := newChatClient(HTTPEmbedderConfig{
+		BaseURL:      config.BaseURL,
+		APIKey:       config.APIKey,
+		Model:        config.Model,
+		MaxRetries:   config.MaxRetries,
+		RetryBackoff: config.RetryBackoff,
+		MaxBackoff:   config.MaxBackoff,
+		Timeout:      config.Timeout,
+		Headers:      config.Headers,
+		HTTPClient:   config.HTTPClient,
+	}, config.Temperature, config.JSONMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.CacheDB != nil {
+		if _, err := config.CacheDB.Exec(extractionCacheSchema); err != nil {
+			return nil, fmt.Errorf("create extraction cache: %w", err)
+		}
+	}
+
+	settings := strings.Join([]string{
+		config.Model,
+		strconv.FormatFloat(config.Temperature, 'g', -1, 64),
+		config.SystemPrompt,
+		config.Prompt,
+		config.GleaningPrompt,
+		strings.Join(config.EntityTypes, ","),
+		strconv.Itoa(config.MaxGleanings),
+	}, "\x00")
+	sum := sha256.Sum256([]byte(settings))
+
+	return &LLMExtractor{
+		config:   config,
+		chat:     chat,
+		prompt:   prompt,
+		gleaning: gleaning,
+		cacheKey: hex.EncodeToString(sum[:]),
+		lru:      list.New(),
+		cache:    make(map[string]*list.Element),
+	}, nil
+}
+
+// Stats reports requests, cache hits and repairs since the extractor was created.
+func (x *LLMExtractor) Stats() LLMExtractorStats {
+	return LLMExtractorStats{
+		Requests:  x.chat.requests.Load(),
+		CacheHits: x.cacheHits.Load(),
+		Repairs:   x.repairs.Load(),
+	}
+}
+
+// Extract returns the entities and relationships the model finds in text.
+// Relationship endpoints missing from the entity list are added as entities.
+func (x *LLMExtractor) Extract(ctx context.Context, text string) (*GraphExtraction, error) {
+	if strings.TrimSpace(text) == "" {
+		return &GraphExtraction{}, nil
+	}
+
+	key := x.textKey(text)
+	if extraction, ok := x.cached(ctx, key); ok {
+		x.cacheHits.Add(1)
+		return extraction, nil
+	}
+
+	vars := map[string]string{"Text": text, "EntityTypes": strings.Join(x.config.EntityTypes, ", ")}
+	prompt, err := renderTemplate(x.prompt, vars)
+	if err != nil {
+		return nil, err
+	}
+	messages := []chatMessage{
+		{Role: "system", Content: x.config.SystemPrompt},
+		{Role: "user", Content: prompt},
+	}
+
+	extraction, messages, err := x.pass(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	if x.config.MaxGleanings > 0 {
+		gleaning, err := renderTemplate(x.gleaning, vars)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < x.config.MaxGleanings; i++ {
+			var more *GraphExtraction
+			more, messages, err = x.pass(ctx, append(messages, chatMessage{Role: "user", Content: gleaning}))
+			if err != nil {
+				return nil, err
+			}
+			if mergeGraphExtraction(extraction, more) == 0 {
+				break
+			}
+		}
+	}
+
+	normalizeGraphExtraction(extraction)
+	x.store(ctx, key, extraction)
+	return extraction, nil
+}
+
+// pass sends one turn and parses the reply, asking the model to fix it once
+// if it can't be repaired. It returns the conversation with the reply appended.
+func (x *LLMExtractor) pass(ctx context.Context, messages []chatMessage) (*GraphExtraction, []chatMessage, error) {
+	reply, err := x.chat.complete(ctx, messages)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages = append(messages, chatMessage{Role: "assistant", Content: reply})
+
+	extraction, repaired, err := parseGraphExtraction(reply)
+	if err != nil {
+		reply, err = x.chat.complete(ctx, append(messages, chatMessage{Role: "user", Content: extractionRepairPrompt}))
+		if err != nil {
+			return nil, nil, err
+		}
+		messages[len(messages)-1].Content = reply
+		if extraction, _, err = parseGraphExtraction(reply); err != nil {
+			return nil, nil, fmt.Errorf("cortexdb: extractor returned invalid JSON: %w", err)
+		}
+		repaired = true
+	}
+	if repaired {
+		x.repairs.Add(1)
+	}
+	return extraction, messages, nil
+}
+
+// textKey keys a chunk's cached result under the current settings
+func (x *LLMExtractor) textKey(text string) string {
+	sum := sha256.Sum256([]byte(x.cacheKey + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+func (x *LLMExtractor) cached(ctx context.Context, key string) (*GraphExtraction, bool) {
+	if x.config.CacheDB != nil {
+		var data string
+		err := x.config.CacheDB.QueryRowContext(ctx,
+			"SELECT extraction FROM graphrag_extraction_cache WHERE cache_key = ?", key).Scan(&data)
+		if err != nil {
+			return nil, false
+		}
+		var extraction GraphExtraction
+		if json.Unmarshal([]byte(data), &extraction) != nil {
+			return nil, false
+		}
+		return &extraction, true
+	}
+	if x.config.CacheSize < 0 {
+		return nil, false
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	elem, ok := x.cache[key]
+	if !ok {
+		return nil, false
+	}
+	x.lru.MoveToFront(elem)
+	return cloneGraphExtraction(elem.Value.(*extractionCacheEntry).extraction), true
+}
+
+// store caches a result; a failed write only costs a later request
+func (x *LLMExtractor) store(ctx context.Context, key string, extraction *GraphExtraction) {
+	if x.config.CacheDB != nil {
+		if data, err := json.Marshal(extraction); err == nil {
+			_, _ = x.config.CacheDB.ExecContext(ctx,
+				"INSERT OR REPLACE INTO graphrag_extraction_cache (cache_key, extraction) VALUES (?, ?)", key, string(data))
+		}
+		return
+	}
+	if x.config.CacheSize < 0 {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if elem, ok := x.cache[key]; ok {
+		x.lru.MoveToFront(elem)
+		return
+	}
+	x.cache[key] = x.lru.PushFront(&extractionCacheEntry{key: key, extraction: cloneGraphExtraction(extraction)})
+	for x.lru.Len() > x.config.CacheSize {
+		oldest := x.lru.Back()
+		x.lru.Remove(oldest)
+		delete(x.cache, oldest.Value.(*extractionCacheEntry).key)
+	}
+}
+
+func cloneGraphExtraction(e *GraphExtraction) *GraphExtraction {
+	return &GraphExtraction{
+		Entities:      append([]GraphEntity(nil), e.Entities...),
+		Relationships: append([]GraphRelationship(nil), e.Relationships...),
+	}
+}
+
+// llmExtraction is the reply format, accepting the key spellings models
+// commonly use instead of the requested ones
+type llmExtraction struct {
+	Entities []struct {
+		Name        string `json:"name"`
+		Entity      string `json:"entity"`
+		Type        string `json:"type"`
+		Description string `json:"description"`
+	} `json:"entities"`
+	Relationships []struct {
+		Source       string    `json:"source"`
+		Target       string    `json:"target"`
+		From         string    `json:"from"`
+		To           string    `json:"to"`
+		Type         string    `json:"type"`
+		Relationship string    `json:"relationship"`
+		Description  string    `json:"description"`
+		Weight       flexFloat `json:"weight"`
+	} `json:"relationships"`
+}
+
+// flexFloat accepts a number or a numeric string
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil // An unreadable weight takes the default
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+// parseGraphExtraction decodes a reply, repairing common JSON mistakes. It
+// reports whether repair was needed.
+func parseGraphExtraction(reply string) (*GraphExtraction, bool, error) {
+	text := jsonPayload(reply)
+	var raw llmExtraction
+	repaired := false
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		// Output cut off mid-item: drop items from the end until the rest parses
+		for cut := text; ; {
+			raw = llmExtraction{}
+			if err = json.Unmarshal([]byte(repairJSON(cut)), &raw); err == nil {
+				break
+			}
+			i := strings.LastIndexByte(cut, ',')
+			if i <= 0 {
+				return nil, false, err
+			}
+			cut = cut[:i]
+		}
+		repaired = true
+	}
+
+	extraction := &GraphExtraction{}
+	for _, e := range raw.Entities {
+		extraction.Entities = append(extraction.Entities, GraphEntity{
+			Name:        strings.TrimSpace(firstNonEmpty(e.Name, e.Entity)),
+			Type:        e.Type,
+			Description: strings.TrimSpace(e.Description),
+		})
+	}
+	for _, r := range raw.Relationships {
+		extraction.Relationships = append(extraction.Relationships, GraphRelationship{
+			From:        strings.TrimSpace(firstNonEmpty(r.Source, r.From)),
+			To:          strings.TrimSpace(firstNonEmpty(r.Target, r.To)),
+			Type:        firstNonEmpty(r.Type, r.Relationship),
+			Weight:      float64(r.Weight),
+			Description: strings.TrimSpace(r.Description),
+		})
+	}
+	return extraction, repaired, nil
+}
+
+// jsonPayload strips code fences and any prose around the JSON object
+func jsonPayload(reply string) string {
+	text := strings.TrimSpace(reply)
+	if i := strings.Index(text, "```"); i >= 0 {
+		text = text[i+3:]
+		if nl := strings.IndexByte(text, '\n'); nl >= 0 && !strings.ContainsAny(text[:nl], "{[") {
+			text = text[nl+1:]
+		}
+		if j := strings.Index(text, "```"); j >= 0 {
+			text = text[:j]
+		}
+	}
+	if i := strings.IndexByte(text, '{'); i >= 0 {
+		text = text[i:]
+	}
+	return strings.TrimSpace(text)
+}
+
+// repairJSON fixes trailing commas, single-quoted strings, unquoted keys,
+// text after the top-level value and output cut off mid-value, by closing
+// open strings, arrays and objects
+func repairJSON(text string) string {
+	var b strings.Builder
+	var stack []byte
+	inString, escaped := false, false
+	quote := byte('"')
+
+	// dropTrailingComma removes a comma written just before a closer
+	dropTrailingComma := func() {
+		s := strings.TrimRight(b.String(), " \t\r\n")
+		if strings.HasSuffix(s, ",") {
+			s = s[:len(s)-1]
+			b.Reset()
+			b.WriteString(s)
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				inString = false
+				b.WriteByte('"')
+				continue
+			case c == '"':
+				b.WriteString(`\"`) // A double quote inside a single-quoted string
+				continue
+			case c == '\n':
+				b.WriteString(`\n`)
+				continue
+			}
+			b.WriteByte(c)
+			continue
+		}
+
+		switch c {
+		case '"', '\'':
+			inString, quote = true, c
+			b.WriteByte('"')
+		case '{', '[':
+			stack = append(stack, c)
+			b.WriteByte(c)
+		case '}', ']':
+			dropTrailingComma()
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			b.WriteByte(c)
+			if len(stack) == 0 {
+				return b.String()
+			}
+		default:
+			if isJSONKeyStart(c) && len(stack) > 0 && stack[len(stack)-1] == '{' && expectsKey(b.String()) {
+				j := i
+				for j < len(text) && isJSONKeyChar(text[j]) {
+					j++
+				}
+				b.WriteString(strconv.Quote(text[i:j]))
+				i = j - 1
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+
+	if inString {
+		b.WriteByte('"')
+	}
+	s := strings.TrimRight(b.String(), " \t\r\n")
+	// A cut-off key or key-value separator can't be completed; drop it
+	if strings.HasSuffix(s, ":") {
+		if k := strings.LastIndexAny(s, "{,"); k >= 0 {
+			s = s[:k+1]
+		}
+	}
+	s = strings.TrimSuffix(s, ",")
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			s += "}"
+		} else {
+			s += "]"
+		}
+	}
+	return s
+}
+
+// expectsKey reports whether the output so far ends where an object key starts
+func expectsKey(written string) bool {
+	s := strings.TrimRight(written, " \t\r\n")
+	return strings.HasSuffix(s, "{") || strings.HasSuffix(s, ",")
+}
+
+func isJSONKeyStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isJSONKeyChar(c byte) bool {
+	return isJSONKeyStart(c) || (c >= '0' && c <= '9')
+}
+
+// mergeGraphExtraction adds src's new entities and relationships to dst,
+// filling in missing types and descriptions, and returns how many were new
+func mergeGraphExtraction(dst, src *GraphExtraction) int {
+	entities := make(map[string]int, len(dst.Entities))
+	for i, e := range dst.Entities {
+		entities[graphEntityNodeID(e.Name)] = i
+	}
+	relationships := make(map[string]struct{}, len(dst.Relationships))
+	for _, r := range dst.Relationships {
+		relationships[graphRelationshipKey(r)] = struct{}{}
+	}
+
+	added := 0
+	for _, e := range src.Entities {
+		if e.Name == "" {
+			continue
+		}
+		id := graphEntityNodeID(e.Name)
+		if i, ok := entities[id]; ok {
+			dst.Entities[i].Type = firstNonEmpty(dst.Entities[i].Type, e.Type)
+			dst.Entities[i].Description = firstNonEmpty(dst.Entities[i].Description, e.Description)
+			continue
+		}
+		entities[id] = len(dst.Entities)
+		dst.Entities = append(dst.Entities, e)
+		added++
+	}
+	for _, r := range src.Relationships {
+		key := graphRelationshipKey(r)
+		if _, ok := relationships[key]; ok || r.From == "" || r.To == "" {
+			continue
+		}
+		relationships[key] = struct{}{}
+		dst.Relationships = append(dst.Relationships, r)
+		added++
+	}
+	return added
+}
+
+func graphRelationshipKey(r GraphRelationship) string {
+	return graphEntityNodeID(r.From) + "|" + graphEntityNodeID(r.To) + "|" + graphRelationType(r.Type)
+}
+
+// normalizeGraphExtraction merges duplicate entities, snake-cases types,
+// clamps weights and adds entities for relationship endpoints
+func normalizeGraphExtraction(e *GraphExtraction) {
+	merged := &GraphExtraction{}
+	mergeGraphExtraction(merged, &GraphExtraction{Entities: e.Entities})
+
+	known := make(map[string]struct{}, len(merged.Entities))
+	for i := range merged.Entities {
+		merged.Entities[i].Type = graphRelationType(merged.Entities[i].Type)
+		known[graphEntityNodeID(merged.Entities[i].Name)] = struct{}{}
+	}
+	for _, r := range e.Relationships {
+		if r.From == "" || r.To == "" {
+			continue
+		}
+		r.Type = graphRelationType(r.Type)
+		if r.Weight < 0 {
+			r.Weight = 0
+		} else if r.Weight > 1 {
+			r.Weight = 1
+		}
+		for _, name := range []string{r.From, r.To} {
+			if _, ok := known[graphEntityNodeID(name)]; !ok {
+				known[graphEntityNodeID(name)] = struct{}{}
+				merged.Entities = append(merged.Entities, GraphEntity{Name: name})
+			}
+		}
+		mergeGraphExtraction(merged, &GraphExtraction{Relationships: []GraphRelationship{r}})
+	}
+	*e = *merged
+}
+
+// graphRelationType lower-cases a type and joins its words with underscores
+func graphRelationType(t string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(t), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '/'
+	}), "_")
+}
@@ -0,0 +1,214 @@
+package cortexdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+)
+
+// chatServer stands in for a chat completions API; reply picks the answer
+// from the conversation so far
+type chatServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	calls [][]chatMessage
+}
+
+func newChatServer(t *testing.T, reply func(messages []chatMessage) string) *chatServer {
+	s := &chatServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Model    string        `json:"model"`
+			Messages []chatMessage `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.calls = append(s.calls, req.Messages)
+		s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": reply(req.Messages)}}},
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *chatServer) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+func TestLLMExtractor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("RepairAndNormalize", func(t *testing.T) {
+		server := newChatServer(t, func([]chatMessage) string {
+			return "Here you go:\n```json\n{entities: [{'name': 'Alice', 'type': 'Person'}, {\"name\": \"acme corp\", \"type\": \"organization\",},],\n" +
+				`"relationships": [{"source": "Alice", "target": "acme corp", "type": "Works For", "weight": "0.8"}, {"source": "Alice", "target": "Bob", "type": "knows"}]}` + "\n```"
+		})
+		x, err := NewLLMExtractor(LLMExtractorConfig{BaseURL: server.URL + "/v1", Model: "stub", EntityTypes: []string{"person", "organization"}})
+		if err != nil {
+			t.Fatalf("NewLLMExtractor failed: %v", err)
+		}
+
+		got, err := x.Extract(ctx, "Alice works for Acme Corp and knows Bob.")
+		if err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		names := extractEntityNames(got.Entities)
+		if strings.Join(names, ",") != "Alice,acme corp,Bob" {
+			t.Errorf("Unexpected entities %v", names)
+		}
+		if got.Entities[0].Type != "person" {
+			t.Errorf("Expected lower-cased type, got %q", got.Entities[0].Type)
+		}
+		if len(got.Relationships) != 2 || got.Relationships[0].Type != "works_for" || got.Relationships[0].Weight != 0.8 {
+			t.Errorf("Unexpected relationships %+v", got.Relationships)
+		}
+		if stats := x.Stats(); stats.Repairs != 1 || stats.Requests != 1 {
+			t.Errorf("Expected one repaired request, got %+v", stats)
+		}
+
+		prompt := server.calls[0][1].Content
+		if !strings.Contains(prompt, "person, organization") || !strings.Contains(prompt, "knows Bob.") {
+			t.Errorf("Prompt missing entity types or text:\n%s", prompt)
+		}
+
+		if _, err := x.Extract(ctx, "Alice works for Acme Corp and knows Bob."); err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		if server.callCount() != 1 || x.Stats().CacheHits != 1 {
+			t.Errorf("Expected the second extraction from the cache, got %d calls, %+v", server.callCount(), x.Stats())
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		got, repaired, err := parseGraphExtraction(`{"entities": [{"name": "Alice"}, {"name": "Bob", "ty`)
+		if err != nil || !repaired {
+			t.Fatalf("Expected a repaired parse, got %v, %v", repaired, err)
+		}
+		if names := extractEntityNames(got.Entities); strings.Join(names, ",") != "Alice,Bob" {
+			t.Errorf("Unexpected entities %v", names)
+		}
+	})
+
+	t.Run("AskForRepair", func(t *testing.T) {
+		server := newChatServer(t, func(messages []chatMessage) string {
+			if messages[len(messages)-1].Content == extractionRepairPrompt {
+				return `{"entities": [{"name": "Alice", "type": "person"}], "relationships": []}`
+			}
+			return "Sorry, I cannot find any entities"
+		})
+		x, err := NewLLMExtractor(LLMExtractorConfig{BaseURL: server.URL + "/v1", Model: "stub"})
+		if err != nil {
+			t.Fatalf("NewLLMExtractor failed: %v", err)
+		}
+		got, err := x.Extract(ctx, "Alice.")
+		if err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		if len(got.Entities) != 1 || server.callCount() != 2 {
+			t.Errorf("Expected one entity after a repair request, got %+v in %d calls", got.Entities, server.callCount())
+		}
+	})
+
+	t.Run("Gleaning", func(t *testing.T) {
+		server := newChatServer(t, func(messages []chatMessage) string {
+			switch len(messages) {
+			case 2:
+				return `{"entities": [{"name": "Alice", "type": "person"}]}`
+			case 4:
+				return `{"entities": [{"name": "Acme", "type": "organization"}], "relationships": [{"source": "Alice", "target": "Acme", "type": "works_at"}]}`
+			default:
+				return `{"entities": [{"name": "alice"}], "relationships": []}`
+			}
+		})
+		x, err := NewLLMExtractor(LLMExtractorConfig{BaseURL: server.URL + "/v1", Model: "stub", MaxGleanings: 5})
+		if err != nil {
+			t.Fatalf("NewLLMExtractor failed: %v", err)
+		}
+		got, err := x.Extract(ctx, "Alice works at Acme.")
+		if err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		if len(got.Entities) != 2 || len(got.Relationships) != 1 {
+			t.Errorf("Expected gleaned entities and relationship, got %+v", got)
+		}
+		if server.callCount() != 3 {
+			t.Errorf("Expected gleaning to stop after a pass with nothing new, got %d calls", server.callCount())
+		}
+		if last := server.calls[2]; last[3].Content != DefaultGleaningPrompt || last[2].Role != "assistant" {
+			t.Errorf("Expected the gleaning prompt after the first answer, got %+v", last)
+		}
+	})
+
+	t.Run("IngestWithSharedCache", func(t *testing.T) {
+		server := newChatServer(t, func([]chatMessage) string {
+			return `{"entities": [{"name": "Alice", "type": "person", "description": "An engineer"}, {"name": "Acme", "type": "organization"}],
+				"relationships": [{"source": "Alice", "target": "Acme", "type": "works_at", "description": "Alice is employed by Acme"}]}`
+		})
+
+		db, err := Open(DefaultConfig(filepath.Join(t.TempDir(), "extract.db")), WithEmbedder(newKeywordEmbedder("alice", "acme", "works")))
+		if err != nil {
+			t.Fatalf("open db: %v", err)
+		}
+		defer db.Close()
+		sqlDB := db.Vector().(*core.SQLiteStore).GetDB()
+
+		newExtractor := func() *LLMExtractor {
+			x, err := NewLLMExtractor(LLMExtractorConfig{BaseURL: server.URL + "/v1", Model: "stub", CacheDB: sqlDB})
+			if err != nil {
+				t.Fatalf("NewLLMExtractor failed: %v", err)
+			}
+			return x
+		}
+
+		doc := GraphRAGDocument{ID: "d", Content: "Alice works at Acme."}
+		if _, err := db.InsertGraphDocument(ctx, doc, GraphRAGIngestOptions{Extractor: newExtractor()}); err != nil {
+			t.Fatalf("InsertGraphDocument failed: %v", err)
+		}
+		node, err := db.Graph().GetNode(ctx, graphEntityNodeID("Alice"))
+		if err != nil {
+			t.Fatalf("GetNode failed: %v", err)
+		}
+		if node.Properties["description"] != "An engineer" {
+			t.Errorf("Expected the entity description stored, got %v", node.Properties)
+		}
+		edges, err := db.Graph().GetEdges(ctx, node.ID, "out")
+		if err != nil {
+			t.Fatalf("GetEdges failed: %v", err)
+		}
+		found := false
+		for _, edge := range edges {
+			if edge.EdgeType == "works_at" && edge.ToNodeID == graphEntityNodeID("Acme") {
+				found = edge.Properties["description"] == "Alice is employed by Acme"
+			}
+		}
+		if !found {
+			t.Errorf("Expected a works_at edge with its description, got %+v", edges)
+		}
+
+		fresh := newExtractor()
+		if _, err := fresh.Extract(ctx, "Alice works at Acme."); err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		if server.callCount() != 1 || fresh.Stats().CacheHits != 1 {
+			t.Errorf("Expected a new extractor to reuse the stored result, got %d calls", server.callCount())
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package cortexdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/chunking"
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+)
+
+// ChunkChanges counts how an ingestion changed a document's chunks.
+type ChunkChanges struct {
+	Added     int `json:"added"`     // Chunks embedded and extracted
+	Unchanged int `json:"unchanged"` // Chunks kept with their IDs, vectors and graph edges
+	Removed   int `json:"removed"`   // Chunks deleted with their graph nodes
+}
+
+// graphRAGChunkPlan maps a document's new chunks onto its stored ones
+type graphRAGChunkPlan struct {
+	ids     []string          // Chunk ID per new chunk
+	hashes  []string          // Content hash per new chunk
+	reused  []*core.Embedding // Stored chunk kept for each new chunk, nil if added
+	removed []*core.Embedding // Stored chunks no new chunk kept
+	stale   int               // Number of stored chunks, bounding the positional edge IDs to delete
+}
+
+func (p *graphRAGChunkPlan) changes() ChunkChanges {
+	c := ChunkChanges{Removed: len(p.removed)}
+	for _, r := range p.reused {
+		if r == nil {
+			c.Added++
+		} else {
+			c.Unchanged++
+		}
+	}
+	return c
+}
+
+// chunkContentHash identifies a chunk's text
+func chunkContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// storedGraphRAGChunks returns the document's stored chunks in chunk order
+func (db *DB) storedGraphRAGChunks(ctx context.Context, documentID string) ([]*core.Embedding, error) {
+	embs, err := db.store.GetByDocID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("get document chunks: %w", err)
+	}
+	chunks := embs[:0]
+	for _, emb := range embs {
+		if emb.Metadata["graph_kind"] == "chunk" {
+			chunks = append(chunks, emb)
+		}
+	}
+	sort.SliceStable(chunks, func(i, j int) bool {
+		a, _ := strconv.Atoi(chunks[i].Metadata["chunk_index"])
+		b, _ := strconv.Atoi(chunks[j].Metadata["chunk_index"])
+		return a < b
+	})
+	return chunks, nil
+}
+
+// planGraphRAGChunks keeps every stored chunk whose text, collection and
+// embedding model still match a new chunk, so only the rest are embedded
+// and extracted again. New chunks take their positional ID unless a kept
+// chunk already holds it.
+func planGraphRAGChunks(documentID, collection, modelID string, dim int, chunks []chunking.Chunk, stored []*core.Embedding) *graphRAGChunkPlan {
+	plan := &graphRAGChunkPlan{
+		ids:    make([]string, len(chunks)),
+		hashes: make([]string, len(chunks)),
+		reused: make([]*core.Embedding, len(chunks)),
+		stale:  len(stored),
+	}
+
+	// Only chunks written by InsertGraphDocument record a hash; lexical
+	// chunks from the no-embedder tools never match
+	available := make(map[string][]*core.Embedding)
+	for _, emb := range stored {
+		h := emb.Metadata["chunk_hash"]
+		if h == "" || emb.Collection != collection || emb.ModelID != modelID || len(emb.Vector) != dim {
+			continue
+		}
+		available[h] = append(available[h], emb)
+	}
+
+	taken := make(map[string]bool, len(chunks))
+	kept := make(map[*core.Embedding]bool, len(chunks))
+	for i, chunk := range chunks {
+		plan.hashes[i] = chunkContentHash(chunk.Text)
+		if candidates := available[plan.hashes[i]]; len(candidates) > 0 {
+			plan.reused[i] = candidates[0]
+			available[plan.hashes[i]] = candidates[1:]
+			plan.ids[i] = candidates[0].ID
+			taken[candidates[0].ID] = true
+			kept[candidates[0]] = true
+		}
+	}
+
+	next := len(chunks)
+	for i := range chunks {
+		if plan.reused[i] != nil {
+			continue
+		}
+		id := graphChunkNodeID(documentID, i)
+		for taken[id] {
+			id = graphChunkNodeID(documentID, next)
+			next++
+		}
+		plan.ids[i] = id
+		taken[id] = true
+	}
+
+	for _, emb := range stored {
+		if !kept[emb] {
+			plan.removed = append(plan.removed, emb)
+		}
+	}
+	return plan
+}
+
+// chunkEntities returns the entities the chunks mention. For removed
+// chunks they are candidates for deletion once the chunks are gone.
+func (db *DB) chunkEntities(ctx context.Context, chunks []*core.Embedding) ([]string, error) {
+	var entityIDs []string
+	for _, emb := range chunks {
+		edges, err := db.graph.GetEdges(ctx, emb.ID, "out")
+		if err != nil {
+			return nil, fmt.Errorf("get chunk edges: %w", err)
+		}
+		for _, edge := range edges {
+			if edge.EdgeType == "mentions" {
+				entityIDs = append(entityIDs, edge.ToNodeID)
+			}
+		}
+	}
+	return entityIDs, nil
+}
+
+// entityCandidates keeps the unique entity node IDs
+func entityCandidates(nodeIDs []string) []string {
+	seen := make(map[string]struct{}, len(nodeIDs))
+	var out []string
+	for _, id := range nodeIDs {
+		if _, ok := seen[id]; ok || !strings.HasPrefix(id, "entity:") {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
+
+// deleteRemovedGraphRAGChunks deletes the removed chunks' embeddings and
+// graph nodes, the relationships extracted from them, and the positional
+// structure edges past the new chunk count. It returns the nodes the
+// deleted relationships connected.
+func deleteRemovedGraphRAGChunks(ctx context.Context, tx *Tx, documentID string, plan *graphRAGChunkPlan) ([]string, error) {
+	removedIDs := make([]string, 0, len(plan.removed))
+	for _, emb := range plan.removed {
+		removedIDs = append(removedIDs, emb.ID)
+		if err := tx.Vector().Delete(ctx, emb.ID); err != nil {
+			return nil, fmt.Errorf("delete removed chunk: %w", err)
+		}
+	}
+	if _, err := tx.Graph().DeleteNodesBatch(ctx, removedIDs); err != nil {
+		return nil, fmt.Errorf("delete removed chunk nodes: %w", err)
+	}
+	related, err := tx.Graph().DeleteEdgesByProperty(ctx, "source_chunk_id", removedIDs)
+	if err != nil {
+		return nil, fmt.Errorf("delete removed chunk relationships: %w", err)
+	}
+
+	var staleEdges []string
+	for i := len(plan.ids); i < plan.stale; i++ {
+		staleEdges = append(staleEdges,
+			fmt.Sprintf("edge:doc_chunk:%s:%d", documentID, i),
+			fmt.Sprintf("edge:chunk_next:%s:%d", documentID, i))
+	}
+	if _, err := tx.Graph().DeleteEdgesBatch(ctx, staleEdges); err != nil {
+		return nil, fmt.Errorf("delete stale chunk edges: %w", err)
+	}
+	return related, nil
+}
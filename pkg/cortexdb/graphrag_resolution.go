@@ -0,0 +1,812 @@
+package cortexdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+	"github.com/liliang-cn/cortexdb/v2/pkg/graph"
+)
+
+var (
+	// ErrEntityMergeNotFound is returned for an unknown or already undone merge
+	ErrEntityMergeNotFound = errors.New("cortexdb: entity merge not found")
+	// ErrEntityMergeConflict is returned when undoing a merge that a later
+	// merge built on; undo the later one first
+	ErrEntityMergeConflict = errors.New("cortexdb: a later merge involves these entities")
+)
+
+const entityResolutionSchema = `
+	CREATE TABLE IF NOT EXISTS graph_entity_aliases (
+		alias_id TEXT PRIMARY KEY,
+		canonical_id TEXT NOT NULL,
+		alias TEXT NOT NULL,
+		merge_id INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_graph_entity_aliases_canonical ON graph_entity_aliases(canonical_id);
+
+	CREATE TABLE IF NOT EXISTS graph_entity_merges (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		canonical_id TEXT NOT NULL,
+		merged_id TEXT NOT NULL,
+		merged_name TEXT NOT NULL,
+		score REAL NOT NULL,
+		auto INTEGER NOT NULL,
+		snapshot TEXT NOT NULL, -- JSON mergeSnapshot
+		created_at DATETIME NOT NULL,
+		undone_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS graph_entity_rejections (
+		a TEXT NOT NULL,
+		b TEXT NOT NULL,
+		PRIMARY KEY (a, b)
+	);
+`
+
+// EntityResolutionOptions controls how duplicate entities are found and,
+// in ResolveEntities, merged. A candidate's score is the weighted mean of
+// its name, embedding and neighbour similarity, each from 0 to 1.
+type EntityResolutionOptions struct {
+	MinScore        float64 // Candidates scoring below this are not proposed (default: 0.6)
+	AutoMergeScore  float64 // ResolveEntities merges candidates scoring at least this (default: 0.9)
+	NameWeight      float64 // Weight of name similarity (default: 0.5)
+	EmbeddingWeight float64 // Weight of name and description embedding similarity (default: 0.3)
+	NeighborWeight  float64 // Weight of shared related entities (default: 0.2)
+	MaxCandidates   int     // Candidates returned at most (default: 100)
+
+	// AllowTypeMismatch proposes entities of different types, e.g. a
+	// person and an organization with the same name. Untyped entities
+	// always match.
+	AllowTypeMismatch bool
+}
+
+// EntityMergeCandidate is a proposed merge of Duplicate into Canonical.
+// The canonical side is the better connected entity.
+type EntityMergeCandidate struct {
+	CanonicalID     string  `json:"canonical_id"`
+	CanonicalName   string  `json:"canonical_name"`
+	DuplicateID     string  `json:"duplicate_id"`
+	DuplicateName   string  `json:"duplicate_name"`
+	Score           float64 `json:"score"`
+	NameScore       float64 `json:"name_score"`
+	EmbeddingScore  float64 `json:"embedding_score"`
+	NeighborScore   float64 `json:"neighbor_score"`
+	SharedNeighbors int     `json:"shared_neighbors"`
+}
+
+// EntityMerge is an entry of the merge log
+type EntityMerge struct {
+	ID          int64     `json:"id"`
+	CanonicalID string    `json:"canonical_id"`
+	MergedID    string    `json:"merged_id"`
+	MergedName  string    `json:"merged_name"`
+	Score       float64   `json:"score"` // Candidate score, 0 for merges requested directly
+	Auto        bool      `json:"auto"`  // Made by ResolveEntities
+	CreatedAt   time.Time `json:"created_at"`
+	Undone      bool      `json:"undone"`
+}
+
+// EntityResolutionResult is the outcome of ResolveEntities
+type EntityResolutionResult struct {
+	Merges []EntityMerge `json:"merges"`
+	// Candidates left for review: scoring below AutoMergeScore
+	Candidates []EntityMergeCandidate `json:"candidates"`
+}
+
+// mergeSnapshot holds what a merge changed so it can be undone
+type mergeSnapshot struct {
+	Canonical *graph.GraphNode   `json:"canonical"`
+	Merged    *graph.GraphNode   `json:"merged"`
+	Edges     []*graph.GraphEdge `json:"edges"`     // Edges of both entities before the merge
+	Created   []string           `json:"created"`   // IDs of rewired edges
+	Repointed []string           `json:"repointed"` // Aliases of the merged entity moved to the canonical one
+}
+
+func initEntityResolutionSchema(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, entityResolutionSchema); err != nil {
+		return fmt.Errorf("create entity resolution tables: %w", err)
+	}
+	return nil
+}
+
+// ProposeEntityMerges finds entities that are likely the same, best first,
+// for review. Pairs are compared when their names share a word or a prefix.
+// Rejected pairs are not proposed again.
+func (db *DB) ProposeEntityMerges(ctx context.Context, opts EntityResolutionOptions) ([]EntityMergeCandidate, error) {
+	applyEntityResolutionDefaults(&opts)
+
+	nodes, err := db.graph.GetAllNodes(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("load entities: %w", err)
+	}
+	var entities []*graph.GraphNode
+	for _, node := range nodes {
+		if strings.HasPrefix(node.ID, "entity:") {
+			entities = append(entities, node)
+		}
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].ID < entities[j].ID })
+
+	rejected, err := db.rejectedEntityPairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Block on name words and prefixes so only plausible pairs are scored
+	blocks := make(map[string][]int)
+	keys := make([]string, len(entities))
+	for i, e := range entities {
+		key := entityNameKey(e.Content)
+		keys[i] = key
+		seen := make(map[string]bool)
+		for _, word := range strings.Fields(key) {
+			seen["w:"+word] = true
+		}
+		if compact := strings.ReplaceAll(key, " ", ""); len([]rune(compact)) >= 4 {
+			seen["p:"+string([]rune(compact)[:4])] = true
+		}
+		for b := range seen {
+			blocks[b] = append(blocks[b], i)
+		}
+	}
+	pairs := make(map[[2]int]bool)
+	for _, members := range blocks {
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				pairs[[2]int{members[x], members[y]}] = true
+			}
+		}
+	}
+
+	type scoredPair struct {
+		a, b            int
+		name            float64
+		shared          int
+		neighbor        float64
+		hasNeighborInfo bool
+	}
+	var scored []scoredPair
+	neighbors := make(map[int]map[string]bool)
+	loadNeighbors := func(i int) (map[string]bool, error) {
+		if set, ok := neighbors[i]; ok {
+			return set, nil
+		}
+		edges, err := db.graph.GetEdges(ctx, entities[i].ID, "both")
+		if err != nil {
+			return nil, fmt.Errorf("load entity edges: %w", err)
+		}
+		set := make(map[string]bool, len(edges))
+		for _, edge := range edges {
+			other := edge.FromNodeID
+			if other == entities[i].ID {
+				other = edge.ToNodeID
+			}
+			// Chunks rarely mention both spellings, so only related entities count
+			if strings.HasPrefix(other, "entity:") {
+				set[other] = true
+			}
+		}
+		neighbors[i] = set
+		return set, nil
+	}
+
+	for pair := range pairs {
+		a, b := entities[pair[0]], entities[pair[1]]
+		if rejected[entityPairKey(a.ID, b.ID)] {
+			continue
+		}
+		if !opts.AllowTypeMismatch && !entityTypesMatch(a.NodeType, b.NodeType) {
+			continue
+		}
+		name := entityNameSimilarity(keys[pair[0]], keys[pair[1]])
+		if name == 0 {
+			continue
+		}
+		na, err := loadNeighbors(pair[0])
+		if err != nil {
+			return nil, err
+		}
+		nb, err := loadNeighbors(pair[1])
+		if err != nil {
+			return nil, err
+		}
+		shared, union := 0, 0
+		for id := range na {
+			if id == b.ID {
+				continue
+			}
+			union++
+			if nb[id] {
+				shared++
+			}
+		}
+		for id := range nb {
+			if id != a.ID && !na[id] {
+				union++
+			}
+		}
+		p := scoredPair{a: pair[0], b: pair[1], name: name, shared: shared, hasNeighborInfo: union > 0}
+		if union > 0 {
+			p.neighbor = float64(shared) / float64(union)
+		}
+		scored = append(scored, p)
+	}
+
+	vectors, err := db.entityDescriptionVectors(ctx, entities, func(yield func(int)) {
+		for _, p := range scored {
+			yield(p.a)
+			yield(p.b)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []EntityMergeCandidate
+	for _, p := range scored {
+		total, weight := opts.NameWeight*p.name, opts.NameWeight
+		embedding := 0.0
+		if va, vb := vectors[p.a], vectors[p.b]; len(va) > 0 && len(va) == len(vb) {
+			embedding = math.Max(0, core.CosineSimilarity(va, vb))
+			total += opts.EmbeddingWeight * embedding
+			weight += opts.EmbeddingWeight
+		}
+		if p.hasNeighborInfo {
+			total += opts.NeighborWeight * p.neighbor
+			weight += opts.NeighborWeight
+		}
+		score := total / weight
+		if score < opts.MinScore {
+			continue
+		}
+
+		canonical, duplicate := p.a, p.b
+		if len(neighbors[p.b]) > len(neighbors[p.a]) {
+			canonical, duplicate = p.b, p.a
+		}
+		candidates = append(candidates, EntityMergeCandidate{
+			CanonicalID:     entities[canonical].ID,
+			CanonicalName:   entities[canonical].Content,
+			DuplicateID:     entities[duplicate].ID,
+			DuplicateName:   entities[duplicate].Content,
+			Score:           score,
+			NameScore:       p.name,
+			EmbeddingScore:  embedding,
+			NeighborScore:   p.neighbor,
+			SharedNeighbors: p.shared,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return entityPairKey(candidates[i].CanonicalID, candidates[i].DuplicateID) < entityPairKey(candidates[j].CanonicalID, candidates[j].DuplicateID)
+	})
+	if len(candidates) > opts.MaxCandidates {
+		candidates = candidates[:opts.MaxCandidates]
+	}
+	return candidates, nil
+}
+
+// ResolveEntities merges every candidate scoring at least AutoMergeScore
+// and returns the rest for review.
+func (db *DB) ResolveEntities(ctx context.Context, opts EntityResolutionOptions) (*EntityResolutionResult, error) {
+	applyEntityResolutionDefaults(&opts)
+	candidates, err := db.ProposeEntityMerges(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EntityResolutionResult{}
+	mergedInto := make(map[string]string)
+	follow := func(id string) string {
+		for mergedInto[id] != "" {
+			id = mergedInto[id]
+		}
+		return id
+	}
+	for _, c := range candidates {
+		canonical, duplicate := follow(c.CanonicalID), follow(c.DuplicateID)
+		if canonical == duplicate {
+			continue
+		}
+		if c.Score < opts.AutoMergeScore {
+			c.CanonicalID, c.DuplicateID = canonical, duplicate
+			result.Candidates = append(result.Candidates, c)
+			continue
+		}
+		merge, err := db.mergeEntities(ctx, canonical, duplicate, c.Score, true)
+		if err != nil {
+			return nil, err
+		}
+		mergedInto[duplicate] = canonical
+		result.Merges = append(result.Merges, *merge)
+	}
+	return result, nil
+}
+
+// MergeEntities merges the duplicate entity into the canonical one, e.g.
+// to accept a proposed candidate. Either may be given as a node ID or a
+// name. The duplicate's edges are moved to the canonical entity, its name
+// and aliases are added to the canonical entity's "aliases" property, and
+// later ingestion of the duplicate's name resolves to the canonical entity.
+func (db *DB) MergeEntities(ctx context.Context, canonical, duplicate string) (*EntityMerge, error) {
+	canonicalID, err := db.ResolveEntity(ctx, canonical)
+	if err != nil {
+		return nil, err
+	}
+	duplicateID, err := db.ResolveEntity(ctx, duplicate)
+	if err != nil {
+		return nil, err
+	}
+	if canonicalID == duplicateID {
+		return nil, fmt.Errorf("cortexdb: cannot merge entity %s into itself", canonicalID)
+	}
+	return db.mergeEntities(ctx, canonicalID, duplicateID, 0, false)
+}
+
+// RejectEntityMerge records that two entities are different, so they are
+// not proposed again
+func (db *DB) RejectEntityMerge(ctx context.Context, a, b string) error {
+	a, b = resolveEntityNodeID(a, ""), resolveEntityNodeID(b, "")
+	if a > b {
+		a, b = b, a
+	}
+	if _, err := db.store.GetDB().ExecContext(ctx,
+		"INSERT OR IGNORE INTO graph_entity_rejections (a, b) VALUES (?, ?)", a, b); err != nil {
+		return fmt.Errorf("reject entity merge: %w", err)
+	}
+	return nil
+}
+
+// ResolveEntity returns the node ID of the entity a name or ID refers to,
+// following merges
+func (db *DB) ResolveEntity(ctx context.Context, nameOrID string) (string, error) {
+	id := resolveEntityNodeID(nameOrID, "")
+	aliases, err := db.entityAliases(ctx, []string{id})
+	if err != nil {
+		return "", err
+	}
+	return firstNonEmpty(aliases[id], id), nil
+}
+
+// EntityMerges returns the merge log, newest first
+func (db *DB) EntityMerges(ctx context.Context, includeUndone bool) ([]EntityMerge, error) {
+	query := "SELECT id, canonical_id, merged_id, merged_name, score, auto, created_at, undone_at FROM graph_entity_merges"
+	if !includeUndone {
+		query += " WHERE undone_at IS NULL"
+	}
+	rows, err := db.store.GetDB().QueryContext(ctx, query+" ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("list entity merges: %w", err)
+	}
+	defer rows.Close()
+
+	var merges []EntityMerge
+	for rows.Next() {
+		var m EntityMerge
+		var undoneAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.CanonicalID, &m.MergedID, &m.MergedName, &m.Score, &m.Auto, &m.CreatedAt, &undoneAt); err != nil {
+			return nil, fmt.Errorf("scan entity merge: %w", err)
+		}
+		m.Undone = undoneAt.Valid
+		merges = append(merges, m)
+	}
+	return merges, rows.Err()
+}
+
+// UndoEntityMerge restores both entities and their edges as they were
+// before the merge. Edges added to the canonical entity since stay with
+// it. Merges built on this one must be undone first.
+func (db *DB) UndoEntityMerge(ctx context.Context, id int64) error {
+	sqlDB := db.store.GetDB()
+	var canonicalID, mergedID, snapshotJSON string
+	err := sqlDB.QueryRowContext(ctx,
+		"SELECT canonical_id, merged_id, snapshot FROM graph_entity_merges WHERE id = ? AND undone_at IS NULL", id,
+	).Scan(&canonicalID, &mergedID, &snapshotJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEntityMergeNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("load entity merge: %w", err)
+	}
+
+	var later int
+	if err := sqlDB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM graph_entity_merges
+		WHERE id > ? AND undone_at IS NULL
+		AND (canonical_id IN (?, ?) OR merged_id IN (?, ?))`,
+		id, canonicalID, mergedID, canonicalID, mergedID).Scan(&later); err != nil {
+		return fmt.Errorf("check later merges: %w", err)
+	}
+	if later > 0 {
+		return ErrEntityMergeConflict
+	}
+
+	var snapshot mergeSnapshot
+	if err := json.Unmarshal([]byte(snapshotJSON), &snapshot); err != nil {
+		return fmt.Errorf("decode merge snapshot: %w", err)
+	}
+
+	return db.Update(ctx, func(tx *Tx) error {
+		if _, err := tx.Graph().DeleteEdgesBatch(ctx, snapshot.Created); err != nil {
+			return fmt.Errorf("delete rewired edges: %w", err)
+		}
+		if _, err := tx.Graph().UpsertNodesBatch(ctx, []*graph.GraphNode{snapshot.Canonical, snapshot.Merged}); err != nil {
+			return fmt.Errorf("restore entities: %w", err)
+		}
+		if _, err := tx.Graph().UpsertEdgesBatch(ctx, snapshot.Edges); err != nil {
+			return fmt.Errorf("restore edges: %w", err)
+		}
+		s := tx.Vector().SQL()
+		if _, err := s.ExecContext(ctx, "DELETE FROM graph_entity_aliases WHERE alias_id = ?", mergedID); err != nil {
+			return fmt.Errorf("delete alias: %w", err)
+		}
+		for _, alias := range snapshot.Repointed {
+			if _, err := s.ExecContext(ctx, "UPDATE graph_entity_aliases SET canonical_id = ? WHERE alias_id = ?", mergedID, alias); err != nil {
+				return fmt.Errorf("restore alias: %w", err)
+			}
+		}
+		if _, err := s.ExecContext(ctx, "UPDATE graph_entity_merges SET undone_at = ? WHERE id = ?", time.Now().UTC(), id); err != nil {
+			return fmt.Errorf("mark merge undone: %w", err)
+		}
+		return nil
+	})
+}
+
+// rewiredEdgeID rebuilds the ID of an edge moved onto the canonical entity
+// from its new endpoints, so re-ingesting updates the same edge. Edges
+// outside ingestion's ID schemes get the canonical ID appended.
+func rewiredEdgeID(edge, moved *graph.GraphEdge, canonicalID string) string {
+	switch {
+	case strings.HasPrefix(edge.ID, "edge:mention:"):
+		return graphMentionEdgeID(moved.FromNodeID, moved.ToNodeID)
+	case strings.HasPrefix(edge.ID, "edge:rel:"):
+		if chunkID, ok := stringProperty(edge.Properties, "source_chunk_id"); ok {
+			return graphRelationEdgeID(chunkID, moved.FromNodeID, moved.ToNodeID, moved.EdgeType)
+		}
+	case strings.HasPrefix(edge.ID, "edge:relation:"):
+		if i, err := strconv.Atoi(edge.ID[strings.LastIndex(edge.ID, ":")+1:]); err == nil {
+			return toolRelationEdgeID(moved.FromNodeID, moved.ToNodeID, moved.EdgeType, i)
+		}
+	}
+	return edge.ID + ":" + canonicalID
+}
+
+func (db *DB) mergeEntities(ctx context.Context, canonicalID, duplicateID string, score float64, auto bool) (*EntityMerge, error) {
+	canonical, err := db.graph.GetNode(ctx, canonicalID)
+	if err != nil {
+		return nil, fmt.Errorf("load entity %s: %w", canonicalID, err)
+	}
+	duplicate, err := db.graph.GetNode(ctx, duplicateID)
+	if err != nil {
+		return nil, fmt.Errorf("load entity %s: %w", duplicateID, err)
+	}
+	canonicalEdges, err := db.graph.GetEdges(ctx, canonicalID, "both")
+	if err != nil {
+		return nil, fmt.Errorf("load entity edges: %w", err)
+	}
+	duplicateEdges, err := db.graph.GetEdges(ctx, duplicateID, "both")
+	if err != nil {
+		return nil, fmt.Errorf("load entity edges: %w", err)
+	}
+
+	snapshot := mergeSnapshot{Canonical: canonical, Merged: duplicate}
+	existing := make(map[string]*graph.GraphEdge, len(canonicalEdges))
+	for _, edge := range canonicalEdges {
+		existing[edge.ID] = edge
+		snapshot.Edges = append(snapshot.Edges, edge)
+	}
+
+	var rewired []*graph.GraphEdge
+	for _, edge := range duplicateEdges {
+		if _, ok := existing[edge.ID]; !ok {
+			// Edges between the two entities are already in canonicalEdges
+			snapshot.Edges = append(snapshot.Edges, edge)
+		}
+		moved := *edge
+		if moved.FromNodeID == duplicateID {
+			moved.FromNodeID = canonicalID
+		}
+		if moved.ToNodeID == duplicateID {
+			moved.ToNodeID = canonicalID
+		}
+		if moved.FromNodeID == moved.ToNodeID {
+			continue
+		}
+		moved.ID = rewiredEdgeID(edge, &moved, canonicalID)
+		if prior, ok := existing[moved.ID]; ok {
+			moved.Weight = math.Max(moved.Weight, prior.Weight)
+		}
+		snapshot.Created = append(snapshot.Created, moved.ID)
+		rewired = append(rewired, &moved)
+	}
+
+	merged := *canonical
+	merged.Properties = make(map[string]interface{}, len(canonical.Properties)+1)
+	for k, v := range canonical.Properties {
+		merged.Properties[k] = v
+	}
+	aliases := stringListProperty(canonical.Properties, "aliases")
+	for _, name := range append([]string{duplicate.Content}, stringListProperty(duplicate.Properties, "aliases")...) {
+		if name != "" && name != canonical.Content && !slices.Contains(aliases, name) {
+			aliases = append(aliases, name)
+		}
+	}
+	merged.Properties["aliases"] = aliases
+	if _, ok := stringProperty(merged.Properties, "description"); !ok {
+		if description, ok := stringProperty(duplicate.Properties, "description"); ok {
+			merged.Properties["description"] = description
+		}
+	}
+
+	var repointed []string
+	rows, err := db.store.GetDB().QueryContext(ctx, "SELECT alias_id FROM graph_entity_aliases WHERE canonical_id = ?", duplicateID)
+	if err != nil {
+		return nil, fmt.Errorf("load aliases: %w", err)
+	}
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan alias: %w", err)
+		}
+		repointed = append(repointed, alias)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load aliases: %w", err)
+	}
+	snapshot.Repointed = repointed
+
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("encode merge snapshot: %w", err)
+	}
+
+	merge := &EntityMerge{
+		CanonicalID: canonicalID,
+		MergedID:    duplicateID,
+		MergedName:  duplicate.Content,
+		Score:       score,
+		Auto:        auto,
+		CreatedAt:   time.Now().UTC(),
+	}
+	err = db.Update(ctx, func(tx *Tx) error {
+		if _, err := tx.Graph().DeleteNodesBatch(ctx, []string{duplicateID}); err != nil {
+			return fmt.Errorf("delete merged entity: %w", err)
+		}
+		if err := tx.Graph().UpsertNode(ctx, &merged); err != nil {
+			return fmt.Errorf("update canonical entity: %w", err)
+		}
+		if _, err := tx.Graph().UpsertEdgesBatch(ctx, rewired); err != nil {
+			return fmt.Errorf("rewire edges: %w", err)
+		}
+
+		s := tx.Vector().SQL()
+		res, err := s.ExecContext(ctx, `
+			INSERT INTO graph_entity_merges (canonical_id, merged_id, merged_name, score, auto, snapshot, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			canonicalID, duplicateID, duplicate.Content, score, auto, string(snapshotJSON), merge.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("log entity merge: %w", err)
+		}
+		if merge.ID, err = res.LastInsertId(); err != nil {
+			return fmt.Errorf("log entity merge: %w", err)
+		}
+		if _, err := s.ExecContext(ctx, "UPDATE graph_entity_aliases SET canonical_id = ? WHERE canonical_id = ?", canonicalID, duplicateID); err != nil {
+			return fmt.Errorf("move aliases: %w", err)
+		}
+		if _, err := s.ExecContext(ctx, `
+			INSERT OR REPLACE INTO graph_entity_aliases (alias_id, canonical_id, alias, merge_id)
+			VALUES (?, ?, ?, ?)`, duplicateID, canonicalID, duplicate.Content, merge.ID); err != nil {
+			return fmt.Errorf("add alias: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// entityAliases maps each merged-away entity ID among ids to the entity it
+// was merged into
+func (db *DB) entityAliases(ctx context.Context, ids []string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	if len(ids) == 0 {
+		return aliases, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := db.store.GetDB().QueryContext(ctx,
+		"SELECT alias_id, canonical_id FROM graph_entity_aliases WHERE alias_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("load entity aliases: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var alias, canonical string
+		if err := rows.Scan(&alias, &canonical); err != nil {
+			return nil, fmt.Errorf("scan entity alias: %w", err)
+		}
+		aliases[alias] = canonical
+	}
+	return aliases, rows.Err()
+}
+
+// extractionEntityIDs lists the entity IDs an extraction refers to
+func extractionEntityIDs(extraction *GraphExtraction) []string {
+	ids := make([]string, 0, len(extraction.Entities)+2*len(extraction.Relationships))
+	for _, e := range extraction.Entities {
+		ids = append(ids, graphEntityNodeID(e.Name))
+	}
+	for _, r := range extraction.Relationships {
+		ids = append(ids, graphEntityNodeID(r.From), graphEntityNodeID(r.To))
+	}
+	return ids
+}
+
+func (db *DB) rejectedEntityPairs(ctx context.Context) (map[string]bool, error) {
+	rows, err := db.store.GetDB().QueryContext(ctx, "SELECT a, b FROM graph_entity_rejections")
+	if err != nil {
+		return nil, fmt.Errorf("load rejected merges: %w", err)
+	}
+	defer rows.Close()
+	rejected := make(map[string]bool)
+	for rows.Next() {
+		var a, b string
+		if err := rows.Scan(&a, &b); err != nil {
+			return nil, fmt.Errorf("scan rejected merge: %w", err)
+		}
+		rejected[entityPairKey(a, b)] = true
+	}
+	return rejected, rows.Err()
+}
+
+// entityDescriptionVectors embeds "name: description" for the selected
+// entities. Without an embedder it falls back to the stored node vectors.
+func (db *DB) entityDescriptionVectors(ctx context.Context, entities []*graph.GraphNode, selected func(yield func(int))) (map[int][]float32, error) {
+	vectors := make(map[int][]float32)
+	var order []int
+	var texts []string
+	selected(func(i int) {
+		if _, ok := vectors[i]; ok {
+			return
+		}
+		vectors[i] = entities[i].Vector
+		order = append(order, i)
+		text := entities[i].Content
+		if description, ok := stringProperty(entities[i].Properties, "description"); ok {
+			text += ": " + description
+		}
+		texts = append(texts, text)
+	})
+	if db.embedder == nil || len(texts) == 0 {
+		return vectors, nil
+	}
+	embedded, err := db.ingestEmbedder().EmbedBatch(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed entity descriptions: %w", err)
+	}
+	for n, i := range order {
+		vectors[i] = embedded[n]
+	}
+	return vectors, nil
+}
+
+func applyEntityResolutionDefaults(opts *EntityResolutionOptions) {
+	if opts.MinScore <= 0 {
+		opts.MinScore = 0.6
+	}
+	if opts.AutoMergeScore <= 0 {
+		opts.AutoMergeScore = 0.9
+	}
+	if opts.NameWeight <= 0 && opts.EmbeddingWeight <= 0 && opts.NeighborWeight <= 0 {
+		opts.NameWeight, opts.EmbeddingWeight, opts.NeighborWeight = 0.5, 0.3, 0.2
+	}
+	if opts.MaxCandidates <= 0 {
+		opts.MaxCandidates = 100
+	}
+}
+
+// entityNameSuffixes are dropped when comparing names
+var entityNameSuffixes = map[string]bool{
+	"inc": true, "incorporated": true, "corp": true, "corporation": true, "co": true, "company": true,
+	"ltd": true, "limited": true, "llc": true, "plc": true, "gmbh": true, "ag": true, "sa": true, "the": true,
+}
+
+// entityNameKey lower-cases a name, splits it into words and drops
+// punctuation and legal suffixes
+func entityNameKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if !entityNameSuffixes[w] {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == 0 {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(kept, " ")
+}
+
+// entityNameSimilarity compares two name keys: 1 for equal keys, 0.9 when
+// one is the other's acronym, otherwise the better of word overlap and
+// character bigram similarity
+func entityNameSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b || strings.ReplaceAll(a, " ", "") == strings.ReplaceAll(b, " ", "") {
+		return 1
+	}
+	if isAcronym(a, b) || isAcronym(b, a) {
+		return 0.9
+	}
+	return math.Max(overlapScore(tokenSet(a), tokenSet(b)), bigramDice(a, b))
+}
+
+func isAcronym(short, long string) bool {
+	words := strings.Fields(long)
+	if len(words) < 2 || strings.Contains(short, " ") || len([]rune(short)) != len(words) {
+		return false
+	}
+	var b strings.Builder
+	for _, w := range words {
+		b.WriteRune([]rune(w)[0])
+	}
+	return b.String() == short
+}
+
+func bigramDice(a, b string) float64 {
+	bigrams := func(s string) map[string]int {
+		r := []rune(strings.ReplaceAll(s, " ", ""))
+		out := make(map[string]int)
+		for i := 0; i+1 < len(r); i++ {
+			out[string(r[i:i+2])]++
+		}
+		return out
+	}
+	ba, bb := bigrams(a), bigrams(b)
+	total, shared := 0, 0
+	for g, n := range ba {
+		total += n
+		shared += min(n, bb[g])
+	}
+	for _, n := range bb {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+func entityTypesMatch(a, b string) bool {
+	return a == b || a == "" || b == "" || a == "entity" || b == "entity"
+}
+
+func entityPairKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
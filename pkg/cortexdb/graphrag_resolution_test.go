@@ -0,0 +1,250 @@
+package cortexdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// companyExtractor knows a few organizations and products by name
+type companyExtractor struct{}
+
+func (companyExtractor) Extract(_ context.Context, text string) (*GraphExtraction, error) {
+	extraction := &GraphExtraction{}
+	var org string
+	for _, name := range []string{"OpenAI Inc.", "OpenAI LLC", "OpenAI", "Open Source Initiative"} {
+		if strings.HasPrefix(text, name) {
+			org = name
+			extraction.Entities = append(extraction.Entities, GraphEntity{Name: name, Type: "organization"})
+			break
+		}
+	}
+	for _, product := range []string{"GPT", "Sora"} {
+		if strings.Contains(text, product) {
+			extraction.Entities = append(extraction.Entities, GraphEntity{Name: product, Type: "product"})
+			if org != "" {
+				extraction.Relationships = append(extraction.Relationships, GraphRelationship{From: org, To: product, Type: "builds", Weight: 1})
+			}
+		}
+	}
+	return extraction, nil
+}
+
+func openResolutionTestDB(t *testing.T, docs ...string) *DB {
+	t.Helper()
+	db, err := Open(DefaultConfig(filepath.Join(t.TempDir(), "resolution.db")),
+		WithEmbedder(newKeywordEmbedder("openai", "gpt", "sora", "open", "source")))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for i, content := range docs {
+		ingestResolutionDoc(t, db, string(rune('a'+i)), content)
+	}
+	return db
+}
+
+func ingestResolutionDoc(t *testing.T, db *DB, id, content string) {
+	t.Helper()
+	doc := GraphRAGDocument{ID: id, Content: content}
+	if _, err := db.InsertGraphDocument(context.Background(), doc, GraphRAGIngestOptions{Extractor: companyExtractor{}}); err != nil {
+		t.Fatalf("InsertGraphDocument failed: %v", err)
+	}
+}
+
+func TestEntityResolution(t *testing.T) {
+	ctx := context.Background()
+	openai, openaiInc := graphEntityNodeID("OpenAI"), graphEntityNodeID("OpenAI Inc.")
+
+	t.Run("NameSimilarity", func(t *testing.T) {
+		tests := []struct {
+			a, b string
+			min  float64
+			max  float64
+		}{
+			{"OpenAI", "OpenAI Inc.", 1, 1},
+			{"Open AI", "openai", 1, 1},
+			{"IBM", "International Business Machines", 0.9, 0.9},
+			{"Acme", "Acme Research Labs", 0, 0.5},
+			{"Alice", "Bob", 0, 0},
+		}
+		for _, tt := range tests {
+			got := entityNameSimilarity(entityNameKey(tt.a), entityNameKey(tt.b))
+			if got < tt.min || got > tt.max {
+				t.Errorf("entityNameSimilarity(%q, %q) = %f, want %f to %f", tt.a, tt.b, got, tt.min, tt.max)
+			}
+		}
+	})
+
+	t.Run("AutoMergeAndUndo", func(t *testing.T) {
+		db := openResolutionTestDB(t, "OpenAI builds GPT.", "OpenAI Inc. builds GPT.", "Open Source Initiative reviewed GPT.")
+
+		result, err := db.ResolveEntities(ctx, EntityResolutionOptions{})
+		if err != nil {
+			t.Fatalf("ResolveEntities failed: %v", err)
+		}
+		if len(result.Merges) != 1 || result.Merges[0].CanonicalID != openai || result.Merges[0].MergedID != openaiInc || !result.Merges[0].Auto {
+			t.Fatalf("Expected OpenAI Inc. merged into OpenAI, got %+v", result.Merges)
+		}
+		for _, c := range result.Candidates {
+			if c.Score >= 0.9 {
+				t.Errorf("Expected only low scoring candidates left, got %+v", c)
+			}
+		}
+
+		if _, err := db.Graph().GetNode(ctx, openaiInc); err == nil {
+			t.Error("Expected the merged entity to be deleted")
+		}
+		node, err := db.Graph().GetNode(ctx, openai)
+		if err != nil {
+			t.Fatalf("GetNode failed: %v", err)
+		}
+		if aliases := stringListProperty(node.Properties, "aliases"); strings.Join(aliases, ",") != "OpenAI Inc." {
+			t.Errorf("Expected the alias recorded, got %v", aliases)
+		}
+		edges, err := db.Graph().GetEdges(ctx, openai, "both")
+		if err != nil {
+			t.Fatalf("GetEdges failed: %v", err)
+		}
+		mentions := 0
+		for _, edge := range edges {
+			if edge.EdgeType == "mentions" {
+				mentions++
+			}
+		}
+		if mentions != 2 {
+			t.Errorf("Expected mentions from both documents, got %d", mentions)
+		}
+
+		if id, err := db.ResolveEntity(ctx, "OpenAI Inc."); err != nil || id != openai {
+			t.Errorf("Expected the alias to resolve to %s, got %s, %v", openai, id, err)
+		}
+		ingestResolutionDoc(t, db, "d", "OpenAI Inc. builds Sora.")
+		if _, err := db.Graph().GetNode(ctx, openaiInc); err == nil {
+			t.Error("Expected ingestion to use the canonical entity")
+		}
+		node, _ = db.Graph().GetNode(ctx, openai)
+		if node.Content != "OpenAI" || len(stringListProperty(node.Properties, "aliases")) != 1 {
+			t.Errorf("Expected the canonical entity unchanged, got %q %v", node.Content, node.Properties)
+		}
+		chunks, err := db.GraphRAGTools().SearchChunksByEntities(ctx, ToolSearchChunksByEntitiesRequest{EntityNames: []string{"OpenAI Inc."}})
+		if err != nil {
+			t.Fatalf("SearchChunksByEntities failed: %v", err)
+		}
+		if len(chunks.Chunks) != 3 {
+			t.Errorf("Expected the alias to find all three chunks, got %d", len(chunks.Chunks))
+		}
+
+		log, err := db.EntityMerges(ctx, false)
+		if err != nil || len(log) != 1 {
+			t.Fatalf("Expected one logged merge, got %+v, %v", log, err)
+		}
+		if err := db.UndoEntityMerge(ctx, log[0].ID); err != nil {
+			t.Fatalf("UndoEntityMerge failed: %v", err)
+		}
+		restored, err := db.Graph().GetEdges(ctx, openaiInc, "both")
+		if err != nil || len(restored) != 2 {
+			t.Errorf("Expected the merged entity's two edges restored, got %d, %v", len(restored), err)
+		}
+		node, _ = db.Graph().GetNode(ctx, openai)
+		if node.Properties["aliases"] != nil {
+			t.Errorf("Expected the aliases removed, got %v", node.Properties["aliases"])
+		}
+		if id, _ := db.ResolveEntity(ctx, "OpenAI Inc."); id != openaiInc {
+			t.Errorf("Expected the alias removed, got %s", id)
+		}
+		if err := db.UndoEntityMerge(ctx, log[0].ID); !errors.Is(err, ErrEntityMergeNotFound) {
+			t.Errorf("Expected ErrEntityMergeNotFound on a second undo, got %v", err)
+		}
+		if log, _ := db.EntityMerges(ctx, true); len(log) != 1 || !log[0].Undone {
+			t.Errorf("Expected the undone merge kept in the log, got %+v", log)
+		}
+	})
+
+	t.Run("Review", func(t *testing.T) {
+		db := openResolutionTestDB(t, "OpenAI builds GPT.", "OpenAI Inc. builds GPT.", "OpenAI LLC builds Sora.")
+
+		candidates, err := db.ProposeEntityMerges(ctx, EntityResolutionOptions{})
+		if err != nil {
+			t.Fatalf("ProposeEntityMerges failed: %v", err)
+		}
+		if len(candidates) != 3 {
+			t.Fatalf("Expected three candidate pairs, got %+v", candidates)
+		}
+		top := candidates[0]
+		if top.CanonicalID != openai || top.DuplicateID != openaiInc || top.SharedNeighbors != 1 || top.NameScore != 1 {
+			t.Errorf("Expected OpenAI Inc. with a shared product first, got %+v", top)
+		}
+
+		if err := db.RejectEntityMerge(ctx, "OpenAI LLC", "OpenAI Inc."); err != nil {
+			t.Fatalf("RejectEntityMerge failed: %v", err)
+		}
+		candidates, _ = db.ProposeEntityMerges(ctx, EntityResolutionOptions{})
+		if len(candidates) != 2 {
+			t.Errorf("Expected the rejected pair left out, got %+v", candidates)
+		}
+
+		first, err := db.MergeEntities(ctx, "OpenAI", "OpenAI Inc.")
+		if err != nil {
+			t.Fatalf("MergeEntities failed: %v", err)
+		}
+		if first.Auto || first.MergedName != "OpenAI Inc." {
+			t.Errorf("Unexpected merge %+v", first)
+		}
+		second, err := db.MergeEntities(ctx, "OpenAI", "OpenAI LLC")
+		if err != nil {
+			t.Fatalf("MergeEntities failed: %v", err)
+		}
+		if err := db.UndoEntityMerge(ctx, first.ID); !errors.Is(err, ErrEntityMergeConflict) {
+			t.Errorf("Expected ErrEntityMergeConflict, got %v", err)
+		}
+		if err := db.UndoEntityMerge(ctx, second.ID); err != nil {
+			t.Fatalf("UndoEntityMerge failed: %v", err)
+		}
+		if err := db.UndoEntityMerge(ctx, first.ID); err != nil {
+			t.Fatalf("UndoEntityMerge failed: %v", err)
+		}
+		if _, err := db.MergeEntities(ctx, "OpenAI", "openai"); err == nil {
+			t.Error("Expected merging an entity into itself to fail")
+		}
+	})
+
+	t.Run("EdgeIDs", func(t *testing.T) {
+		// GPT's ID is a prefix of GPT 4's
+		db := openResolutionTestDB(t, "OpenAI builds GPT.")
+		tools := db.GraphRAGTools()
+		if _, err := tools.UpsertEntities(ctx, ToolUpsertEntitiesRequest{Entities: []ToolEntityInput{{Name: "GPT 4"}, {Name: "ChatGPT"}}}); err != nil {
+			t.Fatalf("UpsertEntities failed: %v", err)
+		}
+		if _, err := tools.UpsertRelations(ctx, ToolUpsertRelationsRequest{Relations: []ToolRelationInput{{From: "GPT", To: "GPT 4", Type: "precedes"}}}); err != nil {
+			t.Fatalf("UpsertRelations failed: %v", err)
+		}
+		if _, err := db.MergeEntities(ctx, "ChatGPT", "GPT"); err != nil {
+			t.Fatalf("MergeEntities failed: %v", err)
+		}
+
+		chatgpt, gpt4 := graphEntityNodeID("ChatGPT"), graphEntityNodeID("GPT 4")
+		edges, err := db.Graph().GetEdges(ctx, chatgpt, "both")
+		if err != nil {
+			t.Fatalf("GetEdges failed: %v", err)
+		}
+		want := map[string]bool{
+			toolRelationEdgeID(chatgpt, gpt4, "precedes", 0):                         false,
+			graphRelationEdgeID(graphChunkNodeID("a", 0), openai, chatgpt, "builds"): false,
+			graphMentionEdgeID(graphChunkNodeID("a", 0), chatgpt):                    false,
+		}
+		for _, edge := range edges {
+			if _, ok := want[edge.ID]; !ok {
+				t.Errorf("Unexpected edge ID %s", edge.ID)
+			}
+			want[edge.ID] = true
+		}
+		for id, found := range want {
+			if !found {
+				t.Errorf("Expected edge %s", id)
+			}
+		}
+	})
+}
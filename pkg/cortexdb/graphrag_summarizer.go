@@ -0,0 +1,197 @@
+package cortexdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultCommunityReportPrompt is the user prompt template for a community
+// report. The template sees .Entities, .Relationships and .SubReports as
+// one item per line.
+const DefaultCommunityReportPrompt = `Write a report on the community of entities below, using only the information given.
+
+Entities:
+{{.Entities}}
+
+Relationships:
+{{.Relationships}}
+{{if .SubReports}}
+Reports on its sub-communities:
+{{.SubReports}}
+{{end}}
+Return a JSON object of this form:
+{"title": "...", "summary": "...", "findings": ["...", "..."], "rating": 5.0}
+
+The title names the community's most important entities. The summary explains how the entities are related in a few sentences. Give up to ten findings, each a self-contained sentence. Rate the community's importance from 0 to 10. Return only the JSON.`
+
+// DefaultCommunityMapPrompt is the user prompt template for the map step of
+// global retrieval. The template sees .Query and .Report.
+const DefaultCommunityMapPrompt = `Using only the community report below, list the points that help answer the question.
+
+Question: {{.Query}}
+
+Report:
+{{.Report}}
+
+Return a JSON object of this form:
+{"points": [{"text": "...", "score": 80}]}
+
+Score each point from 0 to 100 for how much it helps answer the question. Return {"points": []} if the report is not relevant. Return only the JSON.`
+
+const defaultSummarizerSystemPrompt = "You summarize knowledge graphs and answer with JSON only."
+
+// LLMSummarizerConfig configures an LLMSummarizer. Zero values take the
+// defaults noted on each field.
+type LLMSummarizerConfig struct {
+	BaseURL     string  // Endpoint root (default: https://api.openai.com/v1)
+	APIKey      string  // Sent as a bearer token when set
+	Model       string  // Chat model, required
+	Temperature float64 // Sampling temperature (default: 0)
+	JSONMode    bool    // Request response_format json_object, for servers that support it
+
+	SystemPrompt string // System message
+	ReportPrompt string // Community report template (default: DefaultCommunityReportPrompt)
+	MapPrompt    string // Global retrieval map template (default: DefaultCommunityMapPrompt)
+
+	MaxRetries   int               // Retries on 429, 5xx and network errors (default: 3, -1 = none)
+	RetryBackoff time.Duration     // Delay before the first retry, doubled after each (default: 500ms)
+	MaxBackoff   time.Duration     // Upper bound for retry delays (default: 30s)
+	Timeout      time.Duration     // Per-request timeout (default: 120s)
+	Headers      map[string]string // Extra request headers
+	HTTPClient   *http.Client      // Client to use (default: a new client)
+}
+
+// LLMSummarizer writes community reports and maps them onto queries with
+// an OpenAI-compatible /chat/completions endpoint. It is both a
+// CommunitySummarizer and a CommunityMapper.
+type LLMSummarizer struct {
+	config LLMSummarizerConfig
+	report *template.Template
+	mapper *template.Template
+	chat   *chatClient
+}
+
+// NewLLMSummarizer creates an LLMSummarizer
+func NewLLMSummarizer(config LLMSummarizerConfig) (*LLMSummarizer, error) {
+	if config.Model == "" {
+		return nil, fmt.Errorf("cortexdb: summarizer model is required")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.openai.com/v1"
+	}
+	if config.SystemPrompt == "" {
+		config.SystemPrompt = defaultSummarizerSystemPrompt
+	}
+	if config.ReportPrompt == "" {
+		config.ReportPrompt = DefaultCommunityReportPrompt
+	}
+	if config.MapPrompt == "" {
+		config.MapPrompt = DefaultCommunityMapPrompt
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 120 * time.Second
+	}
+
+	report, err := template.New("report").Parse(config.ReportPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("cortexdb: parse community report prompt: %w", err)
+	}
+	mapper, err := template.New("map").Parse(config.MapPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("cortexdb: parse community map prompt: %w", err)
+	}
+
+	chat, err := newChatClient(HTTPEmbedderConfig{
+		BaseURL:      config.BaseURL,
+		APIKey:       config.APIKey,
+		Model:        config.Model,
+		MaxRetries:   config.MaxRetries,
+		RetryBackoff: config.RetryBackoff,
+		MaxBackoff:   config.MaxBackoff,
+		Timeout:      config.Timeout,
+		Headers:      config.Headers,
+		HTTPClient:   config.HTTPClient,
+	}, config.Temperature, config.JSONMode)
+	if err != nil {
+		return nil, err
+	}
+	return &LLMSummarizer{config: config, report: report, mapper: mapper, chat: chat}, nil
+}
+
+// Requests returns the number of chat completions sent
+func (s *LLMSummarizer) Requests() int64 {
+	return s.chat.requests.Load()
+}
+
+// SummarizeCommunity implements CommunitySummarizer
+func (s *LLMSummarizer) SummarizeCommunity(ctx context.Context, c CommunityInput) (*CommunitySummary, error) {
+	var entities, relationships, subReports []string
+	for _, e := range c.Entities {
+		line := fmt.Sprintf("- %s (%s)", e.Name, firstNonEmpty(e.Type, "entity"))
+		if e.Description != "" {
+			line += ": " + e.Description
+		}
+		entities = append(entities, line)
+	}
+	for _, r := range c.Relationships {
+		line := fmt.Sprintf("- %s %s %s", r.From, firstNonEmpty(r.Type, "related_to"), r.To)
+		if r.Description != "" {
+			line += ": " + r.Description
+		}
+		relationships = append(relationships, line)
+	}
+	for _, sub := range c.SubReports {
+		subReports = append(subReports, fmt.Sprintf("- %s: %s", sub.Title, sub.Summary))
+	}
+	if len(relationships) == 0 {
+		relationships = append(relationships, "(none)")
+	}
+
+	prompt, err := renderTemplate(s.report, map[string]string{
+		"Entities":      strings.Join(entities, "\n"),
+		"Relationships": strings.Join(relationships, "\n"),
+		"SubReports":    strings.Join(subReports, "\n"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var summary CommunitySummary
+	if err := s.chat.completeJSON(ctx, s.config.SystemPrompt, prompt, &summary); err != nil {
+		return nil, err
+	}
+	if summary.Title == "" && len(c.Entities) > 0 {
+		summary.Title = c.Entities[0].Name
+	}
+	return &summary, nil
+}
+
+// MapCommunity implements CommunityMapper
+func (s *LLMSummarizer) MapCommunity(ctx context.Context, query string, report CommunityReport) ([]CommunityPoint, error) {
+	prompt, err := renderTemplate(s.mapper, map[string]string{
+		"Query":  query,
+		"Report": communityReportText(report.CommunitySummary),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Points []struct {
+			Text  string    `json:"text"`
+			Score flexFloat `json:"score"`
+		} `json:"points"`
+	}
+	if err := s.chat.completeJSON(ctx, s.config.SystemPrompt, prompt, &resp); err != nil {
+		return nil, err
+	}
+	points := make([]CommunityPoint, 0, len(resp.Points))
+	for _, p := range resp.Points {
+		points = append(points, CommunityPoint{Text: p.Text, Score: float64(p.Score)})
+	}
+	return points, nil
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/chunking"
 )
 
 type keywordEmbedder struct {
@@ -356,3 +360,186 @@ func containsString(values []string, needle string) bool {
 	}
 	return false
 }
+
+func TestGraphRAGChunkers(t *testing.T) {
+	ctx := context.Background()
+	content := "# Alice\n\nAlice works at Acme.\n\n## Research\n\nAcme does GraphRAG research.\n"
+
+	t.Run("IngestOptions", func(t *testing.T) {
+		db, err := Open(DefaultConfig(filepath.Join(t.TempDir(), "chunkers.db")), WithEmbedder(newKeywordEmbedder("alice", "acme", "graphrag", "research")))
+		if err != nil {
+			t.Fatalf("open db: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		ingest, err := db.InsertGraphDocument(ctx, GraphRAGDocument{ID: "md", Content: content}, GraphRAGIngestOptions{
+			Chunker:   chunking.Markdown{},
+			Extractor: fixtureExtractor{},
+		})
+		if err != nil {
+			t.Fatalf("InsertGraphDocument: %v", err)
+		}
+		if len(ingest.ChunkNodeIDs) != 2 {
+			t.Fatalf("Expected a chunk per section, got %v", ingest.ChunkNodeIDs)
+		}
+		emb, err := db.store.GetByID(ctx, ingest.ChunkNodeIDs[1])
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if emb.Metadata["section"] != "Alice > Research" {
+			t.Errorf("Expected the section path in metadata, got %v", emb.Metadata)
+		}
+		start, _ := strconv.Atoi(emb.Metadata["chunk_start"])
+		end, _ := strconv.Atoi(emb.Metadata["chunk_end"])
+		if content[start:end] != emb.Content {
+			t.Errorf("Expected offsets into the source, got [%d, %d) for %q", start, end, emb.Content)
+		}
+	})
+
+	t.Run("Tools", func(t *testing.T) {
+		db, err := Open(DefaultConfig(filepath.Join(t.TempDir(), "chunker-tools.db")))
+		if err != nil {
+			t.Fatalf("open db: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+		tools := db.GraphRAGTools()
+
+		resp, err := tools.Call(ctx, "ingest_document", []byte(`{"document_id":"s","content":"First sentence here. Second one follows.","chunker":"sentence"}`))
+		if err != nil {
+			t.Fatalf("ingest_document: %v", err)
+		}
+		if ids := resp.(*ToolIngestDocumentResponse).ChunkNodeIDs; len(ids) != 2 {
+			t.Errorf("Expected a chunk per sentence, got %v", ids)
+		}
+
+		if _, err := tools.Call(ctx, "knowledge_save", []byte(`{"knowledge_id":"k","content":"text","chunker":"pages"}`)); err == nil {
+			t.Error("Expected an unknown chunker to be rejected")
+		}
+		if _, err := db.GetKnowledge(ctx, KnowledgeGetRequest{KnowledgeID: "k"}); err == nil {
+			t.Error("Expected nothing stored for a rejected save")
+		}
+	})
+}
+
+func TestGraphRAGIncrementalReingest(t *testing.T) {
+	ctx := context.Background()
+	embedder := &countingEmbedder{keywordEmbedder: newKeywordEmbedder("alice", "acme", "graphrag", "research", "retrieval", "company")}
+	db, err := Open(DefaultConfig(filepath.Join(t.TempDir(), "reingest.db")), WithEmbedder(embedder))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	opts := GraphRAGIngestOptions{Chunker: chunking.Markdown{}, Extractor: fixtureExtractor{}}
+	sectionA := "# A\n\nAlice works at Acme.\n\n"
+	sectionB := "# B\n\nGraphRAG research.\n\n"
+	sectionC := "# C\n\nResearch retrieval.\n\n"
+
+	first, err := db.InsertGraphDocument(ctx, GraphRAGDocument{ID: "doc", Content: sectionA + sectionB + sectionC}, opts)
+	if err != nil {
+		t.Fatalf("InsertGraphDocument: %v", err)
+	}
+	if first.Chunks != (ChunkChanges{Added: 3}) {
+		t.Fatalf("Expected 3 added chunks, got %+v", first.Chunks)
+	}
+
+	// Drop B and append D: A and C are kept as they are
+	embedder.texts.Store(0)
+	second, err := db.InsertGraphDocument(ctx, GraphRAGDocument{ID: "doc", Content: sectionA + sectionC + "# D\n\nCompany notes.\n"}, opts)
+	if err != nil {
+		t.Fatalf("InsertGraphDocument: %v", err)
+	}
+	if second.Chunks != (ChunkChanges{Added: 1, Unchanged: 2, Removed: 1}) {
+		t.Fatalf("Unexpected chunk changes %+v", second.Chunks)
+	}
+	if n := embedder.texts.Load(); n != 1 {
+		t.Errorf("Expected only the added chunk to be embedded, embedded %d texts", n)
+	}
+	wantEntities := []string{graphEntityNodeID("Acme"), graphEntityNodeID("Alice"), graphEntityNodeID("Research")}
+	if strings.Join(second.EntityNodeIDs, ",") != strings.Join(wantEntities, ",") || len(second.AddedEntityNodeIDs) != 0 {
+		t.Errorf("Expected the unchanged chunks' entities for the document and none added, got %v and %v", second.EntityNodeIDs, second.AddedEntityNodeIDs)
+	}
+	if second.ChunkNodeIDs[0] != first.ChunkNodeIDs[0] || second.ChunkNodeIDs[1] != first.ChunkNodeIDs[2] {
+		t.Errorf("Expected unchanged chunks to keep their IDs, got %v then %v", first.ChunkNodeIDs, second.ChunkNodeIDs)
+	}
+	if _, err := db.Graph().GetNode(ctx, first.ChunkNodeIDs[1]); err == nil {
+		t.Error("Expected the removed chunk's node to be deleted")
+	}
+	if _, err := db.store.GetByID(ctx, first.ChunkNodeIDs[1]); err == nil {
+		t.Error("Expected the removed chunk's embedding to be deleted")
+	}
+	if _, err := db.Graph().GetNode(ctx, graphEntityNodeID("GraphRAG")); err == nil {
+		t.Error("Expected the entity only the removed chunk mentioned to be deleted")
+	}
+	if _, err := db.Graph().GetNode(ctx, graphEntityNodeID("Research")); err != nil {
+		t.Errorf("Expected an entity an unchanged chunk mentions to remain: %v", err)
+	}
+	mentions, err := db.Graph().GetEdges(ctx, second.ChunkNodeIDs[0], "out")
+	if err != nil || len(mentions) < 2 {
+		t.Errorf("Expected the unchanged chunk's mention edges to remain, got %d (%v)", len(mentions), err)
+	}
+	docEdges, err := db.Graph().GetEdges(ctx, second.DocumentNodeID, "out")
+	if err != nil || len(docEdges) != 3 {
+		t.Errorf("Expected 3 document->chunk edges, got %d (%v)", len(docEdges), err)
+	}
+
+	// Dropping A removes the relationship extracted from it and Alice with it
+	third, err := db.InsertGraphDocument(ctx, GraphRAGDocument{ID: "doc", Content: sectionC}, opts)
+	if err != nil {
+		t.Fatalf("InsertGraphDocument: %v", err)
+	}
+	if third.Chunks != (ChunkChanges{Unchanged: 1, Removed: 2}) {
+		t.Fatalf("Unexpected chunk changes %+v", third.Chunks)
+	}
+	for _, name := range []string{"Alice", "Acme"} {
+		if _, err := db.Graph().GetNode(ctx, graphEntityNodeID(name)); err == nil {
+			t.Errorf("Expected orphaned entity %s to be deleted", name)
+		}
+	}
+	docEdges, err = db.Graph().GetEdges(ctx, third.DocumentNodeID, "out")
+	if err != nil || len(docEdges) != 1 {
+		t.Errorf("Expected stale document->chunk edges to be deleted, got %d (%v)", len(docEdges), err)
+	}
+
+	t.Run("Alias", func(t *testing.T) {
+		if _, err := db.store.CreateAlias(ctx, "current", defaultGraphRAGCollection); err != nil {
+			t.Fatalf("CreateAlias: %v", err)
+		}
+		aliasOpts := opts
+		aliasOpts.Collection = "current"
+		embedder.texts.Store(0)
+		again, err := db.InsertGraphDocument(ctx, GraphRAGDocument{ID: "doc", Content: sectionC}, aliasOpts)
+		if err != nil {
+			t.Fatalf("InsertGraphDocument: %v", err)
+		}
+		if again.Chunks != (ChunkChanges{Unchanged: 1}) || embedder.texts.Load() != 0 {
+			t.Errorf("Expected the chunk kept through the alias, got %+v after embedding %d texts", again.Chunks, embedder.texts.Load())
+		}
+	})
+
+	t.Run("Knowledge", func(t *testing.T) {
+		saved, err := db.SaveKnowledge(ctx, KnowledgeSaveRequest{KnowledgeID: "k", Content: sectionA + sectionB, Chunker: "markdown"})
+		if err != nil {
+			t.Fatalf("SaveKnowledge: %v", err)
+		}
+		if saved.Chunks != (ChunkChanges{Added: 2}) {
+			t.Fatalf("Unexpected chunk changes %+v", saved.Chunks)
+		}
+		content := sectionA + sectionC
+		chunker := "markdown"
+		updated, err := db.UpdateKnowledge(ctx, KnowledgeUpdateRequest{KnowledgeID: "k", Content: &content, Chunker: &chunker})
+		if err != nil {
+			t.Fatalf("UpdateKnowledge: %v", err)
+		}
+		if updated.Chunks != (ChunkChanges{Added: 1, Unchanged: 1, Removed: 1}) {
+			t.Errorf("Unexpected chunk changes %+v", updated.Chunks)
+		}
+		if updated.Knowledge.ChunkIDs[0] != saved.Knowledge.ChunkIDs[0] || updated.Knowledge.Version != 2 {
+			t.Errorf("Expected the unchanged chunk kept in a new version, got %+v", updated.Knowledge)
+		}
+		alice := graphEntityNodeID("Alice")
+		if !containsString(updated.EntityNodeIDs, alice) || containsString(updated.AddedEntityNodeIDs, alice) {
+			t.Errorf("Expected %s from the unchanged chunk listed but not added, got %v and %v", alice, updated.EntityNodeIDs, updated.AddedEntityNodeIDs)
+		}
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/liliang-cn/cortexdb/v2/pkg/chunking"
 	"github.com/liliang-cn/cortexdb/v2/pkg/core"
 	"github.com/liliang-cn/cortexdb/v2/pkg/graph"
 )
@@ -45,6 +46,7 @@ type ToolIngestDocumentRequest struct {
 	Collection   string            `json:"collection,omitempty"`
 	ChunkSize    int               `json:"chunk_size,omitempty"`
 	ChunkOverlap int               `json:"chunk_overlap,omitempty"`
+	Chunker      string            `json:"chunker,omitempty"` // Chunking strategy, see chunking.New (default: words)
 	Metadata     map[string]string `json:"metadata,omitempty"`
 }
 
@@ -221,8 +223,9 @@ func (t *GraphRAGToolbox) Definitions() []ToolDefinition {
 					"title":         toolStringSchema("Optional human-readable title."),
 					"content":       toolStringSchema("Raw document content."),
 					"collection":    toolStringSchema("Optional chunk collection name."),
-					"chunk_size":    toolIntegerSchema("Optional chunk size in words."),
-					"chunk_overlap": toolIntegerSchema("Optional chunk overlap in words."),
+					"chunk_size":    toolIntegerSchema("Optional chunk size: words for the words strategy, tokens for token, characters otherwise."),
+					"chunk_overlap": toolIntegerSchema("Optional chunk overlap, in the chunk size unit (sentences for the sentence strategy)."),
+					"chunker":       toolChunkerSchema(),
 					"metadata":      toolMapSchema("Optional document metadata."),
 				},
 			),
@@ -524,6 +527,13 @@ func (t *GraphRAGToolbox) IngestDocument(ctx context.Context, req ToolIngestDocu
 		ChunkSize:    req.ChunkSize,
 		ChunkOverlap: req.ChunkOverlap,
 	}
+	if req.Chunker != "" {
+		chunker, err := chunking.New(req.Chunker, req.ChunkSize, req.ChunkOverlap)
+		if err != nil {
+			return nil, err
+		}
+		ingestOpts.Chunker = chunker
+	}
 	applyGraphRAGIngestDefaults(&ingestOpts)
 
 	if err := t.db.graph.InitGraphSchema(ctx); err != nil {
@@ -538,7 +548,7 @@ func (t *GraphRAGToolbox) IngestDocument(ctx context.Context, req ToolIngestDocu
 		return nil, err
 	}
 
-	chunks := splitGraphRAGText(req.Content, ingestOpts.ChunkSize, ingestOpts.ChunkOverlap)
+	chunks := splitGraphRAGChunks(req.Content, ingestOpts)
 	if len(chunks) == 0 {
 		return nil, ErrEmptyText
 	}
@@ -549,10 +559,6 @@ func (t *GraphRAGToolbox) IngestDocument(ctx context.Context, req ToolIngestDocu
 		Content: req.Content,
 		Version: 1,
 	}
-	if err := t.db.upsertGraphRAGDocumentRecord(ctx, docRecord); err != nil {
-		return nil, err
-	}
-
 	docNodeID := graphDocumentNodeID(req.DocumentID)
 	docNode := &graph.GraphNode{
 		ID:       docNodeID,
@@ -564,9 +570,6 @@ func (t *GraphRAGToolbox) IngestDocument(ctx context.Context, req ToolIngestDocu
 			"title":       req.Title,
 		},
 	}
-	if err := t.db.graph.UpsertNode(ctx, docNode); err != nil {
-		return nil, fmt.Errorf("upsert document node: %w", err)
-	}
 
 	embeddings := make([]*core.Embedding, 0, len(chunks))
 	chunkNodes := make([]*graph.GraphNode, 0, len(chunks))
@@ -577,33 +580,27 @@ func (t *GraphRAGToolbox) IngestDocument(ctx context.Context, req ToolIngestDocu
 		chunkID := graphChunkNodeID(req.DocumentID, i)
 		chunkIDs = append(chunkIDs, chunkID)
 
-		metadata := map[string]string{
-			"graph_kind":  "chunk",
-			"document_id": req.DocumentID,
-			"chunk_index": fmt.Sprintf("%d", i),
-			"title":       req.Title,
-		}
-		for k, v := range req.Metadata {
-			metadata[k] = v
-		}
+		metadata := graphRAGChunkMetadata(req.DocumentID, req.Title, i, chunk, req.Metadata)
 
-		chunkVector := lexicalVectorForText(chunk, vectorDim)
+		chunkVector := lexicalVectorForText(chunk.Text, vectorDim)
 		embeddings = append(embeddings, &core.Embedding{
 			ID:         chunkID,
 			Collection: ingestOpts.Collection,
 			Vector:     chunkVector,
-			Content:    chunk,
+			Content:    chunk.Text,
 			DocID:      req.DocumentID,
 			Metadata:   metadata,
 		})
 		chunkNodes = append(chunkNodes, &graph.GraphNode{
 			ID:       chunkID,
 			Vector:   chunkVector,
-			Content:  chunk,
+			Content:  chunk.Text,
 			NodeType: "chunk",
 			Properties: map[string]interface{}{
 				"document_id": req.DocumentID,
 				"chunk_index": i,
+				"chunk_start": chunk.Start,
+				"chunk_end":   chunk.End,
 				"title":       req.Title,
 			},
 		})
@@ -625,14 +622,26 @@ func (t *GraphRAGToolbox) IngestDocument(ctx context.Context, req ToolIngestDocu
 		}
 	}
 
-	if err := t.db.store.UpsertBatch(ctx, embeddings); err != nil {
-		return nil, fmt.Errorf("upsert lexical chunks: %w", err)
-	}
-	if _, err := t.db.graph.UpsertNodesBatch(ctx, chunkNodes); err != nil {
-		return nil, fmt.Errorf("upsert chunk nodes: %w", err)
-	}
-	if _, err := t.db.graph.UpsertEdgesBatch(ctx, edges); err != nil {
-		return nil, fmt.Errorf("upsert chunk edges: %w", err)
+	err = t.db.Update(ctx, func(tx *Tx) error {
+		if err := upsertGraphRAGDocumentRecord(ctx, tx, docRecord); err != nil {
+			return err
+		}
+		if err := tx.Graph().UpsertNode(ctx, docNode); err != nil {
+			return fmt.Errorf("upsert document node: %w", err)
+		}
+		if err := tx.Vector().UpsertBatch(ctx, embeddings); err != nil {
+			return fmt.Errorf("upsert lexical chunks: %w", err)
+		}
+		if _, err := tx.Graph().UpsertNodesBatch(ctx, chunkNodes); err != nil {
+			return fmt.Errorf("upsert chunk nodes: %w", err)
+		}
+		if _, err := tx.Graph().UpsertEdgesBatch(ctx, edges); err != nil {
+			return fmt.Errorf("upsert chunk edges: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &ToolIngestDocumentResponse{
@@ -660,11 +669,24 @@ func (t *GraphRAGToolbox) UpsertEntities(ctx context.Context, req ToolUpsertEnti
 	edges := make([]*graph.GraphEdge, 0)
 	entityIDs := make([]string, 0, len(req.Entities))
 
+	requested := make([]string, 0, len(req.Entities))
+	for _, entity := range req.Entities {
+		requested = append(requested, resolveEntityNodeID(entity.ID, entity.Name))
+	}
+	aliases, err := t.db.entityAliases(ctx, requested)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, entity := range req.Entities {
 		if strings.TrimSpace(entity.Name) == "" && strings.TrimSpace(entity.ID) == "" {
 			continue
 		}
 		entityID := resolveEntityNodeID(entity.ID, entity.Name)
+		canonical, merged := aliases[entityID]
+		if merged {
+			entityID = canonical
+		}
 		entityIDs = append(entityIDs, entityID)
 
 		properties := map[string]interface{}{}
@@ -678,20 +700,23 @@ func (t *GraphRAGToolbox) UpsertEntities(ctx context.Context, req ToolUpsertEnti
 			properties[k] = v
 		}
 
-		nodes = append(nodes, &graph.GraphNode{
-			ID:         entityID,
-			Vector:     lexicalVectorForText(strings.TrimSpace(entity.Name+" "+entity.Description), vectorDim),
-			Content:    firstNonEmpty(entity.Name, entity.ID),
-			NodeType:   firstNonEmpty(entity.Type, "entity"),
-			Properties: properties,
-		})
+		if !merged {
+			// A merged-away name only adds mentions to the entity it was merged into
+			nodes = append(nodes, &graph.GraphNode{
+				ID:         entityID,
+				Vector:     lexicalVectorForText(strings.TrimSpace(entity.Name+" "+entity.Description), vectorDim),
+				Content:    firstNonEmpty(entity.Name, entity.ID),
+				NodeType:   firstNonEmpty(entity.Type, "entity"),
+				Properties: properties,
+			})
+		}
 
 		for _, chunkID := range entity.ChunkIDs {
 			if chunkID == "" {
 				continue
 			}
 			edges = append(edges, &graph.GraphEdge{
-				ID:         fmt.Sprintf("edge:mention:%s:%s", chunkID, entityID),
+				ID:         graphMentionEdgeID(chunkID, entityID),
 				FromNodeID: chunkID,
 				ToNodeID:   entityID,
 				EdgeType:   "mentions",
@@ -726,16 +751,27 @@ func (t *GraphRAGToolbox) UpsertRelations(ctx context.Context, req ToolUpsertRel
 		return nil, fmt.Errorf("init graph schema: %w", err)
 	}
 
+	endpoints := make([]string, 0, 2*len(req.Relations))
+	for _, rel := range req.Relations {
+		endpoints = append(endpoints, resolveEntityNodeID("", rel.From), resolveEntityNodeID("", rel.To))
+	}
+	aliases, err := t.db.entityAliases(ctx, endpoints)
+	if err != nil {
+		return nil, err
+	}
+
 	edges := make([]*graph.GraphEdge, 0, len(req.Relations))
 	edgeIDs := make([]string, 0, len(req.Relations))
 	for i, rel := range req.Relations {
 		fromID := resolveEntityNodeID("", rel.From)
+		fromID = firstNonEmpty(aliases[fromID], fromID)
 		toID := resolveEntityNodeID("", rel.To)
+		toID = firstNonEmpty(aliases[toID], toID)
 		if fromID == "" || toID == "" {
 			continue
 		}
 		edgeType := firstNonEmpty(rel.Type, "related_to")
-		edgeID := fmt.Sprintf("edge:relation:%s:%s:%s:%d", fromID, toID, edgeType, i)
+		edgeID := toolRelationEdgeID(fromID, toID, edgeType, i)
 		edgeIDs = append(edgeIDs, edgeID)
 
 		properties := map[string]interface{}{}
@@ -861,9 +897,18 @@ func (t *GraphRAGToolbox) SearchChunksByEntities(ctx context.Context, req ToolSe
 		req.MaxHops = 1
 	}
 
-	scoreMap := make(map[string]float64)
+	requested := make([]string, 0, len(req.EntityNames))
 	for _, entityName := range req.EntityNames {
-		entityID := resolveEntityNodeID("", entityName)
+		requested = append(requested, resolveEntityNodeID("", entityName))
+	}
+	aliases, err := t.db.entityAliases(ctx, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	scoreMap := make(map[string]float64)
+	for _, entityID := range requested {
+		entityID = firstNonEmpty(aliases[entityID], entityID)
 		neighbors, err := t.db.graph.Neighbors(ctx, entityID, graph.TraversalOptions{
 			MaxDepth:  req.MaxHops,
 			Direction: "both",
@@ -1472,6 +1517,10 @@ func toolEnumSchema(description string, values ...string) map[string]any {
 	}
 }
 
+func toolChunkerSchema() map[string]any {
+	return toolEnumSchema("Optional chunking strategy: words (default), recursive separators, markdown headings, sentences, token windows or code declarations.", chunking.Strategies()...)
+}
+
 func toolMapSchema(description string) map[string]any {
 	return map[string]any{
 		"type":                 "object",
@@ -7,14 +7,21 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/liliang-cn/cortexdb/v2/pkg/chunking"
 	"github.com/liliang-cn/cortexdb/v2/pkg/core"
 )
 
+// ErrConflict is returned when a conditional knowledge or memory write
+// (if_match / if_not_exists) fails. It is the same value as core.ErrConflict.
+var ErrConflict = core.ErrConflict
+
 type knowledgeIngestResult struct {
-	documentNodeID  string
-	entityNodeIDs   []string
-	relationEdgeIDs []string
-	collection      string
+	documentNodeID     string
+	entityNodeIDs      []string
+	addedEntityNodeIDs []string
+	relationEdgeIDs    []string
+	collection         string
+	chunks             ChunkChanges
 }
 
 // SaveKnowledge stores or replaces a knowledge item and its retrieval artifacts.
@@ -25,27 +32,57 @@ func (db *DB) SaveKnowledge(ctx context.Context, req KnowledgeSaveRequest) (*Kno
 	if strings.TrimSpace(req.Content) == "" {
 		return nil, ErrEmptyText
 	}
+	if req.Chunker != "" {
+		if _, err := chunking.New(req.Chunker, req.ChunkSize, req.ChunkOverlap); err != nil {
+			return nil, err
+		}
+	}
 
 	existing, err := db.store.GetDocument(ctx, req.KnowledgeID)
 	if err != nil && !errors.Is(err, core.ErrNotFound) {
 		return nil, fmt.Errorf("get existing knowledge: %w", err)
 	}
+
+	metadata := cloneStringMap(req.Metadata)
+	version := 1
 	if existing != nil {
-		if err := db.cleanupKnowledgeArtifacts(ctx, req.KnowledgeID); err != nil {
-			return nil, err
+		version = existing.Version + 1
+	}
+
+	cond := core.WriteCondition{IfMatch: int64(req.IfMatch), IfNotExists: req.IfNotExists}
+	// Lexical chunks are rebuilt from scratch, so the new version is written
+	// first: that archives the stored one while its chunks still exist
+	rebuildLexical := existing != nil && !db.HasEmbedder()
+	if !cond.IsZero() || rebuildLexical {
+		claimed, err := db.claimKnowledgeVersion(ctx, &core.Document{
+			ID:        req.KnowledgeID,
+			Title:     req.Title,
+			Content:   req.Content,
+			SourceURL: req.SourceURL,
+			Author:    req.Author,
+			Metadata:  stringMapToAnyMap(metadata),
+		}, existing, cond)
+		if err != nil {
+			return nil, fmt.Errorf("save knowledge: %w", err)
 		}
+		version = claimed
 	}
 
-	metadata := cloneStringMap(req.Metadata)
-	ingest, err := db.ingestKnowledgeContent(ctx, req.KnowledgeID, req.Title, req.Content, req.Collection, req.ChunkSize, req.ChunkOverlap, metadata, req.Entities, req.Relations)
-	if err != nil {
-		return nil, err
+	removed := 0
+	if rebuildLexical {
+		// Embedded chunks are diffed by InsertGraphDocument; lexical ones
+		// are cheap to rebuild
+		if removed, err = db.cleanupKnowledgeArtifacts(ctx, req.KnowledgeID, false); err != nil {
+			return nil, db.releaseKnowledgeClaim(ctx, req.KnowledgeID, cond, version, err)
+		}
 	}
 
-	version := 1
-	if existing != nil {
-		version = existing.Version + 1
+	ingest, err := db.ingestKnowledgeContent(ctx, req.KnowledgeID, req.Title, req.Content, req.Collection, req.ChunkSize, req.ChunkOverlap, req.Chunker, metadata, req.Entities, req.Relations)
+	if err != nil {
+		return nil, db.releaseKnowledgeClaim(ctx, req.KnowledgeID, cond, version, err)
 	}
+	ingest.chunks.Removed += removed
+
 	if err := db.upsertKnowledgeDocumentRecord(ctx, &core.Document{
 		ID:        req.KnowledgeID,
 		Title:     req.Title,
@@ -64,10 +101,12 @@ func (db *DB) SaveKnowledge(ctx context.Context, req KnowledgeSaveRequest) (*Kno
 	}
 
 	return &KnowledgeSaveResponse{
-		Knowledge:       *record,
-		DocumentNodeID:  ingest.documentNodeID,
-		EntityNodeIDs:   uniqueSortedStrings(ingest.entityNodeIDs),
-		RelationEdgeIDs: uniqueSortedStrings(ingest.relationEdgeIDs),
+		Knowledge:          *record,
+		DocumentNodeID:     ingest.documentNodeID,
+		EntityNodeIDs:      uniqueSortedStrings(ingest.entityNodeIDs),
+		AddedEntityNodeIDs: uniqueSortedStrings(ingest.addedEntityNodeIDs),
+		RelationEdgeIDs:    uniqueSortedStrings(ingest.relationEdgeIDs),
+		Chunks:             ingest.chunks,
 	}, nil
 }
 
@@ -122,17 +161,48 @@ func (db *DB) UpdateKnowledge(ctx context.Context, req KnowledgeUpdateRequest) (
 	if req.ChunkOverlap != nil {
 		chunkOverlap = *req.ChunkOverlap
 	}
+	chunker := ""
+	if req.Chunker != nil {
+		chunker = *req.Chunker
+		if _, err := chunking.New(chunker, chunkSize, chunkOverlap); err != nil {
+			return nil, err
+		}
+	}
 
+	version := existing.Version + 1
+	cond := core.WriteCondition{IfMatch: int64(req.IfMatch)}
 	replaceArtifacts := req.Content != nil || req.Title != nil || req.Collection != nil || req.Metadata != nil
+	// As in SaveKnowledge, archive the stored version before its lexical
+	// chunks are rebuilt
+	rebuildLexical := replaceArtifacts && !db.HasEmbedder()
+	if req.IfMatch != 0 || rebuildLexical {
+		claimed, err := db.claimKnowledgeVersion(ctx, &core.Document{
+			ID:        req.KnowledgeID,
+			Title:     title,
+			Content:   content,
+			SourceURL: sourceURL,
+			Author:    author,
+			Metadata:  stringMapToAnyMap(metadata),
+		}, existing, cond)
+		if err != nil {
+			return nil, fmt.Errorf("update knowledge: %w", err)
+		}
+		version = claimed
+	}
+
 	ingest := &knowledgeIngestResult{}
 	if replaceArtifacts {
-		if err := db.cleanupKnowledgeArtifacts(ctx, req.KnowledgeID); err != nil {
-			return nil, err
+		removed := 0
+		if rebuildLexical {
+			if removed, err = db.cleanupKnowledgeArtifacts(ctx, req.KnowledgeID, false); err != nil {
+				return nil, db.releaseKnowledgeClaim(ctx, req.KnowledgeID, cond, version, err)
+			}
 		}
-		ingest, err = db.ingestKnowledgeContent(ctx, req.KnowledgeID, title, content, collection, chunkSize, chunkOverlap, metadata, req.Entities, req.Relations)
+		ingest, err = db.ingestKnowledgeContent(ctx, req.KnowledgeID, title, content, collection, chunkSize, chunkOverlap, chunker, metadata, req.Entities, req.Relations)
 		if err != nil {
-			return nil, err
+			return nil, db.releaseKnowledgeClaim(ctx, req.KnowledgeID, cond, version, err)
 		}
+		ingest.chunks.Removed += removed
 	} else if len(req.Entities) > 0 || len(req.Relations) > 0 {
 		toolbox := db.GraphRAGTools()
 		if len(req.Entities) > 0 {
@@ -141,10 +211,11 @@ func (db *DB) UpdateKnowledge(ctx context.Context, req KnowledgeUpdateRequest) (
 				Entities:   req.Entities,
 			})
 			if err != nil {
-				return nil, err
+				return nil, db.releaseKnowledgeClaim(ctx, req.KnowledgeID, cond, version, err)
 			}
 			if entityResp != nil {
 				ingest.entityNodeIDs = append(ingest.entityNodeIDs, entityResp.EntityNodeIDs...)
+				ingest.addedEntityNodeIDs = append(ingest.addedEntityNodeIDs, entityResp.EntityNodeIDs...)
 			}
 		}
 		if len(req.Relations) > 0 {
@@ -153,7 +224,7 @@ func (db *DB) UpdateKnowledge(ctx context.Context, req KnowledgeUpdateRequest) (
 				Relations:  req.Relations,
 			})
 			if err != nil {
-				return nil, err
+				return nil, db.releaseKnowledgeClaim(ctx, req.KnowledgeID, cond, version, err)
 			}
 			if relResp != nil {
 				ingest.relationEdgeIDs = append(ingest.relationEdgeIDs, relResp.EdgeIDs...)
@@ -166,7 +237,7 @@ func (db *DB) UpdateKnowledge(ctx context.Context, req KnowledgeUpdateRequest) (
 		Title:     title,
 		Content:   content,
 		SourceURL: sourceURL,
-		Version:   existing.Version + 1,
+		Version:   version,
 		Author:    author,
 		Metadata:  stringMapToAnyMap(metadata),
 	}); err != nil {
@@ -177,12 +248,26 @@ func (db *DB) UpdateKnowledge(ctx context.Context, req KnowledgeUpdateRequest) (
 	if err != nil {
 		return nil, err
 	}
+	if !replaceArtifacts {
+		ingest.chunks.Unchanged = len(record.ChunkIDs)
+		chunks := make([]*core.Embedding, len(record.ChunkIDs))
+		for i, id := range record.ChunkIDs {
+			chunks[i] = &core.Embedding{ID: id}
+		}
+		mentioned, err := db.chunkEntities(ctx, chunks)
+		if err != nil {
+			return nil, err
+		}
+		ingest.entityNodeIDs = append(ingest.entityNodeIDs, mentioned...)
+	}
 
 	return &KnowledgeSaveResponse{
-		Knowledge:       *record,
-		DocumentNodeID:  ingest.documentNodeID,
-		EntityNodeIDs:   uniqueSortedStrings(ingest.entityNodeIDs),
-		RelationEdgeIDs: uniqueSortedStrings(ingest.relationEdgeIDs),
+		Knowledge:          *record,
+		DocumentNodeID:     ingest.documentNodeID,
+		EntityNodeIDs:      uniqueSortedStrings(ingest.entityNodeIDs),
+		AddedEntityNodeIDs: uniqueSortedStrings(ingest.addedEntityNodeIDs),
+		RelationEdgeIDs:    uniqueSortedStrings(ingest.relationEdgeIDs),
+		Chunks:             ingest.chunks,
 	}, nil
 }
 
@@ -264,7 +349,7 @@ func (db *DB) DeleteKnowledge(ctx context.Context, req KnowledgeDeleteRequest) (
 	if _, err := db.store.GetDocument(ctx, req.KnowledgeID); err != nil {
 		return nil, err
 	}
-	if err := db.cleanupKnowledgeArtifacts(ctx, req.KnowledgeID); err != nil {
+	if _, err := db.cleanupKnowledgeArtifacts(ctx, req.KnowledgeID, true); err != nil {
 		return nil, err
 	}
 	return &KnowledgeDeleteResponse{KnowledgeID: req.KnowledgeID, Deleted: true}, nil
@@ -295,7 +380,7 @@ func (t *GraphRAGToolbox) DeleteKnowledge(ctx context.Context, req KnowledgeDele
 	return t.db.DeleteKnowledge(ctx, req)
 }
 
-func (db *DB) ingestKnowledgeContent(ctx context.Context, knowledgeID, title, content, collection string, chunkSize, chunkOverlap int, metadata map[string]string, entities []ToolEntityInput, relations []ToolRelationInput) (*knowledgeIngestResult, error) {
+func (db *DB) ingestKnowledgeContent(ctx context.Context, knowledgeID, title, content, collection string, chunkSize, chunkOverlap int, chunker string, metadata map[string]string, entities []ToolEntityInput, relations []ToolRelationInput) (*knowledgeIngestResult, error) {
 	doc := GraphRAGDocument{
 		ID:       knowledgeID,
 		Title:    title,
@@ -306,17 +391,27 @@ func (db *DB) ingestKnowledgeContent(ctx context.Context, knowledgeID, title, co
 	result := &knowledgeIngestResult{}
 
 	if db.HasEmbedder() {
-		ingest, err := db.InsertGraphDocument(ctx, doc, GraphRAGIngestOptions{
+		opts := GraphRAGIngestOptions{
 			Collection:   collection,
 			ChunkSize:    chunkSize,
 			ChunkOverlap: chunkOverlap,
-		})
+		}
+		if chunker != "" {
+			c, err := chunking.New(chunker, chunkSize, chunkOverlap)
+			if err != nil {
+				return nil, err
+			}
+			opts.Chunker = c
+		}
+		ingest, err := db.InsertGraphDocument(ctx, doc, opts)
 		if err != nil {
 			return nil, err
 		}
 		if ingest != nil {
 			result.documentNodeID = ingest.DocumentNodeID
 			result.entityNodeIDs = append(result.entityNodeIDs, ingest.EntityNodeIDs...)
+			result.addedEntityNodeIDs = append(result.addedEntityNodeIDs, ingest.AddedEntityNodeIDs...)
+			result.chunks = ingest.Chunks
 		}
 	} else {
 		ingest, err := toolbox.IngestDocument(ctx, ToolIngestDocumentRequest{
@@ -326,6 +421,7 @@ func (db *DB) ingestKnowledgeContent(ctx context.Context, knowledgeID, title, co
 			Collection:   collection,
 			ChunkSize:    chunkSize,
 			ChunkOverlap: chunkOverlap,
+			Chunker:      chunker,
 			Metadata:     cloneStringMap(metadata),
 		})
 		if err != nil {
@@ -334,6 +430,7 @@ func (db *DB) ingestKnowledgeContent(ctx context.Context, knowledgeID, title, co
 		if ingest != nil {
 			result.documentNodeID = ingest.DocumentNodeID
 			result.collection = ingest.Collection
+			result.chunks.Added = len(ingest.ChunkNodeIDs)
 		}
 	}
 
@@ -357,6 +454,7 @@ func (db *DB) ingestKnowledgeContent(ctx context.Context, knowledgeID, title, co
 		}
 		if entityResp != nil {
 			result.entityNodeIDs = append(result.entityNodeIDs, entityResp.EntityNodeIDs...)
+			result.addedEntityNodeIDs = append(result.addedEntityNodeIDs, entityResp.EntityNodeIDs...)
 		}
 	}
 	if len(relations) > 0 {
@@ -375,14 +473,18 @@ func (db *DB) ingestKnowledgeContent(ctx context.Context, knowledgeID, title, co
 	return result, nil
 }
 
-func (db *DB) cleanupKnowledgeArtifacts(ctx context.Context, knowledgeID string) error {
+// cleanupKnowledgeArtifacts deletes a knowledge item's chunks and graph
+// nodes, and its document record if deleteRecord is set, returning the
+// number of chunks deleted. Keeping the record keeps the version the
+// rebuilt chunks belong to.
+func (db *DB) cleanupKnowledgeArtifacts(ctx context.Context, knowledgeID string, deleteRecord bool) (int, error) {
 	if err := db.graph.InitGraphSchema(ctx); err != nil {
-		return fmt.Errorf("init graph schema: %w", err)
+		return 0, fmt.Errorf("init graph schema: %w", err)
 	}
 
 	chunks, err := db.store.GetByDocID(ctx, knowledgeID)
 	if err != nil && !errors.Is(err, core.ErrNotFound) {
-		return fmt.Errorf("get knowledge chunks: %w", err)
+		return 0, fmt.Errorf("get knowledge chunks: %w", err)
 	}
 
 	nodeIDs := make([]string, 0, len(chunks)+1)
@@ -391,12 +493,51 @@ func (db *DB) cleanupKnowledgeArtifacts(ctx context.Context, knowledgeID string)
 		nodeIDs = append(nodeIDs, chunk.ID)
 	}
 	if _, err := db.graph.DeleteNodesBatch(ctx, nodeIDs); err != nil {
-		return fmt.Errorf("delete graph nodes: %w", err)
+		return 0, fmt.Errorf("delete graph nodes: %w", err)
+	}
+	if deleteRecord {
+		// Archives the document with its chunks before deleting both
+		if err := db.store.DeleteDocument(ctx, knowledgeID); err != nil {
+			return 0, fmt.Errorf("delete knowledge document: %w", err)
+		}
+		return len(chunks), nil
 	}
-	if err := db.store.DeleteDocument(ctx, knowledgeID); err != nil {
-		return fmt.Errorf("delete knowledge document: %w", err)
+	if err := db.store.DeleteBatch(ctx, nodeIDs[1:]); err != nil {
+		return 0, fmt.Errorf("delete knowledge chunks: %w", err)
 	}
-	return nil
+	return len(chunks), nil
+}
+
+// claimKnowledgeVersion writes the document record under cond before any
+// retrieval artifacts are touched, so concurrent conditional writers fail fast
+// with core.ErrConflict instead of interleaving chunk rewrites.
+func (db *DB) claimKnowledgeVersion(ctx context.Context, doc *core.Document, existing *core.Document, cond core.WriteCondition) (int, error) {
+	if existing != nil {
+		doc.ACL = existing.ACL
+	}
+	if err := db.store.UpsertDocumentWithCondition(ctx, doc, cond); err != nil {
+		return 0, err
+	}
+	return doc.Version, nil
+}
+
+// releaseKnowledgeClaim undoes the write claimKnowledgeVersion made for cond
+// after the ingest that followed it failed, so the same conditional write
+// can be retried. It returns err, noting any failure to release.
+func (db *DB) releaseKnowledgeClaim(ctx context.Context, knowledgeID string, cond core.WriteCondition, version int, err error) error {
+	var releaseErr error
+	switch {
+	case cond.IfNotExists:
+		releaseErr = db.store.DeleteDocument(ctx, knowledgeID)
+	case cond.IfMatch != 0:
+		releaseErr = db.store.RevertDocumentWrite(ctx, knowledgeID, version)
+	default:
+		return err
+	}
+	if releaseErr != nil {
+		return fmt.Errorf("%w (release knowledge claim: %v)", err, releaseErr)
+	}
+	return err
 }
 
 func (db *DB) upsertKnowledgeDocumentRecord(ctx context.Context, doc *core.Document) error {
@@ -432,8 +573,8 @@ func (db *DB) loadKnowledgeRecord(ctx context.Context, knowledgeID string) (*Kno
 	if err != nil {
 		return nil, err
 	}
-	chunks, err := db.store.GetByDocID(ctx, knowledgeID)
-	if err != nil && !errors.Is(err, core.ErrNotFound) {
+	chunks, err := db.storedGraphRAGChunks(ctx, knowledgeID)
+	if err != nil {
 		return nil, err
 	}
 
@@ -466,6 +607,7 @@ func (db *DB) loadKnowledgeRecord(ctx context.Context, knowledgeID string) (*Kno
 		Metadata:   anyMapToStringMap(doc.Metadata),
 		ChunkIDs:   chunkIDs,
 		Entities:   uniqueSortedStrings(sortedKeysFromSet(entitySet)),
+		Version:    doc.Version,
 		CreatedAt:  doc.CreatedAt,
 		UpdatedAt:  doc.UpdatedAt,
 	}, nil
@@ -479,11 +621,7 @@ func (db *DB) knowledgeCollection(ctx context.Context, knowledgeID string) (stri
 	if len(chunks) == 0 {
 		return defaultGraphRAGCollection, nil
 	}
-	firstChunk, err := db.store.GetByID(ctx, chunks[0].ID)
-	if err != nil {
-		return "", err
-	}
-	return firstChunk.Collection, nil
+	return chunks[0].Collection, nil
 }
 
 func (db *DB) aggregateKnowledgeHits(ctx context.Context, chunks []GraphRAGChunkResult) ([]KnowledgeSearchHit, error) {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -231,3 +232,159 @@ func TestMemoryDBAPIWithEmbedder(t *testing.T) {
 		t.Fatalf("expected not found after delete, got %v", err)
 	}
 }
+
+func TestKnowledgeAndMemoryConditionalWrites(t *testing.T) {
+	dbPath := fmt.Sprintf("test_conditional_%d.db", time.Now().UnixNano())
+	defer func() { _ = os.Remove(dbPath) }()
+
+	db, err := Open(DefaultConfig(dbPath))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+
+	saveResp, err := db.SaveKnowledge(ctx, KnowledgeSaveRequest{
+		KnowledgeID: "kc-1",
+		Content:     "Alice works at Acme.",
+		IfNotExists: true,
+	})
+	if err != nil {
+		t.Fatalf("create knowledge: %v", err)
+	}
+	if saveResp.Knowledge.Version != 1 {
+		t.Fatalf("expected version 1, got %d", saveResp.Knowledge.Version)
+	}
+
+	if _, err := db.SaveKnowledge(ctx, KnowledgeSaveRequest{KnowledgeID: "kc-1", Content: "dup", IfNotExists: true}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict on duplicate create, got %v", err)
+	}
+
+	title := "Alice"
+	updateResp, err := db.UpdateKnowledge(ctx, KnowledgeUpdateRequest{KnowledgeID: "kc-1", Title: &title, IfMatch: 1})
+	if err != nil {
+		t.Fatalf("conditional update: %v", err)
+	}
+	if updateResp.Knowledge.Version != 2 {
+		t.Fatalf("expected version 2, got %d", updateResp.Knowledge.Version)
+	}
+
+	stale := "stale writer"
+	if _, err := db.UpdateKnowledge(ctx, KnowledgeUpdateRequest{KnowledgeID: "kc-1", Title: &stale, IfMatch: 1}); !errors.Is(err, core.ErrConflict) {
+		t.Fatalf("expected conflict for stale version, got %v", err)
+	}
+	getResp, err := db.GetKnowledge(ctx, KnowledgeGetRequest{KnowledgeID: "kc-1"})
+	if err != nil {
+		t.Fatalf("get knowledge: %v", err)
+	}
+	if getResp.Knowledge.Title != "Alice" {
+		t.Fatalf("stale write should not apply, title is %q", getResp.Knowledge.Title)
+	}
+
+	memResp, err := db.SaveMemory(ctx, MemorySaveRequest{MemoryID: "mc-1", Content: "likes tea", IfNotExists: true})
+	if err != nil {
+		t.Fatalf("create memory: %v", err)
+	}
+	if memResp.Memory.Revision != 1 {
+		t.Fatalf("expected memory revision 1, got %d", memResp.Memory.Revision)
+	}
+	if _, err := db.SaveMemory(ctx, MemorySaveRequest{MemoryID: "mc-1", Content: "likes coffee", IfNotExists: true}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict on duplicate memory, got %v", err)
+	}
+
+	content := "likes green tea"
+	memUpdate, err := db.UpdateMemory(ctx, MemoryUpdateRequest{MemoryID: "mc-1", Content: &content, IfMatch: 1})
+	if err != nil {
+		t.Fatalf("conditional memory update: %v", err)
+	}
+	if memUpdate.Memory.Revision != 2 {
+		t.Fatalf("expected memory revision 2, got %d", memUpdate.Memory.Revision)
+	}
+	if _, err := db.UpdateMemory(ctx, MemoryUpdateRequest{MemoryID: "mc-1", Content: &content, IfMatch: 1}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict for stale memory revision, got %v", err)
+	}
+}
+
+func TestKnowledgeClaimReleasedOnIngestFailure(t *testing.T) {
+	dbPath := fmt.Sprintf("test_claim_release_%d.db", time.Now().UnixNano())
+	defer func() { _ = os.Remove(dbPath) }()
+
+	// The second ingest fails to embed
+	embedder := &flakyEmbedder{keywordEmbedder: newKeywordEmbedder("alice", "acme", "graph"), failOn: 2}
+	db, err := Open(DefaultConfig(dbPath), WithEmbedder(embedder))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	if _, err := db.SaveKnowledge(ctx, KnowledgeSaveRequest{KnowledgeID: "kr-1", Content: "alice acme", IfNotExists: true}); err != nil {
+		t.Fatalf("create knowledge: %v", err)
+	}
+	if _, err := db.SaveKnowledge(ctx, KnowledgeSaveRequest{KnowledgeID: "kr-1", Content: "alice graph", IfMatch: 1}); !errors.Is(err, ErrEmbeddingFailed) {
+		t.Fatalf("expected the ingest to fail, got %v", err)
+	}
+
+	getResp, err := db.GetKnowledge(ctx, KnowledgeGetRequest{KnowledgeID: "kr-1"})
+	if err != nil {
+		t.Fatalf("get knowledge: %v", err)
+	}
+	if getResp.Knowledge.Version != 1 || getResp.Knowledge.Content != "alice acme" {
+		t.Fatalf("expected the failed write rolled back, got version %d %q", getResp.Knowledge.Version, getResp.Knowledge.Content)
+	}
+
+	saveResp, err := db.SaveKnowledge(ctx, KnowledgeSaveRequest{KnowledgeID: "kr-1", Content: "alice graph", IfMatch: 1})
+	if err != nil {
+		t.Fatalf("retry with the same version: %v", err)
+	}
+	if saveResp.Knowledge.Version != 2 {
+		t.Fatalf("expected version 2, got %d", saveResp.Knowledge.Version)
+	}
+	versions, err := db.store.ListDocumentVersions(ctx, "kr-1")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("expected two versions in the history, got %d, %v", len(versions), err)
+	}
+}
+
+func TestKnowledgeHistoryWithoutEmbedder(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(DefaultConfig(filepath.Join(t.TempDir(), "history.db")))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	contents := []string{"Alice writes the parser.", "Bob reviews the parser.", "Carol ships the parser.", "Dave maintains the parser."}
+	for i, content := range contents[:2] {
+		if _, err := db.SaveKnowledge(ctx, KnowledgeSaveRequest{KnowledgeID: "k", Content: content}); err != nil {
+			t.Fatalf("save %d: %v", i+1, err)
+		}
+	}
+	if _, err := db.UpdateKnowledge(ctx, KnowledgeUpdateRequest{KnowledgeID: "k", Content: &contents[2]}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := db.SaveKnowledge(ctx, KnowledgeSaveRequest{KnowledgeID: "k", Content: contents[3], IfMatch: 3}); err != nil {
+		t.Fatalf("conditional save: %v", err)
+	}
+
+	versions, err := db.store.ListDocumentVersions(ctx, "k")
+	if err != nil {
+		t.Fatalf("ListDocumentVersions: %v", err)
+	}
+	if len(versions) != len(contents) {
+		t.Fatalf("Expected %d versions, got %d", len(contents), len(versions))
+	}
+	for i, v := range versions {
+		if v.Version != i+1 || v.Content != contents[i] {
+			t.Errorf("Version %d: expected %q, got version %d %q", i+1, contents[i], v.Version, v.Content)
+		}
+		version, err := db.store.GetDocumentVersion(ctx, "k", v.Version)
+		if err != nil {
+			t.Fatalf("GetDocumentVersion: %v", err)
+		}
+		if len(version.ChunkIDs) != 1 {
+			t.Errorf("Version %d: expected its chunk archived, got %v", v.Version, version.ChunkIDs)
+		}
+	}
+}
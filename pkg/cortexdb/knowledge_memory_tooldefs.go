@@ -14,11 +14,14 @@ func knowledgeMemoryToolDefinitions() []ToolDefinition {
 					"source_url":    toolStringSchema("Optional source URL."),
 					"author":        toolStringSchema("Optional author."),
 					"collection":    toolStringSchema("Optional chunk collection."),
-					"chunk_size":    toolIntegerSchema("Optional chunk size in words."),
-					"chunk_overlap": toolIntegerSchema("Optional chunk overlap in words."),
+					"chunk_size":    toolIntegerSchema("Optional chunk size: words for the words strategy, tokens for token, characters otherwise."),
+					"chunk_overlap": toolIntegerSchema("Optional chunk overlap, in the chunk size unit (sentences for the sentence strategy)."),
+					"chunker":       toolChunkerSchema(),
 					"metadata":      toolMapSchema("Optional metadata."),
 					"entities":      toolEntityArraySchema(),
 					"relations":     toolRelationArraySchema(),
+					"if_match":      toolIntegerSchema("Optional expected version; the write fails with a conflict if the stored version differs."),
+					"if_not_exists": toolBooleanSchema("Set true to fail with a conflict if the knowledge ID already exists."),
 				},
 			),
 		},
@@ -36,9 +39,11 @@ func knowledgeMemoryToolDefinitions() []ToolDefinition {
 					"collection":    toolStringSchema("Optional new chunk collection."),
 					"chunk_size":    toolIntegerSchema("Optional chunk size for refreshed content."),
 					"chunk_overlap": toolIntegerSchema("Optional chunk overlap for refreshed content."),
+					"chunker":       toolChunkerSchema(),
 					"metadata":      toolMapSchema("Optional replacement metadata."),
 					"entities":      toolEntityArraySchema(),
 					"relations":     toolRelationArraySchema(),
+					"if_match":      toolIntegerSchema("Optional expected version; the update fails with a conflict if the stored version differs."),
 				},
 			),
 		},
@@ -70,7 +75,7 @@ func knowledgeMemoryToolDefinitions() []ToolDefinition {
 					"entity_names":       toolStringArraySchema("Optional entities from structured planning."),
 					"keywords":           toolStringArraySchema("LLM-generated keyword bank derived from the goal."),
 					"alternate_queries":  toolStringArraySchema("Alternate phrasings generated from the same goal."),
-					"retrieval_mode":     toolEnumSchema("Preferred retrieval strategy.", RetrievalModeAuto, RetrievalModeLexical, RetrievalModeGraph),
+					"retrieval_mode":     toolEnumSchema("Preferred retrieval strategy. Global answers from community reports when they have been built.", RetrievalModeAuto, RetrievalModeLexical, RetrievalModeGraph, RetrievalModeGlobal),
 					"disable_graph":      toolBooleanSchema("Legacy alias. Set true to force lexical-only retrieval."),
 				},
 			),
@@ -91,16 +96,18 @@ func knowledgeMemoryToolDefinitions() []ToolDefinition {
 			InputSchema: toolObjectSchema(
 				[]string{"memory_id", "content"},
 				map[string]any{
-					"memory_id":   toolStringSchema("Stable memory ID."),
-					"user_id":     toolStringSchema("Optional user ID for user-scoped memory."),
-					"session_id":  toolStringSchema("Optional session ID for session-scoped memory."),
-					"scope":       toolEnumSchema("Memory scope.", MemoryScopeGlobal, MemoryScopeUser, MemoryScopeSession),
-					"namespace":   toolStringSchema("Optional memory namespace."),
-					"role":        toolStringSchema("Optional message role. Defaults to memory."),
-					"content":     toolStringSchema("Memory text content."),
-					"metadata":    toolMapSchema("Optional metadata."),
-					"importance":  toolNumberSchema("Optional importance score."),
-					"ttl_seconds": toolIntegerSchema("Optional TTL in seconds."),
+					"memory_id":     toolStringSchema("Stable memory ID."),
+					"user_id":       toolStringSchema("Optional user ID for user-scoped memory."),
+					"session_id":    toolStringSchema("Optional session ID for session-scoped memory."),
+					"scope":         toolEnumSchema("Memory scope.", MemoryScopeGlobal, MemoryScopeUser, MemoryScopeSession),
+					"namespace":     toolStringSchema("Optional memory namespace."),
+					"role":          toolStringSchema("Optional message role. Defaults to memory."),
+					"content":       toolStringSchema("Memory text content."),
+					"metadata":      toolMapSchema("Optional metadata."),
+					"importance":    toolNumberSchema("Optional importance score."),
+					"ttl_seconds":   toolIntegerSchema("Optional TTL in seconds."),
+					"if_match":      toolIntegerSchema("Optional expected revision; the write fails with a conflict if the stored revision differs."),
+					"if_not_exists": toolBooleanSchema("Set true to fail with a conflict if the memory ID already exists."),
 				},
 			),
 		},
@@ -115,6 +122,7 @@ func knowledgeMemoryToolDefinitions() []ToolDefinition {
 					"metadata":    toolMapSchema("Optional metadata fields to merge."),
 					"importance":  toolNumberSchema("Optional updated importance score."),
 					"ttl_seconds": toolIntegerSchema("Optional updated TTL in seconds."),
+					"if_match":    toolIntegerSchema("Optional expected revision; the update fails with a conflict if the stored revision differs."),
 				},
 			),
 		},
@@ -25,6 +25,7 @@ type KnowledgeRecord struct {
 	Metadata   map[string]string `json:"metadata,omitempty"`
 	ChunkIDs   []string          `json:"chunk_ids,omitempty"`
 	Entities   []string          `json:"entities,omitempty"`
+	Version    int               `json:"version,omitempty"`
 	CreatedAt  time.Time         `json:"created_at,omitempty"`
 	UpdatedAt  time.Time         `json:"updated_at,omitempty"`
 }
@@ -39,17 +40,22 @@ type KnowledgeSaveRequest struct {
 	Collection   string              `json:"collection,omitempty"`
 	ChunkSize    int                 `json:"chunk_size,omitempty"`
 	ChunkOverlap int                 `json:"chunk_overlap,omitempty"`
+	Chunker      string              `json:"chunker,omitempty"` // Chunking strategy, see chunking.New (default: words)
 	Metadata     map[string]string   `json:"metadata,omitempty"`
 	Entities     []ToolEntityInput   `json:"entities,omitempty"`
 	Relations    []ToolRelationInput `json:"relations,omitempty"`
+	IfMatch      int                 `json:"if_match,omitempty"`      // Replace only if the stored version matches
+	IfNotExists  bool                `json:"if_not_exists,omitempty"` // Create only; fail if the ID is taken
 }
 
 // KnowledgeSaveResponse summarizes a knowledge write.
 type KnowledgeSaveResponse struct {
-	Knowledge       KnowledgeRecord `json:"knowledge"`
-	DocumentNodeID  string          `json:"document_node_id,omitempty"`
-	EntityNodeIDs   []string        `json:"entity_node_ids,omitempty"`
-	RelationEdgeIDs []string        `json:"relation_edge_ids,omitempty"`
+	Knowledge          KnowledgeRecord `json:"knowledge"`
+	DocumentNodeID     string          `json:"document_node_id,omitempty"`
+	EntityNodeIDs      []string        `json:"entity_node_ids,omitempty"`       // Entities the knowledge item mentions after the write
+	AddedEntityNodeIDs []string        `json:"added_entity_node_ids,omitempty"` // Entities extracted or upserted by the write
+	RelationEdgeIDs    []string        `json:"relation_edge_ids,omitempty"`
+	Chunks             ChunkChanges    `json:"chunks"` // Chunks added, kept unchanged and removed by the write
 }
 
 // KnowledgeUpdateRequest updates a durable knowledge item.
@@ -62,9 +68,11 @@ type KnowledgeUpdateRequest struct {
 	Collection   *string             `json:"collection,omitempty"`
 	ChunkSize    *int                `json:"chunk_size,omitempty"`
 	ChunkOverlap *int                `json:"chunk_overlap,omitempty"`
+	Chunker      *string             `json:"chunker,omitempty"`
 	Metadata     map[string]string   `json:"metadata,omitempty"`
 	Entities     []ToolEntityInput   `json:"entities,omitempty"`
 	Relations    []ToolRelationInput `json:"relations,omitempty"`
+	IfMatch      int                 `json:"if_match,omitempty"` // Update only if the stored version matches
 }
 
 // KnowledgeGetRequest fetches a knowledge item by ID.
@@ -141,21 +149,24 @@ type MemoryRecord struct {
 	Importance float64        `json:"importance,omitempty"`
 	TTLSeconds int            `json:"ttl_seconds,omitempty"`
 	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`
+	Revision   int64          `json:"revision,omitempty"`
 	CreatedAt  time.Time      `json:"created_at,omitempty"`
 }
 
 // MemorySaveRequest stores a memory in a dedicated memory bucket.
 type MemorySaveRequest struct {
-	MemoryID   string         `json:"memory_id"`
-	UserID     string         `json:"user_id,omitempty"`
-	SessionID  string         `json:"session_id,omitempty"`
-	Scope      string         `json:"scope,omitempty"`
-	Namespace  string         `json:"namespace,omitempty"`
-	Role       string         `json:"role,omitempty"`
-	Content    string         `json:"content"`
-	Metadata   map[string]any `json:"metadata,omitempty"`
-	Importance float64        `json:"importance,omitempty"`
-	TTLSeconds int            `json:"ttl_seconds,omitempty"`
+	MemoryID    string         `json:"memory_id"`
+	UserID      string         `json:"user_id,omitempty"`
+	SessionID   string         `json:"session_id,omitempty"`
+	Scope       string         `json:"scope,omitempty"`
+	Namespace   string         `json:"namespace,omitempty"`
+	Role        string         `json:"role,omitempty"`
+	Content     string         `json:"content"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	Importance  float64        `json:"importance,omitempty"`
+	TTLSeconds  int            `json:"ttl_seconds,omitempty"`
+	IfMatch     int64          `json:"if_match,omitempty"`      // Replace only if the stored revision matches
+	IfNotExists bool           `json:"if_not_exists,omitempty"` // Create only; fail if the ID is taken
 }
 
 // MemorySaveResponse returns the stored memory.
@@ -170,6 +181,7 @@ type MemoryUpdateRequest struct {
 	Metadata   map[string]any `json:"metadata,omitempty"`
 	Importance *float64       `json:"importance,omitempty"`
 	TTLSeconds *int           `json:"ttl_seconds,omitempty"`
+	IfMatch    int64          `json:"if_match,omitempty"` // Update only if the stored revision matches
 }
 
 // MemoryGetRequest fetches a memory by ID.
@@ -0,0 +1,110 @@
+package cortexdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"text/template"
+)
+
+// chatClient sends chat completions to an OpenAI-compatible API for the
+// LLM-backed extractor and summarizer
+type chatClient struct {
+	model       string
+	temperature float64
+	jsonMode    bool
+	http        *httpEmbedder // Request and retry handling only
+	requests    atomic.Int64
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// newChatClient posts to config.BaseURL + /chat/completions with the
+// embedders' retry and timeout settings
+func newChatClient(config HTTPEmbedderConfig, temperature float64, jsonMode bool) (*chatClient, error) {
+	client, err := newHTTPEmbedder(config, "/chat/completions", 1)
+	if err != nil {
+		return nil, err
+	}
+	return &chatClient{model: config.Model, temperature: temperature, jsonMode: jsonMode, http: client}, nil
+}
+
+// complete sends a chat completion and returns the reply text
+func (c *chatClient) complete(ctx context.Context, messages []chatMessage) (string, error) {
+	req := map[string]any{
+		"model":       c.model,
+		"messages":    messages,
+		"temperature": c.temperature,
+	}
+	if c.jsonMode {
+		req["response_format"] = map[string]string{"type": "json_object"}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("cortexdb: encode chat request: %w", err)
+	}
+
+	c.requests.Add(1)
+	respBody, err := c.http.send(ctx, body)
+	if err != nil {
+		if err == ctx.Err() {
+			return "", err
+		}
+		return "", fmt.Errorf("cortexdb: chat request failed: %w", err)
+	}
+
+	var resp struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("cortexdb: decode chat response: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("cortexdb: chat response has no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// completeJSON sends a single prompt and decodes the reply into v,
+// repairing malformed JSON and asking the model once to fix what can't be
+// repaired
+func (c *chatClient) completeJSON(ctx context.Context, system, prompt string, v any) error {
+	messages := []chatMessage{{Role: "system", Content: system}, {Role: "user", Content: prompt}}
+	reply, err := c.complete(ctx, messages)
+	if err != nil {
+		return err
+	}
+	text := jsonPayload(reply)
+	if json.Unmarshal([]byte(text), v) == nil || json.Unmarshal([]byte(repairJSON(text)), v) == nil {
+		return nil
+	}
+
+	messages = append(messages,
+		chatMessage{Role: "assistant", Content: reply},
+		chatMessage{Role: "user", Content: extractionRepairPrompt})
+	if reply, err = c.complete(ctx, messages); err != nil {
+		return err
+	}
+	text = jsonPayload(reply)
+	if err := json.Unmarshal([]byte(text), v); err != nil {
+		if json.Unmarshal([]byte(repairJSON(text)), v) != nil {
+			return fmt.Errorf("cortexdb: model returned invalid JSON: %w", err)
+		}
+	}
+	return nil
+}
+
+func renderTemplate(t *template.Template, vars map[string]string) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("cortexdb: render %s template: %w", t.Name(), err)
+	}
+	return b.String(), nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"math"
 	"sort"
@@ -22,18 +21,25 @@ type memoryRow struct {
 
 // SaveMemory stores a memory record in a resolved memory bucket.
 func (db *DB) SaveMemory(ctx context.Context, req MemorySaveRequest) (*MemorySaveResponse, error) {
+	return db.saveMemory(ctx, db.store.GetDB(), req)
+}
+
+func (db *DB) saveMemory(ctx context.Context, q sqlQuerier, req MemorySaveRequest) (*MemorySaveResponse, error) {
 	if req.MemoryID == "" {
 		return nil, fmt.Errorf("memory_id is required")
 	}
 	if strings.TrimSpace(req.Content) == "" {
 		return nil, ErrEmptyText
 	}
+	if req.IfNotExists && req.IfMatch != 0 {
+		return nil, fmt.Errorf("if_not_exists cannot be combined with if_match")
+	}
 
 	scope, bucketID, err := resolveMemoryBucket(req.Scope, req.UserID, req.SessionID, req.Namespace)
 	if err != nil {
 		return nil, err
 	}
-	if err := db.ensureMemoryBucket(ctx, bucketID, req.UserID, scope, normalizeMemoryNamespace(req.Namespace)); err != nil {
+	if err := ensureMemoryBucket(ctx, q, bucketID, req.UserID, scope, normalizeMemoryNamespace(req.Namespace)); err != nil {
 		return nil, err
 	}
 
@@ -48,20 +54,41 @@ func (db *DB) SaveMemory(ctx context.Context, req MemorySaveRequest) (*MemorySav
 	}
 
 	role := firstNonEmpty(req.Role, defaultMemoryRole)
-	if _, err := db.store.GetDB().ExecContext(ctx, `
-		INSERT INTO messages (id, session_id, role, content, vector, metadata, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(id) DO UPDATE SET
-			session_id = excluded.session_id,
-			role = excluded.role,
-			content = excluded.content,
-			vector = excluded.vector,
-			metadata = excluded.metadata
-	`, req.MemoryID, bucketID, role, req.Content, vectorBytes, metadataJSON); err != nil {
+	var result sql.Result
+	switch {
+	case req.IfNotExists:
+		result, err = q.ExecContext(ctx, `
+			INSERT INTO messages (id, session_id, role, content, vector, metadata, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(id) DO NOTHING
+		`, req.MemoryID, bucketID, role, req.Content, vectorBytes, metadataJSON)
+	case req.IfMatch != 0:
+		result, err = q.ExecContext(ctx, `
+			UPDATE messages
+			SET session_id = ?, role = ?, content = ?, vector = ?, metadata = ?, revision = revision + 1
+			WHERE id = ? AND revision = ?
+		`, bucketID, role, req.Content, vectorBytes, metadataJSON, req.MemoryID, req.IfMatch)
+	default:
+		result, err = q.ExecContext(ctx, `
+			INSERT INTO messages (id, session_id, role, content, vector, metadata, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(id) DO UPDATE SET
+				session_id = excluded.session_id,
+				role = excluded.role,
+				content = excluded.content,
+				vector = excluded.vector,
+				metadata = excluded.metadata,
+				revision = messages.revision + 1
+		`, req.MemoryID, bucketID, role, req.Content, vectorBytes, metadataJSON)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("save memory: %w", err)
 	}
+	if err := checkMemoryWrite(ctx, q, result, req.MemoryID, req.IfMatch, req.IfNotExists); err != nil {
+		return nil, err
+	}
 
-	row, err := db.loadMemoryRow(ctx, req.MemoryID)
+	row, err := loadMemoryRow(ctx, q, req.MemoryID)
 	if err != nil {
 		return nil, err
 	}
@@ -70,11 +97,15 @@ func (db *DB) SaveMemory(ctx context.Context, req MemorySaveRequest) (*MemorySav
 
 // UpdateMemory updates a memory record and refreshes its vector when needed.
 func (db *DB) UpdateMemory(ctx context.Context, req MemoryUpdateRequest) (*MemorySaveResponse, error) {
+	return db.updateMemory(ctx, db.store.GetDB(), req)
+}
+
+func (db *DB) updateMemory(ctx context.Context, q sqlQuerier, req MemoryUpdateRequest) (*MemorySaveResponse, error) {
 	if req.MemoryID == "" {
 		return nil, fmt.Errorf("memory_id is required")
 	}
 
-	row, err := db.loadMemoryRow(ctx, req.MemoryID)
+	row, err := loadMemoryRow(ctx, q, req.MemoryID)
 	if err != nil {
 		return nil, err
 	}
@@ -112,15 +143,25 @@ func (db *DB) UpdateMemory(ctx context.Context, req MemoryUpdateRequest) (*Memor
 		return nil, fmt.Errorf("marshal memory metadata: %w", err)
 	}
 
-	if _, err := db.store.GetDB().ExecContext(ctx, `
+	query := `
 		UPDATE messages
-		SET content = ?, vector = ?, metadata = ?
+		SET content = ?, vector = ?, metadata = ?, revision = revision + 1
 		WHERE id = ?
-	`, content, vectorBytes, metadataJSON, req.MemoryID); err != nil {
+	`
+	args := []any{content, vectorBytes, metadataJSON, req.MemoryID}
+	if req.IfMatch != 0 {
+		query += " AND revision = ?"
+		args = append(args, req.IfMatch)
+	}
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
 		return nil, fmt.Errorf("update memory: %w", err)
 	}
+	if err := checkMemoryWrite(ctx, q, result, req.MemoryID, req.IfMatch, false); err != nil {
+		return nil, err
+	}
 
-	updated, err := db.loadMemoryRow(ctx, req.MemoryID)
+	updated, err := loadMemoryRow(ctx, q, req.MemoryID)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +170,7 @@ func (db *DB) UpdateMemory(ctx context.Context, req MemoryUpdateRequest) (*Memor
 
 // GetMemory fetches a memory record by ID.
 func (db *DB) GetMemory(ctx context.Context, req MemoryGetRequest) (*MemoryGetResponse, error) {
-	row, err := db.loadMemoryRow(ctx, req.MemoryID)
+	row, err := loadMemoryRow(ctx, db.store.GetDB(), req.MemoryID)
 	if err != nil {
 		return nil, err
 	}
@@ -180,18 +221,22 @@ func (db *DB) SearchMemory(ctx context.Context, req MemorySearchRequest) (*Memor
 
 // DeleteMemory removes a memory record by ID.
 func (db *DB) DeleteMemory(ctx context.Context, req MemoryDeleteRequest) (*MemoryDeleteResponse, error) {
+	return deleteMemory(ctx, db.store.GetDB(), req)
+}
+
+func deleteMemory(ctx context.Context, q sqlQuerier, req MemoryDeleteRequest) (*MemoryDeleteResponse, error) {
 	if req.MemoryID == "" {
 		return nil, fmt.Errorf("memory_id is required")
 	}
 
-	row, err := db.loadMemoryRow(ctx, req.MemoryID)
+	row, err := loadMemoryRow(ctx, q, req.MemoryID)
 	if err != nil {
 		return nil, err
 	}
-	if _, err := db.store.GetDB().ExecContext(ctx, `DELETE FROM messages WHERE id = ?`, req.MemoryID); err != nil {
+	if _, err := q.ExecContext(ctx, `DELETE FROM messages WHERE id = ?`, req.MemoryID); err != nil {
 		return nil, fmt.Errorf("delete memory: %w", err)
 	}
-	if _, err := db.store.GetDB().ExecContext(ctx, `
+	if _, err := q.ExecContext(ctx, `
 		DELETE FROM sessions
 		WHERE id = ?
 		  AND NOT EXISTS (SELECT 1 FROM messages WHERE session_id = ?)
@@ -226,23 +271,24 @@ func (t *GraphRAGToolbox) DeleteMemory(ctx context.Context, req MemoryDeleteRequ
 	return t.db.DeleteMemory(ctx, req)
 }
 
-func (db *DB) ensureMemoryBucket(ctx context.Context, bucketID, userID, scope, namespace string) error {
-	_, err := db.store.GetSession(ctx, bucketID)
-	if err == nil {
-		return nil
-	}
-	if !errors.Is(err, core.ErrNotFound) {
-		return err
-	}
-	return db.store.CreateSession(ctx, &core.Session{
-		ID:     bucketID,
-		UserID: userID,
-		Metadata: map[string]any{
-			"kind":      "memory_bucket",
-			"scope":     scope,
-			"namespace": namespace,
-		},
+func ensureMemoryBucket(ctx context.Context, q sqlQuerier, bucketID, userID, scope, namespace string) error {
+	metadataJSON, err := json.Marshal(map[string]any{
+		"kind":      "memory_bucket",
+		"scope":     scope,
+		"namespace": namespace,
 	})
+	if err != nil {
+		return fmt.Errorf("marshal memory bucket metadata: %w", err)
+	}
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, metadata, created_at, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(id) DO NOTHING
+	`, bucketID, userID, metadataJSON)
+	if err != nil {
+		return fmt.Errorf("ensure memory bucket: %w", err)
+	}
+	return nil
 }
 
 func (db *DB) embedMemoryContent(ctx context.Context, content string) ([]byte, error) {
@@ -260,7 +306,33 @@ func (db *DB) embedMemoryContent(ctx context.Context, content string) ([]byte, e
 	return vectorBytes, nil
 }
 
-func (db *DB) loadMemoryRow(ctx context.Context, memoryID string) (*memoryRow, error) {
+// checkMemoryWrite turns a write that matched no rows into a conflict error.
+func checkMemoryWrite(ctx context.Context, q sqlQuerier, result sql.Result, memoryID string, ifMatch int64, ifNotExists bool) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check memory write: %w", err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var current int64
+	err = q.QueryRowContext(ctx, `SELECT revision FROM messages WHERE id = ?`, memoryID).Scan(&current)
+	switch {
+	case err == sql.ErrNoRows && ifMatch == 0 && !ifNotExists:
+		return core.ErrNotFound
+	case err == sql.ErrNoRows:
+		return fmt.Errorf("%w: memory '%s' does not exist", core.ErrConflict, memoryID)
+	case err != nil:
+		return fmt.Errorf("check memory write: %w", err)
+	case ifNotExists:
+		return fmt.Errorf("%w: memory '%s' already exists at revision %d", core.ErrConflict, memoryID, current)
+	default:
+		return fmt.Errorf("%w: memory '%s' is at revision %d, expected %d", core.ErrConflict, memoryID, current, ifMatch)
+	}
+}
+
+func loadMemoryRow(ctx context.Context, q sqlQuerier, memoryID string) (*memoryRow, error) {
 	if memoryID == "" {
 		return nil, fmt.Errorf("memory_id is required")
 	}
@@ -268,8 +340,8 @@ func (db *DB) loadMemoryRow(ctx context.Context, memoryID string) (*memoryRow, e
 	row := memoryRow{}
 	var metadataJSON []byte
 	var createdAt time.Time
-	err := db.store.GetDB().QueryRowContext(ctx, `
-		SELECT m.id, m.session_id, s.user_id, m.role, m.content, m.vector, m.metadata, m.created_at
+	err := q.QueryRowContext(ctx, `
+		SELECT m.id, m.session_id, s.user_id, m.role, m.content, m.vector, m.metadata, m.revision, m.created_at
 		FROM messages m
 		JOIN sessions s ON s.id = m.session_id
 		WHERE m.id = ?
@@ -281,6 +353,7 @@ func (db *DB) loadMemoryRow(ctx context.Context, memoryID string) (*memoryRow, e
 		&row.record.Content,
 		&row.vector,
 		&metadataJSON,
+		&row.record.Revision,
 		&createdAt,
 	)
 	if err == sql.ErrNoRows {
@@ -318,7 +391,7 @@ func (db *DB) searchMemoryLexical(ctx context.Context, bucketID, query string, k
 
 	for idx, searchQuery := range queries {
 		rows, err := db.store.GetDB().QueryContext(ctx, `
-			SELECT m.id, m.session_id, s.user_id, m.role, m.content, m.metadata, m.created_at, bm25(messages_fts)
+			SELECT m.id, m.session_id, s.user_id, m.role, m.content, m.metadata, m.revision, m.created_at, bm25(messages_fts)
 			FROM messages_fts
 			JOIN messages m ON m.rowid = messages_fts.rowid
 			JOIN sessions s ON s.id = m.session_id
@@ -339,7 +412,7 @@ func (db *DB) searchMemoryLexical(ctx context.Context, bucketID, query string, k
 			var metadataJSON []byte
 			var createdAt time.Time
 			var rawRank float64
-			if err := rows.Scan(&record.ID, &record.SessionID, &record.UserID, &record.Role, &record.Content, &metadataJSON, &createdAt, &rawRank); err != nil {
+			if err := rows.Scan(&record.ID, &record.SessionID, &record.UserID, &record.Role, &record.Content, &metadataJSON, &record.Revision, &createdAt, &rawRank); err != nil {
 				_ = rows.Close()
 				return nil, fmt.Errorf("scan lexical memory: %w", err)
 			}
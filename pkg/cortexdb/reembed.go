@@ -0,0 +1,131 @@
+package cortexdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+)
+
+// ReembedOptions configures DB.Reembed
+type ReembedOptions struct {
+	ModelID    string                // Identifies the new model (default: the embedder's ModelID, required otherwise)
+	BatchSize  int                   // Texts embedded per batch (default: 64)
+	OnProgress func(ReembedProgress) // Called after each batch and once when done
+}
+
+// ReembedProgress reports how far a re-embedding job has come
+type ReembedProgress struct {
+	Collection string `json:"collection"`
+	ModelID    string `json:"model_id"`
+	Processed  int64  `json:"processed"`
+	Total      int64  `json:"total"`
+	Done       bool   `json:"done"`
+}
+
+// ReembedResult summarizes a finished re-embedding job
+type ReembedResult struct {
+	Collection string `json:"collection"`
+	ModelID    string `json:"model_id"`
+	Reembedded int64  `json:"reembedded"` // Texts embedded by this call
+	Promoted   int64  `json:"promoted"`   // Vectors switched to the new model
+	Stale      int64  `json:"stale"`      // Embeddings still from another model, e.g. without content
+	Resumed    bool   `json:"resumed"`    // Whether an interrupted job was continued
+}
+
+// Reembed moves a collection to a new embedding model. Every embedding with
+// content is embedded again in batches and written next to its current
+// vector, so searches are unaffected while the job runs. Progress is
+// checkpointed after each batch: if Reembed fails or ctx is cancelled,
+// calling it again with the same model continues where it stopped. Once all
+// embeddings are done the new vectors replace the old ones, the collection
+// records the new model and its indexes are rebuilt.
+//
+// The new model must produce vectors of the collection's dimension.
+func (db *DB) Reembed(ctx context.Context, collection string, embedder Embedder, opts ReembedOptions) (*ReembedResult, error) {
+	if embedder == nil {
+		return nil, ErrEmbedderNotConfigured
+	}
+	modelID := opts.ModelID
+	if modelID == "" {
+		if m, ok := embedder.(ModelIdentifier); ok {
+			modelID = m.ModelID()
+		}
+	}
+	if modelID == "" {
+		return nil, fmt.Errorf("cortexdb: model ID is required for embedders without a ModelID method")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 64
+	}
+
+	state, err := db.store.BeginReembed(ctx, collection, modelID)
+	if err != nil {
+		return nil, err
+	}
+	result := &ReembedResult{Collection: state.Collection, ModelID: modelID, Resumed: state.LastID != ""}
+	report := func(state *core.ReembedState, done bool) {
+		if opts.OnProgress != nil {
+			opts.OnProgress(ReembedProgress{
+				Collection: state.Collection,
+				ModelID:    modelID,
+				Processed:  state.Processed,
+				Total:      state.Total,
+				Done:       done,
+			})
+		}
+	}
+
+	// The first pass continues from the checkpoint; the second picks up rows
+	// before it that were written while the job was running
+	for _, start := range []string{state.LastID, ""} {
+		after := start
+		for {
+			pending, err := db.store.PendingReembed(ctx, collection, modelID, after, batchSize)
+			if err != nil {
+				return result, err
+			}
+			if len(pending) == 0 {
+				break
+			}
+
+			texts := make([]string, len(pending))
+			for i, emb := range pending {
+				texts[i] = emb.Content
+			}
+			vectors, err := embedder.EmbedBatch(ctx, texts)
+			if err != nil {
+				return result, fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
+			}
+			if len(vectors) != len(pending) {
+				return result, fmt.Errorf("%w: sent %d texts, got %d vectors", ErrEmbeddingFailed, len(pending), len(vectors))
+			}
+			for i, emb := range pending {
+				emb.Vector = vectors[i]
+			}
+
+			state, err = db.store.WriteShadowVectors(ctx, collection, modelID, pending)
+			if err != nil {
+				return result, err
+			}
+			result.Reembedded += int64(len(pending))
+			after = pending[len(pending)-1].ID
+			report(state, false)
+		}
+	}
+
+	result.Promoted, result.Stale, err = db.store.PromoteReembed(ctx, collection, modelID)
+	if err != nil {
+		return result, err
+	}
+	state.Status = core.ReembedDone
+	report(state, true)
+	return result, nil
+}
+
+// ReembedState returns the checkpoint of the job moving collection to
+// modelID, for monitoring a job running in another process.
+func (db *DB) ReembedState(ctx context.Context, collection, modelID string) (*core.ReembedState, error) {
+	return db.store.GetReembedState(ctx, collection, modelID)
+}
@@ -0,0 +1,128 @@
+package cortexdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+)
+
+// flakyEmbedder fails its failOn-th batch
+type flakyEmbedder struct {
+	*keywordEmbedder
+	calls  int
+	failOn int
+}
+
+func (f *flakyEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	f.calls++
+	if f.calls == f.failOn {
+		return nil, errors.New("model unavailable")
+	}
+	return f.keywordEmbedder.EmbedBatch(ctx, texts)
+}
+
+// namedEmbedder gives an embedder a model ID
+type namedEmbedder struct {
+	Embedder
+	id string
+}
+
+func (n namedEmbedder) ModelID() string { return n.id }
+
+func TestReembed(t *testing.T) {
+	ctx := context.Background()
+	oldModel := newKeywordEmbedder("alice", "acme", "graph")
+	db, err := Open(DefaultConfig(filepath.Join(t.TempDir(), "reembed.db")), WithEmbedder(oldModel))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	texts := map[string]string{"a": "alice", "b": "acme", "c": "graph", "d": "alice graph"}
+	if err := db.InsertTextBatch(ctx, texts, nil); err != nil {
+		t.Fatalf("InsertTextBatch: %v", err)
+	}
+
+	// Same words, different dimensions: results only make sense once promoted
+	newModel := &flakyEmbedder{keywordEmbedder: newKeywordEmbedder("graph", "acme", "alice"), failOn: 3}
+	var progress []ReembedProgress
+	opts := ReembedOptions{ModelID: "kw-v2", BatchSize: 1, OnProgress: func(p ReembedProgress) { progress = append(progress, p) }}
+
+	if _, err := db.Reembed(ctx, "", newModel, opts); !errors.Is(err, ErrEmbeddingFailed) {
+		t.Fatalf("Expected the interrupted job to fail, got %v", err)
+	}
+	state, err := db.ReembedState(ctx, "", "kw-v2")
+	if err != nil {
+		t.Fatalf("ReembedState: %v", err)
+	}
+	if state.Status != core.ReembedRunning || state.Processed != 2 || state.Total != 4 || state.LastID != "b" {
+		t.Errorf("Unexpected checkpoint %+v", state)
+	}
+
+	// Old vectors still serve searches mid-job
+	results, err := db.SearchText(ctx, "graph", 1)
+	if err != nil || len(results) == 0 || results[0].ID != "c" {
+		t.Fatalf("Expected old vectors to find c, got %v, %v", results, err)
+	}
+
+	// A row rewritten behind the checkpoint is picked up again
+	if err := db.InsertText(ctx, "a", "alice acme", nil); err != nil {
+		t.Fatalf("InsertText: %v", err)
+	}
+
+	result, err := db.Reembed(ctx, "", newModel, opts)
+	if err != nil {
+		t.Fatalf("Reembed: %v", err)
+	}
+	if !result.Resumed || result.Reembedded != 3 || result.Promoted != 4 || result.Stale != 0 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if last := progress[len(progress)-1]; !last.Done || last.Processed != 5 {
+		t.Errorf("Expected a final progress report, got %+v", last)
+	}
+
+	query, _ := newModel.Embed(ctx, "graph")
+	hits, err := db.Vector().Search(ctx, query, core.SearchOptions{TopK: 1})
+	if err != nil || len(hits) == 0 || hits[0].ID != "c" {
+		t.Errorf("Expected promoted vectors to find c, got %v, %v", hits, err)
+	}
+
+	emb, err := db.store.GetByID(ctx, "d")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if emb.ModelID != "kw-v2" || emb.Vector[0] != 1 || emb.Vector[2] != 1 {
+		t.Errorf("Expected d re-embedded by kw-v2, got %s %v", emb.ModelID, emb.Vector)
+	}
+	coll, err := db.store.GetCollection(ctx, "default")
+	if err != nil || coll.ModelID != "kw-v2" {
+		t.Errorf("Expected the collection to record kw-v2, got %+v, %v", coll, err)
+	}
+	if state, _ := db.ReembedState(ctx, "", "kw-v2"); state.Status != core.ReembedDone {
+		t.Errorf("Expected the job done, got %s", state.Status)
+	}
+
+	if _, err := db.Reembed(ctx, "", oldModel, ReembedOptions{}); err == nil {
+		t.Error("Expected an error without a model ID")
+	}
+
+	// Text operations refuse an embedder that names another model
+	db.embedder = namedEmbedder{Embedder: oldModel, id: "kw-v1"}
+	if _, err := db.SearchText(ctx, "graph", 1); !errors.Is(err, ErrModelMismatch) {
+		t.Errorf("Expected ErrModelMismatch from SearchText, got %v", err)
+	}
+	if err := db.InsertText(ctx, "e", "acme", nil); !errors.Is(err, ErrModelMismatch) {
+		t.Errorf("Expected ErrModelMismatch from InsertText, got %v", err)
+	}
+	doc := GraphRAGDocument{ID: "doc", Content: "alice acme"}
+	if _, err := db.InsertGraphDocument(ctx, doc, GraphRAGIngestOptions{Collection: "default"}); !errors.Is(err, ErrModelMismatch) {
+		t.Errorf("Expected ErrModelMismatch from InsertGraphDocument, got %v", err)
+	}
+	db.embedder = namedEmbedder{Embedder: newModel, id: "kw-v2"}
+	if results, err := db.SearchText(ctx, "graph", 1); err != nil || len(results) == 0 || results[0].ID != "c" {
+		t.Errorf("Expected the promoted model to search, got %v, %v", results, err)
+	}
+}
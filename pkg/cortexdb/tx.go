@@ -0,0 +1,97 @@
+package cortexdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+	"github.com/liliang-cn/cortexdb/v2/pkg/graph"
+)
+
+// sqlQuerier is satisfied by both *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Tx is a single SQLite transaction spanning embeddings, documents, graph
+// nodes and edges, and memories. Either all of its writes become visible on
+// Commit or none do on Rollback. In-memory vector indexes are only updated
+// after a successful commit.
+//
+// Embedding calls (e.g. for SaveMemory) run while the transaction is open, so
+// keep transactions short; other writers wait until it finishes.
+type Tx struct {
+	db    *DB
+	tx    *core.Tx
+	graph *graph.GraphTx
+}
+
+// Begin starts a transaction. The caller must Commit or Rollback it.
+func (db *DB) Begin(ctx context.Context) (*Tx, error) {
+	tx, err := db.store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{db: db, tx: tx, graph: db.graph.WithTx(tx)}, nil
+}
+
+// Update runs fn inside a transaction, committing if fn returns nil and
+// rolling back otherwise.
+func (db *DB) Update(ctx context.Context, fn func(tx *Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Vector returns the embedding and document operations of the transaction
+func (tx *Tx) Vector() *core.Tx {
+	return tx.tx
+}
+
+// Graph returns the graph operations of the transaction
+func (tx *Tx) Graph() *graph.GraphTx {
+	return tx.graph
+}
+
+// Commit commits all writes and applies deferred index updates
+func (tx *Tx) Commit() error {
+	return tx.tx.Commit()
+}
+
+// Rollback discards all writes. It is a no-op after Commit.
+func (tx *Tx) Rollback() error {
+	return tx.tx.Rollback()
+}
+
+// SaveMemory stores a memory record within the transaction
+func (tx *Tx) SaveMemory(ctx context.Context, req MemorySaveRequest) (*MemorySaveResponse, error) {
+	return tx.db.saveMemory(ctx, tx.tx.SQL(), req)
+}
+
+// UpdateMemory updates a memory record within the transaction
+func (tx *Tx) UpdateMemory(ctx context.Context, req MemoryUpdateRequest) (*MemorySaveResponse, error) {
+	return tx.db.updateMemory(ctx, tx.tx.SQL(), req)
+}
+
+// GetMemory fetches a memory record, including uncommitted changes
+func (tx *Tx) GetMemory(ctx context.Context, req MemoryGetRequest) (*MemoryGetResponse, error) {
+	row, err := loadMemoryRow(ctx, tx.tx.SQL(), req.MemoryID)
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryGetResponse{Memory: row.record}, nil
+}
+
+// DeleteMemory removes a memory record within the transaction
+func (tx *Tx) DeleteMemory(ctx context.Context, req MemoryDeleteRequest) (*MemoryDeleteResponse, error) {
+	return deleteMemory(ctx, tx.tx.SQL(), req)
+}
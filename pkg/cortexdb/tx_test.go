@@ -0,0 +1,120 @@
+package cortexdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+	"github.com/liliang-cn/cortexdb/v2/pkg/graph"
+)
+
+func TestTxSpansStores(t *testing.T) {
+	dbPath := fmt.Sprintf("test_tx_%d.db", time.Now().UnixNano())
+	defer func() { _ = os.Remove(dbPath) }()
+
+	db, err := Open(DefaultConfig(dbPath), WithEmbedder(newKeywordEmbedder("alice", "acme", "research")))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	if err := db.Graph().InitGraphSchema(ctx); err != nil {
+		t.Fatalf("init graph schema: %v", err)
+	}
+
+	write := func(tx *Tx) error {
+		if err := tx.Vector().CreateDocument(ctx, &core.Document{ID: "doc-1", Title: "Alice", Version: 1}); err != nil {
+			return err
+		}
+		if err := tx.Vector().Upsert(ctx, &core.Embedding{ID: "chunk-1", Vector: []float32{1, 0, 0}, Content: "alice", DocID: "doc-1"}); err != nil {
+			return err
+		}
+		if err := tx.Graph().UpsertNode(ctx, &graph.GraphNode{ID: "entity:alice", Vector: []float32{1, 0, 0}, NodeType: "person"}); err != nil {
+			return err
+		}
+		_, err := tx.SaveMemory(ctx, MemorySaveRequest{MemoryID: "mem-1", UserID: "u1", Content: "alice likes acme"})
+		return err
+	}
+
+	errAbort := errors.New("abort")
+	err = db.Update(ctx, func(tx *Tx) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+		if _, err := tx.GetMemory(ctx, MemoryGetRequest{MemoryID: "mem-1"}); err != nil {
+			return fmt.Errorf("read own write: %w", err)
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+
+	if _, err := db.Vector().GetDocument(ctx, "doc-1"); err == nil {
+		t.Error("document should have been rolled back")
+	}
+	if _, err := db.store.GetByID(ctx, "chunk-1"); err == nil {
+		t.Error("embedding should have been rolled back")
+	}
+	if _, err := db.Graph().GetNode(ctx, "entity:alice"); err == nil {
+		t.Error("graph node should have been rolled back")
+	}
+	if _, err := db.GetMemory(ctx, MemoryGetRequest{MemoryID: "mem-1"}); !errors.Is(err, core.ErrNotFound) {
+		t.Errorf("memory should have been rolled back, got %v", err)
+	}
+
+	if err := db.Update(ctx, write); err != nil {
+		t.Fatalf("commit transaction: %v", err)
+	}
+	if _, err := db.Vector().GetDocument(ctx, "doc-1"); err != nil {
+		t.Errorf("document missing after commit: %v", err)
+	}
+	if _, err := db.Graph().GetNode(ctx, "entity:alice"); err != nil {
+		t.Errorf("graph node missing after commit: %v", err)
+	}
+	if _, err := db.GetMemory(ctx, MemoryGetRequest{MemoryID: "mem-1"}); err != nil {
+		t.Errorf("memory missing after commit: %v", err)
+	}
+	results, err := db.Vector().Search(ctx, []float32{1, 0, 0}, core.SearchOptions{TopK: 1})
+	if err != nil || len(results) != 1 || results[0].ID != "chunk-1" {
+		t.Errorf("expected committed embedding to be searchable, got %v, %v", results, err)
+	}
+}
+
+func TestInsertGraphDocumentIsAtomic(t *testing.T) {
+	dbPath := fmt.Sprintf("test_tx_graphrag_%d.db", time.Now().UnixNano())
+	defer func() { _ = os.Remove(dbPath) }()
+
+	embedder := newKeywordEmbedder("alice", "acme", "graphrag", "research")
+	db, err := Open(DefaultConfig(dbPath), WithEmbedder(embedder))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	schema := &core.MetadataSchema{Fields: []core.MetadataField{{Name: "tenant", Required: true}}}
+	if _, err := db.Vector().CreateCollection(ctx, "strict", embedder.Dim(), core.WithMetadataSchema(schema)); err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+
+	// Chunk metadata lacks the required field, so the embedding write fails
+	// after the document record and document node were written.
+	_, err = db.InsertGraphDocument(ctx, GraphRAGDocument{ID: "doc-1", Title: "Alice", Content: "Alice works at Acme."},
+		GraphRAGIngestOptions{Collection: "strict", Extractor: fixtureExtractor{}})
+	if !errors.Is(err, core.ErrSchemaViolation) {
+		t.Fatalf("expected schema violation, got %v", err)
+	}
+
+	if _, err := db.Vector().GetDocument(ctx, "doc-1"); err == nil {
+		t.Error("document record should not survive a failed ingest")
+	}
+	if _, err := db.Graph().GetNode(ctx, graphDocumentNodeID("doc-1")); err == nil {
+		t.Error("document node should not survive a failed ingest")
+	}
+}
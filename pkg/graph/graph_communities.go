@@ -0,0 +1,296 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// HierarchyOptions selects the nodes and edges CommunityHierarchy groups
+type HierarchyOptions struct {
+	NodeIDPrefix string // Only nodes whose ID starts with this are grouped (default: all nodes)
+	MaxLevels    int    // Levels to build at most (default: 3)
+
+	// Project lists edge types whose source node is not grouped itself but
+	// links the grouped nodes it points to, e.g. "mentions" from a chunk
+	// connects every entity the chunk mentions with weight 1.
+	Project []string
+}
+
+// CommunityLevel is one level of a community hierarchy. Level 0 is the
+// finest; each community of a level is contained in one of the next.
+type CommunityLevel struct {
+	Level       int         `json:"level"`
+	Communities []Community `json:"communities"`
+	Parents     []int       `json:"parents,omitempty"` // Index of each community's parent in the next level, -1 on the top level
+}
+
+// CommunityHierarchy groups nodes with the Louvain method, keeping the
+// partition found after each aggregation pass as a level. Edges count in
+// both directions. Community.Score is the level's modularity.
+func (g *GraphStore) CommunityHierarchy(ctx context.Context, opts HierarchyOptions) ([]CommunityLevel, error) {
+	if opts.MaxLevels <= 0 {
+		opts.MaxLevels = 3
+	}
+
+	query := "SELECT id FROM graph_nodes"
+	var args []interface{}
+	if opts.NodeIDPrefix != "" {
+		query += " WHERE substr(id, 1, ?) = ?"
+		args = append(args, len(opts.NodeIDPrefix), opts.NodeIDPrefix)
+	}
+	query += " ORDER BY id"
+	rows, err := g.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query nodes: %w", err)
+	}
+	var nodes []string
+	index := make(map[string]int)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[id] = len(nodes)
+		nodes = append(nodes, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	adj := make([]map[int]float64, len(nodes))
+	for i := range adj {
+		adj[i] = make(map[int]float64)
+	}
+	link := func(u, v int, w float64) {
+		if u == v || w <= 0 {
+			return
+		}
+		adj[u][v] += w
+		adj[v][u] += w
+	}
+
+	project := make(map[string]bool, len(opts.Project))
+	for _, t := range opts.Project {
+		project[t] = true
+	}
+	projected := make(map[string][]int)
+
+	edgeRows, err := g.db.QueryContext(ctx, "SELECT from_node_id, to_node_id, COALESCE(edge_type, ''), weight FROM graph_edges ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("query edges: %w", err)
+	}
+	for edgeRows.Next() {
+		var from, to, edgeType string
+		var weight float64
+		if err := edgeRows.Scan(&from, &to, &edgeType, &weight); err != nil {
+			edgeRows.Close()
+			return nil, err
+		}
+		v, ok := index[to]
+		if !ok {
+			continue
+		}
+		if u, ok := index[from]; ok {
+			link(u, v, weight)
+		} else if project[edgeType] {
+			projected[from] = append(projected[from], v)
+		}
+	}
+	edgeRows.Close()
+	if err := edgeRows.Err(); err != nil {
+		return nil, err
+	}
+	for _, members := range projected {
+		for i := range members {
+			for j := i + 1; j < len(members); j++ {
+				link(members[i], members[j], 1)
+			}
+		}
+	}
+
+	var levels []CommunityLevel
+	for _, membership := range louvainLevels(adj, opts.MaxLevels) {
+		levels = append(levels, communityLevel(len(levels), nodes, membership, adj))
+	}
+	for l := 0; l+1 < len(levels); l++ {
+		parentOf := make(map[string]int)
+		for i, c := range levels[l+1].Communities {
+			for _, id := range c.Nodes {
+				parentOf[id] = i
+			}
+		}
+		levels[l].Parents = make([]int, len(levels[l].Communities))
+		for i, c := range levels[l].Communities {
+			levels[l].Parents[i] = parentOf[c.Nodes[0]]
+		}
+	}
+	if len(levels) > 0 {
+		top := &levels[len(levels)-1]
+		top.Parents = make([]int, len(top.Communities))
+		for i := range top.Parents {
+			top.Parents[i] = -1
+		}
+	}
+	return levels, nil
+}
+
+// communityLevel groups node IDs by community, largest first
+func communityLevel(level int, nodes []string, membership []int, adj []map[int]float64) CommunityLevel {
+	groups := make(map[int][]string)
+	for i, c := range membership {
+		groups[c] = append(groups[c], nodes[i])
+	}
+	q := modularity(adj, membership)
+	communities := make([]Community, 0, len(groups))
+	for _, members := range groups {
+		sort.Strings(members)
+		communities = append(communities, Community{Nodes: members, Score: q})
+	}
+	sort.Slice(communities, func(i, j int) bool {
+		if len(communities[i].Nodes) != len(communities[j].Nodes) {
+			return len(communities[i].Nodes) > len(communities[j].Nodes)
+		}
+		return strings.Compare(communities[i].Nodes[0], communities[j].Nodes[0]) < 0
+	})
+	for i := range communities {
+		communities[i].ID = i
+	}
+	return CommunityLevel{Level: level, Communities: communities}
+}
+
+// louvainLevels runs Louvain passes and returns each pass's membership of
+// the original nodes, stopping when a pass merges nothing
+func louvainLevels(adj []map[int]float64, maxLevels int) [][]int {
+	membership := make([]int, len(adj))
+	for i := range membership {
+		membership[i] = i
+	}
+
+	var levels [][]int
+	for len(levels) < maxLevels {
+		comm := louvainPass(adj)
+		n := 0
+		renumber := make(map[int]int)
+		for i, c := range comm {
+			if _, ok := renumber[c]; !ok {
+				renumber[c] = n
+				n++
+			}
+			comm[i] = renumber[c]
+		}
+		if n == len(adj) {
+			break
+		}
+
+		for i, c := range membership {
+			membership[i] = comm[c]
+		}
+		levels = append(levels, append([]int(nil), membership...))
+
+		// Aggregate communities into nodes; internal weight becomes a self loop
+		next := make([]map[int]float64, n)
+		for i := range next {
+			next[i] = make(map[int]float64)
+		}
+		for u, neighbors := range adj {
+			for v, w := range neighbors {
+				next[comm[u]][comm[v]] += w
+			}
+		}
+		adj = next
+	}
+	if len(levels) == 0 {
+		// No edges to merge over: every node is its own community
+		levels = append(levels, membership)
+	}
+	return levels
+}
+
+// louvainPass moves nodes between communities while modularity improves
+func louvainPass(adj []map[int]float64) []int {
+	n := len(adj)
+	comm := make([]int, n)
+	degree := make([]float64, n)
+	total := make([]float64, n)
+	m2 := 0.0
+	for i, neighbors := range adj {
+		comm[i] = i
+		for _, w := range neighbors {
+			degree[i] += w
+		}
+		total[i] = degree[i]
+		m2 += degree[i]
+	}
+	if m2 == 0 {
+		return comm
+	}
+
+	for iter := 0; iter < 100; iter++ {
+		moved := false
+		for i := 0; i < n; i++ {
+			current := comm[i]
+			total[current] -= degree[i]
+
+			weights := make(map[int]float64)
+			for j, w := range adj[i] {
+				if j != i {
+					weights[comm[j]] += w
+				}
+			}
+			candidates := make([]int, 0, len(weights))
+			for c := range weights {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+
+			best := current
+			bestGain := weights[current] - total[current]*degree[i]/m2
+			for _, c := range candidates {
+				if gain := weights[c] - total[c]*degree[i]/m2; gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+
+			comm[i] = best
+			total[best] += degree[i]
+			if best != current {
+				moved = true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return comm
+}
+
+// modularity scores a partition of the original graph
+func modularity(adj []map[int]float64, membership []int) float64 {
+	m2 := 0.0
+	internal := make(map[int]float64)
+	total := make(map[int]float64)
+	for u, neighbors := range adj {
+		for v, w := range neighbors {
+			m2 += w
+			total[membership[u]] += w
+			if membership[u] == membership[v] {
+				internal[membership[u]] += w
+			}
+		}
+	}
+	if m2 == 0 {
+		return 0
+	}
+	q := 0.0
+	for c, tot := range total {
+		q += internal[c]/m2 - (tot/m2)*(tot/m2)
+	}
+	return q
+}
@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCommunityHierarchy(t *testing.T) {
+	_, graph, cleanup := setupTestGraph(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, id := range []string{"e:A", "e:B", "e:C", "e:D", "e:E", "e:F", "e:G", "e:H", "e:I", "chunk1", "chunk2", "chunk3"} {
+		if err := graph.UpsertNode(ctx, &GraphNode{ID: id, Vector: []float32{1, 0, 0}}); err != nil {
+			t.Fatalf("UpsertNode failed: %v", err)
+		}
+	}
+
+	edges := []GraphEdge{
+		// Two triangles joined by a weak link
+		{ID: "1", FromNodeID: "e:A", ToNodeID: "e:B", Weight: 1},
+		{ID: "2", FromNodeID: "e:B", ToNodeID: "e:C", Weight: 1},
+		{ID: "3", FromNodeID: "e:C", ToNodeID: "e:A", Weight: 1},
+		{ID: "4", FromNodeID: "e:D", ToNodeID: "e:E", Weight: 1},
+		{ID: "5", FromNodeID: "e:E", ToNodeID: "e:F", Weight: 1},
+		{ID: "6", FromNodeID: "e:F", ToNodeID: "e:D", Weight: 1},
+		{ID: "7", FromNodeID: "e:C", ToNodeID: "e:D", Weight: 0.1},
+		{ID: "8", FromNodeID: "e:F", ToNodeID: "e:G", Weight: 0.1},
+		// G, H and I are only connected through the chunks mentioning them
+		{ID: "9", FromNodeID: "chunk1", ToNodeID: "e:G", EdgeType: "mentions", Weight: 1},
+		{ID: "10", FromNodeID: "chunk1", ToNodeID: "e:H", EdgeType: "mentions", Weight: 1},
+		{ID: "11", FromNodeID: "chunk2", ToNodeID: "e:H", EdgeType: "mentions", Weight: 1},
+		{ID: "12", FromNodeID: "chunk2", ToNodeID: "e:I", EdgeType: "mentions", Weight: 1},
+		{ID: "13", FromNodeID: "chunk3", ToNodeID: "e:I", EdgeType: "mentions", Weight: 1},
+		{ID: "14", FromNodeID: "chunk3", ToNodeID: "e:G", EdgeType: "mentions", Weight: 1},
+	}
+	for i := range edges {
+		if err := graph.UpsertEdge(ctx, &edges[i]); err != nil {
+			t.Fatalf("UpsertEdge failed: %v", err)
+		}
+	}
+
+	levels, err := graph.CommunityHierarchy(ctx, HierarchyOptions{NodeIDPrefix: "e:", Project: []string{"mentions"}})
+	if err != nil {
+		t.Fatalf("CommunityHierarchy failed: %v", err)
+	}
+	if len(levels) == 0 {
+		t.Fatal("Expected at least one level")
+	}
+
+	var groups []string
+	for _, c := range levels[0].Communities {
+		groups = append(groups, strings.Join(c.Nodes, " "))
+	}
+	want := "e:A e:B e:C|e:D e:E e:F|e:G e:H e:I"
+	if got := strings.Join(groups, "|"); got != want {
+		t.Errorf("Expected level 0 communities %s, got %s", want, got)
+	}
+	if levels[0].Communities[0].Score <= 0 {
+		t.Errorf("Expected positive modularity, got %f", levels[0].Communities[0].Score)
+	}
+
+	top := levels[len(levels)-1]
+	for _, p := range top.Parents {
+		if p != -1 {
+			t.Errorf("Expected no parents on the top level, got %v", top.Parents)
+		}
+	}
+	for l := 0; l+1 < len(levels); l++ {
+		for i, p := range levels[l].Parents {
+			parent := strings.Join(levels[l+1].Communities[p].Nodes, " ")
+			for _, id := range levels[l].Communities[i].Nodes {
+				if !strings.Contains(parent, id) {
+					t.Errorf("Level %d community %d is not inside its parent", l, i)
+				}
+			}
+		}
+	}
+}
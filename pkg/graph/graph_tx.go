@@ -0,0 +1,266 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/core"
+)
+
+// GraphTx performs graph writes inside a core.Tx, so they commit or roll
+// back together with embedding, document and memory writes in the same
+// transaction. HNSW index updates are deferred until the transaction commits.
+type GraphTx struct {
+	g  *GraphStore
+	tx *core.Tx
+}
+
+// WithTx returns a view of the graph store that writes through tx
+func (g *GraphStore) WithTx(tx *core.Tx) *GraphTx {
+	return &GraphTx{g: g, tx: tx}
+}
+
+// UpsertNode inserts or updates a node within the transaction
+func (gt *GraphTx) UpsertNode(ctx context.Context, node *GraphNode) error {
+	if node == nil || node.ID == "" {
+		return fmt.Errorf("invalid node: missing ID")
+	}
+	if len(node.Vector) == 0 {
+		return fmt.Errorf("invalid node: missing vector")
+	}
+
+	result, err := gt.UpsertNodesBatch(ctx, []*GraphNode{node})
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return result.Errors[0]
+	}
+	return nil
+}
+
+// UpsertNodesBatch inserts or updates multiple nodes within the transaction
+func (gt *GraphTx) UpsertNodesBatch(ctx context.Context, nodes []*GraphNode) (*BatchResult, error) {
+	if len(nodes) == 0 {
+		return &BatchResult{}, nil
+	}
+
+	result, err := gt.g.upsertNodesBatchTx(ctx, gt.tx.SQL(), nodes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert nodes: %w", err)
+	}
+
+	gt.tx.OnCommit(func() {
+		if gt.g.hnswIndex == nil {
+			return
+		}
+		for _, node := range nodes {
+			if node == nil || node.ID == "" || len(node.Vector) == 0 {
+				continue
+			}
+			gt.g.hnswIndex.index.Remove(node.ID)
+			_ = gt.g.hnswIndex.index.Add(node.ID, node.Vector)
+		}
+	})
+
+	return result, nil
+}
+
+// DeleteNode removes a node and all its edges within the transaction
+func (gt *GraphTx) DeleteNode(ctx context.Context, nodeID string) error {
+	result, err := gt.DeleteNodesBatch(ctx, []string{nodeID})
+	if err != nil {
+		return err
+	}
+	if result.SuccessCount == 0 {
+		return fmt.Errorf("node not found: %s", nodeID)
+	}
+	return nil
+}
+
+// DeleteNodesBatch removes nodes and their edges within the transaction.
+// Edges are deleted explicitly rather than relying on ON DELETE CASCADE.
+func (gt *GraphTx) DeleteNodesBatch(ctx context.Context, nodeIDs []string) (*BatchResult, error) {
+	if len(nodeIDs) == 0 {
+		return &BatchResult{}, nil
+	}
+
+	placeholders := make([]string, len(nodeIDs))
+	args := make([]interface{}, 0, len(nodeIDs)*2)
+	for i, id := range nodeIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+	args = append(args, args...)
+
+	in := strings.Join(placeholders, ",")
+	query := fmt.Sprintf("DELETE FROM graph_edges WHERE from_node_id IN (%s) OR to_node_id IN (%s)", in, in)
+	if _, err := gt.tx.SQL().ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to delete node edges: %w", err)
+	}
+
+	result, err := gt.g.deleteNodesBatchTx(ctx, gt.tx.SQL(), nodeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete nodes: %w", err)
+	}
+
+	gt.tx.OnCommit(func() {
+		if gt.g.hnswIndex == nil {
+			return
+		}
+		for _, id := range nodeIDs {
+			gt.g.hnswIndex.index.Remove(id)
+		}
+	})
+
+	return result, nil
+}
+
+// UpsertEdge inserts or updates an edge within the transaction
+func (gt *GraphTx) UpsertEdge(ctx context.Context, edge *GraphEdge) error {
+	if edge == nil || edge.ID == "" {
+		return fmt.Errorf("invalid edge: missing ID")
+	}
+	if edge.FromNodeID == "" || edge.ToNodeID == "" {
+		return fmt.Errorf("invalid edge: missing node IDs")
+	}
+
+	result, err := gt.UpsertEdgesBatch(ctx, []*GraphEdge{edge})
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return result.Errors[0]
+	}
+	return nil
+}
+
+// UpsertEdgesBatch inserts or updates multiple edges within the transaction
+func (gt *GraphTx) UpsertEdgesBatch(ctx context.Context, edges []*GraphEdge) (*BatchResult, error) {
+	if len(edges) == 0 {
+		return &BatchResult{}, nil
+	}
+
+	result, err := gt.g.upsertEdgesBatchTx(ctx, gt.tx.SQL(), edges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert edges: %w", err)
+	}
+	return result, nil
+}
+
+// DeleteEdge removes an edge within the transaction
+func (gt *GraphTx) DeleteEdge(ctx context.Context, edgeID string) error {
+	result, err := gt.DeleteEdgesBatch(ctx, []string{edgeID})
+	if err != nil {
+		return err
+	}
+	if result.SuccessCount == 0 {
+		return fmt.Errorf("edge not found: %s", edgeID)
+	}
+	return nil
+}
+
+// DeleteEdgesBatch removes multiple edges within the transaction
+func (gt *GraphTx) DeleteEdgesBatch(ctx context.Context, edgeIDs []string) (*BatchResult, error) {
+	if len(edgeIDs) == 0 {
+		return &BatchResult{}, nil
+	}
+
+	result, err := gt.g.deleteEdgesBatchTx(ctx, gt.tx.SQL(), edgeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete edges: %w", err)
+	}
+	return result, nil
+}
+
+// DeleteEdgesByProperty removes the edges whose JSON property key holds one
+// of values within the transaction. It returns the IDs of the nodes those
+// edges connected, so callers can clean up nodes left without edges.
+func (gt *GraphTx) DeleteEdgesByProperty(ctx context.Context, key string, values []string) ([]string, error) {
+	if key == "" || len(values) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, 0, len(values)+1)
+	args = append(args, "$."+key)
+	for i, v := range values {
+		placeholders[i] = "?"
+		args = append(args, v)
+	}
+	where := fmt.Sprintf("CASE WHEN json_valid(properties) THEN json_extract(properties, ?) END IN (%s)", strings.Join(placeholders, ","))
+
+	rows, err := gt.tx.SQL().QueryContext(ctx, "SELECT from_node_id, to_node_id FROM graph_edges WHERE "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find edges: %w", err)
+	}
+	seen := make(map[string]struct{})
+	var nodeIDs []string
+	for rows.Next() {
+		var from, to string
+		if err := rows.Scan(&from, &to); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan edge: %w", err)
+		}
+		for _, id := range []string{from, to} {
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				nodeIDs = append(nodeIDs, id)
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find edges: %w", err)
+	}
+	if len(nodeIDs) == 0 {
+		return nil, nil
+	}
+
+	if _, err := gt.tx.SQL().ExecContext(ctx, "DELETE FROM graph_edges WHERE "+where, args...); err != nil {
+		return nil, fmt.Errorf("failed to delete edges: %w", err)
+	}
+	return nodeIDs, nil
+}
+
+// DeleteOrphanNodes removes those of nodeIDs that have no edges left within
+// the transaction and returns the IDs it removed
+func (gt *GraphTx) DeleteOrphanNodes(ctx context.Context, nodeIDs []string) ([]string, error) {
+	if len(nodeIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(nodeIDs))
+	args := make([]interface{}, len(nodeIDs))
+	for i, id := range nodeIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	query := fmt.Sprintf(`SELECT id FROM graph_nodes n WHERE id IN (%s)
+		AND NOT EXISTS (SELECT 1 FROM graph_edges e WHERE e.from_node_id = n.id OR e.to_node_id = n.id)`,
+		strings.Join(placeholders, ","))
+
+	rows, err := gt.tx.SQL().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find orphan nodes: %w", err)
+	}
+	var orphans []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan node: %w", err)
+		}
+		orphans = append(orphans, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find orphan nodes: %w", err)
+	}
+
+	if _, err := gt.DeleteNodesBatch(ctx, orphans); err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
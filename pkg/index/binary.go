@@ -0,0 +1,444 @@
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sort"
+	"sync"
+)
+
+// BinaryIndex searches packed bit codes by Hamming distance.
+//
+// Codes use the layout of quantization.BinaryQuantizer: bit d of the code is
+// bit d%8 of byte d/8. They are kept as 64-bit words so that distances are a
+// popcount per word. With Substrings > 0 the index also keeps multi-index
+// hashing (MIH) tables: each code is split into that many disjoint
+// substrings, and a code within distance r of the query must match one
+// substring within floor(r/Substrings) bits, so a search probes growing
+// Hamming balls around the query substrings instead of scanning every code.
+type BinaryIndex struct {
+	Bits       int // Code length in bits
+	Substrings int // MIH tables, 0 = popcount scan only
+
+	words  int                  // uint64 words per code
+	ids    []string             // Position -> ID
+	codes  []uint64             // Codes of all positions, words per position
+	pos    map[string]int       // ID -> position
+	tables []map[uint64][]int32 // Substring value -> positions, one table per substring
+	spans  [][2]int             // First bit and length of each substring
+
+	mu sync.RWMutex
+}
+
+// maxSubstringBits caps the substring length so keys fit in a uint64 and
+// ball enumeration stays cheap
+const maxSubstringBits = 32
+
+// mihProbeCost is the cost of one MIH table probe in code comparisons
+const mihProbeCost = 8
+
+// NewBinaryIndex creates an index for codes of the given length. substrings
+// enables multi-index hashing; it is clamped so substrings are 1 to 32 bits.
+func NewBinaryIndex(numBits, substrings int) *BinaryIndex {
+	if substrings > numBits {
+		substrings = numBits
+	}
+	if substrings > 0 && (numBits+substrings-1)/substrings > maxSubstringBits {
+		substrings = (numBits + maxSubstringBits - 1) / maxSubstringBits
+	}
+	if substrings < 0 {
+		substrings = 0
+	}
+
+	b := &BinaryIndex{
+		Bits:       numBits,
+		Substrings: substrings,
+		words:      (numBits + 63) / 64,
+		pos:        make(map[string]int),
+	}
+
+	if substrings > 0 {
+		b.tables = make([]map[uint64][]int32, substrings)
+		b.spans = make([][2]int, substrings)
+		start := 0
+		for i := 0; i < substrings; i++ {
+			// Spread the remainder so lengths differ by at most one bit
+			length := numBits / substrings
+			if i < numBits%substrings {
+				length++
+			}
+			b.tables[i] = make(map[uint64][]int32)
+			b.spans[i] = [2]int{start, length}
+			start += length
+		}
+	}
+	return b
+}
+
+// CodeBytes returns the packed code length in bytes
+func (b *BinaryIndex) CodeBytes() int {
+	return (b.Bits + 7) / 8
+}
+
+// Add inserts or replaces the code stored for id
+func (b *BinaryIndex) Add(id string, code []byte) error {
+	if len(code) != b.CodeBytes() {
+		return fmt.Errorf("code length %d doesn't match index code length %d", len(code), b.CodeBytes())
+	}
+	words := b.pack(code)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if p, ok := b.pos[id]; ok {
+		b.unhash(p)
+		copy(b.codes[p*b.words:], words)
+		b.hash(p)
+		return nil
+	}
+
+	p := len(b.ids)
+	b.ids = append(b.ids, id)
+	b.codes = append(b.codes, words...)
+	b.pos[id] = p
+	b.hash(p)
+	return nil
+}
+
+// Delete removes id from the index. The last code moves into its slot.
+func (b *BinaryIndex) Delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p, ok := b.pos[id]
+	if !ok {
+		return fmt.Errorf("vector %s not found", id)
+	}
+
+	last := len(b.ids) - 1
+	b.unhash(p)
+	if p != last {
+		b.unhash(last)
+		copy(b.codes[p*b.words:(p+1)*b.words], b.codes[last*b.words:])
+		b.ids[p] = b.ids[last]
+		b.pos[b.ids[p]] = p
+		b.hash(p)
+	}
+
+	b.ids = b.ids[:last]
+	b.codes = b.codes[:last*b.words]
+	delete(b.pos, id)
+	return nil
+}
+
+// Contains reports whether id is in the index
+func (b *BinaryIndex) Contains(id string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.pos[id]
+	return ok
+}
+
+// Size returns the number of codes in the index
+func (b *BinaryIndex) Size() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.ids)
+}
+
+// MemoryUsage returns the approximate bytes held by codes and MIH tables
+func (b *BinaryIndex) MemoryUsage() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return int64(len(b.codes))*8 + int64(len(b.ids)*len(b.tables))*4
+}
+
+// Search returns the k codes closest to query by Hamming distance, nearest
+// first. It uses the MIH tables when present and a popcount scan otherwise.
+func (b *BinaryIndex) Search(query []byte, k int) ([]string, []int, error) {
+	if len(query) != b.CodeBytes() {
+		return nil, nil, fmt.Errorf("query code length %d doesn't match index code length %d", len(query), b.CodeBytes())
+	}
+	q := b.pack(query)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if k <= 0 || len(b.ids) == 0 {
+		return nil, nil, nil
+	}
+	if len(b.tables) > 0 {
+		return b.searchMIH(q, k)
+	}
+	ids, dists := b.searchScan(q, k)
+	return ids, dists, nil
+}
+
+// SearchScan returns the k nearest codes using a popcount scan only
+func (b *BinaryIndex) SearchScan(query []byte, k int) ([]string, []int, error) {
+	if len(query) != b.CodeBytes() {
+		return nil, nil, fmt.Errorf("query code length %d doesn't match index code length %d", len(query), b.CodeBytes())
+	}
+	q := b.pack(query)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if k <= 0 || len(b.ids) == 0 {
+		return nil, nil, nil
+	}
+	ids, dists := b.searchScan(q, k)
+	return ids, dists, nil
+}
+
+// binaryHit is a candidate position and its Hamming distance
+type binaryHit struct {
+	pos  int
+	dist int
+}
+
+// binaryTopK keeps the k nearest hits seen so far
+type binaryTopK struct {
+	k    int
+	hits []binaryHit // Max-heap on dist
+}
+
+func (t *binaryTopK) push(h binaryHit) {
+	if len(t.hits) < t.k {
+		t.hits = append(t.hits, h)
+		t.up(len(t.hits) - 1)
+		return
+	}
+	if h.dist >= t.hits[0].dist {
+		return
+	}
+	t.hits[0] = h
+	t.down(0)
+}
+
+// worst returns the largest distance kept, or -1 while fewer than k are kept
+func (t *binaryTopK) worst() int {
+	if len(t.hits) < t.k {
+		return -1
+	}
+	return t.hits[0].dist
+}
+
+func (t *binaryTopK) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if t.hits[parent].dist >= t.hits[i].dist {
+			return
+		}
+		t.hits[parent], t.hits[i] = t.hits[i], t.hits[parent]
+		i = parent
+	}
+}
+
+func (t *binaryTopK) down(i int) {
+	for {
+		largest := i
+		for _, c := range []int{2*i + 1, 2*i + 2} {
+			if c < len(t.hits) && t.hits[c].dist > t.hits[largest].dist {
+				largest = c
+			}
+		}
+		if largest == i {
+			return
+		}
+		t.hits[i], t.hits[largest] = t.hits[largest], t.hits[i]
+		i = largest
+	}
+}
+
+// results returns the kept hits as IDs and distances, nearest first
+func (b *BinaryIndex) results(t *binaryTopK) ([]string, []int) {
+	sort.Slice(t.hits, func(i, j int) bool {
+		if t.hits[i].dist != t.hits[j].dist {
+			return t.hits[i].dist < t.hits[j].dist
+		}
+		return t.hits[i].pos < t.hits[j].pos
+	})
+	ids := make([]string, len(t.hits))
+	dists := make([]int, len(t.hits))
+	for i, h := range t.hits {
+		ids[i] = b.ids[h.pos]
+		dists[i] = h.dist
+	}
+	return ids, dists
+}
+
+// searchScan computes the distance to every code. The caller holds b.mu.
+func (b *BinaryIndex) searchScan(q []uint64, k int) ([]string, []int) {
+	top := &binaryTopK{k: k}
+	for p := range b.ids {
+		top.push(binaryHit{pos: p, dist: b.distance(q, p)})
+	}
+	return b.results(top)
+}
+
+// searchMIH probes Hamming balls of growing radius r around each query
+// substring. A code at distance d has some substring within floor(d/m) of
+// the query's, so after radius r every code closer than m*(r+1) has been
+// seen and the search can stop once the k-th best is below that bound.
+// Once the probes would cost more than scanning, the rest is scanned, so
+// MIH never does much worse than SearchScan. The caller holds b.mu.
+func (b *BinaryIndex) searchMIH(q []uint64, k int) ([]string, []int, error) {
+	m := len(b.tables)
+	n := len(b.ids)
+	if k > n {
+		k = n
+	}
+
+	keys := make([]uint64, m)
+	for i, span := range b.spans {
+		keys[i] = extractBits(q, span[0], span[1])
+	}
+
+	top := &binaryTopK{k: k}
+	seen := make([]bool, n)
+	spent := 0
+	for r := 0; ; r++ {
+		probes := 0
+		for _, span := range b.spans {
+			if r <= span[1] {
+				probes += binomial(span[1], r)
+			}
+		}
+		spent += probes
+		if probes == 0 || spent*mihProbeCost > n {
+			// Scan whatever the probes have not reached
+			for p := range b.ids {
+				if !seen[p] {
+					top.push(binaryHit{pos: p, dist: b.distance(q, p)})
+				}
+			}
+			break
+		}
+
+		for i, span := range b.spans {
+			if r > span[1] {
+				continue
+			}
+			table := b.tables[i]
+			forEachWithinRadius(keys[i], span[1], r, func(key uint64) {
+				for _, p := range table[key] {
+					if !seen[p] {
+						seen[p] = true
+						top.push(binaryHit{pos: int(p), dist: b.distance(q, int(p))})
+					}
+				}
+			})
+		}
+
+		if worst := top.worst(); worst >= 0 && worst < m*(r+1) {
+			break
+		}
+	}
+
+	ids, dists := b.results(top)
+	return ids, dists, nil
+}
+
+// distance returns the Hamming distance between q and the code at p
+func (b *BinaryIndex) distance(q []uint64, p int) int {
+	code := b.codes[p*b.words : (p+1)*b.words]
+	d := 0
+	for i, w := range code {
+		d += bits.OnesCount64(w ^ q[i])
+	}
+	return d
+}
+
+// hash adds position p to the MIH tables. The caller holds b.mu.
+func (b *BinaryIndex) hash(p int) {
+	code := b.codes[p*b.words : (p+1)*b.words]
+	for i, span := range b.spans {
+		key := extractBits(code, span[0], span[1])
+		b.tables[i][key] = append(b.tables[i][key], int32(p))
+	}
+}
+
+// unhash removes position p from the MIH tables. The caller holds b.mu.
+func (b *BinaryIndex) unhash(p int) {
+	code := b.codes[p*b.words : (p+1)*b.words]
+	for i, span := range b.spans {
+		key := extractBits(code, span[0], span[1])
+		bucket := b.tables[i][key]
+		for j, q := range bucket {
+			if int(q) == p {
+				bucket[j] = bucket[len(bucket)-1]
+				bucket = bucket[:len(bucket)-1]
+				break
+			}
+		}
+		if len(bucket) == 0 {
+			delete(b.tables[i], key)
+		} else {
+			b.tables[i][key] = bucket
+		}
+	}
+}
+
+// pack converts a byte code into little-endian 64-bit words
+func (b *BinaryIndex) pack(code []byte) []uint64 {
+	var buf [8]byte
+	words := make([]uint64, b.words)
+	for i := range words {
+		clear(buf[:])
+		copy(buf[:], code[min(i*8, len(code)):min(i*8+8, len(code))])
+		words[i] = binary.LittleEndian.Uint64(buf[:])
+	}
+	return words
+}
+
+// extractBits returns length bits of words starting at bit start
+func extractBits(words []uint64, start, length int) uint64 {
+	w, off := start/64, start%64
+	v := words[w] >> off
+	if off+length > 64 && w+1 < len(words) {
+		v |= words[w+1] << (64 - off)
+	}
+	if length < 64 {
+		v &= 1<<length - 1
+	}
+	return v
+}
+
+// forEachWithinRadius calls fn for every length-bit key at exactly r bits
+// from key, enumerating flip masks in increasing order (Gosper's hack)
+func forEachWithinRadius(key uint64, length, r int, fn func(uint64)) {
+	if r == 0 {
+		fn(key)
+		return
+	}
+	limit := uint64(1) << length
+	for mask := uint64(1)<<r - 1; mask < limit; {
+		fn(key ^ mask)
+		c := mask & -mask
+		next := mask + c
+		if next == 0 {
+			return
+		}
+		mask = ((next^mask)>>2)/c | next
+	}
+}
+
+// binomial returns n choose k, saturating at a large bound
+func binomial(n, k int) int {
+	if k < 0 || k > n {
+		return 0
+	}
+	if k > n-k {
+		k = n - k
+	}
+	result := 1
+	for i := 1; i <= k; i++ {
+		result = result * (n - k + i) / i
+		if result > 1<<40 {
+			return 1 << 40
+		}
+	}
+	return result
+}
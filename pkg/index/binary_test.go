@@ -0,0 +1,130 @@
+package index
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func randomCode(rng *rand.Rand, numBits int) []byte {
+	code := make([]byte, (numBits+7)/8)
+	rng.Read(code)
+	if rem := numBits % 8; rem != 0 {
+		code[len(code)-1] &= 1<<rem - 1
+	}
+	return code
+}
+
+func TestBinaryIndexMIHMatchesScan(t *testing.T) {
+	for _, tc := range []struct{ bits, substrings int }{
+		{64, 4},
+		{100, 6}, // Uneven substrings spanning word boundaries
+		{256, 16},
+	} {
+		t.Run(fmt.Sprintf("%dbits_%dtables", tc.bits, tc.substrings), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(int64(tc.bits)))
+			idx := NewBinaryIndex(tc.bits, tc.substrings)
+
+			codes := make([][]byte, 2000)
+			for i := range codes {
+				codes[i] = randomCode(rng, tc.bits)
+				if err := idx.Add(fmt.Sprintf("c%d", i), codes[i]); err != nil {
+					t.Fatalf("Add failed: %v", err)
+				}
+			}
+
+			for i := 0; i < 20; i++ {
+				// Queries near a stored code exercise the early exit
+				query := append([]byte(nil), codes[rng.Intn(len(codes))]...)
+				query[0] ^= byte(rng.Intn(256))
+
+				ids, dists, err := idx.Search(query, 10)
+				if err != nil {
+					t.Fatalf("Search failed: %v", err)
+				}
+				_, want, _ := idx.SearchScan(query, 10)
+				if fmt.Sprint(dists) != fmt.Sprint(want) {
+					t.Fatalf("MIH distances %v differ from scan %v", dists, want)
+				}
+				if len(ids) != 10 {
+					t.Fatalf("Expected 10 results, got %d", len(ids))
+				}
+			}
+		})
+	}
+}
+
+func TestBinaryIndexUpdateDelete(t *testing.T) {
+	idx := NewBinaryIndex(16, 4)
+	mustAdd := func(id string, code []byte) {
+		if err := idx.Add(id, code); err != nil {
+			t.Fatalf("Add(%s) failed: %v", id, err)
+		}
+	}
+
+	mustAdd("a", []byte{0x00, 0x00})
+	mustAdd("b", []byte{0xff, 0x00})
+	mustAdd("c", []byte{0xff, 0xff})
+
+	ids, dists, _ := idx.Search([]byte{0x01, 0x00}, 1)
+	if len(ids) != 1 || ids[0] != "a" || dists[0] != 1 {
+		t.Fatalf("Expected a at distance 1, got %v %v", ids, dists)
+	}
+
+	// Replacing a code moves it in the MIH tables
+	mustAdd("a", []byte{0xff, 0xff})
+	ids, _, _ = idx.Search([]byte{0x00, 0x00}, 1)
+	if ids[0] != "b" {
+		t.Errorf("Expected b nearest after replacing a, got %v", ids)
+	}
+
+	// Deleting moves the last code into the freed slot
+	if err := idx.Delete("a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if idx.Contains("a") || idx.Size() != 2 {
+		t.Fatalf("Expected a removed, size %d", idx.Size())
+	}
+	ids, dists, _ = idx.Search([]byte{0xff, 0xff}, 2)
+	if fmt.Sprint(ids) != "[c b]" || fmt.Sprint(dists) != "[0 8]" {
+		t.Errorf("Expected [c b] at [0 8], got %v %v", ids, dists)
+	}
+	if err := idx.Delete("a"); err == nil {
+		t.Error("Expected an error deleting a missing code")
+	}
+	if err := idx.Add("bad", []byte{0x00}); err == nil {
+		t.Error("Expected an error for a short code")
+	}
+}
+
+func BenchmarkBinaryIndexSearch(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	scan := NewBinaryIndex(256, 0)
+	mih := NewBinaryIndex(256, 16)
+	// Codes cluster around centers, as binarized embeddings do
+	centers := make([][]byte, 1000)
+	for i := range centers {
+		centers[i] = randomCode(rng, 256)
+	}
+	for i := 0; i < 100000; i++ {
+		code := append([]byte(nil), centers[i%len(centers)]...)
+		for j := 0; j < 8; j++ {
+			code[rng.Intn(len(code))] ^= 1 << rng.Intn(8)
+		}
+		_ = scan.Add(fmt.Sprint(i), code)
+		_ = mih.Add(fmt.Sprint(i), code)
+	}
+	query := append([]byte(nil), centers[42]...)
+	query[3] ^= 0x11
+
+	b.Run("Scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, _ = scan.Search(query, 10)
+		}
+	})
+	b.Run("MIH", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, _ = mih.Search(query, 10)
+		}
+	})
+}
@@ -0,0 +1,943 @@
+package index
+
+// DiskANN keeps a Vamana proximity graph and the full vectors in a
+// memory-mapped file and only Product Quantization codes in RAM. Searches walk
+// the graph guided by PQ distances and rerank the visited nodes with the exact
+// vectors read from disk, so the Go heap grows with the PQ code size rather
+// than the vector size.
+//
+// File layout (little-endian):
+//
+//	header  64 bytes
+//	  0  magic "CXDA"         4  version uint32     8  dim uint32
+//	  12 R uint32             16 node count uint32  20 medoid uint32
+//	  24 flags uint32 (bit 0: vectors normalized)   28 reserved uint32
+//	  32 metadata offset uint64                     40 metadata length uint64
+//	  48 build ID uint64      56 metadata crc32     60 header crc32 (bytes 0-59)
+//	nodes   node count fixed-size records, starting at offset 64:
+//	          vector [dim]float32 | degree uint32 | neighbors [R]uint32
+//	meta    ids length uint64 | per ID: length uvarint | bytes
+//	        codebooks length uint64 | ProductQuantizer.SerializeCodebooks
+//	        PQ codes [node count][M]byte
+//
+// The graph is static. Vectors added after the build live in a small
+// in-memory set that is searched exhaustively, and deleted or replaced nodes
+// are tombstoned, until the owner rebuilds the file.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/simd"
+	"github.com/liliang-cn/cortexdb/v2/pkg/quantization"
+)
+
+// ErrCorruptDiskANN is returned when a DiskANN file fails validation
+var ErrCorruptDiskANN = errors.New("corrupt DiskANN file")
+
+const (
+	diskANNMagic      = "CXDA"
+	diskANNVersion    = 1
+	diskANNHeaderSize = 64
+
+	diskANNFlagNormalized = 1
+
+	// diskANNSampleSize caps the vectors kept in memory for PQ training
+	diskANNSampleSize = 65536
+)
+
+// DiskANNConfig configures building and searching a DiskANN index
+type DiskANNConfig struct {
+	R           int     // Maximum out-degree of the graph (default: 32)
+	L           int     // Candidate list size during build (default: 64)
+	Alpha       float32 // Pruning slack that keeps long-range edges (default: 1.2)
+	SearchL     int     // Candidate list size during search (default: 64)
+	PQSubspaces int     // PQ bytes per vector, must divide the dimension (0 = about dim/4)
+	Normalize   bool    // Normalize vectors so L2 ranking matches cosine similarity
+	Seed        int64   // Random seed for graph initialization (0 = time based)
+}
+
+// DefaultDiskANNConfig returns the default DiskANN configuration
+func DefaultDiskANNConfig() DiskANNConfig {
+	return DiskANNConfig{
+		R:       32,
+		L:       64,
+		Alpha:   1.2,
+		SearchL: 64,
+	}
+}
+
+// withDefaults fills unset fields for vectors of dimension dim
+func (c DiskANNConfig) withDefaults(dim int) DiskANNConfig {
+	def := DefaultDiskANNConfig()
+	if c.R <= 0 {
+		c.R = def.R
+	}
+	if c.L < c.R {
+		c.L = max(def.L, c.R)
+	}
+	if c.Alpha < 1 {
+		c.Alpha = def.Alpha
+	}
+	if c.SearchL <= 0 {
+		c.SearchL = def.SearchL
+	}
+	if c.PQSubspaces <= 0 || dim%c.PQSubspaces != 0 {
+		c.PQSubspaces = 1
+		for m := max(1, dim/4); m > 1; m-- {
+			if dim%m == 0 {
+				c.PQSubspaces = m
+				break
+			}
+		}
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+	return c
+}
+
+// DiskANN is an opened, memory-mapped DiskANN index
+type DiskANN struct {
+	path       string
+	file       *os.File
+	data       []byte // Memory-mapped file contents
+	dim        int
+	r          int
+	n          int
+	medoid     uint32
+	normalize  bool
+	buildID    uint64
+	recordSize int
+	searchL    int
+
+	ids     []string
+	idIndex map[string]uint32
+	pq      *quantization.ProductQuantizer
+	codes   []byte // PQ codes, n*pq.M bytes
+
+	deleted map[uint32]struct{}    // Tombstoned on-disk nodes
+	fresh   map[string][]float32   // Vectors added since the build
+	mu      sync.RWMutex
+}
+
+// OpenDiskANN memory-maps an index file written by DiskANNBuilder
+func OpenDiskANN(path string, searchL int) (*DiskANN, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := openDiskANN(file, searchL)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	d.path = path
+	return d, nil
+}
+
+func openDiskANN(file *os.File, searchL int) (*DiskANN, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size < diskANNHeaderSize {
+		return nil, fmt.Errorf("%w: file too small", ErrCorruptDiskANN)
+	}
+
+	data, err := mmapFile(file, int(size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to map index file: %w", err)
+	}
+
+	d, err := parseDiskANN(data)
+	if err != nil {
+		_ = munmapFile(data)
+		return nil, err
+	}
+	d.file = file
+	d.data = data
+	d.searchL = searchL
+	if d.searchL <= 0 {
+		d.searchL = DefaultDiskANNConfig().SearchL
+	}
+	return d, nil
+}
+
+// parseDiskANN validates the header and loads IDs, codebooks and PQ codes
+func parseDiskANN(data []byte) (*DiskANN, error) {
+	header := data[:diskANNHeaderSize]
+	if string(header[:4]) != diskANNMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrCorruptDiskANN)
+	}
+	if crc32.ChecksumIEEE(header[:60]) != binary.LittleEndian.Uint32(header[60:]) {
+		return nil, fmt.Errorf("%w: header checksum mismatch", ErrCorruptDiskANN)
+	}
+	if v := binary.LittleEndian.Uint32(header[4:]); v != diskANNVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrCorruptDiskANN, v)
+	}
+
+	d := &DiskANN{
+		dim:       int(binary.LittleEndian.Uint32(header[8:])),
+		r:         int(binary.LittleEndian.Uint32(header[12:])),
+		n:         int(binary.LittleEndian.Uint32(header[16:])),
+		medoid:    binary.LittleEndian.Uint32(header[20:]),
+		normalize: binary.LittleEndian.Uint32(header[24:])&diskANNFlagNormalized != 0,
+		buildID:   binary.LittleEndian.Uint64(header[48:]),
+		deleted:   make(map[uint32]struct{}),
+		fresh:     make(map[string][]float32),
+	}
+	d.recordSize = d.dim*4 + 4 + d.r*4
+
+	metaOffset := binary.LittleEndian.Uint64(header[32:])
+	metaLength := binary.LittleEndian.Uint64(header[40:])
+	if d.dim <= 0 || d.n <= 0 || int(d.medoid) >= d.n ||
+		metaOffset != uint64(diskANNHeaderSize+d.n*d.recordSize) ||
+		metaOffset+metaLength != uint64(len(data)) {
+		return nil, fmt.Errorf("%w: inconsistent header", ErrCorruptDiskANN)
+	}
+
+	meta := data[metaOffset:]
+	if crc32.ChecksumIEEE(meta) != binary.LittleEndian.Uint32(header[56:]) {
+		return nil, fmt.Errorf("%w: metadata checksum mismatch", ErrCorruptDiskANN)
+	}
+
+	// IDs
+	if len(meta) < 16 {
+		return nil, fmt.Errorf("%w: truncated metadata", ErrCorruptDiskANN)
+	}
+	idsLen := binary.LittleEndian.Uint64(meta)
+	if idsLen > uint64(len(meta)-16) {
+		return nil, fmt.Errorf("%w: truncated metadata", ErrCorruptDiskANN)
+	}
+	idData := meta[8 : 8+idsLen]
+	d.ids = make([]string, d.n)
+	d.idIndex = make(map[string]uint32, d.n)
+	for i := range d.ids {
+		l, k := binary.Uvarint(idData)
+		if k <= 0 || l > uint64(len(idData)-k) {
+			return nil, fmt.Errorf("%w: bad ID table", ErrCorruptDiskANN)
+		}
+		d.ids[i] = string(idData[k : k+int(l)])
+		d.idIndex[d.ids[i]] = uint32(i)
+		idData = idData[k+int(l):]
+	}
+	meta = meta[8+idsLen:]
+
+	// Codebooks and codes
+	cbLen := binary.LittleEndian.Uint64(meta)
+	if cbLen > uint64(len(meta)-8) {
+		return nil, fmt.Errorf("%w: truncated metadata", ErrCorruptDiskANN)
+	}
+	d.pq = &quantization.ProductQuantizer{}
+	if err := d.pq.DeserializeCodebooks(meta[8 : 8+cbLen]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptDiskANN, err)
+	}
+	codes := meta[8+cbLen:]
+	if d.pq.D != d.dim || len(codes) != d.n*d.pq.M {
+		return nil, fmt.Errorf("%w: PQ codes do not match the graph", ErrCorruptDiskANN)
+	}
+	d.codes = append([]byte(nil), codes...)
+
+	return d, nil
+}
+
+// Close unmaps and closes the index file
+func (d *DiskANN) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+	err := munmapFile(d.data)
+	if closeErr := d.file.Close(); err == nil {
+		err = closeErr
+	}
+	d.data = nil
+	d.file = nil
+	return err
+}
+
+// Path returns the index file path
+func (d *DiskANN) Path() string {
+	return d.path
+}
+
+// BuildID identifies the build that wrote the index file
+func (d *DiskANN) BuildID() uint64 {
+	return d.buildID
+}
+
+// Dimension returns the vector dimension
+func (d *DiskANN) Dimension() int {
+	return d.dim
+}
+
+// Add indexes a vector added after the build. If the ID exists on disk, the
+// on-disk node is tombstoned and replaced.
+func (d *DiskANN) Add(id string, vector []float32) error {
+	if len(vector) != d.dim {
+		return fmt.Errorf("vector dimension %d doesn't match index dimension %d", len(vector), d.dim)
+	}
+
+	vec := append([]float32(nil), vector...)
+	if d.normalize {
+		normalizeInPlace(vec)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if i, ok := d.idIndex[id]; ok {
+		d.deleted[i] = struct{}{}
+	}
+	d.fresh[id] = vec
+	return nil
+}
+
+// Delete removes a vector from the index
+func (d *DiskANN) Delete(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, inFresh := d.fresh[id]
+	delete(d.fresh, id)
+
+	if i, ok := d.idIndex[id]; ok {
+		if _, gone := d.deleted[i]; !gone {
+			d.deleted[i] = struct{}{}
+			return nil
+		}
+	}
+	if !inFresh {
+		return errors.New("node not found")
+	}
+	return nil
+}
+
+// Contains reports whether id is live in the index
+func (d *DiskANN) Contains(id string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.containsLocked(id)
+}
+
+func (d *DiskANN) containsLocked(id string) bool {
+	if _, ok := d.fresh[id]; ok {
+		return true
+	}
+	i, ok := d.idIndex[id]
+	if !ok {
+		return false
+	}
+	_, gone := d.deleted[i]
+	return !gone
+}
+
+// IDs returns the IDs of all live vectors
+func (d *DiskANN) IDs() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ids := make([]string, 0, d.n+len(d.fresh))
+	for i, id := range d.ids {
+		if _, gone := d.deleted[uint32(i)]; !gone {
+			ids = append(ids, id)
+		}
+	}
+	for id := range d.fresh {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// DeletedIDs returns the IDs of tombstoned on-disk nodes
+func (d *DiskANN) DeletedIDs() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ids := make([]string, 0, len(d.deleted))
+	for i := range d.deleted {
+		ids = append(ids, d.ids[i])
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// PendingChanges returns the number of additions and deletions since the build
+func (d *DiskANN) PendingChanges() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.deleted) + len(d.fresh)
+}
+
+// Size returns the number of live vectors
+func (d *DiskANN) Size() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.n - len(d.deleted) + len(d.fresh)
+}
+
+// Search returns the k nearest live vectors and their squared L2 distances
+// (on normalized vectors when the index normalizes)
+func (d *DiskANN) Search(query []float32, k int) ([]string, []float32) {
+	if len(query) != d.dim || k <= 0 {
+		return nil, nil
+	}
+
+	q := query
+	if d.normalize {
+		q = append([]float32(nil), query...)
+		normalizeInPlace(q)
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.data == nil {
+		return nil, nil
+	}
+
+	table := d.distanceTable(q)
+	l := max(d.searchL, k)
+
+	type candidate struct {
+		node     uint32
+		dist     float32
+		expanded bool
+	}
+	list := []candidate{{node: d.medoid, dist: d.pqDistance(table, d.medoid)}}
+	visited := map[uint32]struct{}{d.medoid: {}}
+
+	type result struct {
+		id   string
+		dist float32
+	}
+	var results []result
+
+	for {
+		next := -1
+		for i := range list {
+			if !list[i].expanded {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		list[next].expanded = true
+		node := list[next].node
+
+		// One read serves both the exact rerank and the adjacency list
+		vec, neighbors := d.record(node)
+		if _, gone := d.deleted[node]; !gone {
+			results = append(results, result{id: d.ids[node], dist: l2FromBytes(q, vec)})
+		}
+
+		for _, nb := range neighbors {
+			if _, seen := visited[nb]; seen || int(nb) >= d.n {
+				continue
+			}
+			visited[nb] = struct{}{}
+			dist := d.pqDistance(table, nb)
+			if len(list) >= l && dist >= list[len(list)-1].dist {
+				continue
+			}
+			pos := sort.Search(len(list), func(i int) bool { return list[i].dist > dist })
+			list = append(list, candidate{})
+			copy(list[pos+1:], list[pos:])
+			list[pos] = candidate{node: nb, dist: dist}
+			if len(list) > l {
+				list = list[:l]
+			}
+		}
+	}
+
+	for id, vec := range d.fresh {
+		results = append(results, result{id: id, dist: simd.SquaredL2(q, vec)})
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].dist < results[j].dist })
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	ids := make([]string, len(results))
+	dists := make([]float32, len(results))
+	for i, r := range results {
+		ids[i] = r.id
+		dists[i] = r.dist
+	}
+	return ids, dists
+}
+
+// record returns the raw vector bytes and the neighbors of node
+func (d *DiskANN) record(node uint32) ([]byte, []uint32) {
+	off := diskANNHeaderSize + int(node)*d.recordSize
+	rec := d.data[off : off+d.recordSize]
+	vec := rec[:d.dim*4]
+	degree := int(binary.LittleEndian.Uint32(rec[d.dim*4:]))
+	if degree > d.r {
+		degree = d.r
+	}
+	neighbors := make([]uint32, degree)
+	adj := rec[d.dim*4+4:]
+	for i := range neighbors {
+		neighbors[i] = binary.LittleEndian.Uint32(adj[i*4:])
+	}
+	return vec, neighbors
+}
+
+// distanceTable precomputes squared distances from the query subvectors to
+// every PQ centroid
+func (d *DiskANN) distanceTable(q []float32) [][]float32 {
+	table := make([][]float32, d.pq.M)
+	for m := range table {
+		sub := q[m*d.pq.SubDim : (m+1)*d.pq.SubDim]
+		table[m] = make([]float32, d.pq.K)
+		simd.SquaredL2Batch(sub, d.pq.Codebooks[m], table[m])
+	}
+	return table
+}
+
+// pqDistance is the asymmetric PQ distance between the query and node
+func (d *DiskANN) pqDistance(table [][]float32, node uint32) float32 {
+	codes := d.codes[int(node)*d.pq.M : (int(node)+1)*d.pq.M]
+	var dist float32
+	for m, c := range codes {
+		dist += table[m][c]
+	}
+	return dist
+}
+
+// DiskANNBuilder streams vectors into a new DiskANN file and builds the graph
+// without holding the full vectors on the Go heap
+type DiskANNBuilder struct {
+	path       string
+	tmpPath    string
+	file       *os.File
+	w          *bufio.Writer
+	cfg        DiskANNConfig
+	dim        int
+	recordSize int
+	ids        []string
+	sample     [][]float32
+	rng        *rand.Rand
+}
+
+// NewDiskANNBuilder starts building an index that will be written to path.
+// The file is assembled next to path and renamed into place by Build.
+func NewDiskANNBuilder(path string, dim int, cfg DiskANNConfig) (*DiskANNBuilder, error) {
+	if dim <= 0 {
+		return nil, errors.New("dimension must be positive")
+	}
+	cfg = cfg.withDefaults(dim)
+
+	tmpPath := path + ".building"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &DiskANNBuilder{
+		path:       path,
+		tmpPath:    tmpPath,
+		file:       file,
+		w:          bufio.NewWriterSize(file, 1<<20),
+		cfg:        cfg,
+		dim:        dim,
+		recordSize: dim*4 + 4 + cfg.R*4,
+		rng:        rand.New(rand.NewSource(cfg.Seed)),
+	}
+
+	// Header is written once the build is complete
+	if _, err := b.w.Write(make([]byte, diskANNHeaderSize)); err != nil {
+		b.Abort()
+		return nil, err
+	}
+	return b, nil
+}
+
+// Add appends a vector to the index file
+func (b *DiskANNBuilder) Add(id string, vector []float32) error {
+	if len(vector) != b.dim {
+		return fmt.Errorf("vector dimension %d doesn't match index dimension %d", len(vector), b.dim)
+	}
+
+	vec := vector
+	if b.cfg.Normalize {
+		vec = append([]float32(nil), vector...)
+		normalizeInPlace(vec)
+	}
+
+	rec := make([]byte, b.recordSize)
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(rec[i*4:], math.Float32bits(v))
+	}
+	if _, err := b.w.Write(rec); err != nil {
+		return err
+	}
+
+	// Reservoir sample for PQ training
+	b.ids = append(b.ids, id)
+	if len(b.sample) < diskANNSampleSize {
+		b.sample = append(b.sample, append([]float32(nil), vec...))
+	} else if j := b.rng.Intn(len(b.ids)); j < diskANNSampleSize {
+		b.sample[j] = append([]float32(nil), vec...)
+	}
+	return nil
+}
+
+// Len returns the number of vectors added so far
+func (b *DiskANNBuilder) Len() int {
+	return len(b.ids)
+}
+
+// Abort discards the partially built file
+func (b *DiskANNBuilder) Abort() {
+	if b.file != nil {
+		_ = b.file.Close()
+		b.file = nil
+	}
+	_ = os.Remove(b.tmpPath)
+}
+
+// Build trains PQ, constructs the Vamana graph, writes the file and opens it
+func (b *DiskANNBuilder) Build() (*DiskANN, error) {
+	d, err := b.build()
+	if err != nil {
+		b.Abort()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (b *DiskANNBuilder) build() (*DiskANN, error) {
+	n := len(b.ids)
+	if n == 0 {
+		return nil, errors.New("no vectors to index")
+	}
+	if err := b.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	data, err := mmapFile(b.file, diskANNHeaderSize+n*b.recordSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map index file: %w", err)
+	}
+	defer func() { _ = munmapFile(data) }()
+
+	g := &vamanaBuilder{
+		data:       data,
+		dim:        b.dim,
+		recordSize: b.recordSize,
+		n:          n,
+		cfg:        b.cfg,
+		rng:        b.rng,
+		vecA:       make([]float32, b.dim),
+		vecB:       make([]float32, b.dim),
+	}
+
+	// Train PQ on the sample and encode every vector
+	m := b.cfg.PQSubspaces
+	k := min(256, len(b.sample)/m)
+	if k < 1 {
+		k = 1
+	}
+	pq, err := quantization.NewProductQuantizer(b.dim, m, k)
+	if err != nil {
+		return nil, err
+	}
+	if err := pq.Train(b.sample); err != nil {
+		return nil, fmt.Errorf("failed to train PQ: %w", err)
+	}
+	b.sample = nil
+
+	codes := make([]byte, 0, n*m)
+	for i := 0; i < n; i++ {
+		c, err := pq.Encode(g.vector(uint32(i), g.vecA))
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c...)
+	}
+
+	medoid := g.build()
+
+	// Write adjacency lists into the node records
+	adj := make([]byte, 4+b.cfg.R*4)
+	for i, neighbors := range g.adj {
+		clear(adj)
+		binary.LittleEndian.PutUint32(adj, uint32(len(neighbors)))
+		for j, nb := range neighbors {
+			binary.LittleEndian.PutUint32(adj[4+j*4:], nb)
+		}
+		off := int64(diskANNHeaderSize + i*b.recordSize + b.dim*4)
+		if _, err := b.file.WriteAt(adj, off); err != nil {
+			return nil, err
+		}
+	}
+
+	// Metadata: IDs, codebooks and codes
+	var idData []byte
+	for _, id := range b.ids {
+		idData = binary.AppendUvarint(idData, uint64(len(id)))
+		idData = append(idData, id...)
+	}
+	codebooks := pq.SerializeCodebooks()
+	meta := binary.LittleEndian.AppendUint64(nil, uint64(len(idData)))
+	meta = append(meta, idData...)
+	meta = binary.LittleEndian.AppendUint64(meta, uint64(len(codebooks)))
+	meta = append(meta, codebooks...)
+	meta = append(meta, codes...)
+
+	metaOffset := int64(diskANNHeaderSize + n*b.recordSize)
+	if _, err := b.file.WriteAt(meta, metaOffset); err != nil {
+		return nil, err
+	}
+
+	var flags uint32
+	if b.cfg.Normalize {
+		flags |= diskANNFlagNormalized
+	}
+	header := []byte(diskANNMagic)
+	header = binary.LittleEndian.AppendUint32(header, diskANNVersion)
+	header = binary.LittleEndian.AppendUint32(header, uint32(b.dim))
+	header = binary.LittleEndian.AppendUint32(header, uint32(b.cfg.R))
+	header = binary.LittleEndian.AppendUint32(header, uint32(n))
+	header = binary.LittleEndian.AppendUint32(header, medoid)
+	header = binary.LittleEndian.AppendUint32(header, flags)
+	header = binary.LittleEndian.AppendUint32(header, 0)
+	header = binary.LittleEndian.AppendUint64(header, uint64(metaOffset))
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(meta)))
+	header = binary.LittleEndian.AppendUint64(header, uint64(b.rng.Int63()))
+	header = binary.LittleEndian.AppendUint32(header, crc32.ChecksumIEEE(meta))
+	header = binary.LittleEndian.AppendUint32(header, crc32.ChecksumIEEE(header))
+	if _, err := b.file.WriteAt(header, 0); err != nil {
+		return nil, err
+	}
+
+	if err := b.file.Sync(); err != nil {
+		return nil, err
+	}
+	if err := b.file.Close(); err != nil {
+		return nil, err
+	}
+	b.file = nil
+
+	if err := os.Rename(b.tmpPath, b.path); err != nil {
+		return nil, err
+	}
+	return OpenDiskANN(b.path, b.cfg.SearchL)
+}
+
+// vamanaBuilder constructs the graph over vectors read from the mapped file
+type vamanaBuilder struct {
+	data       []byte
+	dim        int
+	recordSize int
+	n          int
+	cfg        DiskANNConfig
+	rng        *rand.Rand
+	adj        [][]uint32
+	vecA, vecB []float32
+}
+
+// vector decodes the vector of node into buf
+func (g *vamanaBuilder) vector(node uint32, buf []float32) []float32 {
+	off := diskANNHeaderSize + int(node)*g.recordSize
+	raw := g.data[off : off+g.dim*4]
+	for i := range buf {
+		buf[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+	}
+	return buf
+}
+
+// dist is the squared L2 distance between two nodes
+func (g *vamanaBuilder) dist(a, b uint32) float32 {
+	off := diskANNHeaderSize + int(b)*g.recordSize
+	return l2FromBytes(g.vector(a, g.vecA), g.data[off:off+g.dim*4])
+}
+
+// distTo is the squared L2 distance between a vector and a node
+func (g *vamanaBuilder) distTo(vec []float32, node uint32) float32 {
+	off := diskANNHeaderSize + int(node)*g.recordSize
+	return l2FromBytes(vec, g.data[off:off+g.dim*4])
+}
+
+type scoredNode struct {
+	node uint32
+	dist float32
+}
+
+// build runs the two Vamana passes and returns the medoid
+func (g *vamanaBuilder) build() uint32 {
+	// Medoid: the node closest to the mean vector
+	mean := make([]float32, g.dim)
+	for i := 0; i < g.n; i++ {
+		for j, v := range g.vector(uint32(i), g.vecB) {
+			mean[j] += v
+		}
+	}
+	for j := range mean {
+		mean[j] /= float32(g.n)
+	}
+	medoid := uint32(0)
+	best := float32(math.MaxFloat32)
+	for i := 0; i < g.n; i++ {
+		if d := g.distTo(mean, uint32(i)); d < best {
+			best, medoid = d, uint32(i)
+		}
+	}
+
+	// Random initial graph
+	degree := min(g.cfg.R, g.n-1)
+	g.adj = make([][]uint32, g.n)
+	for i := range g.adj {
+		g.adj[i] = make([]uint32, 0, g.cfg.R)
+		seen := map[uint32]struct{}{uint32(i): {}}
+		for len(g.adj[i]) < degree {
+			j := uint32(g.rng.Intn(g.n))
+			if _, dup := seen[j]; !dup {
+				seen[j] = struct{}{}
+				g.adj[i] = append(g.adj[i], j)
+			}
+		}
+	}
+
+	query := make([]float32, g.dim)
+	for _, alpha := range []float32{1, g.cfg.Alpha} {
+		for _, p := range g.rng.Perm(g.n) {
+			node := uint32(p)
+			copy(query, g.vector(node, g.vecB))
+			visited := g.greedySearch(medoid, query)
+			for _, nb := range g.adj[node] {
+				visited = append(visited, scoredNode{node: nb, dist: g.distTo(query, nb)})
+			}
+			g.adj[node] = g.robustPrune(node, visited, alpha)
+
+			for _, nb := range g.adj[node] {
+				if containsNode(g.adj[nb], node) {
+					continue
+				}
+				if len(g.adj[nb]) < g.cfg.R {
+					g.adj[nb] = append(g.adj[nb], node)
+					continue
+				}
+				candidates := make([]scoredNode, 0, len(g.adj[nb])+1)
+				for _, c := range append(g.adj[nb], node) {
+					candidates = append(candidates, scoredNode{node: c, dist: g.dist(nb, c)})
+				}
+				g.adj[nb] = g.robustPrune(nb, candidates, alpha)
+			}
+		}
+	}
+	return medoid
+}
+
+// greedySearch returns every node expanded while searching for query
+func (g *vamanaBuilder) greedySearch(start uint32, query []float32) []scoredNode {
+	list := []scoredNode{{node: start, dist: g.distTo(query, start)}}
+	expanded := map[uint32]struct{}{}
+	seen := map[uint32]struct{}{start: {}}
+	var visited []scoredNode
+
+	for {
+		next := -1
+		for i, c := range list {
+			if _, done := expanded[c.node]; !done {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return visited
+		}
+		current := list[next]
+		expanded[current.node] = struct{}{}
+		visited = append(visited, current)
+
+		for _, nb := range g.adj[current.node] {
+			if _, ok := seen[nb]; ok {
+				continue
+			}
+			seen[nb] = struct{}{}
+			list = append(list, scoredNode{node: nb, dist: g.distTo(query, nb)})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].dist < list[j].dist })
+		if len(list) > g.cfg.L {
+			list = list[:g.cfg.L]
+		}
+	}
+}
+
+// robustPrune keeps up to R neighbors of node, dropping candidates that are
+// alpha-dominated by a closer kept neighbor
+func (g *vamanaBuilder) robustPrune(node uint32, candidates []scoredNode, alpha float32) []uint32 {
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+
+	kept := make([]uint32, 0, g.cfg.R)
+	removed := make([]bool, len(candidates))
+	for i, c := range candidates {
+		if removed[i] || c.node == node || containsNode(kept, c.node) {
+			continue
+		}
+		kept = append(kept, c.node)
+		if len(kept) == g.cfg.R {
+			break
+		}
+		for j := i + 1; j < len(candidates); j++ {
+			if !removed[j] && alpha*alpha*g.dist(c.node, candidates[j].node) <= candidates[j].dist {
+				removed[j] = true
+			}
+		}
+	}
+	return kept
+}
+
+func containsNode(nodes []uint32, node uint32) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// l2FromBytes is the squared L2 distance between q and a little-endian
+// float32 vector
+func l2FromBytes(q []float32, raw []byte) float32 {
+	var sum float32
+	for i, v := range q {
+		diff := v - math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+		sum += diff * diff
+	}
+	return sum
+}
+
+func normalizeInPlace(v []float32) {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return
+	}
+	inv := float32(1 / math.Sqrt(norm))
+	for i := range v {
+		v[i] *= inv
+	}
+}
@@ -0,0 +1,170 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/simd"
+)
+
+func TestDiskANN(t *testing.T) {
+	const (
+		n   = 1000
+		dim = 16
+	)
+
+	randomVec := newRandomVec(3, dim)
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = randomVec()
+	}
+
+	path := filepath.Join(t.TempDir(), "index.diskann")
+	builder, err := NewDiskANNBuilder(path, dim, DiskANNConfig{R: 24, L: 48, Seed: 1})
+	if err != nil {
+		t.Fatalf("NewDiskANNBuilder failed: %v", err)
+	}
+	for i, vec := range vectors {
+		if err := builder.Add(fmt.Sprintf("vec_%d", i), vec); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	idx, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	defer idx.Close()
+
+	if _, err := os.Stat(path + ".building"); !os.IsNotExist(err) {
+		t.Error("Temporary build file should be renamed into place")
+	}
+	if idx.Size() != n {
+		t.Errorf("Expected %d vectors, got %d", n, idx.Size())
+	}
+
+	bruteForce := func(query []float32, k int) []string {
+		type pair struct {
+			id   string
+			dist float32
+		}
+		pairs := make([]pair, n)
+		for i, vec := range vectors {
+			pairs[i] = pair{fmt.Sprintf("vec_%d", i), simd.SquaredL2(query, vec)}
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].dist < pairs[j].dist })
+		ids := make([]string, k)
+		for i := range ids {
+			ids[i] = pairs[i].id
+		}
+		return ids
+	}
+
+	t.Run("Recall", func(t *testing.T) {
+		const k = 10
+		hits, total := 0, 0
+		for q := 0; q < 50; q++ {
+			query := randomVec()
+			got, _ := idx.Search(query, k)
+			want := make(map[string]bool)
+			for _, id := range bruteForce(query, k) {
+				want[id] = true
+			}
+			for _, id := range got {
+				if want[id] {
+					hits++
+				}
+			}
+			total += k
+		}
+		recall := float64(hits) / float64(total)
+		if recall < 0.9 {
+			t.Errorf("Expected recall@10 >= 0.9, got %.2f", recall)
+		}
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		if err := idx.Delete("vec_5"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		ids, _ := idx.Search(vectors[5], 5)
+		for _, id := range ids {
+			if id == "vec_5" {
+				t.Error("Deleted vector returned by search")
+			}
+		}
+
+		moved := make([]float32, dim)
+		for j := range moved {
+			moved[j] = 5
+		}
+		if err := idx.Add("vec_7", moved); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if err := idx.Add("new", vectors[9]); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+
+		ids, _ = idx.Search(moved, 1)
+		if len(ids) != 1 || ids[0] != "vec_7" {
+			t.Errorf("Expected replaced vector to be found at its new position, got %v", ids)
+		}
+		ids, _ = idx.Search(vectors[7], 3)
+		for _, id := range ids {
+			if id == "vec_7" {
+				t.Error("Replaced vector found at its old position")
+			}
+		}
+		if !idx.Contains("new") || idx.Contains("vec_5") {
+			t.Error("Contains does not reflect pending changes")
+		}
+		if idx.PendingChanges() != 4 || idx.Size() != n {
+			t.Errorf("Expected 4 pending changes and %d vectors, got %d and %d", n, idx.PendingChanges(), idx.Size())
+		}
+		if deleted := idx.DeletedIDs(); len(deleted) != 2 {
+			t.Errorf("Expected 2 tombstones, got %v", deleted)
+		}
+	})
+
+	t.Run("Reopen", func(t *testing.T) {
+		reopened, err := OpenDiskANN(path, 64)
+		if err != nil {
+			t.Fatalf("OpenDiskANN failed: %v", err)
+		}
+		defer reopened.Close()
+
+		if reopened.BuildID() != idx.BuildID() {
+			t.Error("Expected same build ID")
+		}
+		ids, _ := reopened.Search(vectors[42], 1)
+		if len(ids) != 1 || ids[0] != "vec_42" {
+			t.Errorf("Expected vec_42, got %v", ids)
+		}
+	})
+
+	t.Run("Corruption", func(t *testing.T) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		corruptPath := filepath.Join(t.TempDir(), "corrupt.diskann")
+
+		data[len(data)-1] ^= 0xFF
+		if err := os.WriteFile(corruptPath, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenDiskANN(corruptPath, 64); !errors.Is(err, ErrCorruptDiskANN) {
+			t.Errorf("Expected ErrCorruptDiskANN for corrupt metadata, got %v", err)
+		}
+
+		if err := os.WriteFile(corruptPath, data[:len(data)/2], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenDiskANN(corruptPath, 64); !errors.Is(err, ErrCorruptDiskANN) {
+			t.Errorf("Expected ErrCorruptDiskANN for truncated file, got %v", err)
+		}
+	})
+}
@@ -2,7 +2,9 @@ package index
 
 import (
 	"container/heap"
+	"encoding/gob"
 	"fmt"
+	"io"
 	"math"
 	"sync"
 )
@@ -168,6 +170,53 @@ func (f *FlatIndex) Clear() {
 	f.vectors = make(map[string][]float32)
 }
 
+// flatSnapshot is the serialized form of a FlatIndex
+type flatSnapshot struct {
+	Dimension  int
+	Normalized bool
+	Vectors    map[string][]float32
+}
+
+// Save serializes the index to a writer
+func (f *FlatIndex) Save(w io.Writer) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return gob.NewEncoder(w).Encode(flatSnapshot{
+		Dimension:  f.dimension,
+		Normalized: f.normalized,
+		Vectors:    f.vectors,
+	})
+}
+
+// Load deserializes the index from a reader. The distance function of the
+// receiver is kept.
+func (f *FlatIndex) Load(r io.Reader) error {
+	var snap flatSnapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return err
+	}
+	for id, vector := range snap.Vectors {
+		if len(vector) != snap.Dimension {
+			return fmt.Errorf("invalid flat snapshot: vector %s has dimension %d, expected %d", id, len(vector), snap.Dimension)
+		}
+	}
+	if snap.Vectors == nil {
+		snap.Vectors = make(map[string][]float32)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.dimension = snap.Dimension
+	f.normalized = snap.Normalized
+	f.vectors = snap.Vectors
+	if f.distFunc == nil {
+		f.distFunc = EuclideanDistance
+	}
+	return nil
+}
+
 // GetVector returns a copy of the vector for the given ID
 func (f *FlatIndex) GetVector(id string) ([]float32, bool) {
 	f.mu.RLock()
@@ -0,0 +1,16 @@
+package index
+
+import "math/rand"
+
+// newRandomVec returns a generator of dim-sized vectors with components in
+// [0, 1), drawn from a seeded source so runs are repeatable
+func newRandomVec(seed int64, dim int) func() []float32 {
+	rng := rand.New(rand.NewSource(seed))
+	return func() []float32 {
+		vec := make([]float32, dim)
+		for j := range vec {
+			vec[j] = rng.Float32()
+		}
+		return vec
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/simd"
 )
 
 // Quantizer defines interface for vector quantization
@@ -51,6 +53,9 @@ type HNSW struct {
 	// Thread safety
 	mu sync.RWMutex
 	rng *rand.Rand
+
+	// Nodes changed since the last snapshot (nil until one is written or read)
+	dirty map[string]struct{}
 }
 
 // NewHNSW creates a new HNSW index
@@ -142,6 +147,7 @@ func (h *HNSW) Load(r io.Reader) error {
 	// Decode nodes count
 	var count int
 	if err := dec.Decode(&count); err != nil { return err }
+	h.dirty = nil
 
 	// Decode nodes
 	h.Nodes = make(map[string]*HNSWNode, count)
@@ -175,7 +181,8 @@ func (h *HNSW) Insert(id string, vector []float32) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	
-	if _, exists := h.Nodes[id]; exists {
+	existing, exists := h.Nodes[id]
+	if exists && !existing.Deleted {
 		return fmt.Errorf("node %s already exists", id)
 	}
 	
@@ -196,8 +203,12 @@ func (h *HNSW) Insert(id string, vector []float32) error {
 		}
 	}
 	
-	// Create new node
+	// Create new node. A deleted ID keeps its level so that edges other
+	// nodes still hold to it stay within the new node's layers.
 	level := h.selectLevel()
+	if exists {
+		level = existing.Level
+	}
 	node := &HNSWNode{
 		ID:        id,
 		Vector:    storedVector,
@@ -212,9 +223,10 @@ func (h *HNSW) Insert(id string, vector []float32) error {
 	}
 	
 	h.Nodes[id] = node
+	h.markDirty(id)
 	
 	// If this is the first node, set as entry point
-	if h.EntryPoint == "" {
+	if h.EntryPoint == "" || h.EntryPoint == id {
 		h.EntryPoint = id
 		return nil
 	}
@@ -417,6 +429,7 @@ func (h *HNSW) addConnection(from, to string, layer int) {
 	}
 	
 	fromNode.Neighbors[layer] = append(fromNode.Neighbors[layer], to)
+	h.markDirty(from)
 }
 
 // Search performs k-NN search
@@ -491,6 +504,7 @@ func (h *HNSW) Delete(id string) error {
 	}
 	
 	node.Deleted = true
+	h.markDirty(id)
 	
 	// If this was the entry point, find a new one
 	if h.EntryPoint == id {
@@ -593,38 +607,17 @@ func (h *distHeap) Pop() interface{} {
 
 // EuclideanDistance computes Euclidean distance
 func EuclideanDistance(a, b []float32) float32 {
-	var sum float32
-	for i := range a {
-		diff := a[i] - b[i]
-		sum += diff * diff
-	}
-	return float32(math.Sqrt(float64(sum)))
+	return float32(math.Sqrt(float64(simd.SquaredL2(a, b))))
 }
 
 // CosineDistance computes cosine distance (1 - cosine similarity)
 func CosineDistance(a, b []float32) float32 {
-	var dotProduct, normA, normB float32
-	for i := range a {
-		dotProduct += a[i] * b[i]
-		normA += a[i] * a[i]
-		normB += b[i] * b[i]
-	}
-	
-	if normA == 0 || normB == 0 {
-		return 1.0
-	}
-	
-	similarity := dotProduct / (float32(math.Sqrt(float64(normA))) * float32(math.Sqrt(float64(normB))))
-	return 1.0 - similarity
+	return 1.0 - simd.Cosine(a, b)
 }
 
 // DotProductDistance computes negative dot product (for similarity)
 func DotProductDistance(a, b []float32) float32 {
-	var dotProduct float32
-	for i := range a {
-		dotProduct += a[i] * b[i]
-	}
-	return -dotProduct // Negative so smaller is better
+	return -simd.Dot(a, b) // Negative so smaller is better
 }
 
 // InsertBatch inserts multiple vectors into the index efficiently
@@ -669,6 +662,7 @@ func (h *HNSW) InsertBatch(vectors []struct {
 		}
 
 		h.Nodes[v.ID] = node
+		h.markDirty(v.ID)
 
 		// If this is the first node, set as entry point
 		if h.EntryPoint == "" {
@@ -899,6 +893,7 @@ func (h *HNSW) InsertBatchParallel(vectors []struct {
 		for id, node := range pg.nodes {
 			if _, exists := h.Nodes[id]; !exists {
 				h.Nodes[id] = node
+				h.markDirty(id)
 			}
 		}
 	}
@@ -0,0 +1,530 @@
+package index
+
+// HNSW snapshot format
+//
+// Snapshots persist the graph structure only. Vectors are not duplicated: the
+// owner of the index reloads them from its own storage with SetVector after
+// reading a snapshot, then calls DropUnresolved to discard nodes whose vectors
+// are gone.
+//
+// A snapshot is either a full snapshot or a delta segment holding the nodes
+// that changed since the previous snapshot. Both share one layout; integers
+// are little-endian and "uvarint" is the encoding/binary unsigned varint.
+//
+//	header   magic "CXHG" | version uint16 | kind uint8 (1 full, 2 delta) | reserved uint8
+//	section  tag uint8 | length uint32 | payload [length]byte | crc32 uint32 (IEEE, of payload)
+//
+// Sections appear exactly once each, in this order:
+//
+//	META  (1)  M uvarint | efConstruction uvarint | entry point uvarint | record count uvarint
+//	IDS   (2)  count uvarint, then per ID: length uvarint | bytes
+//	GRAPH (3)  per record: flags uint8 (bit 0 deleted) | level uvarint |
+//	           per level 0..level: neighbor count uvarint | neighbor compact IDs uvarint
+//	END   (4)  empty payload
+//
+// A node's compact ID is its position in the IDS table. The entry point is
+// stored as compact ID + 1, with 0 meaning the index is empty. GRAPH holds one
+// record for each of the first "record count" IDs; any further IDs are only
+// referenced as neighbors. Full snapshots omit deleted nodes. Delta records
+// replace the stored node, and a record flagged deleted removes it.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+)
+
+// ErrCorruptSnapshot is returned when a snapshot fails validation
+var ErrCorruptSnapshot = errors.New("corrupt HNSW snapshot")
+
+const (
+	snapshotMagic   = "CXHG"
+	snapshotVersion = 1
+
+	snapshotKindFull  = 1
+	snapshotKindDelta = 2
+
+	sectionMeta  = 1
+	sectionIDs   = 2
+	sectionGraph = 3
+	sectionEnd   = 4
+
+	snapshotMaxLevel = 64
+)
+
+// snapshotRecord is a decoded GRAPH record
+type snapshotRecord struct {
+	id        string
+	deleted   bool
+	level     int
+	neighbors [][]string
+}
+
+// decodedSnapshot is a fully validated snapshot
+type decodedSnapshot struct {
+	m              int
+	efConstruction int
+	entryPoint     string
+	records        []snapshotRecord
+}
+
+// WriteSnapshot writes a full snapshot of the graph and starts tracking
+// changes for WriteDelta
+func (h *HNSW) WriteSnapshot(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ids := make([]string, 0, len(h.Nodes))
+	for id, node := range h.Nodes {
+		if !node.Deleted {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	if err := h.writeSnapshot(w, snapshotKindFull, ids); err != nil {
+		return err
+	}
+	h.dirty = make(map[string]struct{})
+	return nil
+}
+
+// WriteDelta writes the nodes changed since the last snapshot and returns
+// how many were written. It fails if no snapshot has been written or read yet.
+func (h *HNSW) WriteDelta(w io.Writer) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.dirty == nil {
+		return 0, errors.New("no base snapshot to write a delta against")
+	}
+
+	ids := make([]string, 0, len(h.dirty))
+	for id := range h.dirty {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	if err := h.writeSnapshot(w, snapshotKindDelta, ids); err != nil {
+		return 0, err
+	}
+	h.dirty = make(map[string]struct{})
+	return len(ids), nil
+}
+
+// PendingChanges returns the number of nodes changed since the last
+// snapshot, or -1 if the index has no base snapshot
+func (h *HNSW) PendingChanges() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.dirty == nil {
+		return -1
+	}
+	return len(h.dirty)
+}
+
+// ResetSnapshotTracking forgets the base snapshot, so the next snapshot must
+// be a full one. Call it when persisting a snapshot failed.
+func (h *HNSW) ResetSnapshotTracking() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dirty = nil
+}
+
+// ReadSnapshot replaces the graph with a full snapshot. Nodes have no vector
+// data until SetVector is called for them.
+func (h *HNSW) ReadSnapshot(r io.Reader) error {
+	snap, err := decodeSnapshot(r, snapshotKindFull)
+	if err != nil {
+		return err
+	}
+
+	nodes := make(map[string]*HNSWNode, len(snap.records))
+	for _, rec := range snap.records {
+		if !rec.deleted {
+			nodes[rec.id] = &HNSWNode{ID: rec.id, Level: rec.level, Neighbors: rec.neighbors}
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.M = snap.m
+	h.MaxM = snap.m * 2
+	h.EfConstruction = snap.efConstruction
+	h.Nodes = nodes
+	h.EntryPoint = snap.entryPoint
+	h.dirty = make(map[string]struct{})
+	return nil
+}
+
+// ApplyDelta applies a delta segment on top of a previously read snapshot.
+// Vector data of replaced nodes is kept.
+func (h *HNSW) ApplyDelta(r io.Reader) error {
+	snap, err := decodeSnapshot(r, snapshotKindDelta)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, rec := range snap.records {
+		if rec.deleted {
+			delete(h.Nodes, rec.id)
+			continue
+		}
+		node, exists := h.Nodes[rec.id]
+		if !exists {
+			node = &HNSWNode{ID: rec.id}
+			h.Nodes[rec.id] = node
+		}
+		node.Deleted = false
+		node.Level = rec.level
+		node.Neighbors = rec.neighbors
+	}
+	h.EntryPoint = snap.entryPoint
+	return nil
+}
+
+// SetVector attaches vector data to a node read from a snapshot. It returns
+// false if the node is not in the index.
+func (h *HNSW) SetVector(id string, vector []float32) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	node, exists := h.Nodes[id]
+	if !exists {
+		return false
+	}
+
+	node.Vector = vector
+	node.Quantized = nil
+	if h.Quantizer != nil {
+		if quantized, err := h.Quantizer.Encode(vector); err == nil {
+			node.Vector = nil
+			node.Quantized = quantized
+		}
+	}
+	return true
+}
+
+// DropUnresolved removes nodes without vector data, strips neighbor links to
+// nodes that no longer exist and repairs the entry point. It returns the
+// number of nodes removed.
+func (h *HNSW) DropUnresolved() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	dropped := 0
+	for id, node := range h.Nodes {
+		if node.Vector == nil && node.Quantized == nil {
+			delete(h.Nodes, id)
+			h.markDirty(id)
+			dropped++
+		}
+	}
+
+	for _, node := range h.Nodes {
+		for lc, neighbors := range node.Neighbors {
+			kept := neighbors[:0]
+			for _, neighbor := range neighbors {
+				if _, exists := h.Nodes[neighbor]; exists {
+					kept = append(kept, neighbor)
+				}
+			}
+			node.Neighbors[lc] = kept
+		}
+	}
+
+	if _, exists := h.Nodes[h.EntryPoint]; !exists {
+		h.EntryPoint = ""
+		for id, node := range h.Nodes {
+			if h.EntryPoint == "" || node.Level > h.Nodes[h.EntryPoint].Level {
+				h.EntryPoint = id
+			}
+		}
+	}
+
+	return dropped
+}
+
+// markDirty records a node change for the next delta snapshot
+func (h *HNSW) markDirty(id string) {
+	if h.dirty != nil {
+		h.dirty[id] = struct{}{}
+	}
+}
+
+// writeSnapshot encodes the records for ids; the caller holds h.mu
+func (h *HNSW) writeSnapshot(w io.Writer, kind byte, ids []string) error {
+	compact := make(map[string]uint64, len(ids))
+	table := make([]string, 0, len(ids))
+	intern := func(id string) uint64 {
+		if c, ok := compact[id]; ok {
+			return c
+		}
+		c := uint64(len(table))
+		compact[id] = c
+		table = append(table, id)
+		return c
+	}
+	for _, id := range ids {
+		intern(id)
+	}
+
+	var graph []byte
+	for _, id := range ids {
+		node, exists := h.Nodes[id]
+		if !exists || (node.Deleted && kind == snapshotKindDelta) {
+			graph = append(graph, 1)
+			graph = binary.AppendUvarint(graph, 0)
+			graph = binary.AppendUvarint(graph, 0)
+			continue
+		}
+
+		graph = append(graph, 0)
+		graph = binary.AppendUvarint(graph, uint64(node.Level))
+		for lc := 0; lc <= node.Level; lc++ {
+			var neighbors []string
+			if lc < len(node.Neighbors) {
+				neighbors = node.Neighbors[lc]
+			}
+			live := neighbors[:0:0]
+			for _, neighbor := range neighbors {
+				if n, ok := h.Nodes[neighbor]; ok && !n.Deleted {
+					live = append(live, neighbor)
+				}
+			}
+			graph = binary.AppendUvarint(graph, uint64(len(live)))
+			for _, neighbor := range live {
+				graph = binary.AppendUvarint(graph, intern(neighbor))
+			}
+		}
+	}
+
+	var entry uint64
+	if ep, ok := h.Nodes[h.EntryPoint]; ok && !ep.Deleted {
+		entry = intern(h.EntryPoint) + 1
+	}
+
+	var meta []byte
+	meta = binary.AppendUvarint(meta, uint64(h.M))
+	meta = binary.AppendUvarint(meta, uint64(h.EfConstruction))
+	meta = binary.AppendUvarint(meta, entry)
+	meta = binary.AppendUvarint(meta, uint64(len(ids)))
+
+	var idTable []byte
+	idTable = binary.AppendUvarint(idTable, uint64(len(table)))
+	for _, id := range table {
+		idTable = binary.AppendUvarint(idTable, uint64(len(id)))
+		idTable = append(idTable, id...)
+	}
+
+	bw := bufio.NewWriter(w)
+	header := []byte(snapshotMagic)
+	header = binary.LittleEndian.AppendUint16(header, snapshotVersion)
+	header = append(header, kind, 0)
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+	for _, section := range []struct {
+		tag     byte
+		payload []byte
+	}{
+		{sectionMeta, meta},
+		{sectionIDs, idTable},
+		{sectionGraph, graph},
+		{sectionEnd, nil},
+	} {
+		if err := writeSection(bw, section.tag, section.payload); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeSection writes one checksummed section
+func writeSection(w io.Writer, tag byte, payload []byte) error {
+	buf := make([]byte, 0, 9+len(payload))
+	buf = append(buf, tag)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(payload)))
+	buf = append(buf, payload...)
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(payload))
+	_, err := w.Write(buf)
+	return err
+}
+
+// corrupt builds an ErrCorruptSnapshot error
+func corrupt(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrCorruptSnapshot, fmt.Sprintf(format, args...))
+}
+
+// decodeSnapshot reads and validates a complete snapshot of the given kind
+// without touching any index
+func decodeSnapshot(r io.Reader, kind byte) (*decodedSnapshot, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 8 || string(data[:4]) != snapshotMagic {
+		return nil, corrupt("bad magic")
+	}
+	if v := binary.LittleEndian.Uint16(data[4:6]); v != snapshotVersion {
+		return nil, corrupt("unsupported version %d", v)
+	}
+	if data[6] != kind {
+		return nil, corrupt("expected snapshot kind %d, got %d", kind, data[6])
+	}
+	data = data[8:]
+
+	sections := make([][]byte, 0, 4)
+	for tag := byte(sectionMeta); tag <= sectionEnd; tag++ {
+		if len(data) < 5 {
+			return nil, corrupt("truncated section %d", tag)
+		}
+		if data[0] != tag {
+			return nil, corrupt("expected section %d, got %d", tag, data[0])
+		}
+		n := int(binary.LittleEndian.Uint32(data[1:5]))
+		if n > len(data)-9 {
+			return nil, corrupt("truncated section %d", tag)
+		}
+		payload := data[5 : 5+n]
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(data[5+n:9+n]) {
+			return nil, corrupt("checksum mismatch in section %d", tag)
+		}
+		sections = append(sections, payload)
+		data = data[9+n:]
+	}
+	if len(data) != 0 {
+		return nil, corrupt("%d trailing bytes", len(data))
+	}
+
+	meta := &snapshotReader{buf: sections[0]}
+	m, ef, entry, count := meta.uvarint(), meta.uvarint(), meta.uvarint(), meta.uvarint()
+
+	ids := &snapshotReader{buf: sections[1]}
+	n := ids.uvarint()
+	if n > uint64(len(sections[1])) {
+		return nil, corrupt("ID table too large")
+	}
+	table := make([]string, n)
+	for i := range table {
+		table[i] = string(ids.bytes(ids.uvarint()))
+	}
+
+	if err := firstErr(meta.err, ids.err); err != nil {
+		return nil, err
+	}
+	if m == 0 {
+		return nil, corrupt("invalid M")
+	}
+	if count > n || entry > n {
+		return nil, corrupt("metadata references unknown IDs")
+	}
+
+	snap := &decodedSnapshot{m: int(m), efConstruction: int(ef), records: make([]snapshotRecord, count)}
+	if entry > 0 {
+		snap.entryPoint = table[entry-1]
+	}
+
+	graph := &snapshotReader{buf: sections[2]}
+	for i := range snap.records {
+		rec := &snap.records[i]
+		rec.id = table[i]
+		rec.deleted = graph.byte()&1 == 1
+		level := graph.uvarint()
+		if level > snapshotMaxLevel {
+			return nil, corrupt("node %q has level %d", rec.id, level)
+		}
+		rec.level = int(level)
+		rec.neighbors = make([][]string, level+1)
+		for lc := range rec.neighbors {
+			k := graph.uvarint()
+			if k > n {
+				return nil, corrupt("node %q has %d neighbors", rec.id, k)
+			}
+			rec.neighbors[lc] = make([]string, 0, k)
+			for j := uint64(0); j < k; j++ {
+				c := graph.uvarint()
+				if c >= n {
+					return nil, corrupt("node %q references unknown ID %d", rec.id, c)
+				}
+				rec.neighbors[lc] = append(rec.neighbors[lc], table[c])
+			}
+		}
+		if graph.err != nil {
+			return nil, graph.err
+		}
+	}
+	if graph.err != nil {
+		return nil, graph.err
+	}
+	if len(graph.buf) != 0 {
+		return nil, corrupt("trailing graph data")
+	}
+
+	return snap, nil
+}
+
+// snapshotReader decodes section payloads, remembering the first error
+type snapshotReader struct {
+	buf []byte
+	err error
+}
+
+func (r *snapshotReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = corrupt("bad varint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *snapshotReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) == 0 {
+		r.err = corrupt("unexpected end of section")
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *snapshotReader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.buf)) {
+		r.err = corrupt("unexpected end of section")
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+// firstErr returns the first non-nil error
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
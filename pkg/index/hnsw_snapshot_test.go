@@ -0,0 +1,154 @@
+package index
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHNSWSnapshot(t *testing.T) {
+	randomVec := newRandomVec(7, 8)
+	vectors := make(map[string][]float32)
+
+	src := NewHNSW(8, 64, EuclideanDistance)
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("vec_%d", i)
+		vectors[id] = randomVec()
+		if err := src.Insert(id, vectors[id]); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	if src.PendingChanges() != -1 {
+		t.Errorf("Expected no base snapshot, got %d pending changes", src.PendingChanges())
+	}
+	if _, err := src.WriteDelta(&bytes.Buffer{}); err == nil {
+		t.Error("Expected WriteDelta to fail without a base snapshot")
+	}
+
+	var full bytes.Buffer
+	if err := src.WriteSnapshot(&full); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	if src.PendingChanges() != 0 {
+		t.Errorf("Expected 0 pending changes after snapshot, got %d", src.PendingChanges())
+	}
+
+	// Change a few nodes and capture them as a delta
+	for i := 200; i < 210; i++ {
+		id := fmt.Sprintf("vec_%d", i)
+		vectors[id] = randomVec()
+		if err := src.Insert(id, vectors[id]); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if err := src.Delete("vec_3"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	delete(vectors, "vec_3")
+
+	var delta bytes.Buffer
+	n, err := src.WriteDelta(&delta)
+	if err != nil {
+		t.Fatalf("WriteDelta failed: %v", err)
+	}
+	if n < 11 {
+		t.Errorf("Expected at least 11 changed nodes in delta, got %d", n)
+	}
+	if delta.Len() >= full.Len() {
+		t.Errorf("Expected delta (%d bytes) to be smaller than full snapshot (%d bytes)", delta.Len(), full.Len())
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		dst := NewHNSW(16, 200, EuclideanDistance)
+		if err := dst.ReadSnapshot(bytes.NewReader(full.Bytes())); err != nil {
+			t.Fatalf("ReadSnapshot failed: %v", err)
+		}
+		if err := dst.ApplyDelta(bytes.NewReader(delta.Bytes())); err != nil {
+			t.Fatalf("ApplyDelta failed: %v", err)
+		}
+		if dst.M != 8 {
+			t.Errorf("Expected M restored to 8, got %d", dst.M)
+		}
+
+		for id, vec := range vectors {
+			if !dst.SetVector(id, vec) {
+				t.Errorf("Node %s missing from restored graph", id)
+			}
+		}
+		if dropped := dst.DropUnresolved(); dropped != 0 {
+			t.Errorf("Expected no unresolved nodes, dropped %d", dropped)
+		}
+		if dst.Size() != len(vectors) {
+			t.Errorf("Expected %d nodes, got %d", len(vectors), dst.Size())
+		}
+
+		for _, id := range []string{"vec_0", "vec_150", "vec_205"} {
+			ids, _ := dst.Search(vectors[id], 1, 50)
+			if len(ids) == 0 || ids[0] != id {
+				t.Errorf("Expected %s as nearest neighbor of itself, got %v", id, ids)
+			}
+		}
+	})
+
+	t.Run("DropUnresolved", func(t *testing.T) {
+		dst := NewHNSW(16, 200, EuclideanDistance)
+		if err := dst.ReadSnapshot(bytes.NewReader(full.Bytes())); err != nil {
+			t.Fatalf("ReadSnapshot failed: %v", err)
+		}
+		resolved := 0
+		for i := 0; i < 100; i++ {
+			id := fmt.Sprintf("vec_%d", i)
+			if vec, ok := vectors[id]; ok && dst.SetVector(id, vec) {
+				resolved++
+			}
+		}
+		if dropped := dst.DropUnresolved(); dropped != 200-resolved {
+			t.Errorf("Expected %d nodes dropped, got %d", 200-resolved, dropped)
+		}
+		for _, node := range dst.Nodes {
+			for _, neighbors := range node.Neighbors {
+				for _, neighbor := range neighbors {
+					if _, ok := dst.Nodes[neighbor]; !ok {
+						t.Fatalf("Node %s still links to dropped node %s", node.ID, neighbor)
+					}
+				}
+			}
+		}
+		if _, ok := dst.Nodes[dst.EntryPoint]; !ok {
+			t.Errorf("Entry point %q not in graph", dst.EntryPoint)
+		}
+		ids, _ := dst.Search(vectors["vec_42"], 1, 50)
+		if len(ids) == 0 || ids[0] != "vec_42" {
+			t.Errorf("Expected vec_42, got %v", ids)
+		}
+	})
+
+	t.Run("Corruption", func(t *testing.T) {
+		cases := map[string]func([]byte) []byte{
+			"BadMagic":  func(b []byte) []byte { b[0] = 'X'; return b },
+			"FlipByte":  func(b []byte) []byte { b[len(b)/2] ^= 0xFF; return b },
+			"Truncated": func(b []byte) []byte { return b[:len(b)-7] },
+			"Trailing":  func(b []byte) []byte { return append(b, 0) },
+		}
+		for name, mutate := range cases {
+			t.Run(name, func(t *testing.T) {
+				data := mutate(append([]byte(nil), full.Bytes()...))
+				dst := NewHNSW(16, 200, EuclideanDistance)
+				err := dst.ReadSnapshot(bytes.NewReader(data))
+				if !errors.Is(err, ErrCorruptSnapshot) {
+					t.Fatalf("Expected ErrCorruptSnapshot, got %v", err)
+				}
+				if len(dst.Nodes) != 0 {
+					t.Error("Corrupt snapshot must not modify the index")
+				}
+			})
+		}
+
+		dst := NewHNSW(16, 200, EuclideanDistance)
+		if err := dst.ApplyDelta(bytes.NewReader(full.Bytes())); !errors.Is(err, ErrCorruptSnapshot) {
+			t.Errorf("Expected full snapshot to be rejected as delta, got %v", err)
+		}
+	})
+}
@@ -10,6 +10,8 @@ import (
 	"math/rand"
 	"sort"
 	"sync"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/simd"
 )
 
 // IVFIndex implements Inverted File Index for partitioned vector search
@@ -293,19 +295,20 @@ func kMeansIVF(vectors [][]float32, k int, maxIters int) ([][]float32, error) {
 	copy(centroids[0], vectors[rand.Intn(len(vectors))])
 	
 	// Choose remaining centroids with probability proportional to squared distance
+	centroidDists := make([]float32, k)
 	for i := 1; i < k; i++ {
 		distances := make([]float32, len(vectors))
 		totalDist := float32(0)
 		
 		for j, vec := range vectors {
+			simd.SquaredL2Batch(vec, centroids[:i], centroidDists)
 			minDist := float32(math.MaxFloat32)
-			for c := 0; c < i; c++ {
-				dist := euclideanDistanceIVF(vec, centroids[c])
+			for _, dist := range centroidDists[:i] {
 				if dist < minDist {
 					minDist = dist
 				}
 			}
-			distances[j] = minDist * minDist
+			distances[j] = minDist
 			totalDist += distances[j]
 		}
 		
@@ -332,8 +335,8 @@ func kMeansIVF(vectors [][]float32, k int, maxIters int) ([][]float32, error) {
 			minDist := float32(math.MaxFloat32)
 			minIdx := 0
 			
-			for j, centroid := range centroids {
-				dist := euclideanDistanceIVF(vec, centroid)
+			simd.SquaredL2Batch(vec, centroids, centroidDists)
+			for j, dist := range centroidDists {
 				if dist < minDist {
 					minDist = dist
 					minIdx = j
@@ -378,12 +381,7 @@ func kMeansIVF(vectors [][]float32, k int, maxIters int) ([][]float32, error) {
 
 // euclideanDistanceIVF computes Euclidean distance
 func euclideanDistanceIVF(a, b []float32) float32 {
-	sum := float32(0)
-	for i := range a {
-		diff := a[i] - b[i]
-		sum += diff * diff
-	}
-	return float32(math.Sqrt(float64(sum)))
+	return float32(math.Sqrt(float64(simd.SquaredL2(a, b))))
 }
 
 // min returns the minimum of two integers
@@ -396,6 +394,9 @@ func min(a, b int) int {
 
 // Delete removes a vector from the index
 func (ivf *IVFIndex) Delete(id string) error {
+	ivf.mu.Lock()
+	defer ivf.mu.Unlock()
+	
 	if !ivf.Trained {
 		return errors.New("index not trained")
 	}
@@ -423,15 +424,18 @@ func (ivf *IVFIndex) Delete(id string) error {
 	
 	// Remove from inverted lists
 	for i := range ivf.Invlists {
-		for j, idx := range ivf.Invlists[i] {
+		list := ivf.Invlists[i][:0]
+		for _, idx := range ivf.Invlists[i] {
 			if idx == vectorIndex {
-				ivf.Invlists[i] = append(ivf.Invlists[i][:j], ivf.Invlists[i][j+1:]...)
-				break
-			} else if idx > vectorIndex {
+				continue
+			}
+			if idx > vectorIndex {
 				// Update indices after deletion
-				ivf.Invlists[i][j] = idx - 1
+				idx--
 			}
+			list = append(list, idx)
 		}
+		ivf.Invlists[i] = list
 	}
 	
 	return nil
@@ -0,0 +1,496 @@
+package index
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/simd"
+	"github.com/liliang-cn/cortexdb/v2/pkg/quantization"
+)
+
+// IVFPQConfig configures an IVF-PQ index
+type IVFPQConfig struct {
+	NCentroids  int  // Number of inverted lists (default: 100)
+	NProbe      int  // Lists scanned per query (default: 10)
+	PQSubspaces int  // PQ bytes per vector, must divide the dimension (0 = about dim/4)
+	PQCentroids int  // Centroids per subspace, at most 256 (default: 256)
+	Normalize   bool // Index unit vectors so L2 ranks like cosine similarity
+}
+
+// DefaultIVFPQConfig returns the default IVF-PQ configuration
+func DefaultIVFPQConfig() IVFPQConfig {
+	return IVFPQConfig{
+		NCentroids:  100,
+		NProbe:      10,
+		PQCentroids: 256,
+	}
+}
+
+func (c IVFPQConfig) withDefaults(dim int) IVFPQConfig {
+	def := DefaultIVFPQConfig()
+	if c.NCentroids <= 0 {
+		c.NCentroids = def.NCentroids
+	}
+	if c.NProbe <= 0 {
+		c.NProbe = def.NProbe
+	}
+	if c.PQCentroids <= 0 || c.PQCentroids > 256 {
+		c.PQCentroids = def.PQCentroids
+	}
+	if c.PQSubspaces <= 0 || dim%c.PQSubspaces != 0 {
+		c.PQSubspaces = 1
+		for m := max(1, dim/4); m > 1; m-- {
+			if dim%m == 0 {
+				c.PQSubspaces = m
+				break
+			}
+		}
+	}
+	return c
+}
+
+// IVFPQIndex partitions vectors with a coarse k-means quantizer and stores,
+// per inverted list, only the product-quantized residual of each vector from
+// its list centroid. Queries scan the NProbe nearest lists using a distance
+// table built from the query residual, so no raw vectors are kept in memory.
+type IVFPQIndex struct {
+	Dimension  int
+	NCentroids int
+	NProbe     int
+	Normalize  bool
+	Centroids  [][]float32
+	PQ         *quantization.ProductQuantizer
+	Trained    bool
+
+	pqCentroids int
+	lists       []ivfpqList
+	lookup      map[string]ivfpqPos
+	mu          sync.RWMutex
+}
+
+// ivfpqList holds the IDs and concatenated PQ codes of one inverted list
+type ivfpqList struct {
+	ids   []string
+	codes []byte
+}
+
+type ivfpqPos struct {
+	list   int
+	offset int
+}
+
+// ivfpqSnapshot is the serialized form of an IVF-PQ index
+type ivfpqSnapshot struct {
+	Dimension  int
+	NCentroids int
+	NProbe     int
+	Normalize  bool
+	Centroids  [][]float32
+	Codebooks  []byte
+	ListIDs    [][]string
+	ListCodes  [][]byte
+}
+
+// NewIVFPQIndex creates an untrained IVF-PQ index
+func NewIVFPQIndex(dimension int, cfg IVFPQConfig) (*IVFPQIndex, error) {
+	if dimension <= 0 {
+		return nil, fmt.Errorf("invalid dimension %d", dimension)
+	}
+	cfg = cfg.withDefaults(dimension)
+
+	return &IVFPQIndex{
+		Dimension:   dimension,
+		NCentroids:  cfg.NCentroids,
+		NProbe:      min(cfg.NProbe, cfg.NCentroids),
+		Normalize:   cfg.Normalize,
+		pqCentroids: cfg.PQCentroids,
+		PQ: &quantization.ProductQuantizer{
+			M:      cfg.PQSubspaces,
+			D:      dimension,
+			SubDim: dimension / cfg.PQSubspaces,
+		},
+		lists:  make([]ivfpqList, cfg.NCentroids),
+		lookup: make(map[string]ivfpqPos),
+	}, nil
+}
+
+// Train learns the coarse centroids and the residual codebooks. It clears
+// any vectors already in the index.
+func (ivf *IVFPQIndex) Train(vectors [][]float32) error {
+	ivf.mu.Lock()
+	defer ivf.mu.Unlock()
+
+	if len(vectors) < ivf.NCentroids {
+		return fmt.Errorf("need at least %d vectors for training, got %d", ivf.NCentroids, len(vectors))
+	}
+
+	prepared := make([][]float32, len(vectors))
+	for i, vec := range vectors {
+		if len(vec) != ivf.Dimension {
+			return fmt.Errorf("vector dimension %d doesn't match index dimension %d", len(vec), ivf.Dimension)
+		}
+		prepared[i] = ivf.prepare(vec)
+	}
+
+	centroids, err := kMeansIVF(prepared, ivf.NCentroids, 20)
+	if err != nil {
+		return err
+	}
+
+	// Codebooks are learned on residuals, which are spread far less than
+	// the vectors themselves
+	dists := make([]float32, len(centroids))
+	residuals := make([][]float32, len(prepared))
+	for i, vec := range prepared {
+		residuals[i] = residual(vec, centroids[nearest(vec, centroids, dists)])
+	}
+
+	// Small training sets get fewer PQ centroids
+	k := min(ivf.pqCentroids, len(residuals)/ivf.PQ.M)
+	if k < 1 {
+		return fmt.Errorf("need at least %d vectors to train %d PQ subspaces", ivf.PQ.M, ivf.PQ.M)
+	}
+	pq, err := quantization.NewProductQuantizer(ivf.Dimension, ivf.PQ.M, k)
+	if err != nil {
+		return err
+	}
+	if err := pq.Train(residuals); err != nil {
+		return fmt.Errorf("failed to train PQ: %w", err)
+	}
+
+	ivf.Centroids = centroids
+	ivf.PQ = pq
+	ivf.lists = make([]ivfpqList, ivf.NCentroids)
+	ivf.lookup = make(map[string]ivfpqPos)
+	ivf.Trained = true
+
+	return nil
+}
+
+// Add encodes a vector into its nearest list, replacing any vector with the
+// same ID
+func (ivf *IVFPQIndex) Add(id string, vector []float32) error {
+	if len(vector) != ivf.Dimension {
+		return fmt.Errorf("vector dimension %d doesn't match index dimension %d", len(vector), ivf.Dimension)
+	}
+
+	ivf.mu.Lock()
+	defer ivf.mu.Unlock()
+
+	if !ivf.Trained {
+		return errors.New("index not trained")
+	}
+
+	vec := ivf.prepare(vector)
+	listIdx := nearest(vec, ivf.Centroids, make([]float32, len(ivf.Centroids)))
+	codes, err := ivf.PQ.Encode(residual(vec, ivf.Centroids[listIdx]))
+	if err != nil {
+		return err
+	}
+
+	ivf.remove(id)
+	list := &ivf.lists[listIdx]
+	ivf.lookup[id] = ivfpqPos{list: listIdx, offset: len(list.ids)}
+	list.ids = append(list.ids, id)
+	list.codes = append(list.codes, codes...)
+
+	return nil
+}
+
+// Delete removes a vector from the index
+func (ivf *IVFPQIndex) Delete(id string) error {
+	ivf.mu.Lock()
+	defer ivf.mu.Unlock()
+
+	if !ivf.remove(id) {
+		return fmt.Errorf("vector %s not found", id)
+	}
+	return nil
+}
+
+// remove swaps the entry with the last one of its list. The caller holds the
+// write lock.
+func (ivf *IVFPQIndex) remove(id string) bool {
+	pos, ok := ivf.lookup[id]
+	if !ok {
+		return false
+	}
+	delete(ivf.lookup, id)
+
+	m := ivf.PQ.M
+	list := &ivf.lists[pos.list]
+	last := len(list.ids) - 1
+	if pos.offset != last {
+		movedID := list.ids[last]
+		list.ids[pos.offset] = movedID
+		copy(list.codes[pos.offset*m:(pos.offset+1)*m], list.codes[last*m:])
+		ivf.lookup[movedID] = pos
+	}
+	list.ids = list.ids[:last]
+	list.codes = list.codes[:last*m]
+
+	return true
+}
+
+// Search returns the k nearest IDs with their approximate squared L2
+// distances, nearest first
+func (ivf *IVFPQIndex) Search(query []float32, k int) ([]string, []float32, error) {
+	if len(query) != ivf.Dimension {
+		return nil, nil, fmt.Errorf("query dimension %d doesn't match index dimension %d", len(query), ivf.Dimension)
+	}
+
+	ivf.mu.RLock()
+	defer ivf.mu.RUnlock()
+
+	if !ivf.Trained {
+		return nil, nil, errors.New("index not trained")
+	}
+
+	q := ivf.prepare(query)
+
+	centroidDists := make([]float32, len(ivf.Centroids))
+	simd.SquaredL2Batch(q, ivf.Centroids, centroidDists)
+	order := make([]int, len(ivf.Centroids))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return centroidDists[order[i]] < centroidDists[order[j]]
+	})
+
+	type candidate struct {
+		id   string
+		dist float32
+	}
+	var candidates []candidate
+
+	m := ivf.PQ.M
+	table := make([][]float32, m)
+	for sub := range table {
+		table[sub] = make([]float32, ivf.PQ.K)
+	}
+
+	nprobe := min(ivf.NProbe, len(order))
+	for _, listIdx := range order[:nprobe] {
+		list := &ivf.lists[listIdx]
+		if len(list.ids) == 0 {
+			continue
+		}
+
+		// Distances from the query residual to every codeword of this list
+		r := residual(q, ivf.Centroids[listIdx])
+		for sub := range table {
+			simd.SquaredL2Batch(r[sub*ivf.PQ.SubDim:(sub+1)*ivf.PQ.SubDim], ivf.PQ.Codebooks[sub], table[sub])
+		}
+
+		for i, id := range list.ids {
+			codes := list.codes[i*m : (i+1)*m]
+			var dist float32
+			for sub, code := range codes {
+				dist += table[sub][code]
+			}
+			candidates = append(candidates, candidate{id: id, dist: dist})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].dist < candidates[j].dist
+	})
+
+	topK := min(k, len(candidates))
+	ids := make([]string, topK)
+	distances := make([]float32, topK)
+	for i := 0; i < topK; i++ {
+		ids[i] = candidates[i].id
+		distances[i] = candidates[i].dist
+	}
+
+	return ids, distances, nil
+}
+
+// SetNProbe sets the number of lists to scan
+func (ivf *IVFPQIndex) SetNProbe(nprobe int) {
+	ivf.mu.Lock()
+	defer ivf.mu.Unlock()
+	ivf.NProbe = max(1, min(nprobe, ivf.NCentroids))
+}
+
+// Contains reports whether the index holds a vector with the given ID
+func (ivf *IVFPQIndex) Contains(id string) bool {
+	ivf.mu.RLock()
+	defer ivf.mu.RUnlock()
+	_, ok := ivf.lookup[id]
+	return ok
+}
+
+// IDs returns the IDs of all vectors in the index
+func (ivf *IVFPQIndex) IDs() []string {
+	ivf.mu.RLock()
+	defer ivf.mu.RUnlock()
+	ids := make([]string, 0, len(ivf.lookup))
+	for id := range ivf.lookup {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Size returns the number of vectors in the index
+func (ivf *IVFPQIndex) Size() int {
+	ivf.mu.RLock()
+	defer ivf.mu.RUnlock()
+	return len(ivf.lookup)
+}
+
+// Clear removes all vectors but keeps the trained quantizers
+func (ivf *IVFPQIndex) Clear() {
+	ivf.mu.Lock()
+	defer ivf.mu.Unlock()
+	ivf.lists = make([]ivfpqList, ivf.NCentroids)
+	ivf.lookup = make(map[string]ivfpqPos)
+}
+
+// Stats returns index statistics
+func (ivf *IVFPQIndex) Stats() map[string]interface{} {
+	ivf.mu.RLock()
+	defer ivf.mu.RUnlock()
+
+	nonEmpty := 0
+	maxList := 0
+	for _, list := range ivf.lists {
+		if len(list.ids) > 0 {
+			nonEmpty++
+		}
+		maxList = max(maxList, len(list.ids))
+	}
+
+	return map[string]interface{}{
+		"type":            "IVF-PQ",
+		"dimension":       ivf.Dimension,
+		"n_centroids":     ivf.NCentroids,
+		"n_probe":         ivf.NProbe,
+		"pq_subspaces":    ivf.PQ.M,
+		"pq_centroids":    ivf.PQ.K,
+		"trained":         ivf.Trained,
+		"total_vectors":   len(ivf.lookup),
+		"non_empty_lists": nonEmpty,
+		"max_list_size":   maxList,
+		"bytes_per_code":  ivf.PQ.M,
+	}
+}
+
+// Save serializes the index to a writer
+func (ivf *IVFPQIndex) Save(w io.Writer) error {
+	ivf.mu.RLock()
+	defer ivf.mu.RUnlock()
+
+	if !ivf.Trained {
+		return errors.New("index not trained")
+	}
+
+	snap := ivfpqSnapshot{
+		Dimension:  ivf.Dimension,
+		NCentroids: ivf.NCentroids,
+		NProbe:     ivf.NProbe,
+		Normalize:  ivf.Normalize,
+		Centroids:  ivf.Centroids,
+		Codebooks:  ivf.PQ.SerializeCodebooks(),
+		ListIDs:    make([][]string, len(ivf.lists)),
+		ListCodes:  make([][]byte, len(ivf.lists)),
+	}
+	for i, list := range ivf.lists {
+		snap.ListIDs[i] = list.ids
+		snap.ListCodes[i] = list.codes
+	}
+
+	return gob.NewEncoder(w).Encode(&snap)
+}
+
+// Load restores an index written by Save, replacing the current contents
+func (ivf *IVFPQIndex) Load(r io.Reader) error {
+	var snap ivfpqSnapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return err
+	}
+
+	pq := &quantization.ProductQuantizer{}
+	if err := pq.DeserializeCodebooks(snap.Codebooks); err != nil {
+		return err
+	}
+	if pq.D != snap.Dimension || len(snap.Centroids) != snap.NCentroids ||
+		len(snap.ListIDs) != snap.NCentroids || len(snap.ListCodes) != snap.NCentroids {
+		return errors.New("inconsistent IVF-PQ snapshot")
+	}
+
+	lists := make([]ivfpqList, snap.NCentroids)
+	lookup := make(map[string]ivfpqPos)
+	for i := range lists {
+		if len(snap.ListCodes[i]) != len(snap.ListIDs[i])*pq.M {
+			return errors.New("inconsistent IVF-PQ snapshot")
+		}
+		for _, code := range snap.ListCodes[i] {
+			if int(code) >= pq.K {
+				return errors.New("inconsistent IVF-PQ snapshot")
+			}
+		}
+		lists[i] = ivfpqList{ids: snap.ListIDs[i], codes: snap.ListCodes[i]}
+		for offset, id := range snap.ListIDs[i] {
+			lookup[id] = ivfpqPos{list: i, offset: offset}
+		}
+	}
+
+	ivf.mu.Lock()
+	defer ivf.mu.Unlock()
+
+	ivf.Dimension = snap.Dimension
+	ivf.NCentroids = snap.NCentroids
+	ivf.NProbe = snap.NProbe
+	ivf.Normalize = snap.Normalize
+	ivf.Centroids = snap.Centroids
+	ivf.PQ = pq
+	ivf.lists = lists
+	ivf.lookup = lookup
+	ivf.Trained = true
+
+	return nil
+}
+
+// prepare returns the vector as indexed: a normalized copy when Normalize is
+// set, otherwise the vector itself
+func (ivf *IVFPQIndex) prepare(vec []float32) []float32 {
+	if !ivf.Normalize {
+		return vec
+	}
+	out := make([]float32, len(vec))
+	copy(out, vec)
+	normalizeInPlace(out)
+	return out
+}
+
+// nearest returns the index of the centroid closest to vec, using dists as
+// scratch space
+func nearest(vec []float32, centroids [][]float32, dists []float32) int {
+	simd.SquaredL2Batch(vec, centroids, dists)
+	best := 0
+	minDist := float32(math.MaxFloat32)
+	for i, dist := range dists[:len(centroids)] {
+		if dist < minDist {
+			minDist = dist
+			best = i
+		}
+	}
+	return best
+}
+
+// residual returns vec - centroid
+func residual(vec, centroid []float32) []float32 {
+	out := make([]float32, len(vec))
+	for i := range vec {
+		out[i] = vec[i] - centroid[i]
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package index
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/liliang-cn/cortexdb/v2/internal/simd"
+)
+
+func TestIVFPQIndex(t *testing.T) {
+	const (
+		n   = 2000
+		dim = 16
+	)
+
+	randomVec := newRandomVec(11, dim)
+	vectors := make(map[string][]float32, n)
+	for i := 0; i < n; i++ {
+		vectors[fmt.Sprintf("vec_%d", i)] = randomVec()
+	}
+
+	idx, err := NewIVFPQIndex(dim, IVFPQConfig{NCentroids: 16, NProbe: 4, PQSubspaces: 8})
+	if err != nil {
+		t.Fatalf("NewIVFPQIndex failed: %v", err)
+	}
+	if err := idx.Add("early", vectors["vec_0"]); err == nil {
+		t.Error("Expected Add to fail before training")
+	}
+
+	training := make([][]float32, 0, n)
+	for _, vec := range vectors {
+		training = append(training, vec)
+	}
+	if err := idx.Train(training); err != nil {
+		t.Fatalf("Train failed: %v", err)
+	}
+	for id, vec := range vectors {
+		if err := idx.Add(id, vec); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if idx.Size() != n {
+		t.Errorf("Expected %d vectors, got %d", n, idx.Size())
+	}
+
+	bruteForce := func(query []float32, k int) []string {
+		ids := make([]string, 0, len(vectors))
+		for id := range vectors {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return simd.SquaredL2(query, vectors[ids[i]]) < simd.SquaredL2(query, vectors[ids[j]])
+		})
+		return ids[:k]
+	}
+
+	t.Run("Recall", func(t *testing.T) {
+		// Top-10 ground truth among 50 PQ candidates, as seen by a reranker
+		const k = 10
+		idx.SetNProbe(8)
+		hits, total := 0, 0
+		for q := 0; q < 50; q++ {
+			query := randomVec()
+			got, dists, err := idx.Search(query, 5*k)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if !sort.SliceIsSorted(dists, func(i, j int) bool { return dists[i] < dists[j] }) {
+				t.Fatal("Expected distances in ascending order")
+			}
+			found := make(map[string]bool)
+			for _, id := range got {
+				found[id] = true
+			}
+			for _, id := range bruteForce(query, k) {
+				if found[id] {
+					hits++
+				}
+				total++
+			}
+		}
+		if recall := float64(hits) / float64(total); recall < 0.9 {
+			t.Errorf("Expected recall >= 0.9, got %.3f", recall)
+		}
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		if err := idx.Delete("vec_5"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if idx.Contains("vec_5") || idx.Delete("vec_5") == nil {
+			t.Error("Deleted vector should be gone")
+		}
+
+		// Re-adding an ID moves it rather than duplicating it
+		if err := idx.Add("vec_6", vectors["vec_7"]); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if idx.Size() != n-1 {
+			t.Errorf("Expected %d vectors, got %d", n-1, idx.Size())
+		}
+		ids, _, _ := idx.Search(vectors["vec_7"], 2)
+		if len(ids) != 2 || (ids[0] != "vec_6" && ids[0] != "vec_7") {
+			t.Errorf("Expected vec_6 and vec_7 nearest, got %v", ids)
+		}
+	})
+
+	t.Run("SaveLoad", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := idx.Save(&buf); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		loaded := &IVFPQIndex{}
+		if err := loaded.Load(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if loaded.Size() != idx.Size() || !loaded.Trained {
+			t.Errorf("Expected %d vectors after load, got %d", idx.Size(), loaded.Size())
+		}
+
+		query := vectors["vec_100"]
+		want, _, _ := idx.Search(query, 10)
+		got, _, _ := loaded.Search(query, 10)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Loaded index returned %v, want %v", got, want)
+		}
+
+		if err := loaded.Add("new", vectors["vec_1"]); err != nil {
+			t.Errorf("Add after load failed: %v", err)
+		}
+
+		data := buf.Bytes()
+		if err := (&IVFPQIndex{}).Load(bytes.NewReader(data[:len(data)/2])); err == nil {
+			t.Error("Expected truncated snapshot to fail")
+		}
+	})
+}
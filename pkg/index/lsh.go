@@ -2,7 +2,9 @@
 package index
 
 import (
+	"encoding/gob"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"sort"
@@ -266,6 +268,83 @@ func (lsh *LSHIndex) Size() int {
 	return len(lsh.vectors)
 }
 
+// lshSnapshot is the serialized form of an LSHIndex. Hash tables are
+// rebuilt from the vectors on load.
+type lshSnapshot struct {
+	NumTables     int
+	NumHashFuncs  int
+	Dimension     int
+	HashFunctions [][][]float32
+	Vectors       map[string][]float32
+}
+
+// Save serializes the index to a writer
+func (lsh *LSHIndex) Save(w io.Writer) error {
+	lsh.mu.RLock()
+	defer lsh.mu.RUnlock()
+	
+	return gob.NewEncoder(w).Encode(lshSnapshot{
+		NumTables:     lsh.numTables,
+		NumHashFuncs:  lsh.numHashFuncs,
+		Dimension:     lsh.dimension,
+		HashFunctions: lsh.hashFunctions,
+		Vectors:       lsh.vectors,
+	})
+}
+
+// Load deserializes the index from a reader. The distance function of the
+// receiver is kept.
+func (lsh *LSHIndex) Load(r io.Reader) error {
+	var snap lshSnapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return err
+	}
+	if len(snap.HashFunctions) != snap.NumTables {
+		return fmt.Errorf("invalid LSH snapshot: %d hash tables, expected %d", len(snap.HashFunctions), snap.NumTables)
+	}
+	for _, table := range snap.HashFunctions {
+		if len(table) != snap.NumHashFuncs {
+			return fmt.Errorf("invalid LSH snapshot: expected %d hash functions per table", snap.NumHashFuncs)
+		}
+		for _, projection := range table {
+			if len(projection) != snap.Dimension {
+				return fmt.Errorf("invalid LSH snapshot: projection dimension %d, expected %d", len(projection), snap.Dimension)
+			}
+		}
+	}
+	for id, vector := range snap.Vectors {
+		if len(vector) != snap.Dimension {
+			return fmt.Errorf("invalid LSH snapshot: vector %s has dimension %d, expected %d", id, len(vector), snap.Dimension)
+		}
+	}
+	if snap.Vectors == nil {
+		snap.Vectors = make(map[string][]float32)
+	}
+	
+	lsh.mu.Lock()
+	defer lsh.mu.Unlock()
+	
+	lsh.numTables = snap.NumTables
+	lsh.numHashFuncs = snap.NumHashFuncs
+	lsh.dimension = snap.Dimension
+	lsh.hashFunctions = snap.HashFunctions
+	lsh.vectors = snap.Vectors
+	lsh.hashTables = make([]map[uint64][]string, lsh.numTables)
+	for tableIdx := 0; tableIdx < lsh.numTables; tableIdx++ {
+		lsh.hashTables[tableIdx] = make(map[uint64][]string)
+	}
+	for id, vector := range lsh.vectors {
+		for tableIdx := 0; tableIdx < lsh.numTables; tableIdx++ {
+			hash := lsh.computeHash(vector, tableIdx)
+			lsh.hashTables[tableIdx][hash] = append(lsh.hashTables[tableIdx][hash], id)
+		}
+	}
+	if lsh.distFunc == nil {
+		lsh.distFunc = lshEuclideanDistance
+	}
+	return nil
+}
+
 // Stats returns statistics about the LSH index
 func (lsh *LSHIndex) Stats() map[string]interface{} {
 	lsh.mu.RLock()
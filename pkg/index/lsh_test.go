@@ -1,6 +1,7 @@
 package index
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"math/rand"
@@ -137,6 +138,48 @@ func TestLSHDelete(t *testing.T) {
 	}
 }
 
+func TestLSHSaveLoad(t *testing.T) {
+	config := LSHConfig{NumTables: 6, NumHashFuncs: 5, Dimension: 8, Seed: 3}
+	lsh := NewLSHIndex(config)
+	
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 200; i++ {
+		vec := make([]float32, config.Dimension)
+		for j := range vec {
+			vec[j] = rng.Float32()
+		}
+		if err := lsh.Insert(fmt.Sprintf("vec_%d", i), vec); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	
+	var buf bytes.Buffer
+	if err := lsh.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data := buf.Bytes()
+	
+	// Projections come from the snapshot, not the receiver's seed
+	loaded := NewLSHIndex(LSHConfig{Dimension: config.Dimension, Seed: 99})
+	if err := loaded.Load(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Size() != 200 {
+		t.Errorf("Expected 200 vectors, got %d", loaded.Size())
+	}
+	
+	query := lsh.vectors["vec_10"]
+	want, _ := lsh.SearchWithMultiProbe(query, 5, 2)
+	got, _ := loaded.SearchWithMultiProbe(query, 5, 2)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Loaded index returned %v, want %v", got, want)
+	}
+	
+	if err := NewLSHIndex(config).Load(bytes.NewReader(data[:len(data)/2])); err == nil {
+		t.Error("Expected truncated snapshot to fail")
+	}
+}
+
 func TestLSHStats(t *testing.T) {
 	config := LSHConfig{
 		NumTables:    5,
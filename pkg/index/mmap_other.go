@@ -0,0 +1,23 @@
+//go:build !unix
+
+package index
+
+import (
+	"io"
+	"os"
+)
+
+// mmapFile reads the first size bytes of f into memory on platforms without
+// mmap support
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(f, 0, int64(size)), data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// munmapFile releases a mapping created by mmapFile
+func munmapFile(data []byte) error {
+	return nil
+}
@@ -2,6 +2,11 @@
 package index
 
 import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
 	"sort"
 	"sync"
 )
@@ -14,6 +19,7 @@ const (
 	IndexTypeIVF    IndexType = "ivf"
 	IndexTypeFlat   IndexType = "flat"
 	IndexTypeHybrid IndexType = "hybrid"
+	IndexTypeLSH    IndexType = "lsh"
 )
 
 // MultiIndex combines multiple index types for optimal performance
@@ -66,6 +72,19 @@ type VectorIndex interface {
 	Size() int
 }
 
+// PersistentIndex is a VectorIndex that can be snapshotted and restored
+type PersistentIndex interface {
+	VectorIndex
+	Save(w io.Writer) error
+	Load(r io.Reader) error
+}
+
+// Trainable is implemented by indices that must learn from sample vectors
+// before they accept inserts
+type Trainable interface {
+	Train(vectors [][]float32) error
+}
+
 // NewMultiIndex creates a new multi-index
 func NewMultiIndex(config MultiIndexConfig) *MultiIndex {
 	return &MultiIndex{
@@ -205,7 +224,7 @@ func (m *MultiIndex) searchWithRerank(query []float32, k int) ([]string, []float
 	if m.config.RerankTopK > 0 {
 		candidateK = m.config.RerankTopK
 	}
-	candidateIDs, _ := primary.Search(query, candidateK)
+	candidateIDs, primaryDists := primary.Search(query, candidateK)
 	
 	if len(candidateIDs) == 0 {
 		return []string{}, []float32{}
@@ -213,14 +232,15 @@ func (m *MultiIndex) searchWithRerank(query []float32, k int) ([]string, []float
 	
 	// Rerank using secondary indices
 	type result struct {
-		id        string
-		score     float32
-		voteCount int
+		id          string
+		score       float32
+		primaryDist float32
+		voteCount   int
 	}
 	
 	resultMap := make(map[string]*result)
-	for _, id := range candidateIDs {
-		resultMap[id] = &result{id: id, score: 0, voteCount: 0}
+	for i, id := range candidateIDs {
+		resultMap[id] = &result{id: id, primaryDist: primaryDists[i]}
 	}
 	
 	// Get refined distances from secondary indices
@@ -241,6 +261,9 @@ func (m *MultiIndex) searchWithRerank(query []float32, k int) ([]string, []float
 	for _, r := range resultMap {
 		if r.voteCount > 0 {
 			r.score /= float32(r.voteCount)
+		} else {
+			// No secondary saw this candidate; keep the primary distance
+			r.score = r.primaryDist
 		}
 		results = append(results, *r)
 	}
@@ -331,14 +354,102 @@ func (m *MultiIndex) searchWithVoting(query []float32, k int) ([]string, []float
 	return ids, dists
 }
 
-// Delete removes a vector from all indices
+// Delete removes a vector from all indices. Every index is visited even if
+// one fails, so a vector missing from one member still leaves the others;
+// the first error is returned.
 func (m *MultiIndex) Delete(id string) error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	
+	var firstErr error
 	for _, index := range m.indices {
-		if err := index.Delete(id); err != nil {
-			return err
+		if err := index.Delete(id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Train trains every index that needs training with the same sample
+func (m *MultiIndex) Train(vectors [][]float32) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	for indexType, index := range m.indices {
+		if t, ok := index.(Trainable); ok {
+			if err := t.Train(vectors); err != nil {
+				return fmt.Errorf("failed to train %s index: %w", indexType, err)
+			}
+		}
+	}
+	return nil
+}
+
+// NeedsTraining reports whether any index must be trained before inserts
+func (m *MultiIndex) NeedsTraining() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	for _, index := range m.indices {
+		if _, ok := index.(Trainable); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// multiIndexSnapshot is the serialized form of a MultiIndex
+type multiIndexSnapshot struct {
+	Types []IndexType
+	Data  [][]byte
+}
+
+// Save serializes every index. All indices must implement PersistentIndex.
+func (m *MultiIndex) Save(w io.Writer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	types := make([]IndexType, 0, len(m.indices))
+	for indexType := range m.indices {
+		types = append(types, indexType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	
+	snap := multiIndexSnapshot{Types: types, Data: make([][]byte, len(types))}
+	for i, indexType := range types {
+		p, ok := m.indices[indexType].(PersistentIndex)
+		if !ok {
+			return fmt.Errorf("%s index does not support snapshots", indexType)
+		}
+		var buf bytes.Buffer
+		if err := p.Save(&buf); err != nil {
+			return fmt.Errorf("failed to save %s index: %w", indexType, err)
+		}
+		snap.Data[i] = buf.Bytes()
+	}
+	return gob.NewEncoder(w).Encode(snap)
+}
+
+// Load restores a snapshot written by Save into the indices already added
+// with AddIndex. The snapshot must cover exactly the same index types.
+func (m *MultiIndex) Load(r io.Reader) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	var snap multiIndexSnapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return err
+	}
+	if len(snap.Types) != len(m.indices) || len(snap.Data) != len(snap.Types) {
+		return fmt.Errorf("snapshot has %d indices, multi-index has %d", len(snap.Types), len(m.indices))
+	}
+	for i, indexType := range snap.Types {
+		p, ok := m.indices[indexType].(PersistentIndex)
+		if !ok {
+			return fmt.Errorf("snapshot index %s is not part of the multi-index", indexType)
+		}
+		if err := p.Load(bytes.NewReader(snap.Data[i])); err != nil {
+			return fmt.Errorf("failed to load %s index: %w", indexType, err)
 		}
 	}
 	return nil
@@ -474,12 +585,13 @@ func (h *HybridIndex) Delete(id string) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	
-	if err := h.hnsw.Delete(id); err != nil {
-		// Log but don't fail - some indices may not support deletion
-		_ = err
+	hnswErr := h.hnsw.Delete(id)
+	if h.ivf.Trained {
+		if err := h.ivf.Delete(id); err != nil && hnswErr == nil {
+			return err
+		}
 	}
-	// IVF doesn't support delete, would need rebuild
-	return nil
+	return hnswErr
 }
 
 // Size returns the size
@@ -487,6 +599,50 @@ func (h *HybridIndex) Size() int {
 	return h.hnsw.Size()
 }
 
+// Save serializes both component indices
+func (h *HybridIndex) Save(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	
+	var hnswBuf, ivfBuf bytes.Buffer
+	if err := h.hnsw.Save(&hnswBuf); err != nil {
+		return fmt.Errorf("failed to save HNSW component: %w", err)
+	}
+	if err := h.ivf.Save(&ivfBuf); err != nil {
+		return fmt.Errorf("failed to save IVF component: %w", err)
+	}
+	
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(h.alpha); err != nil { return err }
+	if err := enc.Encode(hnswBuf.Bytes()); err != nil { return err }
+	return enc.Encode(ivfBuf.Bytes())
+}
+
+// Load restores both component indices from a snapshot written by Save
+func (h *HybridIndex) Load(r io.Reader) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	dec := gob.NewDecoder(r)
+	var hnswData, ivfData []byte
+	if err := dec.Decode(&h.alpha); err != nil { return err }
+	if err := dec.Decode(&hnswData); err != nil { return err }
+	if err := dec.Decode(&ivfData); err != nil { return err }
+	
+	if err := h.hnsw.Load(bytes.NewReader(hnswData)); err != nil {
+		return fmt.Errorf("failed to load HNSW component: %w", err)
+	}
+	if err := h.ivf.Load(bytes.NewReader(ivfData)); err != nil {
+		return fmt.Errorf("failed to load IVF component: %w", err)
+	}
+	return nil
+}
+
+// SetNProbe sets the number of IVF clusters searched
+func (h *HybridIndex) SetNProbe(nprobe int) {
+	h.ivf.SetNProbe(nprobe)
+}
+
 // SetAlpha sets the weight between HNSW (0) and IVF (1)
 func (h *HybridIndex) SetAlpha(alpha float32) {
 	h.mu.Lock()
@@ -505,7 +661,7 @@ type HNSWAdapter struct {
 // NewHNSWAdapter creates a new HNSW adapter
 func NewHNSWAdapter(dimension, M int, distFunc func([]float32, []float32) float32) *HNSWAdapter {
 	return &HNSWAdapter{
-		HNSW:      NewHNSW(dimension, M, distFunc),
+		HNSW:      NewHNSW(M, 200, distFunc),
 		defaultEf: 50,
 	}
 }
@@ -545,4 +701,65 @@ func (ivf *IVFAdapter) Insert(id string, vector []float32) error {
 func (ivf *IVFAdapter) Search(query []float32, k int) ([]string, []float32) {
 	ids, distances, _ := ivf.IVFIndex.Search(query, k)
 	return ids, distances
-}
\ No newline at end of file
+}
+
+// LSHAdapter wraps LSHIndex to implement VectorIndex interface
+type LSHAdapter struct {
+	*LSHIndex
+	numProbes int
+}
+
+// NewLSHAdapter creates a new LSH adapter. With numProbes > 0, searches also
+// visit that many neighbouring buckets per table.
+func NewLSHAdapter(config LSHConfig, numProbes int) *LSHAdapter {
+	return &LSHAdapter{
+		LSHIndex:  NewLSHIndex(config),
+		numProbes: numProbes,
+	}
+}
+
+// Search implements VectorIndex interface
+func (l *LSHAdapter) Search(query []float32, k int) ([]string, []float32) {
+	var results []LSHSearchResult
+	if l.numProbes > 0 {
+		results, _ = l.LSHIndex.SearchWithMultiProbe(query, k, l.numProbes)
+	} else {
+		results, _ = l.LSHIndex.Search(query, k)
+	}
+	
+	ids := make([]string, len(results))
+	distances := make([]float32, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+		distances[i] = r.Distance
+	}
+	return ids, distances
+}
+
+// Delete implements VectorIndex interface
+func (l *LSHAdapter) Delete(id string) error {
+	if !l.LSHIndex.Delete(id) {
+		return errors.New("vector not found")
+	}
+	return nil
+}
+
+// FlatAdapter wraps FlatIndex to implement VectorIndex interface
+type FlatAdapter struct {
+	*FlatIndex
+}
+
+// NewFlatAdapter creates a new flat adapter
+func NewFlatAdapter(dimension int, distFunc func([]float32, []float32) float32) *FlatAdapter {
+	return &FlatAdapter{
+		FlatIndex: NewFlatIndex(dimension, distFunc),
+	}
+}
+
+// Delete implements VectorIndex interface
+func (f *FlatAdapter) Delete(id string) error {
+	if !f.FlatIndex.Delete(id) {
+		return errors.New("vector not found")
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package index
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"math/rand"
@@ -250,6 +251,114 @@ func TestHybridIndexTrainInsertSearch(t *testing.T) {
 	}
 }
 
+func TestHybridIndexDeleteSaveLoad(t *testing.T) {
+	hybrid := NewHybridIndex(16, 8, 3)
+	vectors := generateTestVectorsMulti(30, 16)
+	if err := hybrid.Train(vectors); err != nil {
+		t.Fatalf("Training failed: %v", err)
+	}
+	for i, vec := range vectors {
+		if err := hybrid.Insert(fmt.Sprintf("vec_%d", i), vec); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	
+	// Deleting must also remove the IVF copy, or it keeps being returned
+	if err := hybrid.Delete("vec_0"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	ids, _ := hybrid.Search(vectors[0], 5)
+	for _, id := range ids {
+		if id == "vec_0" {
+			t.Error("Deleted vector still returned")
+		}
+	}
+	
+	var buf bytes.Buffer
+	if err := hybrid.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded := NewHybridIndex(16, 8, 3)
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Size() != 29 {
+		t.Errorf("Expected 29 vectors after load, got %d", loaded.Size())
+	}
+	ids, _ = loaded.Search(vectors[7], 1)
+	if len(ids) == 0 || ids[0] != "vec_7" {
+		t.Errorf("Expected vec_7 after load, got %v", ids)
+	}
+}
+
+func TestMultiIndexSaveLoad(t *testing.T) {
+	const dim = 16
+	newEnsemble := func() *MultiIndex {
+		mi := NewMultiIndex(MultiIndexConfig{
+			PrimaryIndex:     IndexTypeHNSW,
+			SecondaryIndices: []IndexType{IndexTypeLSH, IndexTypeIVF},
+			CombineStrategy:  StrategyMergeAll,
+		})
+		mi.AddIndex(IndexTypeHNSW, NewHNSWAdapter(dim, 8, EuclideanDistance))
+		mi.AddIndex(IndexTypeLSH, NewLSHAdapter(LSHConfig{NumTables: 4, NumHashFuncs: 4, Dimension: dim, Seed: 1}, 2))
+		mi.AddIndex(IndexTypeIVF, NewIVFAdapter(dim, 4))
+		return mi
+	}
+	
+	mi := newEnsemble()
+	if !mi.NeedsTraining() {
+		t.Error("Expected an ensemble with IVF to need training")
+	}
+	vectors := generateTestVectorsMulti(100, dim)
+	if err := mi.Train(vectors); err != nil {
+		t.Fatalf("Train failed: %v", err)
+	}
+	for i, vec := range vectors {
+		if err := mi.Insert(fmt.Sprintf("vec_%d", i), vec); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	
+	// A vector missing from one member still leaves the others
+	lsh := mi.indices[IndexTypeLSH].(*LSHAdapter)
+	if err := lsh.Delete("vec_1"); err != nil {
+		t.Fatalf("LSH delete failed: %v", err)
+	}
+	if err := mi.Delete("vec_1"); err == nil {
+		t.Error("Expected an error for the member without vec_1")
+	}
+	if mi.Size() != 99 || mi.indices[IndexTypeIVF].Size() != 99 {
+		t.Errorf("Expected vec_1 removed from every member, sizes %v", mi.Stats()["index_sizes"])
+	}
+	
+	var buf bytes.Buffer
+	if err := mi.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data := buf.Bytes()
+	
+	loaded := newEnsemble()
+	if err := loaded.Load(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for indexType, size := range loaded.Stats()["index_sizes"].(map[string]int) {
+		if size != 99 {
+			t.Errorf("Expected 99 vectors in %s after load, got %d", indexType, size)
+		}
+	}
+	ids, _ := loaded.Search(vectors[42], 1)
+	if len(ids) == 0 || ids[0] != "vec_42" {
+		t.Errorf("Expected vec_42 after load, got %v", ids)
+	}
+	
+	// Member types must match the snapshot
+	other := NewMultiIndex(MultiIndexConfig{PrimaryIndex: IndexTypeHNSW})
+	other.AddIndex(IndexTypeHNSW, NewHNSWAdapter(dim, 8, EuclideanDistance))
+	if err := other.Load(bytes.NewReader(data)); err == nil {
+		t.Error("Expected load into a different ensemble to fail")
+	}
+}
+
 func TestHybridIndexAlpha(t *testing.T) {
 	hybrid := NewHybridIndex(16, 8, 3)
 	
@@ -0,0 +1,187 @@
+package loaders
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/cortexdb"
+)
+
+// DirectoryOptions selects and loads the files under a directory.
+//
+// Patterns match slash-separated paths relative to the root. "*" and "?"
+// match within one path element, "**" matches any number of directories,
+// and a pattern without a "/" matches the file name at any depth, so
+// "*.md" selects every Markdown file. Exclude patterns also prune
+// directories.
+type DirectoryOptions struct {
+	Include []string          // Files to load (default: every file with a loader)
+	Exclude []string          // Files and directories to skip
+	Loaders map[string]Loader // Loaders by extension, overriding ForExtension
+
+	// Knowledge is the template for the requests IngestDirectory saves,
+	// e.g. its collection and chunking.
+	Knowledge cortexdb.KnowledgeSaveRequest
+}
+
+// IngestResult reports what IngestDirectory saved
+type IngestResult struct {
+	Files     int      `json:"files"`
+	Documents int      `json:"documents"`
+	IDs       []string `json:"ids,omitempty"`
+}
+
+// LoadDirectory loads every selected file under root, in path order.
+// Document sources and IDs are paths relative to root.
+func LoadDirectory(ctx context.Context, root string, opts DirectoryOptions) ([]Document, error) {
+	var docs []Document
+	_, err := walkDirectory(ctx, root, opts, func(file []Document) error {
+		docs = append(docs, file...)
+		return nil
+	})
+	return docs, err
+}
+
+// IngestDirectory loads the files under root and saves each document with
+// db.SaveKnowledge, stopping at the first error.
+func IngestDirectory(ctx context.Context, db *cortexdb.DB, root string, opts DirectoryOptions) (*IngestResult, error) {
+	result := &IngestResult{}
+	files, err := walkDirectory(ctx, root, opts, func(file []Document) error {
+		for _, doc := range file {
+			if strings.TrimSpace(doc.Content) == "" {
+				continue
+			}
+			if _, err := db.SaveKnowledge(ctx, doc.KnowledgeSaveRequest(opts.Knowledge)); err != nil {
+				return fmt.Errorf("loaders: save %s: %w", doc.ID, err)
+			}
+			result.Documents++
+			result.IDs = append(result.IDs, doc.ID)
+		}
+		return nil
+	})
+	result.Files = files
+	return result, err
+}
+
+// walkDirectory loads the selected files under root and passes each
+// file's documents to fn. It returns the number of files loaded.
+func walkDirectory(ctx context.Context, root string, opts DirectoryOptions, fn func([]Document) error) (int, error) {
+	for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return 0, fmt.Errorf("loaders: bad pattern %q: %w", pattern, err)
+		}
+	}
+
+	var paths []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if matchAny(opts.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			return nil
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("loaders: walk %s: %w", root, err)
+	}
+	sort.Strings(paths)
+
+	files := 0
+	for _, rel := range paths {
+		if err := ctx.Err(); err != nil {
+			return files, err
+		}
+		loader := opts.loader(path.Ext(rel))
+		if loader == nil {
+			if len(opts.Include) > 0 {
+				return files, fmt.Errorf("loaders: no loader for %s", rel)
+			}
+			continue
+		}
+		docs, err := loadFile(loader, filepath.Join(root, filepath.FromSlash(rel)), rel)
+		if err != nil {
+			return files, err
+		}
+		files++
+		if err := fn(docs); err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+// loader picks the loader for an extension
+func (opts DirectoryOptions) loader(ext string) Loader {
+	if loader, ok := opts.Loaders[strings.ToLower(ext)]; ok {
+		return loader
+	}
+	return ForExtension(ext)
+}
+
+// matchAny reports whether rel matches one of the patterns
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if matchGlob(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches path elements against pattern elements, where a "**"
+// element matches zero or more path elements
+func matchGlob(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(elems); i++ {
+				if matchGlob(rest, elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
@@ -0,0 +1,447 @@
+package loaders
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// HTML loads an HTML page as Markdown-like text: headings become # lines,
+// list items "- " lines, table rows cells separated by " | " and <pre>
+// blocks fenced code, so chunking.Markdown can split the result by
+// section. Scripts, styles and the Exclude elements are dropped with their
+// content.
+//
+// The title comes from <title>, else the first <h1>, else the file name.
+// The description, author and keywords meta tags, the page language and
+// the canonical link (as "source_url") become metadata.
+type HTML struct {
+	Exclude []string // Elements dropped with their content (default: nav, footer, aside, form)
+}
+
+// Load implements Loader
+func (h HTML) Load(r io.Reader, source string) ([]Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("loaders: read %s: %w", source, err)
+	}
+	exclude := h.Exclude
+	if exclude == nil {
+		exclude = []string{"nav", "footer", "aside", "form"}
+	}
+
+	page := convertHTML(string(data), exclude)
+	title := firstNonEmpty(page.title, page.h1, titleFromSource(source))
+	return []Document{newDocument(source, title, page.text, page.metadata)}, nil
+}
+
+// htmlPage is the result of converting an HTML document
+type htmlPage struct {
+	text     string
+	title    string
+	h1       string
+	metadata map[string]string
+}
+
+// rawTextElements hold text that isn't markup
+var rawTextElements = map[string]bool{"script": true, "style": true, "title": true, "textarea": true}
+
+// droppedElements are never part of the text
+var droppedElements = map[string]bool{"script": true, "style": true, "noscript": true, "template": true, "svg": true, "iframe": true}
+
+// voidElements have no closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// paragraphElements are separated from their surroundings by a blank line,
+// lineElements by a line break
+var (
+	paragraphElements = map[string]bool{
+		"p": true, "blockquote": true, "section": true, "article": true, "main": true, "header": true,
+		"table": true, "dl": true, "figure": true, "hr": true, "address": true,
+		"details": true, "body": true,
+	}
+	lineElements = map[string]bool{
+		"div": true, "tr": true, "dt": true, "dd": true, "figcaption": true, "summary": true,
+		"caption": true, "thead": true, "tbody": true, "tfoot": true,
+	}
+)
+
+// convertHTML walks the markup once, writing text as it goes
+func convertHTML(src string, exclude []string) htmlPage {
+	page := htmlPage{metadata: make(map[string]string)}
+	excluded := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		excluded[strings.ToLower(name)] = true
+	}
+
+	var w htmlWriter
+	var lists []int // Per open list: -1 for unordered, else the next number
+	skipName, skipDepth := "", 0
+	headingStart := -1
+	pre := 0
+
+	for i := 0; i < len(src); {
+		if src[i] != '<' {
+			end := strings.IndexByte(src[i:], '<')
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += i
+			}
+			if skipDepth == 0 {
+				text := html.UnescapeString(src[i:end])
+				if pre > 0 {
+					if w.newlines > 0 {
+						// A newline right after <pre> isn't content
+						text = strings.TrimPrefix(text, "\n")
+					}
+					w.raw(text)
+				} else {
+					w.text(text)
+				}
+			}
+			i = end
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(src[i:], "<!--"):
+			end := strings.Index(src[i+4:], "-->")
+			if end < 0 {
+				return page.finish(&w)
+			}
+			i += 4 + end + 3
+			continue
+		case strings.HasPrefix(src[i:], "<!") || strings.HasPrefix(src[i:], "<?"):
+			end := strings.IndexByte(src[i:], '>')
+			if end < 0 {
+				return page.finish(&w)
+			}
+			i += end + 1
+			continue
+		}
+
+		tag, next, ok := parseHTMLTag(src, i)
+		if !ok {
+			// A lone '<' is text
+			if skipDepth == 0 {
+				w.text("<")
+			}
+			i++
+			continue
+		}
+		i = next
+
+		if rawTextElements[tag.name] && !tag.closing {
+			end := indexFold(src[i:], "</"+tag.name)
+			if end < 0 {
+				end = len(src) - i
+			}
+			content := src[i : i+end]
+			i += end
+			if close := strings.IndexByte(src[i:], '>'); close >= 0 {
+				i += close + 1
+			} else {
+				i = len(src)
+			}
+			switch {
+			case tag.name == "title" && page.title == "":
+				page.title = collapseSpace(html.UnescapeString(content))
+			case tag.name == "textarea" && skipDepth == 0:
+				w.text(html.UnescapeString(content))
+			}
+			continue
+		}
+
+		if skipDepth > 0 {
+			if tag.name == skipName && !voidElements[tag.name] {
+				if tag.closing {
+					skipDepth--
+				} else if !tag.selfClosing {
+					skipDepth++
+				}
+			}
+			continue
+		}
+		if !tag.closing && !tag.selfClosing && !voidElements[tag.name] && (droppedElements[tag.name] || excluded[tag.name]) {
+			skipName, skipDepth = tag.name, 1
+			continue
+		}
+
+		switch tag.name {
+		case "meta":
+			name := strings.ToLower(firstNonEmpty(tag.attrs["name"], tag.attrs["property"]))
+			switch name {
+			case "description", "author", "keywords":
+				if content := collapseSpace(tag.attrs["content"]); content != "" {
+					page.metadata[name] = content
+				}
+			case "og:title":
+				if page.title == "" {
+					page.title = collapseSpace(tag.attrs["content"])
+				}
+			}
+		case "link":
+			if strings.EqualFold(tag.attrs["rel"], "canonical") && tag.attrs["href"] != "" {
+				page.metadata["source_url"] = tag.attrs["href"]
+			}
+		case "html":
+			if lang := tag.attrs["lang"]; lang != "" && !tag.closing {
+				page.metadata["language"] = lang
+			}
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			w.breakLines(2)
+			if tag.closing {
+				if tag.name == "h1" && page.h1 == "" && headingStart >= 0 {
+					page.h1 = strings.TrimSpace(w.b.String()[headingStart:])
+				}
+				headingStart = -1
+				continue
+			}
+			level, _ := strconv.Atoi(tag.name[1:])
+			w.prefix(strings.Repeat("#", level) + " ")
+			headingStart = w.b.Len()
+		case "li":
+			if tag.closing {
+				w.breakLines(1)
+				continue
+			}
+			w.breakLines(1)
+			marker := "- "
+			if n := len(lists); n > 0 {
+				if lists[n-1] >= 0 {
+					marker = strconv.Itoa(lists[n-1]) + ". "
+					lists[n-1]++
+				}
+				marker = strings.Repeat("  ", n-1) + marker
+			}
+			w.prefix(marker)
+		case "ul", "ol":
+			if tag.closing {
+				if len(lists) > 0 {
+					lists = lists[:len(lists)-1]
+				}
+			} else if tag.name == "ol" {
+				lists = append(lists, 1)
+			} else {
+				lists = append(lists, -1)
+			}
+			// Nested lists continue their parent item's list
+			if len(lists) == 0 || (!tag.closing && len(lists) == 1) {
+				w.breakLines(2)
+			} else {
+				w.breakLines(1)
+			}
+		case "td", "th":
+			if !tag.closing && w.newlines == 0 && w.b.Len() > 0 {
+				w.prefix(" | ")
+			}
+		case "br":
+			w.lineBreak()
+		case "pre":
+			if tag.closing {
+				if pre > 0 {
+					pre--
+				}
+				w.breakLines(1)
+				w.prefix("```")
+				w.breakLines(2)
+			} else {
+				pre++
+				w.breakLines(2)
+				w.prefix("```")
+				w.lineBreak()
+			}
+		default:
+			switch {
+			case paragraphElements[tag.name]:
+				w.breakLines(2)
+			case lineElements[tag.name]:
+				w.breakLines(1)
+			}
+		}
+	}
+	return page.finish(&w)
+}
+
+func (p htmlPage) finish(w *htmlWriter) htmlPage {
+	p.text = strings.TrimSpace(w.b.String())
+	return p
+}
+
+// htmlTag is a parsed start or end tag
+type htmlTag struct {
+	name        string
+	closing     bool
+	selfClosing bool
+	attrs       map[string]string
+}
+
+// parseHTMLTag parses the tag starting at src[i] == '<' and returns the
+// index just past it
+func parseHTMLTag(src string, i int) (htmlTag, int, bool) {
+	var tag htmlTag
+	j := i + 1
+	if j < len(src) && src[j] == '/' {
+		tag.closing = true
+		j++
+	}
+	start := j
+	for j < len(src) && (isASCIILetter(src[j]) || (j > start && (src[j] >= '0' && src[j] <= '9' || src[j] == '-' || src[j] == ':'))) {
+		j++
+	}
+	if j == start {
+		return tag, 0, false
+	}
+	tag.name = strings.ToLower(src[start:j])
+
+	for j < len(src) {
+		for j < len(src) && isHTMLSpace(src[j]) {
+			j++
+		}
+		if j >= len(src) {
+			break
+		}
+		switch src[j] {
+		case '>':
+			return tag, j + 1, true
+		case '/':
+			tag.selfClosing = true
+			j++
+			continue
+		}
+
+		nameStart := j
+		for j < len(src) && !isHTMLSpace(src[j]) && src[j] != '=' && src[j] != '>' && src[j] != '/' {
+			j++
+		}
+		name := strings.ToLower(src[nameStart:j])
+		value := ""
+		for j < len(src) && isHTMLSpace(src[j]) {
+			j++
+		}
+		if j < len(src) && src[j] == '=' {
+			j++
+			for j < len(src) && isHTMLSpace(src[j]) {
+				j++
+			}
+			if j < len(src) && (src[j] == '"' || src[j] == '\'') {
+				quote := src[j]
+				end := strings.IndexByte(src[j+1:], quote)
+				if end < 0 {
+					return tag, len(src), true
+				}
+				value = src[j+1 : j+1+end]
+				j += end + 2
+			} else {
+				valueStart := j
+				for j < len(src) && !isHTMLSpace(src[j]) && src[j] != '>' {
+					j++
+				}
+				value = src[valueStart:j]
+			}
+		}
+		if name != "" {
+			if tag.attrs == nil {
+				tag.attrs = make(map[string]string)
+			}
+			tag.attrs[name] = html.UnescapeString(value)
+		}
+	}
+	return tag, len(src), true
+}
+
+// htmlWriter builds text with collapsed whitespace and controlled breaks
+type htmlWriter struct {
+	b        strings.Builder
+	newlines int  // Line breaks at the end of the text
+	space    bool // Whitespace seen since the last character
+	fresh    bool // A prefix was just written; drop leading whitespace
+}
+
+// text writes inline text, collapsing whitespace runs to one space
+func (w *htmlWriter) text(s string) {
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			w.space = true
+			continue
+		}
+		if w.space && w.newlines == 0 && !w.fresh && w.b.Len() > 0 {
+			w.b.WriteByte(' ')
+		}
+		w.b.WriteRune(r)
+		w.space, w.fresh, w.newlines = false, false, 0
+	}
+}
+
+// raw writes preformatted text as is
+func (w *htmlWriter) raw(s string) {
+	if s == "" {
+		return
+	}
+	w.b.WriteString(s)
+	w.newlines = len(s) - len(strings.TrimRight(s, "\n"))
+	w.space, w.fresh = false, false
+}
+
+// prefix writes markup such as a list marker
+func (w *htmlWriter) prefix(s string) {
+	w.b.WriteString(s)
+	w.newlines, w.space, w.fresh = 0, false, true
+}
+
+// breakLines ends the text with at least n line breaks
+func (w *htmlWriter) breakLines(n int) {
+	if w.b.Len() == 0 {
+		return
+	}
+	for w.newlines < n {
+		w.b.WriteByte('\n')
+		w.newlines++
+	}
+	w.space, w.fresh = false, false
+}
+
+// lineBreak always adds a line break, as <br> does
+func (w *htmlWriter) lineBreak() {
+	w.b.WriteByte('\n')
+	w.newlines++
+	w.space, w.fresh = false, false
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// indexFold is strings.Index ignoring ASCII case
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if s[i] == substr[0] && strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
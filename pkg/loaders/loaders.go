@@ -0,0 +1,150 @@
+// Package loaders turns files into documents ready for cortexdb ingestion:
+// HTML is reduced to text that keeps its heading structure, Markdown
+// front-matter becomes metadata, and CSV or JSON records become one
+// document each.
+package loaders
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/cortexdb"
+)
+
+// Document is a loaded document with its extracted title and metadata
+type Document struct {
+	ID       string            `json:"id"`
+	Title    string            `json:"title,omitempty"`
+	Content  string            `json:"content"`
+	Source   string            `json:"source,omitempty"` // Path the document was loaded from
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// GraphRAGDocument converts the document for DB.InsertGraphDocument.
+func (d Document) GraphRAGDocument() cortexdb.GraphRAGDocument {
+	return cortexdb.GraphRAGDocument{
+		ID:       d.ID,
+		Title:    d.Title,
+		Content:  d.Content,
+		Metadata: cloneMetadata(d.Metadata),
+	}
+}
+
+// KnowledgeSaveRequest converts the document for DB.SaveKnowledge. The
+// "source_url" and "author" metadata, when present, fill the matching
+// fields; template supplies everything else, such as the collection and
+// chunking, and its metadata is overridden by the document's.
+func (d Document) KnowledgeSaveRequest(template cortexdb.KnowledgeSaveRequest) cortexdb.KnowledgeSaveRequest {
+	req := template
+	req.KnowledgeID = d.ID
+	req.Title = d.Title
+	req.Content = d.Content
+	req.Metadata = cloneMetadata(template.Metadata)
+	if len(d.Metadata) > 0 && req.Metadata == nil {
+		req.Metadata = make(map[string]string, len(d.Metadata))
+	}
+	for k, v := range d.Metadata {
+		req.Metadata[k] = v
+	}
+	if v := d.Metadata["source_url"]; v != "" {
+		req.SourceURL = v
+	}
+	if v := d.Metadata["author"]; v != "" {
+		req.Author = v
+	}
+	return req
+}
+
+// Loader reads documents from r. Source names where r came from, usually a
+// path; loaders use it for IDs and default titles.
+type Loader interface {
+	Load(r io.Reader, source string) ([]Document, error)
+}
+
+// Text loads a file as a single document titled by its file name
+type Text struct{}
+
+// Load implements Loader
+func (Text) Load(r io.Reader, source string) ([]Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("loaders: read %s: %w", source, err)
+	}
+	return []Document{newDocument(source, titleFromSource(source), string(data), nil)}, nil
+}
+
+// ForExtension returns the default loader for a file extension such as
+// ".md", or nil when there is none
+func ForExtension(ext string) Loader {
+	switch strings.ToLower(ext) {
+	case ".txt", ".text":
+		return Text{}
+	case ".md", ".markdown", ".mdx":
+		return Markdown{}
+	case ".html", ".htm", ".xhtml":
+		return HTML{}
+	case ".csv":
+		return CSV{}
+	case ".tsv":
+		return CSV{Comma: '\t'}
+	case ".json", ".jsonl", ".ndjson":
+		return JSON{}
+	}
+	return nil
+}
+
+// LoadFile loads a file with the loader for its extension.
+func LoadFile(path string) ([]Document, error) {
+	loader := ForExtension(filepath.Ext(path))
+	if loader == nil {
+		return nil, fmt.Errorf("loaders: no loader for %s", path)
+	}
+	return loadFile(loader, path, filepath.ToSlash(path))
+}
+
+// loadFile opens path and loads it under the given source name
+func loadFile(loader Loader, path, source string) ([]Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("loaders: %w", err)
+	}
+	defer f.Close()
+	return loader.Load(bufio.NewReader(f), source)
+}
+
+// newDocument builds a document, recording its source in the metadata
+func newDocument(source, title, content string, metadata map[string]string) Document {
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	if source != "" {
+		metadata["source"] = source
+	}
+	return Document{ID: source, Title: title, Content: content, Source: source, Metadata: metadata}
+}
+
+// titleFromSource turns a path like docs/getting-started.md into
+// "getting started"
+func titleFromSource(source string) string {
+	base := filepath.Base(filepath.FromSlash(source))
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	if base == "." || base == string(filepath.Separator) {
+		return ""
+	}
+	return strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(base))
+}
+
+func cloneMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	out := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,236 @@
+package loaders
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liliang-cn/cortexdb/v2/pkg/cortexdb"
+)
+
+func loadOne(t *testing.T, loader Loader, text, source string) Document {
+	t.Helper()
+	docs, err := loader.Load(strings.NewReader(text), source)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("Expected 1 document, got %d", len(docs))
+	}
+	return docs[0]
+}
+
+func TestMarkdownFrontMatter(t *testing.T) {
+	text := `---
+title: "Getting Started"
+tags: [go, search]
+author:
+  name: Alice
+aliases:
+  - intro
+  - start # comment
+---
+# Ignored Heading
+
+Body text.
+`
+	doc := loadOne(t, Markdown{}, text, "docs/start.md")
+	if doc.Title != "Getting Started" {
+		t.Errorf("Expected front-matter title, got %q", doc.Title)
+	}
+	want := map[string]string{
+		"tags":        "go, search",
+		"author.name": "Alice",
+		"aliases":     "intro, start",
+		"source":      "docs/start.md",
+	}
+	for k, v := range want {
+		if doc.Metadata[k] != v {
+			t.Errorf("Metadata %s: expected %q, got %q", k, v, doc.Metadata[k])
+		}
+	}
+	if _, ok := doc.Metadata["title"]; ok {
+		t.Error("Expected title to be removed from metadata")
+	}
+	if !strings.HasPrefix(doc.Content, "# Ignored Heading") {
+		t.Errorf("Expected front-matter stripped from content, got %q", doc.Content)
+	}
+
+	t.Run("HeadingTitle", func(t *testing.T) {
+		doc := loadOne(t, Markdown{}, "```\n# not this\n```\n# Real Title\ntext", "a.md")
+		if doc.Title != "Real Title" {
+			t.Errorf("Expected first heading outside fences, got %q", doc.Title)
+		}
+	})
+
+	t.Run("Unclosed", func(t *testing.T) {
+		if _, err := (Markdown{}).Load(strings.NewReader("---\ntitle: x\n"), "a.md"); err == nil {
+			t.Error("Expected an error for unclosed front-matter")
+		}
+	})
+}
+
+func TestHTML(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html lang="en"><head>
+<title>Guide &amp; Notes</title>
+<meta name="description" content="A short guide">
+<link rel="canonical" href="https://example.com/guide">
+<style>body { color: red }</style>
+<script>var x = "<h1>no</h1>";</script>
+</head><body>
+<nav><a href="/">Home</a></nav>
+<h1>Guide</h1>
+<p>First   paragraph<br>second line.</p>
+<h2>Lists</h2>
+<ul><li>one</li><li>two<ol><li>nested</li></ol></li></ul>
+<table><tr><th>a</th><th>b</th></tr><tr><td>1</td><td>2</td></tr></table>
+<pre>
+code  here
+</pre>
+<footer>Copyright</footer>
+</body></html>`
+	doc := loadOne(t, HTML{}, page, "guide.html")
+	if doc.Title != "Guide & Notes" {
+		t.Errorf("Expected <title>, got %q", doc.Title)
+	}
+	if doc.Metadata["description"] != "A short guide" || doc.Metadata["source_url"] != "https://example.com/guide" || doc.Metadata["language"] != "en" {
+		t.Errorf("Unexpected metadata: %v", doc.Metadata)
+	}
+	want := "# Guide\n\nFirst paragraph\nsecond line.\n\n## Lists\n\n- one\n- two\n  1. nested\n\na | b\n1 | 2\n\n```\ncode  here\n```"
+	if doc.Content != want {
+		t.Errorf("Unexpected content:\n%s\nwant:\n%s", doc.Content, want)
+	}
+	for _, dropped := range []string{"Home", "Copyright", "color", "no"} {
+		if strings.Contains(doc.Content, dropped) {
+			t.Errorf("Expected %q to be dropped", dropped)
+		}
+	}
+
+	t.Run("HeadingTitle", func(t *testing.T) {
+		doc := loadOne(t, HTML{}, "<h1>Top <em>Level</em></h1><p>x</p>", "page.html")
+		if doc.Title != "Top Level" {
+			t.Errorf("Expected <h1> title, got %q", doc.Title)
+		}
+	})
+}
+
+func TestCSV(t *testing.T) {
+	text := "id,name,body,lang\n1,Alpha,first row,en\n,Beta,second row,fr\n"
+	loader := CSV{Records: Records{
+		IDField:        "id",
+		TitleField:     "name",
+		ContentFields:  []string{"body"},
+		MetadataFields: map[string]string{"lang": "language"},
+	}}
+	docs, err := loader.Load(strings.NewReader(text), "rows.csv")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+	if docs[0].ID != "1" || docs[0].Title != "Alpha" || docs[0].Content != "first row" || docs[0].Metadata["language"] != "en" {
+		t.Errorf("Unexpected first document: %+v", docs[0])
+	}
+	if docs[1].ID != "rows.csv#2" {
+		t.Errorf("Expected a row-based ID for an empty ID field, got %q", docs[1].ID)
+	}
+
+	t.Run("DefaultContent", func(t *testing.T) {
+		doc := loadOne(t, CSV{Records: Records{TitleField: "name"}}, "name,a,b\nX,1,\n", "r.csv")
+		if doc.Content != "a: 1" {
+			t.Errorf("Expected remaining non-empty fields as content, got %q", doc.Content)
+		}
+	})
+}
+
+func TestJSON(t *testing.T) {
+	loader := JSON{Records: Records{IDField: "id", TitleField: "title", MetadataFields: map[string]string{"tags": ""}}}
+	for name, text := range map[string]string{
+		"Array": `[{"id": "a", "title": "A", "text": "hello", "tags": ["x", "y"], "n": 3}]`,
+		"Lines": "{\"id\": \"a\", \"title\": \"A\", \"text\": \"hello\", \"tags\": [\"x\",\"y\"], \"n\": 3}\n\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			doc := loadOne(t, loader, text, "data.json")
+			if doc.ID != "a" || doc.Title != "A" {
+				t.Errorf("Unexpected ID or title: %+v", doc)
+			}
+			if doc.Content != "n: 3\ntext: hello" {
+				t.Errorf("Unexpected content %q", doc.Content)
+			}
+			if doc.Metadata["tags"] != `["x", "y"]` && doc.Metadata["tags"] != `["x","y"]` {
+				t.Errorf("Expected tags kept as JSON, got %q", doc.Metadata["tags"])
+			}
+		})
+	}
+}
+
+func TestKnowledgeSaveRequest(t *testing.T) {
+	doc := Document{ID: "d", Title: "T", Content: "c", Metadata: map[string]string{"author": "Bob", "k": "doc"}}
+	req := doc.KnowledgeSaveRequest(cortexdb.KnowledgeSaveRequest{
+		Collection: "kb",
+		Metadata:   map[string]string{"k": "template", "team": "x"},
+	})
+	if req.KnowledgeID != "d" || req.Collection != "kb" || req.Author != "Bob" {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+	if req.Metadata["k"] != "doc" || req.Metadata["team"] != "x" {
+		t.Errorf("Expected document metadata over template metadata, got %v", req.Metadata)
+	}
+}
+
+func TestLoadDirectory(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"README.md":           "# Readme\ntext",
+		"docs/guide.md":       "# Guide\ntext",
+		"docs/drafts/wip.md":  "# WIP\ntext",
+		"docs/page.html":      "<h1>Page</h1>",
+		"notes.txt":           "plain",
+		"image.png":           "binary",
+		"node_modules/x/a.md": "# Dep",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(docs []Document) string {
+		var out []string
+		for _, d := range docs {
+			out = append(out, d.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	docs, err := LoadDirectory(context.Background(), root, DirectoryOptions{Exclude: []string{"node_modules"}})
+	if err != nil {
+		t.Fatalf("LoadDirectory failed: %v", err)
+	}
+	if got := ids(docs); got != "README.md,docs/drafts/wip.md,docs/guide.md,docs/page.html,notes.txt" {
+		t.Errorf("Unexpected documents %s", got)
+	}
+
+	docs, err = LoadDirectory(context.Background(), root, DirectoryOptions{
+		Include: []string{"docs/**/*.md"},
+		Exclude: []string{"docs/drafts"},
+	})
+	if err != nil {
+		t.Fatalf("LoadDirectory failed: %v", err)
+	}
+	if got := ids(docs); got != "docs/guide.md" {
+		t.Errorf("Unexpected documents with globs %s", got)
+	}
+
+	if _, err := LoadDirectory(context.Background(), root, DirectoryOptions{Include: []string{"*.png"}}); err == nil {
+		t.Error("Expected an error for an included file without a loader")
+	}
+}
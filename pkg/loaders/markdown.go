@@ -0,0 +1,171 @@
+package loaders
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Markdown loads a Markdown file. YAML front-matter between --- lines
+// becomes metadata; its title field, or else the first level-one heading,
+// or else the file name, becomes the title. The front-matter is removed
+// from the content and the rest is kept as written, so it can be chunked
+// with chunking.Markdown.
+type Markdown struct{}
+
+// Load implements Loader
+func (Markdown) Load(r io.Reader, source string) ([]Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("loaders: read %s: %w", source, err)
+	}
+	body, metadata, err := SplitFrontMatter(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("loaders: %s: %w", source, err)
+	}
+
+	title := metadata["title"]
+	delete(metadata, "title")
+	if title == "" {
+		title = firstMarkdownHeading(body)
+	}
+	if title == "" {
+		title = titleFromSource(source)
+	}
+	doc := newDocument(source, title, strings.TrimSpace(body), metadata)
+	if id := metadata["id"]; id != "" {
+		doc.ID = id
+		delete(metadata, "id")
+	}
+	return []Document{doc}, nil
+}
+
+// SplitFrontMatter separates YAML front-matter from a Markdown document
+// and flattens it into metadata. Scalars are kept as written, lists are
+// joined with ", " and nested keys are joined with dots, as in
+// "author.name". Text without front-matter is returned unchanged.
+func SplitFrontMatter(text string) (string, map[string]string, error) {
+	metadata := make(map[string]string)
+	text = strings.TrimPrefix(text, "\ufeff")
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return text, metadata, nil
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if trimmed := strings.TrimRight(lines[i], "\r\n"); trimmed == "---" || trimmed == "..." {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return "", nil, fmt.Errorf("front-matter is not closed")
+	}
+	if err := parseFrontMatter(lines[1:end], metadata); err != nil {
+		return "", nil, err
+	}
+	return strings.Join(lines[end+1:], ""), metadata, nil
+}
+
+// parseFrontMatter reads the YAML subset used in front-matter: key: value
+// pairs, block and flow lists, and nested mappings
+func parseFrontMatter(lines []string, metadata map[string]string) error {
+	type level struct {
+		indent int
+		prefix string
+	}
+	var parents []level
+	var listKey string
+	var list []string
+	flush := func() {
+		if len(list) > 0 {
+			metadata[listKey] = strings.Join(list, ", ")
+		}
+		listKey, list = "", nil
+	}
+
+	for n, raw := range lines {
+		line := strings.TrimRight(raw, "\r\n")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return fmt.Errorf("front-matter line %d: list item without a key", n+2)
+			}
+			list = append(list, yamlScalar(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return fmt.Errorf("front-matter line %d: expected key: value", n+2)
+		}
+		flush()
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		key = yamlScalar(strings.TrimSpace(key))
+		if len(parents) > 0 {
+			key = parents[len(parents)-1].prefix + "." + key
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			// A list or a nested mapping follows
+			listKey = key
+			parents = append(parents, level{indent: indent, prefix: key})
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			var items []string
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = yamlScalar(strings.TrimSpace(item)); item != "" {
+					items = append(items, item)
+				}
+			}
+			metadata[key] = strings.Join(items, ", ")
+		default:
+			metadata[key] = yamlScalar(value)
+		}
+	}
+	flush()
+	return nil
+}
+
+// yamlScalar strips quotes and trailing comments from a YAML scalar
+func yamlScalar(value string) string {
+	if len(value) >= 2 {
+		if q := value[0]; (q == '"' || q == '\'') && value[len(value)-1] == q {
+			inner := value[1 : len(value)-1]
+			if q == '\'' {
+				return strings.ReplaceAll(inner, "''", "'")
+			}
+			return strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\n`, "\n", `\t`, "\t").Replace(inner)
+		}
+	}
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	return value
+}
+
+// firstMarkdownHeading returns the text of the first # heading outside
+// code fences
+func firstMarkdownHeading(body string) string {
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && strings.HasPrefix(trimmed, "# ") {
+			return strings.TrimSpace(strings.TrimRight(trimmed[2:], "#"))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,190 @@
+package loaders
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Records maps the fields of tabular records to documents. Each record
+// becomes one document.
+type Records struct {
+	IDField        string            // Field holding the document ID (default: source#row, counting from 1)
+	TitleField     string            // Field holding the title
+	ContentFields  []string          // Fields written to the content as "field: value" lines (default: every field not used for the ID, title or metadata)
+	MetadataFields map[string]string // Field name to metadata key; an empty key keeps the field name
+}
+
+// CSV loads a CSV file whose first row names the columns
+type CSV struct {
+	Records
+	Comma rune // Field delimiter (default: ',')
+}
+
+// Load implements Loader
+func (c CSV) Load(r io.Reader, source string) ([]Document, error) {
+	reader := csv.NewReader(r)
+	if c.Comma != 0 {
+		reader.Comma = c.Comma
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loaders: %s: %w", source, err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	var docs []Document
+	for row := 1; ; row++ {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loaders: %s: %w", source, err)
+		}
+		record := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(values) {
+				record[name] = values[i]
+			}
+		}
+		docs = append(docs, c.document(source, row, header, record))
+	}
+	return docs, nil
+}
+
+// JSON loads either a JSON array of objects or JSON Lines, one object per
+// line. Values that aren't strings are kept as JSON text.
+type JSON struct {
+	Records
+}
+
+// Load implements Loader
+func (j JSON) Load(r io.Reader, source string) ([]Document, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loaders: read %s: %w", source, err)
+	}
+
+	var objects []map[string]json.RawMessage
+	if first == '[' {
+		if err := json.NewDecoder(br).Decode(&objects); err != nil {
+			return nil, fmt.Errorf("loaders: %s: %w", source, err)
+		}
+	} else {
+		decoder := json.NewDecoder(br)
+		for {
+			var object map[string]json.RawMessage
+			err := decoder.Decode(&object)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("loaders: %s record %d: %w", source, len(objects)+1, err)
+			}
+			objects = append(objects, object)
+		}
+	}
+
+	docs := make([]Document, 0, len(objects))
+	for i, object := range objects {
+		fields := make([]string, 0, len(object))
+		record := make(map[string]string, len(object))
+		for name, raw := range object {
+			fields = append(fields, name)
+			record[name] = jsonText(raw)
+		}
+		// Objects don't keep key order; sort for stable content
+		sort.Strings(fields)
+		docs = append(docs, j.document(source, i+1, fields, record))
+	}
+	return docs, nil
+}
+
+// document builds the document for one record; fields lists the record's
+// field names in output order
+func (rec Records) document(source string, row int, fields []string, record map[string]string) Document {
+	id := strings.TrimSpace(record[rec.IDField])
+	if rec.IDField == "" || id == "" {
+		id = source + "#" + strconv.Itoa(row)
+	}
+
+	metadata := map[string]string{"row": strconv.Itoa(row)}
+	for field, key := range rec.MetadataFields {
+		if key == "" {
+			key = field
+		}
+		if value := strings.TrimSpace(record[field]); value != "" {
+			metadata[key] = value
+		}
+	}
+
+	contentFields := rec.ContentFields
+	if len(contentFields) == 0 {
+		for _, field := range fields {
+			if _, isMeta := rec.MetadataFields[field]; isMeta || field == rec.IDField || field == rec.TitleField {
+				continue
+			}
+			contentFields = append(contentFields, field)
+		}
+	}
+	var lines []string
+	for _, field := range contentFields {
+		if value := strings.TrimSpace(record[field]); value != "" {
+			lines = append(lines, field+": "+value)
+		}
+	}
+	if len(rec.ContentFields) == 1 && len(lines) == 1 {
+		// A single content field is the text itself
+		lines[0] = strings.TrimSpace(record[rec.ContentFields[0]])
+	}
+
+	doc := newDocument(source, strings.TrimSpace(record[rec.TitleField]), strings.Join(lines, "\n"), metadata)
+	doc.ID = id
+	return doc
+}
+
+// jsonText renders a JSON value as text
+func jsonText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return ""
+	}
+	return string(bytes.TrimSpace(raw))
+}
+
+// peekNonSpace returns the first non-whitespace byte without consuming it
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == ' ' || b == '\t' || b == '\n' || b == '\r' {
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
@@ -279,6 +279,11 @@ func (pq *ProductQuantizer) DeserializeCodebooks(data []byte) error {
 	pq.SubDim = int(binary.LittleEndian.Uint32(data[offset:]))
 	offset += 4
 	
+	if pq.M <= 0 || pq.K <= 0 || pq.K > 256 || pq.SubDim <= 0 || pq.M*pq.SubDim != pq.D ||
+		len(data) != 16+pq.M*pq.K*pq.SubDim*4 {
+		return errors.New("invalid codebook data")
+	}
+	
 	// Initialize codebooks
 	pq.Codebooks = make([][][]float32, pq.M)
 	
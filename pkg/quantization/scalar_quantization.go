@@ -188,6 +188,15 @@ func NewBinaryQuantizer(dimension int) *BinaryQuantizer {
 	}
 }
 
+// NewSignBinaryQuantizer creates a binary quantizer that thresholds every
+// dimension at zero. It is ready to encode without training, which suits
+// embeddings centered around the origin.
+func NewSignBinaryQuantizer(dimension int) *BinaryQuantizer {
+	bq := NewBinaryQuantizer(dimension)
+	bq.Trained = true
+	return bq
+}
+
 // Save serializes the quantizer
 func (bq *BinaryQuantizer) Save(w io.Writer) error {
 	enc := gob.NewEncoder(w)